@@ -0,0 +1,101 @@
+package blob
+
+import (
+	"context"
+	"time"
+
+	"github.com/meigma/blob/registry"
+)
+
+// AuditOperation identifies the client operation an [AuditEvent] records.
+type AuditOperation string
+
+// Audited operations.
+const (
+	AuditPush AuditOperation = "push"
+	AuditPull AuditOperation = "pull"
+)
+
+// AuditVerdict is the outcome of policy evaluation recorded in an
+// [AuditEvent].
+type AuditVerdict string
+
+// Policy verdicts.
+const (
+	// AuditVerdictNone means no policy was evaluated: the operation was a
+	// push, the client has no policies, or the pull failed before the
+	// manifest was verified.
+	AuditVerdictNone AuditVerdict = "none"
+
+	// AuditVerdictAllowed means every policy accepted the manifest.
+	AuditVerdictAllowed AuditVerdict = "allowed"
+
+	// AuditVerdictDenied means a policy rejected the manifest.
+	AuditVerdictDenied AuditVerdict = "denied"
+)
+
+// AuditEvent describes a completed Push or Pull, successful or not.
+type AuditEvent struct {
+	// Operation is the operation that completed.
+	Operation AuditOperation
+
+	// Ref is the reference passed to the operation.
+	Ref string
+
+	// Digest is the manifest digest pushed or pulled. It is empty if the
+	// operation failed before the manifest was known.
+	Digest string
+
+	// Verdict is the outcome of policy evaluation.
+	Verdict AuditVerdict
+
+	// BytesTransferred is the size of the archive blobs the operation moved,
+	// or zero if it failed. For Push it is the size of the blobs uploaded;
+	// blobs the registry already held are skipped and not counted. For Pull
+	// it is the size of the index blob, or zero when the index came from
+	// the cache; file data is read lazily after Pull returns and is not
+	// counted.
+	BytesTransferred int64
+
+	// Start is when the operation began.
+	Start time.Time
+
+	// Duration is how long the operation took.
+	Duration time.Duration
+
+	// Err is the error the operation returned, or nil on success.
+	Err error
+}
+
+// audit reports a completed operation to the audit hook, if one is set.
+func (c *Client) audit(event *AuditEvent) {
+	if c.auditHook == nil {
+		return
+	}
+	event.Duration = time.Since(event.Start)
+	c.auditHook(*event)
+}
+
+// auditPolicies wraps policies so their verdict and the evaluated manifest
+// digest are recorded in event. The registry client evaluates the policies
+// in order with the same request and stops at the first failure.
+func auditPolicies(policies []Policy, event *AuditEvent) []Policy {
+	wrapped := make([]Policy, len(policies))
+	for i, p := range policies {
+		wrapped[i] = registry.PolicyFunc(func(ctx context.Context, req registry.PolicyRequest) error {
+			err := p.Evaluate(ctx, req)
+			event.Digest = req.Digest
+			switch {
+			case err == nil:
+				event.Verdict = AuditVerdictAllowed
+			case ctx.Err() == nil:
+				event.Verdict = AuditVerdictDenied
+			default:
+				// Cancelled before the policy reached a verdict.
+				event.Verdict = AuditVerdictNone
+			}
+			return err
+		})
+	}
+	return wrapped
+}
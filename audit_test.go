@@ -0,0 +1,291 @@
+package blob
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/opencontainers/go-digest"
+	ocispec "github.com/opencontainers/image-spec/specs-go/v1"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/meigma/blob/registry"
+	"github.com/meigma/blob/registry/mocks"
+)
+
+// memRegistry is an in-memory registry for one repository. Blobs are also
+// served over HTTP for the lazy data source created by Pull.
+type memRegistry struct {
+	mu        sync.Mutex
+	blobs     map[digest.Digest][]byte
+	manifests map[digest.Digest][]byte
+	tags      map[string]ocispec.Descriptor
+	ranges    map[digest.Digest][]string // Range headers of blob requests
+	server    *httptest.Server
+}
+
+func newMemRegistry(t *testing.T) *memRegistry {
+	t.Helper()
+
+	r := &memRegistry{
+		blobs:     make(map[digest.Digest][]byte),
+		manifests: make(map[digest.Digest][]byte),
+		tags:      make(map[string]ocispec.Descriptor),
+		ranges:    make(map[digest.Digest][]string),
+	}
+	r.server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		dgst := digest.Digest(strings.TrimPrefix(req.URL.Path, "/blobs/"))
+		r.mu.Lock()
+		data, ok := r.blobs[dgst]
+		r.ranges[dgst] = append(r.ranges[dgst], req.Header.Get("Range"))
+		r.mu.Unlock()
+		if !ok {
+			http.NotFound(w, req)
+			return
+		}
+		http.ServeContent(w, req, "", time.Time{}, bytes.NewReader(data))
+	}))
+	t.Cleanup(r.server.Close)
+	return r
+}
+
+// memClient adds a blob existence check to the mock so Push skips blobs
+// the registry already holds.
+type memClient struct {
+	*mocks.OCIClientMock
+	r *memRegistry
+}
+
+func (m *memClient) BlobExists(_ context.Context, _ string, desc *ocispec.Descriptor) (bool, error) {
+	m.r.mu.Lock()
+	defer m.r.mu.Unlock()
+	_, ok := m.r.blobs[desc.Digest]
+	return ok, nil
+}
+
+// client returns an OCI client backed by the registry.
+func (r *memRegistry) client() registry.OCIClient {
+	return &memClient{r: r, OCIClientMock: &mocks.OCIClientMock{
+		PushBlobFunc: func(_ context.Context, _ string, desc *ocispec.Descriptor, content io.Reader) error {
+			data, err := io.ReadAll(content)
+			if err != nil {
+				return err
+			}
+			r.mu.Lock()
+			defer r.mu.Unlock()
+			r.blobs[desc.Digest] = data
+			return nil
+		},
+		FetchBlobFunc: func(_ context.Context, _ string, desc *ocispec.Descriptor) (io.ReadCloser, error) {
+			r.mu.Lock()
+			defer r.mu.Unlock()
+			data, ok := r.blobs[desc.Digest]
+			if !ok {
+				return nil, registry.ErrNotFound
+			}
+			return io.NopCloser(bytes.NewReader(data)), nil
+		},
+		PushManifestFunc: func(_ context.Context, _, tag string, manifest *ocispec.Manifest) (ocispec.Descriptor, error) {
+			raw, err := json.Marshal(manifest)
+			if err != nil {
+				return ocispec.Descriptor{}, err
+			}
+			desc := ocispec.Descriptor{
+				MediaType: ocispec.MediaTypeImageManifest,
+				Digest:    digest.FromBytes(raw),
+				Size:      int64(len(raw)),
+			}
+			r.mu.Lock()
+			defer r.mu.Unlock()
+			r.manifests[desc.Digest] = raw
+			r.tags[tag] = desc
+			return desc, nil
+		},
+		FetchManifestFunc: func(_ context.Context, _ string, expected *ocispec.Descriptor) (ocispec.Manifest, []byte, error) {
+			r.mu.Lock()
+			raw, ok := r.manifests[expected.Digest]
+			r.mu.Unlock()
+			if !ok {
+				return ocispec.Manifest{}, nil, registry.ErrNotFound
+			}
+			var manifest ocispec.Manifest
+			err := json.Unmarshal(raw, &manifest)
+			return manifest, raw, err
+		},
+		ResolveFunc: func(_ context.Context, _, ref string) (ocispec.Descriptor, error) {
+			r.mu.Lock()
+			defer r.mu.Unlock()
+			desc, ok := r.tags[ref]
+			if !ok {
+				return ocispec.Descriptor{}, registry.ErrNotFound
+			}
+			return desc, nil
+		},
+		BlobURLFunc: func(_, dgst string) (string, error) {
+			return r.server.URL + "/blobs/" + dgst, nil
+		},
+		AuthHeadersFunc: func(context.Context, string) (http.Header, error) {
+			return http.Header{}, nil
+		},
+	}}
+}
+
+// newAuditClient returns a client backed by r whose audit events are
+// collected in the returned slice.
+func newAuditClient(t *testing.T, r *memRegistry, opts ...Option) (*Client, *[]AuditEvent) {
+	t.Helper()
+
+	var events []AuditEvent
+	opts = append(opts,
+		withOCIClient(r.client()),
+		WithAuditHook(func(e AuditEvent) { events = append(events, e) }),
+	)
+	c, err := NewClient(opts...)
+	require.NoError(t, err)
+	return c, &events
+}
+
+func writeAuditFiles(t *testing.T) string {
+	t.Helper()
+
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "a.txt"), []byte("hello"), 0o600))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "b.txt"), []byte("world"), 0o600))
+	return dir
+}
+
+func TestWithAuditHook(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	const ref = "registry.example.com/repo:v1"
+
+	t.Run("push and pull", func(t *testing.T) {
+		t.Parallel()
+
+		r := newMemRegistry(t)
+		var evaluated string
+		allow := registry.PolicyFunc(func(_ context.Context, req registry.PolicyRequest) error {
+			evaluated = req.Digest
+			return nil
+		})
+		c, events := newAuditClient(t, r, WithPolicy(allow))
+
+		require.NoError(t, c.Push(ctx, ref, writeAuditFiles(t)))
+		require.Len(t, *events, 1)
+		push := (*events)[0]
+		assert.Equal(t, AuditPush, push.Operation)
+		assert.Equal(t, ref, push.Ref)
+		assert.Equal(t, r.tags["v1"].Digest.String(), push.Digest)
+		assert.Equal(t, AuditVerdictNone, push.Verdict)
+		assert.Positive(t, push.BytesTransferred)
+		assert.False(t, push.Start.IsZero())
+		require.NoError(t, push.Err)
+
+		archive, err := c.Pull(ctx, ref)
+		require.NoError(t, err)
+		require.Len(t, *events, 2)
+		pull := (*events)[1]
+		assert.Equal(t, AuditPull, pull.Operation)
+		assert.Equal(t, ref, pull.Ref)
+		assert.Equal(t, push.Digest, pull.Digest)
+		assert.Equal(t, push.Digest, evaluated)
+		assert.Equal(t, AuditVerdictAllowed, pull.Verdict)
+		assert.Equal(t, archive.Manifest().IndexDescriptor().Size, pull.BytesTransferred)
+		require.NoError(t, pull.Err)
+
+		data, err := archive.ReadFile("a.txt")
+		require.NoError(t, err)
+		assert.Equal(t, "hello", string(data))
+	})
+
+	t.Run("policy-rejected pull", func(t *testing.T) {
+		t.Parallel()
+
+		r := newMemRegistry(t)
+		pusher, _ := newAuditClient(t, r)
+		require.NoError(t, pusher.Push(ctx, ref, writeAuditFiles(t)))
+
+		deny := registry.PolicyFunc(func(context.Context, registry.PolicyRequest) error {
+			return errors.New("untrusted signer")
+		})
+		c, events := newAuditClient(t, r, WithPolicy(deny))
+		_, err := c.Pull(ctx, ref)
+		require.ErrorIs(t, err, ErrPolicyViolation)
+
+		require.Len(t, *events, 1)
+		pull := (*events)[0]
+		assert.Equal(t, AuditPull, pull.Operation)
+		assert.Equal(t, r.tags["v1"].Digest.String(), pull.Digest)
+		assert.Equal(t, AuditVerdictDenied, pull.Verdict)
+		assert.Zero(t, pull.BytesTransferred)
+		require.ErrorIs(t, pull.Err, ErrPolicyViolation)
+	})
+
+	t.Run("skipped uploads and cached index", func(t *testing.T) {
+		t.Parallel()
+
+		r := newMemRegistry(t)
+		c, events := newAuditClient(t, r, WithCacheDir(t.TempDir()))
+		dir := writeAuditFiles(t)
+		require.NoError(t, c.Push(ctx, ref, dir))
+		require.NoError(t, c.Push(ctx, ref, dir))
+		_, err := c.Pull(ctx, ref)
+		require.NoError(t, err)
+		_, err = c.Pull(ctx, ref)
+		require.NoError(t, err)
+
+		require.Len(t, *events, 4)
+		assert.Positive(t, (*events)[0].BytesTransferred)
+		assert.Zero(t, (*events)[1].BytesTransferred, "blobs the registry already held")
+		assert.Positive(t, (*events)[2].BytesTransferred)
+		assert.Zero(t, (*events)[3].BytesTransferred, "index served from the cache")
+	})
+
+	t.Run("pull without policies", func(t *testing.T) {
+		t.Parallel()
+
+		r := newMemRegistry(t)
+		c, events := newAuditClient(t, r)
+		require.NoError(t, c.Push(ctx, ref, writeAuditFiles(t)))
+		_, err := c.Pull(ctx, ref)
+		require.NoError(t, err)
+
+		require.Len(t, *events, 2)
+		assert.Equal(t, AuditVerdictNone, (*events)[1].Verdict)
+		assert.Equal(t, (*events)[0].Digest, (*events)[1].Digest)
+	})
+
+	t.Run("failed operations", func(t *testing.T) {
+		t.Parallel()
+
+		r := newMemRegistry(t)
+		c, events := newAuditClient(t, r)
+
+		_, err := c.Pull(ctx, "registry.example.com/repo:missing")
+		require.Error(t, err)
+		err = c.Push(ctx, ref, filepath.Join(t.TempDir(), "missing"))
+		require.Error(t, err)
+
+		require.Len(t, *events, 2)
+		for _, e := range *events {
+			require.Error(t, e.Err)
+			assert.Empty(t, e.Digest)
+			assert.Equal(t, AuditVerdictNone, e.Verdict)
+			assert.Zero(t, e.BytesTransferred)
+		}
+		assert.Equal(t, AuditPull, (*events)[0].Operation)
+		assert.Equal(t, AuditPush, (*events)[1].Operation)
+	})
+}
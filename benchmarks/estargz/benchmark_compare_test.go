@@ -833,33 +833,6 @@ func fetchHTTPBytes(client *http.Client, url string) ([]byte, error) {
 	return io.ReadAll(resp.Body)
 }
 
-type memByteSource struct {
-	data     []byte
-	sourceID string
-}
-
-func (m *memByteSource) ReadAt(p []byte, off int64) (int, error) {
-	if off < 0 {
-		return 0, errors.New("negative offset")
-	}
-	if off >= int64(len(m.data)) {
-		return 0, io.EOF
-	}
-	n := copy(p, m.data[off:])
-	if n < len(p) {
-		return n, io.EOF
-	}
-	return n, nil
-}
-
-func (m *memByteSource) Size() int64 {
-	return int64(len(m.data))
-}
-
-func (m *memByteSource) SourceID() string {
-	return m.sourceID
-}
-
 type benchSource struct {
 	name        string
 	newBlob     func(b *testing.B, data []byte) (blob.ByteSource, func(), error)
@@ -885,7 +858,7 @@ func benchSources() []benchSource {
 }
 
 func newBenchMemBlobSource(_ *testing.B, data []byte) (blob.ByteSource, func(), error) {
-	return &memByteSource{data: data, sourceID: "mem"}, nil, nil
+	return blob.NewReaderAtSource(bytes.NewReader(data), int64(len(data)), "mem"), nil, nil
 }
 
 func newBenchMemReaderAt(_ *testing.B, data []byte) (io.ReaderAt, func(), error) {
@@ -19,18 +19,26 @@ type Client struct {
 	orasOpts []oras.Option
 
 	// Caches
-	contentCache  corecache.Cache             // core/cache - for file content
-	blockCache    corecache.BlockCache        // core/cache - for HTTP range optimization
-	refCache      registrycache.RefCache      // registry/cache - tag→digest
-	manifestCache registrycache.ManifestCache // registry/cache - digest→manifest
-	indexCache    registrycache.IndexCache    // registry/cache - digest→index bytes
-	refCacheTTL   time.Duration               // TTL for ref cache entries
+	contentCache     corecache.Cache             // core/cache - for file content
+	blockCache       corecache.BlockCache        // core/cache - for HTTP range optimization
+	refCache         registrycache.RefCache      // registry/cache - tag→digest
+	manifestCache    registrycache.ManifestCache // registry/cache - digest→manifest
+	indexCache       registrycache.IndexCache    // registry/cache - digest→index bytes
+	referrerCache    registrycache.ReferrerCache // registry/cache - referrer listings and content
+	refCacheTTL      time.Duration               // TTL for ref cache entries
+	referrerCacheTTL time.Duration               // TTL for referrer listings
 
 	// Policies
 	policies []Policy
 
+	// Audit
+	auditHook func(AuditEvent)
+
 	// Logger
 	logger *slog.Logger
+
+	// oci replaces the ORAS client when set by withOCIClient.
+	oci registry.OCIClient
 }
 
 // log returns the logger, falling back to a discard logger if nil.
@@ -47,7 +55,8 @@ func (c *Client) log() *slog.Logger {
 // Use [WithDockerConfig] to read credentials from ~/.docker/config.json.
 func NewClient(opts ...Option) (*Client, error) {
 	c := &Client{
-		refCacheTTL: DefaultRefCacheTTL,
+		refCacheTTL:      DefaultRefCacheTTL,
+		referrerCacheTTL: DefaultReferrerCacheTTL,
 	}
 	for _, opt := range opts {
 		if err := opt(c); err != nil {
@@ -9,6 +9,7 @@ import (
 
 	corecache "github.com/meigma/blob/core/cache"
 	coredisk "github.com/meigma/blob/core/cache/disk"
+	"github.com/meigma/blob/registry"
 	registrycache "github.com/meigma/blob/registry/cache"
 	registrydisk "github.com/meigma/blob/registry/cache/disk"
 	"github.com/meigma/blob/registry/oras"
@@ -23,8 +24,10 @@ const (
 	DefaultBlockCacheSize    int64 = 50 << 20  // 50 MB
 	DefaultIndexCacheSize    int64 = 50 << 20  // 50 MB
 	DefaultManifestCacheSize int64 = 10 << 20  // 10 MB
+	DefaultReferrerCacheSize int64 = 10 << 20  // 10 MB
 	DefaultRefCacheSize      int64 = 5 << 20   // 5 MB
 	DefaultRefCacheTTL             = 5 * time.Minute
+	DefaultReferrerCacheTTL        = time.Minute
 )
 
 // --- Authentication Options ---
@@ -93,6 +96,7 @@ func WithUserAgent(ua string) Option {
 //   - dir/refs/    - tag→digest cache (5 MB)
 //   - dir/manifests/ - manifest cache (10 MB)
 //   - dir/indexes/ - index blob cache (50 MB)
+//   - dir/referrers/ - referrer cache (10 MB)
 //
 // For custom sizes or selective caching, use individual cache options.
 func WithCacheDir(dir string) Option {
@@ -152,6 +156,17 @@ func WithCacheDir(dir string) Option {
 		}
 		c.indexCache = indexCache
 
+		// Referrer cache
+		referrerCache, err := registrydisk.NewReferrerCache(
+			filepath.Join(dir, "referrers"),
+			registrydisk.WithMaxBytes(DefaultReferrerCacheSize),
+			registrydisk.WithReferrerCacheTTL(c.referrerCacheTTL),
+		)
+		if err != nil {
+			return err
+		}
+		c.referrerCache = referrerCache
+
 		return nil
 	}
 }
@@ -228,6 +243,26 @@ func WithIndexCacheDir(dir string) Option {
 	}
 }
 
+// WithReferrerCacheDir enables referrer caching for [Client.FetchReferrers]
+// in the specified directory with the default size limit ([DefaultReferrerCacheSize]).
+//
+// Listings use the TTL configured via [WithReferrerCacheTTL], which defaults
+// to [DefaultReferrerCacheTTL] (1 minute). Set [WithReferrerCacheTTL] before
+// this option to customize the TTL.
+func WithReferrerCacheDir(dir string) Option {
+	return func(c *Client) error {
+		cache, err := registrydisk.NewReferrerCache(dir,
+			registrydisk.WithMaxBytes(DefaultReferrerCacheSize),
+			registrydisk.WithReferrerCacheTTL(c.referrerCacheTTL),
+		)
+		if err != nil {
+			return err
+		}
+		c.referrerCache = cache
+		return nil
+	}
+}
+
 // WithRefCacheTTL sets the TTL for reference cache entries.
 // This determines how long tag→digest mappings are considered fresh.
 // Use 0 to disable TTL expiration. Negative values are not allowed.
@@ -244,6 +279,25 @@ func WithRefCacheTTL(ttl time.Duration) Option {
 	}
 }
 
+// WithReferrerCacheTTL sets the TTL for cached referrer listings.
+// This determines how long a listing is trusted before the registry is
+// asked again, so referrers attached later, such as new signatures, are
+// seen once it expires. Referrer content is addressed by digest and is
+// cached without expiry. Use 0 to disable TTL expiration. Negative values
+// are not allowed.
+//
+// This option must be set before [WithCacheDir] or [WithReferrerCacheDir]
+// to take effect, as the TTL is applied when the cache is created.
+func WithReferrerCacheTTL(ttl time.Duration) Option {
+	return func(c *Client) error {
+		if ttl < 0 {
+			return errors.New("referrer cache TTL must be non-negative")
+		}
+		c.referrerCacheTTL = ttl
+		return nil
+	}
+}
+
 // --- Caching Options (Advanced) ---
 
 // WithContentCache sets a custom content cache implementation.
@@ -291,6 +345,15 @@ func WithIndexCache(cache registrycache.IndexCache) Option {
 	}
 }
 
+// WithReferrerCache sets a custom referrer cache implementation.
+// Import github.com/meigma/blob/registry/cache/disk for the disk implementation.
+func WithReferrerCache(cache registrycache.ReferrerCache) Option {
+	return func(c *Client) error {
+		c.referrerCache = cache
+		return nil
+	}
+}
+
 // --- Policy Options ---
 
 // WithPolicy adds a policy that must pass for Fetch and Pull operations.
@@ -316,6 +379,23 @@ func WithPolicies(policies ...Policy) Option {
 	}
 }
 
+// --- Audit Options ---
+
+// WithAuditHook sets a function called after every Push and Pull completes,
+// whether it succeeded or failed, with a record of the ref, manifest
+// digest, policy verdict, and bytes transferred.
+//
+// The hook runs synchronously before the operation returns, so no event is
+// lost, and a slow hook delays the caller. Forward events to a channel or
+// queue if delivery may block. The hook may be called concurrently by
+// concurrent operations.
+func WithAuditHook(hook func(AuditEvent)) Option {
+	return func(c *Client) error {
+		c.auditHook = hook
+		return nil
+	}
+}
+
 // WithLogger sets a logger for the client.
 // The logger is propagated to the underlying registry client.
 // If nil, a discard logger is used (default behavior).
@@ -325,3 +405,12 @@ func WithLogger(logger *slog.Logger) Option {
 		return nil
 	}
 }
+
+// withOCIClient replaces the ORAS client used by every operation. It is
+// unexported so that tests can run against an in-memory registry.
+func withOCIClient(oci registry.OCIClient) Option {
+	return func(c *Client) error {
+		c.oci = oci
+		return nil
+	}
+}
@@ -60,6 +60,25 @@ func TestWithRefCacheTTL(t *testing.T) {
 	}
 }
 
+func TestWithReferrerCacheTTL(t *testing.T) {
+	t.Parallel()
+
+	client, err := NewClient()
+	require.NoError(t, err)
+	assert.Equal(t, DefaultReferrerCacheTTL, client.referrerCacheTTL)
+
+	client, err = NewClient(
+		WithReferrerCacheTTL(10*time.Second),
+		WithReferrerCacheDir(filepath.Join(t.TempDir(), "referrers")),
+	)
+	require.NoError(t, err)
+	require.NotNil(t, client.referrerCache)
+	assert.Equal(t, 10*time.Second, client.referrerCacheTTL)
+
+	_, err = NewClient(WithReferrerCacheTTL(-time.Second))
+	require.ErrorContains(t, err, "referrer cache TTL must be non-negative")
+}
+
 func TestWithCacheDir_AppliesRefCacheTTL(t *testing.T) {
 	t.Parallel()
 
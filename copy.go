@@ -0,0 +1,50 @@
+package blob
+
+import (
+	"context"
+
+	"github.com/meigma/blob/registry"
+)
+
+// CopyArchiveOption configures a Client.Copy operation.
+type CopyArchiveOption func(*copyArchiveConfig)
+
+type copyArchiveConfig struct {
+	skipReferrers bool
+}
+
+// CopyArchiveWithReferrers controls whether signatures, attestations, and
+// other referrers are copied along with the archive (default: true).
+func CopyArchiveWithReferrers(enabled bool) CopyArchiveOption {
+	return func(cfg *copyArchiveConfig) {
+		cfg.skipReferrers = !enabled
+	}
+}
+
+// Copy transfers the archive at srcRef to dstRef, for example to promote
+// it from a staging registry to production.
+//
+// Blobs are streamed between registries by digest without extracting or
+// re-creating the archive, so the manifest digest and annotations are
+// preserved. Blobs already present in the destination are skipped.
+// Referrers such as signatures are copied too; use
+// [CopyArchiveWithReferrers] to disable this. If dstRef has no tag, the
+// source tag is used. A multi-platform image index is copied together with
+// every manifest it lists.
+func (c *Client) Copy(ctx context.Context, srcRef, dstRef string, opts ...CopyArchiveOption) error {
+	cfg := copyArchiveConfig{}
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+
+	c.log().Debug("copying archive", "src", srcRef, "dst", dstRef)
+
+	regClient := registry.New(buildRegistryOpts(c)...)
+
+	var copyOpts []registry.CopyOption
+	if cfg.skipReferrers {
+		copyOpts = append(copyOpts, registry.WithCopyReferrers(false))
+	}
+
+	return regClient.Copy(ctx, srcRef, dstRef, copyOpts...)
+}
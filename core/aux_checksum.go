@@ -0,0 +1,24 @@
+package blob
+
+import (
+	"hash"
+
+	"github.com/cespare/xxhash/v2"
+)
+
+// newAuxHasher returns a hasher for alg, or nil for AuxChecksumNone and
+// unknown algorithms.
+func newAuxHasher(alg AuxChecksum) hash.Hash64 {
+	if alg == AuxChecksumXXH64 {
+		return xxhash.New()
+	}
+	return nil
+}
+
+// auxSum returns the checksum accumulated by h, or zero when h is nil.
+func auxSum(h hash.Hash64) uint64 {
+	if h == nil {
+		return 0
+	}
+	return h.Sum64()
+}
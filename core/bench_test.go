@@ -205,6 +205,47 @@ func BenchmarkEntriesWithPrefix(b *testing.B) {
 	}
 }
 
+func BenchmarkEntriesWithSuffix(b *testing.B) {
+	cases := []struct {
+		name      string
+		fileCount int
+	}{
+		{name: "files=1024", fileCount: 1024},
+		{name: "files=16384", fileCount: 16384},
+	}
+	// Matches one file in ten.
+	const suffix = "5.dat"
+
+	for _, bc := range cases {
+		dir := b.TempDir()
+		makeBenchFiles(b, dir, bc.fileCount, 64, benchPatternCompressible)
+		idx := createBenchIndex(b, dir)
+
+		b.Run(bc.name+"/method=suffix", func(b *testing.B) {
+			b.ReportAllocs()
+			for b.Loop() {
+				count := 0
+				for range idx.EntriesWithSuffixView(suffix) {
+					count++
+				}
+				benchSinkInt = count
+			}
+		})
+		b.Run(bc.name+"/method=filter", func(b *testing.B) {
+			b.ReportAllocs()
+			for b.Loop() {
+				count := 0
+				for view := range idx.EntriesView() {
+					if strings.HasSuffix(view.Path(), suffix) {
+						count++
+					}
+				}
+				benchSinkInt = count
+			}
+		})
+	}
+}
+
 func BenchmarkEntriesWithPrefixCopy(b *testing.B) {
 	cases := []struct {
 		name      string
@@ -18,6 +18,7 @@ import (
 	"path/filepath"
 	"runtime"
 	"sort"
+	"strconv"
 	"strings"
 	"sync"
 	"sync/atomic"
@@ -159,10 +160,6 @@ func (c *countingSource) RangeRequests() int64 {
 	return atomic.LoadInt64(&c.readCalls)
 }
 
-type rangeReader interface {
-	ReadRange(off, length int64) (io.ReadCloser, error)
-}
-
 type countingRangeSource struct {
 	*countingSource
 	rr         rangeReader
@@ -316,26 +313,6 @@ func (c *countingCache) Gets() int64 {
 	return atomic.LoadInt64(&c.gets)
 }
 
-type discardSink struct{}
-
-type discardCommitter struct{}
-
-func (discardCommitter) Write(p []byte) (int, error) { return len(p), nil }
-func (discardCommitter) Commit() error               { return nil }
-func (discardCommitter) Discard() error              { return nil }
-
-func (discardSink) ShouldProcess(*batch.Entry) bool {
-	return true
-}
-
-func (discardSink) Writer(*batch.Entry) (batch.Committer, error) {
-	return discardCommitter{}, nil
-}
-
-func (discardSink) PutBuffered(*batch.Entry, []byte) error {
-	return nil
-}
-
 type httpMetrics struct {
 	requestBytes  int64
 	responseBytes int64
@@ -410,7 +387,7 @@ func newCountingHTTPSource(data []byte, cfg benchHTTPConfig) (benchByteSource, *
 //nolint:unparam // fileSize parameter kept for flexibility
 func buildSyntheticIndex(fileCount, fileSize int) []byte {
 	entries := makeSyntheticEntries(fileCount, fileSize)
-	return buildIndex(entries, uint64(fileCount*fileSize), nil)
+	return buildIndex(entries, indexMetadata{dataSize: uint64(fileCount * fileSize)})
 }
 
 func makeSyntheticEntries(fileCount, fileSize int) []Entry {
@@ -816,7 +793,7 @@ func BenchmarkSequentialVsRandom(b *testing.B) {
 			b.ReportAllocs()
 			b.ResetTimer()
 			for b.Loop() {
-				if _, err := processor.Process(entries, discardSink{}); err != nil {
+				if _, err := processor.Process(entries, batch.NewDiscardSink()); err != nil {
 					b.Fatal(err)
 				}
 			}
@@ -858,9 +835,9 @@ func BenchmarkDirectoryTransfer(b *testing.B) {
 		{
 			name: "single-range",
 			fn: func(blob *Blob, _ benchByteSource) error {
-				entries := blob.collectPrefixEntries(prefix)
+				entries, _ := blob.collectPrefixEntries(prefix, &copyFilter{})
 				processor := batch.NewProcessor(blob.reader.Source(), blob.reader.Pool(), blob.maxFileSize, batch.WithReadConcurrency(1))
-				_, err := processor.Process(entries, discardSink{})
+				_, err := processor.Process(entries, batch.NewDiscardSink())
 				return err
 			},
 		},
@@ -1002,6 +979,54 @@ func BenchmarkFileLookup(b *testing.B) {
 	}
 }
 
+// BenchmarkFileLookupMiss measures lookups of paths that are not in the
+// index, with and without a bloom filter.
+func BenchmarkFileLookupMiss(b *testing.B) {
+	cases := []int{1000, 100000}
+	const fileSize = 4 << 10
+
+	for _, fileCount := range cases {
+		for _, bloom := range []bool{false, true} {
+			name := fmt.Sprintf("files=%d/bloom=%t", fileCount, bloom)
+			b.Run(name, func(b *testing.B) {
+				entries := makeSyntheticEntries(fileCount, fileSize)
+				indexData := buildIndex(entries, indexMetadata{
+					dataSize:    uint64(fileCount * fileSize),
+					bloomFilter: bloom,
+				})
+				idx, err := index.Load(indexData)
+				if err != nil {
+					b.Fatal(err)
+				}
+				misses := make([]string, 1024)
+				for i := range misses {
+					misses[i] = fmt.Sprintf("dir%03d/file%07d.tmp", i%(fileCount/1000+1), i*fileCount/len(misses))
+				}
+
+				b.ReportAllocs()
+				b.ResetTimer()
+				i := 0
+				for b.Loop() {
+					if _, ok := idx.LookupView(misses[i%len(misses)]); ok {
+						b.Fatalf("unexpected hit for %s", misses[i%len(misses)])
+					}
+					i++
+				}
+
+				latency := float64(b.Elapsed().Nanoseconds()) / float64(b.N)
+				params := map[string]any{
+					"file_count": fileCount,
+					"bloom":      bloom,
+				}
+				reportAndEmit(b, params,
+					metric("lookup_latency_ns", latency),
+					metric("index_bytes", float64(len(indexData))),
+				)
+			})
+		}
+	}
+}
+
 func BenchmarkIndexFetchHTTP(b *testing.B) {
 	const fileCount = 10000
 	const fileSize = 4 << 10
@@ -1110,6 +1135,68 @@ func BenchmarkReadDirNested(b *testing.B) {
 	reportAndEmit(b, params, metric("latency_us", latency))
 }
 
+func BenchmarkWalkDir(b *testing.B) {
+	const (
+		fanout = 2
+		depth  = 12
+	)
+
+	// Every directory holds two files and fanout subdirectories.
+	var entries []testutil.TestEntry
+	var addDir func(prefix string, level int)
+	addDir = func(prefix string, level int) {
+		for i := range 2 {
+			entries = append(entries, testutil.TestEntry{Path: fmt.Sprintf("%sf%d.txt", prefix, i), Mode: 0o644})
+		}
+		if level == depth {
+			return
+		}
+		for i := range fanout {
+			addDir(fmt.Sprintf("%sd%d/", prefix, i), level+1)
+		}
+	}
+	addDir("", 0)
+	blob, err := New(testutil.BuildTestIndex(b, entries), testutil.NewMockByteSource(nil))
+	if err != nil {
+		b.Fatal(err)
+	}
+
+	walks := []struct {
+		name string
+		walk func(fs.WalkDirFunc) error
+	}{
+		{name: "fs.WalkDir", walk: func(fn fs.WalkDirFunc) error { return fs.WalkDir(blob, ".", fn) }},
+		{name: "Blob.WalkDir", walk: func(fn fs.WalkDirFunc) error { return blob.WalkDir(".", fn) }},
+	}
+	for _, w := range walks {
+		b.Run(w.name, func(b *testing.B) {
+			visited := 0
+			b.ReportAllocs()
+			b.ResetTimer()
+			for b.Loop() {
+				visited = 0
+				err := w.walk(func(_ string, _ fs.DirEntry, err error) error {
+					visited++
+					return err
+				})
+				if err != nil {
+					b.Fatal(err)
+				}
+			}
+
+			latency := float64(b.Elapsed().Microseconds()) / float64(b.N)
+			params := map[string]any{
+				"depth":       depth,
+				"entry_count": len(entries),
+			}
+			reportAndEmit(b, params,
+				metric("latency_us", latency),
+				metric("visited", float64(visited)),
+			)
+		})
+	}
+}
+
 func BenchmarkCopyDirContiguous(b *testing.B) {
 	const (
 		fileCount = 512
@@ -1126,7 +1213,7 @@ func BenchmarkCopyDirContiguous(b *testing.B) {
 		b.Fatal(err)
 	}
 
-	entries := blob.collectPrefixEntries(prefix)
+	entries, _ := blob.collectPrefixEntries(prefix, &copyFilter{})
 	processor := batch.NewProcessor(blob.reader.Source(), blob.reader.Pool(), blob.maxFileSize, batch.WithReadConcurrency(1))
 	bytesPerOp := int64(len(entries) * fileSize)
 
@@ -1134,7 +1221,7 @@ func BenchmarkCopyDirContiguous(b *testing.B) {
 	b.ReportAllocs()
 	b.ResetTimer()
 	for b.Loop() {
-		if _, err := processor.Process(entries, discardSink{}); err != nil {
+		if _, err := processor.Process(entries, batch.NewDiscardSink()); err != nil {
 			b.Fatal(err)
 		}
 	}
@@ -1181,9 +1268,9 @@ func BenchmarkCopyDirVsIndividual(b *testing.B) {
 		{
 			name: "copydir",
 			fn: func(blob *Blob) error {
-				entries := blob.collectPrefixEntries(prefix)
+				entries, _ := blob.collectPrefixEntries(prefix, &copyFilter{})
 				processor := batch.NewProcessor(blob.reader.Source(), blob.reader.Pool(), blob.maxFileSize, batch.WithReadConcurrency(1))
-				_, err := processor.Process(entries, discardSink{})
+				_, err := processor.Process(entries, batch.NewDiscardSink())
 				return err
 			},
 		},
@@ -1705,6 +1792,69 @@ func BenchmarkRangeRequestOverhead(b *testing.B) {
 	)
 }
 
+func BenchmarkRangeCoalescing(b *testing.B) {
+	const (
+		fileCount = 64
+		fileSize  = 4 << 10
+	)
+
+	dir := b.TempDir()
+	paths := makeBenchFiles(b, dir, fileCount, fileSize, benchPatternCompressible)
+	indexData, dataData := createBenchArchive(b, dir, CompressionNone)
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.ServeContent(w, r, "data", time.Time{}, bytes.NewReader(dataData))
+	}))
+	defer server.Close()
+
+	for _, gap := range []int64{0, 4 << 10} {
+		b.Run(fmt.Sprintf("gap=%d", gap), func(b *testing.B) {
+			metrics := &httpMetrics{}
+			client := newHTTPClientWithMetrics(benchHTTPConfig{latency: 2 * time.Millisecond}, metrics)
+			src, err := blobhttp.NewSource(server.URL,
+				blobhttp.WithClient(client),
+				blobhttp.WithMaxConcurrentRequests(4),
+				blobhttp.WithCoalesceGap(gap),
+			)
+			if err != nil {
+				b.Fatal(err)
+			}
+			blob, err := New(indexData, src)
+			if err != nil {
+				b.Fatal(err)
+			}
+
+			atomic.StoreInt64(&metrics.requestCount, 0)
+			b.ReportAllocs()
+			b.ResetTimer()
+			for b.Loop() {
+				var wg sync.WaitGroup
+				errCh := make(chan error, len(paths))
+				for _, path := range paths {
+					wg.Go(func() {
+						if _, err := blob.ReadFile(path); err != nil {
+							errCh <- err
+						}
+					})
+				}
+				wg.Wait()
+				close(errCh)
+				for err := range errCh {
+					b.Fatal(err)
+				}
+			}
+
+			params := map[string]any{
+				"files":        fileCount,
+				"coalesce_gap": gap,
+			}
+			reportAndEmit(b, params,
+				metric("range_requests", float64(atomic.LoadInt64(&metrics.requestCount))/float64(b.N)),
+			)
+		})
+	}
+}
+
 func BenchmarkConcurrentReads(b *testing.B) {
 	const (
 		fileCount = 64
@@ -2271,8 +2421,17 @@ func BenchmarkCreateWithCompression(b *testing.B) {
 	makeBenchFiles(b, dir, fileCount, fileSize, benchPatternCompressible)
 	totalBytes := int64(fileCount * fileSize)
 
+	levels := []CompressionLevel{
+		CompressionLevelFastest,
+		CompressionLevelDefault,
+		CompressionLevelBetter,
+		CompressionLevelBest,
+	}
+
 	var noneTotal time.Duration
 	var zstdTotal time.Duration
+	levelTotals := make([]time.Duration, len(levels))
+	levelSizes := make([]int, len(levels))
 
 	b.ReportAllocs()
 	b.ResetTimer()
@@ -2296,6 +2455,20 @@ func BenchmarkCreateWithCompression(b *testing.B) {
 			benchSinkBytes = dataBuf.Bytes()
 		}
 		zstdTotal += time.Since(start)
+
+		for i, level := range levels {
+			start = time.Now()
+			var indexBuf, dataBuf bytes.Buffer
+			if err := Create(context.Background(), dir, &indexBuf, &dataBuf,
+				CreateWithCompression(CompressionZstd),
+				CreateWithCompressionLevel(level),
+			); err != nil {
+				b.Fatal(err)
+			}
+			benchSinkBytes = dataBuf.Bytes()
+			levelTotals[i] += time.Since(start)
+			levelSizes[i] = dataBuf.Len()
+		}
 	}
 
 	throughputNone := throughputMBs(totalBytes*int64(b.N), noneTotal)
@@ -2303,10 +2476,83 @@ func BenchmarkCreateWithCompression(b *testing.B) {
 	params := map[string]any{
 		"file_count": fileCount,
 	}
-	reportAndEmit(b, params,
+	metrics := []benchMetric{
 		metric("throughput_none", throughputNone),
 		metric("throughput_zstd", throughputZstd),
+	}
+	for i, level := range levels {
+		metrics = append(metrics,
+			metric("throughput_zstd_"+level.String(), throughputMBs(totalBytes*int64(b.N), levelTotals[i])),
+			metric("ratio_zstd_"+level.String(), float64(levelSizes[i])/float64(totalBytes)),
+		)
+	}
+	reportAndEmit(b, params, metrics...)
+}
+
+func BenchmarkCreateWithCompressionWorkers(b *testing.B) {
+	const (
+		fileCount = 512
+		fileSize  = 64 << 10
 	)
+
+	dir := b.TempDir()
+	makeBenchFiles(b, dir, fileCount, fileSize, benchPatternCompressible)
+	totalBytes := int64(fileCount * fileSize)
+
+	for _, workers := range []int{1, 2, 4, 8} {
+		b.Run(fmt.Sprintf("workers=%d", workers), func(b *testing.B) {
+			b.ReportAllocs()
+			b.SetBytes(totalBytes)
+			var total time.Duration
+			for b.Loop() {
+				start := time.Now()
+				var indexBuf, dataBuf bytes.Buffer
+				if err := Create(context.Background(), dir, &indexBuf, &dataBuf,
+					CreateWithCompression(CompressionZstd),
+					CreateWithCompressionWorkers(workers),
+				); err != nil {
+					b.Fatal(err)
+				}
+				benchSinkBytes = dataBuf.Bytes()
+				total += time.Since(start)
+			}
+			reportAndEmit(b, map[string]any{"file_count": fileCount, "workers": workers},
+				metric("throughput_zstd", throughputMBs(totalBytes*int64(b.N), total)),
+			)
+		})
+	}
+}
+
+// BenchmarkCreateFSMemory reports the peak heap growth of creating archives
+// of many small files, whose entries are spilled to disk beyond
+// entrySpillThreshold.
+func BenchmarkCreateFSMemory(b *testing.B) {
+	const fileSize = 1 << 10
+
+	for _, fileCount := range []int{10000, 100000, 1000000} {
+		b.Run(fmt.Sprintf("files=%d", fileCount), func(b *testing.B) {
+			if fileCount >= 1000000 && !benchLargeEnabled() {
+				b.Skip("BLOB_BENCH_LARGE not set")
+			}
+			tree := syntheticTree{dirs: fileCount / 1000, files: 1000, size: fileSize}
+
+			b.ReportAllocs()
+			b.SetBytes(int64(fileCount * fileSize))
+			var growth uint64
+			for b.Loop() {
+				var indexBuf bytes.Buffer
+				peak := sampleHeapPeak()
+				if err := CreateFS(context.Background(), tree, &indexBuf, io.Discard, CreateWithTempDir(b.TempDir())); err != nil {
+					b.Fatal(err)
+				}
+				growth = max(growth, peak())
+				benchSinkBytes = indexBuf.Bytes()
+			}
+			reportAndEmit(b, map[string]any{"file_count": fileCount},
+				metric("peak_heap_bytes", float64(growth)),
+			)
+		})
+	}
 }
 
 func BenchmarkScaleFileCount(b *testing.B) {
@@ -2431,6 +2677,94 @@ func BenchmarkScaleIndexMemory(b *testing.B) {
 	}
 }
 
+// BenchmarkIndexLoadFromFile compares the memory of an index read onto the
+// heap with one memory-mapped by index.LoadFromFile, after a full scan of
+// its entries. Touched pages of the mapping count toward rss_bytes but are
+// file-backed, so the kernel can reclaim them without swapping. rss_bytes
+// is only reported on Linux.
+func BenchmarkIndexLoadFromFile(b *testing.B) {
+	const (
+		fileCount = 100000
+		fileSize  = 4 << 10
+	)
+	indexPath := filepath.Join(b.TempDir(), "index.blob")
+	if err := os.WriteFile(indexPath, buildSyntheticIndex(fileCount, fileSize), 0o600); err != nil {
+		b.Fatal(err)
+	}
+
+	loaders := []struct {
+		name string
+		load func() (*index.Index, error)
+	}{
+		{"heap", func() (*index.Index, error) {
+			data, err := os.ReadFile(indexPath)
+			if err != nil {
+				return nil, err
+			}
+			return index.Load(data)
+		}},
+		{"mmap", func() (*index.Index, error) {
+			return index.LoadFromFile(indexPath)
+		}},
+	}
+	for _, loader := range loaders {
+		b.Run("load="+loader.name, func(b *testing.B) {
+			runtime.GC()
+			var before, after runtime.MemStats
+			runtime.ReadMemStats(&before)
+			rssBefore := readRSS()
+			idx, err := loader.load()
+			if err != nil {
+				b.Fatal(err)
+			}
+			for view := range idx.EntriesView() {
+				benchSinkInt += int(view.DataSize()) //nolint:gosec // benchmark sink
+			}
+			runtime.ReadMemStats(&after)
+			heapDelta := float64(after.HeapAlloc) - float64(before.HeapAlloc)
+			rssDelta := float64(readRSS()) - float64(rssBefore)
+
+			b.ReportAllocs()
+			b.ResetTimer()
+			for b.Loop() {
+				view, ok := idx.ViewAt(fileCount / 2)
+				if !ok {
+					b.Fatal("missing entry")
+				}
+				benchSinkView = view
+			}
+			runtime.KeepAlive(idx)
+
+			params := map[string]any{
+				"file_count": fileCount,
+				"load":       loader.name,
+			}
+			reportAndEmit(b, params,
+				metric("heap_bytes", heapDelta),
+				metric("rss_bytes", rssDelta),
+			)
+		})
+	}
+}
+
+// readRSS returns the resident set size of the process on Linux, and 0
+// elsewhere.
+func readRSS() int64 {
+	data, err := os.ReadFile("/proc/self/statm")
+	if err != nil {
+		return 0
+	}
+	fields := strings.Fields(string(data))
+	if len(fields) < 2 {
+		return 0
+	}
+	pages, err := strconv.ParseInt(fields[1], 10, 64)
+	if err != nil {
+		return 0
+	}
+	return pages * int64(os.Getpagesize())
+}
+
 func BenchmarkVerifyOnRead(b *testing.B) {
 	const (
 		fileCount = 64
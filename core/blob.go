@@ -2,7 +2,9 @@ package blob
 
 import (
 	"bytes"
+	"context"
 	"crypto/sha256"
+	"encoding/binary"
 	"errors"
 	"fmt"
 	"io"
@@ -10,7 +12,10 @@ import (
 	"iter"
 	"log/slog"
 	"os"
+	"path"
 	"path/filepath"
+	"slices"
+	"strings"
 
 	"golang.org/x/sync/singleflight"
 
@@ -19,6 +24,8 @@ import (
 	"github.com/meigma/blob/core/internal/blobtype"
 	"github.com/meigma/blob/core/internal/file"
 	"github.com/meigma/blob/core/internal/index"
+	"github.com/meigma/blob/core/internal/sizing"
+	"github.com/meigma/blob/core/internal/write"
 )
 
 // Re-export types from internal/blobtype for public API.
@@ -29,9 +36,20 @@ type (
 	// Compression identifies the compression algorithm used for a file.
 	Compression = blobtype.Compression
 
+	// Encryption identifies the scheme used to encrypt file content.
+	Encryption = blobtype.Encryption
+
 	// EntryView provides a read-only view of an index entry.
 	EntryView = blobtype.EntryView
 
+	// AuxChecksum identifies the non-cryptographic checksum recorded for
+	// each entry by CreateWithAuxChecksum.
+	AuxChecksum = blobtype.AuxChecksum
+
+	// Chunk describes one content-defined chunk of an entry stored with
+	// CreateWithChunking.
+	Chunk = blobtype.Chunk
+
 	// ProgressEvent represents a progress update during operations.
 	ProgressEvent = blobtype.ProgressEvent
 
@@ -41,6 +59,10 @@ type (
 	// ProgressFunc receives progress updates during operations.
 	ProgressFunc = blobtype.ProgressFunc
 
+	// IndexVersionError describes an index whose format version is outside
+	// the accepted range. It matches ErrUnsupportedIndexVersion.
+	IndexVersionError = blobtype.IndexVersionError
+
 	// File represents an archive file with optional random access.
 	// ReadAt is only supported for uncompressed entries.
 	File interface {
@@ -58,6 +80,25 @@ const (
 	CompressionZstd = blobtype.CompressionZstd
 )
 
+// Re-export encryption constants.
+const (
+	// EncryptionNone stores file content in the clear.
+	EncryptionNone = blobtype.EncryptionNone
+
+	// EncryptionAESGCM encrypts each file's content with AES-GCM using a
+	// random per-file nonce recorded in the index.
+	EncryptionAESGCM = blobtype.EncryptionAESGCM
+)
+
+// Re-export aux checksum constants.
+const (
+	// AuxChecksumNone records no auxiliary checksum.
+	AuxChecksumNone = blobtype.AuxChecksumNone
+
+	// AuxChecksumXXH64 records the 64-bit xxHash of each file's content.
+	AuxChecksumXXH64 = blobtype.AuxChecksumXXH64
+)
+
 // Re-export progress stage constants.
 const (
 	StageEnumerating      = blobtype.StageEnumerating
@@ -67,6 +108,10 @@ const (
 	StageFetchingManifest = blobtype.StageFetchingManifest
 	StageFetchingIndex    = blobtype.StageFetchingIndex
 	StageExtracting       = blobtype.StageExtracting
+	StageVerifying        = blobtype.StageVerifying
+	StageFetchingData     = blobtype.StageFetchingData
+	StageExtractingFile   = blobtype.StageExtractingFile
+	StageExtractedFile    = blobtype.StageExtractedFile
 )
 
 // Interface compliance.
@@ -75,6 +120,7 @@ var (
 	_ fs.StatFS     = (*Blob)(nil)
 	_ fs.ReadFileFS = (*Blob)(nil)
 	_ fs.ReadDirFS  = (*Blob)(nil)
+	_ fs.SubFS      = (*Blob)(nil)
 )
 
 // Sentinel errors re-exported from internal/blobtype.
@@ -85,10 +131,27 @@ var (
 	// ErrDecompression is returned when decompression fails.
 	ErrDecompression = blobtype.ErrDecompression
 
+	// ErrDecryption is returned when encrypted content cannot be decrypted
+	// because no key was configured or the key is wrong.
+	ErrDecryption = blobtype.ErrDecryption
+
 	// ErrSizeOverflow is returned when byte counts exceed supported limits.
 	ErrSizeOverflow = blobtype.ErrSizeOverflow
+
+	// ErrUnsupportedIndexVersion is returned by New when the index format
+	// version is newer than WithMaxIndexVersion allows.
+	ErrUnsupportedIndexVersion = blobtype.ErrUnsupportedIndexVersion
+
+	// ErrDecoderMemoryLimit is returned when a compressed file cannot be
+	// decoded within SetGlobalDecoderMemoryLimit because files still open
+	// hold the memory it needs.
+	ErrDecoderMemoryLimit = blobtype.ErrDecoderMemoryLimit
 )
 
+// IndexVersion is the index format version written by Create and the newest
+// version New accepts by default.
+const IndexVersion = index.CurrentVersion
+
 // Sentinel errors specific to the blob package.
 var (
 	// ErrSymlink is returned when a symlink is encountered where not allowed.
@@ -96,6 +159,54 @@ var (
 
 	// ErrTooManyFiles is returned when the file count exceeds the configured limit.
 	ErrTooManyFiles = errors.New("blob: too many files")
+
+	// ErrFileChanged is returned when a file changes while Create reads it.
+	// See CreateWithConcurrentModification.
+	ErrFileChanged = write.ErrFileChanged
+
+	// ErrCompressedRange is returned when a byte range is requested from a
+	// compressed entry, which does not support random access.
+	ErrCompressedRange = errors.New("blob: range read of compressed file")
+
+	// ErrPathConflict is returned by Create when path rewriting maps two
+	// files to the same archive path, or a file beneath another file, and by
+	// CopyWithPathMapper when two entries map to the same destination.
+	ErrPathConflict = errors.New("blob: conflicting archive paths")
+
+	// ErrCaseCollision is returned by Create with
+	// CreateWithRejectCaseCollisions when two archive paths differ only in
+	// case.
+	ErrCaseCollision = errors.New("blob: paths differ only in case")
+
+	// ErrPathLimit is returned when a path exceeds a limit set by
+	// CreateWithMaxPathLength, CreateWithMaxPathDepth, CopyWithMaxPathLength,
+	// or CopyWithMaxPathDepth. The concrete error is a *PathLimitError.
+	ErrPathLimit = errors.New("blob: path limit exceeded")
+
+	// ErrExtractionLimit is returned when an extraction exceeds a limit set
+	// by CopyWithMaxFiles or CopyWithMaxTotalBytes. The concrete error is an
+	// *ExtractionLimitError.
+	ErrExtractionLimit = errors.New("blob: extraction limit exceeded")
+
+	// ErrOverlappingEntries is returned by New with WithValidateLayout when
+	// two entries claim overlapping bytes of the data blob.
+	ErrOverlappingEntries = errors.New("blob: overlapping entries")
+
+	// ErrUnsortedEntries is returned by New with WithValidateIndex when the
+	// index paths are not in strictly increasing order.
+	ErrUnsortedEntries = errors.New("blob: index entries not sorted")
+
+	// ErrDataSizeMismatch is returned by New with WithValidateIndex when the
+	// data size recorded in the index differs from the source size.
+	ErrDataSizeMismatch = errors.New("blob: index data size does not match source")
+
+	// ErrRetryBudgetExhausted is returned by a RetryingSource once an
+	// operation has spent the budget set with RetryWithBudget.
+	ErrRetryBudgetExhausted = errors.New("blob: retry budget exhausted")
+
+	// ErrZstdDictionaryMismatch is returned by New when the dictionary set
+	// with WithZstdDictionary is not the one the index references.
+	ErrZstdDictionaryMismatch = errors.New("blob: zstd dictionary does not match index")
 )
 
 // ValidationError describes why a path failed validation.
@@ -120,7 +231,7 @@ type ByteSource interface {
 
 // Blob provides random access to archive files.
 //
-// Blob implements fs.FS, fs.StatFS, fs.ReadFileFS, and fs.ReadDirFS
+// Blob implements fs.FS, fs.StatFS, fs.ReadFileFS, fs.ReadDirFS, and fs.SubFS
 // for compatibility with the standard library.
 type Blob struct {
 	idx                   *index.Index
@@ -132,11 +243,25 @@ type Blob struct {
 	decoderConcurrency    int
 	decoderLowmemSet      bool
 	decoderLowmem         bool
+	decryptionKey         []byte
+	zstdDictionary        []byte // nil unless the index references one
+	maxIndexVersion       uint32
+	maxFiles              int // 0 = no limit
 	verifyOnClose         bool
-	cache                 cache.Cache        // nil = no caching
-	readGroup             singleflight.Group // zero value is valid
-	cacheGroup            singleflight.Group // zero value is valid
+	validateLayout        bool
+	validateIndex         bool
+	indexFromCache        bool
+	cache                 cache.Cache         // nil = no caching
+	readGroup             *singleflight.Group // shared with Subset views
+	cacheGroup            *singleflight.Group // shared with Subset views
 	logger                *slog.Logger
+	lookupIndex           entryIndex     // b.idx, or a wrapper in tests
+	missing               *negativeCache // nil = no negative caching
+	chunkBytes            uint64         // 0 = no chunked prefetch
+	chunks                *chunkIndex    // offset-ordered entries for chunked prefetch
+	hashes                *hashIndex     // hash-ordered entries for ReadByHash
+	root                  string         // subtree root for Subset views; "" = archive root
+	fold                  *foldIndex     // nil = case-sensitive lookup
 }
 
 // log returns the logger, falling back to a discard logger if nil.
@@ -152,21 +277,70 @@ func (b *Blob) log() *slog.Logger {
 // The indexData is the FlatBuffers-encoded index blob and source provides
 // access to file content. Options can be used to configure size and decoder limits.
 func New(indexData []byte, source ByteSource, opts ...Option) (*Blob, error) {
-	idx, err := index.Load(indexData)
+	b := newBlob(opts)
+	idx, err := index.Load(indexData, index.WithMaxVersion(b.maxIndexVersion))
+	if err != nil {
+		return nil, err
+	}
+	return b.init(idx, source)
+}
+
+// NewFromIndexFile creates a Blob whose index is memory-mapped from the
+// file at indexPath, such as one written by Save or CreateBlob.
+//
+// The index is read in place from the mapping rather than held on the heap,
+// which keeps memory flat for very large indexes on local disk. Options
+// apply as in New. The mapping is released once the Blob, and every value
+// derived from it, is unreachable; slices returned by IndexData alias the
+// mapping and must not outlive the Blob. The file must not be modified
+// while the Blob is in use. On platforms without memory mapping the file is
+// read into memory.
+func NewFromIndexFile(indexPath string, source ByteSource, opts ...Option) (*Blob, error) {
+	b := newBlob(opts)
+	idx, err := index.LoadFromFile(indexPath, index.WithMaxVersion(b.maxIndexVersion))
 	if err != nil {
 		return nil, err
 	}
+	return b.init(idx, source)
+}
 
+// newBlob returns a Blob with defaults and opts applied.
+func newBlob(opts []Option) *Blob {
 	b := &Blob{
-		idx:              idx,
-		indexData:        indexData,
 		maxFileSize:      file.DefaultMaxFileSize,
 		maxDecoderMemory: file.DefaultMaxDecoderMemory,
+		maxIndexVersion:  IndexVersion,
 		verifyOnClose:    true,
+		readGroup:        &singleflight.Group{},
+		cacheGroup:       &singleflight.Group{},
 	}
 	for _, opt := range opts {
 		opt(b)
 	}
+	return b
+}
+
+// init attaches the loaded index and the data source to b.
+func (b *Blob) init(idx *index.Index, source ByteSource) (*Blob, error) {
+	if b.maxFiles > 0 && idx.Len() > b.maxFiles {
+		return nil, fmt.Errorf("%w: index has %d entries, limit is %d", ErrTooManyFiles, idx.Len(), b.maxFiles)
+	}
+	b.indexData = idx.Data()
+	b.idx = idx
+	b.lookupIndex = idx
+	b.hashes = &hashIndex{}
+	if b.chunkBytes > 0 {
+		b.chunks = &chunkIndex{}
+	}
+	if b.validateIndex {
+		if err := validateIndex(idx, source.Size()); err != nil {
+			return nil, err
+		}
+	} else if b.validateLayout {
+		if err := validateLayout(idx, source.Size()); err != nil {
+			return nil, err
+		}
+	}
 	readerOpts := []file.Option{
 		file.WithMaxFileSize(b.maxFileSize),
 		file.WithMaxDecoderMemory(b.maxDecoderMemory),
@@ -177,6 +351,21 @@ func New(indexData []byte, source ByteSource, opts ...Option) (*Blob, error) {
 	if b.decoderLowmemSet {
 		readerOpts = append(readerOpts, file.WithDecoderLowmem(b.decoderLowmem))
 	}
+	if hash, ok := idx.ZstdDictionaryHash(); ok && b.zstdDictionary != nil {
+		if sum := sha256.Sum256(b.zstdDictionary); !bytes.Equal(sum[:], hash) {
+			return nil, ErrZstdDictionaryMismatch
+		}
+		readerOpts = append(readerOpts, file.WithDictionary(b.zstdDictionary))
+	} else {
+		b.zstdDictionary = nil
+	}
+	if scheme := idx.Encryption(); scheme != EncryptionNone && len(b.decryptionKey) > 0 {
+		aead, err := file.NewCipher(scheme, b.decryptionKey)
+		if err != nil {
+			return nil, fmt.Errorf("blob: decryption key: %w", err)
+		}
+		readerOpts = append(readerOpts, file.WithCipher(aead))
+	}
 	b.reader = file.NewReader(source, readerOpts...)
 	return b, nil
 }
@@ -196,7 +385,8 @@ func (b *Blob) Open(name string) (fs.File, error) {
 	}
 
 	// Check if it's a file
-	if view, ok := b.idx.LookupView(name); ok {
+	full := b.resolve(name)
+	if view, ok := b.lookup(full); ok && !view.Mode().IsDir() {
 		entry := blobtype.EntryFromViewWithPath(view, name)
 
 		// No cache - existing behavior
@@ -212,6 +402,13 @@ func (b *Blob) Open(name string) (fs.File, error) {
 
 		// Cache miss - populate then return from cache
 		b.log().Debug("file cache miss", "path", name)
+		if len(entry.Chunks) > 0 {
+			f, err := b.openChunked(context.Background(), &entry)
+			if err != nil {
+				return nil, &fs.PathError{Op: "open", Path: name, Err: err}
+			}
+			return newCachedFile(f, &entry, b.verifyOnClose, nil), nil
+		}
 		if err := b.ensureCached(&entry); err != nil {
 			return nil, &fs.PathError{Op: "open", Path: name, Err: err}
 		}
@@ -223,8 +420,8 @@ func (b *Blob) Open(name string) (fs.File, error) {
 	}
 
 	// Check if it's a directory
-	if b.isDir(name) {
-		return &openDir{b: b, name: name}, nil
+	if b.isDir(full) {
+		return &openDir{b: b, name: name, prefix: file.DirPrefix(full)}, nil
 	}
 
 	return nil, &fs.PathError{Op: "open", Path: name, Err: fs.ErrNotExist}
@@ -241,7 +438,8 @@ func (b *Blob) Stat(name string) (fs.FileInfo, error) {
 	}
 
 	// Check if it's a file
-	if view, ok := b.idx.LookupView(name); ok {
+	full := b.resolve(name)
+	if view, ok := b.lookup(full); ok {
 		entry := blobtype.EntryFromViewWithPath(view, name)
 		info, err := file.NewInfo(&entry, file.Base(name))
 		if err != nil {
@@ -251,7 +449,7 @@ func (b *Blob) Stat(name string) (fs.FileInfo, error) {
 	}
 
 	// Check if it's a directory
-	if b.isDir(name) {
+	if b.isDir(full) {
 		dirName := file.Base(name)
 		if name == "." {
 			dirName = "."
@@ -277,8 +475,8 @@ func (b *Blob) Exists(path string) bool {
 // IsDir reports whether path is a directory in the archive.
 //
 // Directories are synthesized from file paths - IsDir returns true if
-// any file exists with path as a prefix. Returns false if path does not
-// exist or is invalid.
+// any file exists with path as a prefix, or if path is an explicit
+// directory entry. Returns false if path does not exist or is invalid.
 //
 // The path is normalized before lookup, so "/etc/nginx/" and "etc/nginx"
 // are equivalent.
@@ -287,7 +485,7 @@ func (b *Blob) IsDir(path string) bool {
 	if !fs.ValidPath(path) {
 		return false
 	}
-	return b.isDir(path)
+	return b.isDir(b.resolve(path))
 }
 
 // IsFile reports whether path is a regular file in the archive.
@@ -301,7 +499,7 @@ func (b *Blob) IsFile(path string) bool {
 	if !fs.ValidPath(path) {
 		return false
 	}
-	view, ok := b.idx.LookupView(path)
+	view, ok := b.lookup(b.resolve(path))
 	if !ok {
 		return false
 	}
@@ -327,10 +525,11 @@ func (b *Blob) ValidateFiles(paths ...string) ([]string, error) {
 			return nil, &ValidationError{Path: path, Reason: "invalid path"}
 		}
 
-		view, ok := b.idx.LookupView(normalized[i])
+		full := b.resolve(normalized[i])
+		view, ok := b.lookup(full)
 		if !ok {
 			// Not a file entry - check if it's a directory
-			if b.isDir(normalized[i]) {
+			if b.isDir(full) {
 				return nil, &ValidationError{Path: path, Reason: "is a directory"}
 			}
 			return nil, &ValidationError{Path: path, Reason: "not found"}
@@ -342,6 +541,60 @@ func (b *Blob) ValidateFiles(paths ...string) ([]string, error) {
 	return normalized, nil
 }
 
+// ReadFileRange reads up to length bytes of the named file starting at off.
+//
+// The range is clamped to the file size, so reads extending past the end
+// return fewer bytes and an offset at or past the end returns an empty
+// slice. Only uncompressed files support range reads; compressed files
+// return ErrCompressedRange.
+//
+// A single range request covering only the requested bytes is issued to
+// the data source. Like partial reads through Open, the returned bytes are
+// not verified against the file hash. The cache is not consulted.
+func (b *Blob) ReadFileRange(name string, off, length int64) ([]byte, error) {
+	if !fs.ValidPath(name) || off < 0 || length < 0 {
+		return nil, &fs.PathError{Op: "readrange", Path: name, Err: fs.ErrInvalid}
+	}
+
+	view, ok := b.lookup(b.resolve(name))
+	if !ok {
+		return nil, &fs.PathError{Op: "readrange", Path: name, Err: fs.ErrNotExist}
+	}
+
+	entry := blobtype.EntryFromViewWithPath(view, name)
+	if entry.Compression != CompressionNone {
+		return nil, &fs.PathError{Op: "readrange", Path: name, Err: ErrCompressedRange}
+	}
+	return b.reader.ReadRange(&entry, off, length)
+}
+
+// OpenRange opens the named file for streaming and returns its size.
+//
+// Unlike ReadFile, the content is never buffered in memory. Uncompressed
+// files are streamed from a single range request over the entry's data
+// region when the source supports it; compressed files are decoded with a
+// streaming zstd decoder. The hash is verified as the final bytes are
+// read and again on Close, which drains any unread content first and
+// returns ErrHashMismatch if the data does not match. The cache is not
+// consulted.
+func (b *Blob) OpenRange(name string) (io.ReadCloser, int64, error) {
+	if !fs.ValidPath(name) {
+		return nil, 0, &fs.PathError{Op: "openrange", Path: name, Err: fs.ErrInvalid}
+	}
+
+	view, ok := b.lookup(b.resolve(name))
+	if !ok || view.Mode().IsDir() {
+		return nil, 0, &fs.PathError{Op: "openrange", Path: name, Err: fs.ErrNotExist}
+	}
+
+	entry := blobtype.EntryFromViewWithPath(view, name)
+	size, err := sizing.ToInt64(entry.OriginalSize, ErrSizeOverflow)
+	if err != nil {
+		return nil, 0, &fs.PathError{Op: "openrange", Path: name, Err: err}
+	}
+	return b.reader.OpenStream(&entry), size, nil
+}
+
 // ReadFile implements fs.ReadFileFS.
 //
 // ReadFile reads and returns the entire contents of the named file.
@@ -350,20 +603,33 @@ func (b *Blob) ValidateFiles(paths ...string) ([]string, error) {
 // When caching is enabled, concurrent calls for the same content are
 // deduplicated using singleflight, preventing redundant network requests.
 func (b *Blob) ReadFile(name string) ([]byte, error) {
+	return b.ReadFileContext(context.Background(), name)
+}
+
+// ReadFileContext is like ReadFile but stops when ctx is canceled.
+//
+// Reads from the data source are bound to ctx, so canceling ctx aborts
+// in-flight range requests on sources that support it, such as the HTTP
+// source. When concurrent reads of the same content are deduplicated, the
+// shared read runs under the first caller's context; later callers stop
+// waiting when their own ctx is canceled.
+func (b *Blob) ReadFileContext(ctx context.Context, name string) ([]byte, error) {
 	if !fs.ValidPath(name) {
 		return nil, &fs.PathError{Op: "readfile", Path: name, Err: fs.ErrInvalid}
 	}
 
-	view, ok := b.idx.LookupView(name)
+	view, ok := b.lookup(b.resolve(name))
 	if !ok {
 		return nil, &fs.PathError{Op: "readfile", Path: name, Err: fs.ErrNotExist}
 	}
 
 	entry := blobtype.EntryFromViewWithPath(view, name)
+	ctx, cancel := withRetryScope(ctx)
+	defer cancel()
 
 	// No cache - existing behavior
 	if b.cache == nil {
-		return b.reader.ReadAll(&entry)
+		return b.reader.ReadAllContext(ctx, &entry)
 	}
 
 	// Cache hit - read from cached file
@@ -385,15 +651,29 @@ func (b *Blob) ReadFile(name string) ([]byte, error) {
 	b.log().Debug("readfile cache miss", "path", name)
 
 	// Cache miss with singleflight
-	result, err, _ := b.readGroup.Do(string(entry.Hash), func() (any, error) {
+	ch := b.readGroup.DoChan(string(entry.Hash), func() (any, error) {
 		// Double-check cache
 		if f, ok := b.cache.Get(entry.Hash); ok {
 			defer f.Close()
 			return io.ReadAll(f)
 		}
 
+		// Chunked entries are cached chunk by chunk rather than whole.
+		if len(entry.Chunks) > 0 {
+			return b.readChunked(ctx, &entry)
+		}
+
+		// Fetch the surrounding chunk; fall back to a direct read if that
+		// fails or the cache did not keep the entry.
+		if b.chunkBytes > 0 && b.prefetchChunk(ctx, &entry) == nil {
+			if f, ok := b.cache.Get(entry.Hash); ok {
+				defer f.Close()
+				return io.ReadAll(f)
+			}
+		}
+
 		// Read into memory (we need []byte anyway)
-		content, err := b.reader.ReadAll(&entry)
+		content, err := b.reader.ReadAllContext(ctx, &entry)
 		if err != nil {
 			return nil, err
 		}
@@ -407,23 +687,32 @@ func (b *Blob) ReadFile(name string) ([]byte, error) {
 		return content, nil
 	})
 
-	if err != nil {
-		return nil, err
+	select {
+	case res := <-ch:
+		if res.Err != nil {
+			return nil, res.Err
+		}
+		return res.Val.([]byte), nil //nolint:errcheck // type assertion always succeeds when err is nil
+	case <-ctx.Done():
+		return nil, fmt.Errorf("read %s: %w", name, ctx.Err())
 	}
-	return result.([]byte), nil //nolint:errcheck // type assertion always succeeds when err is nil
 }
 
 // ReadDir implements fs.ReadDirFS.
 //
 // ReadDir returns directory entries for the named directory, sorted by name.
-// Directory entries are synthesized from file paths—the archive does not
-// store directories explicitly.
+// Subdirectories are synthesized from file paths. An index may also hold
+// explicit directory entries (entries whose mode has [fs.ModeDir] set),
+// which is how empty directories are represented. Explicit and synthesized
+// directories are merged: each name appears exactly once, and when both
+// exist the explicit entry is returned so its mode and modification time
+// are preserved.
 func (b *Blob) ReadDir(name string) ([]fs.DirEntry, error) {
 	if !fs.ValidPath(name) {
 		return nil, &fs.PathError{Op: "readdir", Path: name, Err: fs.ErrInvalid}
 	}
 
-	prefix := file.DirPrefix(name)
+	prefix := file.DirPrefix(b.resolve(name))
 	di := newDirIter(b.idx, prefix)
 	defer di.Close()
 
@@ -436,10 +725,15 @@ func (b *Blob) ReadDir(name string) ([]fs.DirEntry, error) {
 		entries = append(entries, entry)
 	}
 
-	if len(entries) == 0 && name != "." {
+	if len(entries) == 0 && name != "." && !b.isExplicitDir(b.resolve(name)) {
 		return nil, &fs.PathError{Op: "readdir", Path: name, Err: fs.ErrNotExist}
 	}
 
+	// Index order places "a.txt" before directory "a"; fs.ReadDirFS
+	// requires entries sorted by name.
+	slices.SortFunc(entries, func(x, y fs.DirEntry) int {
+		return strings.Compare(x.Name(), y.Name())
+	})
 	return entries, nil
 }
 
@@ -455,6 +749,45 @@ func (b *Blob) IndexData() []byte {
 	return b.indexData
 }
 
+// IndexFromCache reports whether the index was served from a cache rather
+// than fetched, as recorded by WithIndexFromCache. Registry clients set it
+// when a pull is satisfied by their index cache, so cache warmers can skip
+// archives whose index is already local.
+func (b *Blob) IndexFromCache() bool {
+	return b.indexFromCache
+}
+
+// CacheStats returns the counters of the cache configured with WithCache.
+// ok is false when no cache is configured or the cache does not implement
+// cache.StatReporter.
+func (b *Blob) CacheStats() (stats cache.Stats, ok bool) {
+	r, ok := b.cache.(cache.StatReporter)
+	if !ok {
+		return cache.Stats{}, false
+	}
+	return r.Stats(), true
+}
+
+// Encryption returns the scheme used to encrypt file content, or
+// EncryptionNone for unencrypted archives. See CreateWithEncryption.
+func (b *Blob) Encryption() Encryption {
+	return b.idx.Encryption()
+}
+
+// ZstdDictionary returns the zstd dictionary set with WithZstdDictionary.
+// ok is false when the archive was created without a dictionary or none
+// was supplied.
+func (b *Blob) ZstdDictionary() ([]byte, bool) {
+	return b.zstdDictionary, b.zstdDictionary != nil
+}
+
+// AuxChecksum returns the algorithm of the per-entry checksums reported by
+// EntryView.AuxChecksum, or AuxChecksumNone when the archive was created
+// without CreateWithAuxChecksum.
+func (b *Blob) AuxChecksum() AuxChecksum {
+	return b.idx.AuxChecksum()
+}
+
 // DataHash returns the hash of the data blob bytes from the index.
 // The returned slice aliases the index buffer and must be treated as immutable.
 // ok is false when the index did not record data metadata.
@@ -462,18 +795,64 @@ func (b *Blob) DataHash() ([]byte, bool) {
 	return b.idx.DataHash()
 }
 
+// ZstdDictionaryHash returns the SHA-256 of the zstd dictionary the index
+// references. The returned slice aliases the index buffer and must be
+// treated as immutable. ok is false when the archive has no dictionary.
+func (b *Blob) ZstdDictionaryHash() ([]byte, bool) {
+	return b.idx.ZstdDictionaryHash()
+}
+
 // DataSize returns the size of the data blob in bytes from the index.
 // ok is false when the index did not record data metadata.
 func (b *Blob) DataSize() (uint64, bool) {
 	return b.idx.DataSize()
 }
 
+// Checksum returns a SHA256 digest identifying the archive's content.
+//
+// The digest covers each entry's path, file type, and content hash, in
+// index order. It is a content identity, not a byte identity: archives
+// built from the same tree produce the same checksum regardless of
+// compression, modification times, permissions, or ownership, even though
+// their index and data blobs differ. For a Subset view, the checksum covers
+// the entries under the subset root with paths relative to it.
+//
+// ok is false when an entry does not record a SHA256 content hash.
+func (b *Blob) Checksum() ([]byte, bool) {
+	h := sha256.New()
+	h.Write([]byte("blob.checksum.v1\x00"))
+	var buf [binary.MaxVarintLen64]byte
+	for view := range b.Entries() {
+		hash := view.HashBytes()
+		mode := view.Mode()
+		if !mode.IsDir() && len(hash) != sha256.Size {
+			return nil, false
+		}
+		path := view.PathBytes()
+		h.Write(buf[:binary.PutUvarint(buf[:], uint64(len(path)))])
+		h.Write(path)
+		h.Write(buf[:binary.PutUvarint(buf[:], uint64(mode.Type()))])
+		h.Write(buf[:binary.PutUvarint(buf[:], uint64(len(hash)))])
+		h.Write(hash)
+	}
+	return h.Sum(nil), true
+}
+
 // Stream returns a reader that streams the entire data blob from beginning to end.
 // This is useful for copying or transmitting the complete data content.
 func (b *Blob) Stream() io.Reader {
 	return io.NewSectionReader(b.reader.Source(), 0, b.reader.Source().Size())
 }
 
+// SectionReader returns a seekable reader over the entire data blob.
+//
+// Unlike Stream, the reader implements io.Seeker and io.ReaderAt, for
+// libraries that need random access to the raw stored bytes. Each call
+// returns an independent reader positioned at the start.
+func (b *Blob) SectionReader() *io.SectionReader {
+	return NewReadSeeker(b.reader.Source())
+}
+
 // Size returns the total size of the data blob in bytes.
 func (b *Blob) Size() int64 {
 	return b.reader.Source().Size()
@@ -483,14 +862,24 @@ func (b *Blob) Size() int64 {
 //
 // The returned view is only valid while the Blob remains alive.
 func (b *Blob) Entry(path string) (EntryView, bool) {
-	return b.idx.LookupView(path)
+	if b.root == "" {
+		return b.lookup(b.canonicalPath(path))
+	}
+	view, ok := b.lookup(b.canonicalPath(b.rootPrefix() + path))
+	if !ok {
+		return EntryView{}, false
+	}
+	return blobtype.RelativeEntryView(view, len(b.rootPrefix())), true
 }
 
 // Entries returns an iterator over all entries as read-only views.
 //
 // The returned views are only valid while the Blob remains alive.
 func (b *Blob) Entries() iter.Seq[EntryView] {
-	return b.idx.EntriesView()
+	if b.root == "" {
+		return b.idx.EntriesView()
+	}
+	return b.EntriesWithPrefix("")
 }
 
 // EntriesWithPrefix returns an iterator over entries with the given prefix
@@ -498,12 +887,58 @@ func (b *Blob) Entries() iter.Seq[EntryView] {
 //
 // The returned views are only valid while the Blob remains alive.
 func (b *Blob) EntriesWithPrefix(prefix string) iter.Seq[EntryView] {
-	return b.idx.EntriesWithPrefixView(prefix)
+	if b.root == "" {
+		return b.idx.EntriesWithPrefixView(prefix)
+	}
+	rootPrefix := b.rootPrefix()
+	return func(yield func(EntryView) bool) {
+		for view := range b.idx.EntriesWithPrefixView(rootPrefix + prefix) {
+			if !yield(blobtype.RelativeEntryView(view, len(rootPrefix))) {
+				return
+			}
+		}
+	}
+}
+
+// EntriesWithSuffix returns an iterator over entries whose path ends with
+// suffix as read-only views, such as all ".wasm" files.
+//
+// The index is sorted by path rather than by suffix, so this is a full scan
+// costing O(n) in the number of entries, where EntriesWithPrefix finds its
+// first entry in O(log n). The scan compares path bytes in place, so callers
+// need not collect entries into a slice or convert every path to a string.
+//
+// The returned views are only valid while the Blob remains alive.
+func (b *Blob) EntriesWithSuffix(suffix string) iter.Seq[EntryView] {
+	if b.root == "" {
+		return b.idx.EntriesWithSuffixView(suffix)
+	}
+	rootPrefix := b.rootPrefix()
+	suffixBytes := []byte(suffix)
+	return func(yield func(EntryView) bool) {
+		for view := range b.idx.EntriesWithPrefixView(rootPrefix) {
+			if !bytes.HasSuffix(view.PathBytes()[len(rootPrefix):], suffixBytes) {
+				continue
+			}
+			if !yield(blobtype.RelativeEntryView(view, len(rootPrefix))) {
+				return
+			}
+		}
+	}
 }
 
 // Len returns the number of entries in the archive.
+//
+// For a Subset view, Len counts the entries under the subset root.
 func (b *Blob) Len() int {
-	return b.idx.Len()
+	if b.root == "" {
+		return b.idx.Len()
+	}
+	n := 0
+	for range b.idx.EntriesWithPrefixView(b.rootPrefix()) {
+		n++
+	}
+	return n
 }
 
 // DirStats returns statistics for all files under prefix.
@@ -524,8 +959,9 @@ func (b *Blob) DirStats(prefix string) DirStats {
 	var stats DirStats
 
 	// Check for exact file match (prefix is a file path, not a directory)
-	if prefix != "." {
-		if view, ok := b.idx.LookupView(prefix); ok && view.Mode().IsRegular() {
+	full := b.resolve(prefix)
+	if full != "." {
+		if view, ok := b.lookup(full); ok && view.Mode().IsRegular() {
 			stats.FileCount = 1
 			stats.TotalBytes = view.OriginalSize()
 			stats.CompressedBytes = view.DataSize()
@@ -534,7 +970,7 @@ func (b *Blob) DirStats(prefix string) DirStats {
 	}
 
 	// Scan directory children
-	dirPrefix := file.DirPrefix(prefix)
+	dirPrefix := file.DirPrefix(full)
 	for view := range b.idx.EntriesWithPrefixView(dirPrefix) {
 		if view.Mode().IsRegular() {
 			stats.FileCount++
@@ -545,6 +981,44 @@ func (b *Blob) DirStats(prefix string) DirStats {
 	return stats
 }
 
+// AllDirs returns every directory path in the archive, sorted.
+//
+// The result includes each directory implied by file paths, including
+// intermediate ones, and explicit directory entries such as empty
+// directories. Each path appears once and the archive root is not
+// included. For a Subset view, paths are relative to the subset root.
+//
+// AllDirs makes a single pass over the index. Because entries are sorted
+// by path, the entries under a directory are contiguous, so only the chain
+// of directories containing the previous entry needs to be tracked.
+func (b *Blob) AllDirs() []string {
+	var dirs, open []string
+	for view := range b.Entries() {
+		p := view.Path()
+		for len(open) > 0 && !strings.HasPrefix(p, open[len(open)-1]+"/") {
+			open = open[:len(open)-1]
+		}
+		start := 0
+		if len(open) > 0 {
+			start = len(open[len(open)-1]) + 1
+		}
+		for i := start; i < len(p); i++ {
+			if p[i] == '/' {
+				open = append(open, p[:i])
+				dirs = append(dirs, p[:i])
+			}
+		}
+		if view.Mode().IsDir() {
+			open = append(open, p)
+			dirs = append(dirs, p)
+		}
+	}
+	// An explicit directory entry is separated from its children by
+	// siblings such as "a.txt" between "a" and "a/b", so it can be seen twice.
+	slices.Sort(dirs)
+	return slices.Compact(dirs)
+}
+
 // CopyTo extracts specific files to a destination directory.
 //
 // Parent directories are created as needed.
@@ -554,16 +1028,20 @@ func (b *Blob) DirStats(prefix string) DirStats {
 //   - File modes and times are not preserved (use CopyWithPreserveMode/Times)
 //   - Range reads are pipelined (when beneficial) with concurrency 4 (use CopyWithReadConcurrency to change)
 func (b *Blob) CopyTo(destDir string, paths ...string) (CopyStats, error) {
-	if len(paths) == 0 {
-		return CopyStats{}, nil
-	}
-
-	cfg := copyConfig{}
-	return b.copyEntries(destDir, b.collectPathEntries(paths), &cfg)
+	return b.CopyToContext(context.Background(), destDir, paths)
 }
 
 // CopyToWithOptions extracts specific files with options.
 func (b *Blob) CopyToWithOptions(destDir string, paths []string, opts ...CopyOption) (CopyStats, error) {
+	return b.CopyToContext(context.Background(), destDir, paths, opts...)
+}
+
+// CopyToContext is like CopyToWithOptions but stops when ctx is canceled.
+//
+// Cancellation is checked between entries and aborts in-flight range reads
+// on sources that support it. Files written before cancellation are kept
+// and counted in the returned stats; the error wraps ctx.Err().
+func (b *Blob) CopyToContext(ctx context.Context, destDir string, paths []string, opts ...CopyOption) (CopyStats, error) {
 	if len(paths) == 0 {
 		return CopyStats{}, nil
 	}
@@ -575,7 +1053,10 @@ func (b *Blob) CopyToWithOptions(destDir string, paths []string, opts ...CopyOpt
 	if cfg.cleanDest {
 		return CopyStats{}, errors.New("CopyWithCleanDest is only supported by CopyDir")
 	}
-	return b.copyEntries(destDir, b.collectPathEntries(paths), &cfg)
+	if cfg.filter.active() {
+		return CopyStats{}, errors.New("CopyWithInclude and CopyWithExclude are only supported by CopyDir")
+	}
+	return b.copyEntries(ctx, destDir, b.collectPathEntries(paths), &cfg)
 }
 
 // CopyDir extracts all files under a directory prefix to a destination.
@@ -592,11 +1073,32 @@ func (b *Blob) CopyToWithOptions(destDir string, paths []string, opts ...CopyOpt
 //   - Existing files are skipped (use CopyWithOverwrite to overwrite)
 //   - File modes and times are not preserved (use CopyWithPreserveMode/Times)
 //   - Range reads are pipelined (when beneficial) with concurrency 4 (use CopyWithReadConcurrency to change)
+//
+// The returned CopyStats count the files written, their total size, the
+// files skipped because they already existed, and the entries excluded by
+// CopyWithInclude or CopyWithExclude. Stats are returned alongside any
+// error and reflect the files processed before it. Explicit directory
+// entries are created in the destination but are not counted as files.
 func (b *Blob) CopyDir(destDir, prefix string, opts ...CopyOption) (CopyStats, error) {
+	return b.CopyDirContext(context.Background(), destDir, prefix, opts...)
+}
+
+// CopyDirContext is like CopyDir but stops when ctx is canceled.
+//
+// Cancellation is checked between entries and aborts in-flight range reads
+// on sources that support it. Files written before cancellation are kept
+// and counted in the returned stats; the error wraps ctx.Err().
+func (b *Blob) CopyDirContext(ctx context.Context, destDir, prefix string, opts ...CopyOption) (CopyStats, error) {
 	cfg := copyConfig{}
 	for _, opt := range opts {
 		opt(&cfg)
 	}
+	if err := cfg.filter.validate(); err != nil {
+		return CopyStats{}, err
+	}
+	if cfg.cleanDest && cfg.pathMapper != nil {
+		return CopyStats{}, errors.New("CopyWithCleanDest cannot be combined with CopyWithPathMapper")
+	}
 	if cfg.cleanDest {
 		target, err := cleanCopyDest(destDir, prefix)
 		if err != nil {
@@ -607,7 +1109,10 @@ func (b *Blob) CopyDir(destDir, prefix string, opts ...CopyOption) (CopyStats, e
 		}
 		cfg.overwrite = true
 	}
-	return b.copyEntries(destDir, b.collectPrefixEntries(prefix), &cfg)
+	entries, filtered := b.collectPrefixEntries(prefix, &cfg.filter)
+	stats, err := b.copyEntries(ctx, destDir, entries, &cfg)
+	stats.Filtered += filtered
+	return stats, err
 }
 
 // CopyFile extracts a single file to a specific destination path.
@@ -633,6 +1138,12 @@ func (b *Blob) CopyFile(srcPath, destPath string, opts ...CopyOption) (CopyStats
 	if cfg.cleanDest {
 		return CopyStats{}, errors.New("CopyWithCleanDest is only supported by CopyDir")
 	}
+	if cfg.filter.active() {
+		return CopyStats{}, errors.New("CopyWithInclude and CopyWithExclude are only supported by CopyDir")
+	}
+	if cfg.pathMapper != nil {
+		return CopyStats{}, errors.New("CopyWithPathMapper is not supported by CopyFile")
+	}
 
 	// Normalize and validate source path
 	srcPath = NormalizePath(srcPath)
@@ -641,7 +1152,7 @@ func (b *Blob) CopyFile(srcPath, destPath string, opts ...CopyOption) (CopyStats
 	}
 
 	// Look up entry, verify it's a file
-	view, ok := b.idx.LookupView(srcPath)
+	view, ok := b.lookup(b.resolve(srcPath))
 	if !ok {
 		return CopyStats{}, &fs.PathError{Op: "copyfile", Path: srcPath, Err: fs.ErrNotExist}
 	}
@@ -649,6 +1160,15 @@ func (b *Blob) CopyFile(srcPath, destPath string, opts ...CopyOption) (CopyStats
 	if entry.Mode.IsDir() {
 		return CopyStats{}, &fs.PathError{Op: "copyfile", Path: srcPath, Err: errors.New("cannot copy directory")}
 	}
+	if entry.Mode&fs.ModeSymlink != 0 {
+		return b.copySymlinkFile(srcPath, destPath, &entry, &cfg)
+	}
+
+	if budget := newExtractionBudget(&cfg); budget != nil {
+		if err := budget.reserve(&entry); err != nil {
+			return CopyStats{}, err
+		}
+	}
 
 	// Check destination (unless overwrite)
 	if !cfg.overwrite {
@@ -673,6 +1193,39 @@ func (b *Blob) CopyFile(srcPath, destPath string, opts ...CopyOption) (CopyStats
 	}, nil
 }
 
+// copySymlinkFile recreates a symlink entry at destPath for CopyFile.
+//
+// destPath need not mirror the entry's place in the archive, so the target
+// is validated as if the link were at the root of its own directory: it may
+// only point at or below the directory that holds it.
+func (b *Blob) copySymlinkFile(srcPath, destPath string, entry *blobtype.Entry, cfg *copyConfig) (CopyStats, error) {
+	if !cfg.symlinks {
+		return CopyStats{}, &fs.PathError{Op: "copyfile", Path: srcPath, Err: ErrSymlink}
+	}
+	target, err := b.readSymlinkTarget(entry, path.Base(entry.Path))
+	if err != nil {
+		return CopyStats{}, err
+	}
+	if budget := newExtractionBudget(cfg); budget != nil {
+		if err := budget.reserve(entry); err != nil {
+			return CopyStats{}, err
+		}
+	}
+	if info, err := os.Lstat(destPath); err == nil {
+		if !cfg.overwrite {
+			return CopyStats{}, &fs.PathError{Op: "copyfile", Path: destPath, Err: fs.ErrExist}
+		}
+		if info.IsDir() {
+			return CopyStats{}, &fs.PathError{Op: "copyfile", Path: destPath, Err: errors.New("is a directory")}
+		}
+		_ = os.Remove(destPath) // ignore error; Symlink will fail if removal was needed but failed
+	}
+	if err := os.Symlink(target, destPath); err != nil {
+		return CopyStats{}, fmt.Errorf("create symlink: %w", err)
+	}
+	return CopyStats{FileCount: 1, TotalBytes: entry.OriginalSize}, nil
+}
+
 // copyFileAtomic writes content from src to destPath atomically using a temp file.
 func copyFileAtomic(src io.Reader, destPath string, entry *blobtype.Entry, cfg *copyConfig) error {
 	dir := filepath.Dir(destPath)
@@ -741,7 +1294,7 @@ func (b *Blob) collectPathEntries(paths []string) []*batch.Entry {
 		if !fs.ValidPath(path) {
 			continue
 		}
-		view, ok := b.idx.LookupView(path)
+		view, ok := b.lookup(b.resolve(path))
 		if !ok {
 			continue
 		}
@@ -751,37 +1304,96 @@ func (b *Blob) collectPathEntries(paths []string) []*batch.Entry {
 	return entries
 }
 
-// collectPrefixEntries collects all entries under a prefix.
-func (b *Blob) collectPrefixEntries(prefix string) []*batch.Entry {
+// collectPrefixEntries collects all entries under a prefix that pass filter.
+// Returns the entries and the number rejected by filter.
+func (b *Blob) collectPrefixEntries(prefix string, filter *copyFilter) ([]*batch.Entry, int) {
 	if prefix != "" && prefix != "." && !fs.ValidPath(prefix) {
-		return nil
+		return nil, 0
 	}
 
-	var dirPrefix string
-	if prefix == "" || prefix == "." {
-		dirPrefix = ""
-	} else {
-		dirPrefix = file.DirPrefix(prefix)
+	if prefix == "" {
+		prefix = "."
 	}
+	dirPrefix := file.DirPrefix(b.resolve(prefix))
+	trim := len(b.rootPrefix())
 
 	var entries []*batch.Entry //nolint:prealloc // size unknown until iteration
+	filtered := 0
 	for view := range b.idx.EntriesWithPrefixView(dirPrefix) {
-		entry := blobtype.EntryFromViewWithPath(view, view.Path())
+		if filter.active() && !filter.match(string(view.PathBytes()[len(dirPrefix):])) {
+			filtered++
+			continue
+		}
+		entry := blobtype.EntryFromViewWithPath(view, string(view.PathBytes()[trim:]))
 		entries = append(entries, &entry)
 	}
-	return entries
+	return entries, filtered
 }
 
 // copyEntries uses the batch processor to copy entries to destDir.
-func (b *Blob) copyEntries(destDir string, entries []*batch.Entry, cfg *copyConfig) (CopyStats, error) {
+func (b *Blob) copyEntries(ctx context.Context, destDir string, entries []*batch.Entry, cfg *copyConfig) (CopyStats, error) {
 	if len(entries) == 0 {
 		return CopyStats{}, nil
 	}
+	ctx, cancel := withRetryScope(ctx)
+	defer cancel()
 	for _, entry := range entries {
 		if !fs.ValidPath(entry.Path) {
 			return CopyStats{}, &fs.PathError{Op: "copy", Path: entry.Path, Err: fs.ErrInvalid}
 		}
 	}
+	var dropped int
+	if cfg.pathMapper != nil {
+		var err error
+		entries, dropped, err = mapEntries(entries, cfg.pathMapper)
+		if err != nil {
+			return CopyStats{}, err
+		}
+		if len(entries) == 0 {
+			return CopyStats{Filtered: dropped}, nil
+		}
+	}
+	for _, entry := range entries {
+		if err := cfg.pathLimits.check(entry.Path); err != nil {
+			return CopyStats{}, err
+		}
+	}
+
+	// Explicit directory entries carry no content; they are created after
+	// the files are written and are not counted in the stats. Symlinks are
+	// also created after the files, so no file is written through one.
+	entries, dirs, links := splitEntries(entries)
+	linksSkipped := 0
+	if !cfg.symlinks {
+		linksSkipped = len(links)
+		links = nil
+	}
+
+	// Files completed by an earlier run count against the limits, so a
+	// resumed extraction cannot exceed them in total.
+	budget := newExtractionBudget(cfg)
+	var tracker *progressTracker
+	resumed := 0
+	if cfg.progressStore != nil {
+		var err error
+		tracker, err = loadProgressTracker(cfg.progressStore)
+		if err != nil {
+			return CopyStats{}, err
+		}
+		var done []*batch.Entry
+		entries, done = tracker.filter(entries)
+		resumed = len(done)
+		if budget != nil {
+			for _, entry := range done {
+				if err := budget.reserve(entry); err != nil {
+					return CopyStats{}, err
+				}
+			}
+		}
+		if resumed > 0 {
+			b.log().Debug("resuming extraction", "completed", resumed, "remaining", len(entries))
+		}
+	}
 
 	// Create file sink with options
 	sinkOpts := []batch.FileSinkOption{
@@ -792,7 +1404,13 @@ func (b *Blob) copyEntries(destDir string, entries []*batch.Entry, cfg *copyConf
 	if cfg.cleanDest {
 		sinkOpts = append(sinkOpts, batch.WithDirectWrites(true))
 	}
-	sink := batch.NewFileSink(destDir, sinkOpts...)
+	var sink batch.Sink = batch.NewFileSink(destDir, sinkOpts...)
+	if tracker != nil {
+		sink = &trackingSink{Sink: sink, tracker: tracker}
+	}
+	if budget != nil {
+		sink = &limitSink{Sink: sink, budget: budget}
+	}
 
 	// Create processor with options
 	var procOpts []batch.ProcessorOption
@@ -809,20 +1427,88 @@ func (b *Blob) copyEntries(destDir string, entries []*batch.Entry, cfg *copyConf
 	if cfg.readAheadBytesSet {
 		procOpts = append(procOpts, batch.WithReadAheadBytes(cfg.readAheadBytes))
 	}
+	if cfg.maxBytesPerSecond > 0 {
+		procOpts = append(procOpts, batch.WithMaxBytesPerSecond(cfg.maxBytesPerSecond))
+	}
 	if cfg.progress != nil {
 		procOpts = append(procOpts, batch.WithProcessorProgress(cfg.progress))
+		if cfg.perFileProgress {
+			procOpts = append(procOpts, batch.WithPerFileProgress(true))
+		}
+		if cfg.orderedProgress {
+			procOpts = append(procOpts, batch.WithOrderedProgress(true))
+		}
 	}
 	if b.logger != nil {
 		procOpts = append(procOpts, batch.WithProcessorLogger(b.logger))
 	}
+	if aead := b.reader.Cipher(); aead != nil {
+		procOpts = append(procOpts, batch.WithCipher(aead))
+	}
 	proc := batch.NewProcessor(b.reader.Source(), b.reader.Pool(), b.maxFileSize, procOpts...)
 
-	procStats, err := proc.Process(entries, sink)
-	return CopyStats{
+	procStats, err := proc.ProcessContext(ctx, entries, sink)
+	if tracker != nil {
+		if flushErr := tracker.flush(); err == nil {
+			err = flushErr
+		}
+	}
+	stats := CopyStats{
 		FileCount:  procStats.Processed,
 		TotalBytes: procStats.TotalBytes,
-		Skipped:    procStats.Skipped,
-	}, err
+		Skipped:    procStats.Skipped + resumed + linksSkipped,
+		Filtered:   dropped,
+	}
+	if err == nil {
+		err = ctx.Err()
+	}
+	if err != nil {
+		return stats, err
+	}
+	linkStats, err := b.createSymlinkEntries(destDir, links, cfg, budget)
+	stats.FileCount += linkStats.FileCount
+	stats.TotalBytes += linkStats.TotalBytes
+	stats.Skipped += linkStats.Skipped
+	if err != nil {
+		return stats, err
+	}
+	return stats, createDirEntries(destDir, dirs)
+}
+
+// splitEntries separates explicit directory and symlink entries from file
+// entries.
+func splitEntries(entries []*batch.Entry) (files, dirs, links []*batch.Entry) {
+	files = entries[:0:0]
+	for _, entry := range entries {
+		switch {
+		case entry.Mode.IsDir():
+			dirs = append(dirs, entry)
+		case entry.Mode&fs.ModeSymlink != 0:
+			links = append(links, entry)
+		default:
+			files = append(files, entry)
+		}
+	}
+	return files, dirs, links
+}
+
+// createDirEntries creates the directories for explicit directory entries
+// under destDir.
+func createDirEntries(destDir string, dirs []*batch.Entry) error {
+	if len(dirs) == 0 {
+		return nil
+	}
+	root, err := os.OpenRoot(destDir)
+	if err != nil {
+		return err
+	}
+	defer root.Close()
+	for _, dir := range dirs {
+		if err := root.MkdirAll(filepath.FromSlash(dir.Path), 0o750); err != nil {
+			return fmt.Errorf("create directory %s: %w", dir.Path, err)
+		}
+	}
+	return nil
 }
 
 func cleanCopyDest(destDir, prefix string) (string, error) {
@@ -852,13 +1538,14 @@ func cleanCopyDest(destDir, prefix string) (string, error) {
 	return target, nil
 }
 
-// openDir implements fs.File and fs.ReadDirFile for synthetic directories.
-// Archive directories are synthesized from file paths since the format
-// does not store directories explicitly.
+// openDir implements fs.File and fs.ReadDirFile for directories.
+// Archive directories are synthesized from file paths; explicit directory
+// entries are only needed to represent empty directories.
 type openDir struct {
-	b    *Blob
-	name string
-	iter *dirIter
+	b      *Blob
+	name   string
+	prefix string // archive path prefix of the directory's children
+	iter   *dirIter
 }
 
 func (d *openDir) Read(_ []byte) (int, error) {
@@ -885,7 +1572,7 @@ func (d *openDir) Close() error {
 
 func (d *openDir) ReadDir(n int) ([]fs.DirEntry, error) {
 	if d.iter == nil {
-		d.iter = newDirIter(d.b.idx, file.DirPrefix(d.name))
+		d.iter = newDirIter(d.b.idx, d.prefix)
 	}
 
 	if n <= 0 {
@@ -917,26 +1604,45 @@ func (d *openDir) readAll() ([]fs.DirEntry, error) {
 	}
 }
 
-// isDir checks if name is a directory (has entries under it).
+// isDir checks if name is a directory (has entries under it or is an
+// explicit directory entry). The name is an archive path as returned by resolve.
 func (b *Blob) isDir(name string) bool {
 	if name == "." {
 		return b.idx.Len() > 0
 	}
+	if b.isExplicitDir(name) {
+		return true
+	}
+	if notDir, _ := b.missing.get(name); notDir {
+		return false
+	}
 	prefix := name + "/"
-	for range b.idx.EntriesWithPrefixView(prefix) {
+	for range b.lookupIndex.EntriesWithPrefixView(prefix) {
 		return true
 	}
+	b.missing.add(name, true)
 	return false
 }
 
+// isExplicitDir checks if name is an explicit directory entry in the index.
+func (b *Blob) isExplicitDir(name string) bool {
+	view, ok := b.lookup(name)
+	return ok && view.Mode().IsDir()
+}
+
 // dirIter iterates over directory entries, synthesizing subdirectories.
 // It deduplicates entries that share a common directory component and
 // yields synthetic directory entries for nested paths.
+//
+// Explicit directory entries sort before the files beneath them, so a
+// synthesized directory whose name was already returned as an explicit
+// entry is skipped even when other entries fall between the two.
 type dirIter struct {
 	next     func() (EntryView, bool)
 	stop     func()
 	prefix   string
 	lastName string
+	explicit map[string]struct{}
 	done     bool
 }
 
@@ -971,8 +1677,17 @@ func (it *dirIter) Next() (fs.DirEntry, bool) {
 		it.lastName = childName
 
 		if isSubDir {
+			if _, ok := it.explicit[childName]; ok {
+				continue
+			}
 			return file.NewDirEntry(file.NewDirInfo(childName), nil), true
 		}
+		if view.Mode().IsDir() {
+			if it.explicit == nil {
+				it.explicit = make(map[string]struct{})
+			}
+			it.explicit[childName] = struct{}{}
+		}
 		entry := blobtype.EntryFromViewWithPath(view, path)
 		info, err := file.NewInfo(&entry, childName)
 		if err != nil {
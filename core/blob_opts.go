@@ -45,6 +45,70 @@ func WithDecoderLowmem(enabled bool) Option {
 	}
 }
 
+// WithDecryptionKey sets the key used to decrypt archives created with
+// CreateWithEncryption. It is ignored for unencrypted archives.
+//
+// Without a key, an encrypted archive can still be listed, but reading file
+// content fails with ErrDecryption; a wrong key fails the same way. New
+// returns an error if the key length is not valid for the archive's scheme.
+// Each encrypted file is read into memory in full and authenticated before
+// any of its content is returned.
+func WithDecryptionKey(key []byte) Option {
+	return func(b *Blob) {
+		b.decryptionKey = key
+	}
+}
+
+// WithZstdDictionary sets the zstd dictionary for archives created with
+// CreateWithZstdDictionary. It is ignored for archives without one.
+//
+// The dictionary is its own blob: the index records only its SHA-256, and
+// New returns ErrZstdDictionaryMismatch if dict does not match. Without a
+// dictionary such an archive can still be listed, but reading compressed
+// file content fails with ErrDecompression.
+func WithZstdDictionary(dict []byte) Option {
+	return func(b *Blob) {
+		b.zstdDictionary = dict
+	}
+}
+
+// WithMaxIndexVersion sets the newest index format version New accepts
+// (default: IndexVersion). Indexes with a newer version are rejected with an
+// *IndexVersionError matching ErrUnsupportedIndexVersion.
+//
+// Raising the limit lets this version of the library read archives written
+// by a newer one when the format change is additive: fields it does not know
+// are ignored, and everything it does know is read as usual. Only raise it
+// for versions documented as backward compatible.
+func WithMaxIndexVersion(v uint32) Option {
+	return func(b *Blob) {
+		b.maxIndexVersion = v
+	}
+}
+
+// WithMaxFiles limits the number of entries New accepts in an index.
+// Indexes with more than n entries are rejected with ErrTooManyFiles before
+// any per-entry work is done.
+//
+// An index is untrusted input when archives come from outside: a crafted or
+// runaway archive can list millions of entries and inflate the memory and
+// CPU spent by listing, walking, and extraction. Servers that ingest such
+// archives should set a limit. Zero or negative disables the limit (the
+// default). CreateWithMaxFiles applies the same kind of limit when building.
+func WithMaxFiles(n int) Option {
+	return func(b *Blob) {
+		b.maxFiles = n
+	}
+}
+
+// WithIndexFromCache records whether the index data passed to New was served
+// from a cache. It is informational only and is reported by IndexFromCache.
+func WithIndexFromCache(fromCache bool) Option {
+	return func(b *Blob) {
+		b.indexFromCache = fromCache
+	}
+}
+
 // WithVerifyOnClose controls whether Close drains the file to verify the hash.
 //
 // When false, Close returns without reading the remaining data. Integrity is
@@ -55,6 +119,38 @@ func WithVerifyOnClose(enabled bool) Option {
 	}
 }
 
+// WithValidateLayout controls whether New checks the index layout (default: false).
+//
+// When enabled, New verifies that every entry lies within the data blob and
+// that no two entries share bytes, returning ErrOverlappingEntries naming the
+// conflicting paths. This guards against corrupted or crafted indexes that
+// would serve one file's bytes for another. The check is a single pass over
+// the index for archives produced by Create.
+func WithValidateLayout(enabled bool) Option {
+	return func(b *Blob) {
+		b.validateLayout = enabled
+	}
+}
+
+// WithValidateIndex controls whether New checks the index against the data
+// source (default: false).
+//
+// When enabled, New fails on the first violation of:
+//   - the data size recorded in the index equals the source size
+//     (ErrDataSizeMismatch); indexes that record none skip this check
+//   - entry paths are sorted and unique (ErrUnsortedEntries)
+//   - every entry lies within the source (ErrSizeOverflow)
+//   - no two entries share bytes (ErrOverlappingEntries), except entries
+//     with the same hash stored at the same range, as a deduplicating writer
+//     produces
+//
+// The error names the offending paths. This subsumes WithValidateLayout.
+func WithValidateIndex(enabled bool) Option {
+	return func(b *Blob) {
+		b.validateIndex = enabled
+	}
+}
+
 // WithCache enables content-addressed caching.
 //
 // When enabled, file content is cached after first read and served from cache
@@ -65,6 +161,60 @@ func WithCache(c cache.Cache) Option {
 	}
 }
 
+// WithChunkedPrefetch makes cache misses fetch a whole chunk of the data
+// blob. When a file that is not cached is read through Open or ReadFile,
+// every other entry whose data lies entirely within the same
+// chunkBytes-aligned window is read along with it and cached, so later
+// reads of neighboring files are served from the cache. Adjacent entries
+// are fetched with a single range read, and a file larger than a chunk is
+// read on its own.
+//
+// This suits archives of many small files served over high-latency
+// sources such as HTTP. It has no effect without WithCache. Zero (the
+// default) disables chunked prefetching.
+func WithChunkedPrefetch(chunkBytes uint64) Option {
+	return func(b *Blob) {
+		b.chunkBytes = chunkBytes
+	}
+}
+
+// WithNegativeCache remembers up to maxEntries paths that were looked up
+// and not found, so repeated lookups of the same missing path (for example
+// an optional file polled by a service) skip the index search. Paths are
+// evicted oldest first once the limit is reached. Values <= 0 disable the
+// cache (the default).
+//
+// A Blob's index never changes, so entries stay valid for its lifetime;
+// a Blob created for a new archive version starts with an empty cache.
+// Subset views share the cache of the Blob they were created from.
+func WithNegativeCache(maxEntries int) Option {
+	return func(b *Blob) {
+		b.missing = newNegativeCache(maxEntries)
+	}
+}
+
+// WithCaseInsensitiveLookup makes path lookups ignore case, as on Windows
+// and macOS file systems. Open, Stat, Entry, ReadDir, and the other methods
+// that take a path match it against archive paths using Unicode simple case
+// folding; an exact match is always preferred, and otherwise the first
+// archive path in index order that differs only in case is used. Paths
+// reported back, such as directory entry names, keep their archived case.
+//
+// Directories that differ only in case are not merged: listing either one
+// shows only the entries stored under it. Archives created with
+// CreateWithRejectCaseCollisions have no such paths.
+//
+// The folded path table is built on the first lookup that does not match
+// exactly and holds every file and directory path of the archive.
+func WithCaseInsensitiveLookup(enabled bool) Option {
+	return func(b *Blob) {
+		b.fold = nil
+		if enabled {
+			b.fold = &foldIndex{}
+		}
+	}
+}
+
 // WithLogger sets the logger for blob operations.
 // If not set, logging is disabled.
 func WithLogger(logger *slog.Logger) Option {
@@ -89,8 +239,18 @@ type copyConfig struct {
 	readConcurrencySet bool
 	readAheadBytes     uint64
 	readAheadBytesSet  bool
+	maxBytesPerSecond  int64
 	cleanDest          bool
 	progress           ProgressFunc
+	perFileProgress    bool
+	orderedProgress    bool
+	progressStore      ProgressStore
+	filter             copyFilter
+	maxFiles           int
+	maxTotalBytes      uint64
+	pathLimits         pathLimits
+	symlinks           bool
+	pathMapper         PathMapper
 }
 
 // CopyWithOverwrite allows overwriting existing files.
@@ -155,6 +315,17 @@ func CopyWithReadAheadBytes(limit uint64) CopyOption {
 	}
 }
 
+// CopyWithMaxBytesPerSecond limits the rate at which archive data is read
+// during extraction to bps bytes per second, in total across all concurrent
+// reads, so a background copy does not saturate a shared link. Reads are
+// split into smaller ranges to keep the rate even. Zero or negative
+// disables the limit.
+func CopyWithMaxBytesPerSecond(bps int64) CopyOption {
+	return func(c *copyConfig) {
+		c.maxBytesPerSecond = bps
+	}
+}
+
 // CopyWithProgress sets a callback to receive progress updates during extraction.
 // The callback receives events for each file extracted.
 // The callback may be invoked concurrently and must be safe for concurrent use.
@@ -164,6 +335,148 @@ func CopyWithProgress(fn ProgressFunc) CopyOption {
 	}
 }
 
+// CopyWithPerFileProgress adds per-file events to the CopyWithProgress
+// callback: a StageExtractingFile event when a file starts extracting, with
+// BytesTotal set to its size, and a StageExtractedFile event when it is
+// written. Both carry the file's path and the running FilesDone and
+// FilesTotal counts. The StageExtracting event for each file is still sent
+// after its StageExtractedFile event. With concurrent workers, events for
+// different files interleave.
+func CopyWithPerFileProgress(enabled bool) CopyOption {
+	return func(c *copyConfig) {
+		c.perFileProgress = enabled
+	}
+}
+
+// CopyWithOrderedProgress delivers CopyWithProgress events in sorted path
+// order, so logs read as if files were extracted one at a time even with
+// concurrent workers. A file's events are held back until every file
+// before it has been written; extraction itself stays parallel. With
+// CopyWithPerFileProgress, a file's StageExtractingFile event is sent
+// immediately before its completion events.
+func CopyWithOrderedProgress(enabled bool) CopyOption {
+	return func(c *copyConfig) {
+		c.orderedProgress = enabled
+	}
+}
+
+// CopyWithProgressStore persists extraction progress to store so that an
+// interrupted extraction can be resumed, possibly by another process.
+//
+// Before copying, previously completed paths are loaded from the store and
+// skipped without reading their data; they are counted in CopyStats.Skipped
+// and against CopyWithMaxFiles and CopyWithMaxTotalBytes. Progress is saved
+// after every 64 committed files and when the extraction ends.
+//
+// Completed paths are matched against destination-relative entry paths, so
+// a store should only be reused for the same archive and destination.
+func CopyWithProgressStore(store ProgressStore) CopyOption {
+	return func(c *copyConfig) {
+		c.progressStore = store
+	}
+}
+
+// CopyWithInclude restricts CopyDir to entries matching any of patterns.
+//
+// Patterns use path.Match syntax and are evaluated against each entry's path
+// relative to the CopyDir prefix. A pattern without a slash matches the base
+// name, so "*.so" selects shared libraries at any depth. Filtered entries are
+// never read and are counted in CopyStats.Filtered. This is only supported
+// by CopyDir.
+func CopyWithInclude(patterns ...string) CopyOption {
+	return func(c *copyConfig) {
+		c.filter.include = append(c.filter.include, patterns...)
+	}
+}
+
+// CopyWithExclude skips CopyDir entries matching any of patterns.
+//
+// Patterns follow the same rules as CopyWithInclude. When an entry matches
+// both an include and an exclude pattern, the exclude wins. This is only
+// supported by CopyDir.
+func CopyWithExclude(patterns ...string) CopyOption {
+	return func(c *copyConfig) {
+		c.filter.exclude = append(c.filter.exclude, patterns...)
+	}
+}
+
+// CopyWithPathMapper rewrites destination paths during CopyTo and CopyDir.
+//
+// The mapper is called once per entry before anything is written, so it can
+// relocate a subtree (for example "config/" to "etc/app/") or drop entries
+// by returning skip=true. Dropped entries are never read and are counted in
+// CopyStats.Filtered. Mapped paths that are absolute, escape the
+// destination directory, or contain a backslash are rejected with
+// fs.ErrInvalid, and two entries mapped to the same path, after cleaning,
+// fail with ErrPathConflict naming both. Path limits apply to the mapped
+// path.
+func CopyWithPathMapper(mapper PathMapper) CopyOption {
+	return func(c *copyConfig) {
+		c.pathMapper = mapper
+	}
+}
+
+// CopyWithMaxFiles aborts extraction with an *ExtractionLimitError once
+// more than n files would be written. Skipped and filtered files do not
+// count toward the limit. Zero or negative disables the limit.
+//
+// Files written before the limit was reached are left in place; the file
+// that would have exceeded it is never created.
+func CopyWithMaxFiles(n int) CopyOption {
+	return func(c *copyConfig) {
+		c.maxFiles = n
+	}
+}
+
+// CopyWithMaxTotalBytes aborts extraction with an *ExtractionLimitError once
+// the original (uncompressed) sizes of the written files would exceed limit
+// bytes. Skipped and filtered files do not count toward the limit. Zero
+// disables the limit.
+//
+// Use WithMaxFileSize to bound individual files.
+func CopyWithMaxTotalBytes(limit uint64) CopyOption {
+	return func(c *copyConfig) {
+		c.maxTotalBytes = limit
+	}
+}
+
+// CopyWithMaxPathLength aborts CopyTo and CopyDir with a *PathLimitError if
+// any selected entry's path is longer than n bytes. Paths are checked before
+// anything is written. Zero or negative disables the limit.
+func CopyWithMaxPathLength(n int) CopyOption {
+	return func(c *copyConfig) {
+		c.pathLimits.maxLength = n
+	}
+}
+
+// CopyWithMaxPathDepth aborts CopyTo and CopyDir with a *PathLimitError if
+// any selected entry's path has more than n elements. Paths are checked
+// before anything is written. Zero or negative disables the limit.
+func CopyWithMaxPathDepth(n int) CopyOption {
+	return func(c *copyConfig) {
+		c.pathLimits.maxDepth = n
+	}
+}
+
+// CopyWithSymlinks recreates symlink entries (see CreateWithSymlinks) as
+// symbolic links instead of skipping them.
+//
+// Link targets must be relative and must resolve inside the destination
+// when interpreted from the link's directory, also when other links in the
+// archive are followed along the way; absolute or escaping targets fail
+// with ErrSymlink before any link is created. CopyFile, which may write the
+// link anywhere, only accepts targets at or below the link's own directory.
+// Links are created after all files are written and count as files in
+// CopyStats.
+//
+// Without this option, CopyTo and CopyDir skip symlink entries and count
+// them in CopyStats.Skipped, and CopyFile returns ErrSymlink.
+func CopyWithSymlinks(enabled bool) CopyOption {
+	return func(c *copyConfig) {
+		c.symlinks = enabled
+	}
+}
+
 // CopyStats contains statistics about a copy operation.
 type CopyStats struct {
 	// FileCount is the number of files successfully copied.
@@ -174,6 +487,11 @@ type CopyStats struct {
 
 	// Skipped is the number of files skipped (e.g., already exist without overwrite).
 	Skipped int
+
+	// Filtered is the number of files excluded by CopyWithInclude,
+	// CopyWithExclude, or a CopyWithPathMapper skip. Filtered files are not
+	// counted in Skipped.
+	Filtered int
 }
 
 // DirStats contains statistics about files under a directory prefix.
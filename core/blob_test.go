@@ -4,11 +4,15 @@ import (
 	"bytes"
 	"context"
 	"crypto/sha256"
+	"fmt"
 	"io"
 	"io/fs"
 	"os"
+	"path"
 	"path/filepath"
 	"runtime"
+	"slices"
+	"sync"
 	"testing"
 	"time"
 
@@ -90,6 +94,532 @@ func TestCopyDir_SkippedStats(t *testing.T) {
 	assert.Equal(t, 1, stats.Skipped)            // a.txt skipped
 }
 
+func TestCopyDir_ExplicitDirStats(t *testing.T) {
+	t.Parallel()
+
+	content := []byte("hello")
+	hash := sha256.Sum256(content)
+	indexData := testutil.BuildTestIndex(t, []testutil.TestEntry{
+		{
+			Path:         "a.txt",
+			DataSize:     uint64(len(content)),
+			OriginalSize: uint64(len(content)),
+			Hash:         hash[:],
+			Mode:         0o644,
+		},
+		{Path: "empty", Mode: fs.ModeDir | 0o755},
+	})
+	b, err := New(indexData, testutil.NewMockByteSource(content))
+	require.NoError(t, err)
+
+	destDir := t.TempDir()
+	stats, err := b.CopyDir(destDir, "")
+	require.NoError(t, err)
+
+	assert.Equal(t, CopyStats{FileCount: 1, TotalBytes: uint64(len(content))}, stats)
+	info, err := os.Stat(filepath.Join(destDir, "empty"))
+	require.NoError(t, err)
+	assert.True(t, info.IsDir())
+}
+
+func TestCopyDir_Filters(t *testing.T) {
+	t.Parallel()
+
+	files := map[string][]byte{
+		"lib/libfoo.so":          bytes.Repeat([]byte("f"), 100),
+		"lib/libbar.so":          bytes.Repeat([]byte("b"), 200),
+		"lib/debug/libfoo.so":    bytes.Repeat([]byte("d"), 400),
+		"lib/debug/libfoo.debug": bytes.Repeat([]byte("g"), 800),
+		"lib/README.md":          []byte("readme"),
+		"bin/tool":               []byte("tool"),
+	}
+	b := createTestArchive(t, files, CompressionNone)
+
+	t.Run("include by base name", func(t *testing.T) {
+		t.Parallel()
+		destDir := t.TempDir()
+		stats, err := b.CopyDir(destDir, "lib", CopyWithInclude("*.so"))
+		require.NoError(t, err)
+		assert.Equal(t, 3, stats.FileCount)
+		assert.Equal(t, uint64(700), stats.TotalBytes)
+		assert.Equal(t, 2, stats.Filtered)
+		assert.Equal(t, 0, stats.Skipped)
+		assert.NoFileExists(t, filepath.Join(destDir, "lib", "README.md"))
+		assert.NoFileExists(t, filepath.Join(destDir, "lib", "debug", "libfoo.debug"))
+	})
+
+	t.Run("exclude wins over include", func(t *testing.T) {
+		t.Parallel()
+		destDir := t.TempDir()
+		stats, err := b.CopyDir(destDir, "lib",
+			CopyWithInclude("*.so", "*.debug"),
+			CopyWithExclude("debug/*"))
+		require.NoError(t, err)
+		assert.Equal(t, 2, stats.FileCount)
+		assert.Equal(t, uint64(300), stats.TotalBytes)
+		assert.Equal(t, 3, stats.Filtered)
+		assert.FileExists(t, filepath.Join(destDir, "lib", "libfoo.so"))
+		assert.NoDirExists(t, filepath.Join(destDir, "lib", "debug"))
+	})
+
+	t.Run("exclude only", func(t *testing.T) {
+		t.Parallel()
+		stats, err := b.CopyDir(t.TempDir(), ".", CopyWithExclude("*.debug"))
+		require.NoError(t, err)
+		assert.Equal(t, 5, stats.FileCount)
+		assert.Equal(t, 1, stats.Filtered)
+	})
+
+	t.Run("invalid pattern", func(t *testing.T) {
+		t.Parallel()
+		_, err := b.CopyDir(t.TempDir(), "lib", CopyWithInclude("[a-"))
+		require.ErrorIs(t, err, path.ErrBadPattern)
+		_, err = b.CopyTo(t.TempDir(), "lib/libfoo.so")
+		require.NoError(t, err)
+		_, err = b.CopyToWithOptions(t.TempDir(), []string{"lib/libfoo.so"}, CopyWithInclude("*.so"))
+		require.Error(t, err)
+	})
+}
+
+func TestCopyDir_Limits(t *testing.T) {
+	t.Parallel()
+
+	files := map[string][]byte{
+		"a.txt":     bytes.Repeat([]byte("a"), 100),
+		"b.txt":     bytes.Repeat([]byte("b"), 100),
+		"c.txt":     bytes.Repeat([]byte("c"), 100),
+		"dir/d.txt": bytes.Repeat([]byte("d"), 100),
+		"dir/e.txt": bytes.Repeat([]byte("e"), 100),
+	}
+	b := createTestArchive(t, files, CompressionNone)
+
+	// countFiles returns the regular files under dir, including temp files.
+	countFiles := func(t *testing.T, dir string) int {
+		t.Helper()
+		n := 0
+		err := filepath.WalkDir(dir, func(_ string, d fs.DirEntry, err error) error {
+			if err != nil {
+				return err
+			}
+			if d.Type().IsRegular() {
+				n++
+			}
+			return nil
+		})
+		require.NoError(t, err)
+		return n
+	}
+
+	t.Run("max files", func(t *testing.T) {
+		t.Parallel()
+
+		destDir := t.TempDir()
+		stats, err := b.CopyDir(destDir, "", CopyWithMaxFiles(2), CopyWithWorkers(-1))
+		require.ErrorIs(t, err, ErrExtractionLimit)
+
+		var limitErr *ExtractionLimitError
+		require.ErrorAs(t, err, &limitErr)
+		assert.Equal(t, ExtractionLimitFiles, limitErr.Limit)
+		assert.Equal(t, uint64(2), limitErr.Max)
+		assert.Equal(t, 2, stats.FileCount)
+		assert.Equal(t, 2, countFiles(t, destDir))
+	})
+
+	t.Run("max total bytes", func(t *testing.T) {
+		t.Parallel()
+
+		destDir := t.TempDir()
+		stats, err := b.CopyDir(destDir, "", CopyWithMaxTotalBytes(250))
+
+		var limitErr *ExtractionLimitError
+		require.ErrorAs(t, err, &limitErr)
+		assert.Equal(t, ExtractionLimitBytes, limitErr.Limit)
+		assert.LessOrEqual(t, stats.TotalBytes, uint64(250))
+		assert.LessOrEqual(t, countFiles(t, destDir), 2)
+	})
+
+	t.Run("within limits", func(t *testing.T) {
+		t.Parallel()
+
+		destDir := t.TempDir()
+		stats, err := b.CopyDir(destDir, "", CopyWithMaxFiles(5), CopyWithMaxTotalBytes(500))
+		require.NoError(t, err)
+		assert.Equal(t, 5, stats.FileCount)
+	})
+
+	t.Run("copy file", func(t *testing.T) {
+		t.Parallel()
+
+		destPath := filepath.Join(t.TempDir(), "a.txt")
+		_, err := b.CopyFile("a.txt", destPath, CopyWithMaxTotalBytes(50))
+		require.ErrorIs(t, err, ErrExtractionLimit)
+		_, statErr := os.Stat(destPath)
+		require.ErrorIs(t, statErr, fs.ErrNotExist)
+	})
+}
+
+func TestCopyDirContext_Cancel(t *testing.T) {
+	t.Parallel()
+
+	files := map[string][]byte{
+		"a.txt":     bytes.Repeat([]byte("a"), 100),
+		"b.txt":     bytes.Repeat([]byte("b"), 100),
+		"c.txt":     bytes.Repeat([]byte("c"), 100),
+		"dir/d.txt": bytes.Repeat([]byte("d"), 100),
+		"dir/e.txt": bytes.Repeat([]byte("e"), 100),
+	}
+	b := createTestArchive(t, files, CompressionNone)
+
+	t.Run("mid copy", func(t *testing.T) {
+		t.Parallel()
+
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+
+		destDir := t.TempDir()
+		stats, err := b.CopyDirContext(ctx, destDir, "",
+			CopyWithWorkers(-1),
+			CopyWithProgress(func(ProgressEvent) { cancel() }),
+		)
+		require.ErrorIs(t, err, context.Canceled)
+		assert.Equal(t, 1, stats.FileCount)
+
+		var written []string
+		err = filepath.WalkDir(destDir, func(path string, d fs.DirEntry, err error) error {
+			if err != nil {
+				return err
+			}
+			if d.Type().IsRegular() {
+				rel, relErr := filepath.Rel(destDir, path)
+				require.NoError(t, relErr)
+				written = append(written, filepath.ToSlash(rel))
+			}
+			return nil
+		})
+		require.NoError(t, err)
+		assert.Equal(t, []string{"a.txt"}, written)
+	})
+
+	t.Run("canceled before start", func(t *testing.T) {
+		t.Parallel()
+
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+
+		destDir := t.TempDir()
+		stats, err := b.CopyToContext(ctx, destDir, []string{"a.txt", "b.txt"})
+		require.ErrorIs(t, err, context.Canceled)
+		assert.Zero(t, stats.FileCount)
+
+		_, err = b.ReadFileContext(ctx, "a.txt")
+		require.ErrorIs(t, err, context.Canceled)
+	})
+}
+
+func TestCopyDir_MaxBytesPerSecond(t *testing.T) {
+	t.Parallel()
+
+	files := map[string][]byte{
+		"a.bin":     bytes.Repeat([]byte("a"), 16<<10),
+		"dir/b.bin": bytes.Repeat([]byte("b"), 16<<10),
+	}
+	b := createTestArchive(t, files, CompressionNone)
+
+	destDir := t.TempDir()
+	start := time.Now()
+	stats, err := b.CopyDir(destDir, "", CopyWithMaxBytesPerSecond(256<<10))
+	elapsed := time.Since(start)
+	require.NoError(t, err)
+	assert.Equal(t, 2, stats.FileCount)
+	assert.GreaterOrEqual(t, elapsed, 100*time.Millisecond, "32 KiB at 256 KiB/s takes 125ms")
+
+	got, err := os.ReadFile(filepath.Join(destDir, "dir", "b.bin"))
+	require.NoError(t, err)
+	assert.Equal(t, files["dir/b.bin"], got)
+}
+
+func TestCopyDir_PerFileProgress(t *testing.T) {
+	t.Parallel()
+
+	files := map[string][]byte{
+		"a.txt":         bytes.Repeat([]byte("a"), 100),
+		"b.txt":         bytes.Repeat([]byte("b"), 200),
+		"dir/c.txt":     bytes.Repeat([]byte("c"), 300),
+		"dir/sub/d.txt": {},
+	}
+	b := createTestArchive(t, files, CompressionNone)
+
+	copyEvents := func(t *testing.T, opts ...CopyOption) []ProgressEvent {
+		t.Helper()
+		var mu sync.Mutex
+		var events []ProgressEvent
+		record := func(e ProgressEvent) {
+			mu.Lock()
+			defer mu.Unlock()
+			events = append(events, e)
+		}
+		opts = append(opts, CopyWithProgress(record), CopyWithWorkers(4))
+		_, err := b.CopyDir(t.TempDir(), "", opts...)
+		require.NoError(t, err)
+		return events
+	}
+
+	t.Run("enabled", func(t *testing.T) {
+		t.Parallel()
+
+		events := copyEvents(t, CopyWithPerFileProgress(true))
+		started := make(map[string]int)
+		finished := make(map[string]int)
+		for _, e := range events {
+			assert.Equal(t, len(files), e.FilesTotal)
+			size := uint64(len(files[e.Path]))
+			switch e.Stage {
+			case StageExtractingFile:
+				assert.Zero(t, finished[e.Path], "%s started after it finished", e.Path)
+				assert.Equal(t, uint64(0), e.BytesDone)
+				assert.Equal(t, size, e.BytesTotal)
+				started[e.Path]++
+			case StageExtractedFile:
+				assert.Equal(t, 1, started[e.Path], "%s finished before it started", e.Path)
+				assert.Equal(t, size, e.BytesDone)
+				assert.Equal(t, size, e.BytesTotal)
+				finished[e.Path]++
+			case StageExtracting:
+				assert.Equal(t, 1, finished[e.Path], "%s aggregate event before finish", e.Path)
+			default:
+				t.Errorf("unexpected stage %s", e.Stage)
+			}
+		}
+		for path := range files {
+			assert.Equal(t, 1, started[path], "start events for %s", path)
+			assert.Equal(t, 1, finished[path], "finish events for %s", path)
+		}
+		assert.Len(t, events, 3*len(files))
+	})
+
+	t.Run("disabled", func(t *testing.T) {
+		t.Parallel()
+
+		events := copyEvents(t)
+		require.Len(t, events, len(files))
+		for _, e := range events {
+			assert.Equal(t, StageExtracting, e.Stage)
+		}
+	})
+}
+
+func TestCopyDir_OrderedProgress(t *testing.T) {
+	t.Parallel()
+
+	files := make(map[string][]byte)
+	var paths []string
+	for i := range 32 {
+		path := fmt.Sprintf("dir%d/file%02d.txt", i%3, i)
+		// Uneven sizes make workers finish out of order.
+		files[path] = bytes.Repeat([]byte{byte('a' + i%26)}, (32-i)*1024)
+		paths = append(paths, path)
+	}
+	slices.Sort(paths)
+	b := createTestArchive(t, files, CompressionZstd)
+
+	var mu sync.Mutex
+	var events []ProgressEvent
+	_, err := b.CopyDir(t.TempDir(), "",
+		CopyWithWorkers(4),
+		CopyWithPerFileProgress(true),
+		CopyWithOrderedProgress(true),
+		CopyWithProgress(func(e ProgressEvent) {
+			mu.Lock()
+			defer mu.Unlock()
+			events = append(events, e)
+		}),
+	)
+	require.NoError(t, err)
+
+	require.Len(t, events, 3*len(paths))
+	for i, path := range paths {
+		start, finished, extracted := events[3*i], events[3*i+1], events[3*i+2]
+		assert.Equal(t, StageExtractingFile, start.Stage)
+		assert.Equal(t, StageExtractedFile, finished.Stage)
+		assert.Equal(t, StageExtracting, extracted.Stage)
+		for _, e := range []ProgressEvent{start, finished, extracted} {
+			assert.Equal(t, path, e.Path)
+			assert.Equal(t, len(paths), e.FilesTotal)
+		}
+		assert.Equal(t, i, start.FilesDone)
+		assert.Equal(t, i+1, extracted.FilesDone)
+	}
+}
+
+func TestCopyDir_Symlinks(t *testing.T) {
+	t.Parallel()
+
+	srcDir := t.TempDir()
+	createTestFileBytes(t, srcDir, "dir/target.txt", []byte("target"))
+	require.NoError(t, os.Symlink("dir/target.txt", filepath.Join(srcDir, "link.txt")))
+	require.NoError(t, os.Symlink("target.txt", filepath.Join(srcDir, "dir", "sibling.txt")))
+
+	var indexBuf, dataBuf bytes.Buffer
+	err := Create(context.Background(), srcDir, &indexBuf, &dataBuf, CreateWithSymlinks(true))
+	require.NoError(t, err)
+	b, err := New(indexBuf.Bytes(), testutil.NewMockByteSource(dataBuf.Bytes()))
+	require.NoError(t, err)
+
+	view, ok := b.Entry("link.txt")
+	require.True(t, ok)
+	assert.NotZero(t, view.Mode()&fs.ModeSymlink)
+
+	t.Run("round trip", func(t *testing.T) {
+		t.Parallel()
+
+		destDir := t.TempDir()
+		stats, err := b.CopyDir(destDir, "", CopyWithSymlinks(true))
+		require.NoError(t, err)
+		assert.Equal(t, 3, stats.FileCount)
+
+		target, err := os.Readlink(filepath.Join(destDir, "link.txt"))
+		require.NoError(t, err)
+		assert.Equal(t, "dir/target.txt", target)
+		content, err := os.ReadFile(filepath.Join(destDir, "dir", "sibling.txt"))
+		require.NoError(t, err)
+		assert.Equal(t, "target", string(content))
+	})
+
+	t.Run("skipped without option", func(t *testing.T) {
+		t.Parallel()
+
+		destDir := t.TempDir()
+		stats, err := b.CopyDir(destDir, "")
+		require.NoError(t, err)
+		assert.Equal(t, 1, stats.FileCount)
+		assert.Equal(t, 2, stats.Skipped)
+		_, err = os.Lstat(filepath.Join(destDir, "link.txt"))
+		require.ErrorIs(t, err, fs.ErrNotExist)
+
+		_, err = b.CopyFile("link.txt", filepath.Join(destDir, "link.txt"))
+		require.ErrorIs(t, err, ErrSymlink)
+	})
+}
+
+func TestCopyDir_SymlinkRejectsTraversal(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name   string
+		path   string
+		target string
+	}{
+		{name: "parent", path: "link", target: "../outside"},
+		{name: "nested parent", path: "dir/link", target: "../../outside"},
+		{name: "absolute", path: "link", target: "/etc/passwd"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			target := []byte(tt.target)
+			hash := sha256.Sum256(target)
+			indexData := testutil.BuildTestIndex(t, []testutil.TestEntry{
+				{
+					Path:         tt.path,
+					DataSize:     uint64(len(target)),
+					OriginalSize: uint64(len(target)),
+					Hash:         hash[:],
+					Mode:         fs.ModeSymlink | 0o777,
+				},
+			})
+			b, err := New(indexData, testutil.NewMockByteSource(target))
+			require.NoError(t, err)
+
+			destDir := t.TempDir()
+			_, err = b.CopyDir(destDir, "", CopyWithSymlinks(true))
+			require.ErrorIs(t, err, ErrSymlink)
+			_, statErr := os.Lstat(filepath.Join(destDir, filepath.FromSlash(tt.path)))
+			require.ErrorIs(t, statErr, fs.ErrNotExist)
+
+			_, err = b.CopyFile(tt.path, filepath.Join(destDir, "copy"), CopyWithSymlinks(true))
+			require.ErrorIs(t, err, ErrSymlink)
+		})
+	}
+}
+
+// symlinkArchive returns an archive of symlinks mapping each path to its
+// target.
+func symlinkArchive(t *testing.T, links map[string]string) *Blob {
+	t.Helper()
+
+	var data []byte
+	entries := make([]testutil.TestEntry, 0, len(links))
+	for path, target := range links {
+		hash := sha256.Sum256([]byte(target))
+		entries = append(entries, testutil.TestEntry{
+			Path:         path,
+			DataOffset:   uint64(len(data)),
+			DataSize:     uint64(len(target)),
+			OriginalSize: uint64(len(target)),
+			Hash:         hash[:],
+			Mode:         fs.ModeSymlink | 0o777,
+		})
+		data = append(data, target...)
+	}
+	b, err := New(testutil.BuildTestIndex(t, entries), testutil.NewMockByteSource(data))
+	require.NoError(t, err)
+	return b
+}
+
+func TestCopyDir_SymlinkRejectsChainedTraversal(t *testing.T) {
+	t.Parallel()
+
+	// Each target stays inside the destination on its own, but "d/a"
+	// resolves through "d/b" to the destination's parent.
+	b := symlinkArchive(t, map[string]string{
+		"d/a": "b/../secret",
+		"d/b": "..",
+	})
+	destDir := t.TempDir()
+	_, err := b.CopyDir(destDir, ".", CopyWithSymlinks(true))
+	require.ErrorIs(t, err, ErrSymlink)
+	assert.Contains(t, err.Error(), "d/a")
+	_, statErr := os.Lstat(filepath.Join(destDir, "d", "a"))
+	require.ErrorIs(t, statErr, fs.ErrNotExist)
+
+	// Chains that stay inside the destination are allowed.
+	b = symlinkArchive(t, map[string]string{
+		"d/a": "b/../c",
+		"d/b": "e",
+		"d/c": "e/file",
+		"x":   "d/a",
+	})
+	destDir = t.TempDir()
+	stats, err := b.CopyDir(destDir, ".", CopyWithSymlinks(true))
+	require.NoError(t, err)
+	assert.Equal(t, 4, stats.FileCount)
+}
+
+func TestCopyFile_SymlinkStaysInOwnDirectory(t *testing.T) {
+	t.Parallel()
+
+	b := symlinkArchive(t, map[string]string{
+		"a/b/up":   "../x",
+		"a/b/down": "sub/x",
+	})
+	destDir := t.TempDir()
+
+	// The link is written outside its archive directory, where "../x" would
+	// point somewhere else entirely.
+	_, err := b.CopyFile("a/b/up", filepath.Join(destDir, "up"), CopyWithSymlinks(true))
+	require.ErrorIs(t, err, ErrSymlink)
+	_, statErr := os.Lstat(filepath.Join(destDir, "up"))
+	require.ErrorIs(t, statErr, fs.ErrNotExist)
+
+	_, err = b.CopyFile("a/b/down", filepath.Join(destDir, "down"), CopyWithSymlinks(true))
+	require.NoError(t, err)
+	target, err := os.Readlink(filepath.Join(destDir, "down"))
+	require.NoError(t, err)
+	assert.Equal(t, "sub/x", target)
+}
+
 func TestCopyTo_ReturnsStats(t *testing.T) {
 	t.Parallel()
 
@@ -369,6 +899,212 @@ func TestBlobReadFile(t *testing.T) {
 	})
 }
 
+func TestBlobReadFileRange(t *testing.T) {
+	t.Parallel()
+
+	content := []byte("0123456789abcdefghij")
+	files := map[string][]byte{"log.txt": content}
+	dir := t.TempDir()
+	createTestFilesBytes(t, dir, files)
+	var indexBuf, dataBuf bytes.Buffer
+	require.NoError(t, Create(context.Background(), dir, &indexBuf, &dataBuf))
+
+	t.Run("reads only the range", func(t *testing.T) {
+		t.Parallel()
+		src := newCountingSource(testutil.NewMockByteSource(dataBuf.Bytes()))
+		b, err := New(indexBuf.Bytes(), src)
+		require.NoError(t, err)
+
+		got, err := b.ReadFileRange("log.txt", 5, 4)
+		require.NoError(t, err)
+		assert.Equal(t, []byte("5678"), got)
+		assert.Equal(t, int64(1), src.RangeRequests())
+		assert.Equal(t, int64(4), src.BytesRead())
+	})
+
+	t.Run("clamps past EOF", func(t *testing.T) {
+		t.Parallel()
+		b, err := New(indexBuf.Bytes(), testutil.NewMockByteSource(dataBuf.Bytes()))
+		require.NoError(t, err)
+
+		got, err := b.ReadFileRange("log.txt", 15, 100)
+		require.NoError(t, err)
+		assert.Equal(t, []byte("fghij"), got)
+
+		got, err = b.ReadFileRange("log.txt", 20, 10)
+		require.NoError(t, err)
+		assert.Empty(t, got)
+
+		got, err = b.ReadFileRange("log.txt", 1000, 10)
+		require.NoError(t, err)
+		assert.Empty(t, got)
+	})
+
+	t.Run("invalid arguments", func(t *testing.T) {
+		t.Parallel()
+		b, err := New(indexBuf.Bytes(), testutil.NewMockByteSource(dataBuf.Bytes()))
+		require.NoError(t, err)
+
+		_, err = b.ReadFileRange("log.txt", -1, 4)
+		require.ErrorIs(t, err, fs.ErrInvalid)
+		_, err = b.ReadFileRange("log.txt", 0, -1)
+		require.ErrorIs(t, err, fs.ErrInvalid)
+		_, err = b.ReadFileRange("missing.txt", 0, 4)
+		require.ErrorIs(t, err, fs.ErrNotExist)
+	})
+
+	t.Run("rejects compressed files", func(t *testing.T) {
+		t.Parallel()
+		b := createTestArchive(t, map[string][]byte{
+			"log.txt": bytes.Repeat([]byte("compressible "), 100),
+		}, CompressionZstd)
+
+		_, err := b.ReadFileRange("log.txt", 0, 4)
+		require.ErrorIs(t, err, ErrCompressedRange)
+	})
+}
+
+// sectionRangeSource adds ReadRange to a mock source and counts range requests.
+type sectionRangeSource struct {
+	*testutil.MockByteSource
+	ranges int
+}
+
+func (s *sectionRangeSource) ReadRange(off, length int64) (io.ReadCloser, error) {
+	s.ranges++
+	return io.NopCloser(io.NewSectionReader(s.MockByteSource, off, length)), nil
+}
+
+func TestBlobChecksum(t *testing.T) {
+	t.Parallel()
+
+	files := map[string][]byte{
+		"a.txt":       bytes.Repeat([]byte("alpha "), 200),
+		"dir/b.txt":   bytes.Repeat([]byte("bravo "), 200),
+		"dir/c/d.bin": {0, 1, 2, 3},
+	}
+	plain := createTestArchive(t, files, CompressionNone)
+	compressed := createTestArchive(t, files, CompressionZstd)
+	require.NotEqual(t, plain.IndexData(), compressed.IndexData())
+
+	plainSum, ok := plain.Checksum()
+	require.True(t, ok)
+	compressedSum, ok := compressed.Checksum()
+	require.True(t, ok)
+	assert.Equal(t, plainSum, compressedSum)
+	assert.Len(t, plainSum, sha256.Size)
+
+	changed := map[string][]byte{
+		"a.txt":       bytes.Repeat([]byte("alpha "), 200),
+		"dir/b.txt":   bytes.Repeat([]byte("bravo!"), 200),
+		"dir/c/d.bin": {0, 1, 2, 3},
+	}
+	changedSum, ok := createTestArchive(t, changed, CompressionNone).Checksum()
+	require.True(t, ok)
+	assert.NotEqual(t, plainSum, changedSum)
+
+	renamed := map[string][]byte{
+		"a.txt":       bytes.Repeat([]byte("alpha "), 200),
+		"dir/b2.txt":  bytes.Repeat([]byte("bravo "), 200),
+		"dir/c/d.bin": {0, 1, 2, 3},
+	}
+	renamedSum, ok := createTestArchive(t, renamed, CompressionNone).Checksum()
+	require.True(t, ok)
+	assert.NotEqual(t, plainSum, renamedSum)
+}
+
+func TestBlobOpenRange(t *testing.T) {
+	t.Parallel()
+
+	content := bytes.Repeat([]byte("streaming content "), 4096)
+	files := map[string][]byte{"big.bin": content, "dir/small.txt": []byte("small")}
+
+	build := func(t *testing.T, compression Compression) (indexData, data []byte) {
+		t.Helper()
+		dir := t.TempDir()
+		createTestFilesBytes(t, dir, files)
+		var indexBuf, dataBuf bytes.Buffer
+		require.NoError(t, Create(context.Background(), dir, &indexBuf, &dataBuf,
+			CreateWithCompression(compression)))
+		return indexBuf.Bytes(), dataBuf.Bytes()
+	}
+
+	t.Run("uncompressed uses one range request", func(t *testing.T) {
+		t.Parallel()
+		indexData, data := build(t, CompressionNone)
+		src := &sectionRangeSource{MockByteSource: testutil.NewMockByteSource(data)}
+		b, err := New(indexData, src)
+		require.NoError(t, err)
+
+		rc, size, err := b.OpenRange("big.bin")
+		require.NoError(t, err)
+		assert.Equal(t, int64(len(content)), size)
+
+		var got bytes.Buffer
+		_, err = io.Copy(&got, rc)
+		require.NoError(t, err)
+		require.NoError(t, rc.Close())
+		assert.Equal(t, content, got.Bytes())
+		assert.Equal(t, 1, src.ranges)
+	})
+
+	t.Run("compressed", func(t *testing.T) {
+		t.Parallel()
+		indexData, data := build(t, CompressionZstd)
+		b, err := New(indexData, testutil.NewMockByteSource(data))
+		require.NoError(t, err)
+
+		rc, size, err := b.OpenRange("big.bin")
+		require.NoError(t, err)
+		assert.Equal(t, int64(len(content)), size)
+
+		var got bytes.Buffer
+		_, err = io.Copy(&got, rc)
+		require.NoError(t, err)
+		require.NoError(t, rc.Close())
+		assert.Equal(t, content, got.Bytes())
+	})
+
+	t.Run("hash mismatch at close", func(t *testing.T) {
+		t.Parallel()
+		indexData, data := build(t, CompressionNone)
+		corrupted := bytes.Clone(data)
+		idx, err := NewIndexView(indexData)
+		require.NoError(t, err)
+		view, ok := idx.Entry("big.bin")
+		require.True(t, ok)
+		corrupted[view.DataOffset()+10] ^= 0xff
+
+		b, err := New(indexData, &sectionRangeSource{MockByteSource: testutil.NewMockByteSource(corrupted)})
+		require.NoError(t, err)
+
+		rc, _, err := b.OpenRange("big.bin")
+		require.NoError(t, err)
+		_, err = io.Copy(io.Discard, rc)
+		require.ErrorIs(t, err, ErrHashMismatch)
+		require.ErrorIs(t, rc.Close(), ErrHashMismatch)
+
+		// Close verifies unread content too.
+		rc, _, err = b.OpenRange("big.bin")
+		require.NoError(t, err)
+		require.ErrorIs(t, rc.Close(), ErrHashMismatch)
+	})
+
+	t.Run("errors", func(t *testing.T) {
+		t.Parallel()
+		indexData, data := build(t, CompressionNone)
+		b, err := New(indexData, testutil.NewMockByteSource(data))
+		require.NoError(t, err)
+
+		_, _, err = b.OpenRange("missing.bin")
+		require.ErrorIs(t, err, fs.ErrNotExist)
+		_, _, err = b.OpenRange("dir")
+		require.ErrorIs(t, err, fs.ErrNotExist)
+		_, _, err = b.OpenRange("../big.bin")
+		require.ErrorIs(t, err, fs.ErrInvalid)
+	})
+}
+
 func TestBlobStat(t *testing.T) {
 	t.Parallel()
 
@@ -486,6 +1222,94 @@ func TestBlobReadDir(t *testing.T) {
 	})
 }
 
+func TestBlobReadDirExplicitDirs(t *testing.T) {
+	t.Parallel()
+
+	content := []byte("x")
+	hash := sha256.Sum256(content)
+	modTime := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	fileEntry := func(path string) testutil.TestEntry {
+		return testutil.TestEntry{
+			Path:         path,
+			DataSize:     uint64(len(content)),
+			OriginalSize: uint64(len(content)),
+			Hash:         hash[:],
+			Mode:         0o644,
+		}
+	}
+	dirEntry := func(path string, perm fs.FileMode) testutil.TestEntry {
+		return testutil.TestEntry{Path: path, Mode: fs.ModeDir | perm, ModTime: modTime}
+	}
+
+	indexData := testutil.BuildTestIndex(t, []testutil.TestEntry{
+		fileEntry("a.txt"),
+		dirEntry("empty", 0o700),
+		fileEntry("full/x.txt"),
+		// "nested/full" is both explicit and implied by "nested/full/y.txt",
+		// with "nested/full-notes.txt" sorting between the two in the index.
+		dirEntry("nested/full", 0o750),
+		fileEntry("nested/full-notes.txt"),
+		fileEntry("nested/full/y.txt"),
+	})
+	b, err := New(indexData, testutil.NewMockByteSource(content))
+	require.NoError(t, err)
+
+	type dirent struct {
+		name  string
+		isDir bool
+		mode  fs.FileMode
+	}
+	readDir := func(t *testing.T, name string) []dirent {
+		t.Helper()
+		entries, err := b.ReadDir(name)
+		require.NoError(t, err)
+		got := make([]dirent, len(entries))
+		for i, e := range entries {
+			info, err := e.Info()
+			require.NoError(t, err)
+			assert.Equal(t, info.Mode().Type(), e.Type(), e.Name())
+			got[i] = dirent{name: e.Name(), isDir: e.IsDir(), mode: info.Mode()}
+		}
+		return got
+	}
+
+	t.Run("explicit and synthesized", func(t *testing.T) {
+		t.Parallel()
+		assert.Equal(t, []dirent{
+			{name: "a.txt", mode: 0o644},
+			{name: "empty", isDir: true, mode: fs.ModeDir | 0o700},
+			{name: "full", isDir: true, mode: fs.ModeDir | 0o755},
+			{name: "nested", isDir: true, mode: fs.ModeDir | 0o755},
+		}, readDir(t, "."))
+	})
+
+	t.Run("merged without duplicates", func(t *testing.T) {
+		t.Parallel()
+		assert.Equal(t, []dirent{
+			{name: "full", isDir: true, mode: fs.ModeDir | 0o750},
+			{name: "full-notes.txt", mode: 0o644},
+		}, readDir(t, "nested"))
+	})
+
+	t.Run("empty explicit dir", func(t *testing.T) {
+		t.Parallel()
+		assert.Empty(t, readDir(t, "empty"))
+		assert.True(t, b.IsDir("empty"))
+		assert.False(t, b.IsFile("empty"))
+
+		info, err := b.Stat("empty")
+		require.NoError(t, err)
+		assert.True(t, info.IsDir())
+		assert.Equal(t, modTime, info.ModTime().UTC())
+
+		f, err := b.Open("empty")
+		require.NoError(t, err)
+		defer f.Close()
+		_, ok := f.(fs.ReadDirFile)
+		assert.True(t, ok, "explicit directory should open as a directory")
+	})
+}
+
 func TestBlobFSWalk(t *testing.T) {
 	t.Parallel()
 
@@ -926,6 +1750,47 @@ func TestBlob_DirStats_Compressed(t *testing.T) {
 	assert.Less(t, stats.CompressedBytes, stats.TotalBytes) // Compressed should be smaller
 }
 
+func TestBlob_AllDirs(t *testing.T) {
+	t.Parallel()
+
+	hash := sha256.Sum256(nil)
+	var entries []testutil.TestEntry
+	for _, p := range []string{
+		"a.txt",
+		"a/b-x/f.txt",
+		"a/b/c/d.txt",
+		"a/b/e.txt",
+		"a/b0/f.txt",
+		"z/deep/er/still/g.txt",
+	} {
+		entries = append(entries, testutil.TestEntry{Path: p, Hash: hash[:], Mode: 0o644})
+	}
+	entries = append(entries,
+		testutil.TestEntry{Path: "a", Mode: fs.ModeDir | 0o755},
+		testutil.TestEntry{Path: "empty", Mode: fs.ModeDir | 0o755},
+	)
+
+	b, err := New(testutil.BuildTestIndex(t, entries), testutil.NewMockByteSource(nil))
+	require.NoError(t, err)
+
+	assert.Equal(t, []string{
+		"a",
+		"a/b",
+		"a/b-x",
+		"a/b/c",
+		"a/b0",
+		"empty",
+		"z",
+		"z/deep",
+		"z/deep/er",
+		"z/deep/er/still",
+	}, b.AllDirs())
+
+	sub, err := b.Subset("a/b")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"c"}, sub.AllDirs())
+}
+
 func TestBlob_ValidateFiles(t *testing.T) {
 	t.Parallel()
 
@@ -1021,3 +1886,100 @@ func TestBlob_ValidateFiles(t *testing.T) {
 		assert.Equal(t, "/nonexistent.txt", valErr.Path)
 	})
 }
+
+func TestNew_MaxIndexVersion(t *testing.T) {
+	t.Parallel()
+
+	data := []byte("hello")
+	hash := sha256.Sum256(data)
+	indexData := testutil.BuildTestIndexWithMetadata(t, []testutil.TestEntry{
+		{
+			Path:         "hello.txt",
+			DataSize:     uint64(len(data)),
+			OriginalSize: uint64(len(data)),
+			Hash:         hash[:],
+			Mode:         0o644,
+		},
+	}, &testutil.IndexMetadata{
+		DataSize:      uint64(len(data)),
+		DataHash:      hash[:],
+		Version:       IndexVersion + 1,
+		UnknownFields: 1,
+	})
+
+	_, err := New(indexData, testutil.NewMockByteSource(data))
+	require.ErrorIs(t, err, ErrUnsupportedIndexVersion)
+	var versionErr *IndexVersionError
+	require.ErrorAs(t, err, &versionErr)
+	assert.Equal(t, IndexVersion+1, versionErr.Version)
+
+	archive, err := New(indexData, testutil.NewMockByteSource(data), WithMaxIndexVersion(IndexVersion+1))
+	require.NoError(t, err)
+	got, err := archive.ReadFile("hello.txt")
+	require.NoError(t, err)
+	assert.Equal(t, data, got)
+
+	// Metadata-only views accept any version.
+	view, err := NewIndexView(indexData)
+	require.NoError(t, err)
+	assert.Equal(t, 1, view.Len())
+}
+
+func TestNew_MaxFiles(t *testing.T) {
+	t.Parallel()
+
+	archive := createTestArchive(t, map[string][]byte{
+		"a.txt":     []byte("a"),
+		"b.txt":     []byte("b"),
+		"dir/c.txt": []byte("c"),
+	}, CompressionNone)
+	indexData, source := archive.IndexData(), archive.Reader().Source()
+
+	t.Run("over limit", func(t *testing.T) {
+		t.Parallel()
+		_, err := New(indexData, source, WithMaxFiles(2))
+		require.ErrorIs(t, err, ErrTooManyFiles)
+	})
+
+	t.Run("at limit", func(t *testing.T) {
+		t.Parallel()
+		b, err := New(indexData, source, WithMaxFiles(3))
+		require.NoError(t, err)
+		assert.Equal(t, 3, b.Len())
+	})
+
+	t.Run("disabled", func(t *testing.T) {
+		t.Parallel()
+		_, err := New(indexData, source, WithMaxFiles(0))
+		require.NoError(t, err)
+	})
+}
+
+func TestNewFromIndexFile(t *testing.T) {
+	t.Parallel()
+
+	files := map[string][]byte{
+		"a.txt":     []byte("alpha"),
+		"dir/b.txt": []byte("bravo"),
+	}
+	archive := createTestArchive(t, files, CompressionZstd)
+	indexPath := filepath.Join(t.TempDir(), "index.blob")
+	require.NoError(t, os.WriteFile(indexPath, archive.IndexData(), 0o600))
+
+	b, err := NewFromIndexFile(indexPath, archive.Reader().Source())
+	require.NoError(t, err)
+	assert.Equal(t, archive.IndexData(), b.IndexData())
+	assert.Equal(t, archive.Len(), b.Len())
+	for path, content := range files {
+		data, err := b.ReadFile(path)
+		require.NoError(t, err, path)
+		assert.Equal(t, content, data)
+	}
+	_, err = b.Stat("missing.txt")
+	require.ErrorIs(t, err, fs.ErrNotExist)
+
+	_, err = NewFromIndexFile(indexPath, archive.Reader().Source(), WithMaxFiles(1))
+	require.ErrorIs(t, err, ErrTooManyFiles)
+	_, err = NewFromIndexFile(filepath.Join(t.TempDir(), "missing"), archive.Reader().Source())
+	require.ErrorIs(t, err, fs.ErrNotExist)
+}
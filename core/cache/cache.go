@@ -34,3 +34,23 @@ type Cache interface {
 	// Returns the number of bytes freed.
 	Prune(targetBytes int64) (int64, error)
 }
+
+// Stats holds cumulative cache counters.
+type Stats struct {
+	Hits         int64 // Get calls that found content
+	Misses       int64 // Get calls that did not find content
+	Puts         int64 // Put calls that stored new content
+	Evictions    int64 // Entries removed to stay within the size limit or by Prune
+	BytesWritten int64 // Bytes stored by Put
+	BytesEvicted int64 // Bytes removed by evictions
+}
+
+// StatReporter is implemented by caches that track usage counters.
+//
+// Counters cover every call on the cache, including lookups made internally
+// by callers such as Blob, so a single logical read may record more than one
+// miss. Compare hits to misses over time rather than per read.
+type StatReporter interface {
+	// Stats returns a snapshot of the cache counters.
+	Stats() Stats
+}
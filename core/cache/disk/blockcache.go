@@ -156,7 +156,7 @@ func (c *BlockCache) Prune(targetBytes int64) (int64, error) {
 	c.pruneMu.Lock()
 	defer c.pruneMu.Unlock()
 
-	freed, remaining, err := pruneDir(c.dir, targetBytes)
+	_, freed, remaining, err := pruneDir(c.dir, targetBytes)
 	if err != nil {
 		return 0, err
 	}
@@ -10,6 +10,9 @@ import (
 	"path/filepath"
 	"sync"
 	"sync/atomic"
+	"time"
+
+	"github.com/meigma/blob/core/cache"
 )
 
 const (
@@ -21,13 +24,21 @@ const (
 // Files are stored in a directory hierarchy with optional sharding by hash prefix.
 // The cache is safe for concurrent use.
 type Cache struct {
-	dir            string       // root directory for cached files
-	shardPrefixLen int          // number of hex chars for subdirectory sharding
-	dirPerm        os.FileMode  // permissions for created directories
-	maxBytes       int64        // maximum cache size (0 = unlimited)
-	bytes          atomic.Int64 // current total size of cached files
-	pruneMu        sync.Mutex   // serializes prune operations
+	dir            string        // root directory for cached files
+	shardPrefixLen int           // number of hex chars for subdirectory sharding
+	dirPerm        os.FileMode   // permissions for created directories
+	maxBytes       int64         // maximum cache size (0 = unlimited)
+	ttl            time.Duration // maximum entry age (0 = no expiry)
+	bytes          atomic.Int64  // current total size of cached files
+	pruneMu        sync.Mutex    // serializes prune operations
 	logger         *slog.Logger
+
+	hits         atomic.Int64
+	misses       atomic.Int64
+	puts         atomic.Int64
+	evictions    atomic.Int64
+	bytesWritten atomic.Int64
+	bytesEvicted atomic.Int64
 }
 
 // log returns the logger, falling back to a discard logger if nil.
@@ -64,6 +75,18 @@ func WithMaxBytes(n int64) Option {
 	}
 }
 
+// WithTTL sets the maximum age of cached entries, measured from when they
+// were written. Older entries are treated as misses and deleted lazily when
+// accessed. Values < 0 are invalid. Use 0 to disable expiry (the default).
+//
+// Content is addressed by hash, so an expired entry is never stale; TTL
+// exists to bound disk growth by age in addition to WithMaxBytes.
+func WithTTL(d time.Duration) Option {
+	return func(c *Cache) {
+		c.ttl = d
+	}
+}
+
 // WithLogger sets the logger for cache operations.
 // If not set, logging is disabled.
 func WithLogger(logger *slog.Logger) Option {
@@ -91,6 +114,9 @@ func New(dir string, opts ...Option) (*Cache, error) {
 	if c.maxBytes < 0 {
 		return nil, errors.New("max bytes must be >= 0")
 	}
+	if c.ttl < 0 {
+		return nil, errors.New("ttl must be >= 0")
+	}
 	if err := os.MkdirAll(dir, c.dirPerm); err != nil {
 		return nil, err
 	}
@@ -104,17 +130,30 @@ func New(dir string, opts ...Option) (*Cache, error) {
 }
 
 // Get returns an fs.File for reading cached content.
-// Returns nil, false if the content is not cached.
+// Returns nil, false if the content is not cached or has expired.
 func (c *Cache) Get(hash []byte) (fs.File, bool) {
 	path, err := c.path(hash)
 	if err != nil {
+		c.misses.Add(1)
 		return nil, false
 	}
 	f, err := os.Open(path) //nolint:gosec // path is derived from hash, not user input
 	if err != nil {
+		c.misses.Add(1)
 		c.log().Debug("cache miss", "hash", hex.EncodeToString(hash[:min(4, len(hash))]))
 		return nil, false
 	}
+	if c.ttl > 0 {
+		info, statErr := f.Stat()
+		if statErr != nil || c.expired(info) {
+			f.Close()
+			c.misses.Add(1)
+			c.log().Debug("cache expired", "hash", hex.EncodeToString(hash[:min(4, len(hash))]))
+			c.expire(path)
+			return nil, false
+		}
+	}
+	c.hits.Add(1)
 	c.log().Debug("cache hit", "hash", hex.EncodeToString(hash[:min(4, len(hash))]))
 	return f, true
 }
@@ -126,8 +165,11 @@ func (c *Cache) Put(hash []byte, f fs.File) error {
 	if err != nil {
 		return err
 	}
-	if _, statErr := os.Stat(path); statErr == nil {
-		return nil
+	if info, statErr := os.Stat(path); statErr == nil {
+		if !c.expired(info) {
+			return nil
+		}
+		c.expire(path)
 	}
 
 	dir := filepath.Dir(path)
@@ -169,6 +211,8 @@ func (c *Cache) Put(hash []byte, f fs.File) error {
 		return err
 	}
 	c.bytes.Add(written)
+	c.puts.Add(1)
+	c.bytesWritten.Add(written)
 	c.log().Debug("cache put", "hash", hex.EncodeToString(hash[:min(4, len(hash))]), "size", written)
 	return nil
 }
@@ -216,7 +260,9 @@ func (c *Cache) Prune(targetBytes int64) (int64, error) {
 	c.pruneMu.Lock()
 	defer c.pruneMu.Unlock()
 
-	freed, remaining, err := pruneDir(c.dir, targetBytes)
+	removed, freed, remaining, err := pruneDir(c.dir, targetBytes)
+	c.evictions.Add(int64(removed))
+	c.bytesEvicted.Add(freed)
 	if err != nil {
 		return 0, err
 	}
@@ -227,6 +273,37 @@ func (c *Cache) Prune(targetBytes int64) (int64, error) {
 	return freed, nil
 }
 
+// Stats returns a snapshot of the cache counters.
+func (c *Cache) Stats() cache.Stats {
+	return cache.Stats{
+		Hits:         c.hits.Load(),
+		Misses:       c.misses.Load(),
+		Puts:         c.puts.Load(),
+		Evictions:    c.evictions.Load(),
+		BytesWritten: c.bytesWritten.Load(),
+		BytesEvicted: c.bytesEvicted.Load(),
+	}
+}
+
+// expired reports whether a cached file is older than the TTL.
+func (c *Cache) expired(info fs.FileInfo) bool {
+	return c.ttl > 0 && time.Since(info.ModTime()) > c.ttl
+}
+
+// expire removes an expired entry, counting it as an eviction.
+func (c *Cache) expire(path string) {
+	info, err := os.Stat(path)
+	if err != nil || !c.expired(info) {
+		return
+	}
+	if err := os.Remove(path); err != nil {
+		return
+	}
+	c.bytes.Add(-info.Size())
+	c.evictions.Add(1)
+	c.bytesEvicted.Add(info.Size())
+}
+
 func (c *Cache) path(hash []byte) (string, error) {
 	if len(hash) == 0 {
 		return "", errors.New("hash is empty")
@@ -257,3 +334,8 @@ func (c *Cache) ensureCapacity(need int64) (bool, error) {
 	}
 	return c.SizeBytes()+need <= c.maxBytes, nil
 }
+
+var (
+	_ cache.Cache        = (*Cache)(nil)
+	_ cache.StatReporter = (*Cache)(nil)
+)
@@ -4,6 +4,7 @@ import (
 	"bytes"
 	"crypto/sha256"
 	"encoding/hex"
+	"errors"
 	"io"
 	"os"
 	"path/filepath"
@@ -121,6 +122,142 @@ func TestCacheAlreadyCached(t *testing.T) {
 	}
 }
 
+func TestCacheStats(t *testing.T) {
+	t.Parallel()
+
+	c, err := New(t.TempDir(), WithMaxBytes(9))
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+
+	first := []byte("aaaaaa")
+	firstSum := sha256.Sum256(first)
+	if putErr := c.Put(firstSum[:], &bytesFile{Reader: bytes.NewReader(first)}); putErr != nil {
+		t.Fatalf("Put() error = %v", putErr)
+	}
+	f, ok := c.Get(firstSum[:])
+	if !ok {
+		t.Fatal("Get() ok = false, want true")
+	}
+	f.Close()
+
+	second := []byte("bbbb")
+	secondSum := sha256.Sum256(second)
+	if _, ok := c.Get(secondSum[:]); ok {
+		t.Fatal("Get() before Put ok = true, want false")
+	}
+	// Storing second exceeds the limit and evicts first.
+	if putErr := c.Put(secondSum[:], &bytesFile{Reader: bytes.NewReader(second)}); putErr != nil {
+		t.Fatalf("Put() error = %v", putErr)
+	}
+
+	got := c.Stats()
+	if got.Hits != 1 || got.Misses != 1 || got.Puts != 2 {
+		t.Fatalf("Stats() hits/misses/puts = %d/%d/%d, want 1/1/2", got.Hits, got.Misses, got.Puts)
+	}
+	if got.BytesWritten != 10 {
+		t.Fatalf("Stats().BytesWritten = %d, want 10", got.BytesWritten)
+	}
+	if got.Evictions != 1 || got.BytesEvicted != 6 {
+		t.Fatalf("Stats() evictions = %d (%d bytes), want 1 (6 bytes)", got.Evictions, got.BytesEvicted)
+	}
+}
+
+func TestCacheTTLExpiresEntry(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	ttl := 2 * time.Second
+	c, err := New(dir, WithTTL(ttl))
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+
+	content := []byte("aging")
+	sum := sha256.Sum256(content)
+	if putErr := c.Put(sum[:], &bytesFile{Reader: bytes.NewReader(content)}); putErr != nil {
+		t.Fatalf("Put() error = %v", putErr)
+	}
+
+	path, err := c.path(sum[:])
+	if err != nil {
+		t.Fatalf("path() error = %v", err)
+	}
+	expired := time.Now().Add(-2 * ttl)
+	if err := os.Chtimes(path, expired, expired); err != nil {
+		t.Fatalf("Chtimes() error = %v", err)
+	}
+
+	if _, ok := c.Get(sum[:]); ok {
+		t.Fatal("Get() ok = true, want false for expired entry")
+	}
+	if _, err := os.Stat(path); !errors.Is(err, os.ErrNotExist) {
+		t.Fatalf("expected cache file to be deleted, got err=%v", err)
+	}
+	if c.SizeBytes() != 0 {
+		t.Fatalf("SizeBytes() = %d, want 0", c.SizeBytes())
+	}
+	if stats := c.Stats(); stats.Evictions != 1 || stats.Misses != 1 {
+		t.Fatalf("Stats() evictions/misses = %d/%d, want 1/1", stats.Evictions, stats.Misses)
+	}
+
+	// Put replaces an expired entry instead of treating it as cached.
+	if putErr := c.Put(sum[:], &bytesFile{Reader: bytes.NewReader(content)}); putErr != nil {
+		t.Fatalf("Put() error = %v", putErr)
+	}
+	if err := os.Chtimes(path, expired, expired); err != nil {
+		t.Fatalf("Chtimes() error = %v", err)
+	}
+	if putErr := c.Put(sum[:], &bytesFile{Reader: bytes.NewReader(content)}); putErr != nil {
+		t.Fatalf("Put() error = %v", putErr)
+	}
+	f, ok := c.Get(sum[:])
+	if !ok {
+		t.Fatal("Get() after re-Put ok = false, want true")
+	}
+	f.Close()
+	if c.SizeBytes() != int64(len(content)) {
+		t.Fatalf("SizeBytes() = %d, want %d", c.SizeBytes(), len(content))
+	}
+}
+
+func TestCacheTTLWithinWindow(t *testing.T) {
+	t.Parallel()
+
+	c, err := New(t.TempDir(), WithTTL(time.Minute))
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+
+	content := []byte("fresh")
+	sum := sha256.Sum256(content)
+	if putErr := c.Put(sum[:], &bytesFile{Reader: bytes.NewReader(content)}); putErr != nil {
+		t.Fatalf("Put() error = %v", putErr)
+	}
+	path, err := c.path(sum[:])
+	if err != nil {
+		t.Fatalf("path() error = %v", err)
+	}
+	recent := time.Now().Add(-30 * time.Second)
+	if err := os.Chtimes(path, recent, recent); err != nil {
+		t.Fatalf("Chtimes() error = %v", err)
+	}
+
+	f, ok := c.Get(sum[:])
+	if !ok {
+		t.Fatal("Get() ok = false, want true for unexpired entry")
+	}
+	f.Close()
+}
+
+func TestCacheNegativeTTL(t *testing.T) {
+	t.Parallel()
+
+	if _, err := New(t.TempDir(), WithTTL(-time.Second)); err == nil {
+		t.Fatal("New() error = nil, want error for negative ttl")
+	}
+}
+
 // bytesFile wraps a bytes.Reader for testing Put.
 type bytesFile struct {
 	*bytes.Reader
@@ -42,8 +42,9 @@ func dirSize(root string) (int64, error) {
 
 // pruneDir removes files from root until the total size is at or below targetBytes.
 // Files are removed in order of modification time (oldest first).
-// Returns the number of bytes freed and the remaining size.
-func pruneDir(root string, targetBytes int64) (freed, remaining int64, err error) {
+// Returns the number of files removed, the number of bytes freed, and the
+// remaining size.
+func pruneDir(root string, targetBytes int64) (removed int, freed, remaining int64, err error) {
 	if targetBytes < 0 {
 		targetBytes = 0
 	}
@@ -72,15 +73,15 @@ func pruneDir(root string, targetBytes int64) (freed, remaining int64, err error
 		return nil
 	})
 	if errors.Is(walkErr, os.ErrNotExist) {
-		return 0, 0, nil
+		return 0, 0, 0, nil
 	}
 	if walkErr != nil {
-		return 0, 0, walkErr
+		return 0, 0, 0, walkErr
 	}
 
 	remaining = total
 	if remaining <= targetBytes {
-		return 0, remaining, nil
+		return 0, 0, remaining, nil
 	}
 
 	sort.Slice(entries, func(i, j int) bool {
@@ -98,11 +99,12 @@ func pruneDir(root string, targetBytes int64) (freed, remaining int64, err error
 			if errors.Is(err, os.ErrNotExist) {
 				continue
 			}
-			return freed, remaining, err
+			return removed, freed, remaining, err
 		}
 		remaining -= entry.size
 		freed += entry.size
+		removed++
 	}
 
-	return freed, remaining, nil
+	return removed, freed, remaining, nil
 }
@@ -0,0 +1,211 @@
+package cache
+
+import (
+	"bytes"
+	"container/list"
+	"encoding/hex"
+	"errors"
+	"io"
+	"io/fs"
+	"sync"
+	"time"
+)
+
+// Memory implements Cache in process memory with least-recently-used eviction.
+//
+// Get and Put mark an entry as most recently used. When a Put would take the
+// cache over its limit, the least recently used entries are evicted first;
+// content larger than the limit is not stored. Memory is safe for
+// concurrent use.
+type Memory struct {
+	mu       sync.Mutex
+	maxBytes int64
+	size     int64
+	order    *list.List               // front is most recently used
+	items    map[string]*list.Element // key is the raw hash
+	stats    Stats
+}
+
+// memoryEntry is a cached item in the recency list.
+type memoryEntry struct {
+	key  string
+	data []byte
+}
+
+// NewMemory creates an in-memory cache holding at most maxBytes of content.
+// Use 0 (or a negative value) for no limit.
+func NewMemory(maxBytes int64) *Memory {
+	return &Memory{
+		maxBytes: max(maxBytes, 0),
+		order:    list.New(),
+		items:    make(map[string]*list.Element),
+	}
+}
+
+// Get returns an fs.File for reading cached content and marks it as recently
+// used. Returns nil, false if the content is not cached.
+func (m *Memory) Get(hash []byte) (fs.File, bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	elem, ok := m.items[string(hash)]
+	if !ok {
+		m.stats.Misses++
+		return nil, false
+	}
+	m.stats.Hits++
+	m.order.MoveToFront(elem)
+	entry := elem.Value.(*memoryEntry) //nolint:errcheck,forcetypeassert // list only holds *memoryEntry
+	return newMemoryFile(entry), true
+}
+
+// Put stores content by reading from the provided fs.File, evicting least
+// recently used entries as needed. The cache reads the file to completion;
+// caller still owns/closes the file.
+func (m *Memory) Put(hash []byte, f fs.File) error {
+	if len(hash) == 0 {
+		return errors.New("hash is empty")
+	}
+	key := string(hash)
+
+	m.mu.Lock()
+	if elem, ok := m.items[key]; ok {
+		m.order.MoveToFront(elem)
+		m.mu.Unlock()
+		return nil
+	}
+	m.mu.Unlock()
+
+	data, err := io.ReadAll(f)
+	if err != nil {
+		return err
+	}
+	need := int64(len(data))
+	if m.maxBytes > 0 && need > m.maxBytes {
+		return nil
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if elem, ok := m.items[key]; ok {
+		m.order.MoveToFront(elem)
+		return nil
+	}
+	if m.maxBytes > 0 {
+		m.evict(m.maxBytes - need)
+	}
+	m.items[key] = m.order.PushFront(&memoryEntry{key: key, data: data})
+	m.size += need
+	m.stats.Puts++
+	m.stats.BytesWritten += need
+	return nil
+}
+
+// Delete removes cached content for the given hash.
+func (m *Memory) Delete(hash []byte) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if elem, ok := m.items[string(hash)]; ok {
+		m.remove(elem)
+	}
+	return nil
+}
+
+// MaxBytes returns the configured cache size limit (0 = unlimited).
+func (m *Memory) MaxBytes() int64 {
+	return m.maxBytes
+}
+
+// SizeBytes returns the current cache size in bytes.
+func (m *Memory) SizeBytes() int64 {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.size
+}
+
+// Stats returns a snapshot of the cache counters.
+func (m *Memory) Stats() Stats {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.stats
+}
+
+// Prune removes least recently used entries until the cache is at or below
+// targetBytes. Returns the number of bytes freed.
+func (m *Memory) Prune(targetBytes int64) (int64, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.evict(max(targetBytes, 0)), nil
+}
+
+// evict removes entries from the back of the recency list until the cache
+// holds at most target bytes. The caller must hold m.mu.
+func (m *Memory) evict(target int64) int64 {
+	var freed int64
+	for m.size > target {
+		elem := m.order.Back()
+		if elem == nil {
+			break
+		}
+		n := m.remove(elem)
+		freed += n
+		m.stats.Evictions++
+		m.stats.BytesEvicted += n
+	}
+	return freed
+}
+
+// remove deletes elem from the cache and returns its size.
+// The caller must hold m.mu.
+func (m *Memory) remove(elem *list.Element) int64 {
+	entry := m.order.Remove(elem).(*memoryEntry) //nolint:errcheck,forcetypeassert // list only holds *memoryEntry
+	delete(m.items, entry.key)
+	n := int64(len(entry.data))
+	m.size -= n
+	return n
+}
+
+var (
+	_ Cache        = (*Memory)(nil)
+	_ StatReporter = (*Memory)(nil)
+)
+
+// memoryFile is an fs.File over cached bytes.
+type memoryFile struct {
+	*bytes.Reader
+	info memoryFileInfo
+}
+
+func newMemoryFile(entry *memoryEntry) *memoryFile {
+	return &memoryFile{
+		Reader: bytes.NewReader(entry.data),
+		info: memoryFileInfo{
+			name: hex.EncodeToString([]byte(entry.key)),
+			size: int64(len(entry.data)),
+		},
+	}
+}
+
+// Stat returns file info for the cached content.
+func (f *memoryFile) Stat() (fs.FileInfo, error) {
+	return f.info, nil
+}
+
+// Close is a no-op; the cached bytes are shared and immutable.
+func (f *memoryFile) Close() error {
+	return nil
+}
+
+// memoryFileInfo describes cached content. The name is the hex-encoded hash.
+type memoryFileInfo struct {
+	name string
+	size int64
+}
+
+func (fi memoryFileInfo) Name() string       { return fi.name }
+func (fi memoryFileInfo) Size() int64        { return fi.size }
+func (fi memoryFileInfo) Mode() fs.FileMode  { return 0o444 }
+func (fi memoryFileInfo) ModTime() time.Time { return time.Time{} }
+func (fi memoryFileInfo) IsDir() bool        { return false }
+func (fi memoryFileInfo) Sys() any           { return nil }
@@ -0,0 +1,188 @@
+package cache
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"fmt"
+	"io"
+	"io/fs"
+	"sync"
+	"testing"
+)
+
+// bytesFile adapts a bytes.Reader to fs.File for Put.
+type bytesFile struct {
+	*bytes.Reader
+}
+
+func (f *bytesFile) Stat() (fs.FileInfo, error) {
+	return memoryFileInfo{size: f.Size()}, nil
+}
+
+func (f *bytesFile) Close() error { return nil }
+
+func putBytes(t *testing.T, c Cache, content []byte) []byte {
+	t.Helper()
+
+	sum := sha256.Sum256(content)
+	if err := c.Put(sum[:], &bytesFile{Reader: bytes.NewReader(content)}); err != nil {
+		t.Fatalf("Put() error = %v", err)
+	}
+	return sum[:]
+}
+
+func readCached(t *testing.T, c Cache, hash []byte) ([]byte, bool) {
+	t.Helper()
+
+	f, ok := c.Get(hash)
+	if !ok {
+		return nil, false
+	}
+	defer f.Close()
+	got, err := io.ReadAll(f)
+	if err != nil {
+		t.Fatalf("ReadAll() error = %v", err)
+	}
+	return got, true
+}
+
+func TestMemoryPutGet(t *testing.T) {
+	t.Parallel()
+
+	c := NewMemory(0)
+	content := []byte("hello")
+	hash := putBytes(t, c, content)
+
+	got, ok := readCached(t, c, hash)
+	if !ok {
+		t.Fatal("Get() ok = false, want true")
+	}
+	if !bytes.Equal(got, content) {
+		t.Fatalf("Get() content = %q, want %q", got, content)
+	}
+	if c.SizeBytes() != int64(len(content)) {
+		t.Fatalf("SizeBytes() = %d, want %d", c.SizeBytes(), len(content))
+	}
+
+	f, _ := c.Get(hash)
+	info, err := f.Stat()
+	if err != nil {
+		t.Fatalf("Stat() error = %v", err)
+	}
+	if info.Size() != int64(len(content)) {
+		t.Fatalf("Stat().Size() = %d, want %d", info.Size(), len(content))
+	}
+
+	if err := c.Delete(hash); err != nil {
+		t.Fatalf("Delete() error = %v", err)
+	}
+	if _, ok := c.Get(hash); ok {
+		t.Fatal("Get() after Delete ok = true, want false")
+	}
+	if c.SizeBytes() != 0 {
+		t.Fatalf("SizeBytes() after Delete = %d, want 0", c.SizeBytes())
+	}
+}
+
+func TestMemoryEvictsLeastRecentlyUsed(t *testing.T) {
+	t.Parallel()
+
+	c := NewMemory(30)
+	first := putBytes(t, c, bytes.Repeat([]byte("1"), 10))
+	second := putBytes(t, c, bytes.Repeat([]byte("2"), 10))
+	third := putBytes(t, c, bytes.Repeat([]byte("3"), 10))
+
+	// Touch first so second becomes the oldest.
+	if _, ok := c.Get(first); !ok {
+		t.Fatal("Get(first) ok = false, want true")
+	}
+
+	fourth := putBytes(t, c, bytes.Repeat([]byte("4"), 10))
+	if _, ok := c.Get(second); ok {
+		t.Fatal("second should have been evicted first")
+	}
+	for name, hash := range map[string][]byte{"first": first, "third": third, "fourth": fourth} {
+		if _, ok := c.Get(hash); !ok {
+			t.Fatalf("Get(%s) ok = false, want true", name)
+		}
+	}
+	if c.SizeBytes() != 30 {
+		t.Fatalf("SizeBytes() = %d, want 30", c.SizeBytes())
+	}
+
+	// Content larger than the limit is not stored.
+	big := putBytes(t, c, bytes.Repeat([]byte("b"), 31))
+	if _, ok := c.Get(big); ok {
+		t.Fatal("oversized content should not be cached")
+	}
+
+	freed, err := c.Prune(10)
+	if err != nil {
+		t.Fatalf("Prune() error = %v", err)
+	}
+	if freed != 20 {
+		t.Fatalf("Prune() freed = %d, want 20", freed)
+	}
+	if c.SizeBytes() != 10 {
+		t.Fatalf("SizeBytes() after Prune = %d, want 10", c.SizeBytes())
+	}
+}
+
+func TestMemoryConcurrent(t *testing.T) {
+	t.Parallel()
+
+	c := NewMemory(64 * 20)
+	contents := make([][]byte, 50)
+	for i := range contents {
+		contents[i] = bytes.Repeat([]byte(fmt.Sprintf("%02d", i)), 32)
+	}
+
+	var wg sync.WaitGroup
+	for w := range 8 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range 200 {
+				content := contents[(w*7+i)%len(contents)]
+				sum := sha256.Sum256(content)
+				if err := c.Put(sum[:], &bytesFile{Reader: bytes.NewReader(content)}); err != nil {
+					t.Errorf("Put() error = %v", err)
+					return
+				}
+				if f, ok := c.Get(sum[:]); ok {
+					got, err := io.ReadAll(f)
+					f.Close()
+					if err != nil || !bytes.Equal(got, content) {
+						t.Errorf("Get() content mismatch (err = %v)", err)
+						return
+					}
+				}
+			}
+		}()
+	}
+	wg.Wait()
+
+	if size := c.SizeBytes(); size > c.MaxBytes() {
+		t.Fatalf("SizeBytes() = %d exceeds MaxBytes() = %d", size, c.MaxBytes())
+	}
+}
+
+func TestMemoryStats(t *testing.T) {
+	t.Parallel()
+
+	c := NewMemory(20)
+	first := putBytes(t, c, bytes.Repeat([]byte("1"), 10))
+	if _, ok := c.Get(first); !ok {
+		t.Fatal("Get(first) ok = false, want true")
+	}
+	if _, ok := c.Get([]byte("missing")); ok {
+		t.Fatal("Get(missing) ok = true, want false")
+	}
+	putBytes(t, c, bytes.Repeat([]byte("2"), 10))
+	putBytes(t, c, bytes.Repeat([]byte("3"), 10))
+
+	want := Stats{Hits: 1, Misses: 1, Puts: 3, Evictions: 1, BytesWritten: 30, BytesEvicted: 10}
+	if got := c.Stats(); got != want {
+		t.Fatalf("Stats() = %+v, want %+v", got, want)
+	}
+}
@@ -2,6 +2,7 @@ package blob
 
 import (
 	"bytes"
+	"context"
 	"crypto/sha256"
 	"fmt"
 	"hash"
@@ -165,6 +166,10 @@ func (b *Blob) ensureCached(entry *Entry) error {
 			return struct{}{}, nil //nolint:nilnil // returning nil error is intentional for cache hit
 		}
 
+		if b.chunkBytes > 0 && b.prefetchChunk(context.Background(), entry) == nil {
+			return struct{}{}, nil
+		}
+
 		// Stream from source to cache
 		f := b.reader.OpenFile(entry, true)
 		err := b.cache.Put(entry.Hash, f)
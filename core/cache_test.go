@@ -12,6 +12,7 @@ import (
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 
+	blobcache "github.com/meigma/blob/core/cache"
 	"github.com/meigma/blob/core/testutil"
 )
 
@@ -301,3 +302,55 @@ func readAll(f interface{ Read([]byte) (int, error) }) ([]byte, error) {
 		}
 	}
 }
+
+func TestBlobCacheStats(t *testing.T) {
+	t.Parallel()
+
+	var indexBuf, dataBuf bytes.Buffer
+	dir := t.TempDir()
+	createTestFilesBytes(t, dir, map[string][]byte{
+		"a.txt": []byte("alpha"),
+		"b.txt": []byte("bravo"),
+	})
+	require.NoError(t, Create(context.Background(), dir, &indexBuf, &dataBuf))
+
+	t.Run("no cache", func(t *testing.T) {
+		t.Parallel()
+		b, err := New(indexBuf.Bytes(), testutil.NewMockByteSource(dataBuf.Bytes()))
+		require.NoError(t, err)
+		_, ok := b.CacheStats()
+		assert.False(t, ok)
+	})
+
+	t.Run("reporting cache", func(t *testing.T) {
+		t.Parallel()
+		b, err := New(indexBuf.Bytes(), testutil.NewMockByteSource(dataBuf.Bytes()),
+			WithCache(blobcache.NewMemory(0)))
+		require.NoError(t, err)
+
+		_, err = b.ReadFile("a.txt")
+		require.NoError(t, err)
+		stats, ok := b.CacheStats()
+		require.True(t, ok)
+		assert.Zero(t, stats.Hits)
+		assert.Positive(t, stats.Misses)
+		assert.Equal(t, int64(1), stats.Puts)
+		assert.Equal(t, int64(len("alpha")), stats.BytesWritten)
+		misses := stats.Misses
+
+		for range 3 {
+			_, err = b.ReadFile("a.txt")
+			require.NoError(t, err)
+		}
+		stats, _ = b.CacheStats()
+		assert.Equal(t, int64(3), stats.Hits)
+		assert.Equal(t, misses, stats.Misses)
+
+		_, err = b.ReadFile("b.txt")
+		require.NoError(t, err)
+		stats, _ = b.CacheStats()
+		assert.Equal(t, int64(3), stats.Hits)
+		assert.Greater(t, stats.Misses, misses)
+		assert.Equal(t, int64(2), stats.Puts)
+	})
+}
@@ -0,0 +1,119 @@
+package blob
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/meigma/blob/core/internal/index"
+)
+
+// foldPath returns the canonical case-folded form of p. Two paths have the
+// same folded form exactly when strings.EqualFold reports them equal.
+func foldPath(p string) string {
+	for i := range len(p) {
+		if p[i] >= utf8.RuneSelf {
+			return strings.Map(foldRune, p)
+		}
+	}
+	// The smallest rune of an ASCII letter's orbit is its upper case.
+	return strings.ToUpper(p)
+}
+
+// foldRune maps r to the smallest rune in its simple case folding orbit,
+// so 'K', 'k', and the Kelvin sign all fold to 'K'.
+func foldRune(r rune) rune {
+	smallest := r
+	for f := unicode.SimpleFold(r); f != r; f = unicode.SimpleFold(f) {
+		smallest = min(smallest, f)
+	}
+	return smallest
+}
+
+// foldIndex maps the folded form of every archive path, including the
+// directories synthesized from file paths, to the path it was built from.
+// It is built on first use and shared by Subset views.
+type foldIndex struct {
+	once  sync.Once
+	paths map[string]string // folded path -> first archive path in index order
+}
+
+// get returns the archive path whose folded form is folded.
+func (f *foldIndex) get(idx *index.Index, folded string) (string, bool) {
+	f.once.Do(func() {
+		f.paths = make(map[string]string, idx.Len())
+		for view := range idx.EntriesView() {
+			path := string(view.PathBytes())
+			for i := range len(path) {
+				if path[i] == '/' {
+					f.add(path[:i])
+				}
+			}
+			f.add(path)
+		}
+	})
+	path, ok := f.paths[folded]
+	return path, ok
+}
+
+func (f *foldIndex) add(path string) {
+	folded := foldPath(path)
+	if _, ok := f.paths[folded]; !ok {
+		f.paths[folded] = path
+	}
+}
+
+// canonicalPath maps the archive path name to the archive path it matches
+// under WithCaseInsensitiveLookup. An exact match is preferred; otherwise
+// the first path in index order that differs only in case is used. Without
+// the option, or when nothing matches, name is returned unchanged.
+func (b *Blob) canonicalPath(name string) string {
+	if b.fold == nil || name == "." {
+		return name
+	}
+	canonical, ok := b.fold.get(b.idx, foldPath(name))
+	if !ok || canonical == name {
+		return name
+	}
+	if _, ok := b.lookup(name); ok || b.isDir(name) {
+		return name
+	}
+	return canonical
+}
+
+// caseCollisions detects archive paths, or the directories above them, that
+// differ only in case from one already seen.
+type caseCollisions struct {
+	seen map[string]string // folded path -> archive path
+}
+
+// check records path and its parent directories, returning an error
+// matching ErrCaseCollision if any of them collides with an earlier path.
+func (c *caseCollisions) check(path string) error {
+	if c.seen == nil {
+		c.seen = make(map[string]string)
+	}
+	for i := range len(path) {
+		if path[i] == '/' {
+			if err := c.add(path[:i]); err != nil {
+				return err
+			}
+		}
+	}
+	return c.add(path)
+}
+
+func (c *caseCollisions) add(path string) error {
+	folded := foldPath(path)
+	prev, ok := c.seen[folded]
+	if !ok {
+		c.seen[folded] = path
+		return nil
+	}
+	if prev != path {
+		return fmt.Errorf("%w: %s and %s", ErrCaseCollision, prev, path)
+	}
+	return nil
+}
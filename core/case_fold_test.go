@@ -0,0 +1,214 @@
+package blob
+
+import (
+	"bytes"
+	"context"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"testing"
+	"testing/fstest"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/meigma/blob/core/testutil"
+)
+
+func newCaseTestBlob(t *testing.T, fsys fstest.MapFS, opts ...Option) *Blob {
+	t.Helper()
+
+	var indexBuf, dataBuf bytes.Buffer
+	require.NoError(t, CreateFS(context.Background(), fsys, &indexBuf, &dataBuf))
+	b, err := New(indexBuf.Bytes(), testutil.NewMockByteSource(dataBuf.Bytes()), opts...)
+	require.NoError(t, err)
+	return b
+}
+
+func TestWithCaseInsensitiveLookup(t *testing.T) {
+	t.Parallel()
+
+	fsys := fstest.MapFS{
+		"README":             {Data: []byte("upper")},
+		"readme":             {Data: []byte("lower")},
+		"Docs/Guide.md":      {Data: []byte("guide")},
+		"Docs/img/Logo.png":  {Data: []byte("logo")},
+		"src/Main.go":        {Data: []byte("main")},
+		"ÄRGER/Straße.txt":   {Data: []byte("unicode")},
+		"kelvin/K-scale.txt": {Data: []byte("kelvin")},
+	}
+	b := newCaseTestBlob(t, fsys, WithCaseInsensitiveLookup(true))
+
+	t.Run("files", func(t *testing.T) {
+		t.Parallel()
+
+		for name, want := range map[string]string{
+			"docs/guide.md":      "guide",
+			"DOCS/IMG/logo.PNG":  "logo",
+			"Src/main.GO":        "main",
+			"ärger/STRASSE.txt":  "",
+			"ärger/straße.TXT":   "unicode",
+			"KELVIN/K-scale.txt": "kelvin",
+		} {
+			data, err := b.ReadFile(name)
+			if want == "" {
+				// Simple case folding does not expand ß to SS.
+				require.ErrorIs(t, err, fs.ErrNotExist, name)
+				continue
+			}
+			require.NoError(t, err, name)
+			assert.Equal(t, want, string(data), name)
+
+			f, err := b.Open(name)
+			require.NoError(t, err, name)
+			require.NoError(t, f.Close())
+		}
+	})
+
+	t.Run("exact match wins", func(t *testing.T) {
+		t.Parallel()
+
+		for name, want := range map[string]string{"README": "upper", "readme": "lower", "ReadMe": "upper"} {
+			data, err := b.ReadFile(name)
+			require.NoError(t, err, name)
+			assert.Equal(t, want, string(data), name)
+		}
+	})
+
+	t.Run("stat and entry", func(t *testing.T) {
+		t.Parallel()
+
+		info, err := b.Stat("docs/GUIDE.md")
+		require.NoError(t, err)
+		assert.Equal(t, "GUIDE.md", info.Name(), "the name is the one looked up, as with fs.FS")
+		assert.Equal(t, int64(len("guide")), info.Size())
+
+		info, err = b.Stat("DOCS")
+		require.NoError(t, err)
+		assert.True(t, info.IsDir())
+
+		view, ok := b.Entry("docs/img/LOGO.png")
+		require.True(t, ok)
+		assert.Equal(t, "Docs/img/Logo.png", view.Path())
+
+		_, ok = b.Entry("docs/missing.md")
+		assert.False(t, ok)
+		_, err = b.Stat("docs/missing.md")
+		require.ErrorIs(t, err, fs.ErrNotExist)
+	})
+
+	t.Run("directories keep archived names", func(t *testing.T) {
+		t.Parallel()
+
+		entries, err := b.ReadDir("docs")
+		require.NoError(t, err)
+		require.Len(t, entries, 2)
+		assert.Equal(t, "Guide.md", entries[0].Name())
+		assert.Equal(t, "img", entries[1].Name())
+	})
+
+	t.Run("subset", func(t *testing.T) {
+		t.Parallel()
+
+		sub, err := b.Subset("DOCS")
+		require.NoError(t, err)
+		data, err := sub.ReadFile("IMG/logo.png")
+		require.NoError(t, err)
+		assert.Equal(t, "logo", string(data))
+		_, ok := sub.Entry("guide.MD")
+		assert.True(t, ok)
+	})
+
+	t.Run("disabled by default", func(t *testing.T) {
+		t.Parallel()
+
+		strict := newCaseTestBlob(t, fsys)
+		_, err := strict.ReadFile("docs/guide.md")
+		require.ErrorIs(t, err, fs.ErrNotExist)
+		_, ok := strict.Entry("docs/guide.md")
+		assert.False(t, ok)
+
+		strict = newCaseTestBlob(t, fsys, WithCaseInsensitiveLookup(true), WithCaseInsensitiveLookup(false))
+		_, err = strict.Stat("docs/guide.md")
+		require.ErrorIs(t, err, fs.ErrNotExist)
+	})
+}
+
+func TestCreateWithRejectCaseCollisions(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	create := func(fsys fstest.MapFS, opts ...CreateOption) error {
+		var indexBuf, dataBuf bytes.Buffer
+		return CreateFS(ctx, fsys, &indexBuf, &dataBuf, opts...)
+	}
+
+	for name, fsys := range map[string]fstest.MapFS{
+		"files": {
+			"README": {Data: []byte("a")},
+			"readme": {Data: []byte("b")},
+		},
+		"directories": {
+			"Docs/a.md": {Data: []byte("a")},
+			"docs/b.md": {Data: []byte("b")},
+		},
+		"file and directory": {
+			"bin":         {Data: []byte("a")},
+			"BIN/tool.sh": {Data: []byte("b")},
+		},
+		"unicode": {
+			"ÄRGER.txt": {Data: []byte("a")},
+			"ärger.txt": {Data: []byte("b")},
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			err := create(fsys, CreateWithRejectCaseCollisions(true))
+			require.ErrorIs(t, err, ErrCaseCollision)
+			require.NoError(t, create(fsys), "collisions are allowed by default")
+		})
+	}
+
+	t.Run("distinct paths", func(t *testing.T) {
+		t.Parallel()
+
+		fsys := fstest.MapFS{
+			"Docs/a.md":   {Data: []byte("a")},
+			"Docs/b.md":   {Data: []byte("b")},
+			"Docs.md":     {Data: []byte("c")},
+			"docs-old/a":  {Data: []byte("d")},
+			"straße.txt":  {Data: []byte("e")},
+			"strasse.txt": {Data: []byte("f")},
+		}
+		require.NoError(t, create(fsys, CreateWithRejectCaseCollisions(true)))
+	})
+
+	t.Run("after path rewriting", func(t *testing.T) {
+		t.Parallel()
+
+		fsys := fstest.MapFS{
+			"x/Lib/a.go": {Data: []byte("a")},
+			"y/lib/b.go": {Data: []byte("b")},
+		}
+		require.NoError(t, create(fsys, CreateWithRejectCaseCollisions(true)))
+		err := create(fsys, CreateWithRejectCaseCollisions(true), CreateWithStripPrefix(1))
+		require.ErrorIs(t, err, ErrCaseCollision)
+	})
+
+	t.Run("directory", func(t *testing.T) {
+		t.Parallel()
+
+		dir := t.TempDir()
+		require.NoError(t, os.WriteFile(filepath.Join(dir, "Makefile"), []byte("a"), 0o600))
+		require.NoError(t, os.WriteFile(filepath.Join(dir, "makefile"), []byte("b"), 0o600))
+		if _, err := os.Stat(filepath.Join(dir, "MAKEFILE")); err == nil {
+			t.Skip("file system is case-insensitive")
+		}
+
+		var indexBuf, dataBuf bytes.Buffer
+		err := Create(ctx, dir, &indexBuf, &dataBuf, CreateWithRejectCaseCollisions(true))
+		require.ErrorIs(t, err, ErrCaseCollision)
+		assert.ErrorContains(t, err, "Makefile and makefile")
+	})
+}
@@ -0,0 +1,91 @@
+package blob
+
+import (
+	"cmp"
+	"context"
+	"slices"
+	"sync"
+
+	"github.com/meigma/blob/core/internal/batch"
+	"github.com/meigma/blob/core/internal/blobtype"
+	"github.com/meigma/blob/core/internal/index"
+)
+
+// chunkIndex lists index positions of entries with data, ordered by data
+// offset. It is built on first use and shared by Subset views.
+type chunkIndex struct {
+	once      sync.Once
+	positions []int
+	offsets   []uint64
+}
+
+// build fills the offset-ordered tables from idx.
+func (c *chunkIndex) build(idx *index.Index) {
+	c.once.Do(func() {
+		type located struct {
+			pos    int
+			offset uint64
+		}
+		n := idx.Len()
+		all := make([]located, 0, n)
+		for i := range n {
+			view, ok := idx.ViewAt(i)
+			if !ok || view.DataSize() == 0 || view.Mode().IsDir() {
+				continue
+			}
+			all = append(all, located{pos: i, offset: view.DataOffset()})
+		}
+		slices.SortFunc(all, func(a, b located) int {
+			return cmp.Compare(a.offset, b.offset)
+		})
+		c.positions = make([]int, len(all))
+		c.offsets = make([]uint64, len(all))
+		for i, l := range all {
+			c.positions[i] = l.pos
+			c.offsets[i] = l.offset
+		}
+	})
+}
+
+// chunkEntries returns the entries whose data lies entirely within the
+// chunk-aligned window containing entry's data offset. The entry itself is
+// always included, even when it is larger than a chunk.
+func (b *Blob) chunkEntries(entry *blobtype.Entry) []*batch.Entry {
+	chunk := b.chunkBytes
+	start := entry.DataOffset - entry.DataOffset%chunk
+	end := start + chunk
+	if end < start { // overflow at the end of the address space
+		end = ^uint64(0)
+	}
+
+	c := b.chunks
+	c.build(b.idx)
+	entries := []*batch.Entry{entry}
+	seen := map[string]struct{}{string(entry.Hash): {}}
+	i, _ := slices.BinarySearch(c.offsets, start)
+	for ; i < len(c.offsets) && c.offsets[i] < end; i++ {
+		view, ok := b.idx.ViewAt(c.positions[i])
+		if !ok || view.DataOffset()+view.DataSize() > end {
+			continue
+		}
+		neighbor := blobtype.EntryFromViewWithPath(view, view.Path())
+		if _, dup := seen[string(neighbor.Hash)]; dup {
+			continue
+		}
+		seen[string(neighbor.Hash)] = struct{}{}
+		entries = append(entries, &neighbor)
+	}
+	return entries
+}
+
+// prefetchChunk caches entry together with its neighbors in the same chunk.
+// Failures are logged; callers fall back to reading entry on its own.
+func (b *Blob) prefetchChunk(ctx context.Context, entry *blobtype.Entry) error {
+	entries := b.chunkEntries(entry)
+	b.log().Debug("chunk prefetch", "path", entry.Path, "entries", len(entries))
+	err := b.cacheEntries(ctx, entries)
+	if err != nil {
+		b.log().Debug("chunk prefetch failed", "path", entry.Path, "error", err)
+	}
+	return err
+}
@@ -0,0 +1,111 @@
+package blob
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/meigma/blob/core/testutil"
+)
+
+func TestChunkedPrefetch(t *testing.T) {
+	t.Parallel()
+
+	// 20 files of 100 bytes stored uncompressed, so offsets are predictable.
+	files := make(map[string][]byte)
+	for i := range 20 {
+		files[fmt.Sprintf("f%02d.txt", i)] = bytes.Repeat([]byte{byte('a' + i)}, 100)
+	}
+	var indexBuf, dataBuf bytes.Buffer
+	dir := t.TempDir()
+	createTestFilesBytes(t, dir, files)
+	require.NoError(t, Create(context.Background(), dir, &indexBuf, &dataBuf))
+
+	newBlob := func(t *testing.T, opts ...Option) (*Blob, *countingSource) {
+		t.Helper()
+		src := newCountingSource(testutil.NewMockByteSource(dataBuf.Bytes()))
+		opts = append([]Option{WithCache(testutil.NewMockCache())}, opts...)
+		b, err := New(indexBuf.Bytes(), src, opts...)
+		require.NoError(t, err)
+		return b, src
+	}
+
+	inChunk := func(b *Blob, name string, chunk uint64) bool {
+		view, ok := b.Entry(name)
+		return ok && view.DataOffset()/chunk == 0 && view.DataOffset()+view.DataSize() <= chunk
+	}
+
+	t.Run("read warms neighbors", func(t *testing.T) {
+		t.Parallel()
+		const chunk = 1000
+		b, src := newBlob(t, WithChunkedPrefetch(chunk))
+
+		content, err := b.ReadFile("f03.txt")
+		require.NoError(t, err)
+		assert.Equal(t, files["f03.txt"], content)
+		assert.Equal(t, int64(1), src.RangeRequests())
+		assert.Equal(t, int64(chunk), src.BytesRead())
+
+		src.Reset()
+		warmed := 0
+		for name, want := range files {
+			if !inChunk(b, name, chunk) {
+				continue
+			}
+			warmed++
+			got, err := b.ReadFile(name)
+			require.NoError(t, err)
+			assert.Equal(t, want, got)
+		}
+		assert.Equal(t, 10, warmed)
+		assert.Zero(t, src.RangeRequests())
+
+		// Files outside the chunk are still fetched on demand.
+		_, err = b.ReadFile("f15.txt")
+		require.NoError(t, err)
+		assert.Equal(t, int64(1), src.RangeRequests())
+	})
+
+	t.Run("open warms neighbors", func(t *testing.T) {
+		t.Parallel()
+		b, src := newBlob(t, WithChunkedPrefetch(500))
+
+		f, err := b.Open("f00.txt")
+		require.NoError(t, err)
+		_, err = io.Copy(io.Discard, f)
+		require.NoError(t, err)
+		require.NoError(t, f.Close())
+
+		src.Reset()
+		_, err = b.ReadFile("f04.txt")
+		require.NoError(t, err)
+		assert.Zero(t, src.RangeRequests())
+	})
+
+	t.Run("file larger than chunk", func(t *testing.T) {
+		t.Parallel()
+		b, src := newBlob(t, WithChunkedPrefetch(64))
+
+		content, err := b.ReadFile("f01.txt")
+		require.NoError(t, err)
+		assert.Equal(t, files["f01.txt"], content)
+		assert.Equal(t, int64(100), src.BytesRead())
+	})
+
+	t.Run("disabled", func(t *testing.T) {
+		t.Parallel()
+		b, src := newBlob(t)
+
+		_, err := b.ReadFile("f00.txt")
+		require.NoError(t, err)
+		src.Reset()
+		_, err = b.ReadFile("f01.txt")
+		require.NoError(t, err)
+		assert.Equal(t, int64(1), src.RangeRequests())
+	})
+}
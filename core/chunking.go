@@ -0,0 +1,195 @@
+package blob
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"fmt"
+	"io"
+	"io/fs"
+
+	"github.com/meigma/blob/core/internal/file"
+	"github.com/meigma/blob/core/internal/sizing"
+)
+
+// readChunked reads an entry stored with CreateWithChunking through the
+// cache with a chunkedFile and verifies the content against the entry's
+// hash.
+func (b *Blob) readChunked(ctx context.Context, entry *Entry) ([]byte, error) {
+	f, err := b.openChunked(ctx, entry)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	size, err := sizing.ToInt(entry.OriginalSize, ErrSizeOverflow)
+	if err != nil {
+		return nil, fmt.Errorf("read %s: %w", entry.Path, err)
+	}
+	content := make([]byte, size)
+	if _, err := io.ReadFull(f, content); err != nil {
+		return nil, err
+	}
+	if err := file.EnsureNoExtra(f); err != nil {
+		return nil, err
+	}
+	if sum := sha256.Sum256(content); !bytes.Equal(sum[:], entry.Hash) {
+		return nil, ErrHashMismatch
+	}
+	return content, nil
+}
+
+// openChunked returns a chunkedFile for an entry stored with
+// CreateWithChunking.
+func (b *Blob) openChunked(ctx context.Context, entry *Entry) (*chunkedFile, error) {
+	if err := file.ValidateChunks(entry); err != nil {
+		return nil, fmt.Errorf("read %s: %w", entry.Path, err)
+	}
+	if limit := b.reader.MaxFileSize(); limit > 0 && entry.OriginalSize > limit {
+		return nil, fmt.Errorf("read %s: %w", entry.Path, ErrSizeOverflow)
+	}
+	return &chunkedFile{b: b, ctx: ctx, entry: entry}, nil
+}
+
+// chunkedFile streams a chunked entry through the cache one chunk at a
+// time, so only the current chunk is held in memory. Chunks are cached
+// under their own hashes: chunks cached by an earlier read of this or
+// another archive are reused, and each run of missing chunks is fetched
+// with a single read. Every chunk is verified against its hash before it
+// is returned; verifying the whole-file hash is left to the caller.
+type chunkedFile struct {
+	b     *Blob
+	ctx   context.Context
+	entry *Entry
+
+	next    int           // index of the next chunk to load
+	chunk   []byte        // unread bytes of the current chunk
+	buf     []byte        // backing storage for chunk
+	run     io.ReadCloser // decoded stream of the missing chunks being fetched
+	runEnd  int           // index of the first chunk after run
+	fetched int
+	err     error
+}
+
+// Read implements io.Reader.
+func (f *chunkedFile) Read(p []byte) (int, error) {
+	for len(f.chunk) == 0 {
+		if f.err != nil {
+			return 0, f.err
+		}
+		f.err = f.load()
+	}
+	n := copy(p, f.chunk)
+	f.chunk = f.chunk[n:]
+	return n, nil
+}
+
+// load makes the next chunk current, from the cache when it holds the
+// chunk and from the data source otherwise. It returns io.EOF after the
+// last chunk.
+func (f *chunkedFile) load() error {
+	chunks := f.entry.Chunks
+	if f.next == len(chunks) {
+		f.b.log().Debug("chunked read", "path", f.entry.Path, "chunks", len(chunks), "fetched", f.fetched)
+		return io.EOF
+	}
+	c := &chunks[f.next]
+	if cap(f.buf) < int(c.OriginalSize) {
+		f.buf = make([]byte, c.OriginalSize)
+	}
+	part := f.buf[:c.OriginalSize]
+
+	if f.run == nil {
+		if f.b.readCachedChunk(c.Hash, part) {
+			f.next++
+			f.chunk = part
+			return nil
+		}
+		end := f.next + 1
+		for end < len(chunks) && !f.b.hasCachedChunk(chunks[end].Hash) {
+			end++
+		}
+		run, err := f.b.reader.OpenChunksContext(f.ctx, f.entry, f.next, end)
+		if err != nil {
+			return err
+		}
+		f.run, f.runEnd = run, end
+	}
+
+	if _, err := io.ReadFull(f.run, part); err != nil {
+		return err
+	}
+	if sum := sha256.Sum256(part); !bytes.Equal(sum[:], c.Hash) {
+		return ErrHashMismatch
+	}
+	_ = f.b.cache.Put(c.Hash, &bytesFile{ //nolint:errcheck // caching is opportunistic
+		Reader: bytes.NewReader(part),
+		size:   int64(len(part)),
+	})
+	f.fetched++
+	f.next++
+	if f.next == f.runEnd {
+		err := file.EnsureNoExtra(f.run)
+		f.closeRun()
+		if err != nil {
+			return err
+		}
+	}
+	f.chunk = part
+	return nil
+}
+
+func (f *chunkedFile) closeRun() {
+	if f.run != nil {
+		_ = f.run.Close()
+		f.run = nil
+	}
+}
+
+// Stat returns file info from the entry metadata.
+func (f *chunkedFile) Stat() (fs.FileInfo, error) {
+	return file.NewInfo(f.entry, file.Base(f.entry.Path))
+}
+
+// Close releases any chunk run in progress.
+func (f *chunkedFile) Close() error {
+	f.closeRun()
+	f.chunk = nil
+	if f.err == nil {
+		f.err = fs.ErrClosed
+	}
+	return nil
+}
+
+// hasCachedChunk reports whether the cache holds the chunk whose hash is
+// sum, without reading it.
+func (b *Blob) hasCachedChunk(sum []byte) bool {
+	f, ok := b.cache.Get(sum)
+	if ok {
+		_ = f.Close()
+	}
+	return ok
+}
+
+// readCachedChunk fills dst with the cached chunk whose hash is sum. It
+// reports false when the chunk is not cached or the cached copy is not
+// exactly the chunk; a corrupt copy is removed from the cache.
+func (b *Blob) readCachedChunk(sum, dst []byte) bool {
+	f, ok := b.cache.Get(sum)
+	if !ok {
+		return false
+	}
+	defer f.Close()
+
+	if _, err := io.ReadFull(f, dst); err != nil {
+		return false
+	}
+	if file.EnsureNoExtra(f) != nil {
+		return false
+	}
+	if got := sha256.Sum256(dst); !bytes.Equal(got[:], sum) {
+		_ = b.cache.Delete(sum) //nolint:errcheck // best-effort cache cleanup on hash mismatch
+		return false
+	}
+	return true
+}
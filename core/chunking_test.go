@@ -0,0 +1,283 @@
+package blob
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"io"
+	"sync/atomic"
+	"testing"
+	"testing/fstest"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/meigma/blob/core/testutil"
+)
+
+// bytesReadSource counts the bytes read from a ByteSource.
+type bytesReadSource struct {
+	ByteSource
+	n atomic.Int64
+}
+
+func (s *bytesReadSource) ReadAt(p []byte, off int64) (int, error) {
+	n, err := s.ByteSource.ReadAt(p, off)
+	s.n.Add(int64(n))
+	return n, err
+}
+
+// chunkTestContent returns size bytes of content that compresses, but not
+// trivially, and has no repeating structure that aligns chunk boundaries.
+func chunkTestContent(t *testing.T, size int) []byte {
+	t.Helper()
+
+	content := make([]byte, size)
+	_, err := rand.Read(content)
+	require.NoError(t, err)
+	for i := range content {
+		content[i] = 'a' + content[i]%8
+	}
+	return content
+}
+
+func createChunked(t *testing.T, content []byte, opts ...CreateOption) (index, data []byte) {
+	t.Helper()
+
+	var indexBuf, dataBuf bytes.Buffer
+	fsys := fstest.MapFS{
+		"big.bin":   {Data: content},
+		"small.txt": {Data: []byte("below the chunking threshold")},
+	}
+	require.NoError(t, CreateFS(context.Background(), fsys, &indexBuf, &dataBuf, opts...))
+	return indexBuf.Bytes(), dataBuf.Bytes()
+}
+
+func chunkHashes(t *testing.T, index []byte) map[string]bool {
+	t.Helper()
+
+	b, err := New(index, testutil.NewMockByteSource(nil))
+	require.NoError(t, err)
+	view, ok := b.Entry("big.bin")
+	require.True(t, ok)
+	hashes := make(map[string]bool)
+	for _, c := range view.Chunks() {
+		hashes[string(c.Hash)] = true
+	}
+	return hashes
+}
+
+func TestCreateWithChunking(t *testing.T) {
+	t.Parallel()
+
+	v1 := chunkTestContent(t, 1<<20)
+	v2 := bytes.Clone(v1)
+	copy(v2[300_000:], "a small edit")
+	v2 = append(v2[:700_000:700_000], append([]byte("an insertion"), v2[700_000:]...)...)
+
+	for _, compression := range []Compression{CompressionNone, CompressionZstd} {
+		t.Run(compression.String(), func(t *testing.T) {
+			t.Parallel()
+
+			index1, data1 := createChunked(t, v1, CreateWithChunking(4<<10), CreateWithCompression(compression))
+			index2, data2 := createChunked(t, v2, CreateWithChunking(4<<10), CreateWithCompression(compression))
+
+			b, err := New(index1, testutil.NewMockByteSource(data1))
+			require.NoError(t, err)
+			view, ok := b.Entry("big.bin")
+			require.True(t, ok)
+			chunks := view.Chunks()
+			require.Greater(t, len(chunks), 100)
+			var original uint64
+			for i, c := range chunks {
+				// Only the final chunk may fall short of the minimum size.
+				if i < len(chunks)-1 {
+					assert.GreaterOrEqual(t, c.OriginalSize, uint32(1<<10))
+				}
+				assert.LessOrEqual(t, c.OriginalSize, uint32(16<<10))
+				original += uint64(c.OriginalSize)
+			}
+			assert.Equal(t, view.OriginalSize(), original)
+			assert.Equal(t, compression, view.Compression())
+			small, ok := b.Entry("small.txt")
+			require.True(t, ok)
+			assert.Empty(t, small.Chunks(), "small files are not chunked")
+
+			// A small edit and insertion change only the chunks around them.
+			h1, h2 := chunkHashes(t, index1), chunkHashes(t, index2)
+			changed := 0
+			for h := range h2 {
+				if !h1[h] {
+					changed++
+				}
+			}
+			assert.Positive(t, changed)
+			assert.LessOrEqual(t, changed, 6, "of %d chunks", len(h2))
+
+			// Readers that ignore chunks read the entry as a whole.
+			got, err := b.ReadFile("big.bin")
+			require.NoError(t, err)
+			assert.Equal(t, v1, got)
+			b2, err := New(index2, testutil.NewMockByteSource(data2))
+			require.NoError(t, err)
+			got, err = b2.ReadFile("big.bin")
+			require.NoError(t, err)
+			assert.Equal(t, v2, got)
+		})
+	}
+}
+
+func TestChunkedReadThroughCache(t *testing.T) {
+	t.Parallel()
+
+	v1 := chunkTestContent(t, 1<<20)
+	v2 := bytes.Clone(v1)
+	copy(v2[500_000:], "a small edit")
+
+	for _, compression := range []Compression{CompressionNone, CompressionZstd} {
+		t.Run(compression.String(), func(t *testing.T) {
+			t.Parallel()
+
+			cache := testutil.NewMockCache()
+			open := func(content []byte) (*Blob, *bytesReadSource) {
+				index, data := createChunked(t, content, CreateWithChunking(4<<10), CreateWithCompression(compression))
+				source := &bytesReadSource{ByteSource: testutil.NewMockByteSource(data)}
+				b, err := New(index, source, WithCache(cache))
+				require.NoError(t, err)
+				return b, source
+			}
+			b1, _ := open(v1)
+			b2, source2 := open(v2)
+
+			got, err := b1.ReadFile("big.bin")
+			require.NoError(t, err)
+			assert.Equal(t, v1, got)
+
+			// Only the edited chunks of v2 are fetched; the rest are shared.
+			got, err = b2.ReadFile("big.bin")
+			require.NoError(t, err)
+			assert.Equal(t, v2, got)
+			assert.Positive(t, source2.n.Load())
+			assert.Less(t, source2.n.Load(), int64(64<<10))
+
+			// Reads are served from cached chunks from now on.
+			before := source2.n.Load()
+			f, err := b2.Open("big.bin")
+			require.NoError(t, err)
+			got, err = io.ReadAll(f)
+			require.NoError(t, err)
+			require.NoError(t, f.Close())
+			assert.Equal(t, v2, got)
+			assert.Equal(t, before, source2.n.Load())
+		})
+	}
+
+	t.Run("open streams", func(t *testing.T) {
+		t.Parallel()
+
+		index, data := createChunked(t, v1, CreateWithChunking(4<<10), CreateWithCompression(CompressionNone))
+		source := &bytesReadSource{ByteSource: testutil.NewMockByteSource(data)}
+		b, err := New(index, source, WithCache(testutil.NewMockCache()))
+		require.NoError(t, err)
+
+		// Reading the start of a file fetches only the chunks around it.
+		f, err := b.Open("big.bin")
+		require.NoError(t, err)
+		head := make([]byte, 10<<10)
+		_, err = io.ReadFull(f, head)
+		require.NoError(t, err)
+		assert.Equal(t, v1[:len(head)], head)
+		assert.Less(t, source.n.Load(), int64(len(v1)/4))
+		require.NoError(t, f.Close(), "closing verifies the rest")
+
+		// The rest is fetched, chunk by chunk, and verified as a whole.
+		f, err = b.Open("big.bin")
+		require.NoError(t, err)
+		got, err := io.ReadAll(f)
+		require.NoError(t, err)
+		require.NoError(t, f.Close())
+		assert.Equal(t, v1, got)
+	})
+
+	t.Run("corrupt data", func(t *testing.T) {
+		t.Parallel()
+
+		index, data := createChunked(t, v1, CreateWithChunking(4<<10), CreateWithCompression(CompressionNone))
+		b, err := New(index, testutil.NewMockByteSource(data))
+		require.NoError(t, err)
+		view, ok := b.Entry("big.bin")
+		require.True(t, ok)
+		data[view.DataOffset()+100_000] ^= 0xff
+
+		for _, opts := range [][]Option{nil, {WithCache(testutil.NewMockCache())}} {
+			b, err := New(index, testutil.NewMockByteSource(data), opts...)
+			require.NoError(t, err)
+			_, err = b.ReadFile("big.bin")
+			require.ErrorIs(t, err, ErrHashMismatch)
+		}
+	})
+
+	t.Run("corrupt cached chunk", func(t *testing.T) {
+		t.Parallel()
+
+		index, data := createChunked(t, v1, CreateWithChunking(4<<10))
+		cache := testutil.NewMockCache()
+		b, err := New(index, testutil.NewMockByteSource(data), WithCache(cache))
+		require.NoError(t, err)
+		view, ok := b.Entry("big.bin")
+		require.True(t, ok)
+		chunk := view.Chunks()[3]
+		require.NoError(t, cache.Put(chunk.Hash, &bytesFile{Reader: bytes.NewReader([]byte("garbage")), size: 7}))
+
+		got, err := b.ReadFile("big.bin")
+		require.NoError(t, err)
+		assert.Equal(t, v1, got)
+		f, ok := cache.Get(chunk.Hash)
+		require.True(t, ok)
+		cached, err := io.ReadAll(f)
+		require.NoError(t, err)
+		assert.Len(t, cached, int(chunk.OriginalSize), "the corrupt chunk is replaced")
+	})
+}
+
+func TestUpdateKeepsChunks(t *testing.T) {
+	t.Parallel()
+
+	content := chunkTestContent(t, 256<<10)
+	index, data := createChunked(t, content, CreateWithChunking(4<<10))
+	base, err := New(index, testutil.NewMockByteSource(data))
+	require.NoError(t, err)
+
+	var indexBuf, dataBuf bytes.Buffer
+	changes := []FileChange{{Path: "added.bin", Content: chunkTestContent(t, 64<<10)}}
+	require.NoError(t, Update(context.Background(), base, &indexBuf, &dataBuf, changes, CreateWithChunking(4<<10)))
+
+	b, err := New(indexBuf.Bytes(), testutil.NewMockByteSource(dataBuf.Bytes()), WithCache(testutil.NewMockCache()))
+	require.NoError(t, err)
+	for name, want := range map[string][]byte{"big.bin": content, "added.bin": changes[0].Content} {
+		view, ok := b.Entry(name)
+		require.True(t, ok, name)
+		assert.NotEmpty(t, view.Chunks(), name)
+		got, err := b.ReadFile(name)
+		require.NoError(t, err, name)
+		assert.Equal(t, want, got, name)
+	}
+}
+
+func TestCreateWithChunkingInvalid(t *testing.T) {
+	t.Parallel()
+
+	for name, opts := range map[string][]CreateOption{
+		"too small":  {CreateWithChunking(100)},
+		"too large":  {CreateWithChunking(1 << 30)},
+		"encryption": {CreateWithChunking(4 << 10), CreateWithEncryption(make([]byte, 32), EncryptionAESGCM)},
+	} {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			var indexBuf, dataBuf bytes.Buffer
+			err := CreateFS(context.Background(), fstest.MapFS{"a": {Data: []byte("a")}}, &indexBuf, &dataBuf, opts...)
+			require.Error(t, err)
+		})
+	}
+}
@@ -0,0 +1,60 @@
+package blob
+
+import (
+	"fmt"
+	"path"
+	"strings"
+)
+
+// copyFilter selects entries for CopyDir using include and exclude patterns.
+type copyFilter struct {
+	include []string
+	exclude []string
+}
+
+// active reports whether any patterns are configured.
+func (f *copyFilter) active() bool {
+	return len(f.include) > 0 || len(f.exclude) > 0
+}
+
+// validate checks that all patterns are well formed.
+func (f *copyFilter) validate() error {
+	for _, patterns := range [][]string{f.include, f.exclude} {
+		for _, pattern := range patterns {
+			if _, err := path.Match(pattern, ""); err != nil {
+				return fmt.Errorf("copy filter %q: %w", pattern, err)
+			}
+		}
+	}
+	return nil
+}
+
+// match reports whether the entry at rel should be copied.
+// Exclude patterns take precedence over include patterns.
+func (f *copyFilter) match(rel string) bool {
+	for _, pattern := range f.exclude {
+		if matchPattern(pattern, rel) {
+			return false
+		}
+	}
+	if len(f.include) == 0 {
+		return true
+	}
+	for _, pattern := range f.include {
+		if matchPattern(pattern, rel) {
+			return true
+		}
+	}
+	return false
+}
+
+// matchPattern matches rel against pattern using path.Match. Patterns
+// without a slash match the base name, so "*.so" selects files at any depth.
+// Patterns are validated up front, so match errors cannot occur.
+func matchPattern(pattern, rel string) bool {
+	if !strings.Contains(pattern, "/") {
+		rel = path.Base(rel)
+	}
+	ok, _ := path.Match(pattern, rel) //nolint:errcheck // patterns validated by copyFilter.validate
+	return ok
+}
@@ -0,0 +1,115 @@
+package blob
+
+import (
+	"fmt"
+	"sync"
+
+	"github.com/meigma/blob/core/internal/batch"
+)
+
+// ExtractionLimit identifies which extraction limit was exceeded.
+type ExtractionLimit string
+
+// Extraction limits.
+const (
+	// ExtractionLimitFiles is the limit set by CopyWithMaxFiles.
+	ExtractionLimitFiles ExtractionLimit = "files"
+
+	// ExtractionLimitBytes is the limit set by CopyWithMaxTotalBytes.
+	ExtractionLimitBytes ExtractionLimit = "bytes"
+)
+
+// ExtractionLimitError describes an extraction aborted by a limit.
+//
+// It matches ErrExtractionLimit with errors.Is.
+type ExtractionLimitError struct {
+	Path  string          // The entry that would have exceeded the limit
+	Limit ExtractionLimit // Which limit was exceeded
+	Max   uint64          // The configured limit
+}
+
+func (e *ExtractionLimitError) Error() string {
+	return fmt.Sprintf("%s: extraction exceeds max %s (%d): %s", ErrExtractionLimit, e.Limit, e.Max, e.Path)
+}
+
+// Unwrap returns ErrExtractionLimit.
+func (e *ExtractionLimitError) Unwrap() error {
+	return ErrExtractionLimit
+}
+
+// extractionBudget tracks the files and bytes reserved by an extraction.
+type extractionBudget struct {
+	maxFiles int
+	maxBytes uint64
+
+	mu    sync.Mutex
+	files int
+	bytes uint64
+}
+
+// newExtractionBudget returns a budget for cfg, or nil if no limits are set.
+func newExtractionBudget(cfg *copyConfig) *extractionBudget {
+	if cfg.maxFiles <= 0 && cfg.maxTotalBytes == 0 {
+		return nil
+	}
+	return &extractionBudget{maxFiles: cfg.maxFiles, maxBytes: cfg.maxTotalBytes}
+}
+
+// reserve claims budget for entry, failing if any limit would be exceeded.
+func (b *extractionBudget) reserve(entry *batch.Entry) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.maxFiles > 0 && b.files+1 > b.maxFiles {
+		return &ExtractionLimitError{Path: entry.Path, Limit: ExtractionLimitFiles, Max: uint64(b.maxFiles)}
+	}
+	if b.maxBytes > 0 && (entry.OriginalSize > b.maxBytes || b.bytes > b.maxBytes-entry.OriginalSize) {
+		return &ExtractionLimitError{Path: entry.Path, Limit: ExtractionLimitBytes, Max: b.maxBytes}
+	}
+	b.files++
+	b.bytes += entry.OriginalSize
+	return nil
+}
+
+// release returns budget claimed for an entry that was not written.
+func (b *extractionBudget) release(entry *batch.Entry) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.files--
+	b.bytes -= entry.OriginalSize
+}
+
+// limitSink wraps a Sink and enforces an extraction budget.
+//
+// Budget is reserved before a Committer is created, so entries beyond a
+// limit never create temp files and at most the configured number of
+// files and bytes are committed.
+type limitSink struct {
+	batch.Sink
+	budget *extractionBudget
+}
+
+// Writer reserves budget for entry before delegating to the wrapped Sink.
+func (s *limitSink) Writer(entry *batch.Entry) (batch.Committer, error) {
+	if err := s.budget.reserve(entry); err != nil {
+		return nil, err
+	}
+	w, err := s.Sink.Writer(entry)
+	if err != nil {
+		s.budget.release(entry)
+		return nil, err
+	}
+	return &limitCommitter{Committer: w, entry: entry, budget: s.budget}, nil
+}
+
+// limitCommitter releases its reservation when the write is discarded.
+type limitCommitter struct {
+	batch.Committer
+	entry  *batch.Entry
+	budget *extractionBudget
+}
+
+// Discard aborts the write and releases the entry's reservation.
+func (c *limitCommitter) Discard() error {
+	c.budget.release(c.entry)
+	return c.Committer.Discard()
+}
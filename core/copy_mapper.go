@@ -0,0 +1,68 @@
+package blob
+
+import (
+	"fmt"
+	"io/fs"
+	"path"
+	"strings"
+
+	"github.com/meigma/blob/core/internal/batch"
+)
+
+// PathMapper rewrites an entry's destination during extraction.
+//
+// srcPath is the slash-separated path the entry would be written to,
+// relative to the destination directory: the archive path for CopyTo, and
+// the path relative to the prefix for CopyDir. The mapper returns the path
+// to write instead, also relative to the destination directory, or skip=true
+// to drop the entry. A non-nil error aborts the copy before any file is
+// written.
+type PathMapper func(srcPath string) (destRelPath string, skip bool, err error)
+
+// mapEntries applies mapper to entries, returning the kept entries with
+// their paths rewritten and the number dropped. Mapped paths are cleaned,
+// must stay within the destination directory, may not contain backslashes, and
+// must differ between entries.
+func mapEntries(entries []*batch.Entry, mapper PathMapper) (kept []*batch.Entry, dropped int, err error) {
+	kept = entries[:0:0]
+	sources := make(map[string]string, len(entries)) // mapped path -> source path
+	for _, entry := range entries {
+		dest, skip, err := mapper(entry.Path)
+		if err != nil {
+			return nil, 0, fmt.Errorf("map path %s: %w", entry.Path, err)
+		}
+		if skip {
+			dropped++
+			continue
+		}
+		mapped := path.Clean(dest)
+		if !fs.ValidPath(mapped) || mapped == "." {
+			return nil, 0, &fs.PathError{
+				Op:   "copy",
+				Path: entry.Path,
+				Err:  fmt.Errorf("mapped path %q escapes destination: %w", dest, fs.ErrInvalid),
+			}
+		}
+		if strings.Contains(mapped, `\`) {
+			return nil, 0, &fs.PathError{
+				Op:   "copy",
+				Path: entry.Path,
+				Err:  fmt.Errorf("mapped path %q contains a backslash: %w", dest, fs.ErrInvalid),
+			}
+		}
+		if other, ok := sources[mapped]; ok {
+			return nil, 0, &fs.PathError{
+				Op:   "copy",
+				Path: mapped,
+				Err:  fmt.Errorf("%w: %s and %s map to the same path", ErrPathConflict, other, entry.Path),
+			}
+		}
+		sources[mapped] = entry.Path
+		if entry.IndexPath == "" {
+			entry.IndexPath = entry.Path
+		}
+		entry.Path = mapped
+		kept = append(kept, entry)
+	}
+	return kept, dropped, nil
+}
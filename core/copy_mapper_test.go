@@ -0,0 +1,146 @@
+package blob
+
+import (
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCopyWithPathMapper(t *testing.T) {
+	t.Parallel()
+
+	b := createTestArchive(t, map[string][]byte{
+		"config/app.yaml":    []byte("app"),
+		"config/db/pg.yaml":  []byte("pg"),
+		"config/secret.key":  []byte("secret"),
+		"docs/README.md":     []byte("readme"),
+		"bin/tool":           []byte("tool"),
+		"bin/tool.debug.map": []byte("map"),
+	}, CompressionNone)
+
+	t.Run("prefix and skip", func(t *testing.T) {
+		t.Parallel()
+
+		mapper := func(src string) (string, bool, error) {
+			switch {
+			case strings.HasSuffix(src, ".key"), strings.HasSuffix(src, ".map"):
+				return "", true, nil
+			case strings.HasPrefix(src, "config/"):
+				return "etc/app/" + strings.TrimPrefix(src, "config/"), false, nil
+			default:
+				return src, false, nil
+			}
+		}
+
+		dest := t.TempDir()
+		stats, err := b.CopyDir(dest, ".", CopyWithPathMapper(mapper))
+		require.NoError(t, err)
+		assert.Equal(t, 4, stats.FileCount)
+		assert.Equal(t, 2, stats.Filtered)
+
+		var got []string
+		err = filepath.WalkDir(dest, func(path string, d fs.DirEntry, err error) error {
+			if err != nil || d.IsDir() {
+				return err
+			}
+			rel, err := filepath.Rel(dest, path)
+			got = append(got, filepath.ToSlash(rel))
+			return err
+		})
+		require.NoError(t, err)
+		assert.ElementsMatch(t, []string{
+			"etc/app/app.yaml",
+			"etc/app/db/pg.yaml",
+			"docs/README.md",
+			"bin/tool",
+		}, got)
+
+		content, err := os.ReadFile(filepath.Join(dest, "etc", "app", "db", "pg.yaml"))
+		require.NoError(t, err)
+		assert.Equal(t, []byte("pg"), content)
+	})
+
+	t.Run("CopyToWithOptions", func(t *testing.T) {
+		t.Parallel()
+
+		dest := t.TempDir()
+		stats, err := b.CopyToWithOptions(dest, []string{"docs/README.md"}, CopyWithPathMapper(
+			func(string) (string, bool, error) { return "README.md", false, nil },
+		))
+		require.NoError(t, err)
+		assert.Equal(t, 1, stats.FileCount)
+		_, err = os.Stat(filepath.Join(dest, "README.md"))
+		require.NoError(t, err)
+	})
+
+	for _, escape := range []string{"../outside.txt", "/etc/passwd", "a/../../outside.txt", ".", `..\outside.txt`} {
+		t.Run("rejects "+escape, func(t *testing.T) {
+			t.Parallel()
+
+			parent := t.TempDir()
+			dest := filepath.Join(parent, "dest")
+			require.NoError(t, os.Mkdir(dest, 0o755))
+
+			_, err := b.CopyDir(dest, ".", CopyWithPathMapper(
+				func(src string) (string, bool, error) {
+					if src == "docs/README.md" {
+						return escape, false, nil
+					}
+					return src, false, nil
+				},
+			))
+			require.ErrorIs(t, err, fs.ErrInvalid)
+
+			entries, readErr := os.ReadDir(dest)
+			require.NoError(t, readErr)
+			assert.Empty(t, entries, "nothing should be written")
+			_, statErr := os.Stat(filepath.Join(parent, "outside.txt"))
+			assert.True(t, os.IsNotExist(statErr))
+		})
+	}
+
+	t.Run("rejects collisions", func(t *testing.T) {
+		t.Parallel()
+
+		dest := t.TempDir()
+		_, err := b.CopyDir(dest, ".", CopyWithPathMapper(
+			func(src string) (string, bool, error) {
+				if src == "docs/README.md" {
+					return "./bin//tool", false, nil
+				}
+				return src, false, nil
+			},
+		))
+		require.ErrorIs(t, err, ErrPathConflict)
+		assert.Contains(t, err.Error(), "bin/tool and docs/README.md")
+
+		entries, readErr := os.ReadDir(dest)
+		require.NoError(t, readErr)
+		assert.Empty(t, entries, "nothing should be written")
+	})
+
+	t.Run("mapper error", func(t *testing.T) {
+		t.Parallel()
+
+		errBoom := errors.New("boom")
+		_, err := b.CopyDir(t.TempDir(), ".", CopyWithPathMapper(
+			func(string) (string, bool, error) { return "", false, errBoom },
+		))
+		require.ErrorIs(t, err, errBoom)
+	})
+
+	t.Run("CopyFile unsupported", func(t *testing.T) {
+		t.Parallel()
+
+		_, err := b.CopyFile("bin/tool", filepath.Join(t.TempDir(), "tool"), CopyWithPathMapper(
+			func(src string) (string, bool, error) { return src, false, nil },
+		))
+		require.Error(t, err)
+	})
+}
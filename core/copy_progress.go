@@ -0,0 +1,178 @@
+package blob
+
+import (
+	"fmt"
+	"slices"
+	"sync"
+
+	"github.com/meigma/blob/core/internal/batch"
+)
+
+// ExtractionProgress records the work completed by a resumable extraction.
+type ExtractionProgress struct {
+	// CompletedPaths lists the destination-relative paths of files that were
+	// fully written and committed.
+	CompletedPaths []string
+
+	// CompletedBytes is the sum of original (uncompressed) sizes of the
+	// completed files.
+	CompletedBytes uint64
+}
+
+// ProgressStore persists extraction progress outside the current process.
+//
+// Stores enable an extraction interrupted in one process to be resumed in
+// another: completed files are skipped without issuing range reads, and the
+// saved progress can drive a progress bar that survives worker restarts.
+//
+// Save is called after every 64 committed files and once more when the
+// extraction ends, whether or not it succeeded, so a resumed extraction
+// repeats at most the files committed since the last save. Calls are never
+// concurrent. Save may retain the CompletedPaths slice but must not modify
+// it.
+type ProgressStore interface {
+	// Load returns previously saved progress.
+	// A store without saved progress returns a zero ExtractionProgress.
+	Load() (ExtractionProgress, error)
+
+	// Save persists the current progress, replacing any previous state.
+	Save(progress ExtractionProgress) error
+}
+
+// progressSaveBatch is the number of committed files between saves.
+const progressSaveBatch = 64
+
+// progressTracker records committed entries and forwards them to a
+// ProgressStore in batches.
+type progressTracker struct {
+	store     ProgressStore
+	mu        sync.Mutex
+	progress  ExtractionProgress
+	completed map[string]struct{}
+	pending   int // files recorded since the last snapshot
+
+	saveMu sync.Mutex // serializes Save calls
+	saved  int        // len(CompletedPaths) of the last saved snapshot
+}
+
+// loadProgressTracker loads prior progress from store.
+func loadProgressTracker(store ProgressStore) (*progressTracker, error) {
+	progress, err := store.Load()
+	if err != nil {
+		return nil, fmt.Errorf("load extraction progress: %w", err)
+	}
+	completed := make(map[string]struct{}, len(progress.CompletedPaths))
+	for _, path := range progress.CompletedPaths {
+		completed[path] = struct{}{}
+	}
+	progress.CompletedPaths = append([]string(nil), progress.CompletedPaths...)
+	return &progressTracker{
+		store:     store,
+		progress:  progress,
+		completed: completed,
+		saved:     len(progress.CompletedPaths),
+	}, nil
+}
+
+// filter removes entries already recorded as completed.
+// Returns the remaining entries and the removed ones.
+func (t *progressTracker) filter(entries []*batch.Entry) (remaining, resumed []*batch.Entry) {
+	remaining = entries[:0]
+	for _, entry := range entries {
+		if _, ok := t.completed[entry.Path]; ok {
+			resumed = append(resumed, entry)
+			continue
+		}
+		remaining = append(remaining, entry)
+	}
+	return remaining, resumed
+}
+
+// record marks entry as completed, saving the progress once a batch of
+// files has accumulated.
+func (t *progressTracker) record(entry *batch.Entry) error {
+	t.mu.Lock()
+	if _, ok := t.completed[entry.Path]; ok {
+		t.mu.Unlock()
+		return nil
+	}
+	t.completed[entry.Path] = struct{}{}
+	t.progress.CompletedPaths = append(t.progress.CompletedPaths, entry.Path)
+	t.progress.CompletedBytes += entry.OriginalSize
+	t.pending++
+	if t.pending < progressSaveBatch {
+		t.mu.Unlock()
+		return nil
+	}
+	snapshot := t.snapshotLocked()
+	t.mu.Unlock()
+	return t.save(snapshot)
+}
+
+// flush saves any progress recorded since the last save.
+func (t *progressTracker) flush() error {
+	t.mu.Lock()
+	if t.pending == 0 {
+		t.mu.Unlock()
+		return nil
+	}
+	snapshot := t.snapshotLocked()
+	t.mu.Unlock()
+	return t.save(snapshot)
+}
+
+// snapshotLocked returns the current progress and starts a new batch.
+// Paths are append-only, so a clipped slice is a stable snapshot.
+func (t *progressTracker) snapshotLocked() ExtractionProgress {
+	t.pending = 0
+	return ExtractionProgress{
+		CompletedPaths: slices.Clip(t.progress.CompletedPaths),
+		CompletedBytes: t.progress.CompletedBytes,
+	}
+}
+
+// save writes snapshot to the store outside the recording lock, so workers
+// keep committing files while a slow store saves. A snapshot older than
+// one already saved is dropped.
+func (t *progressTracker) save(snapshot ExtractionProgress) error {
+	t.saveMu.Lock()
+	defer t.saveMu.Unlock()
+	if len(snapshot.CompletedPaths) <= t.saved {
+		return nil
+	}
+	if err := t.store.Save(snapshot); err != nil {
+		return fmt.Errorf("save extraction progress: %w", err)
+	}
+	t.saved = len(snapshot.CompletedPaths)
+	return nil
+}
+
+// trackingSink wraps a Sink and records each committed entry.
+type trackingSink struct {
+	batch.Sink
+	tracker *progressTracker
+}
+
+// Writer returns a Committer that records progress after a successful commit.
+func (s *trackingSink) Writer(entry *batch.Entry) (batch.Committer, error) {
+	w, err := s.Sink.Writer(entry)
+	if err != nil {
+		return nil, err
+	}
+	return &trackingCommitter{Committer: w, entry: entry, tracker: s.tracker}, nil
+}
+
+// trackingCommitter records progress once the wrapped Committer commits.
+type trackingCommitter struct {
+	batch.Committer
+	entry   *batch.Entry
+	tracker *progressTracker
+}
+
+// Commit finalizes the write and records the entry as completed.
+func (c *trackingCommitter) Commit() error {
+	if err := c.Committer.Commit(); err != nil {
+		return err
+	}
+	return c.tracker.record(c.entry)
+}
@@ -0,0 +1,150 @@
+package blob
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// memProgressStore is an in-memory ProgressStore shared across "processes".
+type memProgressStore struct {
+	mu       sync.Mutex
+	progress ExtractionProgress
+	saves    int
+	saveErr  error
+}
+
+func (s *memProgressStore) Load() (ExtractionProgress, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return ExtractionProgress{
+		CompletedPaths: append([]string(nil), s.progress.CompletedPaths...),
+		CompletedBytes: s.progress.CompletedBytes,
+	}, nil
+}
+
+func (s *memProgressStore) Save(progress ExtractionProgress) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.saveErr != nil {
+		return s.saveErr
+	}
+	s.progress = progress
+	s.saves++
+	return nil
+}
+
+func TestCopyWithProgressStore_Resume(t *testing.T) {
+	t.Parallel()
+
+	files := map[string][]byte{
+		"a.txt":     []byte("aaaa"),
+		"b.txt":     []byte("bbbbbbbb"),
+		"dir/c.txt": []byte("cccccccccccc"),
+	}
+	b := createTestArchive(t, files, CompressionZstd)
+	store := &memProgressStore{}
+	destDir := t.TempDir()
+
+	// First worker extracts a single file before "crashing".
+	stats, err := b.CopyToWithOptions(destDir, []string{"a.txt"}, CopyWithProgressStore(store))
+	require.NoError(t, err)
+	assert.Equal(t, 1, stats.FileCount)
+	assert.Equal(t, []string{"a.txt"}, store.progress.CompletedPaths)
+	assert.Equal(t, uint64(4), store.progress.CompletedBytes)
+
+	// Tamper with the extracted file so a re-extraction would be visible.
+	sentinel := []byte("sentinel")
+	require.NoError(t, os.WriteFile(filepath.Join(destDir, "a.txt"), sentinel, 0o644))
+
+	// Second worker resumes with the same store and overwrite enabled.
+	stats, err = b.CopyDir(destDir, "", CopyWithOverwrite(true), CopyWithProgressStore(store))
+	require.NoError(t, err)
+	assert.Equal(t, 2, stats.FileCount)
+	assert.Equal(t, 1, stats.Skipped)
+	assert.Equal(t, uint64(20), stats.TotalBytes)
+
+	got, err := os.ReadFile(filepath.Join(destDir, "a.txt"))
+	require.NoError(t, err)
+	assert.Equal(t, sentinel, got, "completed file should not be re-extracted")
+	got, err = os.ReadFile(filepath.Join(destDir, "dir", "c.txt"))
+	require.NoError(t, err)
+	assert.Equal(t, files["dir/c.txt"], got)
+
+	assert.ElementsMatch(t, []string{"a.txt", "b.txt", "dir/c.txt"}, store.progress.CompletedPaths)
+	assert.Equal(t, uint64(24), store.progress.CompletedBytes)
+	assert.Equal(t, 2, store.saves, "one save at the end of each run")
+
+	// A third run has nothing left to do.
+	stats, err = b.CopyDir(destDir, "", CopyWithProgressStore(store))
+	require.NoError(t, err)
+	assert.Equal(t, 0, stats.FileCount)
+	assert.Equal(t, 3, stats.Skipped)
+}
+
+func TestCopyWithProgressStore_SaveError(t *testing.T) {
+	t.Parallel()
+
+	files := map[string][]byte{
+		"a.txt": []byte("aaaa"),
+	}
+	b := createTestArchive(t, files, CompressionNone)
+	saveErr := errors.New("store unavailable")
+	store := &memProgressStore{saveErr: saveErr}
+
+	_, err := b.CopyDir(t.TempDir(), "", CopyWithProgressStore(store))
+	require.ErrorIs(t, err, saveErr)
+}
+
+func TestCopyWithProgressStore_BatchesSaves(t *testing.T) {
+	t.Parallel()
+
+	files := make(map[string][]byte, 2*progressSaveBatch+1)
+	for i := range 2*progressSaveBatch + 1 {
+		files[fmt.Sprintf("f%03d.txt", i)] = []byte("x")
+	}
+	b := createTestArchive(t, files, CompressionNone)
+	store := &memProgressStore{}
+
+	stats, err := b.CopyDir(t.TempDir(), "", CopyWithProgressStore(store))
+	require.NoError(t, err)
+	assert.Equal(t, len(files), stats.FileCount)
+	assert.Len(t, store.progress.CompletedPaths, len(files))
+	assert.Equal(t, 3, store.saves, "two full batches and a final save")
+}
+
+func TestCopyWithProgressStore_ResumedFilesCountAgainstLimits(t *testing.T) {
+	t.Parallel()
+
+	files := map[string][]byte{
+		"a.txt": []byte("aaaa"),
+		"b.txt": []byte("bbbb"),
+		"c.txt": []byte("cccc"),
+	}
+	b := createTestArchive(t, files, CompressionNone)
+	store := &memProgressStore{}
+	destDir := t.TempDir()
+
+	_, err := b.CopyToWithOptions(destDir, []string{"a.txt", "b.txt"}, CopyWithProgressStore(store))
+	require.NoError(t, err)
+
+	_, err = b.CopyDir(destDir, "", CopyWithProgressStore(store), CopyWithMaxFiles(2))
+	var limitErr *ExtractionLimitError
+	require.ErrorAs(t, err, &limitErr)
+	assert.Equal(t, ExtractionLimitFiles, limitErr.Limit)
+
+	_, err = b.CopyDir(destDir, "", CopyWithProgressStore(store), CopyWithMaxTotalBytes(8))
+	require.ErrorAs(t, err, &limitErr)
+	assert.Equal(t, ExtractionLimitBytes, limitErr.Limit)
+
+	stats, err := b.CopyDir(destDir, "", CopyWithProgressStore(store), CopyWithMaxFiles(3), CopyWithMaxTotalBytes(12))
+	require.NoError(t, err)
+	assert.Equal(t, 1, stats.FileCount)
+	assert.Equal(t, 2, stats.Skipped)
+}
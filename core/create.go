@@ -1,8 +1,12 @@
 package blob
 
 import (
+	"bytes"
 	"context"
+	"crypto/cipher"
+	"crypto/rand"
 	"crypto/sha256"
+	"encoding/hex"
 	"errors"
 	"fmt"
 	"io"
@@ -10,11 +14,17 @@ import (
 	"log/slog"
 	"os"
 	"path/filepath"
+	"slices"
+	"strings"
+	"time"
 
 	flatbuffers "github.com/google/flatbuffers/go"
 	"github.com/klauspost/compress/zstd"
+	"github.com/opencontainers/go-digest"
 
 	"github.com/meigma/blob/core/internal/fb"
+	"github.com/meigma/blob/core/internal/file"
+	"github.com/meigma/blob/core/internal/index"
 	"github.com/meigma/blob/core/internal/platform"
 	"github.com/meigma/blob/core/internal/write"
 )
@@ -28,18 +38,27 @@ const DefaultMaxFiles = 200_000
 // efficient directory fetches via single range requests. The index is
 // written as a FlatBuffers-encoded blob to the index writer.
 //
-// Create builds the entire index in memory; memory use scales with entry
-// count and path length. Rough guide: ~30-50MB for 100k files with ~60B
-// average paths (entries plus FlatBuffers buffer).
+// Create streams file content to the data writer and does not hold the
+// data blob in memory. Entry metadata is kept in memory for the first 65536
+// files and moved to a temporary file (see CreateWithTempDir) beyond that.
+// The FlatBuffers index itself is built in memory before it is written, so
+// peak usage grows with the index size: roughly 10-15MB for 100k files with
+// ~60B average paths. With compression enabled, files up to 16 MiB are
+// staged in memory to check CreateWithMinCompressionRatio, which adds up to
+// that much to peak usage (larger files are judged from a sample that is
+// not staged); so do sorting paths for CreateWithStripPrefix and
+// CreateWithPathPrefix, and the paths kept by CreateWithRejectCaseCollisions.
 //
-// Create walks dir recursively, including all regular files. Empty
-// directories are not preserved. Symbolic links are not followed.
+// Create walks dir recursively, including all regular files not filtered
+// out by CreateWithExclude, CreateWithInclude, or CreateWithIgnoreFile.
+// Empty directories are not preserved. Symbolic links are not followed and
+// are skipped unless CreateWithSymlinks is set.
 //
 // The context can be used for cancellation of long-running archive creation.
 func Create(ctx context.Context, dir string, indexW, dataW io.Writer, opts ...CreateOption) error {
-	cfg := createConfig{}
-	for _, opt := range opts {
-		opt(&cfg)
+	w, err := newWriter(opts)
+	if err != nil {
+		return err
 	}
 
 	root, err := os.OpenRoot(dir)
@@ -48,8 +67,7 @@ func Create(ctx context.Context, dir string, indexW, dataW io.Writer, opts ...Cr
 	}
 	defer root.Close()
 
-	w := &writer{cfg: cfg, logger: cfg.logger}
-	w.log().Info("creating archive", "dir", dir, "compression", cfg.compression.String())
+	w.log().Info("creating archive", "dir", dir, "compression", w.cfg.compression.String(), "level", w.cfg.compressionLevel.String())
 
 	hasher := sha256.New()
 	dataWriter := io.MultiWriter(dataW, hasher)
@@ -57,18 +75,132 @@ func Create(ctx context.Context, dir string, indexW, dataW io.Writer, opts ...Cr
 	if err != nil {
 		return err
 	}
+	defer entries.Close()
+
+	w.log().Debug("archive data written", "file_count", entries.Len(), "data_size", dataSize)
+	return w.writeIndex(indexW, entries, dataSize, hasher.Sum(nil))
+}
+
+// newWriter applies opts and prepares the state shared by Create and
+// CreateFS.
+func newWriter(opts []CreateOption) (*writer, error) {
+	cfg := createConfig{}
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+
+	if cfg.auxChecksum > AuxChecksumXXH64 {
+		return nil, fmt.Errorf("unsupported aux checksum: %s", cfg.auxChecksum)
+	}
+	filter, err := newCreateFilter(&cfg)
+	if err != nil {
+		return nil, err
+	}
+	if err := validateRewrite(&cfg); err != nil {
+		return nil, err
+	}
+	w := &writer{cfg: cfg, logger: cfg.logger, filter: filter}
+	if err := w.initChunking(); err != nil {
+		return nil, err
+	}
+	if cfg.encryption != EncryptionNone {
+		aead, err := file.NewCipher(cfg.encryption, cfg.encryptionKey)
+		if err != nil {
+			return nil, fmt.Errorf("encryption key: %w", err)
+		}
+		w.aead = aead
+	}
+	return w, nil
+}
 
-	w.log().Debug("archive data written", "file_count", len(entries), "data_size", dataSize)
+// writeIndex builds the index for entries, writes it to indexW, and records
+// the digests requested with CreateWithDigests.
+func (w *writer) writeIndex(indexW io.Writer, entries entrySource, dataSize uint64, dataHash []byte) error {
+	indexData, err := buildIndexFrom(entries, indexMetadata{
+		dataSize:       dataSize,
+		dataHash:       dataHash,
+		zstdDictionary: dictionaryHash(w.dictionary()),
+		encryption:     w.cfg.encryption,
+		auxChecksum:    w.cfg.auxChecksum,
+		bloomFilter:    w.cfg.bloomFilter,
+	})
+	if err != nil {
+		return err
+	}
+	if _, err := indexW.Write(indexData); err != nil {
+		return err
+	}
 
-	indexData := buildIndex(entries, dataSize, hasher.Sum(nil))
-	_, err = indexW.Write(indexData)
-	return err
+	if w.cfg.indexDigest != nil {
+		*w.cfg.indexDigest = digest.FromBytes(indexData)
+	}
+	if w.cfg.dataDigest != nil {
+		*w.cfg.dataDigest = digest.NewDigestFromEncoded(digest.SHA256, hex.EncodeToString(dataHash))
+	}
+	return nil
 }
 
 // writer holds state for archive creation.
 type writer struct {
 	cfg    createConfig
 	logger *slog.Logger
+	plain  bytes.Buffer // staging buffer for content to encrypt
+	aead   cipher.AEAD  // nil unless CreateWithEncryption is set
+	now    time.Time    // modification time for Update changes without one
+	filter createFilter // include, exclude, and ignore file patterns
+
+	// cases records the paths written, for CreateWithRejectCaseCollisions.
+	cases caseCollisions
+
+	// chunker splits large files into chunks; nil unless CreateWithChunking
+	// is set.
+	chunker *write.Chunker
+
+	// afterStat, when set, is called once a file has been statted and before
+	// it is read. Tests use it to modify files mid-create.
+	afterStat func(path string)
+}
+
+// dictionary returns the zstd dictionary used by the encoder, or nil when
+// compression is disabled.
+func (w *writer) dictionary() []byte {
+	if w.cfg.compression == CompressionNone {
+		return nil
+	}
+	return w.cfg.zstdDictionary
+}
+
+// dictionaryHash returns the SHA-256 of dict, or nil for no dictionary.
+func dictionaryHash(dict []byte) []byte {
+	if len(dict) == 0 {
+		return nil
+	}
+	sum := sha256.Sum256(dict)
+	return sum[:]
+}
+
+// initChunking prepares the chunker requested with CreateWithChunking.
+func (w *writer) initChunking() error {
+	if w.cfg.chunkSize == 0 {
+		return nil
+	}
+	if w.cfg.encryption != EncryptionNone {
+		return errors.New("chunking cannot be combined with encryption")
+	}
+	chunker, err := write.NewChunker(w.cfg.chunkSize)
+	if err != nil {
+		return fmt.Errorf("chunking: %w", err)
+	}
+	w.chunker = chunker
+	return nil
+}
+
+// minSavings returns the configured minimum compression savings.
+func (w *writer) minSavings() float64 {
+	if !w.cfg.minSavingsSet {
+		return DefaultMinCompressionRatio
+	}
+	return w.cfg.minSavings
 }
 
 // reportProgress sends a progress event if a callback is configured.
@@ -96,52 +228,290 @@ func (w *writer) log() *slog.Logger {
 
 // writeData walks the directory tree and writes file contents to data.
 // Returns the collected entries and total bytes written.
-func (w *writer) writeData(ctx context.Context, root *os.Root, data io.Writer) (entries []Entry, totalBytes uint64, err error) {
-	entries = make([]Entry, 0, 1024)
+func (w *writer) writeData(ctx context.Context, root *os.Root, data io.Writer) (entries *entryLog, totalBytes uint64, err error) {
 	strict := w.cfg.changeDetection == ChangeDetectionStrict
-	maxFiles := w.cfg.maxFiles
-	if maxFiles == 0 {
-		maxFiles = DefaultMaxFiles
-	}
+	return w.writeEntries(ctx, root.FS(), data, func(dest io.Writer, ws *workspace, path string, d fs.DirEntry, walkErr error, count int) (Entry, bool, error) {
+		return w.processEntry(ctx, root, dest, ws, path, d, walkErr, strict, w.maxFiles(), count)
+	})
+}
 
-	var enc *zstd.Encoder
-	if w.cfg.compression != CompressionNone {
-		var encErr error
-		enc, encErr = zstd.NewWriter(io.Discard, zstd.WithEncoderConcurrency(1), zstd.WithLowerEncoderMem(true))
-		if encErr != nil {
-			return nil, 0, fmt.Errorf("create zstd encoder: %w", encErr)
+// addFunc appends an entry written by an entryFunc to the archive; see
+// writeEntries.
+type addFunc func(name string, entry Entry, content *bytes.Buffer) error
+
+// entryFunc writes the content of one walked path to data and returns its
+// entry, or reports that the path is skipped. count is the number of
+// entries written so far, or zero when it is not known because files are
+// written concurrently.
+type entryFunc func(data io.Writer, ws *workspace, path string, d fs.DirEntry, walkErr error, count int) (Entry, bool, error)
+
+// writeEntries walks fsys in index order, writing each entry with process.
+// With path rewriting, entries are written in the order of their rewritten
+// paths instead. It handles encryption, offsets, and progress for every
+// entry. Returns the collected entries, which the caller must close, and
+// total bytes written.
+func (w *writer) writeEntries(ctx context.Context, fsys fs.FS, data io.Writer, process entryFunc) (entries *entryLog, totalBytes uint64, err error) {
+	if w.cfg.ignoreFile != "" {
+		if err := w.filter.loadIgnoreFile(fsys, w.cfg.ignoreFile); err != nil {
+			return nil, 0, err
 		}
 	}
-	buf := make([]byte, 32*1024)
 
-	// Signal enumeration start
-	w.reportProgress(StageEnumerating, "", 0, 0, 0, 0)
+	// Count the files to write so progress can report totals.
+	var filesTotal int
+	var bytesTotal, bytesDone uint64
+	if w.cfg.progress != nil {
+		filesTotal, bytesTotal, err = w.enumerate(ctx, fsys)
+		if err != nil {
+			return nil, 0, err
+		}
+		w.reportProgress(StageEnumerating, "", 0, bytesTotal, 0, filesTotal)
+	}
 
-	err = fs.WalkDir(root.FS(), ".", func(path string, d fs.DirEntry, walkErr error) error {
-		entry, skip, procErr := w.processEntry(ctx, root, data, enc, buf, path, d, walkErr, strict, maxFiles, len(entries))
-		if procErr != nil || skip {
-			return procErr
+	entries = newEntryLog(w.cfg.tempDir, entrySpillThreshold)
+
+	// add appends entry, stored in the archive as name, to the archive.
+	// When content is non-nil it holds the bytes process wrote for the
+	// entry, which still have to be written to data: plaintext to seal
+	// with encryption, or the output of a compression worker.
+	add := func(name string, entry Entry, content *bytes.Buffer) error {
+		if w.cfg.caseCollisions {
+			if err := w.cases.check(name); err != nil {
+				return err
+			}
+		}
+		if w.rewriting() {
+			if err := w.cfg.pathLimits.check(name); err != nil {
+				return err
+			}
+			entry.Path = name
+		}
+		w.stamp(&entry)
+		entry.DataOffset = totalBytes
+		switch {
+		case w.aead != nil:
+			if err := w.sealEntry(data, content.Bytes(), &entry); err != nil {
+				return err
+			}
+		case content != nil:
+			if _, err := content.WriteTo(data); err != nil {
+				return fmt.Errorf("write %s: %w", entry.Path, err)
+			}
 		}
 		if entry.DataSize > ^uint64(0)-totalBytes {
 			return ErrSizeOverflow
 		}
-		entry.DataOffset = totalBytes
-		entries = append(entries, entry)
+		if err := entries.Append(entry); err != nil {
+			return err
+		}
 		totalBytes += entry.DataSize
-		w.reportProgress(StageCompressing, path, totalBytes, 0, len(entries), 0)
+		if entry.Mode&fs.ModeSymlink == 0 {
+			bytesDone += entry.OriginalSize
+		}
+		w.reportProgress(StageCompressing, name, bytesDone, bytesTotal, entries.Len(), filesTotal)
 		return nil
-	})
+	}
+
+	if w.cfg.workers > 1 {
+		err = w.walkParallel(ctx, fsys, data, process, add)
+	} else {
+		err = w.walkSerial(ctx, fsys, data, process, add)
+	}
 	if err != nil {
+		_ = entries.Close() //nolint:errcheck // the walk error takes precedence
 		return nil, 0, err
 	}
 
 	return entries, totalBytes, nil
 }
 
+// walkSerial writes each walked path with process on the calling goroutine
+// and passes the entries to add.
+func (w *writer) walkSerial(ctx context.Context, fsys fs.FS, data io.Writer, process entryFunc, add addFunc) error {
+	ws, err := w.newWorkspace()
+	if err != nil {
+		return err
+	}
+	count := 0
+	return w.walk(ctx, fsys, func(path, name string, d fs.DirEntry, walkErr error) error {
+		dest, content := data, (*bytes.Buffer)(nil)
+		if w.aead != nil {
+			w.plain.Reset()
+			dest, content = &w.plain, &w.plain
+		}
+		entry, skip, err := process(dest, ws, path, d, walkErr, count)
+		if err != nil || skip {
+			return err
+		}
+		count++
+		return add(name, entry, content)
+	})
+}
+
+// walk calls visit for every path of fsys that is not filtered out, in the
+// order entries are written.
+func (w *writer) walk(ctx context.Context, fsys fs.FS, visit func(path, name string, d fs.DirEntry, walkErr error) error) error {
+	if w.rewriting() {
+		return w.walkRewritten(ctx, fsys, visit)
+	}
+	return fs.WalkDir(indexOrderFS{fsys}, ".", func(path string, d fs.DirEntry, walkErr error) error {
+		if w.filtered(path, d, walkErr) {
+			if d.IsDir() {
+				return fs.SkipDir
+			}
+			return nil
+		}
+		return visit(path, path, d, walkErr)
+	})
+}
+
+// enumerate counts the regular files, and symbolic links when they are
+// recorded, that a walk of fsys will write, along with the total size of
+// the regular files. Errors are left for the writing walk to report, so
+// the counts are estimates when files cannot be read or change meanwhile.
+func (w *writer) enumerate(ctx context.Context, fsys fs.FS) (files int, size uint64, err error) {
+	err = w.walk(ctx, fsys, func(_, _ string, d fs.DirEntry, walkErr error) error {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if walkErr != nil || d.IsDir() {
+			return nil
+		}
+		if d.Type()&fs.ModeSymlink != 0 {
+			if w.cfg.symlinks {
+				files++
+			}
+			return nil
+		}
+		if !d.Type().IsRegular() {
+			return nil
+		}
+		info, err := d.Info()
+		if err != nil {
+			return nil //nolint:nilerr // reported by the writing walk
+		}
+		files++
+		size += uint64(max(info.Size(), 0))
+		return nil
+	})
+	return files, size, err
+}
+
+// filtered reports whether the walked path is left out by the create
+// filter. Filtered directories must not be descended into.
+func (w *writer) filtered(path string, d fs.DirEntry, walkErr error) bool {
+	if walkErr != nil || path == "." || !w.filter.active() {
+		return false
+	}
+	if !w.filter.skip(path, d.IsDir()) {
+		return false
+	}
+	w.log().Debug("filtered path", "path", path)
+	return true
+}
+
+// stamp applies the CreateWithModTime override to entry.
+func (w *writer) stamp(entry *Entry) {
+	if w.cfg.modTime != nil {
+		entry.ModTime = *w.cfg.modTime
+	}
+}
+
+// maxFiles returns the configured file limit, applying the default.
+func (w *writer) maxFiles() int {
+	if w.cfg.maxFiles == 0 {
+		return DefaultMaxFiles
+	}
+	return w.cfg.maxFiles
+}
+
+// newEncoder returns a zstd encoder for a workspace, or nil when
+// compression is disabled.
+func (w *writer) newEncoder() (*zstd.Encoder, error) {
+	if w.cfg.compression == CompressionNone {
+		return nil, nil //nolint:nilnil // a nil encoder means uncompressed writes
+	}
+	encOpts := []zstd.EOption{
+		zstd.WithEncoderConcurrency(1),
+		zstd.WithLowerEncoderMem(true),
+		zstd.WithEncoderLevel(w.cfg.compressionLevel.encoderLevel()),
+	}
+	if dict := w.dictionary(); len(dict) > 0 {
+		encOpts = append(encOpts, zstd.WithEncoderDict(dict))
+	}
+	enc, err := zstd.NewWriter(io.Discard, encOpts...)
+	if err != nil {
+		return nil, fmt.Errorf("create zstd encoder: %w", err)
+	}
+	return enc, nil
+}
+
+// workspace holds the state used to write file content on one goroutine.
+type workspace struct {
+	enc     *zstd.Encoder // nil when compression is disabled
+	buf     []byte        // copy buffer
+	scratch bytes.Buffer  // staging buffer for adaptive compression
+	staged  bytes.Buffer  // staging buffer for ConcurrentModificationRetry
+}
+
+// newWorkspace returns a workspace with its own encoder.
+func (w *writer) newWorkspace() (*workspace, error) {
+	enc, err := w.newEncoder()
+	if err != nil {
+		return nil, err
+	}
+	return &workspace{enc: enc, buf: make([]byte, 32*1024)}, nil
+}
+
+// sealEntry encrypts plain, the staged content of entry, writes it to data,
+// and records the nonce and encrypted size on entry. The seal binds the
+// bytes to entry's path and DataOffset, which must already be set.
+func (w *writer) sealEntry(data io.Writer, plain []byte, entry *Entry) error {
+	nonce := make([]byte, w.aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return fmt.Errorf("encrypt %s: %w", entry.Path, err)
+	}
+	sealed := w.aead.Seal(nil, nonce, plain, file.AssociatedData(entry))
+	if _, err := data.Write(sealed); err != nil {
+		return fmt.Errorf("write %s: %w", entry.Path, err)
+	}
+	entry.DataSize = uint64(len(sealed))
+	entry.Nonce = nonce
+	return nil
+}
+
+// indexOrderFS wraps an fs.FS so fs.WalkDir visits files in index order.
+//
+// The index is sorted by the byte order of full paths, which differs from
+// the per-directory name order fs.WalkDir uses when a name sorts below '/':
+// "a.txt" precedes "a/b" in the index, but the "a" directory is visited
+// before "a.txt". Sorting directories as if their names ended in '/' makes
+// the walk order match.
+type indexOrderFS struct {
+	fs.FS
+}
+
+// ReadDir implements fs.ReadDirFS.
+func (f indexOrderFS) ReadDir(name string) ([]fs.DirEntry, error) {
+	entries, err := fs.ReadDir(f.FS, name)
+	slices.SortFunc(entries, func(a, b fs.DirEntry) int {
+		return strings.Compare(indexSortKey(a), indexSortKey(b))
+	})
+	return entries, err
+}
+
+// indexSortKey returns the name used to order a directory entry in the index.
+func indexSortKey(d fs.DirEntry) string {
+	if d.IsDir() {
+		return d.Name() + "/"
+	}
+	return d.Name()
+}
+
 // processEntry handles a single directory entry during archive creation.
 //
 //nolint:gocritic // unnamedResult is acceptable for this internal helper
-func (w *writer) processEntry(ctx context.Context, root *os.Root, data io.Writer, enc *zstd.Encoder, buf []byte, path string, d fs.DirEntry, walkErr error, strict bool, maxFiles, count int) (Entry, bool, error) {
+func (w *writer) processEntry(ctx context.Context, root *os.Root, data io.Writer, ws *workspace, path string, d fs.DirEntry, walkErr error, strict bool, maxFiles, count int) (Entry, bool, error) {
 	if walkErr != nil {
 		return Entry{}, false, walkErr
 	}
@@ -153,6 +523,17 @@ func (w *writer) processEntry(ctx context.Context, root *os.Root, data io.Writer
 	}
 
 	fsPath := filepath.FromSlash(path)
+	if w.cfg.symlinks && d.Type()&fs.ModeSymlink != 0 {
+		if maxFiles > 0 && count >= maxFiles {
+			return Entry{}, false, ErrTooManyFiles
+		}
+		if err := w.checkPathLimits(path); err != nil {
+			return Entry{}, false, err
+		}
+		entry, err := writeSymlinkEntry(root, data, path, fsPath, w.cfg.auxChecksum)
+		return entry, false, err
+	}
+
 	info, ok, err := write.ResolveEntryInfo(root, fsPath, d, strict)
 	if err != nil {
 		return Entry{}, false, err
@@ -164,8 +545,11 @@ func (w *writer) processEntry(ctx context.Context, root *os.Root, data io.Writer
 	if maxFiles > 0 && count >= maxFiles {
 		return Entry{}, false, ErrTooManyFiles
 	}
+	if err := w.checkPathLimits(path); err != nil {
+		return Entry{}, false, err
+	}
 
-	entry, err := w.writeEntry(ctx, root, data, enc, buf, path, fsPath, info, strict)
+	entry, err := w.writeEntry(ctx, root, data, ws, path, fsPath, info, strict)
 	if err != nil {
 		if errors.Is(err, platform.ErrSymlink) {
 			w.log().Debug("skipped symlink", "path", path)
@@ -178,7 +562,36 @@ func (w *writer) processEntry(ctx context.Context, root *os.Root, data io.Writer
 }
 
 // writeEntry writes a single file's content to data and returns its metadata.
-func (w *writer) writeEntry(ctx context.Context, root *os.Root, data io.Writer, enc *zstd.Encoder, buf []byte, path, fsPath string, info fs.FileInfo, strict bool) (Entry, error) {
+// Under ConcurrentModificationRetry the content is staged so attempts that
+// observe a changing file can be discarded.
+func (w *writer) writeEntry(ctx context.Context, root *os.Root, data io.Writer, ws *workspace, path, fsPath string, info fs.FileInfo, strict bool) (Entry, error) {
+	if w.cfg.modification != ConcurrentModificationRetry {
+		return w.writeEntryOnce(ctx, root, data, ws, path, fsPath, info, strict)
+	}
+
+	for attempt := 0; ; attempt++ {
+		ws.staged.Reset()
+		entry, err := w.writeEntryOnce(ctx, root, &ws.staged, ws, path, fsPath, info, strict)
+		if errors.Is(err, ErrFileChanged) && attempt < maxModificationRetries {
+			w.log().Debug("file changed during read, retrying", "path", path, "attempt", attempt+1)
+			// The walk-time info no longer describes the file.
+			if info, err = root.Lstat(fsPath); err != nil {
+				return Entry{}, err
+			}
+			continue
+		}
+		if err != nil {
+			return Entry{}, err
+		}
+		if _, err := ws.staged.WriteTo(data); err != nil {
+			return Entry{}, err
+		}
+		return entry, nil
+	}
+}
+
+// writeEntryOnce reads a file once and writes its content to data.
+func (w *writer) writeEntryOnce(ctx context.Context, root *os.Root, data io.Writer, ws *workspace, path, fsPath string, info fs.FileInfo, strict bool) (Entry, error) {
 	f, err := platform.OpenFileNoFollow(root, fsPath)
 	if err != nil {
 		return Entry{}, err
@@ -195,47 +608,171 @@ func (w *writer) writeEntry(ctx context.Context, root *os.Root, data io.Writer,
 	if validateErr := write.ValidateFileInfo(path, info, finfo, strict); validateErr != nil {
 		return Entry{}, validateErr
 	}
+	if w.afterStat != nil {
+		w.afterStat(path)
+	}
+
+	entry, err := w.writeContent(ctx, f, data, ws, path, finfo)
+	if err != nil {
+		return Entry{}, err
+	}
 
+	if w.cfg.modification != ConcurrentModificationTruncate {
+		changed, err := write.SizeChanged(f, finfo.Size(), entry.OriginalSize)
+		if err != nil {
+			return Entry{}, fmt.Errorf("write %s: %w", path, err)
+		}
+		if changed {
+			return Entry{}, fmt.Errorf("%w: %s (size was %d bytes at stat)", ErrFileChanged, path, finfo.Size())
+		}
+	}
+
+	if err := write.CheckFileUnchanged(f, path, finfo, strict); err != nil {
+		return Entry{}, err
+	}
+	return entry, nil
+}
+
+// writeContent writes up to info.Size() bytes of a regular file's content
+// from r through the hash and compression pipeline and returns its entry.
+// Entry metadata comes from info.
+func (w *writer) writeContent(ctx context.Context, r io.ReadSeeker, data io.Writer, ws *workspace, path string, info fs.FileInfo) (Entry, error) {
 	compression := w.cfg.compression
-	if compression != CompressionNone && write.ShouldSkip(path, finfo, w.cfg.skipCompression) {
+	if compression != CompressionNone && write.ShouldSkip(path, info, w.cfg.skipCompression) {
 		compression = CompressionNone
 	}
 
-	if finfo.Size() < 0 {
+	if info.Size() < 0 {
 		return Entry{}, fmt.Errorf("negative file size: %s", path)
 	}
 
-	dataSize, originalSize, hash, err := write.File(ctx, f, data, enc, buf, compression, finfo.Size())
+	var (
+		dataSize, originalSize uint64
+		hash                   []byte
+		chunks                 []Chunk
+		err                    error
+	)
+	aux := newAuxHasher(w.cfg.auxChecksum)
+	chunked := w.chunker != nil && info.Size() > int64(w.chunker.MaxSize())
+	trial := compression != CompressionNone && w.minSavings() > 0
+	if trial && info.Size() > write.MaxTrialSize {
+		// Too large to stage: decide from a sample and stream the file.
+		worth, err := write.SampleWorthCompressing(ctx, r, ws.enc, ws.buf, w.minSavings())
+		if err != nil {
+			return Entry{}, fmt.Errorf("write %s: %w", path, err)
+		}
+		if !worth {
+			compression = CompressionNone
+		}
+		trial = false
+	}
+	switch {
+	case chunked && trial:
+		dataSize, originalSize, hash, chunks, compression, err = write.ChunkedAdaptive(ctx, r, data, ws.enc, w.chunker, &ws.scratch, info.Size(), w.minSavings(), aux)
+	case chunked:
+		dataSize, originalSize, hash, chunks, err = write.Chunked(ctx, r, data, ws.enc, w.chunker, compression, info.Size(), aux)
+	case trial:
+		dataSize, originalSize, hash, compression, err = write.FileAdaptive(ctx, r, data, ws.enc, ws.buf, &ws.scratch, info.Size(), w.minSavings(), aux)
+	default:
+		dataSize, originalSize, hash, err = write.File(ctx, r, data, ws.enc, ws.buf, compression, info.Size(), aux)
+	}
 	if err != nil {
 		return Entry{}, fmt.Errorf("write %s: %w", path, err)
 	}
 
-	if err := write.CheckFileUnchanged(f, path, finfo, strict); err != nil {
-		return Entry{}, err
-	}
-
-	uid, gid := platform.FileOwner(finfo)
+	uid, gid := platform.FileOwner(info)
 	return Entry{
 		Path:         path,
 		DataSize:     dataSize,
 		OriginalSize: originalSize,
 		Hash:         hash,
-		Mode:         finfo.Mode().Perm(),
+		Mode:         info.Mode().Perm(),
 		UID:          uid,
 		GID:          gid,
-		ModTime:      finfo.ModTime(),
+		ModTime:      info.ModTime(),
 		Compression:  compression,
+		AuxChecksum:  auxSum(aux),
+		Chunks:       chunks,
+	}, nil
+}
+
+// writeSymlinkEntry writes a symbolic link's target to data and returns its
+// metadata. The target is stored uncompressed.
+func writeSymlinkEntry(root *os.Root, data io.Writer, path, fsPath string, auxChecksum AuxChecksum) (Entry, error) {
+	info, err := root.Lstat(fsPath)
+	if err != nil {
+		return Entry{}, err
+	}
+	target, err := root.Readlink(fsPath)
+	if err != nil {
+		return Entry{}, fmt.Errorf("read symlink %s: %w", path, err)
+	}
+	return symlinkEntry(data, path, target, info, auxChecksum)
+}
+
+// symlinkEntry writes target to data and returns the entry for the symbolic
+// link at path described by info.
+func symlinkEntry(data io.Writer, path, target string, info fs.FileInfo, auxChecksum AuxChecksum) (Entry, error) {
+	if _, err := io.WriteString(data, target); err != nil {
+		return Entry{}, fmt.Errorf("write %s: %w", path, err)
+	}
+
+	hash := sha256.Sum256([]byte(target))
+	aux := newAuxHasher(auxChecksum)
+	if aux != nil {
+		_, _ = io.WriteString(aux, target) //nolint:errcheck // hash writes never fail
+	}
+	uid, gid := platform.FileOwner(info)
+	return Entry{
+		Path:         path,
+		DataSize:     uint64(len(target)),
+		OriginalSize: uint64(len(target)),
+		Hash:         hash[:],
+		Mode:         fs.ModeSymlink | info.Mode().Perm(),
+		UID:          uid,
+		GID:          gid,
+		ModTime:      info.ModTime(),
+		Compression:  CompressionNone,
+		AuxChecksum:  auxSum(aux),
 	}, nil
 }
 
+// indexMetadata holds archive-wide fields written alongside the entries.
+type indexMetadata struct {
+	dataSize       uint64
+	dataHash       []byte
+	zstdDictionary []byte // SHA-256 of the dictionary
+	encryption     Encryption
+	auxChecksum    AuxChecksum
+	bloomFilter    bool
+}
+
 // buildIndex serializes entries to FlatBuffers format.
-func buildIndex(entries []Entry, dataSize uint64, dataHash []byte) []byte {
+func buildIndex(entries []Entry, meta indexMetadata) []byte {
+	data, _ := buildIndexFrom(entrySlice(entries), meta) //nolint:errcheck // entrySlice never fails
+	return data
+}
+
+// buildIndexFrom is buildIndex for entries read one at a time from src.
+func buildIndexFrom(src entrySource, meta indexMetadata) ([]byte, error) {
 	builder := flatbuffers.NewBuilder(1024)
+	n := src.Len()
+
+	var bloom *index.BloomBuilder
+	if meta.bloomFilter {
+		bloom = index.NewBloomBuilder(n)
+	}
 
 	// Build entries in reverse order (FlatBuffers requirement)
-	entryOffsets := make([]flatbuffers.UOffsetT, len(entries))
-	for i := len(entries) - 1; i >= 0; i-- {
-		e := entries[i]
+	entryOffsets := make([]flatbuffers.UOffsetT, n)
+	for i := n - 1; i >= 0; i-- {
+		e, err := src.At(i)
+		if err != nil {
+			return nil, err
+		}
+		if bloom != nil {
+			bloom.Add(e.Path)
+		}
 
 		pathOffset := builder.CreateString(e.Path)
 
@@ -245,6 +782,16 @@ func buildIndex(entries []Entry, dataSize uint64, dataHash []byte) []byte {
 		}
 		hashOffset := builder.EndVector(len(e.Hash))
 
+		var nonceOffset flatbuffers.UOffsetT
+		if len(e.Nonce) > 0 {
+			nonceOffset = builder.CreateByteVector(e.Nonce)
+		}
+
+		var chunkSizesOffset, chunkOriginalSizesOffset, chunkHashesOffset flatbuffers.UOffsetT
+		if len(e.Chunks) > 0 {
+			chunkSizesOffset, chunkOriginalSizesOffset, chunkHashesOffset = buildChunks(builder, e.Chunks)
+		}
+
 		fb.EntryStart(builder)
 		fb.EntryAddPath(builder, pathOffset)
 		fb.EntryAddDataOffset(builder, e.DataOffset)
@@ -256,17 +803,28 @@ func buildIndex(entries []Entry, dataSize uint64, dataHash []byte) []byte {
 		fb.EntryAddGid(builder, e.GID)
 		fb.EntryAddMtimeNs(builder, e.ModTime.UnixNano())
 		fb.EntryAddCompression(builder, fb.Compression(e.Compression)) //nolint:gosec // Compression is bounded 0-1
+		if nonceOffset != 0 {
+			fb.EntryAddNonce(builder, nonceOffset)
+		}
+		if meta.auxChecksum != AuxChecksumNone {
+			fb.EntryAddAuxChecksum(builder, e.AuxChecksum)
+		}
+		if chunkSizesOffset != 0 {
+			fb.EntryAddChunkSizes(builder, chunkSizesOffset)
+			fb.EntryAddChunkOriginalSizes(builder, chunkOriginalSizesOffset)
+			fb.EntryAddChunkHashes(builder, chunkHashesOffset)
+		}
 		entryOffsets[i] = fb.EntryEnd(builder)
 	}
 
-	fb.IndexStartEntriesVector(builder, len(entries))
+	fb.IndexStartEntriesVector(builder, n)
 	for i := len(entryOffsets) - 1; i >= 0; i-- {
 		builder.PrependUOffsetT(entryOffsets[i])
 	}
-	entriesOffset := builder.EndVector(len(entries))
+	entriesOffset := builder.EndVector(n)
 
 	var dataHashOffset flatbuffers.UOffsetT
-	if len(dataHash) > 0 {
+	if dataHash := meta.dataHash; len(dataHash) > 0 {
 		fb.IndexStartDataHashVector(builder, len(dataHash))
 		for i := len(dataHash) - 1; i >= 0; i-- {
 			builder.PrependByte(dataHash[i])
@@ -274,16 +832,60 @@ func buildIndex(entries []Entry, dataSize uint64, dataHash []byte) []byte {
 		dataHashOffset = builder.EndVector(len(dataHash))
 	}
 
+	var dictOffset flatbuffers.UOffsetT
+	if hash := meta.zstdDictionary; len(hash) > 0 {
+		dictOffset = builder.CreateByteVector(hash)
+	}
+
+	var bloomOffset flatbuffers.UOffsetT
+	if bloom != nil {
+		bloomOffset = builder.CreateByteVector(bloom.Bytes())
+	}
+
 	fb.IndexStart(builder)
-	fb.IndexAddVersion(builder, 1)
+	fb.IndexAddVersion(builder, IndexVersion)
 	fb.IndexAddHashAlgorithm(builder, fb.HashAlgorithmSHA256)
 	fb.IndexAddEntries(builder, entriesOffset)
-	fb.IndexAddDataSize(builder, dataSize)
+	fb.IndexAddDataSize(builder, meta.dataSize)
 	if dataHashOffset != 0 {
 		fb.IndexAddDataHash(builder, dataHashOffset)
 	}
+	if dictOffset != 0 {
+		fb.IndexAddZstdDictionaryHash(builder, dictOffset)
+	}
+	if meta.encryption != EncryptionNone {
+		fb.IndexAddEncryption(builder, fb.Encryption(meta.encryption)) //nolint:gosec // Encryption is bounded 0-1
+	}
+	if meta.auxChecksum != AuxChecksumNone {
+		fb.IndexAddAuxChecksum(builder, fb.AuxChecksum(meta.auxChecksum)) //nolint:gosec // AuxChecksum is bounded 0-1
+	}
+	if bloomOffset != 0 {
+		fb.IndexAddBloomFilter(builder, bloomOffset)
+	}
 	indexOffset := fb.IndexEnd(builder)
 
 	builder.Finish(indexOffset)
-	return builder.FinishedBytes()
+	return builder.FinishedBytes(), nil
+}
+
+// buildChunks serializes the chunk vectors of an entry.
+func buildChunks(builder *flatbuffers.Builder, chunks []Chunk) (sizes, originalSizes, hashes flatbuffers.UOffsetT) {
+	fb.EntryStartChunkSizesVector(builder, len(chunks))
+	for i := len(chunks) - 1; i >= 0; i-- {
+		builder.PrependUint32(chunks[i].Size)
+	}
+	sizes = builder.EndVector(len(chunks))
+
+	fb.EntryStartChunkOriginalSizesVector(builder, len(chunks))
+	for i := len(chunks) - 1; i >= 0; i-- {
+		builder.PrependUint32(chunks[i].OriginalSize)
+	}
+	originalSizes = builder.EndVector(len(chunks))
+
+	all := make([]byte, 0, len(chunks)*sha256.Size)
+	for _, c := range chunks {
+		all = append(all, c.Hash...)
+	}
+	hashes = builder.CreateByteVector(all)
+	return sizes, originalSizes, hashes
 }
@@ -64,5 +64,5 @@ func CreateBlob(ctx context.Context, srcDir, destDir string, opts ...CreateBlobO
 	}
 
 	// Reopen for reading via OpenFile
-	return OpenFile(indexPath, dataPath)
+	return OpenFile(indexPath, dataPath, cfg.openOpts...)
 }
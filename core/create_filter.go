@@ -0,0 +1,194 @@
+package blob
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"io/fs"
+	"path"
+	"strings"
+)
+
+// createFilter selects the paths walked by Create and CreateFS using the
+// CreateWithInclude, CreateWithExclude, and CreateWithIgnoreFile patterns.
+type createFilter struct {
+	include []ignoreRule
+	exclude []ignoreRule
+	ignore  []ignoreRule // from the ignore file, in file order
+}
+
+// ignoreRule is a compiled gitignore-style pattern.
+type ignoreRule struct {
+	pattern  string
+	segments []string // slash-separated elements; "**" matches any number
+	dirOnly  bool     // pattern ended in "/"
+	negate   bool     // ignore file line started with "!"
+}
+
+// newCreateFilter compiles the configured include and exclude patterns.
+func newCreateFilter(cfg *createConfig) (createFilter, error) {
+	var f createFilter
+	for _, pattern := range cfg.include {
+		rule, err := compileIgnoreRule(pattern)
+		if err != nil {
+			return createFilter{}, fmt.Errorf("include pattern %q: %w", pattern, err)
+		}
+		f.include = append(f.include, rule)
+	}
+	for _, pattern := range cfg.exclude {
+		rule, err := compileIgnoreRule(pattern)
+		if err != nil {
+			return createFilter{}, fmt.Errorf("exclude pattern %q: %w", pattern, err)
+		}
+		f.exclude = append(f.exclude, rule)
+	}
+	if cfg.ignoreFile != "" && !fs.ValidPath(cfg.ignoreFile) {
+		return createFilter{}, &fs.PathError{Op: "ignore file", Path: cfg.ignoreFile, Err: fs.ErrInvalid}
+	}
+	return f, nil
+}
+
+// active reports whether any patterns are configured.
+func (f *createFilter) active() bool {
+	return len(f.include) > 0 || len(f.exclude) > 0 || len(f.ignore) > 0
+}
+
+// loadIgnoreFile reads the patterns of the ignore file name at the root of
+// fsys. A missing file adds no patterns.
+func (f *createFilter) loadIgnoreFile(fsys fs.FS, name string) error {
+	data, err := fs.ReadFile(fsys, name)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("read ignore file: %w", err)
+	}
+
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for line := 1; scanner.Scan(); line++ {
+		text := strings.TrimRight(scanner.Text(), " \t\r")
+		if text == "" || strings.HasPrefix(text, "#") {
+			continue
+		}
+		negate := strings.HasPrefix(text, "!")
+		text = strings.TrimPrefix(text, "!")
+		// A leading backslash escapes a literal "#" or "!".
+		if strings.HasPrefix(text, `\#`) || strings.HasPrefix(text, `\!`) {
+			text = text[1:]
+		}
+		rule, err := compileIgnoreRule(text)
+		if err != nil {
+			return fmt.Errorf("%s:%d: pattern %q: %w", name, line, text, err)
+		}
+		rule.negate = negate
+		f.ignore = append(f.ignore, rule)
+	}
+	return scanner.Err()
+}
+
+// skip reports whether the walked path rel is left out of the archive.
+// Directories that are skipped are not descended into.
+func (f *createFilter) skip(rel string, isDir bool) bool {
+	if matchAny(f.include, rel, isDir) {
+		return false
+	}
+	if matchAny(f.exclude, rel, isDir) || f.ignored(rel, isDir) {
+		return true
+	}
+	if len(f.include) == 0 || isDir {
+		return false
+	}
+	// A file beneath an included directory is included too.
+	for dir := path.Dir(rel); dir != "."; dir = path.Dir(dir) {
+		if matchAny(f.include, dir, true) {
+			return false
+		}
+	}
+	return true
+}
+
+// ignored applies the ignore file rules to rel. As in gitignore, the last
+// matching rule decides, so a later "!" rule re-includes the path.
+func (f *createFilter) ignored(rel string, isDir bool) bool {
+	for i := len(f.ignore) - 1; i >= 0; i-- {
+		if f.ignore[i].match(rel, isDir) {
+			return !f.ignore[i].negate
+		}
+	}
+	return false
+}
+
+func matchAny(rules []ignoreRule, rel string, isDir bool) bool {
+	for i := range rules {
+		if rules[i].match(rel, isDir) {
+			return true
+		}
+	}
+	return false
+}
+
+// compileIgnoreRule parses a gitignore-style pattern. A trailing "/" matches
+// only directories. A pattern with a leading or inner "/" is anchored at the
+// root; otherwise it matches at any depth. Elements use path.Match syntax,
+// and a "**" element matches zero or more path elements.
+func compileIgnoreRule(pattern string) (ignoreRule, error) {
+	rule := ignoreRule{pattern: pattern}
+	p := pattern
+	if strings.HasSuffix(p, "/") {
+		rule.dirOnly = true
+		p = strings.TrimSuffix(p, "/")
+	}
+	anchored := strings.Contains(p, "/")
+	p = strings.TrimPrefix(p, "/")
+	if p == "" {
+		return ignoreRule{}, path.ErrBadPattern
+	}
+
+	segments := strings.Split(p, "/")
+	for _, seg := range segments {
+		if seg == "**" {
+			continue
+		}
+		if _, err := path.Match(seg, ""); err != nil {
+			return ignoreRule{}, err
+		}
+	}
+	if !anchored {
+		segments = append([]string{"**"}, segments...)
+	}
+	rule.segments = segments
+	return rule, nil
+}
+
+// match reports whether rel matches the rule.
+func (r *ignoreRule) match(rel string, isDir bool) bool {
+	if r.dirOnly && !isDir {
+		return false
+	}
+	return matchSegments(r.segments, strings.Split(rel, "/"))
+}
+
+// matchSegments matches path elements against pattern elements, expanding
+// "**" to any number of elements.
+func matchSegments(pattern, elems []string) bool {
+	for len(pattern) > 0 {
+		if pattern[0] == "**" {
+			for i := 0; i <= len(elems); i++ {
+				if matchSegments(pattern[1:], elems[i:]) {
+					return true
+				}
+			}
+			return false
+		}
+		if len(elems) == 0 {
+			return false
+		}
+		ok, _ := path.Match(pattern[0], elems[0]) //nolint:errcheck // patterns validated by compileIgnoreRule
+		if !ok {
+			return false
+		}
+		pattern, elems = pattern[1:], elems[1:]
+	}
+	return len(elems) == 0
+}
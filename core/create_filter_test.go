@@ -0,0 +1,255 @@
+package blob
+
+import (
+	"bytes"
+	"context"
+	"io/fs"
+	"strings"
+	"testing"
+	"testing/fstest"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/meigma/blob/core/testutil"
+)
+
+// filterTree is a source tree with the usual clutter to leave out.
+var filterTree = map[string]string{
+	".git/HEAD":                      "ref: refs/heads/main",
+	".git/objects/ab/cdef":           "object",
+	"README.md":                      "readme",
+	"build/out.bin":                  "binary",
+	"docs/guide.md":                  "guide",
+	"docs/api/ref.md":                "reference",
+	"docs/api/notes.tmp":             "scratch",
+	"node_modules/left-pad/index.js": "module",
+	"src/main.go":                    "package main",
+	"src/build/gen.go":               "package build",
+	"src/web/node_modules/x/x.js":    "nested module",
+	"src/web/app.js":                 "app",
+	"src/web/cache.tmp":              "scratch",
+}
+
+// archivedPaths creates an archive of files with opts and returns the
+// archived file paths.
+func archivedPaths(t *testing.T, files map[string]string, opts ...CreateOption) []string {
+	t.Helper()
+	dir := t.TempDir()
+	createTestFiles(t, dir, files)
+
+	var indexBuf, dataBuf bytes.Buffer
+	require.NoError(t, Create(context.Background(), dir, &indexBuf, &dataBuf, opts...))
+	b, err := New(indexBuf.Bytes(), testutil.NewMockByteSource(dataBuf.Bytes()))
+	require.NoError(t, err)
+
+	var paths []string
+	for view := range b.Entries() {
+		paths = append(paths, view.Path())
+	}
+	return paths
+}
+
+func TestCreateWithExclude(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name     string
+		patterns []string
+		want     []string
+	}{
+		{
+			name:     "names match at any depth",
+			patterns: []string{".git", "node_modules", "*.tmp"},
+			want: []string{
+				"README.md", "build/out.bin", "docs/api/ref.md", "docs/guide.md",
+				"src/build/gen.go", "src/main.go", "src/web/app.js",
+			},
+		},
+		{
+			name:     "leading slash anchors at root",
+			patterns: []string{"/build"},
+			want: []string{
+				".git/HEAD", ".git/objects/ab/cdef", "README.md",
+				"docs/api/notes.tmp", "docs/api/ref.md", "docs/guide.md",
+				"node_modules/left-pad/index.js", "src/build/gen.go", "src/main.go",
+				"src/web/app.js", "src/web/cache.tmp", "src/web/node_modules/x/x.js",
+			},
+		},
+		{
+			name:     "inner slash anchors and double star spans directories",
+			patterns: []string{"docs/**/*.md", "src/*/*.tmp", "**/x"},
+			want: []string{
+				".git/HEAD", ".git/objects/ab/cdef", "README.md", "build/out.bin",
+				"docs/api/notes.tmp", "node_modules/left-pad/index.js",
+				"src/build/gen.go", "src/main.go", "src/web/app.js",
+			},
+		},
+		{
+			name:     "trailing slash matches directories only",
+			patterns: []string{"build/", "README.md/"},
+			want: []string{
+				".git/HEAD", ".git/objects/ab/cdef", "README.md",
+				"docs/api/notes.tmp", "docs/api/ref.md", "docs/guide.md",
+				"node_modules/left-pad/index.js", "src/main.go",
+				"src/web/app.js", "src/web/cache.tmp", "src/web/node_modules/x/x.js",
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			got := archivedPaths(t, filterTree, CreateWithExclude(tt.patterns...))
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestCreateWithInclude(t *testing.T) {
+	t.Parallel()
+
+	t.Run("restricts to matching files", func(t *testing.T) {
+		t.Parallel()
+		got := archivedPaths(t, filterTree, CreateWithInclude("*.go"))
+		assert.Equal(t, []string{"src/build/gen.go", "src/main.go"}, got)
+	})
+
+	t.Run("directory includes its files", func(t *testing.T) {
+		t.Parallel()
+		got := archivedPaths(t, filterTree, CreateWithInclude("/docs"))
+		assert.Equal(t, []string{"docs/api/notes.tmp", "docs/api/ref.md", "docs/guide.md"}, got)
+	})
+
+	t.Run("include overrides exclude", func(t *testing.T) {
+		t.Parallel()
+		got := archivedPaths(t, filterTree,
+			CreateWithExclude("*.tmp", "build"),
+			CreateWithInclude("cache.tmp", "/build", "*.go"),
+		)
+		assert.Equal(t, []string{"build/out.bin", "src/main.go", "src/web/cache.tmp"}, got)
+	})
+
+	t.Run("exclude still applies beneath an included directory", func(t *testing.T) {
+		t.Parallel()
+		got := archivedPaths(t, filterTree, CreateWithInclude("docs"), CreateWithExclude("*.tmp"))
+		assert.Equal(t, []string{"docs/api/ref.md", "docs/guide.md"}, got)
+	})
+}
+
+func TestCreateWithIgnoreFile(t *testing.T) {
+	t.Parallel()
+
+	withIgnore := func(content string) map[string]string {
+		files := make(map[string]string, len(filterTree)+1)
+		for path, data := range filterTree {
+			files[path] = data
+		}
+		files[".blobignore"] = content
+		return files
+	}
+
+	t.Run("nested matches", func(t *testing.T) {
+		t.Parallel()
+		ignore := strings.Join([]string{
+			"# version control and dependencies",
+			".git/",
+			"node_modules",
+			"",
+			"*.tmp",
+			"/build",
+			"docs/api/",
+		}, "\n")
+		got := archivedPaths(t, withIgnore(ignore), CreateWithIgnoreFile(".blobignore"))
+		assert.Equal(t, []string{
+			".blobignore", "README.md", "docs/guide.md",
+			"src/build/gen.go", "src/main.go", "src/web/app.js",
+		}, got)
+	})
+
+	t.Run("negation re-includes", func(t *testing.T) {
+		t.Parallel()
+		ignore := "*.tmp\n!cache.tmp\n.blobignore\n.git\nnode_modules/\n"
+		got := archivedPaths(t, withIgnore(ignore), CreateWithIgnoreFile(".blobignore"))
+		assert.Equal(t, []string{
+			"README.md", "build/out.bin", "docs/api/ref.md", "docs/guide.md",
+			"src/build/gen.go", "src/main.go", "src/web/app.js", "src/web/cache.tmp",
+		}, got)
+	})
+
+	t.Run("cannot re-include beneath excluded directory", func(t *testing.T) {
+		t.Parallel()
+		ignore := "docs/\n!docs/guide.md\n"
+		got := archivedPaths(t, withIgnore(ignore), CreateWithIgnoreFile(".blobignore"), CreateWithInclude("*.md"))
+		assert.Equal(t, []string{"README.md"}, got)
+	})
+
+	t.Run("include overrides ignore file", func(t *testing.T) {
+		t.Parallel()
+		got := archivedPaths(t, withIgnore("*.md\n"), CreateWithIgnoreFile(".blobignore"), CreateWithInclude("guide.md"))
+		assert.Equal(t, []string{"docs/guide.md"}, got)
+	})
+
+	t.Run("missing file", func(t *testing.T) {
+		t.Parallel()
+		got := archivedPaths(t, filterTree, CreateWithIgnoreFile(".blobignore"))
+		assert.Len(t, got, len(filterTree))
+	})
+
+	t.Run("malformed pattern", func(t *testing.T) {
+		t.Parallel()
+		dir := t.TempDir()
+		createTestFiles(t, dir, withIgnore("ok\n[bad\n"))
+		var indexBuf, dataBuf bytes.Buffer
+		err := Create(context.Background(), dir, &indexBuf, &dataBuf, CreateWithIgnoreFile(".blobignore"))
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), ".blobignore:2")
+	})
+}
+
+func TestCreateFilterInvalidPattern(t *testing.T) {
+	t.Parallel()
+
+	for _, opt := range []CreateOption{
+		CreateWithExclude("[bad"),
+		CreateWithInclude("a/[bad/b"),
+		CreateWithExclude("/"),
+		CreateWithIgnoreFile("../.blobignore"),
+	} {
+		var indexBuf, dataBuf bytes.Buffer
+		err := Create(context.Background(), t.TempDir(), &indexBuf, &dataBuf, opt)
+		assert.Error(t, err)
+	}
+}
+
+// openRecordingFS records the paths opened through it.
+type openRecordingFS struct {
+	fs.FS
+	opened []string
+}
+
+func (f *openRecordingFS) Open(name string) (fs.File, error) {
+	f.opened = append(f.opened, name)
+	return f.FS.Open(name)
+}
+
+func TestCreateFSExcludePrunesDirectories(t *testing.T) {
+	t.Parallel()
+
+	mapFS := fstest.MapFS{}
+	for path, content := range filterTree {
+		mapFS[path] = &fstest.MapFile{Data: []byte(content), Mode: 0o644}
+	}
+	fsys := &openRecordingFS{FS: mapFS}
+
+	var indexBuf, dataBuf bytes.Buffer
+	err := CreateFS(context.Background(), fsys, &indexBuf, &dataBuf, CreateWithExclude(".git", "node_modules"))
+	require.NoError(t, err)
+
+	for _, name := range fsys.opened {
+		assert.NotContains(t, name, ".git", "opened %s", name)
+		assert.NotContains(t, name, "node_modules", "opened %s", name)
+	}
+	b, err := New(indexBuf.Bytes(), testutil.NewMockByteSource(dataBuf.Bytes()))
+	require.NoError(t, err)
+	assert.Equal(t, len(filterTree)-4, b.Len())
+}
@@ -0,0 +1,129 @@
+package blob
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"fmt"
+	"io"
+	"io/fs"
+)
+
+// CreateFS builds an archive from the contents of fsys.
+//
+// It is the fs.FS counterpart of Create for trees that do not live on disk,
+// such as embed.FS, zip-backed file systems, or fstest.MapFS. Files are
+// walked, sorted, hashed, and compressed exactly as Create does, and all
+// CreateOptions apply except the change detection and concurrent
+// modification options, which only make sense for files on disk: content
+// is read once and must match the size reported by Stat, or CreateFS
+// fails with ErrFileChanged.
+//
+// Entry modes and modification times come from each file's fs.FileInfo.
+// Symbolic links are recorded with CreateWithSymlinks when fsys implements
+// fs.ReadLinkFS and are skipped otherwise, as are other non-regular files.
+func CreateFS(ctx context.Context, fsys fs.FS, indexW, dataW io.Writer, opts ...CreateOption) error {
+	w, err := newWriter(opts)
+	if err != nil {
+		return err
+	}
+
+	w.log().Info("creating archive from fs.FS", "compression", w.cfg.compression.String(), "level", w.cfg.compressionLevel.String())
+
+	hasher := sha256.New()
+	dataWriter := io.MultiWriter(dataW, hasher)
+	entries, dataSize, err := w.writeEntries(ctx, fsys, dataWriter, func(dest io.Writer, ws *workspace, path string, d fs.DirEntry, walkErr error, count int) (Entry, bool, error) {
+		return w.processFSEntry(ctx, fsys, dest, ws, path, d, walkErr, count)
+	})
+	if err != nil {
+		return err
+	}
+	defer entries.Close()
+
+	w.log().Debug("archive data written", "file_count", entries.Len(), "data_size", dataSize)
+	return w.writeIndex(indexW, entries, dataSize, hasher.Sum(nil))
+}
+
+// processFSEntry handles a single entry of an fs.FS walk.
+//
+//nolint:gocritic // unnamedResult is acceptable for this internal helper
+func (w *writer) processFSEntry(ctx context.Context, fsys fs.FS, data io.Writer, ws *workspace, path string, d fs.DirEntry, walkErr error, count int) (Entry, bool, error) {
+	if walkErr != nil {
+		return Entry{}, false, walkErr
+	}
+	if err := ctx.Err(); err != nil {
+		return Entry{}, false, err
+	}
+	if d.IsDir() {
+		return Entry{}, true, nil
+	}
+
+	isSymlink := d.Type()&fs.ModeSymlink != 0
+	if isSymlink && !w.cfg.symlinks || !isSymlink && !d.Type().IsRegular() {
+		w.log().Debug("skipped non-regular file", "path", path)
+		return Entry{}, true, nil
+	}
+	if maxFiles := w.maxFiles(); maxFiles > 0 && count >= maxFiles {
+		return Entry{}, false, ErrTooManyFiles
+	}
+	if err := w.checkPathLimits(path); err != nil {
+		return Entry{}, false, err
+	}
+
+	if isSymlink {
+		if _, ok := fsys.(fs.ReadLinkFS); !ok {
+			w.log().Debug("skipped symlink", "path", path)
+			return Entry{}, true, nil
+		}
+		target, err := fs.ReadLink(fsys, path)
+		if err != nil {
+			return Entry{}, false, fmt.Errorf("read symlink %s: %w", path, err)
+		}
+		info, err := fs.Lstat(fsys, path)
+		if err != nil {
+			return Entry{}, false, err
+		}
+		entry, err := symlinkEntry(data, path, target, info, w.cfg.auxChecksum)
+		return entry, false, err
+	}
+
+	entry, err := w.writeFSFile(ctx, fsys, data, ws, path)
+	return entry, false, err
+}
+
+// writeFSFile writes the content of the regular file at path in fsys.
+func (w *writer) writeFSFile(ctx context.Context, fsys fs.FS, data io.Writer, ws *workspace, path string) (Entry, error) {
+	f, err := fsys.Open(path)
+	if err != nil {
+		return Entry{}, err
+	}
+	defer f.Close()
+
+	info, err := f.Stat()
+	if err != nil {
+		return Entry{}, err
+	}
+
+	// Adaptive compression rewinds the content, so files that cannot seek
+	// are staged in memory.
+	r, ok := f.(io.ReadSeeker)
+	if !ok {
+		content, err := io.ReadAll(io.LimitReader(f, info.Size()+1))
+		if err != nil {
+			return Entry{}, fmt.Errorf("read %s: %w", path, err)
+		}
+		r = bytes.NewReader(content)
+	}
+
+	entry, err := w.writeContent(ctx, r, data, ws, path, info)
+	if err != nil {
+		return Entry{}, err
+	}
+	// writeContent reads at most info.Size() bytes; one more byte means the
+	// file is larger than reported.
+	var extra [1]byte
+	if n, _ := r.Read(extra[:]); n > 0 || entry.OriginalSize != uint64(info.Size()) { //nolint:gosec // size is non-negative after writeContent
+		return Entry{}, fmt.Errorf("%w: %s (size was %d bytes at stat)", ErrFileChanged, path, info.Size())
+	}
+	return entry, nil
+}
@@ -0,0 +1,129 @@
+package blob
+
+import (
+	"bytes"
+	"context"
+	"io/fs"
+	"os"
+	"testing"
+	"testing/fstest"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/meigma/blob/core/testutil"
+)
+
+func TestCreateFS_MapFS(t *testing.T) {
+	t.Parallel()
+
+	modTime := time.Unix(1700000000, 0)
+	fsys := fstest.MapFS{
+		"a.txt":             {Data: []byte("alpha"), Mode: 0o644, ModTime: modTime},
+		"a/b.txt":           {Data: bytes.Repeat([]byte("bravo "), 500), Mode: 0o600, ModTime: modTime},
+		"a/deep/c.txt":      {Data: []byte("charlie"), Mode: 0o755, ModTime: modTime},
+		"empty.txt":         {Data: nil, Mode: 0o644, ModTime: modTime},
+		"link":              {Data: []byte("a.txt"), Mode: fs.ModeSymlink | 0o777, ModTime: modTime},
+		"emptydir":          {Mode: fs.ModeDir | 0o755},
+		"z/last/file.bytes": {Data: []byte{0, 1, 2, 3}, Mode: 0o644, ModTime: modTime},
+	}
+
+	for _, compression := range []Compression{CompressionNone, CompressionZstd} {
+		t.Run(compression.String(), func(t *testing.T) {
+			t.Parallel()
+
+			var indexBuf, dataBuf bytes.Buffer
+			err := CreateFS(context.Background(), fsys, &indexBuf, &dataBuf,
+				CreateWithCompression(compression),
+				CreateWithSymlinks(true),
+			)
+			require.NoError(t, err)
+
+			b, err := New(indexBuf.Bytes(), testutil.NewMockByteSource(dataBuf.Bytes()))
+			require.NoError(t, err)
+			require.NoError(t, b.Verify(context.Background()))
+			assert.Equal(t, 6, b.Len(), "directories are not recorded")
+
+			for name, f := range fsys {
+				if f.Mode.IsDir() {
+					continue
+				}
+				view, ok := b.Entry(name)
+				require.True(t, ok, name)
+				assert.Equal(t, f.Mode, view.Mode(), name)
+				assert.True(t, modTime.Equal(view.ModTime()), name)
+				if f.Mode&fs.ModeSymlink != 0 {
+					continue
+				}
+				got, err := b.ReadFile(name)
+				require.NoError(t, err, name)
+				assert.Equal(t, string(f.Data), string(got), name)
+			}
+
+			// Symlink targets are stored uncompressed as the entry content.
+			assert.Equal(t, "a.txt", string(storedBytes(t, b, "link")))
+		})
+	}
+}
+
+func TestCreateFS_SkipsSymlinksByDefault(t *testing.T) {
+	t.Parallel()
+
+	fsys := fstest.MapFS{
+		"a.txt": {Data: []byte("alpha")},
+		"link":  {Data: []byte("a.txt"), Mode: fs.ModeSymlink | 0o777},
+	}
+
+	var indexBuf, dataBuf bytes.Buffer
+	require.NoError(t, CreateFS(context.Background(), fsys, &indexBuf, &dataBuf))
+
+	b, err := New(indexBuf.Bytes(), testutil.NewMockByteSource(dataBuf.Bytes()))
+	require.NoError(t, err)
+	assert.True(t, b.Exists("a.txt"))
+	assert.False(t, b.Exists("link"))
+}
+
+func TestCreateFS_MatchesCreate(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	createTestFilesBytes(t, dir, map[string][]byte{
+		"a.txt":         []byte("alpha"),
+		"a-b.txt":       []byte("a-b"),
+		"a/b.txt":       bytes.Repeat([]byte("bravo "), 500),
+		"dir/sub/c.txt": []byte("charlie"),
+	})
+
+	opts := []CreateOption{CreateWithCompression(CompressionZstd)}
+	var wantIndex, wantData bytes.Buffer
+	require.NoError(t, Create(context.Background(), dir, &wantIndex, &wantData, opts...))
+	var gotIndex, gotData bytes.Buffer
+	require.NoError(t, CreateFS(context.Background(), os.DirFS(dir), &gotIndex, &gotData, opts...))
+
+	assert.Equal(t, wantIndex.Bytes(), gotIndex.Bytes())
+	assert.Equal(t, wantData.Bytes(), gotData.Bytes())
+}
+
+func TestCreateFS_MaxFiles(t *testing.T) {
+	t.Parallel()
+
+	fsys := fstest.MapFS{
+		"a.txt": {Data: []byte("a")},
+		"b.txt": {Data: []byte("b")},
+		"c.txt": {Data: []byte("c")},
+	}
+	var indexBuf, dataBuf bytes.Buffer
+	err := CreateFS(context.Background(), fsys, &indexBuf, &dataBuf, CreateWithMaxFiles(2))
+	require.ErrorIs(t, err, ErrTooManyFiles)
+}
+
+func TestCreateFS_Canceled(t *testing.T) {
+	t.Parallel()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	var indexBuf, dataBuf bytes.Buffer
+	err := CreateFS(ctx, fstest.MapFS{"a.txt": {Data: []byte("a")}}, &indexBuf, &dataBuf)
+	require.ErrorIs(t, err, context.Canceled)
+}
@@ -2,6 +2,10 @@ package blob
 
 import (
 	"log/slog"
+	"time"
+
+	"github.com/klauspost/compress/zstd"
+	"github.com/opencontainers/go-digest"
 
 	"github.com/meigma/blob/core/internal/write"
 )
@@ -25,14 +29,107 @@ const (
 	ChangeDetectionStrict
 )
 
+// ConcurrentModification controls how Create handles a file whose size
+// changes between stat and read.
+type ConcurrentModification uint8
+
+// Concurrent modification modes.
+const (
+	// ConcurrentModificationError fails Create with ErrFileChanged.
+	ConcurrentModificationError ConcurrentModification = iota
+	// ConcurrentModificationRetry re-reads the file from the start, failing
+	// with ErrFileChanged if it keeps changing. Each file is staged in memory
+	// before it is written so a failed attempt can be discarded.
+	ConcurrentModificationRetry
+	// ConcurrentModificationTruncate records the content read up to the size
+	// observed at stat time. Bytes appended afterwards are ignored; a file
+	// that shrank is recorded with the bytes that remained.
+	ConcurrentModificationTruncate
+)
+
+// maxModificationRetries bounds re-reads under ConcurrentModificationRetry.
+const maxModificationRetries = 3
+
+// CompressionLevel controls the speed/ratio tradeoff of the zstd encoder.
+//
+// The level only affects archive creation. It is not recorded in the index
+// because zstd decompression is level-independent.
+type CompressionLevel uint8
+
+// Compression levels.
+const (
+	// CompressionLevelDefault uses the zstd default level (roughly zstd level 3).
+	CompressionLevelDefault CompressionLevel = iota
+	// CompressionLevelFastest favors throughput over ratio (roughly zstd level 1).
+	CompressionLevelFastest
+	// CompressionLevelBetter trades throughput for ratio (roughly zstd level 7-8).
+	CompressionLevelBetter
+	// CompressionLevelBest favors ratio over throughput (roughly zstd level 11).
+	CompressionLevelBest
+)
+
+// String returns the human-readable name of the compression level.
+func (l CompressionLevel) String() string {
+	switch l {
+	case CompressionLevelDefault:
+		return "default"
+	case CompressionLevelFastest:
+		return "fastest"
+	case CompressionLevelBetter:
+		return "better"
+	case CompressionLevelBest:
+		return "best"
+	default:
+		return "unknown"
+	}
+}
+
+// encoderLevel maps the level to the zstd encoder level.
+// Unknown levels fall back to the default.
+func (l CompressionLevel) encoderLevel() zstd.EncoderLevel {
+	switch l {
+	case CompressionLevelFastest:
+		return zstd.SpeedFastest
+	case CompressionLevelBetter:
+		return zstd.SpeedBetterCompression
+	case CompressionLevelBest:
+		return zstd.SpeedBestCompression
+	default:
+		return zstd.SpeedDefault
+	}
+}
+
 // createConfig holds configuration for archive creation.
 type createConfig struct {
-	compression     Compression
-	changeDetection ChangeDetection
-	skipCompression []SkipCompressionFunc
-	maxFiles        int
-	logger          *slog.Logger
-	progress        ProgressFunc
+	compression      Compression
+	compressionLevel CompressionLevel
+	workers          int
+	changeDetection  ChangeDetection
+	modification     ConcurrentModification
+	skipCompression  []SkipCompressionFunc
+	maxFiles         int
+	pathLimits       pathLimits
+	caseCollisions   bool
+	symlinks         bool
+	include          []string
+	exclude          []string
+	ignoreFile       string
+	stripComponents  int
+	pathPrefix       string
+	modTime          *time.Time
+	encryption       Encryption
+	encryptionKey    []byte
+	auxChecksum      AuxChecksum
+	bloomFilter      bool
+	chunkSize        int
+	tempDir          string
+	minSavings       float64
+	minSavingsSet    bool
+	zstdDictionary   []byte
+	logger           *slog.Logger
+	progress         ProgressFunc
+	indexDigest      *digest.Digest
+	dataDigest       *digest.Digest
 }
 
 // CreateOption configures archive creation via the Create function.
@@ -46,6 +143,55 @@ func CreateWithCompression(c Compression) CreateOption {
 	}
 }
 
+// CreateWithCompressionLevel sets the zstd encoder level.
+// It has no effect unless compression is enabled with CreateWithCompression.
+// The zero value uses CompressionLevelDefault.
+func CreateWithCompressionLevel(level CompressionLevel) CreateOption {
+	return func(cfg *createConfig) {
+		cfg.compressionLevel = level
+	}
+}
+
+// DefaultMinCompressionRatio is the minimum fraction of space a compressed
+// file must save to be stored compressed when no CreateWithMinCompressionRatio
+// option is set.
+const DefaultMinCompressionRatio = 0.05
+
+// CreateWithMinCompressionRatio sets the minimum fraction of the original size
+// that compression must save for a file to be stored compressed (default: 0.05).
+// Files that do not meet the threshold are stored uncompressed, producing a
+// mixed-compression archive.
+//
+// Each candidate file up to 16 MiB is compressed into a scratch buffer
+// before being written. To bound that buffer, larger files are judged by
+// compressing their first 4 MiB: if the sample meets the threshold the file
+// is streamed compressed, and otherwise it is stored uncompressed. Values
+// <= 0 disable the check for every file.
+func CreateWithMinCompressionRatio(ratio float64) CreateOption {
+	return func(cfg *createConfig) {
+		cfg.minSavings = ratio
+		cfg.minSavingsSet = true
+	}
+}
+
+// CreateWithZstdDictionary compresses files using a pre-trained zstd dictionary.
+//
+// Dictionaries substantially improve ratios for archives of many small,
+// similar files (configs, manifests, source), where each file is compressed
+// independently and has little history of its own. The dictionary is not
+// written to the archive: it is kept as its own blob, shared by any number
+// of archives, and the index records only its SHA-256. Readers supply it
+// with WithZstdDictionary. Use TrainZstdDictionary to build one from sample
+// content.
+//
+// The dictionary must be in the zstd dictionary format. It has no effect
+// unless compression is enabled with CreateWithCompression.
+func CreateWithZstdDictionary(dict []byte) CreateOption {
+	return func(cfg *createConfig) {
+		cfg.zstdDictionary = dict
+	}
+}
+
 // CreateWithChangeDetection controls whether the writer verifies files did not change
 // during archive creation. The zero value disables change detection to reduce
 // syscalls; enable ChangeDetectionStrict for stronger guarantees.
@@ -55,6 +201,20 @@ func CreateWithChangeDetection(cd ChangeDetection) CreateOption {
 	}
 }
 
+// CreateWithConcurrentModification sets how Create handles a file whose size
+// changes between stat and read, as happens when archiving a live directory.
+// The zero value, ConcurrentModificationError, fails with ErrFileChanged
+// naming the path.
+//
+// With ChangeDetectionStrict, any change detected after reading is reported
+// as ErrFileChanged and is retried under ConcurrentModificationRetry, but is
+// not ignored by ConcurrentModificationTruncate.
+func CreateWithConcurrentModification(mode ConcurrentModification) CreateOption {
+	return func(cfg *createConfig) {
+		cfg.modification = mode
+	}
+}
+
 // CreateWithSkipCompression adds predicates that decide to store a file uncompressed.
 // If any predicate returns true, compression is skipped for that file.
 // These checks are on the hot path, so keep them cheap.
@@ -65,13 +225,277 @@ func CreateWithSkipCompression(fns ...SkipCompressionFunc) CreateOption {
 }
 
 // CreateWithMaxFiles limits the number of files included in the archive.
-// Zero uses DefaultMaxFiles. Negative means no limit.
+// The limit is enforced during the walk: Create fails with ErrTooManyFiles
+// as soon as one more file would be written. Zero uses DefaultMaxFiles.
+// Negative means no limit. Readers can apply a limit of their own with
+// WithMaxFiles.
 func CreateWithMaxFiles(n int) CreateOption {
 	return func(cfg *createConfig) {
 		cfg.maxFiles = n
 	}
 }
 
+// CreateWithMaxPathLength rejects files whose archive path is longer than n
+// bytes, failing Create with a *PathLimitError. Zero or negative disables the
+// limit (the default).
+func CreateWithMaxPathLength(n int) CreateOption {
+	return func(cfg *createConfig) {
+		cfg.pathLimits.maxLength = n
+	}
+}
+
+// CreateWithRejectCaseCollisions fails Create with ErrCaseCollision when
+// enabled and two archive paths, or the directories above them, differ only
+// in case, such as "README" and "readme" or "Docs/a" and "docs/b". Such
+// archives collide when extracted on case-insensitive file systems and are
+// ambiguous under WithCaseInsensitiveLookup. Paths are compared after path
+// rewriting, using Unicode simple case folding. Disabled by default.
+func CreateWithRejectCaseCollisions(enabled bool) CreateOption {
+	return func(cfg *createConfig) {
+		cfg.caseCollisions = enabled
+	}
+}
+
+// CreateWithMaxPathDepth rejects files whose archive path has more than n
+// elements ("a/b/c" has depth 3), failing Create with a *PathLimitError.
+// Zero or negative disables the limit (the default).
+func CreateWithMaxPathDepth(n int) CreateOption {
+	return func(cfg *createConfig) {
+		cfg.pathLimits.maxDepth = n
+	}
+}
+
+// CreateWithExclude leaves out files and directories matching any of
+// patterns. Excluded directories are pruned: the walk does not descend into
+// them, so skipping trees such as ".git" or "node_modules" costs nothing.
+//
+// Patterns are matched against slash-separated paths relative to the
+// archive root using gitignore rules: a pattern without a slash, such as
+// "*.tmp" or "node_modules", matches at any depth; a pattern with a leading
+// or inner slash, such as "/build" or "docs/*.md", is anchored at the root;
+// a trailing slash matches only directories; and a "**" element matches any
+// number of directories. Other elements use path.Match syntax. Create
+// returns an error for malformed patterns.
+//
+// CreateWithInclude takes precedence: a path matching an include pattern is
+// archived even when an exclude pattern also matches it. A file beneath an
+// excluded directory cannot be included, since the directory is never read;
+// include the directory itself instead.
+func CreateWithExclude(patterns ...string) CreateOption {
+	return func(cfg *createConfig) {
+		cfg.exclude = append(cfg.exclude, patterns...)
+	}
+}
+
+// CreateWithInclude restricts the archive to files matching any of
+// patterns, or lying beneath a directory that matches one. Directories are
+// always walked unless excluded, so "*.go" selects Go files at any depth.
+// Patterns follow the rules of CreateWithExclude.
+//
+// Include patterns override exclude patterns and the ignore file: a path
+// matching both CreateWithInclude and CreateWithExclude is archived. Files
+// beneath an included directory are still subject to exclusion, so
+// including "src" and excluding "*.tmp" archives src without its temporary
+// files.
+func CreateWithInclude(patterns ...string) CreateOption {
+	return func(cfg *createConfig) {
+		cfg.include = append(cfg.include, patterns...)
+	}
+}
+
+// CreateWithIgnoreFile reads exclusion patterns from the file name at the
+// root of the tree being archived, such as ".blobignore". A missing file is
+// ignored.
+//
+// The file uses gitignore syntax: one pattern per line, following the rules
+// of CreateWithExclude, with blank lines and lines starting with "#"
+// skipped. A line starting with "!" re-includes paths matched by an earlier
+// line; the last matching line wins. As in gitignore, a file cannot be
+// re-included once its parent directory is excluded. Only the root file is
+// read; ignore files in subdirectories have no effect. The ignore file
+// itself is archived unless a pattern excludes it.
+func CreateWithIgnoreFile(name string) CreateOption {
+	return func(cfg *createConfig) {
+		cfg.ignoreFile = name
+	}
+}
+
+// CreateWithStripPrefix drops the first n elements of every path, as
+// tar --strip-components does, so archiving a tree holding
+// "build/output/dist/app.js" with n = 2 stores "dist/app.js". Files with n
+// or fewer elements are skipped. Negative values are rejected.
+//
+// Paths are rewritten after the walk and before sorting, so the archive is
+// ordered by the stored paths. Include, exclude, and ignore file patterns
+// match the original paths; CreateWithMaxPathLength and
+// CreateWithMaxPathDepth apply to the stored ones. If two files map to the
+// same path, or a file maps to a path inside another file, Create fails
+// with ErrPathConflict naming both sources.
+func CreateWithStripPrefix(n int) CreateOption {
+	return func(cfg *createConfig) {
+		cfg.stripComponents = n
+	}
+}
+
+// CreateWithPathPrefix stores every file under the directory p, so "app.js"
+// becomes "p/app.js". It is applied after CreateWithStripPrefix. The prefix
+// must be a valid fs.FS path; a trailing slash is ignored.
+func CreateWithPathPrefix(p string) CreateOption {
+	return func(cfg *createConfig) {
+		cfg.pathPrefix = p
+	}
+}
+
+// CreateWithSymlinks records symbolic links in the archive instead of
+// skipping them.
+//
+// A link is stored as an uncompressed entry whose content is the link target
+// and whose mode has fs.ModeSymlink set. Targets are recorded as-is; links
+// are never followed. Links count toward CreateWithMaxFiles. Use
+// CopyWithSymlinks to recreate them during extraction.
+func CreateWithSymlinks(enabled bool) CreateOption {
+	return func(cfg *createConfig) {
+		cfg.symlinks = enabled
+	}
+}
+
+// CreateWithModTime records t as the modification time of every entry
+// instead of each file's own modification time.
+//
+// File modification times differ between checkouts and machines, so they
+// are the usual reason two builds of the same tree produce different
+// indexes. With a fixed time and the same compression settings, building
+// the same tree twice yields byte-identical index and data blobs. Owner IDs
+// and permission bits are still recorded from the files, and
+// CreateWithEncryption uses random nonces, so those must also match (or be
+// left unset) for reproducible output.
+func CreateWithModTime(t time.Time) CreateOption {
+	return func(cfg *createConfig) {
+		cfg.modTime = &t
+	}
+}
+
+// CreateWithModTimeZero records the Unix epoch, time.Unix(0, 0), as the
+// modification time of every entry. It is shorthand for
+// CreateWithModTime(time.Unix(0, 0)).
+func CreateWithModTimeZero() CreateOption {
+	return CreateWithModTime(time.Unix(0, 0))
+}
+
+// CreateWithEncryption encrypts each file's content in the data blob.
+//
+// Content is compressed first (if enabled) and then sealed with the given
+// scheme using a random per-file nonce recorded in the index, so each file
+// can still be fetched and decrypted with its own range read. The seal
+// covers the file's path and data offset, so stored bytes moved to another
+// entry or position fail to decrypt. With
+// EncryptionAESGCM the key must be 16, 24, or 32 bytes; Create returns an
+// error otherwise.
+//
+// Only file content is encrypted. The index, including paths, sizes, and
+// metadata, stays in the clear, and entry hashes are computed over the
+// plaintext so they identify content independently of the key. Readers need
+// the same key via WithDecryptionKey.
+//
+// A file is sealed and authenticated as a whole, so each file is held in
+// memory in full while it is written, and again whenever it is read. Use
+// encryption for archives whose individual files fit comfortably in memory.
+func CreateWithEncryption(key []byte, scheme Encryption) CreateOption {
+	return func(cfg *createConfig) {
+		cfg.encryption = scheme
+		cfg.encryptionKey = key
+	}
+}
+
+// CreateWithAuxChecksum records a fast non-cryptographic checksum of each
+// file's uncompressed content in the index, alongside its SHA256 hash.
+//
+// The checksum is exposed by EntryView.AuxChecksum and lets SyncDir detect
+// unchanged local files without hashing them with SHA256. It is not a
+// substitute for the SHA256 hash: reads are always verified with SHA256.
+// Use AuxChecksumXXH64; AuxChecksumNone (the default) records nothing.
+func CreateWithAuxChecksum(alg AuxChecksum) CreateOption {
+	return func(cfg *createConfig) {
+		cfg.auxChecksum = alg
+	}
+}
+
+// CreateWithBloomFilter stores a bloom filter over the archived paths in the
+// index when enabled is true.
+//
+// Lookups of paths that are not in the archive, such as Stat or Open of a
+// missing file, are then usually rejected without a binary search of the
+// index. The filter costs about 10 bits per entry of index size and answers
+// false positives for about 1% of missing paths, which fall back to the
+// search. It is off by default and pays off for archives with many entries
+// that see frequent misses. Archives without a filter read as before.
+func CreateWithBloomFilter(enabled bool) CreateOption {
+	return func(cfg *createConfig) {
+		cfg.bloomFilter = enabled
+	}
+}
+
+// CreateWithCompressionWorkers compresses up to n files concurrently.
+//
+// Each worker writes a file's compressed content to a buffer of its own,
+// and the buffers are appended to the data blob in path order, so the
+// output is byte-for-byte the same for any n. Files larger than 8 MiB are
+// not buffered but compressed straight into the data blob when their turn
+// comes, so at most n+1 buffers of up to 8 MiB each are held in memory.
+// Values <= 1 (the default) compress files one at a time on the calling
+// goroutine.
+func CreateWithCompressionWorkers(n int) CreateOption {
+	return func(cfg *createConfig) {
+		cfg.workers = n
+	}
+}
+
+// CreateWithTempDir sets the directory for temporary files created while
+// building an archive. Create keeps the metadata of the first 65536 entries
+// in memory and moves it to a temporary file once an archive has more, so
+// memory use does not grow with the entry count; the file is removed when
+// Create returns. The default is os.TempDir.
+func CreateWithTempDir(dir string) CreateOption {
+	return func(cfg *createConfig) {
+		cfg.tempDir = dir
+	}
+}
+
+// CreateWithChunking stores files larger than avgChunkSize*4 bytes as
+// content-defined chunks averaging avgChunkSize bytes.
+//
+// Chunk boundaries are chosen by a rolling hash of the content, so a small
+// edit to a large file changes only the chunks around it. Each chunk's
+// SHA256 hash is recorded in the index, and Blob.Open and Blob.ReadFile
+// with a cache store chunks under their own hashes: reading a new version
+// of a file after an old one fetches only the chunks that changed. Open
+// streams the file a chunk at a time. The whole-file hash is still
+// recorded and verified once every chunk has been read. CopyDir and other
+// batch extraction do not use the cache and read chunked files whole.
+//
+// Chunks are stored back to back in the file's data region, each compressed
+// independently, so readers that ignore chunks read the file as before. The
+// size must be between 1 KiB and 64 MiB; zero (the default) disables
+// chunking. Chunking cannot be combined with CreateWithEncryption.
+func CreateWithChunking(avgChunkSize int) CreateOption {
+	return func(cfg *createConfig) {
+		cfg.chunkSize = avgChunkSize
+	}
+}
+
+// CreateWithDigests records the OCI digests of the index and data blobs.
+//
+// The digests are computed while the blobs are written, so pipelines that
+// push the output can supply them without hashing the data a second time.
+// Either pointer may be nil. The targets are only written when Create
+// succeeds.
+func CreateWithDigests(index, data *digest.Digest) CreateOption {
+	return func(cfg *createConfig) {
+		cfg.indexDigest = index
+		cfg.dataDigest = data
+	}
+}
+
 // CreateWithLogger sets the logger for archive creation.
 // If not set, logging is disabled.
 func CreateWithLogger(logger *slog.Logger) CreateOption {
@@ -81,6 +505,13 @@ func CreateWithLogger(logger *slog.Logger) CreateOption {
 }
 
 // CreateWithProgress sets a callback to receive progress updates.
+//
+// Before writing, the tree is walked once to count the files to archive: a
+// StageEnumerating event reports that count in FilesTotal and their total
+// size in BytesTotal. Then a StageCompressing event follows each file as
+// it is compressed and appended, with FilesDone and BytesDone counting the
+// files and uncompressed bytes written so far. BytesDone reaches BytesTotal
+// unless files change during the walk.
 // The callback may be invoked concurrently and must be safe for concurrent use.
 func CreateWithProgress(fn ProgressFunc) CreateOption {
 	return func(cfg *createConfig) {
@@ -0,0 +1,130 @@
+package blob
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"io/fs"
+	"sync/atomic"
+)
+
+// parallelBufferSize is the largest file a compression worker buffers.
+// Larger files are written by the serializer, streaming as in a serial walk.
+const parallelBufferSize = 8 << 20
+
+// errWalkStopped ends a parallel walk once an earlier entry has failed.
+var errWalkStopped = errors.New("walk stopped")
+
+// compressJob is a walked path whose content a worker writes to data.
+type compressJob struct {
+	name  string
+	data  bytes.Buffer
+	entry Entry
+	skip  bool
+	err   error
+	done  chan struct{} // closed when the worker has finished
+
+	// inline jobs are left to the serializer, which writes them directly.
+	inline bool
+	path   string
+	d      fs.DirEntry
+}
+
+// walkParallel is the CreateWithCompressionWorkers counterpart of
+// walkSerial. The walk hands each path to one of the workers, which writes
+// its content to the job's own buffer, while a serializer waits for the
+// jobs in walk order and passes them to add. Files larger than
+// parallelBufferSize are not buffered: the serializer writes them to data
+// itself when their turn comes. Entries, offsets, and data bytes are
+// therefore the same as with a serial walk.
+func (w *writer) walkParallel(ctx context.Context, fsys fs.FS, data io.Writer, process entryFunc, add addFunc) error {
+	n := w.cfg.workers
+	workspaces := make(chan *workspace, n)
+	for range n {
+		ws, err := w.newWorkspace()
+		if err != nil {
+			return err
+		}
+		workspaces <- ws
+	}
+	inlineWS, err := w.newWorkspace()
+	if err != nil {
+		return err
+	}
+
+	// The queue bounds the jobs waiting to be serialized, and with them the
+	// buffered content, to n besides the one being serialized.
+	queue := make(chan *compressJob, n)
+	var stopped atomic.Bool
+	serialized := make(chan error, 1)
+	go func() {
+		var err error
+		count := 0
+		maxFiles := w.maxFiles()
+		for job := range queue {
+			<-job.done
+			if err != nil {
+				continue // drain the remaining jobs
+			}
+			content := &job.data
+			if job.inline {
+				dest := data
+				content = nil
+				if w.aead != nil {
+					w.plain.Reset()
+					dest, content = &w.plain, &w.plain
+				}
+				job.entry, job.skip, job.err = process(dest, inlineWS, job.path, job.d, nil, count)
+			}
+			switch {
+			case job.err != nil:
+				err = job.err
+			case job.skip:
+			case maxFiles > 0 && count >= maxFiles:
+				// Workers do not know how many entries precede theirs.
+				err = ErrTooManyFiles
+			default:
+				count++
+				err = add(job.name, job.entry, content)
+			}
+			if err != nil {
+				stopped.Store(true)
+			}
+		}
+		serialized <- err
+	}()
+
+	walkErr := w.walk(ctx, fsys, func(path, name string, d fs.DirEntry, walkErr error) error {
+		if stopped.Load() {
+			return errWalkStopped
+		}
+		job := &compressJob{name: name, done: make(chan struct{})}
+		if walkErr == nil && d.Type().IsRegular() {
+			if info, err := d.Info(); err == nil && info.Size() > parallelBufferSize {
+				job.inline, job.path, job.d = true, path, d
+				close(job.done)
+				queue <- job
+				return nil
+			}
+		}
+		ws := <-workspaces
+		queue <- job
+		go func() {
+			defer close(job.done)
+			job.entry, job.skip, job.err = process(&job.data, ws, path, d, walkErr, 0)
+			workspaces <- ws
+		}()
+		return nil
+	})
+	close(queue)
+
+	// The serializer waits for every queued job, so no worker is left running.
+	if err := <-serialized; err != nil {
+		return err
+	}
+	if walkErr != nil && !errors.Is(walkErr, errWalkStopped) {
+		return walkErr
+	}
+	return nil
+}
@@ -0,0 +1,132 @@
+package blob
+
+import (
+	"context"
+	"fmt"
+	"io/fs"
+	"path"
+	"slices"
+	"strings"
+)
+
+// rewrittenPath is a walked file and the archive path it is stored at.
+type rewrittenPath struct {
+	src  string
+	dest string
+	d    fs.DirEntry
+}
+
+// validateRewrite checks the CreateWithStripPrefix and CreateWithPathPrefix
+// settings and normalizes the prefix.
+func validateRewrite(cfg *createConfig) error {
+	if cfg.stripComponents < 0 {
+		return fmt.Errorf("strip prefix: negative component count %d", cfg.stripComponents)
+	}
+	cfg.pathPrefix = strings.TrimSuffix(cfg.pathPrefix, "/")
+	if cfg.pathPrefix != "" && (!fs.ValidPath(cfg.pathPrefix) || cfg.pathPrefix == ".") {
+		return &fs.PathError{Op: "path prefix", Path: cfg.pathPrefix, Err: fs.ErrInvalid}
+	}
+	return nil
+}
+
+// rewriting reports whether stored paths differ from walked paths.
+func (w *writer) rewriting() bool {
+	return w.cfg.stripComponents > 0 || w.cfg.pathPrefix != ""
+}
+
+// checkPathLimits checks the walked path against the configured path
+// limits. With path rewriting the stored path is checked instead, once it
+// is known.
+func (w *writer) checkPathLimits(path string) error {
+	if w.rewriting() {
+		return nil
+	}
+	return w.cfg.pathLimits.check(path)
+}
+
+// rewritePath returns the archive path for the walked path p, or false when
+// stripping leaves nothing of it.
+func (w *writer) rewritePath(p string) (string, bool) {
+	for range w.cfg.stripComponents {
+		slash := strings.IndexByte(p, '/')
+		if slash < 0 {
+			return "", false
+		}
+		p = p[slash+1:]
+	}
+	if w.cfg.pathPrefix != "" {
+		p = w.cfg.pathPrefix + "/" + p
+	}
+	return p, true
+}
+
+// walkRewritten walks fsys, rewrites the path of every file, and calls visit
+// for each file in the index order of the rewritten paths.
+func (w *writer) walkRewritten(ctx context.Context, fsys fs.FS, visit func(path, name string, d fs.DirEntry, walkErr error) error) error {
+	var paths []rewrittenPath
+	err := fs.WalkDir(fsys, ".", func(p string, d fs.DirEntry, walkErr error) error {
+		if walkErr != nil {
+			return walkErr
+		}
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if w.filtered(p, d, nil) {
+			if d.IsDir() {
+				return fs.SkipDir
+			}
+			return nil
+		}
+		isSymlink := d.Type()&fs.ModeSymlink != 0
+		if d.IsDir() || !d.Type().IsRegular() && !(isSymlink && w.cfg.symlinks) {
+			return nil
+		}
+		dest, ok := w.rewritePath(p)
+		if !ok {
+			w.log().Debug("skipped path shorter than strip prefix", "path", p)
+			return nil
+		}
+		paths = append(paths, rewrittenPath{src: p, dest: dest, d: d})
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	slices.SortFunc(paths, func(a, b rewrittenPath) int {
+		if c := strings.Compare(a.dest, b.dest); c != 0 {
+			return c
+		}
+		return strings.Compare(a.src, b.src)
+	})
+	if err := checkRewriteConflicts(paths); err != nil {
+		return err
+	}
+
+	for _, p := range paths {
+		if err := visit(p.src, p.dest, p.d, nil); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// checkRewriteConflicts rejects sorted paths where two files share an
+// archive path or a file would sit beneath another file.
+func checkRewriteConflicts(paths []rewrittenPath) error {
+	files := make(map[string]string, len(paths)) // dest -> src
+	for i, p := range paths {
+		if i > 0 && paths[i-1].dest == p.dest {
+			return fmt.Errorf("%w: %s and %s both map to %s", ErrPathConflict, paths[i-1].src, p.src, p.dest)
+		}
+		files[p.dest] = p.src
+	}
+	for _, p := range paths {
+		for dir := path.Dir(p.dest); dir != "."; dir = path.Dir(dir) {
+			if src, ok := files[dir]; ok {
+				return fmt.Errorf("%w: %s maps to %s, beneath file %s from %s", ErrPathConflict, p.src, p.dest, dir, src)
+			}
+		}
+	}
+	return nil
+}
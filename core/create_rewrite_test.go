@@ -0,0 +1,158 @@
+package blob
+
+import (
+	"bytes"
+	"context"
+	"testing"
+	"testing/fstest"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/meigma/blob/core/testutil"
+)
+
+func TestCreateWithStripPrefix(t *testing.T) {
+	t.Parallel()
+
+	files := map[string]string{
+		"build/output/dist/app.js":        "app",
+		"build/output/dist/css/site.css":  "css",
+		"build/output/dist/index.html":    "html",
+		"build/output/manifest.json":      "manifest",
+		"build/output/zz/late.txt":        "late",
+		"build/output.log":                "dropped",
+		"top.txt":                         "dropped",
+		"build/output/dist/nested/a/b.md": "nested",
+	}
+
+	got := archivedPaths(t, files, CreateWithStripPrefix(2))
+	assert.Equal(t, []string{
+		"dist/app.js", "dist/css/site.css", "dist/index.html",
+		"dist/nested/a/b.md", "manifest.json", "zz/late.txt",
+	}, got)
+
+	// Content follows its file to the new path.
+	dir := t.TempDir()
+	createTestFiles(t, dir, files)
+	var indexBuf, dataBuf bytes.Buffer
+	require.NoError(t, Create(context.Background(), dir, &indexBuf, &dataBuf,
+		CreateWithStripPrefix(2), CreateWithCompression(CompressionZstd)))
+	b, err := New(indexBuf.Bytes(), testutil.NewMockByteSource(dataBuf.Bytes()), WithValidateLayout(true))
+	require.NoError(t, err)
+	content, err := b.ReadFile("dist/css/site.css")
+	require.NoError(t, err)
+	assert.Equal(t, "css", string(content))
+
+	// Data is stored in the order of the rewritten paths, so directory
+	// contents stay contiguous.
+	var prevEnd uint64
+	for view := range b.Entries() {
+		assert.Equal(t, prevEnd, view.DataOffset(), view.Path())
+		prevEnd = view.DataOffset() + view.DataSize()
+	}
+}
+
+func TestCreateWithPathPrefix(t *testing.T) {
+	t.Parallel()
+
+	files := map[string]string{
+		"a.txt":     "a",
+		"dir/b.txt": "b",
+	}
+
+	t.Run("prepend", func(t *testing.T) {
+		t.Parallel()
+		got := archivedPaths(t, files, CreateWithPathPrefix("opt/app/"))
+		assert.Equal(t, []string{"opt/app/a.txt", "opt/app/dir/b.txt"}, got)
+	})
+
+	t.Run("after strip", func(t *testing.T) {
+		t.Parallel()
+		got := archivedPaths(t, files, CreateWithStripPrefix(1), CreateWithPathPrefix("lib"))
+		assert.Equal(t, []string{"lib/b.txt"}, got)
+	})
+
+	t.Run("CreateFS", func(t *testing.T) {
+		t.Parallel()
+		fsys := fstest.MapFS{
+			"x/one.txt": {Data: []byte("1")},
+			"y/two.txt": {Data: []byte("2")},
+		}
+		var indexBuf, dataBuf bytes.Buffer
+		require.NoError(t, CreateFS(context.Background(), fsys, &indexBuf, &dataBuf,
+			CreateWithStripPrefix(1), CreateWithPathPrefix("root")))
+		b, err := New(indexBuf.Bytes(), testutil.NewMockByteSource(dataBuf.Bytes()))
+		require.NoError(t, err)
+		content, err := b.ReadFile("root/two.txt")
+		require.NoError(t, err)
+		assert.Equal(t, "2", string(content))
+	})
+
+	t.Run("path limits apply to stored paths", func(t *testing.T) {
+		t.Parallel()
+		dir := t.TempDir()
+		createTestFiles(t, dir, files)
+		var indexBuf, dataBuf bytes.Buffer
+		err := Create(context.Background(), dir, &indexBuf, &dataBuf,
+			CreateWithPathPrefix("deep/er"), CreateWithMaxPathDepth(3))
+		require.ErrorIs(t, err, ErrPathLimit)
+	})
+}
+
+func TestCreateRewriteConflicts(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name  string
+		files map[string]string
+		want  string
+	}{
+		{
+			name: "same path",
+			files: map[string]string{
+				"a/x.txt": "a",
+				"b/x.txt": "b",
+				"c/x.txt": "c",
+			},
+			want: "a/x.txt and b/x.txt both map to x.txt",
+		},
+		{
+			name: "file beneath file",
+			files: map[string]string{
+				"a/lib":       "file",
+				"b/lib/z.txt": "nested",
+			},
+			want: "b/lib/z.txt maps to lib/z.txt, beneath file lib from a/lib",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			// Repeated runs report the same conflict.
+			for range 3 {
+				dir := t.TempDir()
+				createTestFiles(t, dir, tt.files)
+				var indexBuf, dataBuf bytes.Buffer
+				err := Create(context.Background(), dir, &indexBuf, &dataBuf, CreateWithStripPrefix(1))
+				require.ErrorIs(t, err, ErrPathConflict)
+				assert.Contains(t, err.Error(), tt.want)
+			}
+		})
+	}
+}
+
+func TestCreateRewriteInvalidOptions(t *testing.T) {
+	t.Parallel()
+
+	for _, opt := range []CreateOption{
+		CreateWithStripPrefix(-1),
+		CreateWithPathPrefix("../up"),
+		CreateWithPathPrefix("/abs"),
+		CreateWithPathPrefix("./"),
+	} {
+		var indexBuf, dataBuf bytes.Buffer
+		err := Create(context.Background(), t.TempDir(), &indexBuf, &dataBuf, opt)
+		assert.Error(t, err)
+	}
+}
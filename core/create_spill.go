@@ -0,0 +1,273 @@
+package blob
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"time"
+)
+
+// entrySpillThreshold is the number of entries an entryLog holds in memory
+// before it moves them to a temporary file. Below it, archives are built
+// entirely in memory as before.
+const entrySpillThreshold = 1 << 16
+
+// entrySource provides the entries of an index by position.
+type entrySource interface {
+	Len() int
+	At(i int) (Entry, error)
+}
+
+// entrySlice is an entrySource over entries held in memory.
+type entrySlice []Entry
+
+func (s entrySlice) Len() int { return len(s) }
+
+func (s entrySlice) At(i int) (Entry, error) { return s[i], nil }
+
+// entryLog collects the entries written by Create. Once it holds more than
+// threshold entries it encodes them to a temporary file in dir, so memory
+// use no longer grows with the entry count beyond an offset per entry. The
+// index is built from the log with buildIndexFrom.
+type entryLog struct {
+	dir       string
+	threshold int
+	mem       []Entry
+
+	file    *os.File
+	w       *bufio.Writer
+	offsets []int64 // start of each record in file, then its end
+	dirty   bool    // w holds records not yet flushed to file
+	buf     []byte
+}
+
+// newEntryLog returns an empty entryLog that spills to dir, or to the
+// default directory for temporary files when dir is empty.
+func newEntryLog(dir string, threshold int) *entryLog {
+	return &entryLog{dir: dir, threshold: threshold}
+}
+
+// Append adds entry to the log.
+func (l *entryLog) Append(entry Entry) error {
+	if l.file == nil {
+		if len(l.mem) < l.threshold {
+			l.mem = append(l.mem, entry)
+			return nil
+		}
+		if err := l.spill(); err != nil {
+			return err
+		}
+	}
+	return l.write(&entry)
+}
+
+// spill moves the entries held in memory to a new temporary file.
+func (l *entryLog) spill() error {
+	f, err := os.CreateTemp(l.dir, "blob-entries-*")
+	if err != nil {
+		return fmt.Errorf("create entry spill file: %w", err)
+	}
+	l.file = f
+	l.w = bufio.NewWriterSize(f, 64<<10)
+	l.offsets = make([]int64, 1, 2*len(l.mem))
+	for i := range l.mem {
+		if err := l.write(&l.mem[i]); err != nil {
+			return err
+		}
+	}
+	l.mem = nil
+	return nil
+}
+
+// write appends the record for entry to the spill file.
+func (l *entryLog) write(entry *Entry) error {
+	l.buf = appendEntryRecord(l.buf[:0], entry)
+	if _, err := l.w.Write(l.buf); err != nil {
+		return fmt.Errorf("write entry spill file: %w", err)
+	}
+	l.offsets = append(l.offsets, l.offsets[len(l.offsets)-1]+int64(len(l.buf)))
+	l.dirty = true
+	return nil
+}
+
+// Len returns the number of entries in the log.
+func (l *entryLog) Len() int {
+	if l.file == nil {
+		return len(l.mem)
+	}
+	return len(l.offsets) - 1
+}
+
+// At returns the entry at position i.
+func (l *entryLog) At(i int) (Entry, error) {
+	if l.file == nil {
+		return l.mem[i], nil
+	}
+	if l.dirty {
+		if err := l.w.Flush(); err != nil {
+			return Entry{}, fmt.Errorf("write entry spill file: %w", err)
+		}
+		l.dirty = false
+	}
+	n := int(l.offsets[i+1] - l.offsets[i])
+	if cap(l.buf) < n {
+		l.buf = make([]byte, n)
+	}
+	record := l.buf[:n]
+	if _, err := l.file.ReadAt(record, l.offsets[i]); err != nil {
+		return Entry{}, fmt.Errorf("read entry spill file: %w", err)
+	}
+	return parseEntryRecord(record)
+}
+
+// Close removes the spill file, if any.
+func (l *entryLog) Close() error {
+	l.mem = nil
+	if l.file == nil {
+		return nil
+	}
+	name := l.file.Name()
+	err := l.file.Close()
+	l.file = nil
+	return errors.Join(err, os.Remove(name))
+}
+
+// appendEntryRecord appends the encoding of the fields of entry stored in
+// the index to b. Integers are varints and byte strings are length-prefixed.
+func appendEntryRecord(b []byte, entry *Entry) []byte {
+	b = appendRecordBytes(b, []byte(entry.Path))
+	b = binary.AppendUvarint(b, entry.DataOffset)
+	b = binary.AppendUvarint(b, entry.DataSize)
+	b = binary.AppendUvarint(b, entry.OriginalSize)
+	b = appendRecordBytes(b, entry.Hash)
+	b = binary.AppendUvarint(b, uint64(entry.Mode))
+	b = binary.AppendUvarint(b, uint64(entry.UID))
+	b = binary.AppendUvarint(b, uint64(entry.GID))
+	b = binary.AppendVarint(b, entry.ModTime.UnixNano())
+	b = append(b, byte(entry.Compression))
+	b = appendRecordBytes(b, entry.Nonce)
+	b = binary.AppendUvarint(b, entry.AuxChecksum)
+	b = binary.AppendUvarint(b, uint64(len(entry.Chunks)))
+	for _, c := range entry.Chunks {
+		b = binary.AppendUvarint(b, uint64(c.Size))
+		b = binary.AppendUvarint(b, uint64(c.OriginalSize))
+		b = appendRecordBytes(b, c.Hash)
+	}
+	return b
+}
+
+func appendRecordBytes(b, data []byte) []byte {
+	b = binary.AppendUvarint(b, uint64(len(data)))
+	return append(b, data...)
+}
+
+// parseEntryRecord decodes a record written by appendEntryRecord. The
+// entry does not alias record.
+func parseEntryRecord(record []byte) (Entry, error) {
+	r := recordReader{b: record}
+	entry := Entry{
+		Path:         string(r.bytes()),
+		DataOffset:   r.uvarint(),
+		DataSize:     r.uvarint(),
+		OriginalSize: r.uvarint(),
+		Hash:         r.bytes(),
+		Mode:         fs.FileMode(r.uint32()),
+		UID:          r.uint32(),
+		GID:          r.uint32(),
+		ModTime:      time.Unix(0, r.varint()),
+		Compression:  Compression(r.byte()),
+		Nonce:        r.bytes(),
+		AuxChecksum:  r.uvarint(),
+	}
+	if n := r.uvarint(); n > 0 && r.err == nil {
+		if n > uint64(len(r.b)) {
+			return Entry{}, errCorruptEntryRecord
+		}
+		entry.Chunks = make([]Chunk, n)
+		for i := range entry.Chunks {
+			entry.Chunks[i] = Chunk{Size: r.uint32(), OriginalSize: r.uint32(), Hash: r.bytes()}
+		}
+	}
+	if r.err != nil || len(r.b) > 0 {
+		return Entry{}, errCorruptEntryRecord
+	}
+	return entry, nil
+}
+
+// errCorruptEntryRecord is returned when a spilled entry cannot be decoded.
+var errCorruptEntryRecord = errors.New("blob: corrupt entry spill record")
+
+// recordReader decodes the fields of an entry record. The first failure is
+// kept in err, and later reads return zero values.
+type recordReader struct {
+	b   []byte
+	err error
+}
+
+func (r *recordReader) uvarint() uint64 {
+	if r.err != nil {
+		return 0
+	}
+	v, n := binary.Uvarint(r.b)
+	if n <= 0 {
+		r.err = io.ErrUnexpectedEOF
+		return 0
+	}
+	r.b = r.b[n:]
+	return v
+}
+
+func (r *recordReader) varint() int64 {
+	if r.err != nil {
+		return 0
+	}
+	v, n := binary.Varint(r.b)
+	if n <= 0 {
+		r.err = io.ErrUnexpectedEOF
+		return 0
+	}
+	r.b = r.b[n:]
+	return v
+}
+
+func (r *recordReader) uint32() uint32 {
+	v := r.uvarint()
+	if v > 1<<32-1 {
+		r.err = errCorruptEntryRecord
+		return 0
+	}
+	return uint32(v)
+}
+
+func (r *recordReader) byte() byte {
+	if r.err != nil {
+		return 0
+	}
+	if len(r.b) == 0 {
+		r.err = io.ErrUnexpectedEOF
+		return 0
+	}
+	v := r.b[0]
+	r.b = r.b[1:]
+	return v
+}
+
+// bytes returns a copy of the next length-prefixed byte string, or nil when
+// it is empty.
+func (r *recordReader) bytes() []byte {
+	n := r.uvarint()
+	if r.err != nil || n == 0 {
+		return nil
+	}
+	if n > uint64(len(r.b)) {
+		r.err = io.ErrUnexpectedEOF
+		return nil
+	}
+	v := append([]byte(nil), r.b[:n]...)
+	r.b = r.b[n:]
+	return v
+}
@@ -0,0 +1,252 @@
+package blob
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"runtime"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/meigma/blob/core/testutil"
+)
+
+func TestEntryLogSpill(t *testing.T) {
+	t.Parallel()
+
+	entries := make([]Entry, 10)
+	for i := range entries {
+		entries[i] = Entry{
+			Path:         fmt.Sprintf("dir/file%02d", i),
+			DataOffset:   uint64(i) * 100,
+			DataSize:     uint64(i) * 10,
+			OriginalSize: uint64(i) * 20,
+			Hash:         bytes.Repeat([]byte{byte(i)}, 32),
+			Mode:         0o644,
+			UID:          uint32(1000 + i),
+			GID:          uint32(2000 + i),
+			ModTime:      time.Unix(1700000000, int64(i)),
+			Compression:  CompressionZstd,
+			AuxChecksum:  uint64(i) << 40,
+		}
+	}
+	entries[3].Mode = fs.ModeSymlink | 0o777
+	entries[4].Nonce = bytes.Repeat([]byte{4}, 12)
+	entries[5].Chunks = []Chunk{
+		{Size: 7, OriginalSize: 9, Hash: bytes.Repeat([]byte{1}, 32)},
+		{Size: 3, OriginalSize: 11, Hash: bytes.Repeat([]byte{2}, 32)},
+	}
+	entries[6].ModTime = time.Time{}
+
+	dir := t.TempDir()
+	log := newEntryLog(dir, 3)
+	for i, e := range entries {
+		require.NoError(t, log.Append(e))
+		assert.Equal(t, i+1, log.Len())
+	}
+	spilled, err := os.ReadDir(dir)
+	require.NoError(t, err)
+	assert.Len(t, spilled, 1)
+
+	for i, want := range entries {
+		got, err := log.At(i)
+		require.NoError(t, err)
+		// The index records modification times as nanoseconds.
+		assert.Equal(t, want.ModTime.UnixNano(), got.ModTime.UnixNano(), i)
+		got.ModTime = want.ModTime
+		assert.Equal(t, want, got, i)
+	}
+
+	// The index built from the log is the one built from the entries.
+	meta := indexMetadata{dataSize: 1000, bloomFilter: true, auxChecksum: AuxChecksumXXH64}
+	fromLog, err := buildIndexFrom(log, meta)
+	require.NoError(t, err)
+	assert.Equal(t, buildIndex(entries, meta), fromLog)
+
+	require.NoError(t, log.Close())
+	spilled, err = os.ReadDir(dir)
+	require.NoError(t, err)
+	assert.Empty(t, spilled)
+}
+
+func TestEntryLogCorruptRecord(t *testing.T) {
+	t.Parallel()
+
+	record := appendEntryRecord(nil, &Entry{Path: "a", Hash: make([]byte, 32)})
+	for _, bad := range [][]byte{record[:len(record)-1], append(bytes.Clone(record), 0)} {
+		_, err := parseEntryRecord(bad)
+		require.ErrorIs(t, err, errCorruptEntryRecord)
+	}
+}
+
+// syntheticTree is an fs.FS of dirs directories of files files each, all
+// size bytes long. Content and listings are generated on demand, so the tree
+// takes no memory of its own.
+type syntheticTree struct {
+	dirs, files, size int
+}
+
+func (s syntheticTree) Open(name string) (fs.File, error) {
+	info, ok := s.stat(name)
+	if !ok {
+		return nil, &fs.PathError{Op: "open", Path: name, Err: fs.ErrNotExist}
+	}
+	content := ""
+	if !info.dir {
+		content = strings.Repeat(name+"\n", s.size/(len(name)+1)+1)[:s.size]
+	}
+	return &syntheticFile{info: info, Reader: strings.NewReader(content)}, nil
+}
+
+func (s syntheticTree) ReadDir(name string) ([]fs.DirEntry, error) {
+	var names []string
+	switch {
+	case name == ".":
+		for d := range s.dirs {
+			names = append(names, fmt.Sprintf("d%03d", d))
+		}
+	case strings.HasPrefix(name, "d") && !strings.Contains(name, "/"):
+		for f := range s.files {
+			names = append(names, fmt.Sprintf("%s/f%06d", name, f))
+		}
+	default:
+		return nil, &fs.PathError{Op: "readdir", Path: name, Err: fs.ErrNotExist}
+	}
+	entries := make([]fs.DirEntry, len(names))
+	for i, n := range names {
+		info, _ := s.stat(n)
+		entries[i] = fs.FileInfoToDirEntry(info)
+	}
+	return entries, nil
+}
+
+func (s syntheticTree) stat(name string) (syntheticInfo, bool) {
+	var d, f int
+	switch {
+	case name == ".":
+		return syntheticInfo{name: ".", dir: true}, true
+	case len(name) == 4:
+		if _, err := fmt.Sscanf(name, "d%03d", &d); err != nil || d >= s.dirs {
+			return syntheticInfo{}, false
+		}
+		return syntheticInfo{name: name, dir: true}, true
+	default:
+		if _, err := fmt.Sscanf(name, "d%03d/f%06d", &d, &f); err != nil || d >= s.dirs || f >= s.files {
+			return syntheticInfo{}, false
+		}
+		return syntheticInfo{name: name[5:], size: int64(s.size)}, true
+	}
+}
+
+type syntheticInfo struct {
+	name string
+	size int64
+	dir  bool
+}
+
+func (i syntheticInfo) Name() string       { return i.name }
+func (i syntheticInfo) Size() int64        { return i.size }
+func (i syntheticInfo) ModTime() time.Time { return time.Unix(1700000000, 0) }
+func (i syntheticInfo) IsDir() bool        { return i.dir }
+func (i syntheticInfo) Sys() any           { return nil }
+
+func (i syntheticInfo) Mode() fs.FileMode {
+	if i.dir {
+		return fs.ModeDir | 0o755
+	}
+	return 0o644
+}
+
+type syntheticFile struct {
+	info syntheticInfo
+	*strings.Reader
+}
+
+func (f *syntheticFile) Stat() (fs.FileInfo, error) { return f.info, nil }
+func (f *syntheticFile) Close() error               { return nil }
+
+// sampleHeapPeak samples the heap in use until the returned function is
+// called, which reports the peak growth over the heap in use at the start.
+func sampleHeapPeak() func() uint64 {
+	runtime.GC()
+	var stats runtime.MemStats
+	runtime.ReadMemStats(&stats)
+	base := stats.HeapAlloc
+
+	var peak atomic.Uint64
+	stop := make(chan struct{})
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		ticker := time.NewTicker(2 * time.Millisecond)
+		defer ticker.Stop()
+		for {
+			var stats runtime.MemStats
+			runtime.ReadMemStats(&stats)
+			if stats.HeapAlloc > base && stats.HeapAlloc-base > peak.Load() {
+				peak.Store(stats.HeapAlloc - base)
+			}
+			select {
+			case <-stop:
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+	return func() uint64 {
+		close(stop)
+		<-done
+		return peak.Load()
+	}
+}
+
+// TestCreateBoundedMemory measures the heap, so it does not run in parallel
+// with other tests.
+func TestCreateBoundedMemory(t *testing.T) {
+	if testing.Short() {
+		t.Skip("builds a large archive")
+	}
+
+	const fileSize = 2 << 10
+	tree := syntheticTree{dirs: 100, files: 1000, size: fileSize}
+	fileCount := tree.dirs * tree.files
+	dir := t.TempDir()
+
+	var spilled atomic.Bool
+	progress := func(e ProgressEvent) {
+		if e.Stage == StageCompressing && e.FilesDone == entrySpillThreshold+1 {
+			names, err := os.ReadDir(dir)
+			spilled.Store(err == nil && len(names) == 1)
+		}
+	}
+
+	var indexBuf bytes.Buffer
+	peak := sampleHeapPeak()
+	err := CreateFS(context.Background(), tree, &indexBuf, io.Discard,
+		CreateWithTempDir(dir), CreateWithProgress(progress))
+	growth := peak()
+	require.NoError(t, err)
+
+	b, err := New(indexBuf.Bytes(), testutil.NewMockByteSource(nil))
+	require.NoError(t, err)
+	assert.Equal(t, fileCount, b.Len())
+	assert.True(t, spilled.Load(), "entries are moved to the temp dir")
+	names, err := os.ReadDir(dir)
+	require.NoError(t, err)
+	assert.Empty(t, names, "the spill file is removed")
+
+	// Memory is bounded by the index being built, not by the data written
+	// or the entries collected.
+	dataSize := uint64(fileCount * fileSize)
+	ceiling := 4*uint64(indexBuf.Len()) + 16<<20
+	t.Logf("peak heap growth %d MiB for %d MiB index, %d MiB data", growth>>20, indexBuf.Len()>>20, dataSize>>20)
+	assert.Less(t, growth, ceiling)
+}
@@ -3,18 +3,24 @@ package blob
 import (
 	"bytes"
 	"context"
+	"crypto/rand"
 	"crypto/sha256"
+	"fmt"
 	"io/fs"
 	"os"
 	"path/filepath"
+	"strings"
 	"testing"
 	"time"
 
 	"github.com/klauspost/compress/zstd"
+	"github.com/opencontainers/go-digest"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 
 	"github.com/meigma/blob/core/internal/index"
+	"github.com/meigma/blob/core/internal/write"
+	"github.com/meigma/blob/core/testutil"
 )
 
 func TestCreate(t *testing.T) {
@@ -73,6 +79,131 @@ func TestCreate(t *testing.T) {
 	assert.Equal(t, uint64(dataBuf.Len()), dataSize)
 }
 
+func TestCreateWithDigests(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	createTestFiles(t, dir, map[string]string{
+		"a.txt":     "content of a",
+		"sub/b.txt": "content of b",
+	})
+
+	var indexBuf, dataBuf bytes.Buffer
+	var indexDigest, dataDigest digest.Digest
+	err := Create(context.Background(), dir, &indexBuf, &dataBuf,
+		CreateWithCompression(CompressionZstd),
+		CreateWithDigests(&indexDigest, &dataDigest))
+	require.NoError(t, err)
+
+	assert.Equal(t, digest.SHA256.FromBytes(indexBuf.Bytes()), indexDigest)
+	assert.Equal(t, digest.SHA256.FromBytes(dataBuf.Bytes()), dataDigest)
+	require.NoError(t, dataDigest.Validate())
+
+	// Nil targets are ignored.
+	indexBuf.Reset()
+	dataBuf.Reset()
+	var onlyData digest.Digest
+	require.NoError(t, Create(context.Background(), dir, &indexBuf, &dataBuf, CreateWithDigests(nil, &onlyData)))
+	assert.Equal(t, digest.SHA256.FromBytes(dataBuf.Bytes()), onlyData)
+}
+
+func TestCreateConcurrentModification(t *testing.T) {
+	t.Parallel()
+
+	const initial = "initial log line\n"
+
+	// create archives a directory whose live.log is rewritten by modify
+	// after it is statted, on the first `times` reads only.
+	create := func(t *testing.T, mode ConcurrentModification, times int, modify func(t *testing.T, path string)) (*Blob, error) {
+		t.Helper()
+		dir := t.TempDir()
+		createTestFiles(t, dir, map[string]string{
+			"live.log":   initial,
+			"stable.txt": "stable",
+		})
+		root, err := os.OpenRoot(dir)
+		require.NoError(t, err)
+		t.Cleanup(func() { root.Close() })
+
+		reads := 0
+		w := &writer{cfg: createConfig{modification: mode}}
+		w.afterStat = func(path string) {
+			if path != "live.log" {
+				return
+			}
+			reads++
+			if reads <= times {
+				modify(t, filepath.Join(dir, path))
+			}
+		}
+
+		var dataBuf bytes.Buffer
+		entries, dataSize, err := w.writeData(context.Background(), root, &dataBuf)
+		if err != nil {
+			return nil, err
+		}
+		defer entries.Close()
+		indexData, err := buildIndexFrom(entries, indexMetadata{dataSize: dataSize})
+		if err != nil {
+			return nil, err
+		}
+		return New(indexData, testutil.NewMockByteSource(dataBuf.Bytes()))
+	}
+	grow := func(t *testing.T, path string) {
+		f, err := os.OpenFile(path, os.O_APPEND|os.O_WRONLY, 0)
+		require.NoError(t, err)
+		_, err = f.WriteString("appended line\n")
+		require.NoError(t, err)
+		require.NoError(t, f.Close())
+	}
+	shrink := func(t *testing.T, path string) {
+		require.NoError(t, os.Truncate(path, 4))
+	}
+
+	t.Run("error by default", func(t *testing.T) {
+		t.Parallel()
+		_, err := create(t, ConcurrentModificationError, 1, grow)
+		require.ErrorIs(t, err, ErrFileChanged)
+		assert.Contains(t, err.Error(), "live.log")
+
+		_, err = create(t, ConcurrentModificationError, 1, shrink)
+		require.ErrorIs(t, err, ErrFileChanged)
+	})
+
+	t.Run("retry reads the settled content", func(t *testing.T) {
+		t.Parallel()
+		b, err := create(t, ConcurrentModificationRetry, 1, grow)
+		require.NoError(t, err)
+		content, err := b.ReadFile("live.log")
+		require.NoError(t, err)
+		assert.Equal(t, initial+"appended line\n", string(content))
+		content, err = b.ReadFile("stable.txt")
+		require.NoError(t, err)
+		assert.Equal(t, "stable", string(content))
+	})
+
+	t.Run("retry gives up", func(t *testing.T) {
+		t.Parallel()
+		_, err := create(t, ConcurrentModificationRetry, maxModificationRetries+1, grow)
+		require.ErrorIs(t, err, ErrFileChanged)
+	})
+
+	t.Run("truncate", func(t *testing.T) {
+		t.Parallel()
+		b, err := create(t, ConcurrentModificationTruncate, 1, grow)
+		require.NoError(t, err)
+		content, err := b.ReadFile("live.log")
+		require.NoError(t, err)
+		assert.Equal(t, initial, string(content))
+
+		b, err = create(t, ConcurrentModificationTruncate, 1, shrink)
+		require.NoError(t, err)
+		content, err = b.ReadFile("live.log")
+		require.NoError(t, err)
+		assert.Equal(t, initial[:4], string(content))
+	})
+}
+
 func TestCreateEmpty(t *testing.T) {
 	t.Parallel()
 
@@ -134,6 +265,146 @@ func TestCreateCompression(t *testing.T) {
 	assert.Equal(t, expectedHash[:], view.HashBytes())
 }
 
+func TestCreateCompressionLevel(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	content := bytes.Repeat([]byte("the quick brown fox jumps over the lazy dog "), 2000)
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "test.txt"), content, 0o644))
+
+	levels := []CompressionLevel{
+		CompressionLevelDefault,
+		CompressionLevelFastest,
+		CompressionLevelBetter,
+		CompressionLevelBest,
+	}
+	for _, level := range levels {
+		t.Run(level.String(), func(t *testing.T) {
+			t.Parallel()
+
+			var indexBuf, dataBuf bytes.Buffer
+			err := Create(context.Background(), dir, &indexBuf, &dataBuf,
+				CreateWithCompression(CompressionZstd),
+				CreateWithCompressionLevel(level),
+			)
+			require.NoError(t, err)
+
+			archive, err := New(indexBuf.Bytes(), testutil.NewMockByteSource(dataBuf.Bytes()))
+			require.NoError(t, err)
+
+			view, ok := archive.Entry("test.txt")
+			require.True(t, ok)
+			assert.Equal(t, CompressionZstd, view.Compression())
+			assert.Less(t, view.DataSize(), view.OriginalSize())
+
+			got, err := archive.ReadFile("test.txt")
+			require.NoError(t, err)
+			assert.Equal(t, content, got)
+		})
+	}
+}
+
+func TestCreateMinCompressionRatio(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	random := make([]byte, 64<<10)
+	_, err := rand.Read(random)
+	require.NoError(t, err)
+	repetitive := bytes.Repeat([]byte("hello world "), 4096)
+	createTestFilesBytes(t, dir, map[string][]byte{
+		"random.bin":     random,
+		"repetitive.txt": repetitive,
+	})
+
+	tests := []struct {
+		name           string
+		opts           []CreateOption
+		wantRandom     Compression
+		wantRepetitive Compression
+	}{
+		{
+			name:           "default threshold",
+			wantRandom:     CompressionNone,
+			wantRepetitive: CompressionZstd,
+		},
+		{
+			name:           "disabled",
+			opts:           []CreateOption{CreateWithMinCompressionRatio(0)},
+			wantRandom:     CompressionZstd,
+			wantRepetitive: CompressionZstd,
+		},
+		{
+			name:           "unreachable threshold",
+			opts:           []CreateOption{CreateWithMinCompressionRatio(1)},
+			wantRandom:     CompressionNone,
+			wantRepetitive: CompressionNone,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			opts := append([]CreateOption{CreateWithCompression(CompressionZstd)}, tt.opts...)
+			var indexBuf, dataBuf bytes.Buffer
+			require.NoError(t, Create(context.Background(), dir, &indexBuf, &dataBuf, opts...))
+
+			archive, err := New(indexBuf.Bytes(), testutil.NewMockByteSource(dataBuf.Bytes()))
+			require.NoError(t, err)
+
+			view, ok := archive.Entry("random.bin")
+			require.True(t, ok)
+			assert.Equal(t, tt.wantRandom, view.Compression())
+			if tt.wantRandom == CompressionNone {
+				assert.Equal(t, view.OriginalSize(), view.DataSize())
+			}
+
+			view, ok = archive.Entry("repetitive.txt")
+			require.True(t, ok)
+			assert.Equal(t, tt.wantRepetitive, view.Compression())
+
+			got, err := archive.ReadFile("random.bin")
+			require.NoError(t, err)
+			assert.Equal(t, random, got)
+			got, err = archive.ReadFile("repetitive.txt")
+			require.NoError(t, err)
+			assert.Equal(t, repetitive, got)
+		})
+	}
+}
+
+func TestCreateMinCompressionRatioLargeFile(t *testing.T) {
+	t.Parallel()
+
+	// Files above the trial size are judged by a sample and then streamed,
+	// so incompressible content is stored raw and the rest compressed.
+	random := make([]byte, write.MaxTrialSize+1)
+	_, err := rand.Read(random)
+	require.NoError(t, err)
+	repetitive := bytes.Repeat([]byte("large compressible line\n"), write.MaxTrialSize/24+1)
+
+	dir := t.TempDir()
+	createTestFilesBytes(t, dir, map[string][]byte{"random.bin": random, "repetitive.txt": repetitive})
+
+	var indexBuf, dataBuf bytes.Buffer
+	require.NoError(t, Create(context.Background(), dir, &indexBuf, &dataBuf, CreateWithCompression(CompressionZstd)))
+
+	archive, err := New(indexBuf.Bytes(), testutil.NewMockByteSource(dataBuf.Bytes()))
+	require.NoError(t, err)
+	for name, want := range map[string]Compression{"random.bin": CompressionNone, "repetitive.txt": CompressionZstd} {
+		view, ok := archive.Entry(name)
+		require.True(t, ok)
+		assert.Equal(t, want, view.Compression(), name)
+	}
+	got, err := archive.ReadFile("random.bin")
+	require.NoError(t, err)
+	assert.Equal(t, random, got)
+	got, err = archive.ReadFile("repetitive.txt")
+	require.NoError(t, err)
+	assert.Equal(t, repetitive, got)
+}
+
 func TestCreateMetadata(t *testing.T) {
 	t.Parallel()
 
@@ -181,6 +452,190 @@ func TestCreateSkipsSymlinks(t *testing.T) {
 	assert.False(t, ok)
 }
 
+func TestCreateMaxFiles(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	createTestFiles(t, dir, map[string]string{
+		"a.txt":     "a",
+		"b.txt":     "b",
+		"dir/c.txt": "c",
+	})
+
+	var indexBuf, dataBuf bytes.Buffer
+	err := Create(context.Background(), dir, &indexBuf, &dataBuf, CreateWithMaxFiles(2))
+	require.ErrorIs(t, err, ErrTooManyFiles)
+
+	indexBuf.Reset()
+	dataBuf.Reset()
+	require.NoError(t, Create(context.Background(), dir, &indexBuf, &dataBuf, CreateWithMaxFiles(3)))
+}
+
+func TestCreateWithProgress(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	createTestFiles(t, dir, map[string]string{
+		"a.txt":        "alpha",
+		"b.txt":        strings.Repeat("compressible ", 1000),
+		"dir/c.txt":    "gamma",
+		"skip/d.txt":   "excluded",
+		"dir/empty.md": "",
+	})
+
+	var events []ProgressEvent
+	var indexBuf, dataBuf bytes.Buffer
+	require.NoError(t, Create(context.Background(), dir, &indexBuf, &dataBuf,
+		CreateWithCompression(CompressionZstd),
+		CreateWithExclude("skip/"),
+		CreateWithProgress(func(e ProgressEvent) { events = append(events, e) }),
+	))
+
+	const total = uint64(len("alpha") + 13*1000 + len("gamma"))
+	require.Len(t, events, 5)
+	assert.Equal(t, ProgressEvent{Stage: StageEnumerating, BytesTotal: total, FilesTotal: 4}, events[0])
+
+	var prev uint64
+	for i, e := range events[1:] {
+		assert.Equal(t, StageCompressing, e.Stage)
+		assert.Equal(t, i+1, e.FilesDone)
+		assert.Equal(t, 4, e.FilesTotal)
+		assert.Equal(t, total, e.BytesTotal)
+		assert.GreaterOrEqual(t, e.BytesDone, prev)
+		prev = e.BytesDone
+	}
+	assert.Equal(t, []string{"a.txt", "b.txt", "dir/c.txt", "dir/empty.md"}, []string{events[1].Path, events[2].Path, events[3].Path, events[4].Path})
+	assert.Equal(t, total, events[4].BytesDone, "final bytes processed equal the input size")
+	assert.Less(t, uint64(dataBuf.Len()), total, "bytes count input, not compressed output")
+}
+
+func TestCreateWithCompressionWorkers(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	files := make(map[string]string)
+	for i := range 64 {
+		files[fmt.Sprintf("dir%d/file%02d.txt", i%5, i)] = strings.Repeat(fmt.Sprintf("line %d\n", i), 200*i)
+	}
+	random := make([]byte, 64<<10)
+	_, err := rand.Read(random)
+	require.NoError(t, err)
+	files["random.bin"] = string(random)
+	// Larger than a worker buffers, so the serializer writes it itself.
+	files["dir1/large.txt"] = strings.Repeat("large file line\n", parallelBufferSize/16+1)
+	createTestFiles(t, dir, files)
+
+	create := func(t *testing.T, opts ...CreateOption) (index, data []byte) {
+		t.Helper()
+
+		var indexBuf, dataBuf bytes.Buffer
+		require.NoError(t, Create(context.Background(), dir, &indexBuf, &dataBuf, opts...))
+		return indexBuf.Bytes(), dataBuf.Bytes()
+	}
+
+	for name, opts := range map[string][]CreateOption{
+		"zstd":         {CreateWithCompression(CompressionZstd), CreateWithModTimeZero()},
+		"none":         {CreateWithModTimeZero()},
+		"chunking":     {CreateWithCompression(CompressionZstd), CreateWithChunking(1 << 10), CreateWithModTimeZero()},
+		"path rewrite": {CreateWithCompression(CompressionZstd), CreateWithStripPrefix(1), CreateWithModTimeZero()},
+	} {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			wantIndex, wantData := create(t, opts...)
+			for _, workers := range []int{2, 3, 8} {
+				index, data := create(t, append(opts, CreateWithCompressionWorkers(workers))...)
+				assert.Equal(t, wantIndex, index, "workers=%d", workers)
+				assert.Equal(t, wantData, data, "workers=%d", workers)
+			}
+		})
+	}
+
+	t.Run("encryption", func(t *testing.T) {
+		t.Parallel()
+
+		key := make([]byte, 32)
+		index, data := create(t, CreateWithCompression(CompressionZstd), CreateWithEncryption(key, EncryptionAESGCM), CreateWithCompressionWorkers(4))
+		b, err := New(index, testutil.NewMockByteSource(data), WithDecryptionKey(key))
+		require.NoError(t, err)
+		for name, want := range files {
+			got, err := b.ReadFile(name)
+			require.NoError(t, err, name)
+			assert.Equal(t, want, string(got), name)
+		}
+	})
+
+	t.Run("errors", func(t *testing.T) {
+		t.Parallel()
+
+		var indexBuf, dataBuf bytes.Buffer
+		err := Create(context.Background(), dir, &indexBuf, &dataBuf, CreateWithCompressionWorkers(4), CreateWithMaxFiles(10))
+		require.ErrorIs(t, err, ErrTooManyFiles)
+
+		err = Create(context.Background(), dir, &indexBuf, &dataBuf, CreateWithCompressionWorkers(4), CreateWithMaxPathLength(12))
+		require.Error(t, err)
+
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+		err = Create(ctx, dir, &indexBuf, &dataBuf, CreateWithCompressionWorkers(4))
+		require.ErrorIs(t, err, context.Canceled)
+	})
+}
+
+func TestCreateBloomFilter(t *testing.T) {
+	t.Parallel()
+
+	files := map[string]string{
+		"a.txt":         "a",
+		"dir/b.txt":     "b",
+		"dir/sub/c.txt": "c",
+	}
+	dir := t.TempDir()
+	createTestFiles(t, dir, files)
+
+	var indexBuf, dataBuf bytes.Buffer
+	require.NoError(t, Create(context.Background(), dir, &indexBuf, &dataBuf, CreateWithBloomFilter(true)))
+	b, err := New(indexBuf.Bytes(), testutil.NewMockByteSource(dataBuf.Bytes()))
+	require.NoError(t, err)
+	require.True(t, b.idx.HasBloomFilter())
+
+	for path, content := range files {
+		data, err := b.ReadFile(path)
+		require.NoError(t, err, path)
+		assert.Equal(t, content, string(data))
+	}
+	_, err = b.Stat("dir/missing.txt")
+	assert.ErrorIs(t, err, fs.ErrNotExist)
+	sub, err := b.Subset("dir")
+	require.NoError(t, err)
+	_, err = sub.Stat("sub/c.txt")
+	require.NoError(t, err)
+
+	t.Run("update keeps filter", func(t *testing.T) {
+		t.Parallel()
+
+		var newIndex, newData bytes.Buffer
+		changes := []FileChange{{Path: "dir/new.txt", Content: []byte("new")}}
+		require.NoError(t, Update(context.Background(), b, &newIndex, &newData, changes))
+		updated, err := New(newIndex.Bytes(), testutil.NewMockByteSource(newData.Bytes()))
+		require.NoError(t, err)
+		assert.True(t, updated.idx.HasBloomFilter())
+		data, err := updated.ReadFile("dir/new.txt")
+		require.NoError(t, err)
+		assert.Equal(t, "new", string(data))
+	})
+
+	t.Run("disabled by default", func(t *testing.T) {
+		t.Parallel()
+
+		var indexBuf, dataBuf bytes.Buffer
+		require.NoError(t, Create(context.Background(), dir, &indexBuf, &dataBuf))
+		b, err := New(indexBuf.Bytes(), testutil.NewMockByteSource(dataBuf.Bytes()))
+		require.NoError(t, err)
+		assert.False(t, b.idx.HasBloomFilter())
+	})
+}
+
 func TestCreateCancellation(t *testing.T) {
 	t.Parallel()
 
@@ -227,6 +682,98 @@ func TestCreatePrefixScans(t *testing.T) {
 	assert.Equal(t, []string{"assets/css/main.css", "assets/css/reset.css"}, cssPaths)
 }
 
+func TestCreateIndexOrder(t *testing.T) {
+	t.Parallel()
+
+	// Names that sort below '/' make directory walk order differ from
+	// byte order of full paths.
+	dir := t.TempDir()
+	files := map[string]string{
+		"a.txt":     "file",
+		"a/b.txt":   "nested",
+		"a-b/c.txt": "dash",
+		"b":         "plain",
+	}
+	createTestFiles(t, dir, files)
+
+	var indexBuf, dataBuf bytes.Buffer
+	require.NoError(t, Create(context.Background(), dir, &indexBuf, &dataBuf))
+
+	idx, err := index.Load(indexBuf.Bytes())
+	require.NoError(t, err)
+	paths := make([]string, 0, len(files))
+	for view := range idx.EntriesView() {
+		paths = append(paths, view.Path())
+	}
+	assert.Equal(t, []string{"a-b/c.txt", "a.txt", "a/b.txt", "b"}, paths)
+
+	for path := range files {
+		_, ok := idx.LookupView(path)
+		assert.True(t, ok, path)
+	}
+}
+
+func TestCreateWithModTime_Reproducible(t *testing.T) {
+	t.Parallel()
+
+	files := map[string]string{
+		"a.txt":         strings.Repeat("alpha ", 100),
+		"dir/b.txt":     "bravo",
+		"dir/sub/c.txt": strings.Repeat("charlie ", 100),
+	}
+	// Two checkouts of the same tree with different file times.
+	build := func(t *testing.T, mtime time.Time, opts ...CreateOption) (indexData, data []byte) {
+		t.Helper()
+		dir := t.TempDir()
+		createTestFiles(t, dir, files)
+		for path := range files {
+			require.NoError(t, os.Chtimes(filepath.Join(dir, path), mtime, mtime))
+		}
+		var indexBuf, dataBuf bytes.Buffer
+		require.NoError(t, Create(context.Background(), dir, &indexBuf, &dataBuf, opts...))
+		return indexBuf.Bytes(), dataBuf.Bytes()
+	}
+	first := time.Date(2024, 1, 2, 3, 4, 5, 6, time.UTC)
+	second := first.Add(36 * time.Hour)
+
+	t.Run("real mod times differ", func(t *testing.T) {
+		t.Parallel()
+		index1, _ := build(t, first)
+		index2, _ := build(t, second)
+		assert.NotEqual(t, index1, index2)
+	})
+
+	t.Run("fixed mod time", func(t *testing.T) {
+		t.Parallel()
+		fixed := time.Date(2020, 6, 1, 0, 0, 0, 0, time.UTC)
+		opts := []CreateOption{CreateWithCompression(CompressionZstd), CreateWithModTime(fixed)}
+		index1, data1 := build(t, first, opts...)
+		index2, data2 := build(t, second, opts...)
+		assert.Equal(t, index1, index2)
+		assert.Equal(t, data1, data2)
+
+		b, err := New(index1, testutil.NewMockByteSource(data1))
+		require.NoError(t, err)
+		for view := range b.Entries() {
+			assert.True(t, fixed.Equal(view.ModTime()), view.Path())
+		}
+	})
+
+	t.Run("epoch", func(t *testing.T) {
+		t.Parallel()
+		index1, data1 := build(t, first, CreateWithModTimeZero())
+		index2, data2 := build(t, second, CreateWithModTimeZero())
+		assert.Equal(t, index1, index2)
+		assert.Equal(t, data1, data2)
+
+		b, err := New(index1, testutil.NewMockByteSource(data1))
+		require.NoError(t, err)
+		view, ok := b.Entry("a.txt")
+		require.True(t, ok)
+		assert.Equal(t, int64(0), view.ModTime().Unix())
+	})
+}
+
 // createTestFiles creates files in dir from a map of relative path to content.
 func createTestFiles(t *testing.T, dir string, files map[string]string) {
 	t.Helper()
@@ -0,0 +1,32 @@
+package blob
+
+import "github.com/meigma/blob/core/internal/file"
+
+// SetGlobalDecoderMemoryLimit caps the estimated memory held by active zstd
+// decoders across every Blob in the process. A limit of 0 (the default)
+// removes the cap.
+//
+// Each decode is charged its uncompressed size, bounded by the Blob's
+// WithMaxDecoderMemory, plus a small fixed overhead. When a decode would
+// exceed the limit it waits until running decodes finish, so reads still
+// complete but may be serialized; a single decode larger than the limit runs
+// alone. Waiting stops when the context of ReadFileContext, CopyDirContext
+// or a similar call is done.
+//
+// Open files hold their charge until Close. Since the goroutine holding
+// them may be the one asking, a decode that does not fit beside the charges
+// of open files fails with ErrDecoderMemoryLimit instead of waiting. Zstd
+// range responses decoded by the HTTP source are charged without waiting.
+// Idle decoders kept for reuse are not charged.
+//
+// SetGlobalDecoderMemoryLimit is safe to call concurrently with reads;
+// lowering the limit does not interrupt decodes already running.
+func SetGlobalDecoderMemoryLimit(bytes uint64) {
+	file.SetDecoderMemoryLimit(bytes)
+}
+
+// GlobalDecoderMemoryInUse returns the estimated memory currently charged to
+// active zstd decoders across the process.
+func GlobalDecoderMemoryInUse() uint64 {
+	return file.DecoderMemoryInUse()
+}
@@ -0,0 +1,108 @@
+package blob
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io/fs"
+	"sync"
+	"sync/atomic"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestSetGlobalDecoderMemoryLimit mutates process-wide state, so it does not
+// run in parallel with other tests.
+func TestSetGlobalDecoderMemoryLimit(t *testing.T) {
+	const (
+		fileSize = 256 << 10
+		limit    = 1 << 20
+		archives = 4
+		readers  = 8
+	)
+
+	files := make(map[string][]byte)
+	for i := range 8 {
+		files[fmt.Sprintf("file%d.bin", i)] = bytes.Repeat([]byte(fmt.Sprintf("content-%d ", i)), fileSize/10)
+	}
+	blobs := make([]*Blob, archives)
+	for i := range blobs {
+		blobs[i] = createTestArchive(t, files, CompressionZstd)
+	}
+
+	SetGlobalDecoderMemoryLimit(limit)
+	t.Cleanup(func() { SetGlobalDecoderMemoryLimit(0) })
+
+	var peak atomic.Uint64
+	stop := make(chan struct{})
+	sampled := make(chan struct{})
+	go func() {
+		defer close(sampled)
+		for {
+			select {
+			case <-stop:
+				return
+			default:
+				if used := GlobalDecoderMemoryInUse(); used > peak.Load() {
+					peak.Store(used)
+				}
+			}
+		}
+	}()
+
+	var wg sync.WaitGroup
+	for _, b := range blobs {
+		for range readers {
+			wg.Go(func() {
+				for name, want := range files {
+					got, err := b.ReadFile(name)
+					if !assert.NoError(t, err, name) {
+						return
+					}
+					assert.Equal(t, want, got, name)
+				}
+			})
+		}
+	}
+	wg.Wait()
+	close(stop)
+	<-sampled
+
+	assert.LessOrEqual(t, peak.Load(), uint64(limit))
+	assert.Zero(t, GlobalDecoderMemoryInUse(), "all charges must be released")
+
+	// An open file holds its decoder's charge until Close.
+	f, err := blobs[0].Open("file0.bin")
+	require.NoError(t, err)
+	_, err = f.Read(make([]byte, 1))
+	require.NoError(t, err)
+	assert.NotZero(t, GlobalDecoderMemoryInUse(), "open decode must be charged")
+	require.NoError(t, f.Close())
+	assert.Zero(t, GlobalDecoderMemoryInUse())
+
+	// Opening more files than the limit holds fails instead of blocking the
+	// goroutine that keeps the others open.
+	var open []fs.File
+	for i := range 2 {
+		f, err := blobs[0].Open(fmt.Sprintf("file%d.bin", i))
+		require.NoError(t, err)
+		open = append(open, f)
+		_, err = f.Read(make([]byte, 1))
+		require.NoError(t, err)
+	}
+	f, err = blobs[0].Open("file2.bin")
+	require.NoError(t, err)
+	_, err = f.Read(make([]byte, 1))
+	require.ErrorIs(t, err, ErrDecoderMemoryLimit)
+	_ = f.Close()
+	_, err = blobs[0].ReadFileContext(context.Background(), "file3.bin")
+	require.ErrorIs(t, err, ErrDecoderMemoryLimit)
+	for _, f := range open {
+		require.NoError(t, f.Close())
+	}
+	assert.Zero(t, GlobalDecoderMemoryInUse())
+	_, err = blobs[0].ReadFile("file3.bin")
+	require.NoError(t, err)
+}
@@ -0,0 +1,137 @@
+package blob
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+
+	"github.com/klauspost/compress/zstd"
+)
+
+// DefaultZstdDictionarySize is the dictionary size used by TrainZstdDictionary
+// when maxSize is zero.
+const DefaultZstdDictionarySize = 64 << 10
+
+const (
+	// maxDictionarySampleSize bounds how much of each file is sampled.
+	maxDictionarySampleSize = 128 << 10
+	// dictionarySampleBudget bounds the total sampled bytes as a multiple of
+	// the dictionary size.
+	dictionarySampleBudget = 100
+	// minDictionaryID is the lowest dictionary ID outside the range reserved
+	// by the zstd format.
+	minDictionaryID = 32768
+)
+
+// errNoDictionarySamples is returned when a directory has no usable samples.
+var errNoDictionarySamples = errors.New("no sample files for zstd dictionary")
+
+// TrainZstdDictionary builds a zstd dictionary from the regular files in dir.
+//
+// The result is intended for CreateWithZstdDictionary and works best when
+// dir holds representative samples of the content being archived, such as
+// the directory itself. At most maxSize bytes of sample content are kept;
+// zero uses DefaultZstdDictionarySize.
+//
+// Files are sampled in path order until a fixed budget is reached, so
+// training on very large trees reads only a prefix of their content.
+// Symbolic links are not followed.
+func TrainZstdDictionary(ctx context.Context, dir string, maxSize int) ([]byte, error) {
+	if maxSize < 0 {
+		return nil, fmt.Errorf("invalid zstd dictionary size %d", maxSize)
+	}
+	if maxSize == 0 {
+		maxSize = DefaultZstdDictionarySize
+	}
+
+	root, err := os.OpenRoot(dir)
+	if err != nil {
+		return nil, err
+	}
+	defer root.Close()
+
+	samples, err := collectDictionarySamples(ctx, root, maxSize*dictionarySampleBudget)
+	if err != nil {
+		return nil, err
+	}
+	if len(samples) == 0 {
+		return nil, errNoDictionarySamples
+	}
+
+	history := dictionaryHistory(samples, maxSize)
+	sum := sha256.Sum256(history)
+	id := binary.BigEndian.Uint32(sum[:4])%(1<<31-minDictionaryID) + minDictionaryID
+
+	dict, err := zstd.BuildDict(zstd.BuildDictOptions{
+		ID:       id,
+		Contents: samples,
+		History:  history,
+		Offsets:  [3]int{1, 4, 8},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("build zstd dictionary: %w", err)
+	}
+	return dict, nil
+}
+
+// collectDictionarySamples reads up to budget bytes of file content from root.
+func collectDictionarySamples(ctx context.Context, root *os.Root, budget int) ([][]byte, error) {
+	var samples [][]byte
+	total := 0
+	err := fs.WalkDir(root.FS(), ".", func(path string, d fs.DirEntry, walkErr error) error {
+		if walkErr != nil {
+			return walkErr
+		}
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if !d.Type().IsRegular() {
+			return nil
+		}
+		if total >= budget {
+			return fs.SkipAll
+		}
+
+		f, err := root.Open(path)
+		if err != nil {
+			return err
+		}
+		limit := min(maxDictionarySampleSize, budget-total)
+		sample, err := io.ReadAll(io.LimitReader(f, int64(limit)))
+		f.Close()
+		if err != nil {
+			return fmt.Errorf("read %s: %w", path, err)
+		}
+		if len(sample) == 0 {
+			return nil
+		}
+		samples = append(samples, sample)
+		total += len(sample)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return samples, nil
+}
+
+// dictionaryHistory assembles up to size bytes of dictionary content from
+// whole samples, each capped to a quarter of the dictionary so that a single
+// large file cannot crowd out the rest.
+func dictionaryHistory(samples [][]byte, size int) []byte {
+	limit := max(size/4, 64)
+	history := make([]byte, 0, size)
+	for _, sample := range samples {
+		n := min(len(sample), limit, size-len(history))
+		history = append(history, sample[:n]...)
+		if len(history) == size {
+			break
+		}
+	}
+	return history
+}
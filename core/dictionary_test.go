@@ -0,0 +1,131 @@
+package blob
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/meigma/blob/core/testutil"
+)
+
+func TestZstdDictionary(t *testing.T) {
+	t.Parallel()
+
+	// Many small, similar files: little history per file, lots shared across files.
+	files := make(map[string][]byte, 200)
+	for i := range 200 {
+		files[fmt.Sprintf("services/svc-%03d/config.json", i)] = fmt.Appendf(nil,
+			`{"apiVersion":"v1","kind":"ServiceConfig","metadata":{"name":"svc-%03d","namespace":"production"},`+
+				`"spec":{"replicas":%d,"port":%d,"healthCheck":{"path":"/healthz","intervalSeconds":10}}}`,
+			i, i%5+1, 8000+i)
+	}
+	dir := t.TempDir()
+	createTestFilesBytes(t, dir, files)
+
+	ctx := context.Background()
+	dict, err := TrainZstdDictionary(ctx, dir, 4<<10)
+	require.NoError(t, err)
+	require.NotEmpty(t, dict)
+
+	create := func(opts ...CreateOption) (indexData, data []byte) {
+		var indexBuf, dataBuf bytes.Buffer
+		opts = append([]CreateOption{CreateWithCompression(CompressionZstd)}, opts...)
+		require.NoError(t, Create(ctx, dir, &indexBuf, &dataBuf, opts...))
+		return indexBuf.Bytes(), dataBuf.Bytes()
+	}
+	_, plainData := create()
+	indexData, dictData := create(CreateWithZstdDictionary(dict))
+
+	assert.Less(t, len(dictData), len(plainData)/2,
+		"dictionary archive should be substantially smaller (%d vs %d bytes)", len(dictData), len(plainData))
+
+	// The index references the dictionary by hash instead of embedding it.
+	assert.NotContains(t, string(indexData), string(dict[len(dict)-256:]))
+	noDict, err := New(indexData, testutil.NewMockByteSource(dictData))
+	require.NoError(t, err)
+	hash, ok := noDict.idx.ZstdDictionaryHash()
+	require.True(t, ok)
+	assert.Equal(t, dictionaryHash(dict), hash)
+	_, ok = noDict.ZstdDictionary()
+	assert.False(t, ok)
+	_, err = noDict.Stat("services/svc-001/config.json")
+	require.NoError(t, err, "listing works without the dictionary")
+	_, err = noDict.ReadFile("services/svc-001/config.json")
+	require.ErrorIs(t, err, ErrDecompression)
+
+	_, err = New(indexData, testutil.NewMockByteSource(dictData), WithZstdDictionary(dict[:len(dict)-1]))
+	require.ErrorIs(t, err, ErrZstdDictionaryMismatch)
+
+	b, err := New(indexData, testutil.NewMockByteSource(dictData), WithZstdDictionary(dict))
+	require.NoError(t, err)
+	gotDict, ok := b.ZstdDictionary()
+	require.True(t, ok)
+	assert.Equal(t, dict, gotDict)
+
+	for path, want := range files {
+		got, err := b.ReadFile(path)
+		require.NoError(t, err, path)
+		assert.Equal(t, want, got, path)
+	}
+
+	destDir := t.TempDir()
+	stats, err := b.CopyDir(destDir, ".")
+	require.NoError(t, err)
+	assert.Equal(t, len(files), stats.FileCount)
+	got, err := os.ReadFile(filepath.Join(destDir, "services", "svc-042", "config.json"))
+	require.NoError(t, err)
+	assert.Equal(t, files["services/svc-042/config.json"], got)
+
+	// Update keeps the base dictionary, which it needs to copy entries.
+	change := []FileChange{{Path: "services/new/config.json", Content: []byte(`{"kind":"ServiceConfig"}`)}}
+	var updIndex, updData bytes.Buffer
+	require.Error(t, Update(ctx, noDict, &updIndex, &updData, change, CreateWithCompression(CompressionZstd)))
+	require.NoError(t, Update(ctx, b, &updIndex, &updData, change, CreateWithCompression(CompressionZstd)))
+	updated, err := New(updIndex.Bytes(), testutil.NewMockByteSource(updData.Bytes()), WithZstdDictionary(dict))
+	require.NoError(t, err)
+	got, err = updated.ReadFile("services/new/config.json")
+	require.NoError(t, err)
+	assert.Equal(t, change[0].Content, got)
+	got, err = updated.ReadFile("services/svc-042/config.json")
+	require.NoError(t, err)
+	assert.Equal(t, files["services/svc-042/config.json"], got)
+}
+
+func TestZstdDictionary_Errors(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+
+	_, err := TrainZstdDictionary(ctx, t.TempDir(), 0)
+	require.ErrorIs(t, err, errNoDictionarySamples)
+
+	_, err = TrainZstdDictionary(ctx, t.TempDir(), -1)
+	require.Error(t, err)
+
+	dir := t.TempDir()
+	createTestFilesBytes(t, dir, map[string][]byte{"a.txt": []byte("content a")})
+	var indexBuf, dataBuf bytes.Buffer
+	err = Create(ctx, dir, &indexBuf, &dataBuf,
+		CreateWithCompression(CompressionZstd),
+		CreateWithZstdDictionary([]byte("not a dictionary")))
+	require.Error(t, err)
+
+	// Without compression the dictionary is ignored and not stored.
+	indexBuf.Reset()
+	dataBuf.Reset()
+	require.NoError(t, Create(ctx, dir, &indexBuf, &dataBuf,
+		CreateWithZstdDictionary([]byte("not a dictionary"))))
+	b, err := New(indexBuf.Bytes(), testutil.NewMockByteSource(dataBuf.Bytes()),
+		WithZstdDictionary([]byte("not a dictionary")))
+	require.NoError(t, err)
+	_, ok := b.idx.ZstdDictionaryHash()
+	assert.False(t, ok)
+	_, ok = b.ZstdDictionary()
+	assert.False(t, ok, "an unreferenced dictionary is dropped")
+}
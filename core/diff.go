@@ -0,0 +1,65 @@
+package blob
+
+import (
+	"bytes"
+	"iter"
+)
+
+// Diff compares the files of two archives by path and content hash.
+//
+// added lists paths present only in b, removed paths present only in a, and
+// changed paths present in both whose content hash differs. Each list is
+// sorted by path. Only the indexes are read, so neither data blob is
+// accessed. Entries whose mode or modification time changed but whose
+// content did not are not reported, and explicit directory entries are
+// ignored. For Subset views, paths are compared relative to each subset
+// root.
+func Diff(a, b *Blob) (added, removed, changed []string) {
+	nextA, stopA := iter.Pull(a.diffEntries())
+	defer stopA()
+	nextB, stopB := iter.Pull(b.diffEntries())
+	defer stopB()
+
+	va, okA := nextA()
+	vb, okB := nextB()
+	for okA || okB {
+		var c int
+		switch {
+		case !okA:
+			c = 1
+		case !okB:
+			c = -1
+		default:
+			c = bytes.Compare(va.PathBytes(), vb.PathBytes())
+		}
+		switch {
+		case c < 0:
+			removed = append(removed, va.Path())
+			va, okA = nextA()
+		case c > 0:
+			added = append(added, vb.Path())
+			vb, okB = nextB()
+		default:
+			if !bytes.Equal(va.HashBytes(), vb.HashBytes()) {
+				changed = append(changed, va.Path())
+			}
+			va, okA = nextA()
+			vb, okB = nextB()
+		}
+	}
+	return added, removed, changed
+}
+
+// diffEntries returns the non-directory entries of b in path order.
+func (b *Blob) diffEntries() iter.Seq[EntryView] {
+	return func(yield func(EntryView) bool) {
+		for view := range b.Entries() {
+			if view.Mode().IsDir() {
+				continue
+			}
+			if !yield(view) {
+				return
+			}
+		}
+	}
+}
@@ -0,0 +1,107 @@
+package blob
+
+import (
+	"bytes"
+	"context"
+	"io/fs"
+	"testing"
+	"testing/fstest"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/meigma/blob/core/testutil"
+)
+
+// diffSource is a ByteSource that fails every read, proving Diff needs no
+// data blob access.
+type diffSource struct{ size int64 }
+
+func (s diffSource) ReadAt([]byte, int64) (int, error) { return 0, fs.ErrPermission }
+func (s diffSource) Size() int64                       { return s.size }
+func (s diffSource) SourceID() string                  { return "diff" }
+
+func newDiffTestBlob(t *testing.T, fsys fstest.MapFS, opts ...CreateOption) *Blob {
+	t.Helper()
+
+	var indexBuf, dataBuf bytes.Buffer
+	require.NoError(t, CreateFS(context.Background(), fsys, &indexBuf, &dataBuf, opts...))
+	b, err := New(indexBuf.Bytes(), diffSource{size: int64(dataBuf.Len())})
+	require.NoError(t, err)
+	return b
+}
+
+func TestDiff(t *testing.T) {
+	t.Parallel()
+
+	modTime := time.Unix(1700000000, 0)
+	v1 := newDiffTestBlob(t, fstest.MapFS{
+		"app/config.yaml": {Data: []byte("replicas: 1"), Mode: 0o644, ModTime: modTime},
+		"app/run.sh":      {Data: []byte("#!/bin/sh"), Mode: 0o644, ModTime: modTime},
+		"app/old.txt":     {Data: []byte("old"), Mode: 0o644, ModTime: modTime},
+		"lib/a.so":        {Data: []byte("a"), Mode: 0o644, ModTime: modTime},
+		"same.txt":        {Data: []byte("same"), Mode: 0o644, ModTime: modTime},
+	})
+	v2 := newDiffTestBlob(t, fstest.MapFS{
+		"app/config.yaml": {Data: []byte("replicas: 3"), Mode: 0o644, ModTime: modTime},
+		"app/run.sh":      {Data: []byte("#!/bin/sh"), Mode: 0o755, ModTime: modTime.Add(time.Hour)},
+		"app/new.txt":     {Data: []byte("new"), Mode: 0o644, ModTime: modTime},
+		"lib/a.so":        {Data: []byte("a"), Mode: 0o644, ModTime: modTime},
+		"lib/b.so":        {Data: []byte("b"), Mode: 0o644, ModTime: modTime},
+		"same.txt":        {Data: []byte("same"), Mode: 0o644, ModTime: modTime},
+		"zz/last.txt":     {Data: []byte("z"), Mode: 0o644, ModTime: modTime},
+	}, CreateWithCompression(CompressionZstd))
+
+	added, removed, changed := Diff(v1, v2)
+	assert.Equal(t, []string{"app/new.txt", "lib/b.so", "zz/last.txt"}, added)
+	assert.Equal(t, []string{"app/old.txt"}, removed)
+	assert.Equal(t, []string{"app/config.yaml"}, changed, "mode-only changes and compression are not content changes")
+
+	added, removed, changed = Diff(v2, v1)
+	assert.Equal(t, []string{"app/old.txt"}, added)
+	assert.Equal(t, []string{"app/new.txt", "lib/b.so", "zz/last.txt"}, removed)
+	assert.Equal(t, []string{"app/config.yaml"}, changed)
+
+	t.Run("identical", func(t *testing.T) {
+		t.Parallel()
+
+		added, removed, changed := Diff(v1, v1)
+		assert.Empty(t, added)
+		assert.Empty(t, removed)
+		assert.Empty(t, changed)
+	})
+
+	t.Run("subsets", func(t *testing.T) {
+		t.Parallel()
+
+		a, err := v1.Subset("app")
+		require.NoError(t, err)
+		b, err := v2.Subset("app")
+		require.NoError(t, err)
+		added, removed, changed := Diff(a, b)
+		assert.Equal(t, []string{"new.txt"}, added)
+		assert.Equal(t, []string{"old.txt"}, removed)
+		assert.Equal(t, []string{"config.yaml"}, changed)
+	})
+
+	t.Run("directory entries", func(t *testing.T) {
+		t.Parallel()
+
+		content := []byte("x")
+		entry := testutil.TestEntry{Path: "dir/file", DataSize: 1, OriginalSize: 1, Hash: bytes.Repeat([]byte{1}, 32), Mode: 0o644}
+		withDir, err := New(testutil.BuildTestIndex(t, []testutil.TestEntry{
+			{Path: "dir", Mode: fs.ModeDir | 0o755},
+			{Path: "empty", Mode: fs.ModeDir | 0o755},
+			entry,
+		}), testutil.NewMockByteSource(content))
+		require.NoError(t, err)
+		withoutDir, err := New(testutil.BuildTestIndex(t, []testutil.TestEntry{entry}), testutil.NewMockByteSource(content))
+		require.NoError(t, err)
+
+		added, removed, changed := Diff(withoutDir, withDir)
+		assert.Empty(t, added)
+		assert.Empty(t, removed)
+		assert.Empty(t, changed)
+	})
+}
@@ -0,0 +1,155 @@
+package blob
+
+import (
+	"io/fs"
+	"slices"
+	"strings"
+
+	"github.com/meigma/blob/core/internal/blobtype"
+	"github.com/meigma/blob/core/internal/file"
+)
+
+// ReadDirPage returns up to limit entries of the named directory whose names
+// sort after afterName, in the same name order as ReadDir.
+//
+// Pass an empty afterName for the first page and the returned nextToken for
+// each following page; nextToken is the last name in the page, or "" when
+// the directory has no more entries. Because the cursor is a name, pages stay
+// consistent if the caller pauses between them, and every entry, including
+// synthesized subdirectories, appears on exactly one page. A limit <= 0
+// returns all remaining entries.
+//
+// The start of each page is found by binary search, and subdirectory
+// contents are skipped rather than scanned, so the cost of a page is
+// proportional to its size rather than to its position in the directory.
+func (b *Blob) ReadDirPage(name, afterName string, limit int) (entries []fs.DirEntry, nextToken string, err error) {
+	if !fs.ValidPath(name) {
+		return nil, "", &fs.PathError{Op: "readdir", Path: name, Err: fs.ErrInvalid}
+	}
+	full := b.resolve(name)
+	if name != "." && !b.isDir(full) {
+		return nil, "", &fs.PathError{Op: "readdir", Path: name, Err: fs.ErrNotExist}
+	}
+
+	prefix := file.DirPrefix(full)
+	page := dirPage{limit: limit}
+	n := b.idx.Len()
+	for i := b.idx.Search(prefix + afterName); i < n; {
+		view, ok := b.idx.ViewAt(i)
+		if !ok {
+			break
+		}
+		path := string(view.PathBytes())
+		if !strings.HasPrefix(path, prefix) {
+			break
+		}
+		childName, isSubDir := file.Child(path, prefix)
+		next := i + 1
+		if isSubDir {
+			// Skip the rest of the subdirectory: "0" sorts just after "/".
+			next = b.idx.Search(prefix + childName + "0")
+		}
+		if childName <= afterName || page.has(childName) {
+			i = next
+			continue
+		}
+		if page.complete(path[len(prefix):], childName) {
+			return page.entries, page.last(), nil
+		}
+
+		if isSubDir {
+			page.add(childName, file.NewDirEntry(file.NewDirInfo(childName), nil))
+		} else {
+			entry := blobtype.EntryFromViewWithPath(view, path)
+			info, infoErr := file.NewInfo(&entry, childName)
+			if infoErr != nil {
+				info = &file.Info{}
+			}
+			page.add(childName, file.NewDirEntry(info, infoErr))
+		}
+		i = next
+	}
+
+	page.sort()
+	if page.truncated {
+		return page.entries, page.last(), nil
+	}
+	return page.entries, "", nil
+}
+
+// ReadDirN returns the page of at most n entries of the named directory
+// that follows cursor, in ReadDir order. cursor is the last name of the
+// previous page, or "" for the first page; nextCursor is "" once the
+// directory is exhausted. It is ReadDirPage with the argument names of
+// fs.ReadDirFile.ReadDir, for callers paging by path rather than through an
+// open directory.
+func (b *Blob) ReadDirN(name, cursor string, n int) (entries []fs.DirEntry, nextCursor string, err error) {
+	return b.ReadDirPage(name, cursor, n)
+}
+
+// dirPage collects the smallest names of a directory page.
+//
+// Entries are visited in path order, which differs from name order for
+// subdirectories: "a/x" sorts after "a.txt", so directory "a" is found after
+// file "a.txt" even though its name sorts first. The page therefore keeps
+// collecting until no later path can produce a name that belongs on it.
+type dirPage struct {
+	limit     int
+	entries   []fs.DirEntry
+	names     map[string]struct{}
+	truncated bool
+}
+
+func (p *dirPage) has(name string) bool {
+	_, ok := p.names[name]
+	return ok
+}
+
+func (p *dirPage) add(name string, entry fs.DirEntry) {
+	if p.names == nil {
+		p.names = make(map[string]struct{})
+	}
+	p.names[name] = struct{}{}
+	p.entries = append(p.entries, entry)
+	if p.limit > 0 && len(p.entries) > p.limit {
+		p.sort()
+		p.entries = p.entries[:p.limit]
+		p.truncated = true
+	}
+}
+
+// complete reports whether the page is full and no entry at rel or after it
+// can sort before its last name. rel is the entry path relative to the
+// directory and name its child name, which is known to be new.
+func (p *dirPage) complete(rel, name string) bool {
+	if p.limit <= 0 || len(p.entries) < p.limit {
+		return false
+	}
+	p.sort()
+	last := p.last()
+	if name < last {
+		return false
+	}
+	// A later subdirectory D can still sort before last when last extends D
+	// with a byte below '/'. The shortest such D has the latest paths.
+	for i := range len(last) {
+		if last[i] < '/' {
+			return rel >= last[:i]+"0"
+		}
+	}
+	p.truncated = true
+	return true
+}
+
+func (p *dirPage) sort() {
+	slices.SortFunc(p.entries, func(x, y fs.DirEntry) int {
+		return strings.Compare(x.Name(), y.Name())
+	})
+}
+
+func (p *dirPage) last() string {
+	if len(p.entries) == 0 {
+		return ""
+	}
+	return p.entries[len(p.entries)-1].Name()
+}
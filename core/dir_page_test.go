@@ -0,0 +1,184 @@
+package blob
+
+import (
+	"crypto/sha256"
+	"fmt"
+	"io/fs"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/meigma/blob/core/testutil"
+)
+
+func TestReadDirPage(t *testing.T) {
+	t.Parallel()
+
+	content := []byte("x")
+	hash := sha256.Sum256(content)
+	fileEntry := func(path string) testutil.TestEntry {
+		return testutil.TestEntry{
+			Path:         path,
+			DataSize:     uint64(len(content)),
+			OriginalSize: uint64(len(content)),
+			Hash:         hash[:],
+			Mode:         0o644,
+		}
+	}
+
+	var entries []testutil.TestEntry
+	for i := range 95 {
+		entries = append(entries, fileEntry(fmt.Sprintf("wide/file%03d.txt", i)))
+	}
+	for i := range 12 {
+		entries = append(entries, fileEntry(fmt.Sprintf("wide/sub%02d/nested/deep.txt", i)))
+	}
+	// Subdirectories whose paths sort after sibling files with the same
+	// stem ("a.txt" < "a/x" in the index, but "a" < "a.txt" by name).
+	entries = append(entries,
+		fileEntry("wide/a-b"),
+		fileEntry("wide/a.txt"),
+		fileEntry("wide/a/x"),
+		fileEntry("wide/a/y"),
+		fileEntry("wide/a b/z"),
+		testutil.TestEntry{Path: "wide/explicit", Mode: fs.ModeDir | 0o755},
+		fileEntry("wide/explicit-notes.txt"),
+		fileEntry("wide/explicit/inside.txt"),
+		testutil.TestEntry{Path: "wide/empty", Mode: fs.ModeDir | 0o755},
+		fileEntry("other.txt"),
+	)
+	b, err := New(testutil.BuildTestIndex(t, entries), testutil.NewMockByteSource(content))
+	require.NoError(t, err)
+
+	full, err := b.ReadDir("wide")
+	require.NoError(t, err)
+	want := make([]string, len(full))
+	for i, e := range full {
+		want[i] = e.Name()
+	}
+
+	for _, limit := range []int{1, 3, 10} {
+		t.Run(fmt.Sprintf("limit %d", limit), func(t *testing.T) {
+			t.Parallel()
+
+			var got []string
+			token := ""
+			for pages := 0; ; pages++ {
+				require.Less(t, pages, len(want)+1, "pagination did not terminate")
+				page, next, err := b.ReadDirPage("wide", token, limit)
+				require.NoError(t, err)
+				require.LessOrEqual(t, len(page), limit)
+				for _, e := range page {
+					got = append(got, e.Name())
+				}
+				if next == "" {
+					break
+				}
+				require.Len(t, page, limit)
+				require.Equal(t, page[len(page)-1].Name(), next)
+				token = next
+			}
+			assert.Equal(t, want, got)
+		})
+	}
+
+	t.Run("entry types", func(t *testing.T) {
+		t.Parallel()
+
+		page, _, err := b.ReadDirPage("wide", "", 4)
+		require.NoError(t, err)
+		require.Len(t, page, 4)
+		assert.Equal(t, "a", page[0].Name())
+		assert.True(t, page[0].IsDir())
+		assert.Equal(t, "a b", page[1].Name())
+		assert.True(t, page[1].IsDir())
+		assert.Equal(t, "a-b", page[2].Name())
+		assert.False(t, page[2].IsDir())
+		assert.Equal(t, "a.txt", page[3].Name())
+	})
+
+	t.Run("unlimited", func(t *testing.T) {
+		t.Parallel()
+
+		page, next, err := b.ReadDirPage("wide", "file050.txt", 0)
+		require.NoError(t, err)
+		assert.Empty(t, next)
+		assert.Equal(t, "file051.txt", page[0].Name())
+	})
+
+	t.Run("root", func(t *testing.T) {
+		t.Parallel()
+
+		page, next, err := b.ReadDirPage(".", "", 10)
+		require.NoError(t, err)
+		assert.Empty(t, next)
+		require.Len(t, page, 2)
+		assert.Equal(t, "other.txt", page[0].Name())
+		assert.Equal(t, "wide", page[1].Name())
+	})
+
+	t.Run("missing directory", func(t *testing.T) {
+		t.Parallel()
+
+		_, _, err := b.ReadDirPage("missing", "", 10)
+		require.ErrorIs(t, err, fs.ErrNotExist)
+	})
+}
+
+func TestReadDirN(t *testing.T) {
+	t.Parallel()
+
+	content := []byte("x")
+	hash := sha256.Sum256(content)
+	var entries []testutil.TestEntry
+	for i := range 2000 {
+		path := fmt.Sprintf("big/f%05d", i)
+		if i%100 == 0 {
+			path = fmt.Sprintf("big/d%05d/inner.txt", i)
+		}
+		entries = append(entries, testutil.TestEntry{
+			Path:         path,
+			DataSize:     uint64(len(content)),
+			OriginalSize: uint64(len(content)),
+			Hash:         hash[:],
+			Mode:         0o644,
+		})
+	}
+	b, err := New(testutil.BuildTestIndex(t, entries), testutil.NewMockByteSource(content))
+	require.NoError(t, err)
+
+	full, err := b.ReadDir("big")
+	require.NoError(t, err)
+	require.Len(t, full, 2000)
+
+	// Paging twice over the full directory yields the same pages, and the
+	// pages concatenate to ReadDir.
+	for range 2 {
+		var got []fs.DirEntry
+		cursor := ""
+		for {
+			page, next, err := b.ReadDirN("big", cursor, 128)
+			require.NoError(t, err)
+			got = append(got, page...)
+			if next == "" {
+				break
+			}
+			cursor = next
+		}
+		require.Len(t, got, len(full))
+		for i := range full {
+			assert.Equal(t, full[i].Name(), got[i].Name())
+			assert.Equal(t, full[i].IsDir(), got[i].IsDir())
+		}
+	}
+
+	// Resuming from any cursor continues with the following entry.
+	page, _, err := b.ReadDirN("big", full[999].Name(), 1)
+	require.NoError(t, err)
+	require.Len(t, page, 1)
+	assert.Equal(t, full[1000].Name(), page[0].Name())
+
+	_, _, err = b.ReadDirN("big/f00001", "", 10)
+	require.ErrorIs(t, err, fs.ErrNotExist)
+}
@@ -0,0 +1,217 @@
+package blob
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/meigma/blob/core/testutil"
+)
+
+var encryptionFiles = map[string][]byte{
+	"a.txt":     bytes.Repeat([]byte("alpha secret "), 200),
+	"dir/b.txt": []byte("bravo secret"),
+	"empty.txt": {},
+}
+
+// buildEncryptedArchive creates an archive from encryptionFiles encrypted with key.
+func buildEncryptedArchive(t *testing.T, key []byte, compression Compression) (indexData, data []byte) {
+	t.Helper()
+
+	dir := t.TempDir()
+	createTestFilesBytes(t, dir, encryptionFiles)
+
+	var indexBuf, dataBuf bytes.Buffer
+	err := Create(context.Background(), dir, &indexBuf, &dataBuf,
+		CreateWithCompression(compression),
+		CreateWithEncryption(key, EncryptionAESGCM),
+	)
+	require.NoError(t, err)
+	return indexBuf.Bytes(), dataBuf.Bytes()
+}
+
+func TestEncryption_RoundTrip(t *testing.T) {
+	t.Parallel()
+
+	key := bytes.Repeat([]byte{0x42}, 32)
+	for _, compression := range []Compression{CompressionNone, CompressionZstd} {
+		t.Run(compression.String(), func(t *testing.T) {
+			t.Parallel()
+
+			indexData, data := buildEncryptedArchive(t, key, compression)
+			assert.NotContains(t, string(data), "secret", "data blob should not hold plaintext")
+
+			b, err := New(indexData, testutil.NewMockByteSource(data), WithDecryptionKey(key))
+			require.NoError(t, err)
+			require.NoError(t, b.Verify(context.Background()))
+
+			for name, want := range encryptionFiles {
+				got, err := b.ReadFile(name)
+				require.NoError(t, err, name)
+				assert.Equal(t, want, got, name)
+
+				f, err := b.Open(name)
+				require.NoError(t, err, name)
+				streamed, err := io.ReadAll(f)
+				require.NoError(t, err, name)
+				require.NoError(t, f.Close(), name)
+				assert.Equal(t, want, streamed, name)
+			}
+
+			if compression == CompressionNone {
+				got, err := b.ReadFileRange("dir/b.txt", 6, 6)
+				require.NoError(t, err)
+				assert.Equal(t, "secret", string(got))
+			}
+
+			destDir := t.TempDir()
+			stats, err := b.CopyDir(destDir, "")
+			require.NoError(t, err)
+			assert.Equal(t, len(encryptionFiles), stats.FileCount)
+			for name, want := range encryptionFiles {
+				got, err := os.ReadFile(filepath.Join(destDir, filepath.FromSlash(name)))
+				require.NoError(t, err, name)
+				assert.Equal(t, want, got, name)
+			}
+		})
+	}
+}
+
+func TestEncryption_WrongKey(t *testing.T) {
+	t.Parallel()
+
+	indexData, data := buildEncryptedArchive(t, bytes.Repeat([]byte{0x42}, 32), CompressionZstd)
+
+	t.Run("wrong key", func(t *testing.T) {
+		t.Parallel()
+
+		b, err := New(indexData, testutil.NewMockByteSource(data), WithDecryptionKey(bytes.Repeat([]byte{0x24}, 32)))
+		require.NoError(t, err)
+
+		_, err = b.ReadFile("a.txt")
+		require.ErrorIs(t, err, ErrDecryption)
+
+		_, err = b.CopyDir(t.TempDir(), "")
+		require.ErrorIs(t, err, ErrDecryption)
+	})
+
+	t.Run("no key", func(t *testing.T) {
+		t.Parallel()
+
+		b, err := New(indexData, testutil.NewMockByteSource(data))
+		require.NoError(t, err)
+		assert.True(t, b.Exists("dir/b.txt"), "paths stay readable without a key")
+
+		_, err = b.ReadFile("dir/b.txt")
+		require.ErrorIs(t, err, ErrDecryption)
+	})
+
+	t.Run("invalid key length", func(t *testing.T) {
+		t.Parallel()
+
+		_, err := New(indexData, testutil.NewMockByteSource(data), WithDecryptionKey([]byte("short")))
+		require.Error(t, err)
+
+		err = Create(context.Background(), t.TempDir(), io.Discard, io.Discard,
+			CreateWithEncryption([]byte("short"), EncryptionAESGCM))
+		require.Error(t, err)
+	})
+}
+
+// reindex rebuilds an encrypted archive's index after edit changes its
+// entries.
+func reindex(t *testing.T, indexData, data []byte, edit func(entries []Entry)) []byte {
+	t.Helper()
+
+	b, err := New(indexData, testutil.NewMockByteSource(data))
+	require.NoError(t, err)
+	var entries []Entry
+	for view := range b.Entries() {
+		entries = append(entries, view.Entry())
+	}
+	edit(entries)
+	return buildIndex(entries, indexMetadata{dataSize: uint64(len(data)), encryption: EncryptionAESGCM})
+}
+
+func TestEncryption_BoundToPathAndOffset(t *testing.T) {
+	t.Parallel()
+
+	key := bytes.Repeat([]byte{0x42}, 32)
+	indexData, data := buildEncryptedArchive(t, key, CompressionZstd)
+
+	t.Run("swapped entries", func(t *testing.T) {
+		t.Parallel()
+
+		swapped := reindex(t, indexData, data, func(entries []Entry) {
+			// Swap everything but the paths of a.txt and dir/b.txt.
+			entries[0].Path, entries[1].Path = entries[1].Path, entries[0].Path
+			entries[0], entries[1] = entries[1], entries[0]
+		})
+		b, err := New(swapped, testutil.NewMockByteSource(data), WithDecryptionKey(key))
+		require.NoError(t, err)
+
+		_, err = b.ReadFile("a.txt")
+		require.ErrorIs(t, err, ErrDecryption)
+		_, err = b.CopyDir(t.TempDir(), "")
+		require.ErrorIs(t, err, ErrDecryption)
+	})
+
+	t.Run("moved data", func(t *testing.T) {
+		t.Parallel()
+
+		const shift = 16
+		moved := append(make([]byte, shift), data...)
+		shifted := reindex(t, indexData, moved, func(entries []Entry) {
+			for i := range entries {
+				entries[i].DataOffset += shift
+			}
+		})
+		b, err := New(shifted, testutil.NewMockByteSource(moved), WithDecryptionKey(key))
+		require.NoError(t, err)
+
+		_, err = b.ReadFile("dir/b.txt")
+		require.ErrorIs(t, err, ErrDecryption)
+	})
+
+	t.Run("rewritten paths", func(t *testing.T) {
+		t.Parallel()
+
+		b, err := New(indexData, testutil.NewMockByteSource(data), WithDecryptionKey(key))
+		require.NoError(t, err)
+		want := encryptionFiles["dir/b.txt"]
+
+		sub, err := b.Subset("dir")
+		require.NoError(t, err)
+		got, err := sub.ReadFile("b.txt")
+		require.NoError(t, err)
+		assert.Equal(t, want, got)
+
+		destDir := t.TempDir()
+		_, err = b.CopyDir(destDir, "dir", CopyWithPathMapper(func(string) (string, bool, error) {
+			return "renamed.txt", false, nil
+		}))
+		require.NoError(t, err)
+		got, err = os.ReadFile(filepath.Join(destDir, "renamed.txt"))
+		require.NoError(t, err)
+		assert.Equal(t, want, got)
+
+		// Update re-seals the copied entries for their new paths and offsets.
+		var indexBuf, dataBuf bytes.Buffer
+		err = Update(context.Background(), sub, &indexBuf, &dataBuf,
+			[]FileChange{{Path: "a.txt", Content: []byte("first")}},
+			CreateWithEncryption(key, EncryptionAESGCM))
+		require.NoError(t, err)
+		updated, err := New(indexBuf.Bytes(), testutil.NewMockByteSource(dataBuf.Bytes()), WithDecryptionKey(key))
+		require.NoError(t, err)
+		require.NoError(t, updated.Verify(context.Background()))
+		got, err = updated.ReadFile("b.txt")
+		require.NoError(t, err)
+		assert.Equal(t, want, got)
+	})
+}
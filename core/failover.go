@@ -0,0 +1,143 @@
+package blob
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"sync/atomic"
+
+	"github.com/meigma/blob/core/internal/file"
+)
+
+// NewFailoverSource returns a ByteSource that serves reads from the first
+// healthy source among mirrors of the same data blob.
+//
+// Each read starts at the source that last succeeded (initially the first)
+// and advances through the others in order when a read fails, so a dead
+// mirror is not re-probed on every call while a healthy primary is never
+// bypassed. io.EOF and context cancellation are returned without failing
+// over. If every source fails, the returned error wraps each source's error.
+//
+// All sources must report the same Size; NewFailoverSource returns an error
+// otherwise. Mirrors on different hosts have different SourceIDs, so the
+// failover source reports the SourceID of the first source. The returned
+// source supports range streams only when every source does.
+func NewFailoverSource(sources ...ByteSource) (ByteSource, error) {
+	if len(sources) == 0 {
+		return nil, errors.New("failover source: no sources")
+	}
+	size := sources[0].Size()
+	allRange := true
+	for i, src := range sources {
+		if src == nil {
+			return nil, fmt.Errorf("failover source: source %d is nil", i)
+		}
+		if src.Size() != size {
+			return nil, fmt.Errorf("failover source: source %d (%s) size %d does not match %d",
+				i, src.SourceID(), src.Size(), size)
+		}
+		if _, ok := src.(rangeReader); !ok {
+			allRange = false
+		}
+	}
+	fs := &failoverSource{sources: sources}
+	if allRange {
+		return &failoverRangeSource{failoverSource: fs}, nil
+	}
+	return fs, nil
+}
+
+// failoverSource reads from the first healthy source in a list of mirrors.
+type failoverSource struct {
+	sources []ByteSource
+	current atomic.Int32 // index of the last source that succeeded
+}
+
+// Size returns the shared size of the sources.
+func (s *failoverSource) Size() int64 {
+	return s.sources[0].Size()
+}
+
+// SourceID returns the identifier of the first source.
+func (s *failoverSource) SourceID() string {
+	return s.sources[0].SourceID()
+}
+
+// preferred returns the index of the source that reads start from.
+func (s *failoverSource) preferred() int {
+	return int(s.current.Load())
+}
+
+// ReadAt implements io.ReaderAt.
+func (s *failoverSource) ReadAt(p []byte, off int64) (int, error) {
+	return s.ReadAtContext(context.Background(), p, off)
+}
+
+// ReadAtContext is like ReadAt but stops failing over once ctx is done.
+func (s *failoverSource) ReadAtContext(ctx context.Context, p []byte, off int64) (int, error) {
+	start := s.preferred()
+	read := 0
+	var errs []error
+	for i := range s.sources {
+		idx := (start + i) % len(s.sources)
+		n, err := file.ReadAtContext(ctx, s.sources[idx], p[read:], off+int64(read))
+		read += n
+		if err == nil || !s.shouldFailover(err) {
+			s.markHealthy(start, idx)
+			return read, err
+		}
+		errs = append(errs, fmt.Errorf("source %d: %w", idx, err))
+	}
+	return read, s.allFailed(errs)
+}
+
+// shouldFailover reports whether err warrants trying the next source.
+func (s *failoverSource) shouldFailover(err error) bool {
+	return !errors.Is(err, io.EOF) && !errors.Is(err, context.Canceled) && !errors.Is(err, context.DeadlineExceeded)
+}
+
+// markHealthy records idx as the preferred source if reads started elsewhere.
+func (s *failoverSource) markHealthy(start, idx int) {
+	if idx != start {
+		s.current.CompareAndSwap(int32(start), int32(idx)) //nolint:gosec // index bounded by len(sources)
+	}
+}
+
+// allFailed builds the error returned when no source could serve a read.
+func (s *failoverSource) allFailed(errs []error) error {
+	return fmt.Errorf("all %d sources failed: %w", len(s.sources), errors.Join(errs...))
+}
+
+// failoverRangeSource adds range streams to failoverSource.
+type failoverRangeSource struct {
+	*failoverSource
+}
+
+// ReadRange opens [off, off+length) on the first source that accepts it.
+func (s *failoverRangeSource) ReadRange(off, length int64) (io.ReadCloser, error) {
+	return s.ReadRangeContext(context.Background(), off, length)
+}
+
+// ReadRangeContext is like ReadRange but binds the stream to ctx.
+//
+// Failover happens only when opening the range; errors while reading the
+// returned stream are reported as-is.
+func (s *failoverRangeSource) ReadRangeContext(ctx context.Context, off, length int64) (io.ReadCloser, error) {
+	start := s.preferred()
+	var errs []error
+	for i := range s.sources {
+		idx := (start + i) % len(s.sources)
+		rr := file.WithContext(ctx, s.sources[idx]).(rangeReader) //nolint:forcetypeassert // checked at construction
+		rc, err := rr.ReadRange(off, length)
+		if err == nil || !s.shouldFailover(err) {
+			s.markHealthy(start, idx)
+			return rc, err
+		}
+		if rc != nil {
+			rc.Close()
+		}
+		errs = append(errs, fmt.Errorf("source %d: %w", idx, err))
+	}
+	return nil, s.allFailed(errs)
+}
@@ -0,0 +1,129 @@
+package blob
+
+import (
+	"errors"
+	"io"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/meigma/blob/core/testutil"
+)
+
+func TestFailoverSource(t *testing.T) {
+	t.Parallel()
+
+	data := []byte("mirrored data blob content")
+	down := errors.New("mirror down")
+
+	t.Run("fails over to secondary", func(t *testing.T) {
+		t.Parallel()
+
+		primary := &flakySource{MockByteSource: testutil.NewMockByteSource(data), failures: 1 << 20, err: down}
+		secondary := &flakySource{MockByteSource: testutil.NewMockByteSource(data)}
+		src, err := NewFailoverSource(primary, secondary)
+		require.NoError(t, err)
+		assert.Equal(t, primary.SourceID(), src.SourceID())
+
+		for range 3 {
+			buf := make([]byte, 8)
+			n, err := src.ReadAt(buf, 9)
+			require.NoError(t, err)
+			assert.Equal(t, data[9:17], buf[:n])
+		}
+		assert.Equal(t, int32(1), primary.calls.Load(), "dead primary should not be re-probed")
+		assert.Equal(t, int32(3), secondary.calls.Load())
+	})
+
+	t.Run("healthy primary is not bypassed", func(t *testing.T) {
+		t.Parallel()
+
+		primary := &flakySource{MockByteSource: testutil.NewMockByteSource(data)}
+		secondary := &flakySource{MockByteSource: testutil.NewMockByteSource(data)}
+		src, err := NewFailoverSource(primary, secondary)
+		require.NoError(t, err)
+
+		for range 3 {
+			_, err := src.ReadAt(make([]byte, 4), 0)
+			require.NoError(t, err)
+		}
+		assert.Equal(t, int32(3), primary.calls.Load())
+		assert.Zero(t, secondary.calls.Load())
+	})
+
+	t.Run("EOF does not fail over", func(t *testing.T) {
+		t.Parallel()
+
+		primary := &flakySource{MockByteSource: testutil.NewMockByteSource(data)}
+		secondary := &flakySource{MockByteSource: testutil.NewMockByteSource(data)}
+		src, err := NewFailoverSource(primary, secondary)
+		require.NoError(t, err)
+
+		_, err = src.ReadAt(make([]byte, 4), int64(len(data)))
+		require.ErrorIs(t, err, io.EOF)
+		assert.Zero(t, secondary.calls.Load())
+	})
+
+	t.Run("all sources fail", func(t *testing.T) {
+		t.Parallel()
+
+		primary := &flakySource{MockByteSource: testutil.NewMockByteSource(data), failures: 1, err: down}
+		secondary := &flakySource{MockByteSource: testutil.NewMockByteSource(data), failures: 1, err: down}
+		src, err := NewFailoverSource(primary, secondary)
+		require.NoError(t, err)
+
+		_, err = src.ReadAt(make([]byte, 4), 0)
+		require.ErrorIs(t, err, down)
+	})
+
+	t.Run("validation", func(t *testing.T) {
+		t.Parallel()
+
+		_, err := NewFailoverSource()
+		require.Error(t, err)
+
+		_, err = NewFailoverSource(testutil.NewMockByteSource(data), testutil.NewMockByteSource(data[1:]))
+		require.ErrorContains(t, err, "size")
+	})
+}
+
+func TestFailoverSource_ReadRange(t *testing.T) {
+	t.Parallel()
+
+	data := []byte("mirrored range content")
+
+	mock := testutil.NewMockByteSource(data)
+	secondary := &flakyRangeSource{flakySource: &flakySource{MockByteSource: mock}}
+	secondary.opens.Store(1) // skip the injected mid-stream failure
+	src := mustFailover(t, &failingRangeSource{MockByteSource: mock}, secondary)
+
+	rr, ok := src.(rangeReader)
+	require.True(t, ok)
+	rc, err := rr.ReadRange(3, 5)
+	require.NoError(t, err)
+	got, err := io.ReadAll(rc)
+	require.NoError(t, err)
+	require.NoError(t, rc.Close())
+	assert.Equal(t, data[3:8], got)
+
+	_, ok = mustFailover(t, mock, &flakySource{MockByteSource: mock}).(rangeReader)
+	assert.False(t, ok, "range support requires every source to support it")
+}
+
+// failingRangeSource is a range-capable source whose range opens always fail.
+type failingRangeSource struct {
+	*testutil.MockByteSource
+}
+
+func (s *failingRangeSource) ReadRange(int64, int64) (io.ReadCloser, error) {
+	return nil, errors.New("range open failed")
+}
+
+func mustFailover(t *testing.T, sources ...ByteSource) ByteSource {
+	t.Helper()
+
+	src, err := NewFailoverSource(sources...)
+	require.NoError(t, err)
+	return src
+}
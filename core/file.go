@@ -3,6 +3,7 @@ package blob
 import (
 	"encoding/hex"
 	"fmt"
+	"math"
 	"os"
 	"path/filepath"
 
@@ -98,7 +99,7 @@ func OpenFile(indexPath, dataPath string, opts ...Option) (*BlobFile, error) {
 
 	// Wrap data file as ByteSource
 	sourceID := ""
-	if idx, loadErr := index.Load(indexData); loadErr == nil {
+	if idx, loadErr := index.Load(indexData, index.WithMaxVersion(math.MaxUint32)); loadErr == nil {
 		if hash, ok := idx.DataHash(); ok {
 			sourceID = "sha256:" + hex.EncodeToString(hash)
 		}
@@ -1,10 +1,17 @@
 package blob
 
+import (
+	"time"
+
+	"github.com/opencontainers/go-digest"
+)
+
 // createBlobConfig holds configuration for CreateBlob.
 type createBlobConfig struct {
 	indexName  string
 	dataName   string
 	createOpts []CreateOption
+	openOpts   []Option
 }
 
 // getIndexName returns the configured index file name or the default.
@@ -47,6 +54,60 @@ func CreateBlobWithCompression(compression Compression) CreateBlobOption {
 	}
 }
 
+// CreateBlobWithCompressionLevel sets the zstd encoder level.
+func CreateBlobWithCompressionLevel(level CompressionLevel) CreateBlobOption {
+	return func(c *createBlobConfig) {
+		c.createOpts = append(c.createOpts, CreateWithCompressionLevel(level))
+	}
+}
+
+// CreateBlobWithCompressionWorkers compresses up to n files concurrently.
+// See CreateWithCompressionWorkers.
+func CreateBlobWithCompressionWorkers(n int) CreateBlobOption {
+	return func(c *createBlobConfig) {
+		c.createOpts = append(c.createOpts, CreateWithCompressionWorkers(n))
+	}
+}
+
+// CreateBlobWithTempDir sets the directory for temporary files.
+// See CreateWithTempDir.
+func CreateBlobWithTempDir(dir string) CreateBlobOption {
+	return func(c *createBlobConfig) {
+		c.createOpts = append(c.createOpts, CreateWithTempDir(dir))
+	}
+}
+
+// CreateBlobWithMinCompressionRatio sets the minimum savings for compressed storage.
+func CreateBlobWithMinCompressionRatio(ratio float64) CreateBlobOption {
+	return func(c *createBlobConfig) {
+		c.createOpts = append(c.createOpts, CreateWithMinCompressionRatio(ratio))
+	}
+}
+
+// CreateBlobWithZstdDictionary sets the zstd dictionary used for compression.
+// The returned BlobFile is opened with it; pass WithZstdDictionary to
+// OpenFile when reopening the archive.
+func CreateBlobWithZstdDictionary(dict []byte) CreateBlobOption {
+	return func(c *createBlobConfig) {
+		c.createOpts = append(c.createOpts, CreateWithZstdDictionary(dict))
+		c.openOpts = append(c.openOpts, WithZstdDictionary(dict))
+	}
+}
+
+// CreateBlobWithDigests records the OCI digests of the index and data blobs.
+func CreateBlobWithDigests(index, data *digest.Digest) CreateBlobOption {
+	return func(c *createBlobConfig) {
+		c.createOpts = append(c.createOpts, CreateWithDigests(index, data))
+	}
+}
+
+// CreateBlobWithConcurrentModification sets the concurrent modification mode.
+func CreateBlobWithConcurrentModification(mode ConcurrentModification) CreateBlobOption {
+	return func(c *createBlobConfig) {
+		c.createOpts = append(c.createOpts, CreateWithConcurrentModification(mode))
+	}
+}
+
 // CreateBlobWithChangeDetection sets the change detection mode.
 func CreateBlobWithChangeDetection(cd ChangeDetection) CreateBlobOption {
 	return func(c *createBlobConfig) {
@@ -67,3 +128,113 @@ func CreateBlobWithMaxFiles(n int) CreateBlobOption {
 		c.createOpts = append(c.createOpts, CreateWithMaxFiles(n))
 	}
 }
+
+// CreateBlobWithMaxPathLength rejects files whose archive path is longer than n bytes.
+func CreateBlobWithMaxPathLength(n int) CreateBlobOption {
+	return func(c *createBlobConfig) {
+		c.createOpts = append(c.createOpts, CreateWithMaxPathLength(n))
+	}
+}
+
+// CreateBlobWithMaxPathDepth rejects files whose archive path has more than n elements.
+func CreateBlobWithMaxPathDepth(n int) CreateBlobOption {
+	return func(c *createBlobConfig) {
+		c.createOpts = append(c.createOpts, CreateWithMaxPathDepth(n))
+	}
+}
+
+// CreateBlobWithRejectCaseCollisions rejects archive paths that differ only
+// in case. See CreateWithRejectCaseCollisions.
+func CreateBlobWithRejectCaseCollisions(enabled bool) CreateBlobOption {
+	return func(c *createBlobConfig) {
+		c.createOpts = append(c.createOpts, CreateWithRejectCaseCollisions(enabled))
+	}
+}
+
+// CreateBlobWithExclude leaves out paths matching any of patterns.
+// See CreateWithExclude.
+func CreateBlobWithExclude(patterns ...string) CreateBlobOption {
+	return func(c *createBlobConfig) {
+		c.createOpts = append(c.createOpts, CreateWithExclude(patterns...))
+	}
+}
+
+// CreateBlobWithInclude restricts the archive to paths matching any of
+// patterns. See CreateWithInclude.
+func CreateBlobWithInclude(patterns ...string) CreateBlobOption {
+	return func(c *createBlobConfig) {
+		c.createOpts = append(c.createOpts, CreateWithInclude(patterns...))
+	}
+}
+
+// CreateBlobWithIgnoreFile reads exclusion patterns from the named file at
+// the root of the source directory. See CreateWithIgnoreFile.
+func CreateBlobWithIgnoreFile(name string) CreateBlobOption {
+	return func(c *createBlobConfig) {
+		c.createOpts = append(c.createOpts, CreateWithIgnoreFile(name))
+	}
+}
+
+// CreateBlobWithStripPrefix drops the first n elements of every path.
+// See CreateWithStripPrefix.
+func CreateBlobWithStripPrefix(n int) CreateBlobOption {
+	return func(c *createBlobConfig) {
+		c.createOpts = append(c.createOpts, CreateWithStripPrefix(n))
+	}
+}
+
+// CreateBlobWithPathPrefix stores every file under the directory p.
+// See CreateWithPathPrefix.
+func CreateBlobWithPathPrefix(p string) CreateBlobOption {
+	return func(c *createBlobConfig) {
+		c.createOpts = append(c.createOpts, CreateWithPathPrefix(p))
+	}
+}
+
+// CreateBlobWithSymlinks records symbolic links in the archive.
+func CreateBlobWithSymlinks(enabled bool) CreateBlobOption {
+	return func(c *createBlobConfig) {
+		c.createOpts = append(c.createOpts, CreateWithSymlinks(enabled))
+	}
+}
+
+// CreateBlobWithModTime records t as the modification time of every entry.
+// See CreateWithModTime.
+func CreateBlobWithModTime(t time.Time) CreateBlobOption {
+	return func(c *createBlobConfig) {
+		c.createOpts = append(c.createOpts, CreateWithModTime(t))
+	}
+}
+
+// CreateBlobWithAuxChecksum records a per-entry checksum computed with alg.
+// See CreateWithAuxChecksum.
+func CreateBlobWithAuxChecksum(alg AuxChecksum) CreateBlobOption {
+	return func(c *createBlobConfig) {
+		c.createOpts = append(c.createOpts, CreateWithAuxChecksum(alg))
+	}
+}
+
+// CreateBlobWithChunking stores large files as content-defined chunks
+// averaging avgChunkSize bytes. See CreateWithChunking.
+func CreateBlobWithChunking(avgChunkSize int) CreateBlobOption {
+	return func(c *createBlobConfig) {
+		c.createOpts = append(c.createOpts, CreateWithChunking(avgChunkSize))
+	}
+}
+
+// CreateBlobWithBloomFilter stores a bloom filter over the archived paths
+// in the index. See CreateWithBloomFilter.
+func CreateBlobWithBloomFilter(enabled bool) CreateBlobOption {
+	return func(c *createBlobConfig) {
+		c.createOpts = append(c.createOpts, CreateWithBloomFilter(enabled))
+	}
+}
+
+// CreateBlobWithEncryption encrypts file content in the data blob.
+// The returned BlobFile is opened with the same key.
+func CreateBlobWithEncryption(key []byte, scheme Encryption) CreateBlobOption {
+	return func(c *createBlobConfig) {
+		c.createOpts = append(c.createOpts, CreateWithEncryption(key, scheme))
+		c.openOpts = append(c.openOpts, WithDecryptionKey(key))
+	}
+}
@@ -0,0 +1,185 @@
+package http //nolint:revive // intentional naming for domain clarity
+
+import (
+	"cmp"
+	"context"
+	"slices"
+	"sync"
+	"sync/atomic"
+)
+
+// Coalescing limits.
+const (
+	// maxCoalescedSpan bounds the range, and so the buffer, of a merged
+	// request. A single read larger than this is still fetched whole.
+	maxCoalescedSpan = 8 << 20
+
+	// defaultCoalesceRunners is the number of merged requests in flight
+	// when WithMaxConcurrentRequests is not set.
+	defaultCoalesceRunners = 4
+)
+
+// coalescer merges queued ReadAt calls into shared range requests.
+//
+// Reads are queued in pending. Up to limit runners drain the queue; each
+// runner takes the lowest-offset read plus every queued read that starts
+// within gap bytes of the range collected so far, as long as the range
+// stays within maxCoalescedSpan, fetches the combined range once, and hands
+// each read its slice of the response.
+type coalescer struct {
+	src   *Source
+	gap   int64
+	limit int
+
+	mu      sync.Mutex
+	pending []*coalescedRead
+	active  int
+}
+
+// coalescedRead is a single ReadAt call waiting in a coalescer.
+type coalescedRead struct {
+	ctx  context.Context //nolint:containedctx // bound to the waiting call
+	p    []byte
+	off  int64
+	n    int
+	err  error
+	done chan struct{}
+}
+
+func newCoalescer(src *Source, gap int64) *coalescer {
+	limit := defaultCoalesceRunners
+	if src.sem != nil {
+		limit = cap(src.sem)
+	}
+	return &coalescer{src: src, gap: gap, limit: limit}
+}
+
+// readAt queues a read of [off, off+len(p)) and waits for it to be served.
+// The range must lie within the content.
+func (c *coalescer) readAt(ctx context.Context, p []byte, off int64) (int, error) {
+	r := &coalescedRead{ctx: ctx, p: p, off: off, done: make(chan struct{})}
+
+	c.mu.Lock()
+	c.pending = append(c.pending, r)
+	start := c.active < c.limit
+	if start {
+		c.active++
+	}
+	c.mu.Unlock()
+	if start {
+		go c.run()
+	}
+
+	select {
+	case <-r.done:
+		return r.n, r.err
+	case <-ctx.Done():
+	}
+
+	// A read still in the queue can leave immediately. One already taken by
+	// a runner must wait, since the runner writes into p.
+	c.mu.Lock()
+	if i := slices.Index(c.pending, r); i >= 0 {
+		c.pending = slices.Delete(c.pending, i, i+1)
+		c.mu.Unlock()
+		return 0, ctx.Err()
+	}
+	c.mu.Unlock()
+	<-r.done
+	return r.n, r.err
+}
+
+// run serves queued reads until the queue is empty.
+func (c *coalescer) run() {
+	for {
+		group := c.take()
+		if group == nil {
+			return
+		}
+		c.fetch(group)
+	}
+}
+
+// take removes the next group of mergeable reads from the queue. It returns
+// nil, and retires the runner, when the queue is empty.
+func (c *coalescer) take() []*coalescedRead {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if len(c.pending) == 0 {
+		c.active--
+		return nil
+	}
+	slices.SortFunc(c.pending, func(a, b *coalescedRead) int {
+		return cmp.Compare(a.off, b.off)
+	})
+
+	start := c.pending[0].off
+	end := start + int64(len(c.pending[0].p))
+	n := 1
+	for ; n < len(c.pending); n++ {
+		r := c.pending[n]
+		rEnd := max(end, r.off+int64(len(r.p)))
+		if r.off-end > c.gap || rEnd-start > maxCoalescedSpan {
+			break
+		}
+		end = rEnd
+	}
+	group := slices.Clone(c.pending[:n])
+	c.pending = slices.Delete(c.pending, 0, n)
+	return group
+}
+
+// fetch reads the range spanning group with one request and distributes the
+// bytes. Reads beyond a short response receive the request's error, or
+// their own context's error once it is done.
+func (c *coalescer) fetch(group []*coalescedRead) {
+	start := group[0].off
+	end := start
+	for _, r := range group {
+		end = max(end, r.off+int64(len(r.p)))
+	}
+
+	ctx, cancel := groupContext(group)
+	defer cancel()
+
+	if len(group) > 1 {
+		c.src.log().Debug("coalescing reads", "reads", len(group), "offset", start, "length", end-start)
+	}
+	buf := make([]byte, end-start)
+	n, err := c.src.readFull(ctx, buf, start)
+
+	for _, r := range group {
+		rel := r.off - start
+		avail := max(int64(n)-rel, 0)
+		r.n = copy(r.p, buf[min(rel, int64(n)):n])
+		if avail < int64(len(r.p)) {
+			r.err = err
+			if ctxErr := r.ctx.Err(); ctxErr != nil {
+				r.err = ctxErr
+			}
+		}
+		close(r.done)
+	}
+}
+
+// groupContext returns a context for a merged request that keeps the values
+// of the first read and is canceled once every read in group is done.
+func groupContext(group []*coalescedRead) (context.Context, context.CancelFunc) {
+	ctx, cancel := context.WithCancel(context.WithoutCancel(group[0].ctx))
+	remaining := int64(len(group))
+	stops := make([]func() bool, 0, len(group))
+	for _, r := range group {
+		stops = append(stops, context.AfterFunc(r.ctx, func() {
+			if atomic.AddInt64(&remaining, -1) == 0 {
+				cancel()
+			}
+		}))
+	}
+	return ctx, func() {
+		for _, stop := range stops {
+			stop()
+		}
+		cancel()
+	}
+}
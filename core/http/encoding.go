@@ -0,0 +1,89 @@
+package http //nolint:revive // intentional naming for domain clarity
+
+import (
+	"compress/gzip"
+	"fmt"
+	"io"
+	nethttp "net/http"
+	"strings"
+
+	"github.com/klauspost/compress/zstd"
+
+	"github.com/meigma/blob/core/internal/file"
+)
+
+// compressedAcceptEncoding is sent on range requests when
+// WithCompressedTransfer is set.
+const compressedAcceptEncoding = "zstd, gzip"
+
+// minResponseWindow is the smallest zstd window accepted for a range
+// response. It covers the window encoders pick at common levels when they
+// stream a body of unknown size.
+const minResponseWindow = 8 << 20
+
+// responseWindow returns the largest zstd window accepted for a range
+// response of length bytes: length rounded up to a power of two, but no
+// less than minResponseWindow. A response cannot need a window larger than
+// the bytes it encodes, so this bounds decoder memory by the range rather
+// than by the 512 MiB zstd default.
+func responseWindow(length int64) uint64 {
+	window := uint64(minResponseWindow)
+	for window < uint64(max(length, 0)) && window < zstd.MaxWindowSize {
+		window <<= 1
+	}
+	return window
+}
+
+// decodedBody decodes a response body sent with a zstd or gzip
+// Content-Encoding. Closing it releases the decoder but leaves the response
+// body open.
+type decodedBody struct {
+	io.Reader
+	close func()
+}
+
+func (d *decodedBody) Close() error {
+	d.close()
+	return nil
+}
+
+// responseBody returns the body of a range response of length bytes with any
+// content coding removed. The caller must close the result, which releases
+// any decoder, and then drain and close resp.Body itself.
+//
+// A zstd decoder accepts windows up to responseWindow(length) and is
+// charged that much against the global decoder memory limit (see
+// blob.SetGlobalDecoderMemoryLimit), without waiting, since the read that
+// requested the range may already hold a charge of its own.
+//
+// Servers that compress range responses encode only the requested bytes, so
+// the decoded body holds exactly the range announced in Content-Range.
+// Servers that disable compression for ranges answer without a
+// Content-Encoding and the body is returned as is.
+func responseBody(resp *nethttp.Response, length int64) (io.ReadCloser, error) {
+	encoding := strings.ToLower(strings.TrimSpace(resp.Header.Get("Content-Encoding")))
+	switch encoding {
+	case "", "identity":
+		return io.NopCloser(resp.Body), nil
+	case "gzip", "x-gzip":
+		zr, err := gzip.NewReader(resp.Body)
+		if err != nil {
+			return nil, fmt.Errorf("decode gzip range response: %w", err)
+		}
+		return &decodedBody{Reader: zr, close: func() { _ = zr.Close() }}, nil
+	case "zstd":
+		window := responseWindow(length)
+		release := file.ChargeDecoderMemory(window)
+		zr, err := zstd.NewReader(resp.Body, zstd.WithDecoderConcurrency(1), zstd.WithDecoderMaxWindow(window))
+		if err != nil {
+			release()
+			return nil, fmt.Errorf("decode zstd range response: %w", err)
+		}
+		return &decodedBody{Reader: zr, close: func() {
+			zr.Close()
+			release()
+		}}, nil
+	default:
+		return nil, fmt.Errorf("range response has unsupported Content-Encoding %q", encoding)
+	}
+}
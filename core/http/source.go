@@ -3,6 +3,7 @@ package http //nolint:revive // intentional naming for domain clarity
 import (
 	"bytes"
 	"context"
+	"encoding/base64"
 	"errors"
 	"fmt"
 	"io"
@@ -10,6 +11,7 @@ import (
 	nethttp "net/http"
 	"strconv"
 	"strings"
+	"sync"
 )
 
 // Source implements random access reads via HTTP range requests.
@@ -18,14 +20,26 @@ type Source struct {
 	url                   string
 	client                *nethttp.Client
 	headers               nethttp.Header
+	authorization         string
 	size                  int64
 	etag                  string
 	lastModified          string
 	sourceID              string
 	useConditionalHeaders bool
+	validate              bool
+	compressedTransfer    bool
+	validator             string
+	sem                   chan struct{}
+	coalesceGap           int64
+	coalescer             *coalescer
 	logger                *slog.Logger
 }
 
+// ErrSourceChanged is returned by sources created with WithValidator when
+// the server reports that the content no longer matches the version seen
+// when the source was created.
+var ErrSourceChanged = errors.New("http source: content changed")
+
 // log returns the logger, falling back to a discard logger if nil.
 func (s *Source) log() *slog.Logger {
 	if s.logger == nil {
@@ -34,6 +48,29 @@ func (s *Source) log() *slog.Logger {
 	return s.logger
 }
 
+// StatusError reports a range request that failed with an unexpected HTTP status.
+type StatusError struct {
+	StatusCode int
+	Status     string
+}
+
+func (e *StatusError) Error() string {
+	return fmt.Sprintf("range request failed: %s", e.Status)
+}
+
+// Temporary reports whether the status indicates a transient server condition
+// (408, 429, or 5xx) that may succeed on retry.
+func (e *StatusError) Temporary() bool {
+	switch {
+	case e.StatusCode == nethttp.StatusRequestTimeout,
+		e.StatusCode == nethttp.StatusTooManyRequests,
+		e.StatusCode >= nethttp.StatusInternalServerError:
+		return true
+	default:
+		return false
+	}
+}
+
 // Option configures a Source.
 type Option func(*Source)
 
@@ -64,6 +101,26 @@ func WithHeader(key, value string) Option {
 	}
 }
 
+// WithBearerToken sends "Authorization: Bearer <token>" on every request.
+// It takes precedence over an Authorization header set with WithHeaders or
+// WithHeader. The Go HTTP client drops the header on redirects to another
+// host, so signed CDN redirects do not receive the token.
+func WithBearerToken(token string) Option {
+	return func(s *Source) {
+		s.authorization = "Bearer " + token
+	}
+}
+
+// WithBasicAuth sends HTTP Basic credentials on every request.
+// It takes precedence over an Authorization header set with WithHeaders or
+// WithHeader. The Go HTTP client drops the header on redirects to another
+// host.
+func WithBasicAuth(username, password string) Option {
+	return func(s *Source) {
+		s.authorization = "Basic " + base64.StdEncoding.EncodeToString([]byte(username+":"+password))
+	}
+}
+
 // WithSourceID overrides the default source identifier used for caching.
 func WithSourceID(id string) Option {
 	return func(s *Source) {
@@ -79,6 +136,68 @@ func WithConditionalHeaders() Option {
 	}
 }
 
+// WithValidator guards reads against the content changing mid-session, for
+// example when a mutable URL is re-pointed at a different blob.
+//
+// The ETag (or, if the ETag is weak or absent, the Last-Modified date) from
+// the first response is sent as If-Range on every range request. If the
+// server answers with the full content, a failed precondition, or a
+// different validator, the read fails with ErrSourceChanged instead of
+// returning bytes from the new content. WithValidator takes precedence over
+// WithConditionalHeaders.
+func WithValidator() Option {
+	return func(s *Source) {
+		s.validate = true
+	}
+}
+
+// WithCompressedTransfer asks the server to compress range responses by
+// sending "Accept-Encoding: zstd, gzip" and decodes them transparently, so
+// archives stored with CompressionNone still travel compressed over the
+// wire. Servers that do not compress range responses are unaffected. An
+// Accept-Encoding header set with WithHeaders or WithHeader takes
+// precedence, and its responses are decoded the same way.
+//
+// A zstd response may use a window of at most 8 MiB or the range length
+// rounded up to a power of two, whichever is larger; responses declaring
+// more are rejected. The decoder counts toward the global decoder memory
+// limit.
+func WithCompressedTransfer() Option {
+	return func(s *Source) {
+		s.compressedTransfer = true
+	}
+}
+
+// WithMaxConcurrentRequests caps the number of range requests in flight at
+// once, regardless of how many goroutines read from the source. Excess
+// reads queue until a request finishes or their context is done. A stream
+// from ReadRange holds its slot until it is closed. Values <= 0 leave
+// requests unlimited.
+func WithMaxConcurrentRequests(n int) Option {
+	return func(s *Source) {
+		if n <= 0 {
+			s.sem = nil
+			return
+		}
+		s.sem = make(chan struct{}, n)
+	}
+}
+
+// WithCoalesceGap merges concurrent ReadAt calls whose ranges are separated
+// by at most gap bytes into a single range request, copying each caller's
+// bytes out of the shared response. Reads queue while the source's requests
+// are busy and are merged when a request slot frees up; by default four
+// merged requests are in flight at a time, and WithMaxConcurrentRequests
+// sets that number instead. A merged request spans at most 8 MiB. The bytes
+// in the gaps are transferred and discarded, trading a little bandwidth for
+// fewer round-trips on high-latency links. Streams from ReadRange are not
+// coalesced. Values <= 0 disable coalescing.
+func WithCoalesceGap(gap int64) Option {
+	return func(s *Source) {
+		s.coalesceGap = max(gap, 0)
+	}
+}
+
 // WithLogger sets the logger for HTTP source operations.
 // If not set, logging is disabled.
 func WithLogger(logger *slog.Logger) Option {
@@ -109,9 +228,18 @@ func NewSource(url string, opts ...Option) (*Source, error) {
 	s.size = size
 	s.etag = etag
 	s.lastModified = lastModified
+	if s.validate {
+		s.validator = rangeValidator(etag, lastModified)
+		if s.validator == "" {
+			s.log().Debug("no validator available, content changes will not be detected", "url", s.url)
+		}
+	}
 	if s.sourceID == "" {
 		s.sourceID = s.defaultSourceID()
 	}
+	if s.coalesceGap > 0 {
+		s.coalescer = newCoalescer(s, s.coalesceGap)
+	}
 	return s, nil
 }
 
@@ -130,6 +258,12 @@ func (s *Source) SourceID() string {
 // beyond the content size, it returns io.EOF. The returned reader must be closed
 // by the caller to release the underlying HTTP connection.
 func (s *Source) ReadRange(off, length int64) (io.ReadCloser, error) {
+	return s.ReadRangeContext(context.Background(), off, length)
+}
+
+// ReadRangeContext is like ReadRange but binds the request to ctx.
+// Canceling ctx aborts the request and any read from the returned reader.
+func (s *Source) ReadRangeContext(ctx context.Context, off, length int64) (io.ReadCloser, error) {
 	if length < 0 {
 		return nil, fmt.Errorf("read range length %d: negative length", length)
 	}
@@ -148,36 +282,66 @@ func (s *Source) ReadRange(off, length int64) (io.ReadCloser, error) {
 
 	s.log().Debug("reading range", "offset", off, "length", length)
 
+	// The request slot is held until the stream is closed, since the
+	// response body keeps the connection busy.
+	if err := s.acquire(ctx); err != nil {
+		return nil, err
+	}
+	rc, err := s.openRange(ctx, off, length)
+	if err != nil {
+		s.release()
+		if errors.Is(err, io.EOF) {
+			return io.NopCloser(bytes.NewReader(nil)), io.EOF
+		}
+		return nil, err
+	}
+	rc.release = s.release
+	return rc, nil
+}
+
+// openRange issues the range request for [off, off+length) and returns the
+// response body as a stream.
+func (s *Source) openRange(ctx context.Context, off, length int64) (*rangeReadCloser, error) {
 	end := off + length - 1
-	resp, err := s.rangeRequest(off, end, true)
+	resp, err := s.rangeRequest(ctx, off, end, true)
 	if err != nil {
 		return nil, err
 	}
 	if resp.StatusCode == nethttp.StatusPreconditionFailed && s.hasConditionalHeaders() {
 		resp.Body.Close()
-		resp, err = s.rangeRequest(off, end, false)
+		resp, err = s.rangeRequest(ctx, off, end, false)
 		if err != nil {
 			return nil, err
 		}
 	}
+	if err := s.checkUnchanged(resp); err != nil {
+		resp.Body.Close()
+		return nil, err
+	}
 
 	switch resp.StatusCode {
 	case nethttp.StatusPartialContent:
 		// ok
 	case nethttp.StatusRequestedRangeNotSatisfiable:
 		resp.Body.Close()
-		return io.NopCloser(bytes.NewReader(nil)), io.EOF
+		return nil, io.EOF
 	case nethttp.StatusOK:
 		resp.Body.Close()
 		return nil, errors.New("range requests not supported")
 	default:
 		resp.Body.Close()
-		return nil, fmt.Errorf("range request failed: %s", resp.Status)
+		return nil, &StatusError{StatusCode: resp.StatusCode, Status: resp.Status}
 	}
 
+	body, err := responseBody(resp, length)
+	if err != nil {
+		resp.Body.Close()
+		return nil, err
+	}
 	return &rangeReadCloser{
-		body:   resp.Body,
-		reader: io.LimitReader(resp.Body, length),
+		raw:     resp.Body,
+		decoder: body,
+		reader:  io.LimitReader(body, length),
 	}, nil
 }
 
@@ -185,6 +349,12 @@ func (s *Source) ReadRange(off, length int64) (io.ReadCloser, error) {
 // It implements [io.ReaderAt]. If fewer bytes are available than requested, it returns
 // the number of bytes read along with io.EOF.
 func (s *Source) ReadAt(p []byte, off int64) (int, error) {
+	return s.ReadAtContext(context.Background(), p, off)
+}
+
+// ReadAtContext is like ReadAt but binds the range request to ctx.
+// Canceling ctx aborts a request in flight.
+func (s *Source) ReadAtContext(ctx context.Context, p []byte, off int64) (int, error) {
 	if len(p) == 0 {
 		return 0, nil
 	}
@@ -195,28 +365,52 @@ func (s *Source) ReadAt(p []byte, off int64) (int, error) {
 		return 0, io.EOF
 	}
 
-	end := off + int64(len(p)) - 1
 	expected := len(p)
-	if end >= s.size {
-		end = s.size - 1
-		expected = int(end - off + 1)
+	if int64(expected) > s.size-off {
+		expected = int(s.size - off)
 	}
 
-	resp, err := s.rangeRequest(off, end, true)
+	var n int
+	var err error
+	if s.coalescer != nil {
+		n, err = s.coalescer.readAt(ctx, p[:expected], off)
+	} else {
+		n, err = s.readFull(ctx, p[:expected], off)
+	}
+	if err != nil {
+		return n, err
+	}
+	if expected < len(p) {
+		return n, io.EOF
+	}
+	return n, nil
+}
+
+// readFull fills p from [off, off+len(p)) with a single range request.
+// The range must lie within the content.
+func (s *Source) readFull(ctx context.Context, p []byte, off int64) (int, error) {
+	if err := s.acquire(ctx); err != nil {
+		return 0, err
+	}
+	defer s.release()
+
+	end := off + int64(len(p)) - 1
+	resp, err := s.rangeRequest(ctx, off, end, true)
 	if err != nil {
 		return 0, err
 	}
 	if resp.StatusCode == nethttp.StatusPreconditionFailed && s.hasConditionalHeaders() {
 		resp.Body.Close()
-		resp, err = s.rangeRequest(off, end, false)
+		resp, err = s.rangeRequest(ctx, off, end, false)
 		if err != nil {
 			return 0, err
 		}
 	}
-	defer func() {
-		_, _ = io.Copy(io.Discard, resp.Body) //nolint:errcheck // best-effort drain for connection reuse
-		_ = resp.Body.Close()
-	}()
+	raw := resp.Body
+	defer func() { _ = drainAndClose(raw) }()
+	if err := s.checkUnchanged(resp); err != nil {
+		return 0, err
+	}
 
 	switch resp.StatusCode {
 	case nethttp.StatusPartialContent:
@@ -226,17 +420,15 @@ func (s *Source) ReadAt(p []byte, off int64) (int, error) {
 	case nethttp.StatusOK:
 		return 0, errors.New("range requests not supported")
 	default:
-		return 0, fmt.Errorf("range request failed: %s", resp.Status)
+		return 0, &StatusError{StatusCode: resp.StatusCode, Status: resp.Status}
 	}
 
-	n, err := io.ReadFull(resp.Body, p[:expected])
+	body, err := responseBody(resp, int64(len(p)))
 	if err != nil {
-		return n, err
-	}
-	if expected < len(p) {
-		return n, io.EOF
+		return 0, err
 	}
-	return n, nil
+	defer body.Close()
+	return io.ReadFull(body, p)
 }
 
 // defaultSourceID builds a source identifier from the URL and available metadata.
@@ -280,7 +472,7 @@ func (s *Source) fetchMetadata() (size int64, etag, lastModified string, err err
 
 // rangeProbe verifies range request support and extracts content size from Content-Range.
 func (s *Source) rangeProbe() (size int64, etag, lastModified string, err error) {
-	req, err := s.newRequest(nethttp.MethodGet, false)
+	req, err := s.newRequest(context.Background(), nethttp.MethodGet, false)
 	if err != nil {
 		return 0, "", "", err
 	}
@@ -290,10 +482,7 @@ func (s *Source) rangeProbe() (size int64, etag, lastModified string, err error)
 	if err != nil {
 		return 0, "", "", err
 	}
-	defer func() {
-		_, _ = io.Copy(io.Discard, resp.Body) //nolint:errcheck // best-effort drain for connection reuse
-		_ = resp.Body.Close()
-	}()
+	defer func() { _ = drainAndClose(resp.Body) }()
 
 	if resp.StatusCode != nethttp.StatusPartialContent {
 		if resp.StatusCode == nethttp.StatusOK {
@@ -316,7 +505,7 @@ func (s *Source) rangeProbe() (size int64, etag, lastModified string, err error)
 
 // doHead performs a HEAD request to retrieve metadata without body content.
 func (s *Source) doHead() (*nethttp.Response, error) {
-	req, err := s.newRequest(nethttp.MethodHead, false)
+	req, err := s.newRequest(context.Background(), nethttp.MethodHead, false)
 	if err != nil {
 		return nil, err
 	}
@@ -324,8 +513,8 @@ func (s *Source) doHead() (*nethttp.Response, error) {
 }
 
 // newRequest creates an HTTP request with configured headers and optional conditional headers.
-func (s *Source) newRequest(method string, withConditions bool) (*nethttp.Request, error) {
-	req, err := nethttp.NewRequestWithContext(context.Background(), method, s.url, nethttp.NoBody)
+func (s *Source) newRequest(ctx context.Context, method string, withConditions bool) (*nethttp.Request, error) {
+	req, err := nethttp.NewRequestWithContext(ctx, method, s.url, nethttp.NoBody)
 	if err != nil {
 		return nil, err
 	}
@@ -334,10 +523,19 @@ func (s *Source) newRequest(method string, withConditions bool) (*nethttp.Reques
 			req.Header.Add(key, value)
 		}
 	}
+	if s.authorization != "" {
+		req.Header.Set("Authorization", s.authorization)
+	}
 	if req.Header.Get("Accept-Encoding") == "" {
-		req.Header.Set("Accept-Encoding", "identity")
+		if method == nethttp.MethodGet && s.compressedTransfer {
+			req.Header.Set("Accept-Encoding", compressedAcceptEncoding)
+		} else {
+			req.Header.Set("Accept-Encoding", "identity")
+		}
 	}
-	if method == nethttp.MethodGet && withConditions && s.useConditionalHeaders {
+	if method == nethttp.MethodGet && withConditions && s.validator != "" {
+		req.Header.Set("If-Range", s.validator)
+	} else if method == nethttp.MethodGet && withConditions && s.useConditionalHeaders {
 		if s.etag != "" && req.Header.Get("If-Match") == "" {
 			req.Header.Set("If-Match", s.etag)
 		}
@@ -349,8 +547,8 @@ func (s *Source) newRequest(method string, withConditions bool) (*nethttp.Reques
 }
 
 // rangeRequest performs a GET request for the specified byte range.
-func (s *Source) rangeRequest(off, end int64, withConditions bool) (*nethttp.Response, error) {
-	req, err := s.newRequest(nethttp.MethodGet, withConditions)
+func (s *Source) rangeRequest(ctx context.Context, off, end int64, withConditions bool) (*nethttp.Response, error) {
+	req, err := s.newRequest(ctx, nethttp.MethodGet, withConditions)
 	if err != nil {
 		return nil, err
 	}
@@ -360,17 +558,86 @@ func (s *Source) rangeRequest(off, end int64, withConditions bool) (*nethttp.Res
 
 // hasConditionalHeaders reports whether conditional headers are enabled and available.
 func (s *Source) hasConditionalHeaders() bool {
-	if !s.useConditionalHeaders {
+	if !s.useConditionalHeaders || s.validator != "" {
 		return false
 	}
 	return s.etag != "" || s.lastModified != ""
 }
 
+// checkUnchanged returns ErrSourceChanged if resp shows that the content no
+// longer matches the validator captured when the source was created.
+func (s *Source) checkUnchanged(resp *nethttp.Response) error {
+	if s.validator == "" {
+		return nil
+	}
+	switch resp.StatusCode {
+	case nethttp.StatusOK, nethttp.StatusPreconditionFailed:
+		// A server honoring If-Range sends the full new content when the
+		// validator no longer matches.
+		return fmt.Errorf("%w: %s", ErrSourceChanged, resp.Status)
+	case nethttp.StatusPartialContent:
+		// Servers that ignore If-Range still report the current validator.
+		if etag := resp.Header.Get("ETag"); s.etag != "" && etag != "" && etag != s.etag {
+			return fmt.Errorf("%w: etag %s, want %s", ErrSourceChanged, etag, s.etag)
+		}
+		if lm := resp.Header.Get("Last-Modified"); s.etag == "" && lm != "" && lm != s.lastModified {
+			return fmt.Errorf("%w: last modified %s, want %s", ErrSourceChanged, lm, s.lastModified)
+		}
+	}
+	return nil
+}
+
+// rangeValidator returns the If-Range value for etag and lastModified.
+// If-Range requires a strong validator, so weak ETags fall back to the
+// modification date.
+func rangeValidator(etag, lastModified string) string {
+	if etag != "" && !strings.HasPrefix(etag, "W/") {
+		return etag
+	}
+	return lastModified
+}
+
+// acquire takes a request slot when WithMaxConcurrentRequests is set,
+// waiting until one is free or ctx is done.
+func (s *Source) acquire(ctx context.Context) error {
+	if s.sem == nil {
+		return nil
+	}
+	select {
+	case s.sem <- struct{}{}:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// release returns a request slot taken by acquire.
+func (s *Source) release() {
+	if s.sem != nil {
+		<-s.sem
+	}
+}
+
+// maxDrain bounds the bytes read from a response body before it is closed.
+// A short remainder is read so the connection can be reused; a longer one
+// is cheaper to abandon along with the connection.
+const maxDrain = 256 << 10
+
+// drainAndClose discards up to maxDrain bytes of raw, an undecoded response
+// body, and closes it.
+func drainAndClose(raw io.ReadCloser) error {
+	_, _ = io.CopyN(io.Discard, raw, maxDrain) //nolint:errcheck // best-effort drain for connection reuse
+	return raw.Close()
+}
+
 // rangeReadCloser wraps an HTTP response body with a limit reader.
 // It drains the body on close to enable connection reuse.
 type rangeReadCloser struct {
-	body   io.ReadCloser
-	reader io.Reader
+	raw         io.ReadCloser // undecoded response body
+	decoder     io.Closer     // releases the content decoder, if any
+	reader      io.Reader
+	release     func()
+	releaseOnce sync.Once
 }
 
 // Read reads from the underlying limit reader.
@@ -378,10 +645,15 @@ func (r *rangeReadCloser) Read(p []byte) (int, error) {
 	return r.reader.Read(p)
 }
 
-// Close drains and closes the underlying response body.
+// Close releases the decoder, drains and closes the underlying response
+// body, and frees its request slot.
 func (r *rangeReadCloser) Close() error {
-	_, _ = io.Copy(io.Discard, r.body) //nolint:errcheck // best-effort drain for connection reuse
-	return r.body.Close()
+	_ = r.decoder.Close()
+	err := drainAndClose(r.raw)
+	if r.release != nil {
+		r.releaseOnce.Do(r.release)
+	}
+	return err
 }
 
 // parseContentRange extracts the total size from a Content-Range header value.
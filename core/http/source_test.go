@@ -2,14 +2,23 @@ package http_test
 
 import (
 	"bytes"
+	"compress/gzip"
+	"context"
+	"crypto/rand"
+	"errors"
+	"fmt"
 	"io"
 	nethttp "net/http"
 	"net/http/httptest"
 	"strconv"
+	"strings"
+	"sync"
 	"sync/atomic"
 	"testing"
 	"time"
 
+	"github.com/klauspost/compress/zstd"
+
 	blobhttp "github.com/meigma/blob/core/http"
 )
 
@@ -94,6 +103,42 @@ func TestNewSource_RangeUnsupported(t *testing.T) {
 	}
 }
 
+func TestSource_ReadAt_StatusError(t *testing.T) {
+	t.Parallel()
+
+	data := []byte("unavailable")
+	var fail atomic.Bool
+	server := httptest.NewServer(nethttp.HandlerFunc(func(w nethttp.ResponseWriter, r *nethttp.Request) {
+		if fail.Load() {
+			w.WriteHeader(nethttp.StatusServiceUnavailable)
+			return
+		}
+		nethttp.ServeContent(w, r, "data", time.Time{}, bytes.NewReader(data))
+	}))
+	t.Cleanup(server.Close)
+
+	src, err := blobhttp.NewSource(server.URL)
+	if err != nil {
+		t.Fatalf("NewSource() error = %v", err)
+	}
+	fail.Store(true)
+
+	_, err = src.ReadAt(make([]byte, 4), 0)
+	var statusErr *blobhttp.StatusError
+	if !errors.As(err, &statusErr) {
+		t.Fatalf("ReadAt() error = %v, want *StatusError", err)
+	}
+	if statusErr.StatusCode != nethttp.StatusServiceUnavailable {
+		t.Fatalf("StatusCode = %d, want %d", statusErr.StatusCode, nethttp.StatusServiceUnavailable)
+	}
+	if !statusErr.Temporary() {
+		t.Fatal("Temporary() = false, want true for 503")
+	}
+	if (&blobhttp.StatusError{StatusCode: nethttp.StatusForbidden}).Temporary() {
+		t.Fatal("Temporary() = true, want false for 403")
+	}
+}
+
 func TestSource_ReadAt_RetriesWithoutIfMatchOn412(t *testing.T) {
 	t.Parallel()
 
@@ -146,3 +191,810 @@ func TestSource_ReadAt_RetriesWithoutIfMatchOn412(t *testing.T) {
 		t.Fatalf("expected one range retry without If-Match, got %d", withoutIfMatchRange)
 	}
 }
+
+func TestSource_ReadAtContext_CancelAbortsRequest(t *testing.T) {
+	t.Parallel()
+
+	data := []byte("hello world")
+	started := make(chan struct{}, 1)
+	server := httptest.NewServer(nethttp.HandlerFunc(func(w nethttp.ResponseWriter, r *nethttp.Request) {
+		if r.Method == nethttp.MethodGet && r.Header.Get("Range") != "bytes=0-0" {
+			started <- struct{}{}
+			<-r.Context().Done()
+			return
+		}
+		nethttp.ServeContent(w, r, "data", time.Time{}, bytes.NewReader(data))
+	}))
+	t.Cleanup(server.Close)
+
+	src, err := blobhttp.NewSource(server.URL)
+	if err != nil {
+		t.Fatalf("NewSource() error = %v", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	go func() {
+		<-started
+		cancel()
+	}()
+
+	_, err = src.ReadAtContext(ctx, make([]byte, 5), 6)
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("ReadAtContext() error = %v, want %v", err, context.Canceled)
+	}
+}
+
+// mutableServer serves content whose ETag can change between requests, like
+// a URL that is re-pointed at a different blob.
+type mutableServer struct {
+	mu           sync.Mutex
+	data         []byte
+	etag         string
+	ifRange      []string
+	honorIfRange bool
+}
+
+func (m *mutableServer) set(data []byte, etag string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.data = data
+	m.etag = etag
+}
+
+func (m *mutableServer) ServeHTTP(w nethttp.ResponseWriter, r *nethttp.Request) {
+	m.mu.Lock()
+	data, etag := m.data, m.etag
+	if v := r.Header.Get("If-Range"); v != "" {
+		m.ifRange = append(m.ifRange, v)
+	}
+	m.mu.Unlock()
+	if !m.honorIfRange {
+		r.Header.Del("If-Range")
+	}
+	w.Header().Set("ETag", etag)
+	nethttp.ServeContent(w, r, "data", time.Time{}, bytes.NewReader(data))
+}
+
+func TestSource_WithValidator(t *testing.T) {
+	t.Parallel()
+
+	for _, tt := range []struct {
+		name         string
+		honorIfRange bool
+	}{
+		{name: "server honors If-Range", honorIfRange: true},
+		{name: "server ignores If-Range", honorIfRange: false},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			m := &mutableServer{data: []byte("hello world"), etag: `"v1"`, honorIfRange: tt.honorIfRange}
+			server := httptest.NewServer(m)
+			t.Cleanup(server.Close)
+
+			src, err := blobhttp.NewSource(server.URL, blobhttp.WithValidator())
+			if err != nil {
+				t.Fatalf("NewSource() error = %v", err)
+			}
+
+			buf := make([]byte, 5)
+			if _, err := src.ReadAt(buf, 0); err != nil {
+				t.Fatalf("ReadAt() before change error = %v", err)
+			}
+			if string(buf) != "hello" {
+				t.Fatalf("ReadAt() = %q, want %q", buf, "hello")
+			}
+
+			m.set([]byte("HELLO WORLD"), `"v2"`)
+
+			n, err := src.ReadAt(buf, 6)
+			if !errors.Is(err, blobhttp.ErrSourceChanged) {
+				t.Fatalf("ReadAt() after change error = %v, want ErrSourceChanged", err)
+			}
+			if n != 0 {
+				t.Fatalf("ReadAt() after change returned %d bytes", n)
+			}
+			if _, err := src.ReadRange(0, 5); !errors.Is(err, blobhttp.ErrSourceChanged) {
+				t.Fatalf("ReadRange() after change error = %v, want ErrSourceChanged", err)
+			}
+
+			m.mu.Lock()
+			defer m.mu.Unlock()
+			for _, v := range m.ifRange {
+				if v != `"v1"` {
+					t.Fatalf("If-Range = %q, want the original ETag", v)
+				}
+			}
+			if len(m.ifRange) == 0 {
+				t.Fatal("no range request carried If-Range")
+			}
+		})
+	}
+}
+
+func TestSource_WithValidator_WeakETag(t *testing.T) {
+	t.Parallel()
+
+	modified := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	var ifRange atomic.Value
+	server := httptest.NewServer(nethttp.HandlerFunc(func(w nethttp.ResponseWriter, r *nethttp.Request) {
+		if v := r.Header.Get("If-Range"); v != "" {
+			ifRange.Store(v)
+		}
+		w.Header().Set("ETag", `W/"weak"`)
+		nethttp.ServeContent(w, r, "data", modified, bytes.NewReader([]byte("hello world")))
+	}))
+	t.Cleanup(server.Close)
+
+	src, err := blobhttp.NewSource(server.URL, blobhttp.WithValidator())
+	if err != nil {
+		t.Fatalf("NewSource() error = %v", err)
+	}
+	buf := make([]byte, 5)
+	if _, err := src.ReadAt(buf, 6); err != nil {
+		t.Fatalf("ReadAt() error = %v", err)
+	}
+	if got, _ := ifRange.Load().(string); got != modified.Format(nethttp.TimeFormat) {
+		t.Fatalf("If-Range = %q, want Last-Modified date", got)
+	}
+}
+
+func TestSource_Authorization(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name string
+		opts []blobhttp.Option
+		want string
+	}{
+		{
+			name: "bearer token",
+			opts: []blobhttp.Option{blobhttp.WithBearerToken("s3cr3t")},
+			want: "Bearer s3cr3t",
+		},
+		{
+			name: "basic auth",
+			opts: []blobhttp.Option{blobhttp.WithBasicAuth("user", "pass")},
+			want: "Basic dXNlcjpwYXNz",
+		},
+		{
+			name: "auth option overrides header",
+			opts: []blobhttp.Option{
+				blobhttp.WithBearerToken("s3cr3t"),
+				blobhttp.WithHeader("Authorization", "Bearer stale"),
+			},
+			want: "Bearer s3cr3t",
+		},
+		{
+			name: "custom header",
+			opts: []blobhttp.Option{blobhttp.WithHeader("Authorization", "Token abc")},
+			want: "Token abc",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			var mu sync.Mutex
+			var rangeAuth []string
+			server := httptest.NewServer(nethttp.HandlerFunc(func(w nethttp.ResponseWriter, r *nethttp.Request) {
+				if r.Header.Get("Authorization") != tt.want {
+					w.WriteHeader(nethttp.StatusUnauthorized)
+					return
+				}
+				if r.Method == nethttp.MethodGet && r.Header.Get("Range") != "bytes=0-0" {
+					mu.Lock()
+					rangeAuth = append(rangeAuth, r.Header.Get("Authorization"))
+					mu.Unlock()
+				}
+				nethttp.ServeContent(w, r, "data", time.Time{}, bytes.NewReader([]byte("hello world")))
+			}))
+			t.Cleanup(server.Close)
+
+			src, err := blobhttp.NewSource(server.URL, tt.opts...)
+			if err != nil {
+				t.Fatalf("NewSource() error = %v", err)
+			}
+			buf := make([]byte, 5)
+			if _, err := src.ReadAt(buf, 6); err != nil {
+				t.Fatalf("ReadAt() error = %v", err)
+			}
+			rc, err := src.ReadRange(0, 5)
+			if err != nil {
+				t.Fatalf("ReadRange() error = %v", err)
+			}
+			rc.Close()
+
+			mu.Lock()
+			defer mu.Unlock()
+			if len(rangeAuth) != 2 {
+				t.Fatalf("recorded %d authorized range requests, want 2", len(rangeAuth))
+			}
+		})
+	}
+}
+
+func TestSource_WithMaxConcurrentRequests(t *testing.T) {
+	t.Parallel()
+
+	const limit = 3
+	data := bytes.Repeat([]byte("0123456789"), 100)
+	var inFlight, maxInFlight atomic.Int32
+	server := httptest.NewServer(nethttp.HandlerFunc(func(w nethttp.ResponseWriter, r *nethttp.Request) {
+		if r.Method == nethttp.MethodGet && r.Header.Get("Range") != "bytes=0-0" {
+			n := inFlight.Add(1)
+			defer inFlight.Add(-1)
+			for {
+				peak := maxInFlight.Load()
+				if n <= peak || maxInFlight.CompareAndSwap(peak, n) {
+					break
+				}
+			}
+			time.Sleep(10 * time.Millisecond)
+		}
+		nethttp.ServeContent(w, r, "data", time.Time{}, bytes.NewReader(data))
+	}))
+	t.Cleanup(server.Close)
+
+	src, err := blobhttp.NewSource(server.URL, blobhttp.WithMaxConcurrentRequests(limit))
+	if err != nil {
+		t.Fatalf("NewSource() error = %v", err)
+	}
+
+	var wg sync.WaitGroup
+	errs := make(chan error, 32)
+	for i := range 32 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			off := int64(i * 10)
+			if i%2 == 0 {
+				buf := make([]byte, 10)
+				if _, err := src.ReadAt(buf, off); err != nil {
+					errs <- err
+				}
+				return
+			}
+			rc, err := src.ReadRange(off, 10)
+			if err != nil {
+				errs <- err
+				return
+			}
+			defer rc.Close()
+			if _, err := io.ReadAll(rc); err != nil {
+				errs <- err
+			}
+		}()
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		t.Fatalf("read error = %v", err)
+	}
+
+	if peak := maxInFlight.Load(); peak > limit {
+		t.Fatalf("max in-flight requests = %d, want <= %d", peak, limit)
+	} else if peak < 2 {
+		t.Fatalf("max in-flight requests = %d, reads did not run concurrently", peak)
+	}
+}
+
+func TestSource_WithMaxConcurrentRequests_ContextCanceledWhileQueued(t *testing.T) {
+	t.Parallel()
+
+	server := httptest.NewServer(nethttp.HandlerFunc(func(w nethttp.ResponseWriter, r *nethttp.Request) {
+		nethttp.ServeContent(w, r, "data", time.Time{}, bytes.NewReader([]byte("hello world")))
+	}))
+	t.Cleanup(server.Close)
+
+	src, err := blobhttp.NewSource(server.URL, blobhttp.WithMaxConcurrentRequests(1))
+	if err != nil {
+		t.Fatalf("NewSource() error = %v", err)
+	}
+
+	// An open stream holds the only slot.
+	rc, err := src.ReadRange(0, 5)
+	if err != nil {
+		t.Fatalf("ReadRange() error = %v", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if _, err := src.ReadAtContext(ctx, make([]byte, 5), 6); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("ReadAtContext() error = %v, want context.DeadlineExceeded", err)
+	}
+
+	rc.Close()
+	buf := make([]byte, 5)
+	if _, err := src.ReadAt(buf, 6); err != nil {
+		t.Fatalf("ReadAt() after Close error = %v", err)
+	}
+	if string(buf) != "world" {
+		t.Fatalf("ReadAt() = %q, want %q", buf, "world")
+	}
+}
+
+func TestSource_WithCoalesceGap(t *testing.T) {
+	t.Parallel()
+
+	const reads = 16
+	data := bytes.Repeat([]byte("0123456789abcdef"), 64)
+	var requests atomic.Int32
+	server := httptest.NewServer(nethttp.HandlerFunc(func(w nethttp.ResponseWriter, r *nethttp.Request) {
+		if r.Method == nethttp.MethodGet && r.Header.Get("Range") != "bytes=0-0" {
+			requests.Add(1)
+		}
+		nethttp.ServeContent(w, r, "data", time.Time{}, bytes.NewReader(data))
+	}))
+	t.Cleanup(server.Close)
+
+	src, err := blobhttp.NewSource(server.URL,
+		blobhttp.WithMaxConcurrentRequests(1),
+		blobhttp.WithCoalesceGap(8),
+	)
+	if err != nil {
+		t.Fatalf("NewSource() error = %v", err)
+	}
+
+	// An open stream holds the only slot so the reads below queue up.
+	rc, err := src.ReadRange(0, 1)
+	if err != nil {
+		t.Fatalf("ReadRange() error = %v", err)
+	}
+
+	// Closely packed 8-byte reads with 8-byte gaps between them.
+	var wg sync.WaitGroup
+	errs := make(chan error, reads)
+	for i := range reads {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			off := int64(i * 16)
+			buf := make([]byte, 8)
+			if _, err := src.ReadAt(buf, off); err != nil {
+				errs <- err
+				return
+			}
+			if !bytes.Equal(buf, data[off:off+8]) {
+				errs <- errors.New("ReadAt() returned wrong bytes at offset " + strconv.FormatInt(off, 10))
+			}
+		}()
+	}
+	time.Sleep(50 * time.Millisecond)
+	rc.Close()
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		t.Fatalf("read error = %v", err)
+	}
+
+	// One request for the stream plus at most a few merged reads.
+	if got := requests.Load(); got > 4 {
+		t.Fatalf("range requests = %d, want <= 4 for %d coalesced reads", got, reads)
+	}
+}
+
+func TestSource_WithCoalesceGap_MaxSpan(t *testing.T) {
+	t.Parallel()
+
+	const (
+		reads   = 16
+		readLen = 1 << 20
+	)
+	data := bytes.Repeat([]byte("0123456789abcdef"), reads*readLen/16)
+	var widest atomic.Int64
+	server := httptest.NewServer(nethttp.HandlerFunc(func(w nethttp.ResponseWriter, r *nethttp.Request) {
+		var start, end int64
+		if _, err := fmt.Sscanf(r.Header.Get("Range"), "bytes=%d-%d", &start, &end); err == nil {
+			for {
+				cur := widest.Load()
+				if end-start+1 <= cur || widest.CompareAndSwap(cur, end-start+1) {
+					break
+				}
+			}
+		}
+		nethttp.ServeContent(w, r, "data", time.Time{}, bytes.NewReader(data))
+	}))
+	t.Cleanup(server.Close)
+
+	src, err := blobhttp.NewSource(server.URL,
+		blobhttp.WithMaxConcurrentRequests(1),
+		blobhttp.WithCoalesceGap(1),
+	)
+	if err != nil {
+		t.Fatalf("NewSource() error = %v", err)
+	}
+
+	// An open stream holds the only slot so the adjacent reads below queue up.
+	rc, err := src.ReadRange(0, 1)
+	if err != nil {
+		t.Fatalf("ReadRange() error = %v", err)
+	}
+	var wg sync.WaitGroup
+	errs := make(chan error, reads)
+	for i := range reads {
+		wg.Go(func() {
+			off := int64(i * readLen)
+			buf := make([]byte, readLen)
+			if _, err := src.ReadAt(buf, off); err != nil {
+				errs <- err
+				return
+			}
+			if !bytes.Equal(buf, data[off:off+readLen]) {
+				errs <- errors.New("ReadAt() returned wrong bytes at offset " + strconv.FormatInt(off, 10))
+			}
+		})
+	}
+	time.Sleep(50 * time.Millisecond)
+	rc.Close()
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		t.Fatalf("read error = %v", err)
+	}
+
+	if got := widest.Load(); got > 8<<20 {
+		t.Fatalf("widest range request = %d bytes, want <= %d", got, 8<<20)
+	}
+}
+
+func TestSource_WithCoalesceGap_BeyondGap(t *testing.T) {
+	t.Parallel()
+
+	data := bytes.Repeat([]byte("0123456789"), 10)
+	server := httptest.NewServer(nethttp.HandlerFunc(func(w nethttp.ResponseWriter, r *nethttp.Request) {
+		nethttp.ServeContent(w, r, "data", time.Time{}, bytes.NewReader(data))
+	}))
+	t.Cleanup(server.Close)
+
+	src, err := blobhttp.NewSource(server.URL, blobhttp.WithCoalesceGap(4))
+	if err != nil {
+		t.Fatalf("NewSource() error = %v", err)
+	}
+
+	var wg sync.WaitGroup
+	errs := make(chan error, 10)
+	for i := range 10 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			off := int64(i * 10)
+			buf := make([]byte, 3)
+			if _, err := src.ReadAt(buf, off); err != nil {
+				errs <- err
+				return
+			}
+			if !bytes.Equal(buf, data[off:off+3]) {
+				errs <- errors.New("ReadAt() returned wrong bytes at offset " + strconv.FormatInt(off, 10))
+			}
+		}()
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		t.Fatalf("read error = %v", err)
+	}
+
+	buf := make([]byte, 8)
+	n, err := src.ReadAt(buf, 96)
+	if !errors.Is(err, io.EOF) || n != 4 || string(buf[:n]) != "6789" {
+		t.Fatalf("ReadAt() past end = %d, %v, %q; want 4, io.EOF, %q", n, err, buf[:n], "6789")
+	}
+}
+
+func TestSource_WithCoalesceGap_ContextCanceledWhileQueued(t *testing.T) {
+	t.Parallel()
+
+	server := httptest.NewServer(nethttp.HandlerFunc(func(w nethttp.ResponseWriter, r *nethttp.Request) {
+		nethttp.ServeContent(w, r, "data", time.Time{}, bytes.NewReader([]byte("hello world")))
+	}))
+	t.Cleanup(server.Close)
+
+	src, err := blobhttp.NewSource(server.URL,
+		blobhttp.WithMaxConcurrentRequests(1),
+		blobhttp.WithCoalesceGap(16),
+	)
+	if err != nil {
+		t.Fatalf("NewSource() error = %v", err)
+	}
+
+	rc, err := src.ReadRange(0, 5)
+	if err != nil {
+		t.Fatalf("ReadRange() error = %v", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if _, err := src.ReadAtContext(ctx, make([]byte, 5), 6); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("ReadAtContext() error = %v, want context.DeadlineExceeded", err)
+	}
+
+	rc.Close()
+	buf := make([]byte, 5)
+	if _, err := src.ReadAt(buf, 6); err != nil {
+		t.Fatalf("ReadAt() after Close error = %v", err)
+	}
+	if string(buf) != "world" {
+		t.Fatalf("ReadAt() = %q, want %q", buf, "world")
+	}
+}
+
+// encodingServer serves ranges of data, compressing each range response
+// with the first of its encodings the client accepts.
+func encodingServer(t *testing.T, data []byte) *httptest.Server {
+	t.Helper()
+	server := httptest.NewServer(nethttp.HandlerFunc(func(w nethttp.ResponseWriter, r *nethttp.Request) {
+		accept := r.Header.Get("Accept-Encoding")
+		rangeHeader := r.Header.Get("Range")
+		if !strings.Contains(accept, "gzip") && !strings.Contains(accept, "zstd") || rangeHeader == "" {
+			nethttp.ServeContent(w, r, "data", time.Time{}, bytes.NewReader(data))
+			return
+		}
+		var start, end int64
+		if _, err := fmt.Sscanf(rangeHeader, "bytes=%d-%d", &start, &end); err != nil {
+			nethttp.Error(w, err.Error(), nethttp.StatusBadRequest)
+			return
+		}
+		end = min(end, int64(len(data))-1)
+
+		var encoded bytes.Buffer
+		if strings.Contains(accept, "zstd") {
+			enc, err := zstd.NewWriter(&encoded)
+			if err != nil {
+				nethttp.Error(w, err.Error(), nethttp.StatusInternalServerError)
+				return
+			}
+			_, _ = enc.Write(data[start : end+1])
+			_ = enc.Close()
+			w.Header().Set("Content-Encoding", "zstd")
+		} else {
+			gz := gzip.NewWriter(&encoded)
+			_, _ = gz.Write(data[start : end+1])
+			_ = gz.Close()
+			w.Header().Set("Content-Encoding", "gzip")
+		}
+		w.Header().Set("Content-Range", fmt.Sprintf("bytes %d-%d/%d", start, end, len(data)))
+		w.Header().Set("Content-Length", strconv.Itoa(encoded.Len()))
+		w.WriteHeader(nethttp.StatusPartialContent)
+		_, _ = w.Write(encoded.Bytes())
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+// countingBodyTransport counts response body bytes as received on the wire.
+type countingBodyTransport struct {
+	base  nethttp.RoundTripper
+	bytes atomic.Int64
+}
+
+func (c *countingBodyTransport) RoundTrip(req *nethttp.Request) (*nethttp.Response, error) {
+	resp, err := c.base.RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+	resp.Body = &countingBody{ReadCloser: resp.Body, n: &c.bytes}
+	return resp, nil
+}
+
+type countingBody struct {
+	io.ReadCloser
+	n *atomic.Int64
+}
+
+func (b *countingBody) Read(p []byte) (int, error) {
+	n, err := b.ReadCloser.Read(p)
+	b.n.Add(int64(n))
+	return n, err
+}
+
+func TestSource_WithCompressedTransfer(t *testing.T) {
+	t.Parallel()
+
+	data := bytes.Repeat([]byte("compressible range data "), 2048)
+	tests := []struct {
+		name   string
+		header string
+	}{
+		{name: "zstd"},
+		{name: "gzip", header: "gzip"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			server := encodingServer(t, data)
+			transport := &countingBodyTransport{base: nethttp.DefaultTransport}
+			opts := []blobhttp.Option{
+				blobhttp.WithClient(&nethttp.Client{Transport: transport}),
+				blobhttp.WithCompressedTransfer(),
+			}
+			if tt.header != "" {
+				opts = append(opts, blobhttp.WithHeader("Accept-Encoding", tt.header))
+			}
+			src, err := blobhttp.NewSource(server.URL, opts...)
+			if err != nil {
+				t.Fatalf("NewSource() error = %v", err)
+			}
+			if src.Size() != int64(len(data)) {
+				t.Fatalf("Size() = %d, want %d", src.Size(), len(data))
+			}
+
+			transport.bytes.Store(0)
+			const off, length = 1000, 32 << 10
+			buf := make([]byte, length)
+			n, err := src.ReadAt(buf, off)
+			if err != nil {
+				t.Fatalf("ReadAt() error = %v", err)
+			}
+			if n != length || !bytes.Equal(buf, data[off:off+length]) {
+				t.Fatalf("ReadAt() returned %d bytes with wrong content", n)
+			}
+			if wire := transport.bytes.Load(); wire >= length {
+				t.Fatalf("wire bytes = %d, want fewer than %d decoded bytes", wire, length)
+			}
+
+			rc, err := src.ReadRange(off, length)
+			if err != nil {
+				t.Fatalf("ReadRange() error = %v", err)
+			}
+			got, err := io.ReadAll(rc)
+			rc.Close()
+			if err != nil {
+				t.Fatalf("ReadRange() read error = %v", err)
+			}
+			if !bytes.Equal(got, data[off:off+length]) {
+				t.Fatalf("ReadRange() returned %d bytes with wrong content", len(got))
+			}
+
+			// A short read at the end still reports io.EOF.
+			tail := make([]byte, 64)
+			n, err = src.ReadAt(tail, int64(len(data)-10))
+			if !errors.Is(err, io.EOF) || n != 10 || !bytes.Equal(tail[:n], data[len(data)-10:]) {
+				t.Fatalf("ReadAt() at end = %d, %v; want 10, io.EOF", n, err)
+			}
+		})
+	}
+}
+
+func TestSource_WithCompressedTransfer_WindowLimit(t *testing.T) {
+	t.Parallel()
+
+	// A response for a few bytes that declares a 64 MiB window is refused
+	// rather than given a decoder sized for it.
+	server := httptest.NewServer(nethttp.HandlerFunc(func(w nethttp.ResponseWriter, r *nethttp.Request) {
+		if r.Header.Get("Range") != "bytes=6-10" {
+			nethttp.ServeContent(w, r, "data", time.Time{}, strings.NewReader("hello world"))
+			return
+		}
+		// Magic, a descriptor with no content size, a 64 MiB window, and
+		// one raw block holding the range.
+		frame := []byte{0x28, 0xb5, 0x2f, 0xfd, 0x00, 16 << 3, 5<<3 | 1, 0, 0}
+		frame = append(frame, "world"...)
+		w.Header().Set("Content-Encoding", "zstd")
+		w.Header().Set("Content-Range", "bytes 6-10/11")
+		w.WriteHeader(nethttp.StatusPartialContent)
+		_, _ = w.Write(frame)
+	}))
+	t.Cleanup(server.Close)
+
+	src, err := blobhttp.NewSource(server.URL, blobhttp.WithCompressedTransfer())
+	if err != nil {
+		t.Fatalf("NewSource() error = %v", err)
+	}
+	if _, err := src.ReadAt(make([]byte, 5), 6); err == nil {
+		t.Fatal("ReadAt() error = nil, want window size error")
+	}
+}
+
+func TestSource_WithCompressedTransfer_IdentityRanges(t *testing.T) {
+	t.Parallel()
+
+	// Servers that disable compression for ranges answer with raw bytes.
+	data := []byte("hello world")
+	var accept atomic.Value
+	server := httptest.NewServer(nethttp.HandlerFunc(func(w nethttp.ResponseWriter, r *nethttp.Request) {
+		accept.Store(r.Header.Get("Accept-Encoding"))
+		nethttp.ServeContent(w, r, "data", time.Time{}, bytes.NewReader(data))
+	}))
+	t.Cleanup(server.Close)
+
+	src, err := blobhttp.NewSource(server.URL, blobhttp.WithCompressedTransfer())
+	if err != nil {
+		t.Fatalf("NewSource() error = %v", err)
+	}
+	buf := make([]byte, 5)
+	if _, err := src.ReadAt(buf, 6); err != nil {
+		t.Fatalf("ReadAt() error = %v", err)
+	}
+	if string(buf) != "world" {
+		t.Fatalf("ReadAt() = %q, want %q", buf, "world")
+	}
+	if got := accept.Load(); got != "zstd, gzip" {
+		t.Fatalf("Accept-Encoding = %q, want %q", got, "zstd, gzip")
+	}
+}
+
+func TestSource_UnsupportedContentEncoding(t *testing.T) {
+	t.Parallel()
+
+	data := []byte("hello world")
+	server := httptest.NewServer(nethttp.HandlerFunc(func(w nethttp.ResponseWriter, r *nethttp.Request) {
+		if r.Header.Get("Range") != "bytes=0-0" {
+			w.Header().Set("Content-Encoding", "br")
+		}
+		nethttp.ServeContent(w, r, "data", time.Time{}, bytes.NewReader(data))
+	}))
+	t.Cleanup(server.Close)
+
+	src, err := blobhttp.NewSource(server.URL, blobhttp.WithCompressedTransfer())
+	if err != nil {
+		t.Fatalf("NewSource() error = %v", err)
+	}
+	if _, err := src.ReadAt(make([]byte, 5), 6); err == nil {
+		t.Fatal("ReadAt() error = nil, want unsupported encoding error")
+	}
+}
+
+func TestSource_DrainIsBounded(t *testing.T) {
+	t.Parallel()
+
+	// Range responses carry far more encoded data than the range they
+	// announce. Closing them must not read the rest of the stream.
+	data := []byte("hello world")
+	padding := make([]byte, 4<<20)
+	if _, err := rand.Read(padding); err != nil {
+		t.Fatal(err)
+	}
+	var encoded bytes.Buffer
+	gz, err := gzip.NewWriterLevel(&encoded, gzip.NoCompression)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, _ = gz.Write(data[6:])
+	_, _ = gz.Write(padding)
+	_ = gz.Close()
+
+	server := httptest.NewServer(nethttp.HandlerFunc(func(w nethttp.ResponseWriter, r *nethttp.Request) {
+		if r.Header.Get("Range") != "bytes=6-10" {
+			nethttp.ServeContent(w, r, "data", time.Time{}, bytes.NewReader(data))
+			return
+		}
+		w.Header().Set("Content-Encoding", "gzip")
+		w.Header().Set("Content-Range", fmt.Sprintf("bytes 6-10/%d", len(data)))
+		w.WriteHeader(nethttp.StatusPartialContent)
+		_, _ = w.Write(encoded.Bytes())
+	}))
+	t.Cleanup(server.Close)
+
+	transport := &countingBodyTransport{base: nethttp.DefaultTransport}
+	src, err := blobhttp.NewSource(server.URL,
+		blobhttp.WithClient(&nethttp.Client{Transport: transport}),
+		blobhttp.WithCompressedTransfer(),
+	)
+	if err != nil {
+		t.Fatalf("NewSource() error = %v", err)
+	}
+
+	transport.bytes.Store(0)
+	buf := make([]byte, 5)
+	if _, err := src.ReadAt(buf, 6); err != nil {
+		t.Fatalf("ReadAt() error = %v", err)
+	}
+	if string(buf) != "world" {
+		t.Fatalf("ReadAt() = %q, want %q", buf, "world")
+	}
+	rc, err := src.ReadRange(6, 5)
+	if err != nil {
+		t.Fatalf("ReadRange() error = %v", err)
+	}
+	got, err := io.ReadAll(rc)
+	rc.Close()
+	if err != nil || string(got) != "world" {
+		t.Fatalf("ReadRange() = %q, %v; want %q", got, err, "world")
+	}
+	if wire := transport.bytes.Load(); wire >= int64(len(padding)) {
+		t.Fatalf("wire bytes = %d, want fewer than the %d byte stream", wire, len(padding))
+	}
+}
@@ -2,6 +2,7 @@ package blob
 
 import (
 	"iter"
+	"math"
 
 	"github.com/meigma/blob/core/internal/blobtype"
 	"github.com/meigma/blob/core/internal/index"
@@ -24,7 +25,7 @@ type IndexView struct {
 // The provided data is retained by the IndexView; callers must not modify it
 // after calling NewIndexView.
 func NewIndexView(indexData []byte) (*IndexView, error) {
-	idx, err := index.Load(indexData)
+	idx, err := index.Load(indexData, index.WithMaxVersion(math.MaxUint32))
 	if err != nil {
 		return nil, err
 	}
@@ -78,6 +79,14 @@ func (v *IndexView) EntriesWithPrefix(prefix string) iter.Seq[blobtype.EntryView
 	return v.idx.EntriesWithPrefixView(prefix)
 }
 
+// EntriesWithSuffix returns an iterator over entries whose path ends with
+// suffix. It scans every entry; see Blob.EntriesWithSuffix.
+//
+// The returned views are only valid while the IndexView remains alive.
+func (v *IndexView) EntriesWithSuffix(suffix string) iter.Seq[blobtype.EntryView] {
+	return v.idx.EntriesWithSuffixView(suffix)
+}
+
 // IndexData returns the raw FlatBuffers-encoded index.
 // This is useful for caching or transmitting the index.
 func (v *IndexView) IndexData() []byte {
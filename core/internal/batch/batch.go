@@ -3,6 +3,7 @@ package batch
 import (
 	"bytes"
 	"context"
+	"crypto/cipher"
 	"crypto/sha256"
 	"errors"
 	"fmt"
@@ -43,17 +44,22 @@ const (
 type Processor struct {
 	source           file.ByteSource
 	pool             *file.DecompressPool
+	aead             cipher.AEAD
 	maxFileSize      uint64
 	workers          int // 0 = auto, <0 = serial, >0 = fixed count
 	readConcurrency  int
 	readAheadBytes   uint64
 	readAheadEnabled bool
+	throttle         *throttle
 	logger           *slog.Logger
 	progress         blobtype.ProgressFunc
+	perFileProgress  bool
+	orderedProgress  bool
 
 	// Progress tracking state (set during Process call)
 	progressTotal     int
 	progressProcessed atomic.Int64
+	order             *progressOrder // nil unless progress is ordered
 }
 
 // log returns the logger, falling back to a discard logger if nil.
@@ -64,12 +70,51 @@ func (p *Processor) log() *slog.Logger {
 	return p.logger
 }
 
+// reportEntryStart reports that an entry has started extracting when
+// per-file progress is enabled. With ordered progress the start event is
+// sent together with the entry's completion events instead.
+func (p *Processor) reportEntryStart(entry *Entry) {
+	if p.progress == nil || !p.perFileProgress || p.order != nil {
+		return
+	}
+	p.emitEntryStart(entry, int(p.progressProcessed.Load()))
+}
+
 // reportEntryProgress reports extraction progress for a single entry.
 func (p *Processor) reportEntryProgress(entry *Entry) {
 	if p.progress == nil {
 		return
 	}
-	done := int(p.progressProcessed.Add(1))
+	if p.order != nil {
+		p.order.complete(entry)
+		return
+	}
+	p.emitEntryDone(entry, int(p.progressProcessed.Add(1)))
+}
+
+func (p *Processor) emitEntryStart(entry *Entry, done int) {
+	p.progress(blobtype.ProgressEvent{
+		Stage:      blobtype.StageExtractingFile,
+		Path:       entry.Path,
+		BytesTotal: entry.OriginalSize,
+		FilesDone:  done,
+		FilesTotal: p.progressTotal,
+	})
+}
+
+// emitEntryDone sends the completion events for an entry; done counts the
+// entry itself.
+func (p *Processor) emitEntryDone(entry *Entry, done int) {
+	if p.perFileProgress {
+		p.progress(blobtype.ProgressEvent{
+			Stage:      blobtype.StageExtractedFile,
+			Path:       entry.Path,
+			BytesDone:  entry.OriginalSize,
+			BytesTotal: entry.OriginalSize,
+			FilesDone:  done,
+			FilesTotal: p.progressTotal,
+		})
+	}
 	p.progress(blobtype.ProgressEvent{
 		Stage:      blobtype.StageExtracting,
 		Path:       entry.Path,
@@ -112,6 +157,19 @@ func WithReadAheadBytes(limit uint64) ProcessorOption {
 	}
 }
 
+// WithMaxBytesPerSecond limits the rate at which entry data is read from
+// the source to bps bytes per second, shared by all read workers. Reads are
+// split into smaller ranges so the rate holds within each group. A value
+// of 0 or less disables the limit.
+func WithMaxBytesPerSecond(bps int64) ProcessorOption {
+	return func(p *Processor) {
+		p.throttle = nil
+		if bps > 0 {
+			p.throttle = newThrottle(bps)
+		}
+	}
+}
+
 // WithProcessorLogger sets the logger for batch processing operations.
 // If not set, logging is disabled.
 func WithProcessorLogger(logger *slog.Logger) ProcessorOption {
@@ -128,6 +186,33 @@ func WithProcessorProgress(fn blobtype.ProgressFunc) ProcessorOption {
 	}
 }
 
+// WithPerFileProgress adds a StageExtractingFile event before each file is
+// extracted and a StageExtractedFile event after it, alongside the
+// StageExtracting events. It has no effect without WithProcessorProgress.
+func WithPerFileProgress(enabled bool) ProcessorOption {
+	return func(p *Processor) {
+		p.perFileProgress = enabled
+	}
+}
+
+// WithOrderedProgress delivers progress events in path order, whatever
+// order concurrent workers finish in. Events of a finished entry are held
+// back until every entry before it in path order has finished; per-file
+// start events are then sent immediately before the entry's completion
+// events. Extraction itself is not serialized.
+func WithOrderedProgress(enabled bool) ProcessorOption {
+	return func(p *Processor) {
+		p.orderedProgress = enabled
+	}
+}
+
+// WithCipher sets the AEAD used to decrypt encrypted entries.
+func WithCipher(aead cipher.AEAD) ProcessorOption {
+	return func(p *Processor) {
+		p.aead = aead
+	}
+}
+
 // NewProcessor creates a new batch processor.
 //
 // The source provides random access to the data blob.
@@ -157,7 +242,19 @@ func NewProcessor(source file.ByteSource, pool *file.DecompressPool, maxFileSize
 // contains counts for processed and skipped entries, and total bytes written.
 // On error, partial stats are returned reflecting work completed before the error.
 func (p *Processor) Process(entries []*Entry, sink Sink) (ProcessStats, error) {
+	return p.ProcessContext(context.Background(), entries, sink)
+}
+
+// ProcessContext is like Process but stops when ctx is canceled.
+//
+// Cancellation is checked between entries and aborts in-flight range reads
+// from sources that implement file.ContextReaderAt. Entries already written
+// to the sink are kept; the returned error wraps ctx.Err().
+func (p *Processor) ProcessContext(ctx context.Context, entries []*Entry, sink Sink) (ProcessStats, error) {
 	var stats ProcessStats
+	if err := ctx.Err(); err != nil {
+		return stats, err
+	}
 	if len(entries) == 0 {
 		return stats, nil
 	}
@@ -178,6 +275,10 @@ func (p *Processor) Process(entries []*Entry, sink Sink) (ProcessStats, error) {
 	// Initialize progress tracking
 	p.progressTotal = len(toProcess)
 	p.progressProcessed.Store(0)
+	p.order = nil
+	if p.progress != nil && p.orderedProgress {
+		p.order = newProgressOrder(p, toProcess)
+	}
 
 	// Validate all entries
 	sourceSize := p.source.Size()
@@ -187,8 +288,9 @@ func (p *Processor) Process(entries []*Entry, sink Sink) (ProcessStats, error) {
 		}
 	}
 
-	// Sort by data offset for efficient grouping
-	slices.SortFunc(toProcess, func(a, b *Entry) int {
+	// Sort by data offset for efficient grouping. The sort is stable so
+	// entries sharing an offset, such as empty files, keep their order.
+	slices.SortStableFunc(toProcess, func(a, b *Entry) int {
 		if a.DataOffset < b.DataOffset {
 			return -1
 		}
@@ -205,9 +307,9 @@ func (p *Processor) Process(entries []*Entry, sink Sink) (ProcessStats, error) {
 	var procStats ProcessStats
 	var err error
 	if len(groups) > 1 && (p.readConcurrency > 1 || p.readAheadEnabled) {
-		procStats, err = p.processGroupsPipelined(groups, sink)
+		procStats, err = p.processGroupsPipelined(ctx, groups, sink)
 	} else {
-		procStats, err = p.processGroupsSequential(groups, sink)
+		procStats, err = p.processGroupsSequential(ctx, groups, sink)
 	}
 	stats.add(procStats)
 	return stats, err
@@ -229,10 +331,10 @@ type groupResult struct {
 }
 
 // processGroupsSequential processes groups one at a time without pipelining.
-func (p *Processor) processGroupsSequential(groups []rangeGroup, sink Sink) (ProcessStats, error) {
+func (p *Processor) processGroupsSequential(ctx context.Context, groups []rangeGroup, sink Sink) (ProcessStats, error) {
 	var stats ProcessStats
 	for _, group := range groups {
-		groupStats, err := p.processGroup(group, sink)
+		groupStats, err := p.processGroup(ctx, group, sink)
 		stats.add(groupStats)
 		if err != nil {
 			return stats, err
@@ -242,7 +344,7 @@ func (p *Processor) processGroupsSequential(groups []rangeGroup, sink Sink) (Pro
 }
 
 //nolint:gocognit,gocyclo // complex pipeline logic requires coordination between producers/consumers
-func (p *Processor) processGroupsPipelined(groups []rangeGroup, sink Sink) (ProcessStats, error) {
+func (p *Processor) processGroupsPipelined(parent context.Context, groups []rangeGroup, sink Sink) (ProcessStats, error) {
 	if len(groups) == 0 {
 		return ProcessStats{}, nil
 	}
@@ -263,7 +365,7 @@ func (p *Processor) processGroupsPipelined(groups []rangeGroup, sink Sink) (Proc
 
 	readCh := make(chan groupTask)
 	readyCh := make(chan groupResult, readWorkers)
-	eg, ctx := errgroup.WithContext(context.Background())
+	eg, ctx := errgroup.WithContext(parent)
 
 	var readWg sync.WaitGroup
 	readWg.Add(readWorkers)
@@ -280,7 +382,7 @@ func (p *Processor) processGroupsPipelined(groups []rangeGroup, sink Sink) (Proc
 						return err
 					}
 				}
-				data, err := p.readGroupData(task.group)
+				data, err := p.readGroupData(ctx, task.group)
 				if err != nil {
 					if budget != nil {
 						budget.Release(task.size)
@@ -351,7 +453,7 @@ func (p *Processor) processGroupsPipelined(groups []rangeGroup, sink Sink) (Proc
 						break
 					}
 					delete(pending, next)
-					groupStats, err := p.processGroupWithData(res.group, res.data, sink)
+					groupStats, err := p.processGroupWithData(ctx, res.group, res.data, sink)
 					statsMu.Lock()
 					stats.add(groupStats)
 					statsMu.Unlock()
@@ -375,35 +477,40 @@ func (p *Processor) processGroupsPipelined(groups []rangeGroup, sink Sink) (Proc
 }
 
 // processGroup reads a contiguous range and processes each entry.
-func (p *Processor) processGroup(group rangeGroup, sink Sink) (ProcessStats, error) {
-	data, err := p.readGroupData(group)
+func (p *Processor) processGroup(ctx context.Context, group rangeGroup, sink Sink) (ProcessStats, error) {
+	data, err := p.readGroupData(ctx, group)
 	if err != nil {
 		return ProcessStats{}, err
 	}
-	return p.processGroupWithData(group, data, sink)
+	return p.processGroupWithData(ctx, group, data, sink)
 }
 
 // processGroupWithData processes all entries in a group using pre-fetched data.
-func (p *Processor) processGroupWithData(group rangeGroup, data []byte, sink Sink) (ProcessStats, error) {
+func (p *Processor) processGroupWithData(ctx context.Context, group rangeGroup, data []byte, sink Sink) (ProcessStats, error) {
 	if len(group.entries) == 0 {
 		return ProcessStats{}, nil
 	}
 	workers := p.workerCount(group.entries)
 	if workers < 2 {
-		return p.processEntriesSerial(group.entries, data, group.start, sink)
+		return p.processEntriesSerial(ctx, group.entries, data, group.start, sink)
 	}
-	return p.processEntriesParallel(group.entries, data, group.start, sink, workers)
+	return p.processEntriesParallel(ctx, group.entries, data, group.start, sink, workers)
 }
 
 // readGroupData reads the contiguous byte range for a group.
-func (p *Processor) readGroupData(group rangeGroup) ([]byte, error) {
+func (p *Processor) readGroupData(ctx context.Context, group rangeGroup) ([]byte, error) {
 	size := group.end - group.start
 	sizeInt, err := sizing.ToInt(size, blobtype.ErrSizeOverflow)
 	if err != nil {
 		return nil, fmt.Errorf("batch: %w", err)
 	}
 	data := make([]byte, sizeInt)
-	n, err := p.source.ReadAt(data, int64(group.start)) //nolint:gosec // offset fits in int64 after validation
+	var n int
+	if p.throttle != nil {
+		n, err = p.readThrottled(ctx, data, int64(group.start)) //nolint:gosec // offset fits in int64 after validation
+	} else {
+		n, err = file.ReadAtContext(ctx, p.source, data, int64(group.start)) //nolint:gosec // offset fits in int64 after validation
+	}
 	if err != nil && !errors.Is(err, io.EOF) {
 		return nil, fmt.Errorf("batch: %w", err)
 	}
@@ -413,6 +520,24 @@ func (p *Processor) readGroupData(group rangeGroup) ([]byte, error) {
 	return data, nil
 }
 
+// readThrottled fills data from off in chunks paced by the throttle.
+func (p *Processor) readThrottled(ctx context.Context, data []byte, off int64) (int, error) {
+	chunk := p.throttle.chunkSize()
+	total := 0
+	for total < len(data) {
+		size := min(chunk, len(data)-total)
+		if err := p.throttle.wait(ctx, size); err != nil {
+			return total, err
+		}
+		n, err := file.ReadAtContext(ctx, p.source, data[total:total+size], off+int64(total))
+		total += n
+		if err != nil {
+			return total, err
+		}
+	}
+	return total, nil
+}
+
 // groupSize returns the total byte size of a group as int64.
 func groupSize(group rangeGroup) (int64, error) {
 	size := group.end - group.start
@@ -420,10 +545,14 @@ func groupSize(group rangeGroup) (int64, error) {
 }
 
 // processEntriesSerial processes entries one at a time.
-func (p *Processor) processEntriesSerial(entries []*Entry, data []byte, groupStart uint64, sink Sink) (ProcessStats, error) {
+func (p *Processor) processEntriesSerial(ctx context.Context, entries []*Entry, data []byte, groupStart uint64, sink Sink) (ProcessStats, error) {
 	var stats ProcessStats
 	for _, entry := range entries {
-		if err := p.processEntry(entry, data, groupStart, sink); err != nil {
+		if err := ctx.Err(); err != nil {
+			return stats, err
+		}
+		p.reportEntryStart(entry)
+		if err := p.processEntry(ctx, entry, data, groupStart, sink); err != nil {
 			return stats, err
 		}
 		stats.Processed++
@@ -434,7 +563,7 @@ func (p *Processor) processEntriesSerial(entries []*Entry, data []byte, groupSta
 }
 
 // processEntriesParallel processes entries concurrently.
-func (p *Processor) processEntriesParallel(entries []*Entry, data []byte, groupStart uint64, sink Sink, workers int) (ProcessStats, error) {
+func (p *Processor) processEntriesParallel(ctx context.Context, entries []*Entry, data []byte, groupStart uint64, sink Sink, workers int) (ProcessStats, error) {
 	var stop atomic.Bool
 	var processed atomic.Int64
 	var totalBytes atomic.Uint64
@@ -449,8 +578,15 @@ func (p *Processor) processEntriesParallel(entries []*Entry, data []byte, groupS
 				if stop.Load() {
 					return
 				}
+				if err := ctx.Err(); err != nil {
+					if stop.CompareAndSwap(false, true) {
+						errCh <- err
+					}
+					return
+				}
 				entry := entries[i]
-				if err := p.processEntry(entry, data, groupStart, sink); err != nil {
+				p.reportEntryStart(entry)
+				if err := p.processEntry(ctx, entry, data, groupStart, sink); err != nil {
 					if stop.CompareAndSwap(false, true) {
 						errCh <- err
 					}
@@ -477,8 +613,9 @@ func (p *Processor) processEntriesParallel(entries []*Entry, data []byte, groupS
 	}
 }
 
-// processEntry decompresses, verifies, and writes a single entry.
-func (p *Processor) processEntry(entry *Entry, groupData []byte, groupStart uint64, sink Sink) error {
+// processEntry decompresses, verifies, and writes a single entry. Waiting
+// for decoder memory stops when ctx is done.
+func (p *Processor) processEntry(ctx context.Context, entry *Entry, groupData []byte, groupStart uint64, sink Sink) error {
 	// Extract this entry's data from the group
 	localOffset := entry.DataOffset - groupStart
 	localEnd := localOffset + entry.DataSize
@@ -494,9 +631,14 @@ func (p *Processor) processEntry(entry *Entry, groupData []byte, groupStart uint
 		return err
 	}
 	entryData := groupData[start:end]
+	if file.IsEncrypted(entry) {
+		if entryData, err = file.Decrypt(p.aead, entry, entryData); err != nil {
+			return fmt.Errorf("batch: %s: %w", entry.Path, err)
+		}
+	}
 
 	if bufferedSink, ok := sink.(BufferedSink); ok {
-		content, err := p.decompress(entry, entryData)
+		content, err := p.decompress(ctx, entry, entryData)
 		if err != nil {
 			return fmt.Errorf("batch: %s: %w", entry.Path, err)
 		}
@@ -520,7 +662,7 @@ func (p *Processor) processEntry(entry *Entry, groupData []byte, groupStart uint
 	if entry.Compression == blobtype.CompressionNone {
 		processErr = p.writeVerifyUncompressed(entry, entryData, w)
 	} else {
-		processErr = p.streamDecompressVerify(entry, entryData, w)
+		processErr = p.streamDecompressVerify(ctx, entry, entryData, w)
 	}
 	if processErr != nil {
 		_ = w.Discard() //nolint:errcheck // best-effort cleanup
@@ -556,7 +698,7 @@ func (p *Processor) verifyUncompressed(entry *Entry, data []byte) error {
 }
 
 // decompress decompresses entry data based on its compression type.
-func (p *Processor) decompress(entry *Entry, data []byte) ([]byte, error) {
+func (p *Processor) decompress(ctx context.Context, entry *Entry, data []byte) ([]byte, error) {
 	switch entry.Compression {
 	case blobtype.CompressionNone:
 		if uint64(len(data)) != entry.OriginalSize {
@@ -568,9 +710,9 @@ func (p *Processor) decompress(entry *Entry, data []byte) ([]byte, error) {
 		if err != nil {
 			return nil, err
 		}
-		dec, closeFn, err := p.pool.Get(bytes.NewReader(data))
+		dec, closeFn, err := p.pool.GetSizedContext(ctx, bytes.NewReader(data), entry.OriginalSize)
 		if err != nil {
-			return nil, fmt.Errorf("%w: %v", blobtype.ErrDecompression, err)
+			return nil, decoderError(ctx, err)
 		}
 		defer closeFn()
 
@@ -591,8 +733,8 @@ func (p *Processor) decompress(entry *Entry, data []byte) ([]byte, error) {
 }
 
 // streamDecompressVerify decompresses entry data, verifies hash, and writes to w.
-func (p *Processor) streamDecompressVerify(entry *Entry, data []byte, w io.Writer) error {
-	reader, closeFn, err := p.newEntryReader(entry, data)
+func (p *Processor) streamDecompressVerify(ctx context.Context, entry *Entry, data []byte, w io.Writer) error {
+	reader, closeFn, err := p.newEntryReader(ctx, entry, data)
 	if err != nil {
 		return err
 	}
@@ -628,14 +770,14 @@ func (p *Processor) streamDecompressVerify(entry *Entry, data []byte, w io.Write
 }
 
 // newEntryReader creates a reader for the entry's compressed data.
-func (p *Processor) newEntryReader(entry *Entry, data []byte) (io.Reader, func(), error) {
+func (p *Processor) newEntryReader(ctx context.Context, entry *Entry, data []byte) (io.Reader, func(), error) {
 	switch entry.Compression {
 	case blobtype.CompressionNone:
 		return bytes.NewReader(data), func() {}, nil
 	case blobtype.CompressionZstd:
-		dec, closeFn, err := p.pool.Get(bytes.NewReader(data))
+		dec, closeFn, err := p.pool.GetSizedContext(ctx, bytes.NewReader(data), entry.OriginalSize)
 		if err != nil {
-			return nil, nil, fmt.Errorf("%w: %v", blobtype.ErrDecompression, err)
+			return nil, nil, decoderError(ctx, err)
 		}
 		return dec, closeFn, nil
 	default:
@@ -643,6 +785,16 @@ func (p *Processor) newEntryReader(entry *Entry, data []byte) (io.Reader, func()
 	}
 }
 
+// decoderError reports a failure to obtain a decoder. Cancellation and the
+// decoder memory limit are returned as is; anything else is a
+// decompression failure.
+func decoderError(ctx context.Context, err error) error {
+	if ctx.Err() != nil || errors.Is(err, blobtype.ErrDecoderMemoryLimit) {
+		return err
+	}
+	return fmt.Errorf("%w: %v", blobtype.ErrDecompression, err)
+}
+
 // workerCount determines the number of workers to use for processing.
 func (p *Processor) workerCount(entries []*Entry) int {
 	if len(entries) < 2 {
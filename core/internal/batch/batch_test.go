@@ -1,13 +1,22 @@
 package batch
 
 import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"context"
 	"crypto/sha256"
 	"encoding/hex"
+	"fmt"
 	"io"
+	"io/fs"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
+
+	"github.com/meigma/blob/core/internal/blobtype"
 )
 
 // mockByteSource provides an in-memory ByteSource for testing.
@@ -215,8 +224,293 @@ func TestProcessor_EmptyEntries(t *testing.T) {
 	assert.Equal(t, ProcessStats{}, stats)
 }
 
+func TestProcessor_MaxBytesPerSecond(t *testing.T) {
+	t.Parallel()
+
+	const (
+		entryCount = 32
+		entrySize  = 8 << 10
+		bps        = 1 << 20
+	)
+	// With a gap after every fourth entry the entries form several groups.
+	build := func(gap int) ([]byte, []*Entry) {
+		var data []byte
+		entries := make([]*Entry, 0, entryCount)
+		for i := range entryCount {
+			if gap > 0 && i%4 == 0 {
+				data = append(data, make([]byte, gap)...)
+			}
+			content := bytes.Repeat([]byte{byte(i)}, entrySize)
+			entries = append(entries, &Entry{
+				Path:         fmt.Sprintf("f%02d", i),
+				DataOffset:   uint64(len(data)),
+				DataSize:     entrySize,
+				OriginalSize: entrySize,
+				Hash:         sha256Hash(string(content)),
+			})
+			data = append(data, content...)
+		}
+		return data, entries
+	}
+
+	tests := []struct {
+		name string
+		gap  int
+		opts []ProcessorOption
+	}{
+		{name: "single group", gap: 0},
+		{name: "concurrent groups", gap: 16, opts: []ProcessorOption{WithReadConcurrency(4)}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			data, entries := build(tt.gap)
+			opts := append([]ProcessorOption{WithMaxBytesPerSecond(bps)}, tt.opts...)
+			proc := NewProcessor(&mockByteSource{data: data}, nil, 0, opts...)
+
+			sink := newMockSink()
+			start := time.Now()
+			stats, err := proc.Process(entries, sink)
+			elapsed := time.Since(start)
+			require.NoError(t, err)
+			assert.Equal(t, entryCount, stats.Processed)
+
+			want := time.Duration(float64(entryCount*entrySize) / bps * float64(time.Second))
+			assert.GreaterOrEqual(t, elapsed, want*9/10, "read faster than the limit")
+			assert.Less(t, elapsed, want*3, "read much slower than the limit")
+		})
+	}
+
+	t.Run("canceled while waiting", func(t *testing.T) {
+		t.Parallel()
+
+		data, entries := build(0)
+		proc := NewProcessor(&mockByteSource{data: data}, nil, 0, WithMaxBytesPerSecond(1))
+		ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+		defer cancel()
+		_, err := proc.ProcessContext(ctx, entries, newMockSink())
+		require.ErrorIs(t, err, context.DeadlineExceeded)
+	})
+}
+
+func TestCountingSink(t *testing.T) {
+	t.Parallel()
+
+	contents := []string{"hello", "", "batch processing", "world!"}
+	var data []byte
+	var entries []*Entry
+	var total uint64
+	for i, content := range contents {
+		entries = append(entries, &Entry{
+			Path:         fmt.Sprintf("f%d", i),
+			DataOffset:   uint64(len(data)),
+			DataSize:     uint64(len(content)),
+			OriginalSize: uint64(len(content)),
+			Hash:         sha256Hash(content),
+		})
+		data = append(data, content...)
+		total += uint64(len(content))
+	}
+
+	t.Run("counts verified entries", func(t *testing.T) {
+		t.Parallel()
+
+		sink := NewCountingSink()
+		proc := NewProcessor(&mockByteSource{data: data}, nil, 0, WithWorkers(2))
+		stats, err := proc.Process(entries, sink)
+		require.NoError(t, err)
+		assert.Equal(t, int64(len(contents)), sink.Files())
+		assert.Equal(t, total, sink.Bytes())
+		assert.Equal(t, stats.TotalBytes, sink.Bytes())
+	})
+
+	t.Run("hash mismatch is not counted", func(t *testing.T) {
+		t.Parallel()
+
+		bad := *entries[0]
+		bad.Hash = sha256Hash("other")
+		sink := NewCountingSink()
+		proc := NewProcessor(&mockByteSource{data: data}, nil, 0)
+		_, err := proc.Process([]*Entry{&bad}, sink)
+		require.ErrorIs(t, err, blobtype.ErrHashMismatch)
+		assert.Zero(t, sink.Files())
+		assert.Zero(t, sink.Bytes())
+	})
+
+	t.Run("writer counts on commit", func(t *testing.T) {
+		t.Parallel()
+
+		sink := NewCountingSink()
+		w, err := sink.Writer(entries[0])
+		require.NoError(t, err)
+		_, err = w.Write([]byte("hello"))
+		require.NoError(t, err)
+		require.NoError(t, w.Discard())
+		assert.Zero(t, sink.Files())
+
+		w, err = sink.Writer(entries[0])
+		require.NoError(t, err)
+		_, err = w.Write([]byte("hello"))
+		require.NoError(t, err)
+		require.NoError(t, w.Commit())
+		assert.Equal(t, int64(1), sink.Files())
+		assert.Equal(t, uint64(5), sink.Bytes())
+	})
+}
+
+func TestDiscardSink(t *testing.T) {
+	t.Parallel()
+
+	content := "discarded"
+	entry := &Entry{
+		Path:         "a",
+		DataSize:     uint64(len(content)),
+		OriginalSize: uint64(len(content)),
+		Hash:         sha256Hash(content),
+	}
+	proc := NewProcessor(&mockByteSource{data: []byte(content)}, nil, 0)
+	stats, err := proc.Process([]*Entry{entry}, NewDiscardSink())
+	require.NoError(t, err)
+	assert.Equal(t, 1, stats.Processed)
+	assert.Equal(t, uint64(len(content)), stats.TotalBytes)
+}
+
+func TestTarSink(t *testing.T) {
+	t.Parallel()
+
+	modTime := time.Date(2021, 5, 6, 7, 8, 9, 0, time.UTC)
+	want := make(map[string][]byte)
+	var data []byte
+	var entries []*Entry
+	for i := range 16 {
+		content := bytes.Repeat([]byte{byte('a' + i)}, 70<<10+i)
+		path := fmt.Sprintf("dir/f%02d", i)
+		want[path] = content
+		entries = append(entries, &Entry{
+			Path:         path,
+			DataOffset:   uint64(len(data)),
+			DataSize:     uint64(len(content)),
+			OriginalSize: uint64(len(content)),
+			Hash:         sha256Hash(string(content)),
+			Mode:         0o640,
+			ModTime:      modTime,
+		})
+		data = append(data, content...)
+	}
+	entries = append(entries, &Entry{Path: "dir", Mode: fs.ModeDir | 0o755})
+
+	var buf bytes.Buffer
+	sink := NewTarSink(&buf)
+	proc := NewProcessor(&mockByteSource{data: data}, nil, 0, WithWorkers(4))
+	stats, err := proc.Process(entries, sink)
+	require.NoError(t, err)
+	assert.Equal(t, 16, stats.Processed)
+	assert.Equal(t, 1, stats.Skipped)
+	require.NoError(t, sink.Close())
+
+	got := make(map[string][]byte)
+	tr := tar.NewReader(&buf)
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		require.NoError(t, err)
+		assert.Equal(t, int64(0o640), hdr.Mode, hdr.Name)
+		assert.True(t, hdr.ModTime.Equal(modTime), hdr.Name)
+		content, err := io.ReadAll(tr)
+		require.NoError(t, err)
+		got[hdr.Name] = content
+	}
+	assert.Equal(t, want, got)
+
+	t.Run("failed entry poisons the stream", func(t *testing.T) {
+		t.Parallel()
+
+		bad := *entries[0]
+		bad.Hash = sha256Hash("other")
+		sink := NewTarSink(io.Discard)
+		proc := NewProcessor(&mockByteSource{data: data}, nil, 0)
+		_, err := proc.Process([]*Entry{&bad}, sink)
+		require.ErrorIs(t, err, blobtype.ErrHashMismatch)
+		assert.Error(t, sink.Close())
+	})
+}
+
 // sha256Hash returns a valid SHA256 hash for the given content.
 func sha256Hash(content string) []byte {
 	h := sha256.Sum256([]byte(content))
 	return h[:]
 }
+
+func TestZipSink(t *testing.T) {
+	t.Parallel()
+
+	modTime := time.Date(2021, 5, 6, 7, 8, 10, 0, time.UTC)
+	want := make(map[string][]byte)
+	var data []byte
+	var entries []*Entry
+	for i := range 16 {
+		content := bytes.Repeat([]byte{byte('a' + i)}, 70<<10+i)
+		path := fmt.Sprintf("dir/f%02d", i)
+		want[path] = content
+		entries = append(entries, &Entry{
+			Path:         path,
+			DataOffset:   uint64(len(data)),
+			DataSize:     uint64(len(content)),
+			OriginalSize: uint64(len(content)),
+			Hash:         sha256Hash(string(content)),
+			Mode:         0o640,
+			ModTime:      modTime,
+		})
+		data = append(data, content...)
+	}
+	entries = append(entries, &Entry{Path: "dir", Mode: fs.ModeDir | 0o755})
+
+	var buf bytes.Buffer
+	sink := NewZipSink(&buf, WithZipHeaderFunc(func(_ *zip.Writer, entry *Entry, hdr *zip.FileHeader) error {
+		if entry.Path == "dir/f00" {
+			hdr.Method = zip.Store
+		}
+		return nil
+	}))
+	proc := NewProcessor(&mockByteSource{data: data}, nil, 0, WithWorkers(4))
+	stats, err := proc.Process(entries, sink)
+	require.NoError(t, err)
+	assert.Equal(t, 16, stats.Processed)
+	assert.Equal(t, 1, stats.Skipped)
+	require.NoError(t, sink.Close())
+
+	zr, err := zip.NewReader(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	require.NoError(t, err)
+	got := make(map[string][]byte)
+	for _, f := range zr.File {
+		assert.Equal(t, fs.FileMode(0o640), f.Mode(), f.Name)
+		assert.True(t, f.Modified.Equal(modTime), f.Name)
+		wantMethod := zip.Deflate
+		if f.Name == "dir/f00" {
+			wantMethod = zip.Store
+		}
+		assert.Equal(t, wantMethod, f.Method, f.Name)
+		rc, err := f.Open()
+		require.NoError(t, err)
+		content, err := io.ReadAll(rc)
+		require.NoError(t, err)
+		require.NoError(t, rc.Close())
+		got[f.Name] = content
+	}
+	assert.Equal(t, want, got)
+
+	t.Run("failed entry poisons the stream", func(t *testing.T) {
+		t.Parallel()
+
+		bad := *entries[0]
+		bad.Hash = sha256Hash("other")
+		sink := NewZipSink(io.Discard)
+		proc := NewProcessor(&mockByteSource{data: data}, nil, 0)
+		_, err := proc.Process([]*Entry{&bad}, sink)
+		require.ErrorIs(t, err, blobtype.ErrHashMismatch)
+		assert.Error(t, sink.Close())
+	})
+}
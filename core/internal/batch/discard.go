@@ -0,0 +1,97 @@
+package batch
+
+import "sync/atomic"
+
+// DiscardSink is a Sink that verifies entries and drops their content.
+//
+// Processing entries into a DiscardSink reads, decompresses, and
+// hash-verifies them without writing anything, which is useful for
+// validation runs and for warming caches below the source.
+type DiscardSink struct{}
+
+// NewDiscardSink returns a sink that processes every entry and discards
+// its content.
+func NewDiscardSink() DiscardSink {
+	return DiscardSink{}
+}
+
+// ShouldProcess implements Sink; every entry is processed.
+func (DiscardSink) ShouldProcess(*Entry) bool {
+	return true
+}
+
+// Writer implements Sink.
+func (DiscardSink) Writer(*Entry) (Committer, error) {
+	return discardCommitter{}, nil
+}
+
+// PutBuffered implements BufferedSink.
+func (DiscardSink) PutBuffered(*Entry, []byte) error {
+	return nil
+}
+
+type discardCommitter struct{}
+
+func (discardCommitter) Write(p []byte) (int, error) { return len(p), nil }
+func (discardCommitter) Commit() error               { return nil }
+func (discardCommitter) Discard() error              { return nil }
+
+// CountingSink is a DiscardSink that counts the entries and bytes that
+// pass verification. It is safe for concurrent use by a Processor.
+type CountingSink struct {
+	files atomic.Int64
+	bytes atomic.Uint64
+}
+
+// NewCountingSink returns a sink that discards content and counts verified
+// entries and their uncompressed bytes.
+func NewCountingSink() *CountingSink {
+	return &CountingSink{}
+}
+
+// ShouldProcess implements Sink; every entry is processed.
+func (s *CountingSink) ShouldProcess(*Entry) bool {
+	return true
+}
+
+// Writer implements Sink. Content counts once the write is committed.
+func (s *CountingSink) Writer(*Entry) (Committer, error) {
+	return &countingCommitter{sink: s}, nil
+}
+
+// PutBuffered implements BufferedSink.
+func (s *CountingSink) PutBuffered(_ *Entry, content []byte) error {
+	s.files.Add(1)
+	s.bytes.Add(uint64(len(content)))
+	return nil
+}
+
+// Files returns the number of verified entries.
+func (s *CountingSink) Files() int64 {
+	return s.files.Load()
+}
+
+// Bytes returns the total uncompressed size of the verified entries.
+func (s *CountingSink) Bytes() uint64 {
+	return s.bytes.Load()
+}
+
+type countingCommitter struct {
+	sink    *CountingSink
+	written uint64
+}
+
+func (c *countingCommitter) Write(p []byte) (int, error) {
+	c.written += uint64(len(p))
+	return len(p), nil
+}
+
+func (c *countingCommitter) Commit() error {
+	c.sink.files.Add(1)
+	c.sink.bytes.Add(c.written)
+	return nil
+}
+
+func (c *countingCommitter) Discard() error {
+	return nil
+}
@@ -0,0 +1,56 @@
+package batch
+
+import (
+	"slices"
+	"strings"
+	"sync"
+)
+
+// progressOrder holds back entry completions so that their progress events
+// are sent in path order. A cursor tracks the next entry in path order;
+// each completion is parked until the cursor reaches it.
+type progressOrder struct {
+	p *Processor
+
+	mu      sync.Mutex
+	rank    map[*Entry]int // entry -> position in path order
+	pending map[int]*Entry // finished entries not yet reported
+	next    int            // position of the next entry to report
+}
+
+func newProgressOrder(p *Processor, entries []*Entry) *progressOrder {
+	ordered := slices.Clone(entries)
+	slices.SortStableFunc(ordered, func(a, b *Entry) int {
+		return strings.Compare(a.Path, b.Path)
+	})
+	rank := make(map[*Entry]int, len(ordered))
+	for i, entry := range ordered {
+		rank[entry] = i
+	}
+	return &progressOrder{
+		p:       p,
+		rank:    rank,
+		pending: make(map[int]*Entry),
+	}
+}
+
+// complete records that entry finished and sends the events of every
+// entry the cursor can now pass.
+func (o *progressOrder) complete(entry *Entry) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	o.pending[o.rank[entry]] = entry
+	for {
+		entry, ok := o.pending[o.next]
+		if !ok {
+			return
+		}
+		delete(o.pending, o.next)
+		o.next++
+		if o.p.perFileProgress {
+			o.p.emitEntryStart(entry, o.next-1)
+		}
+		o.p.emitEntryDone(entry, o.next)
+	}
+}
@@ -0,0 +1,172 @@
+package batch
+
+import (
+	"archive/tar"
+	"errors"
+	"io"
+	"io/fs"
+	"sync"
+
+	"github.com/meigma/blob/core/internal/blobtype"
+	"github.com/meigma/blob/core/internal/sizing"
+)
+
+// TarSink is a Sink that writes each processed entry to a tar stream as a
+// header followed by its content.
+//
+// Entries are written in the order the Processor delivers them. Only one
+// entry is written at a time: Writer blocks until the previous entry is
+// committed or discarded, so concurrent workers are serialized. A discarded
+// entry leaves a partial body in the stream, and every later write fails.
+// Directory and symlink entries are not processed. Close writes the tar
+// footer.
+type TarSink struct {
+	tw     *tar.Writer
+	header func(tw *tar.Writer, entry *Entry, hdr *tar.Header) error
+
+	mu  sync.Mutex // held from Writer until Commit or Discard
+	err error      // first failure; the stream is unusable after it
+}
+
+// TarSinkOption configures a TarSink.
+type TarSinkOption func(*TarSink)
+
+// WithTarHeaderFunc sets a function called before each entry's header is
+// written. It may modify hdr and may write other entries to tw first, such
+// as directories. An error aborts the entry.
+func WithTarHeaderFunc(fn func(tw *tar.Writer, entry *Entry, hdr *tar.Header) error) TarSinkOption {
+	return func(s *TarSink) {
+		s.header = fn
+	}
+}
+
+// NewTarSink returns a sink that writes entries to w as a tar stream.
+// Headers carry the entry path, permission bits, modification time, and
+// ownership. The caller must call Close to finish the stream; w itself is
+// not closed.
+func NewTarSink(w io.Writer, opts ...TarSinkOption) *TarSink {
+	s := &TarSink{tw: tar.NewWriter(w)}
+	for _, opt := range opts {
+		opt(s)
+	}
+	return s
+}
+
+// ShouldProcess implements Sink; regular files are processed.
+func (s *TarSink) ShouldProcess(entry *Entry) bool {
+	return entry.Mode.Type()&(fs.ModeDir|fs.ModeSymlink) == 0
+}
+
+// Writer implements Sink. It writes the entry's header and returns a
+// writer for its body.
+func (s *TarSink) Writer(entry *Entry) (Committer, error) {
+	s.mu.Lock()
+	if s.err != nil {
+		s.mu.Unlock()
+		return nil, s.err
+	}
+	size, err := sizing.ToInt64(entry.OriginalSize, blobtype.ErrSizeOverflow)
+	if err != nil {
+		s.mu.Unlock()
+		return nil, err
+	}
+	hdr := &tar.Header{
+		Typeflag: tar.TypeReg,
+		Name:     entry.Path,
+		Mode:     int64(entry.Mode.Perm()),
+		ModTime:  entry.ModTime,
+		Uid:      int(entry.UID),
+		Gid:      int(entry.GID),
+		Size:     size,
+	}
+	if s.header != nil {
+		if err := s.header(s.tw, entry, hdr); err != nil {
+			s.fail(err)
+			s.mu.Unlock()
+			return nil, err
+		}
+	}
+	if err := s.tw.WriteHeader(hdr); err != nil {
+		s.fail(err)
+		s.mu.Unlock()
+		return nil, err
+	}
+	return &tarCommitter{sink: s}, nil
+}
+
+// Append calls fn with the underlying tar writer so entries can be added
+// after those processed so far, for example directories that sort after
+// the last file. It must not be called while processing is in progress.
+func (s *TarSink) Append(fn func(tw *tar.Writer) error) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.err != nil {
+		return s.err
+	}
+	if err := fn(s.tw); err != nil {
+		s.fail(err)
+		return err
+	}
+	return nil
+}
+
+// Close writes the tar footer. It fails if an entry failed earlier.
+func (s *TarSink) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.err != nil {
+		return s.err
+	}
+	return s.tw.Close()
+}
+
+// fail records the first error. The caller holds s.mu.
+func (s *TarSink) fail(err error) {
+	if s.err == nil {
+		s.err = err
+	}
+}
+
+// tarCommitter writes an entry body. The sink lock is held until Commit or
+// Discard.
+type tarCommitter struct {
+	sink *TarSink
+	done bool
+}
+
+func (c *tarCommitter) Write(p []byte) (int, error) {
+	n, err := c.sink.tw.Write(p)
+	if err != nil {
+		c.sink.fail(err)
+	}
+	return n, err
+}
+
+// Commit ends the entry; it fails if fewer bytes than the header size were
+// written.
+func (c *tarCommitter) Commit() error {
+	if c.done {
+		return nil
+	}
+	c.done = true
+	defer c.sink.mu.Unlock()
+	if err := c.sink.tw.Flush(); err != nil {
+		c.sink.fail(err)
+		return err
+	}
+	return nil
+}
+
+// Discard ends the entry without completing it. The stream cannot be
+// recovered, so the sink fails all later writes.
+func (c *tarCommitter) Discard() error {
+	if c.done {
+		return nil
+	}
+	c.done = true
+	c.sink.fail(errTarEntryDiscarded)
+	c.sink.mu.Unlock()
+	return nil
+}
+
+var errTarEntryDiscarded = errors.New("batch: tar entry discarded after its header was written")
@@ -0,0 +1,59 @@
+package batch
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// Bounds on the size of a throttled read. Reads are split into chunks of
+// about a quarter second of the configured rate, so a large group is spread
+// over time instead of arriving in one burst after a long wait.
+const (
+	minThrottleChunk = 32 << 10
+	maxThrottleChunk = 4 << 20
+)
+
+// throttle paces reads to an average rate shared by all read workers.
+//
+// It is a token bucket without burst: each read reserves the next slot of
+// n/bps seconds and waits until that slot ends, so reads complete no faster
+// than the configured rate however many workers issue them.
+type throttle struct {
+	bps int64
+
+	mu   sync.Mutex
+	next time.Time // end of the last reserved slot
+}
+
+func newThrottle(bps int64) *throttle {
+	return &throttle{bps: bps}
+}
+
+// chunkSize returns the largest read that should be issued at once.
+func (t *throttle) chunkSize() int {
+	return int(min(max(t.bps/4, minThrottleChunk), maxThrottleChunk))
+}
+
+// wait blocks until n more bytes may be read or ctx is done.
+func (t *throttle) wait(ctx context.Context, n int) error {
+	d := time.Duration(float64(n) / float64(t.bps) * float64(time.Second))
+
+	t.mu.Lock()
+	now := time.Now()
+	if t.next.Before(now) {
+		t.next = now
+	}
+	t.next = t.next.Add(d)
+	delay := t.next.Sub(now)
+	t.mu.Unlock()
+
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
@@ -0,0 +1,159 @@
+package batch
+
+import (
+	"archive/zip"
+	"errors"
+	"io"
+	"io/fs"
+	"sync"
+)
+
+// ZipSink is a Sink that writes each processed entry to a zip stream.
+//
+// Entries are written in the order the Processor delivers them and, as
+// with TarSink, one at a time: Writer blocks until the previous entry is
+// committed or discarded. A discarded entry leaves the stream unusable and
+// every later write fails. Directory and symlink entries are not
+// processed. Close writes the central directory.
+type ZipSink struct {
+	zw     *zip.Writer
+	header func(zw *zip.Writer, entry *Entry, hdr *zip.FileHeader) error
+
+	mu  sync.Mutex // held from Writer until Commit or Discard
+	err error      // first failure; the stream is unusable after it
+}
+
+// ZipSinkOption configures a ZipSink.
+type ZipSinkOption func(*ZipSink)
+
+// WithZipHeaderFunc sets a function called before each entry's header is
+// written. It may modify hdr, for example to choose the compression
+// method, and may write other entries to zw first. An error aborts the
+// entry.
+func WithZipHeaderFunc(fn func(zw *zip.Writer, entry *Entry, hdr *zip.FileHeader) error) ZipSinkOption {
+	return func(s *ZipSink) {
+		s.header = fn
+	}
+}
+
+// NewZipSink returns a sink that writes entries to w as a zip stream.
+// Headers carry the entry path, mode, and modification time, and content
+// is compressed with Deflate unless a header function chooses otherwise.
+// The caller must call Close to finish the stream; w itself is not closed.
+func NewZipSink(w io.Writer, opts ...ZipSinkOption) *ZipSink {
+	s := &ZipSink{zw: zip.NewWriter(w)}
+	for _, opt := range opts {
+		opt(s)
+	}
+	return s
+}
+
+// ShouldProcess implements Sink; regular files are processed.
+func (s *ZipSink) ShouldProcess(entry *Entry) bool {
+	return entry.Mode.Type()&(fs.ModeDir|fs.ModeSymlink) == 0
+}
+
+// Writer implements Sink. It writes the entry's header and returns a
+// writer for its content.
+func (s *ZipSink) Writer(entry *Entry) (Committer, error) {
+	s.mu.Lock()
+	if s.err != nil {
+		s.mu.Unlock()
+		return nil, s.err
+	}
+	hdr := &zip.FileHeader{
+		Name:     entry.Path,
+		Method:   zip.Deflate,
+		Modified: entry.ModTime,
+	}
+	hdr.SetMode(entry.Mode)
+	if s.header != nil {
+		if err := s.header(s.zw, entry, hdr); err != nil {
+			s.fail(err)
+			s.mu.Unlock()
+			return nil, err
+		}
+	}
+	w, err := s.zw.CreateHeader(hdr)
+	if err != nil {
+		s.fail(err)
+		s.mu.Unlock()
+		return nil, err
+	}
+	return &zipCommitter{sink: s, w: w}, nil
+}
+
+// Append calls fn with the underlying zip writer so entries can be added
+// after those processed so far. It must not be called while processing is
+// in progress.
+func (s *ZipSink) Append(fn func(zw *zip.Writer) error) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.err != nil {
+		return s.err
+	}
+	if err := fn(s.zw); err != nil {
+		s.fail(err)
+		return err
+	}
+	return nil
+}
+
+// Close writes the central directory. It fails if an entry failed
+// earlier.
+func (s *ZipSink) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.err != nil {
+		return s.err
+	}
+	return s.zw.Close()
+}
+
+// fail records the first error. The caller holds s.mu.
+func (s *ZipSink) fail(err error) {
+	if s.err == nil {
+		s.err = err
+	}
+}
+
+// zipCommitter writes an entry's content. The sink lock is held until
+// Commit or Discard.
+type zipCommitter struct {
+	sink *ZipSink
+	w    io.Writer
+	done bool
+}
+
+func (c *zipCommitter) Write(p []byte) (int, error) {
+	n, err := c.w.Write(p)
+	if err != nil {
+		c.sink.fail(err)
+	}
+	return n, err
+}
+
+// Commit ends the entry. The zip writer completes it when the next entry
+// starts or the sink is closed.
+func (c *zipCommitter) Commit() error {
+	if c.done {
+		return nil
+	}
+	c.done = true
+	c.sink.mu.Unlock()
+	return nil
+}
+
+// Discard ends the entry without completing it. The stream cannot be
+// recovered, so the sink fails all later writes.
+func (c *zipCommitter) Discard() error {
+	if c.done {
+		return nil
+	}
+	c.done = true
+	c.sink.fail(errZipEntryDiscarded)
+	c.sink.mu.Unlock()
+	return nil
+}
+
+var errZipEntryDiscarded = errors.New("batch: zip entry discarded after its header was written")
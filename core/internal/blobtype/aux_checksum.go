@@ -0,0 +1,30 @@
+package blobtype
+
+import "github.com/meigma/blob/core/internal/fb"
+
+// AuxChecksum identifies the non-cryptographic checksum recorded for each
+// entry alongside its SHA256 hash.
+type AuxChecksum uint8
+
+const (
+	AuxChecksumNone AuxChecksum = iota
+	AuxChecksumXXH64
+)
+
+// String returns the human-readable name of the checksum algorithm.
+func (c AuxChecksum) String() string {
+	switch c {
+	case AuxChecksumNone:
+		return "none"
+	case AuxChecksumXXH64:
+		return "xxh64"
+	default:
+		return "unknown"
+	}
+}
+
+// AuxChecksumFromFB converts a FlatBuffers AuxChecksum to an AuxChecksum.
+// Unknown values are preserved so readers can ignore them.
+func AuxChecksumFromFB(c fb.AuxChecksum) AuxChecksum {
+	return AuxChecksum(uint8(c)) //nolint:gosec // unknown values are ignored by callers
+}
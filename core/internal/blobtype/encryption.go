@@ -0,0 +1,29 @@
+package blobtype
+
+import "github.com/meigma/blob/core/internal/fb"
+
+// Encryption identifies the scheme used to encrypt file content in the data blob.
+type Encryption uint8
+
+const (
+	EncryptionNone Encryption = iota
+	EncryptionAESGCM
+)
+
+// String returns the human-readable name of the encryption scheme.
+func (e Encryption) String() string {
+	switch e {
+	case EncryptionNone:
+		return "none"
+	case EncryptionAESGCM:
+		return "aes-gcm"
+	default:
+		return "unknown"
+	}
+}
+
+// EncryptionFromFB converts a FlatBuffers Encryption to an Encryption.
+// Unknown values are preserved so readers can reject them.
+func EncryptionFromFB(e fb.Encryption) Encryption {
+	return Encryption(uint8(e)) //nolint:gosec // unknown values are rejected by callers
+}
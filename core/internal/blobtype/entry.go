@@ -10,6 +10,11 @@ type Entry struct {
 	// Path is the file path relative to the archive root (e.g., "src/main.go").
 	Path string
 
+	// IndexPath is the path recorded in the index when Path has been
+	// rewritten, such as relative to a Subset root or by a path mapper. It
+	// is empty when Path is the recorded path.
+	IndexPath string
+
 	// DataOffset is the byte offset in the data blob where this file's content begins.
 	DataOffset uint64
 
@@ -38,4 +43,31 @@ type Entry struct {
 
 	// Compression is the algorithm used to compress this file.
 	Compression Compression
+
+	// Nonce is the AEAD nonce used to encrypt this file's content.
+	// It is nil when the content is not encrypted.
+	Nonce []byte
+
+	// AuxChecksum is a non-cryptographic checksum of the uncompressed
+	// content, computed with the index's AuxChecksum algorithm. It is zero
+	// when the index records none.
+	AuxChecksum uint64
+
+	// Chunks lists the content-defined chunks the content is stored as, in
+	// order. It is nil for entries stored as a single region.
+	Chunks []Chunk
+}
+
+// Chunk describes one content-defined chunk of a chunked entry. Chunks are
+// stored back to back, so chunk i begins where chunk i-1 ends.
+type Chunk struct {
+	// Size is the size in bytes of the chunk in the data blob.
+	// For compressed entries, this is the compressed size.
+	Size uint32
+
+	// OriginalSize is the uncompressed size in bytes.
+	OriginalSize uint32
+
+	// Hash is the SHA256 hash of the chunk's uncompressed content.
+	Hash []byte
 }
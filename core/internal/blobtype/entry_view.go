@@ -1,6 +1,8 @@
 package blobtype
 
 import (
+	"bytes"
+	"crypto/sha256"
 	"io/fs"
 	"time"
 
@@ -14,11 +16,12 @@ import (
 // that produced it remains alive.
 type EntryView struct {
 	entry fb.Entry
+	trim  int // leading path bytes hidden by RelativeEntryView
 }
 
 // PathBytes returns the path bytes from the index buffer.
 func (ev EntryView) PathBytes() []byte {
-	return ev.entry.Path()
+	return ev.entry.Path()[ev.trim:]
 }
 
 // HashBytes returns the SHA256 hash bytes from the index buffer.
@@ -28,7 +31,7 @@ func (ev EntryView) HashBytes() []byte {
 
 // Path returns the path as a string.
 func (ev EntryView) Path() string {
-	return string(ev.entry.Path())
+	return string(ev.PathBytes())
 }
 
 // DataOffset returns the data blob offset for this entry.
@@ -71,9 +74,33 @@ func (ev EntryView) Compression() Compression {
 	return CompressionFromFB(ev.entry.Compression())
 }
 
+// NonceBytes returns the entry's encryption nonce without copying.
+// It is empty when the entry is not encrypted.
+func (ev EntryView) NonceBytes() []byte {
+	return ev.entry.NonceBytes()
+}
+
+// AuxChecksum returns the entry's non-cryptographic checksum, or zero when
+// the index records none. The algorithm is reported by the index.
+func (ev EntryView) AuxChecksum() uint64 {
+	return ev.entry.AuxChecksum()
+}
+
+// Chunks returns a copy of the entry's content-defined chunks, or nil when
+// the entry is stored as a single region or its chunk vectors disagree in
+// length.
+func (ev EntryView) Chunks() []Chunk {
+	return chunksFromFlatBuffers(&ev.entry)
+}
+
 // Entry returns a fully copied Entry.
 func (ev EntryView) Entry() Entry {
-	return EntryFromFlatBuffers(&ev.entry)
+	entry := EntryFromFlatBuffers(&ev.entry)
+	if ev.trim > 0 {
+		entry.IndexPath = entry.Path
+		entry.Path = ev.Path()
+	}
+	return entry
 }
 
 // EntryViewFromFlatBuffers creates an EntryView from a FlatBuffers Entry.
@@ -81,13 +108,26 @@ func EntryViewFromFlatBuffers(entry fb.Entry) EntryView {
 	return EntryView{entry: entry}
 }
 
+// RelativeEntryView returns a view whose path omits the first trim bytes.
+// It is used to present entries relative to a subtree root; trim must not
+// exceed the length of the entry path.
+func RelativeEntryView(ev EntryView, trim int) EntryView {
+	ev.trim += trim
+	return ev
+}
+
 // EntryFromViewWithPath creates an Entry from an EntryView with the given path.
 func EntryFromViewWithPath(ev EntryView, path string) Entry {
 	hashBytes := ev.HashBytes()
 	hash := make([]byte, len(hashBytes))
 	copy(hash, hashBytes)
+	var indexPath string
+	if full := ev.entry.Path(); string(full) != path {
+		indexPath = string(full)
+	}
 	return Entry{
 		Path:         path,
+		IndexPath:    indexPath,
 		DataOffset:   ev.DataOffset(),
 		DataSize:     ev.DataSize(),
 		OriginalSize: ev.OriginalSize(),
@@ -97,6 +137,9 @@ func EntryFromViewWithPath(ev EntryView, path string) Entry {
 		GID:          ev.GID(),
 		ModTime:      ev.ModTime(),
 		Compression:  ev.Compression(),
+		Nonce:        cloneNonce(ev.NonceBytes()),
+		AuxChecksum:  ev.AuxChecksum(),
+		Chunks:       ev.Chunks(),
 	}
 }
 
@@ -120,7 +163,37 @@ func EntryFromFlatBuffers(entry *fb.Entry) Entry {
 		GID:          entry.Gid(),
 		ModTime:      time.Unix(0, entry.MtimeNs()),
 		Compression:  CompressionFromFB(entry.Compression()),
+		Nonce:        cloneNonce(entry.NonceBytes()),
+		AuxChecksum:  entry.AuxChecksum(),
+		Chunks:       chunksFromFlatBuffers(entry),
+	}
+}
+
+// chunksFromFlatBuffers copies the chunk vectors of entry.
+func chunksFromFlatBuffers(entry *fb.Entry) []Chunk {
+	n := entry.ChunkSizesLength()
+	if n == 0 || entry.ChunkOriginalSizesLength() != n || entry.ChunkHashesLength() != n*sha256.Size {
+		return nil
+	}
+	hashes := bytes.Clone(entry.ChunkHashesBytes())
+	chunks := make([]Chunk, n)
+	for i := range chunks {
+		chunks[i] = Chunk{
+			Size:         entry.ChunkSizes(i),
+			OriginalSize: entry.ChunkOriginalSizes(i),
+			Hash:         hashes[i*sha256.Size : (i+1)*sha256.Size : (i+1)*sha256.Size],
+		}
+	}
+	return chunks
+}
+
+// cloneNonce copies a nonce out of the FlatBuffers buffer, returning nil
+// for unencrypted entries.
+func cloneNonce(nonce []byte) []byte {
+	if len(nonce) == 0 {
+		return nil
 	}
+	return bytes.Clone(nonce)
 }
 
 // CompressionFromFB converts a FlatBuffers Compression to a Compression.
@@ -1,6 +1,9 @@
 package blobtype
 
-import "errors"
+import (
+	"errors"
+	"fmt"
+)
 
 // Sentinel errors for blob operations.
 var (
@@ -10,6 +13,37 @@ var (
 	// ErrDecompression is returned when decompression fails.
 	ErrDecompression = errors.New("blob: decompression failed")
 
+	// ErrDecryption is returned when encrypted content cannot be decrypted,
+	// for example because the key is missing or wrong.
+	ErrDecryption = errors.New("blob: decryption failed")
+
 	// ErrSizeOverflow is returned when byte counts exceed supported limits.
 	ErrSizeOverflow = errors.New("blob: size overflow")
+
+	// ErrUnsupportedIndexVersion is returned when an index declares a format
+	// version outside the supported range.
+	ErrUnsupportedIndexVersion = errors.New("blob: unsupported index version")
+
+	// ErrDecoderMemoryLimit is returned when a decoder cannot fit within the
+	// global decoder memory limit because open files hold the memory.
+	ErrDecoderMemoryLimit = errors.New("blob: decoder memory limit reached by open files")
 )
+
+// IndexVersionError describes an index whose format version is outside the
+// accepted range.
+//
+// It matches ErrUnsupportedIndexVersion with errors.Is.
+type IndexVersionError struct {
+	Version    uint32 // The version declared by the index
+	MinVersion uint32 // The oldest version accepted
+	MaxVersion uint32 // The newest version accepted
+}
+
+func (e *IndexVersionError) Error() string {
+	return fmt.Sprintf("%s %d (supported %d-%d)", ErrUnsupportedIndexVersion, e.Version, e.MinVersion, e.MaxVersion)
+}
+
+// Unwrap returns ErrUnsupportedIndexVersion.
+func (e *IndexVersionError) Unwrap() error {
+	return ErrUnsupportedIndexVersion
+}
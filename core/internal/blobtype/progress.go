@@ -26,7 +26,7 @@ type ProgressEvent struct {
 // ProgressStage identifies the current phase of an operation.
 type ProgressStage uint8
 
-// Progress stages for push, pull, and extraction operations.
+// Progress stages for push, pull, extraction, and verification operations.
 const (
 	// StageEnumerating indicates the operation is walking the directory tree.
 	StageEnumerating ProgressStage = iota
@@ -48,6 +48,18 @@ const (
 
 	// StageExtracting indicates files are being extracted.
 	StageExtracting
+
+	// StageVerifying indicates files are being verified against their hashes.
+	StageVerifying
+
+	// StageFetchingData indicates data blob bytes are being fetched.
+	StageFetchingData
+
+	// StageExtractingFile indicates a single file has started extracting.
+	StageExtractingFile
+
+	// StageExtractedFile indicates a single file has finished extracting.
+	StageExtractedFile
 )
 
 // String returns the string representation of the stage.
@@ -67,6 +79,14 @@ func (s ProgressStage) String() string {
 		return "fetching index"
 	case StageExtracting:
 		return "extracting"
+	case StageVerifying:
+		return "verifying"
+	case StageFetchingData:
+		return "fetching data"
+	case StageExtractingFile:
+		return "extracting file"
+	case StageExtractedFile:
+		return "extracted file"
 	default:
 		return "unknown"
 	}
@@ -0,0 +1,145 @@
+package file
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"io"
+)
+
+// ValidateChunks checks that a chunked entry's chunks are well formed and
+// add up to its stored and original sizes.
+func ValidateChunks(entry *Entry) error {
+	if IsEncrypted(entry) {
+		return errors.New("invalid chunks: entry is encrypted")
+	}
+	var size, originalSize uint64
+	for i := range entry.Chunks {
+		c := &entry.Chunks[i]
+		if len(c.Hash) != sha256.Size {
+			return fmt.Errorf("invalid chunk %d hash length: %d", i, len(c.Hash))
+		}
+		size += uint64(c.Size)
+		originalSize += uint64(c.OriginalSize)
+	}
+	if size != entry.DataSize || originalSize != entry.OriginalSize {
+		return fmt.Errorf("%w: chunk sizes do not match entry", ErrSizeOverflow)
+	}
+	return nil
+}
+
+// ReadChunksContext reads chunks [first, last) of a chunked entry with a
+// single read of their stored bytes, decodes them into dst, and verifies
+// each against its hash. dst must hold exactly the chunks' original sizes.
+// Reads from the source are bound to ctx.
+func (r *Reader) ReadChunksContext(ctx context.Context, entry *Entry, first, last int, dst []byte) error {
+	var want uint64
+	if first >= 0 && first < last && last <= len(entry.Chunks) {
+		for _, c := range entry.Chunks[first:last] {
+			want += uint64(c.OriginalSize)
+		}
+	}
+	if uint64(len(dst)) != want {
+		return fmt.Errorf("read %s: chunk buffer size mismatch", entry.Path)
+	}
+	reader, err := r.openChunks(ctx, entry, first, last, false)
+	if err != nil {
+		return err
+	}
+	defer reader.Close()
+
+	for _, c := range entry.Chunks[first:last] {
+		part := dst[:c.OriginalSize]
+		dst = dst[c.OriginalSize:]
+		if _, err := io.ReadFull(reader, part); err != nil {
+			return err
+		}
+		if sum := sha256.Sum256(part); !bytes.Equal(sum[:], c.Hash) {
+			return ErrHashMismatch
+		}
+	}
+	return EnsureNoExtra(reader)
+}
+
+// OpenChunksContext returns the decoded content of chunks [first, last) of
+// a chunked entry, read from the source with a single range read bound to
+// ctx. The chunks are not verified; callers check each against its hash.
+// The reader is meant to back an open file, so its decoder memory stays
+// charged until it is closed.
+func (r *Reader) OpenChunksContext(ctx context.Context, entry *Entry, first, last int) (io.ReadCloser, error) {
+	return r.openChunks(ctx, entry, first, last, true)
+}
+
+// openChunks implements OpenChunksContext; held is passed to entryReader.
+func (r *Reader) openChunks(ctx context.Context, entry *Entry, first, last int, held bool) (io.ReadCloser, error) {
+	if err := ValidateChunks(entry); err != nil {
+		return nil, fmt.Errorf("read %s: %w", entry.Path, err)
+	}
+	if first < 0 || last > len(entry.Chunks) || first >= last {
+		return nil, fmt.Errorf("read %s: invalid chunk range [%d, %d)", entry.Path, first, last)
+	}
+
+	run := Entry{
+		Path:        entry.Path,
+		DataOffset:  entry.DataOffset,
+		Compression: entry.Compression,
+	}
+	for _, c := range entry.Chunks[:first] {
+		run.DataOffset += uint64(c.Size)
+	}
+	for _, c := range entry.Chunks[first:last] {
+		run.DataSize += uint64(c.Size)
+		run.OriginalSize += uint64(c.OriginalSize)
+	}
+	if err := ValidateForRead(&run, r.source.Size(), r.maxFileSize); err != nil {
+		return nil, fmt.Errorf("read %s: %w", entry.Path, err)
+	}
+	if err := ValidateCompression(&run); err != nil {
+		return nil, err
+	}
+
+	bound := *r
+	bound.source = WithContext(ctx, r.source)
+	section, err := bound.sectionReader(&run)
+	if err != nil {
+		return nil, fmt.Errorf("read %s: %w", entry.Path, err)
+	}
+	reader, release, err := bound.entryReader(&run, section, held)
+	if err != nil {
+		return nil, err
+	}
+	return &chunkRun{ctx: ctx, run: run, reader: reader, release: release}, nil
+}
+
+// chunkRun is the decoded stream of a run of chunks. Read errors are
+// reported as for a whole entry read.
+type chunkRun struct {
+	ctx     context.Context
+	run     Entry
+	reader  io.Reader
+	release func()
+	read    uint64
+}
+
+func (c *chunkRun) Read(p []byte) (int, error) {
+	n, err := c.reader.Read(p)
+	c.read += uint64(n)
+	if err == nil || (err == io.EOF && c.read >= c.run.OriginalSize) {
+		return n, err
+	}
+	if c.ctx.Err() != nil {
+		return n, fmt.Errorf("read %s: %w", c.run.Path, c.ctx.Err())
+	}
+	if err == io.EOF {
+		err = io.ErrUnexpectedEOF
+	}
+	return n, mapReadError(&c.run, int(c.read), int(c.run.OriginalSize), err) //nolint:gosec // sizes were validated for reading
+}
+
+// Close releases the decoder.
+func (c *chunkRun) Close() error {
+	c.release()
+	return nil
+}
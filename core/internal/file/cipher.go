@@ -0,0 +1,57 @@
+package file
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"encoding/binary"
+	"fmt"
+)
+
+// NewCipher returns the AEAD for the given encryption scheme and key.
+// AES-GCM accepts 16, 24, or 32 byte keys.
+func NewCipher(scheme Encryption, key []byte) (cipher.AEAD, error) {
+	switch scheme {
+	case EncryptionAESGCM:
+		block, err := aes.NewCipher(key)
+		if err != nil {
+			return nil, fmt.Errorf("aes-gcm: %w", err)
+		}
+		return cipher.NewGCM(block)
+	default:
+		return nil, fmt.Errorf("unsupported encryption scheme: %s", scheme)
+	}
+}
+
+// IsEncrypted reports whether the entry's stored bytes are encrypted.
+func IsEncrypted(entry *Entry) bool {
+	return len(entry.Nonce) > 0
+}
+
+// AssociatedData returns the data an entry's stored bytes are sealed with:
+// the path recorded in the index followed by the big-endian data offset.
+// Sealed bytes moved to another path or offset fail authentication.
+func AssociatedData(entry *Entry) []byte {
+	path := entry.Path
+	if entry.IndexPath != "" {
+		path = entry.IndexPath
+	}
+	return binary.BigEndian.AppendUint64([]byte(path), entry.DataOffset)
+}
+
+// Decrypt authenticates and decrypts an encrypted entry's stored bytes.
+// The result is the entry's compressed (or plain) content. A nil aead, a
+// malformed nonce, or a failed authentication (including bytes sealed for
+// another entry) returns ErrDecryption.
+func Decrypt(aead cipher.AEAD, entry *Entry, data []byte) ([]byte, error) {
+	if aead == nil {
+		return nil, fmt.Errorf("%w: %s: no decryption key", ErrDecryption, entry.Path)
+	}
+	if len(entry.Nonce) != aead.NonceSize() {
+		return nil, fmt.Errorf("%w: %s: invalid nonce length %d", ErrDecryption, entry.Path, len(entry.Nonce))
+	}
+	plain, err := aead.Open(nil, entry.Nonce, data, AssociatedData(entry))
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s", ErrDecryption, entry.Path)
+	}
+	return plain, nil
+}
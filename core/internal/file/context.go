@@ -0,0 +1,88 @@
+package file
+
+import (
+	"context"
+	"io"
+)
+
+// ContextReaderAt is implemented by sources whose reads can be aborted when
+// a context is canceled, such as HTTP range sources.
+type ContextReaderAt interface {
+	ReadAtContext(ctx context.Context, p []byte, off int64) (int, error)
+}
+
+// contextRangeReader is implemented by sources whose range streams can be
+// aborted when a context is canceled.
+type contextRangeReader interface {
+	ReadRangeContext(ctx context.Context, off, length int64) (io.ReadCloser, error)
+}
+
+// ReadAtContext reads from src at off, returning ctx.Err() if ctx is already
+// done. Sources implementing ContextReaderAt also abort in-flight reads.
+func ReadAtContext(ctx context.Context, src io.ReaderAt, p []byte, off int64) (int, error) {
+	if err := ctx.Err(); err != nil {
+		return 0, err
+	}
+	if cr, ok := src.(ContextReaderAt); ok {
+		return cr.ReadAtContext(ctx, p, off)
+	}
+	return src.ReadAt(p, off)
+}
+
+// WithContext returns a ByteSource whose reads are bound to ctx.
+//
+// Reads fail with ctx.Err() once ctx is done. Sources implementing
+// ContextReaderAt (and ReadRangeContext for range streams) also abort
+// reads already in flight. The returned source supports ReadRange only
+// when src does. If ctx can never be canceled, src is returned unchanged.
+func WithContext(ctx context.Context, src ByteSource) ByteSource {
+	if ctx.Done() == nil {
+		return src
+	}
+	bound := contextSource{ctx: ctx, ByteSource: src}
+	if rr, ok := src.(rangeReader); ok {
+		return contextRangeSource{contextSource: bound, rr: rr}
+	}
+	return bound
+}
+
+// contextSource binds a ByteSource's reads to a context.
+type contextSource struct {
+	ByteSource
+	ctx context.Context //nolint:containedctx // binds a source to a single call
+}
+
+// ReadAt implements io.ReaderAt.
+func (s contextSource) ReadAt(p []byte, off int64) (int, error) {
+	return ReadAtContext(s.ctx, s.ByteSource, p, off)
+}
+
+// contextRangeSource binds a ByteSource that supports range streams to a context.
+type contextRangeSource struct {
+	contextSource
+	rr rangeReader
+}
+
+// ReadRange returns a reader for [off, off+length) bound to the context.
+func (s contextRangeSource) ReadRange(off, length int64) (io.ReadCloser, error) {
+	if err := s.ctx.Err(); err != nil {
+		return nil, err
+	}
+	if cr, ok := s.rr.(contextRangeReader); ok {
+		return cr.ReadRangeContext(s.ctx, off, length)
+	}
+	return s.rr.ReadRange(off, length)
+}
+
+// sourceContext returns the context src's reads are bound to, or
+// context.Background if src is not bound by WithContext.
+func sourceContext(src ByteSource) context.Context {
+	switch s := src.(type) {
+	case contextSource:
+		return s.ctx
+	case contextRangeSource:
+		return s.ctx
+	default:
+		return context.Background()
+	}
+}
@@ -0,0 +1,141 @@
+package file
+
+import (
+	"context"
+	"math"
+	"sync"
+)
+
+// decoderOverhead approximates the fixed buffers a zstd decoder allocates
+// in addition to its window.
+const decoderOverhead = 128 << 10
+
+// decoderBudget bounds the memory held by active decoders across every
+// DecompressPool in the process.
+//
+// Charges taken for open files are tracked separately as held: they last
+// until the caller closes the file, possibly after opening others, so a
+// request that only fits once held charges are released fails instead of
+// waiting for them.
+type decoderBudget struct {
+	mu      sync.Mutex
+	changed chan struct{} // closed and replaced whenever room may have freed
+	limit   uint64        // 0 means unlimited
+	used    uint64
+	held    uint64
+	peak    uint64
+}
+
+var globalDecoderBudget = newDecoderBudget()
+
+func newDecoderBudget() *decoderBudget {
+	return &decoderBudget{changed: make(chan struct{})}
+}
+
+// SetDecoderMemoryLimit sets the process-wide limit on estimated memory
+// held by active zstd decoders. A limit of 0 removes the limit.
+func SetDecoderMemoryLimit(limit uint64) {
+	globalDecoderBudget.setLimit(limit)
+}
+
+// DecoderMemoryInUse returns the estimated memory currently charged to
+// active zstd decoders.
+func DecoderMemoryInUse() uint64 {
+	globalDecoderBudget.mu.Lock()
+	defer globalDecoderBudget.mu.Unlock()
+	return globalDecoderBudget.used
+}
+
+// ChargeDecoderMemory charges a decoder for content of the given size
+// against the global decoder memory limit without waiting, returning the
+// function that releases the charge.
+//
+// It is for decoders created inside a read that may already hold a
+// charge, such as a transport decoding a compressed response: waiting
+// there could block on the caller's own charge. The charge still counts
+// toward the limit, so other decodes wait for it to be released.
+func ChargeDecoderMemory(size uint64) (release func()) {
+	return globalDecoderBudget.charge((*DecompressPool)(nil).decoderCharge(size), false)
+}
+
+func (b *decoderBudget) setLimit(limit uint64) {
+	b.mu.Lock()
+	b.limit = limit
+	b.notifyLocked()
+	b.mu.Unlock()
+}
+
+// notifyLocked wakes every waiter. b.mu must be held.
+func (b *decoderBudget) notifyLocked() {
+	close(b.changed)
+	b.changed = make(chan struct{})
+}
+
+// acquire waits until n bytes fit within the limit and charges them,
+// returning the function that releases the charge. Requests larger than
+// the limit are clamped so they can proceed once nothing else is charged.
+// A held charge lasts until an open file is closed.
+//
+// acquire returns ErrDecoderMemoryLimit rather than waiting when held
+// charges alone leave no room for n, since the goroutine waiting may be
+// the one holding them. It returns ctx.Err() if ctx is done first.
+func (b *decoderBudget) acquire(ctx context.Context, n uint64, held bool) (release func(), err error) {
+	b.mu.Lock()
+	if b.limit > 0 && n > b.limit {
+		n = b.limit
+	}
+	for b.limit > 0 && b.used > 0 && b.used+n > b.limit {
+		if b.held > 0 && b.held+n > b.limit {
+			b.mu.Unlock()
+			return nil, ErrDecoderMemoryLimit
+		}
+		changed := b.changed
+		b.mu.Unlock()
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-changed:
+		}
+		b.mu.Lock()
+	}
+	b.mu.Unlock()
+	return b.charge(n, held), nil
+}
+
+// charge adds n bytes to the budget unconditionally and returns the
+// function that releases them.
+func (b *decoderBudget) charge(n uint64, held bool) (release func()) {
+	b.mu.Lock()
+	b.used += n
+	if held {
+		b.held += n
+	}
+	b.peak = max(b.peak, b.used)
+	b.mu.Unlock()
+
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			b.mu.Lock()
+			b.used -= n
+			if held {
+				b.held -= n
+			}
+			b.notifyLocked()
+			b.mu.Unlock()
+		})
+	}
+}
+
+// decoderCharge estimates the memory a decoder needs for content of the
+// given uncompressed size. The window never exceeds the content size or
+// the pool's decoder memory limit.
+func (p *DecompressPool) decoderCharge(size uint64) uint64 {
+	if p != nil && p.maxDecoderMemory != 0 {
+		size = min(size, p.maxDecoderMemory)
+	}
+	if size > math.MaxUint64-decoderOverhead {
+		return math.MaxUint64
+	}
+	return size + decoderOverhead
+}
@@ -0,0 +1,183 @@
+package file
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"sync"
+	"testing"
+)
+
+func TestDecoderBudget_BoundsConcurrentCharges(t *testing.T) {
+	t.Parallel()
+
+	const (
+		limit  = 1000
+		charge = 300
+	)
+	b := newDecoderBudget()
+	b.setLimit(limit)
+
+	var wg sync.WaitGroup
+	for range 32 {
+		wg.Go(func() {
+			release, err := b.acquire(context.Background(), charge, false)
+			if err != nil {
+				t.Errorf("acquire() error = %v", err)
+				return
+			}
+			defer release()
+			b.mu.Lock()
+			used := b.used
+			b.mu.Unlock()
+			if used > limit {
+				t.Errorf("used = %d, want <= %d", used, limit)
+			}
+		})
+	}
+	wg.Wait()
+
+	if b.peak > limit {
+		t.Errorf("peak = %d, want <= %d", b.peak, limit)
+	}
+	if b.used != 0 {
+		t.Errorf("used after release = %d, want 0", b.used)
+	}
+}
+
+func TestDecoderBudget_ClampsOversizedCharge(t *testing.T) {
+	t.Parallel()
+
+	b := newDecoderBudget()
+	b.setLimit(100)
+
+	release, err := b.acquire(context.Background(), 1<<20, false)
+	if err != nil {
+		t.Fatalf("acquire() error = %v", err)
+	}
+	if b.used != 100 {
+		t.Errorf("used = %d, want 100", b.used)
+	}
+	release()
+	release() // releasing twice must not underflow
+	if b.used != 0 {
+		t.Errorf("used after release = %d, want 0", b.used)
+	}
+}
+
+func TestDecoderBudget_RaisingLimitWakesWaiters(t *testing.T) {
+	t.Parallel()
+
+	b := newDecoderBudget()
+	b.setLimit(100)
+	release, err := b.acquire(context.Background(), 100, false)
+	if err != nil {
+		t.Fatalf("acquire() error = %v", err)
+	}
+
+	done := make(chan error)
+	go func() {
+		release, err := b.acquire(context.Background(), 100, false)
+		if err == nil {
+			release()
+		}
+		done <- err
+	}()
+
+	b.setLimit(0)
+	if err := <-done; err != nil {
+		t.Errorf("acquire() error = %v", err)
+	}
+	release()
+}
+
+func TestDecoderBudget_WaitStopsOnCancel(t *testing.T) {
+	t.Parallel()
+
+	b := newDecoderBudget()
+	b.setLimit(100)
+	release, err := b.acquire(context.Background(), 100, false)
+	if err != nil {
+		t.Fatalf("acquire() error = %v", err)
+	}
+	defer release()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error)
+	go func() {
+		_, err := b.acquire(ctx, 100, false)
+		done <- err
+	}()
+
+	cancel()
+	if err := <-done; !errors.Is(err, context.Canceled) {
+		t.Errorf("acquire() error = %v, want %v", err, context.Canceled)
+	}
+}
+
+func TestDecoderBudget_HeldChargesFailInsteadOfWaiting(t *testing.T) {
+	t.Parallel()
+
+	b := newDecoderBudget()
+	b.setLimit(100)
+	release, err := b.acquire(context.Background(), 60, true)
+	if err != nil {
+		t.Fatalf("acquire() error = %v", err)
+	}
+
+	if _, err := b.acquire(context.Background(), 60, true); !errors.Is(err, ErrDecoderMemoryLimit) {
+		t.Errorf("acquire() error = %v, want %v", err, ErrDecoderMemoryLimit)
+	}
+	if _, err := b.acquire(context.Background(), 60, false); !errors.Is(err, ErrDecoderMemoryLimit) {
+		t.Errorf("short acquire() error = %v, want %v", err, ErrDecoderMemoryLimit)
+	}
+
+	release()
+	release, err = b.acquire(context.Background(), 60, true)
+	if err != nil {
+		t.Fatalf("acquire() after release error = %v", err)
+	}
+	release()
+}
+
+func TestChargeDecoderMemory_DoesNotWait(t *testing.T) {
+	t.Parallel()
+
+	b := newDecoderBudget()
+	b.setLimit(100)
+	release, err := b.acquire(context.Background(), 100, false)
+	if err != nil {
+		t.Fatalf("acquire() error = %v", err)
+	}
+	extra := b.charge(50, false)
+	if b.used != 150 {
+		t.Errorf("used = %d, want 150", b.used)
+	}
+	extra()
+	release()
+	if b.used != 0 {
+		t.Errorf("used after release = %d, want 0", b.used)
+	}
+}
+
+func TestDecompressPool_GetSized(t *testing.T) {
+	t.Parallel()
+
+	original := bytes.Repeat([]byte("sized decode "), 100)
+	compressed := compressData(t, original)
+	pool := NewDecompressPool(0)
+
+	dec, release, err := pool.GetSized(bytes.NewReader(compressed), uint64(len(original)))
+	if err != nil {
+		t.Fatalf("GetSized() error = %v", err)
+	}
+	result, err := io.ReadAll(dec)
+	release()
+	if err != nil {
+		t.Fatalf("ReadAll() error = %v", err)
+	}
+	if !bytes.Equal(result, original) {
+		t.Errorf("decoded %d bytes, want %d", len(result), len(original))
+	}
+}
@@ -1,7 +1,9 @@
 package file
 
 import (
+	"context"
 	"io"
+	"math"
 	"sync"
 
 	"github.com/klauspost/compress/zstd"
@@ -15,6 +17,7 @@ type DecompressPool struct {
 	decoderConcurrency    int
 	decoderLowmemSet      bool
 	decoderLowmem         bool
+	dicts                 [][]byte
 }
 
 // decompressOption configures a DecompressPool.
@@ -39,6 +42,13 @@ func withDecoderLowmem(b bool) decompressOption {
 	}
 }
 
+// withDecoderDicts registers zstd dictionaries with every decoder.
+func withDecoderDicts(dicts ...[]byte) decompressOption {
+	return func(p *DecompressPool) {
+		p.dicts = append(p.dicts, dicts...)
+	}
+}
+
 // NewDecompressPool creates a new pool for zstd decoders.
 // If maxMemory is 0, no memory limit is applied to decoders.
 func NewDecompressPool(maxMemory uint64, opts ...decompressOption) *DecompressPool {
@@ -67,7 +77,50 @@ func NewDecompressPool(maxMemory uint64, opts ...decompressOption) *DecompressPo
 // Get returns a decoder configured to read from r.
 // The caller must call the returned release function when done.
 // If an error is returned, no release function needs to be called.
+//
+// The content size is unknown, so the decoder is charged against the global
+// decoder memory limit at the pool's maximum decoder memory. Prefer GetSized
+// when the uncompressed size is known.
 func (p *DecompressPool) Get(r io.Reader) (*zstd.Decoder, func(), error) {
+	return p.GetSized(r, math.MaxUint64)
+}
+
+// GetSized is like Get for content of the given uncompressed size.
+//
+// When a global decoder memory limit is set (see SetDecoderMemoryLimit),
+// GetSized blocks until the decoder's estimated memory fits within it. The
+// charge is released by the returned release function.
+func (p *DecompressPool) GetSized(r io.Reader, size uint64) (*zstd.Decoder, func(), error) {
+	return p.GetSizedContext(context.Background(), r, size)
+}
+
+// GetSizedContext is like GetSized but stops waiting for decoder memory
+// when ctx is done, returning ctx.Err(). It returns ErrDecoderMemoryLimit
+// instead of waiting when open files hold the memory the decoder needs.
+func (p *DecompressPool) GetSizedContext(ctx context.Context, r io.Reader, size uint64) (*zstd.Decoder, func(), error) {
+	return p.getCharged(ctx, r, size, false)
+}
+
+// getCharged returns a decoder for r after charging its estimated memory.
+// A held charge belongs to an open file and lasts until the file is closed.
+func (p *DecompressPool) getCharged(ctx context.Context, r io.Reader, size uint64, held bool) (*zstd.Decoder, func(), error) {
+	releaseMem, err := globalDecoderBudget.acquire(ctx, p.decoderCharge(size), held)
+	if err != nil {
+		return nil, nil, err
+	}
+	dec, release, err := p.get(r)
+	if err != nil {
+		releaseMem()
+		return nil, nil, err
+	}
+	return dec, func() {
+		release()
+		releaseMem()
+	}, nil
+}
+
+// get returns a pooled or new decoder configured to read from r.
+func (p *DecompressPool) get(r io.Reader) (*zstd.Decoder, func(), error) {
 	if p == nil || p.pool == nil {
 		// No pool available, create a one-off decoder
 		dec, err := p.newDecoder(r)
@@ -120,7 +173,7 @@ func (p *DecompressPool) newDecoder(r io.Reader) (*zstd.Decoder, error) {
 		return zstd.NewReader(r)
 	}
 
-	opts := make([]zstd.DOption, 0, 3)
+	opts := make([]zstd.DOption, 0, 4)
 	if p.decoderConcurrencySet {
 		opts = append(opts, zstd.WithDecoderConcurrency(p.decoderConcurrency))
 	}
@@ -130,6 +183,9 @@ func (p *DecompressPool) newDecoder(r io.Reader) (*zstd.Decoder, error) {
 	if p.maxDecoderMemory != 0 {
 		opts = append(opts, zstd.WithDecoderMaxMemory(p.maxDecoderMemory))
 	}
+	if len(p.dicts) > 0 {
+		opts = append(opts, zstd.WithDecoderDicts(p.dicts...))
+	}
 	if len(opts) == 0 {
 		return zstd.NewReader(r)
 	}
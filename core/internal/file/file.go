@@ -17,6 +17,7 @@ type File struct {
 	reader        *Reader
 	entry         Entry
 	verifyOnClose bool
+	rangeStream   bool
 
 	r         io.Reader
 	release   func()
@@ -41,6 +42,15 @@ func (r *Reader) OpenFile(entry *Entry, verifyOnClose bool) *File {
 	}
 }
 
+// OpenStream creates a File that always verifies its hash on Close.
+// Uncompressed entries are read with a single range request when the
+// source supports it, rather than through ReadAt calls.
+func (r *Reader) OpenStream(entry *Entry) *File {
+	f := r.OpenFile(entry, true)
+	f.rangeStream = true
+	return f
+}
+
 // Read implements io.Reader with incremental hash verification.
 func (f *File) Read(p []byte) (int, error) {
 	if err := f.init(); err != nil {
@@ -101,6 +111,9 @@ func (f *File) ReadAt(p []byte, off int64) (int, error) {
 	if f.entry.Compression != CompressionNone {
 		return 0, fmt.Errorf("read at %s: unsupported compression", f.entry.Path)
 	}
+	if IsEncrypted(&f.entry) {
+		return 0, fmt.Errorf("read at %s: unsupported encryption", f.entry.Path)
+	}
 
 	size, err := sizing.ToInt64(f.entry.OriginalSize, ErrSizeOverflow)
 	if err != nil {
@@ -197,7 +210,7 @@ func (f *File) init() error {
 		return f.initErr
 	}
 
-	rd, release, err := f.reader.entryReader(&f.entry, section)
+	rd, release, err := f.streamReader(section)
 	if err != nil {
 		f.initErr = err
 		return f.initErr
@@ -211,6 +224,21 @@ func (f *File) init() error {
 	return nil
 }
 
+// streamReader returns the entry reader, preferring a single range request
+// for uncompressed entries opened with OpenStream.
+func (f *File) streamReader(section *io.SectionReader) (io.Reader, func(), error) {
+	if f.rangeStream && f.entry.Compression == CompressionNone && !IsEncrypted(&f.entry) {
+		if rr, ok := f.reader.source.(rangeReader); ok {
+			rc, err := f.reader.rangeReader(&f.entry, rr)
+			if err != nil {
+				return nil, func() {}, fmt.Errorf("read %s: %w", f.entry.Path, err)
+			}
+			return rc, func() { _ = rc.Close() }, nil
+		}
+	}
+	return f.reader.entryReader(&f.entry, section, true)
+}
+
 // readExtra checks for unexpected extra data after the expected content.
 func (f *File) readExtra() (int, error) {
 	var scratch [1]byte
@@ -271,8 +299,8 @@ func (fi *Info) Mode() fs.FileMode { return fi.entry.Mode }
 // ModTime returns the file modification time.
 func (fi *Info) ModTime() time.Time { return fi.entry.ModTime }
 
-// IsDir returns false since Info represents a regular file.
-func (fi *Info) IsDir() bool { return false }
+// IsDir reports whether the entry is an explicit directory entry.
+func (fi *Info) IsDir() bool { return fi.entry.Mode.IsDir() }
 
 // Sys returns nil; no underlying data source is available.
 func (fi *Info) Sys() any { return nil }
@@ -2,7 +2,10 @@ package file
 
 import (
 	"bytes"
+	"context"
+	"crypto/cipher"
 	"crypto/sha256"
+	"errors"
 	"fmt"
 	"io"
 
@@ -38,6 +41,8 @@ type Reader struct {
 	decoderConcurrency    int
 	decoderLowmemSet      bool
 	decoderLowmem         bool
+	dictionary            []byte
+	aead                  cipher.AEAD
 	pool                  *DecompressPool
 }
 
@@ -80,6 +85,20 @@ func WithDecoderLowmem(enabled bool) Option {
 	}
 }
 
+// WithDictionary sets the zstd dictionary used to decode compressed entries.
+func WithDictionary(dict []byte) Option {
+	return func(r *Reader) {
+		r.dictionary = dict
+	}
+}
+
+// WithCipher sets the AEAD used to decrypt encrypted entries.
+func WithCipher(aead cipher.AEAD) Option {
+	return func(r *Reader) {
+		r.aead = aead
+	}
+}
+
 // NewReader creates a Reader for reading files from the given source.
 func NewReader(source ByteSource, opts ...Option) *Reader {
 	r := &Reader{
@@ -90,13 +109,16 @@ func NewReader(source ByteSource, opts ...Option) *Reader {
 	for _, opt := range opts {
 		opt(r)
 	}
-	poolOpts := make([]decompressOption, 0, 2)
+	poolOpts := make([]decompressOption, 0, 3)
 	if r.decoderConcurrencySet {
 		poolOpts = append(poolOpts, withDecoderConcurrency(r.decoderConcurrency))
 	}
 	if r.decoderLowmemSet {
 		poolOpts = append(poolOpts, withDecoderLowmem(r.decoderLowmem))
 	}
+	if len(r.dictionary) > 0 {
+		poolOpts = append(poolOpts, withDecoderDicts(r.dictionary))
+	}
 	r.pool = NewDecompressPool(r.maxDecoderMemory, poolOpts...)
 	return r
 }
@@ -113,7 +135,7 @@ func (r *Reader) ReadAll(entry *Entry) ([]byte, error) {
 		return nil, fmt.Errorf("read %s: %w", entry.Path, err)
 	}
 
-	reader, release, err := r.entryReader(entry, section)
+	reader, release, err := r.entryReader(entry, section, false)
 	if err != nil {
 		return nil, err
 	}
@@ -131,6 +153,86 @@ func (r *Reader) ReadAll(entry *Entry) ([]byte, error) {
 	return content, nil
 }
 
+// ReadAllContext is like ReadAll but binds reads from the source to ctx.
+// If ctx is canceled mid-read, the returned error wraps ctx.Err().
+func (r *Reader) ReadAllContext(ctx context.Context, entry *Entry) ([]byte, error) {
+	if ctx.Done() == nil {
+		return r.ReadAll(entry)
+	}
+	if err := ctx.Err(); err != nil {
+		return nil, fmt.Errorf("read %s: %w", entry.Path, err)
+	}
+	bound := *r
+	bound.source = WithContext(ctx, r.source)
+	content, err := bound.ReadAll(entry)
+	if err != nil && ctx.Err() != nil {
+		return nil, fmt.Errorf("read %s: %w", entry.Path, ctx.Err())
+	}
+	return content, err
+}
+
+// ReadRange reads length bytes starting at off from an uncompressed entry.
+// The range is clamped to the entry size; an offset at or past the end
+// returns an empty slice. The content is not hash verified.
+func (r *Reader) ReadRange(entry *Entry, off, length int64) ([]byte, error) {
+	if entry.Compression != CompressionNone {
+		return nil, fmt.Errorf("read range %s: compressed entry", entry.Path)
+	}
+	if err := ValidateForRead(entry, r.source.Size(), 0); err != nil {
+		return nil, fmt.Errorf("read range %s: %w", entry.Path, err)
+	}
+	if err := ValidateCompression(entry); err != nil {
+		return nil, err
+	}
+
+	size, err := sizing.ToInt64(entry.OriginalSize, ErrSizeOverflow)
+	if err != nil {
+		return nil, fmt.Errorf("read range %s: %w", entry.Path, err)
+	}
+	if off >= size || length == 0 {
+		return []byte{}, nil
+	}
+	length = min(length, size-off)
+	if r.maxFileSize > 0 && uint64(length) > r.maxFileSize { //nolint:gosec // length is positive
+		return nil, fmt.Errorf("read range %s: %w", entry.Path, ErrSizeOverflow)
+	}
+	start := int64(entry.DataOffset) + off //nolint:gosec // bounds checked by ValidateForRead
+
+	// Encrypted entries can only be authenticated as a whole.
+	if IsEncrypted(entry) {
+		section, err := r.sectionReader(entry)
+		if err != nil {
+			return nil, fmt.Errorf("read range %s: %w", entry.Path, err)
+		}
+		plain, err := r.decrypt(entry, section)
+		if err != nil {
+			return nil, err
+		}
+		if uint64(len(plain)) != entry.OriginalSize {
+			return nil, fmt.Errorf("%w: size mismatch", ErrDecompression)
+		}
+		return plain[off : off+length], nil
+	}
+
+	content := make([]byte, length)
+	if rr, ok := r.source.(rangeReader); ok {
+		rc, err := rr.ReadRange(start, length)
+		if err != nil {
+			return nil, fmt.Errorf("read range %s: %w", entry.Path, err)
+		}
+		defer rc.Close()
+		if _, err := io.ReadFull(rc, content); err != nil {
+			return nil, fmt.Errorf("read range %s: %w", entry.Path, err)
+		}
+		return content, nil
+	}
+	// ReaderAt may return io.EOF alongside a full read at the end of the source.
+	if n, err := r.source.ReadAt(content, start); err != nil && n < len(content) {
+		return nil, fmt.Errorf("read range %s: %w", entry.Path, err)
+	}
+	return content, nil
+}
+
 // Source returns the underlying ByteSource.
 func (r *Reader) Source() ByteSource {
 	return r.source
@@ -141,6 +243,11 @@ func (r *Reader) MaxFileSize() uint64 {
 	return r.maxFileSize
 }
 
+// Cipher returns the AEAD used to decrypt encrypted entries, or nil.
+func (r *Reader) Cipher() cipher.AEAD {
+	return r.aead
+}
+
 // Pool returns the decompression pool for reuse.
 func (r *Reader) Pool() *DecompressPool {
 	return r.pool
@@ -160,7 +267,17 @@ func (r *Reader) sectionReader(entry *Entry) (*io.SectionReader, error) {
 }
 
 // entryReader creates the appropriate reader for an entry based on compression.
-func (r *Reader) entryReader(entry *Entry, section *io.SectionReader) (io.Reader, func(), error) {
+// Encrypted entries are read and decrypted in full before decoding. held
+// marks a reader kept by an open file, whose decoder memory stays charged
+// until the file is closed.
+func (r *Reader) entryReader(entry *Entry, section *io.SectionReader, held bool) (io.Reader, func(), error) {
+	if IsEncrypted(entry) {
+		plain, err := r.decrypt(entry, section)
+		if err != nil {
+			return nil, func() {}, err
+		}
+		return r.decoder(entry, bytes.NewReader(plain), held)
+	}
 	switch entry.Compression {
 	case CompressionNone:
 		return section, func() {}, nil
@@ -170,19 +287,32 @@ func (r *Reader) entryReader(entry *Entry, section *io.SectionReader) (io.Reader
 			if err != nil {
 				return nil, func() {}, fmt.Errorf("%w: %v", ErrDecompression, err)
 			}
-			dec, release, err := r.pool.Get(reader)
+			dec, release, err := r.pool.getCharged(sourceContext(r.source), reader, entry.OriginalSize, held)
 			if err != nil {
 				_ = reader.Close()
-				return nil, func() {}, fmt.Errorf("%w: %v", ErrDecompression, err)
+				return nil, func() {}, decoderError(entry, err)
 			}
 			return dec, func() {
 				release()
 				_ = reader.Close()
 			}, nil
 		}
-		dec, release, err := r.pool.Get(section)
+		return r.decoder(entry, section, held)
+	default:
+		return nil, func() {}, fmt.Errorf("unknown compression algorithm: %d", entry.Compression)
+	}
+}
+
+// decoder wraps src, which holds an entry's stored (unencrypted) bytes,
+// with the decoder for the entry's compression.
+func (r *Reader) decoder(entry *Entry, src io.Reader, held bool) (io.Reader, func(), error) {
+	switch entry.Compression {
+	case CompressionNone:
+		return src, func() {}, nil
+	case CompressionZstd:
+		dec, release, err := r.pool.getCharged(sourceContext(r.source), src, entry.OriginalSize, held)
 		if err != nil {
-			return nil, func() {}, fmt.Errorf("%w: %v", ErrDecompression, err)
+			return nil, func() {}, decoderError(entry, err)
 		}
 		return dec, release, nil
 	default:
@@ -190,6 +320,30 @@ func (r *Reader) entryReader(entry *Entry, section *io.SectionReader) (io.Reader
 	}
 }
 
+// decoderError reports a failure to obtain a decoder for entry. Waiting for
+// decoder memory that was cut short is reported as is; anything else is a
+// decompression failure.
+func decoderError(entry *Entry, err error) error {
+	if errors.Is(err, ErrDecoderMemoryLimit) || errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return fmt.Errorf("read %s: %w", entry.Path, err)
+	}
+	return fmt.Errorf("%w: %v", ErrDecompression, err)
+}
+
+// decrypt reads an encrypted entry's stored bytes from section in full and
+// decrypts them.
+func (r *Reader) decrypt(entry *Entry, section *io.SectionReader) ([]byte, error) {
+	size, err := sizing.ToInt(entry.DataSize, ErrSizeOverflow)
+	if err != nil {
+		return nil, fmt.Errorf("read %s: %w", entry.Path, err)
+	}
+	data := make([]byte, size)
+	if _, err := io.ReadFull(section, data); err != nil {
+		return nil, fmt.Errorf("read %s: %w", entry.Path, err)
+	}
+	return Decrypt(r.aead, entry, data)
+}
+
 func (r *Reader) rangeReader(entry *Entry, rr rangeReader) (io.ReadCloser, error) {
 	offset, err := sizing.ToInt64(entry.DataOffset, ErrSizeOverflow)
 	if err != nil {
@@ -6,6 +6,7 @@ import "github.com/meigma/blob/core/internal/blobtype"
 type (
 	Entry       = blobtype.Entry
 	Compression = blobtype.Compression
+	Encryption  = blobtype.Encryption
 )
 
 // Re-export compression constants.
@@ -14,9 +15,17 @@ const (
 	CompressionZstd = blobtype.CompressionZstd
 )
 
+// Re-export encryption constants.
+const (
+	EncryptionNone   = blobtype.EncryptionNone
+	EncryptionAESGCM = blobtype.EncryptionAESGCM
+)
+
 // Re-export sentinel errors.
 var (
-	ErrHashMismatch  = blobtype.ErrHashMismatch
-	ErrDecompression = blobtype.ErrDecompression
-	ErrSizeOverflow  = blobtype.ErrSizeOverflow
+	ErrHashMismatch       = blobtype.ErrHashMismatch
+	ErrDecompression      = blobtype.ErrDecompression
+	ErrDecryption         = blobtype.ErrDecryption
+	ErrSizeOverflow       = blobtype.ErrSizeOverflow
+	ErrDecoderMemoryLimit = blobtype.ErrDecoderMemoryLimit
 )
@@ -44,9 +44,9 @@ func ValidateHash(entry *Entry) error {
 }
 
 // ValidateCompression checks that compression metadata is consistent.
-// For uncompressed files, DataSize must equal OriginalSize.
+// For uncompressed, unencrypted files, DataSize must equal OriginalSize.
 func ValidateCompression(entry *Entry) error {
-	if entry.Compression == CompressionNone && entry.DataSize != entry.OriginalSize {
+	if entry.Compression == CompressionNone && !IsEncrypted(entry) && entry.DataSize != entry.OriginalSize {
 		return fmt.Errorf("%w: size mismatch", ErrDecompression)
 	}
 	return nil
@@ -0,0 +1,112 @@
+package index
+
+import (
+	"errors"
+
+	"github.com/cespare/xxhash/v2"
+)
+
+// Bloom filter parameters used by NewBloomFilter. Ten bits per entry with
+// seven hash functions gives a false positive rate of about 1%.
+const (
+	bloomBitsPerEntry = 10
+	bloomHashes       = 7
+
+	// maxBloomHashes bounds the hash count accepted from an index, so a
+	// corrupt filter cannot make each lookup arbitrarily expensive.
+	maxBloomHashes = 32
+)
+
+// errInvalidBloomFilter is returned by Load for a malformed bloom filter.
+var errInvalidBloomFilter = errors.New("blob: invalid index bloom filter")
+
+// bloomFilter answers whether a path may be in the index.
+//
+// The encoded form is one byte holding the number of hash functions k,
+// followed by the bit array; bit j is bit j%8 of byte 1+j/8. Bit positions
+// for a path come from double hashing its xxh64 digest.
+type bloomFilter struct {
+	bits []byte // aliases the index buffer
+	m    uint64 // number of bits; 0 = no filter
+	k    uint64
+}
+
+// NewBloomFilter returns the encoded bloom filter for paths, for storing in
+// the index.
+func NewBloomFilter(paths []string) []byte {
+	b := NewBloomBuilder(len(paths))
+	for _, p := range paths {
+		b.Add(p)
+	}
+	return b.Bytes()
+}
+
+// BloomBuilder builds the same filter as NewBloomFilter one path at a time,
+// for callers that do not hold all paths at once.
+type BloomBuilder struct {
+	data []byte
+	f    bloomFilter
+}
+
+// NewBloomBuilder returns a BloomBuilder sized for n paths.
+func NewBloomBuilder(n int) *BloomBuilder {
+	m := uint64(max(n*bloomBitsPerEntry, 64))
+	m = (m + 7) &^ 7
+	data := make([]byte, 1+m/8)
+	data[0] = bloomHashes
+	return &BloomBuilder{data: data, f: bloomFilter{bits: data[1:], m: m, k: bloomHashes}}
+}
+
+// Add adds path to the filter.
+func (b *BloomBuilder) Add(path string) {
+	b.f.add(path)
+}
+
+// Bytes returns the encoded filter.
+func (b *BloomBuilder) Bytes() []byte {
+	return b.data
+}
+
+// parseBloomFilter decodes an encoded filter. An empty input yields the
+// zero filter, which reports every path as possibly present.
+func parseBloomFilter(data []byte) (bloomFilter, error) {
+	if len(data) == 0 {
+		return bloomFilter{}, nil
+	}
+	k := uint64(data[0])
+	if len(data) < 2 || k == 0 || k > maxBloomHashes {
+		return bloomFilter{}, errInvalidBloomFilter
+	}
+	return bloomFilter{bits: data[1:], m: uint64(len(data)-1) * 8, k: k}, nil
+}
+
+func (f *bloomFilter) add(path string) {
+	h1, h2 := bloomHash(path)
+	for i := range f.k {
+		pos := (h1 + i*h2) % f.m
+		f.bits[pos/8] |= 1 << (pos % 8)
+	}
+}
+
+// mayContain reports whether path may be present. False means the path is
+// definitely absent.
+func (f *bloomFilter) mayContain(path string) bool {
+	if f.m == 0 {
+		return true
+	}
+	h1, h2 := bloomHash(path)
+	for i := range f.k {
+		pos := (h1 + i*h2) % f.m
+		if f.bits[pos/8]&(1<<(pos%8)) == 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// bloomHash splits the xxh64 digest of path into the two hashes used for
+// double hashing. The second is forced odd so it never degenerates to zero.
+func bloomHash(path string) (h1, h2 uint64) {
+	sum := xxhash.Sum64String(path)
+	return sum & 0xffffffff, sum>>32 | 1
+}
@@ -0,0 +1,68 @@
+package index
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"os"
+	"runtime"
+
+	"github.com/meigma/blob/core/internal/platform"
+)
+
+// LoadFromFile loads the index blob stored at path by memory-mapping it.
+//
+// FlatBuffers reads go directly to the mapping, so the index is not copied
+// onto the heap and its pages are loaded by the kernel as lookups touch
+// them. The mapping is released once the returned Index is unreachable;
+// as with Load, views and slices taken from the index are only valid while
+// it is alive. The file must not be modified or truncated while it is
+// mapped. On platforms without memory mapping the file is read into memory
+// instead.
+func LoadFromFile(path string, opts ...LoadOption) (*Index, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	info, err := f.Stat()
+	if err != nil {
+		return nil, fmt.Errorf("stat %s: %w", path, err)
+	}
+	size := info.Size()
+	if size == 0 {
+		return Load(nil, opts...)
+	}
+	if size > math.MaxInt {
+		return nil, fmt.Errorf("mmap %s: file too large (%d bytes)", path, size)
+	}
+
+	data, err := platform.MapFile(f, int(size))
+	if errors.Is(err, errors.ErrUnsupported) {
+		data, err = os.ReadFile(path)
+		if err != nil {
+			return nil, err
+		}
+		return Load(data, opts...)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("mmap %s: %w", path, err)
+	}
+
+	idx, err := Load(data, opts...)
+	if err != nil {
+		_ = platform.Unmap(data) //nolint:errcheck // the load error is reported
+		return nil, err
+	}
+	runtime.AddCleanup(idx, func(data []byte) {
+		_ = platform.Unmap(data) //nolint:errcheck // nothing to report to
+	}, data)
+	return idx, nil
+}
+
+// Data returns the encoded index blob the index was loaded from.
+// The returned slice aliases the index buffer and must be treated as immutable.
+func (idx *Index) Data() []byte {
+	return idx.data
+}
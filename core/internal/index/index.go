@@ -18,15 +18,53 @@ import (
 //
 // Accessors return read-only EntryView values that alias index data.
 type Index struct {
-	data []byte
-	root *fb.Index
+	data  []byte
+	root  *fb.Index
+	bloom bloomFilter
+}
+
+// Index format versions.
+const (
+	// CurrentVersion is the format version written by this package.
+	CurrentVersion uint32 = 1
+
+	// MinVersion is the oldest format version this package can read.
+	MinVersion uint32 = 1
+)
+
+// LoadOption configures Load.
+type LoadOption func(*loadConfig)
+
+type loadConfig struct {
+	maxVersion uint32
+}
+
+// WithMaxVersion sets the newest format version Load accepts (default:
+// CurrentVersion).
+//
+// Newer versions are only readable when their changes are additive:
+// FlatBuffers readers ignore fields they do not know, so an older reader
+// sees the fields it understands. Raising the limit is an explicit opt-in
+// to that compatibility.
+func WithMaxVersion(v uint32) LoadOption {
+	return func(cfg *loadConfig) {
+		cfg.maxVersion = v
+	}
 }
 
 // Load parses a FlatBuffers-encoded index blob.
 //
+// Indexes declaring a version below MinVersion or above the configured
+// maximum are rejected with a *blobtype.IndexVersionError.
+//
 // The provided data is retained by the index; callers must not modify it
 // after calling Load.
-func Load(data []byte) (idx *Index, err error) {
+func Load(data []byte, opts ...LoadOption) (idx *Index, err error) {
+	cfg := loadConfig{maxVersion: CurrentVersion}
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+
 	defer func() {
 		if r := recover(); r != nil {
 			idx = nil
@@ -41,10 +79,19 @@ func Load(data []byte) (idx *Index, err error) {
 	if root == nil {
 		return nil, errors.New("blob: failed to parse index")
 	}
+	if v := root.Version(); v < MinVersion || v > cfg.maxVersion {
+		return nil, &blobtype.IndexVersionError{Version: v, MinVersion: MinVersion, MaxVersion: cfg.maxVersion}
+	}
+
+	bloom, err := parseBloomFilter(root.BloomFilterBytes())
+	if err != nil {
+		return nil, err
+	}
 
 	return &Index{
-		data: data,
-		root: root,
+		data:  data,
+		root:  root,
+		bloom: bloom,
 	}, nil
 }
 
@@ -72,10 +119,44 @@ func (idx *Index) DataSize() (uint64, bool) {
 	return idx.root.DataSize(), true
 }
 
+// ZstdDictionaryHash returns the SHA-256 of the zstd dictionary shared by
+// compressed entries. The returned slice aliases the index buffer and must
+// be treated as immutable. ok is false when the archive was created without
+// a dictionary.
+func (idx *Index) ZstdDictionaryHash() ([]byte, bool) {
+	hash := idx.root.ZstdDictionaryHashBytes()
+	if len(hash) == 0 {
+		return nil, false
+	}
+	return hash, true
+}
+
+// Encryption returns the scheme used to encrypt entry content.
+func (idx *Index) Encryption() blobtype.Encryption {
+	return blobtype.EncryptionFromFB(idx.root.Encryption())
+}
+
+// AuxChecksum returns the algorithm of the per-entry auxiliary checksums,
+// or AuxChecksumNone when the index records none.
+func (idx *Index) AuxChecksum() blobtype.AuxChecksum {
+	return blobtype.AuxChecksumFromFB(idx.root.AuxChecksum())
+}
+
+// HasBloomFilter reports whether the index carries a bloom filter over its
+// paths.
+func (idx *Index) HasBloomFilter() bool {
+	return idx.bloom.m > 0
+}
+
 // LookupView returns a read-only view of the entry for the given path.
+// When the index has a bloom filter, paths it rules out are rejected
+// without a binary search.
 //
 // The returned view is only valid while the index remains alive.
 func (idx *Index) LookupView(path string) (blobtype.EntryView, bool) {
+	if !idx.bloom.mayContain(path) {
+		return blobtype.EntryView{}, false
+	}
 	var fbEntry fb.Entry
 	if !idx.root.EntriesByKey(&fbEntry, path) {
 		return blobtype.EntryView{}, false
@@ -88,6 +169,33 @@ func (idx *Index) Len() int {
 	return idx.root.EntriesLength()
 }
 
+// Search returns the position of the first entry whose path is not less
+// than path in byte order, or Len if there is none.
+func (idx *Index) Search(path string) int {
+	pathBytes := []byte(path)
+	return sort.Search(idx.root.EntriesLength(), func(i int) bool {
+		var fbEntry fb.Entry
+		if !idx.root.Entries(&fbEntry, i) {
+			return false
+		}
+		return bytes.Compare(fbEntry.Path(), pathBytes) >= 0
+	})
+}
+
+// ViewAt returns a read-only view of the entry at position i in path order.
+//
+// The returned view is only valid while the index remains alive.
+func (idx *Index) ViewAt(i int) (blobtype.EntryView, bool) {
+	if i < 0 || i >= idx.root.EntriesLength() {
+		return blobtype.EntryView{}, false
+	}
+	var fbEntry fb.Entry
+	if !idx.root.Entries(&fbEntry, i) {
+		return blobtype.EntryView{}, false
+	}
+	return blobtype.EntryViewFromFlatBuffers(fbEntry), true
+}
+
 // EntriesView returns an iterator over all entries as read-only views.
 //
 // The returned views are only valid while the index remains alive.
@@ -117,16 +225,8 @@ func (idx *Index) EntriesWithPrefixView(prefix string) iter.Seq[blobtype.EntryVi
 		}
 		prefixBytes := []byte(prefix)
 
-		start := sort.Search(n, func(i int) bool {
-			var fbEntry fb.Entry
-			if !idx.root.Entries(&fbEntry, i) {
-				return false
-			}
-			return bytes.Compare(fbEntry.Path(), prefixBytes) >= 0
-		})
-
 		var fbEntry fb.Entry
-		for i := start; i < n; i++ {
+		for i := idx.Search(prefix); i < n; i++ {
 			if !idx.root.Entries(&fbEntry, i) {
 				return
 			}
@@ -140,3 +240,30 @@ func (idx *Index) EntriesWithPrefixView(prefix string) iter.Seq[blobtype.EntryVi
 		}
 	}
 }
+
+// EntriesWithSuffixView returns an iterator over entries whose path ends
+// with suffix as read-only views.
+//
+// The index is sorted by path, so unlike EntriesWithPrefixView, which
+// starts with a binary search, this scans every entry. Paths are compared
+// in place without allocating.
+//
+// The returned views are only valid while the index remains alive.
+func (idx *Index) EntriesWithSuffixView(suffix string) iter.Seq[blobtype.EntryView] {
+	return func(yield func(blobtype.EntryView) bool) {
+		suffixBytes := []byte(suffix)
+
+		var fbEntry fb.Entry
+		for i := range idx.root.EntriesLength() {
+			if !idx.root.Entries(&fbEntry, i) {
+				return
+			}
+			if !bytes.HasSuffix(fbEntry.Path(), suffixBytes) {
+				continue
+			}
+			if !yield(blobtype.EntryViewFromFlatBuffers(fbEntry)) {
+				return
+			}
+		}
+	}
+}
@@ -2,7 +2,10 @@ package index
 
 import (
 	"crypto/sha256"
+	"fmt"
 	"io/fs"
+	"os"
+	"path/filepath"
 	"testing"
 	"time"
 
@@ -41,6 +44,39 @@ func TestLoad(t *testing.T) {
 	})
 }
 
+func TestLoadVersion(t *testing.T) {
+	t.Parallel()
+
+	entries := []testutil.TestEntry{
+		{Path: "test.txt", DataOffset: 0, DataSize: 100, OriginalSize: 100},
+	}
+	future := testutil.BuildTestIndexWithMetadata(t, entries, &testutil.IndexMetadata{
+		Version:       CurrentVersion + 1,
+		UnknownFields: 2,
+	})
+
+	t.Run("compatible future version", func(t *testing.T) {
+		t.Parallel()
+		idx, err := Load(future, WithMaxVersion(CurrentVersion+1))
+		require.NoError(t, err)
+		assert.Equal(t, CurrentVersion+1, idx.Version())
+		view, ok := idx.LookupView("test.txt")
+		require.True(t, ok)
+		assert.Equal(t, uint64(100), view.DataSize())
+	})
+
+	t.Run("beyond max", func(t *testing.T) {
+		t.Parallel()
+		_, err := Load(future)
+		require.ErrorIs(t, err, blobtype.ErrUnsupportedIndexVersion)
+
+		var versionErr *blobtype.IndexVersionError
+		require.ErrorAs(t, err, &versionErr)
+		assert.Equal(t, CurrentVersion+1, versionErr.Version)
+		assert.Equal(t, CurrentVersion, versionErr.MaxVersion)
+	})
+}
+
 func TestIndexLookup(t *testing.T) {
 	t.Parallel()
 
@@ -159,6 +195,74 @@ func TestIndexEntriesWithPrefix(t *testing.T) {
 	}
 }
 
+func TestIndexEntriesWithSuffix(t *testing.T) {
+	t.Parallel()
+
+	entries := []testutil.TestEntry{
+		{Path: "app/main.wasm"},
+		{Path: "app/main.wasm.map"},
+		{Path: "lib/util.wasm"},
+		{Path: "lib/vendor/deep/x.wasm"},
+		{Path: "readme.md"},
+		{Path: "wasm"},
+	}
+	data := testutil.BuildTestIndex(t, entries)
+	idx := mustLoadIndex(t, data)
+
+	tests := []struct {
+		name     string
+		suffix   string
+		expected []string
+	}{
+		{
+			name:     "extension",
+			suffix:   ".wasm",
+			expected: []string{"app/main.wasm", "lib/util.wasm", "lib/vendor/deep/x.wasm"},
+		},
+		{
+			name:     "bare name",
+			suffix:   "wasm",
+			expected: []string{"app/main.wasm", "lib/util.wasm", "lib/vendor/deep/x.wasm", "wasm"},
+		},
+		{
+			name:     "path element",
+			suffix:   "/x.wasm",
+			expected: []string{"lib/vendor/deep/x.wasm"},
+		},
+		{
+			name:     "no match",
+			suffix:   ".go",
+			expected: []string{},
+		},
+		{
+			name:     "empty suffix matches all",
+			suffix:   "",
+			expected: []string{"app/main.wasm", "app/main.wasm.map", "lib/util.wasm", "lib/vendor/deep/x.wasm", "readme.md", "wasm"},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			paths := make([]string, 0, len(tc.expected))
+			for view := range idx.EntriesWithSuffixView(tc.suffix) {
+				paths = append(paths, view.Path())
+			}
+			assert.Equal(t, tc.expected, paths)
+		})
+	}
+
+	t.Run("stops early", func(t *testing.T) {
+		t.Parallel()
+		count := 0
+		for range idx.EntriesWithSuffixView(".wasm") {
+			count++
+			break
+		}
+		assert.Equal(t, 1, count)
+	})
+}
+
 func TestIndexEntryMetadata(t *testing.T) {
 	t.Parallel()
 
@@ -252,3 +356,139 @@ func TestIndexDataMetadata(t *testing.T) {
 		assert.Equal(t, uint64(0), gotSize)
 	})
 }
+
+func TestIndexBloomFilter(t *testing.T) {
+	t.Parallel()
+
+	entries := []testutil.TestEntry{
+		{Path: "a.txt"},
+		{Path: "dir/b.txt"},
+		{Path: "dir/sub/c.txt"},
+	}
+	paths := []string{"a.txt", "dir/b.txt", "dir/sub/c.txt"}
+
+	t.Run("absent", func(t *testing.T) {
+		t.Parallel()
+
+		idx := mustLoadIndex(t, testutil.BuildTestIndex(t, entries))
+		assert.False(t, idx.HasBloomFilter())
+		_, ok := idx.LookupView("dir/b.txt")
+		assert.True(t, ok)
+		_, ok = idx.LookupView("missing.txt")
+		assert.False(t, ok)
+	})
+
+	t.Run("present", func(t *testing.T) {
+		t.Parallel()
+
+		data := testutil.BuildTestIndexWithMetadata(t, entries, &testutil.IndexMetadata{
+			BloomFilter: NewBloomFilter(paths),
+		})
+		idx := mustLoadIndex(t, data)
+		assert.True(t, idx.HasBloomFilter())
+		for _, p := range paths {
+			view, ok := idx.LookupView(p)
+			require.True(t, ok, "lookup %s", p)
+			assert.Equal(t, p, view.Path())
+		}
+		for _, p := range []string{"missing.txt", "dir", "dir/b.tx", "dir/sub/c.txt/"} {
+			_, ok := idx.LookupView(p)
+			assert.False(t, ok, "lookup %s", p)
+		}
+	})
+
+	t.Run("rules out missing paths", func(t *testing.T) {
+		t.Parallel()
+
+		many := make([]string, 1000)
+		for i := range many {
+			many[i] = fmt.Sprintf("dir%02d/file%04d.dat", i%10, i)
+		}
+		f, err := parseBloomFilter(NewBloomFilter(many))
+		require.NoError(t, err)
+		for _, p := range many {
+			require.True(t, f.mayContain(p), "false negative for %s", p)
+		}
+		falsePositives := 0
+		for i := range 10000 {
+			if f.mayContain(fmt.Sprintf("missing/file%05d.dat", i)) {
+				falsePositives++
+			}
+		}
+		assert.Less(t, falsePositives, 300, "false positive rate too high")
+	})
+
+	t.Run("malformed", func(t *testing.T) {
+		t.Parallel()
+
+		for _, filter := range [][]byte{{7}, {0, 0xff}, {maxBloomHashes + 1, 0xff}} {
+			data := testutil.BuildTestIndexWithMetadata(t, entries, &testutil.IndexMetadata{BloomFilter: filter})
+			_, err := Load(data)
+			assert.ErrorIs(t, err, errInvalidBloomFilter)
+		}
+	})
+}
+
+func TestLoadFromFile(t *testing.T) {
+	t.Parallel()
+
+	entries := make([]testutil.TestEntry, 100)
+	for i := range entries {
+		entries[i] = testutil.TestEntry{
+			Path:         fmt.Sprintf("dir%d/file%03d.txt", i%5, i),
+			DataOffset:   uint64(i) * 10,
+			DataSize:     10,
+			OriginalSize: 10,
+			Mode:         0o644,
+		}
+	}
+	data := testutil.BuildTestIndexWithMetadata(t, entries, &testutil.IndexMetadata{DataSize: 1000})
+	path := filepath.Join(t.TempDir(), "index.blob")
+	require.NoError(t, os.WriteFile(path, data, 0o600))
+
+	want := mustLoadIndex(t, data)
+	got, err := LoadFromFile(path)
+	require.NoError(t, err)
+
+	assert.Equal(t, data, got.Data())
+	assert.Equal(t, want.Len(), got.Len())
+	assert.Equal(t, want.Version(), got.Version())
+	gotSize, _ := got.DataSize()
+	wantSize, _ := want.DataSize()
+	assert.Equal(t, wantSize, gotSize)
+	var wantEntries, gotEntries []blobtype.Entry
+	for view := range want.EntriesView() {
+		wantEntries = append(wantEntries, view.Entry())
+	}
+	for view := range got.EntriesView() {
+		gotEntries = append(gotEntries, view.Entry())
+	}
+	assert.Equal(t, wantEntries, gotEntries)
+	view, ok := got.LookupView("dir2/file042.txt")
+	require.True(t, ok)
+	assert.Equal(t, uint64(420), view.DataOffset())
+	_, ok = got.LookupView("missing.txt")
+	assert.False(t, ok)
+
+	t.Run("errors", func(t *testing.T) {
+		t.Parallel()
+
+		dir := t.TempDir()
+		_, err := LoadFromFile(filepath.Join(dir, "missing"))
+		require.ErrorIs(t, err, fs.ErrNotExist)
+
+		empty := filepath.Join(dir, "empty")
+		require.NoError(t, os.WriteFile(empty, nil, 0o600))
+		_, err = LoadFromFile(empty)
+		require.Error(t, err)
+
+		future := filepath.Join(dir, "future")
+		futureData := testutil.BuildTestIndexWithMetadata(t, entries, &testutil.IndexMetadata{Version: CurrentVersion + 1})
+		require.NoError(t, os.WriteFile(future, futureData, 0o600))
+		_, err = LoadFromFile(future)
+		var versionErr *blobtype.IndexVersionError
+		require.ErrorAs(t, err, &versionErr)
+		_, err = LoadFromFile(future, WithMaxVersion(CurrentVersion+1))
+		require.NoError(t, err)
+	})
+}
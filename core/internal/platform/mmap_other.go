@@ -0,0 +1,20 @@
+//go:build !linux && !darwin
+
+package platform
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"runtime"
+)
+
+// MapFile reports that memory mapping is unavailable on this platform.
+func MapFile(_ *os.File, _ int) ([]byte, error) {
+	return nil, fmt.Errorf("memory mapping is not supported on %s: %w", runtime.GOOS, errors.ErrUnsupported)
+}
+
+// Unmap is never called because MapFile always fails.
+func Unmap(_ []byte) error {
+	return nil
+}
@@ -0,0 +1,19 @@
+//go:build linux || darwin
+
+package platform
+
+import (
+	"os"
+	"syscall"
+)
+
+// MapFile maps size bytes of f read-only. The mapping stays valid after f
+// is closed, until Unmap.
+func MapFile(f *os.File, size int) ([]byte, error) {
+	return syscall.Mmap(int(f.Fd()), 0, size, syscall.PROT_READ, syscall.MAP_SHARED)
+}
+
+// Unmap releases a mapping created by MapFile.
+func Unmap(data []byte) error {
+	return syscall.Munmap(data)
+}
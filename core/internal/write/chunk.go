@@ -0,0 +1,209 @@
+package write
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"hash"
+	"io"
+	"math/bits"
+
+	"github.com/klauspost/compress/zstd"
+
+	"github.com/meigma/blob/core/internal/blobtype"
+	"github.com/meigma/blob/core/internal/file"
+)
+
+// Bounds on the average chunk size accepted by NewChunker.
+const (
+	MinAvgChunkSize = 1 << 10
+	MaxAvgChunkSize = 64 << 20
+)
+
+// gear holds the random values mixed into the rolling hash for each byte.
+// The table is fixed so that chunk boundaries, and therefore chunk hashes,
+// are stable across processes and releases.
+var gear = func() (table [256]uint64) {
+	// splitmix64 with a fixed seed.
+	state := uint64(0x626c6f622d636463) // "blob-cdc"
+	for i := range table {
+		state += 0x9e3779b97f4a7c15
+		z := state
+		z = (z ^ (z >> 30)) * 0xbf58476d1ce4e5b9
+		z = (z ^ (z >> 27)) * 0x94d049bb133111eb
+		table[i] = z ^ (z >> 31)
+	}
+	return table
+}()
+
+// Chunker finds content-defined chunk boundaries using a gear rolling hash
+// with normalized chunking, as in FastCDC. Boundaries depend only on the
+// bytes around them, so an edit moves at most the boundaries next to it and
+// the remaining chunks keep their hashes.
+type Chunker struct {
+	min, avg, max int
+	maskS, maskL  uint64 // stricter mask below avg, looser mask above it
+}
+
+// NewChunker returns a Chunker whose chunks average about avg bytes and
+// range from avg/4 to avg*4 bytes.
+func NewChunker(avg int) (*Chunker, error) {
+	if avg < MinAvgChunkSize || avg > MaxAvgChunkSize {
+		return nil, fmt.Errorf("average chunk size %d out of range [%d, %d]", avg, MinAvgChunkSize, MaxAvgChunkSize)
+	}
+	// The top bits of the gear hash depend on the most bytes, so the masks
+	// select those.
+	n := bits.Len(uint(avg)) - 1
+	return &Chunker{
+		min:   avg / 4,
+		avg:   avg,
+		max:   avg * 4,
+		maskS: ^uint64(0) << (64 - (n + 1)),
+		maskL: ^uint64(0) << (64 - (n - 1)),
+	}, nil
+}
+
+// MaxSize returns the largest chunk the Chunker produces.
+func (c *Chunker) MaxSize() int {
+	return c.max
+}
+
+// Cut returns the length of the first chunk of data. Unless data holds the
+// end of the content, it must hold at least MaxSize bytes.
+func (c *Chunker) Cut(data []byte) int {
+	n := len(data)
+	if n <= c.min {
+		return n
+	}
+	n = min(n, c.max)
+	normal := min(n, c.avg)
+
+	var h uint64
+	i := c.min
+	for ; i < normal; i++ {
+		h = h<<1 + gear[data[i]]
+		if h&c.maskS == 0 {
+			return i + 1
+		}
+	}
+	for ; i < n; i++ {
+		h = h<<1 + gear[data[i]]
+		if h&c.maskL == 0 {
+			return i + 1
+		}
+	}
+	return n
+}
+
+// Chunked is like File but splits the content into chunks with c and stores
+// each chunk as an independent region: raw, or a zstd frame of its own when
+// compression is enabled. The regions are contiguous, so the stored bytes
+// still decode to the whole content. Returns the chunks alongside File's
+// results.
+func Chunked(ctx context.Context, r io.Reader, w io.Writer, enc *zstd.Encoder, c *Chunker, compression blobtype.Compression, expectedSize int64, aux hash.Hash) (dataSize, originalSize uint64, sum []byte, chunks []blobtype.Chunk, err error) {
+	if expectedSize < 0 {
+		return 0, 0, nil, nil, errors.New("negative file size")
+	}
+
+	sha := sha256.New()
+	if aux != nil {
+		aux.Reset()
+	}
+	cw := &file.CountingWriter{W: w}
+	lr := io.LimitReader(r, expectedSize)
+
+	buf := make([]byte, min(int64(c.max), expectedSize))
+	var filled int
+	eof := false
+	for {
+		if err := ctx.Err(); err != nil {
+			return 0, 0, nil, nil, err
+		}
+		if !eof {
+			n, err := io.ReadFull(lr, buf[filled:])
+			filled += n
+			switch {
+			case errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF):
+				eof = true
+			case err != nil:
+				return 0, 0, nil, nil, err
+			}
+		}
+		if filled == 0 {
+			break
+		}
+
+		n := c.Cut(buf[:filled])
+		chunk := buf[:n]
+		_, _ = sha.Write(chunk) //nolint:errcheck // hash writes never fail
+		if aux != nil {
+			_, _ = aux.Write(chunk) //nolint:errcheck // hash writes never fail
+		}
+		before := cw.N
+		if err := writeChunk(cw, enc, compression, chunk); err != nil {
+			return 0, 0, nil, nil, err
+		}
+		chunkSum := sha256.Sum256(chunk)
+		chunks = append(chunks, blobtype.Chunk{
+			Size:         uint32(cw.N - before), //nolint:gosec // chunks are at most MaxAvgChunkSize*4 bytes
+			OriginalSize: uint32(n),             //nolint:gosec // chunks are at most MaxAvgChunkSize*4 bytes
+			Hash:         chunkSum[:],
+		})
+		originalSize += uint64(n)
+		filled = copy(buf, buf[n:filled])
+	}
+
+	return cw.N, originalSize, sha.Sum(nil), chunks, nil
+}
+
+// writeChunk writes one chunk to w, as a zstd frame when compressing.
+func writeChunk(w io.Writer, enc *zstd.Encoder, compression blobtype.Compression, chunk []byte) error {
+	if compression == blobtype.CompressionNone {
+		_, err := w.Write(chunk)
+		return err
+	}
+	enc.Reset(w)
+	if _, err := enc.Write(chunk); err != nil {
+		enc.Close()
+		return err
+	}
+	if err := enc.Close(); err != nil {
+		return fmt.Errorf("close zstd encoder: %w", err)
+	}
+	return nil
+}
+
+// ChunkedAdaptive is like FileAdaptive but writes the content with Chunked.
+// The compression decision is made for the file as a whole.
+func ChunkedAdaptive(ctx context.Context, r io.ReadSeeker, w io.Writer, enc *zstd.Encoder, c *Chunker, scratch *bytes.Buffer, expectedSize int64, minSavings float64, aux hash.Hash) (dataSize, originalSize uint64, sum []byte, chunks []blobtype.Chunk, compression blobtype.Compression, err error) {
+	if expectedSize < 0 {
+		return 0, 0, nil, nil, 0, errors.New("negative file size")
+	}
+
+	budget := compressionBudget(uint64(expectedSize), minSavings)
+	scratch.Reset()
+	dataSize, originalSize, sum, chunks, err = Chunked(ctx, r, &budgetWriter{w: scratch, remaining: budget}, enc, c, blobtype.CompressionZstd, expectedSize, aux)
+	switch {
+	case err == nil:
+		if dataSize <= budget {
+			if _, err := scratch.WriteTo(w); err != nil {
+				return 0, 0, nil, nil, 0, err
+			}
+			return dataSize, originalSize, sum, chunks, blobtype.CompressionZstd, nil
+		}
+	case errors.Is(err, errNotWorthCompressing):
+	default:
+		return 0, 0, nil, nil, 0, err
+	}
+
+	if _, err := r.Seek(0, io.SeekStart); err != nil {
+		return 0, 0, nil, nil, 0, fmt.Errorf("rewind for uncompressed write: %w", err)
+	}
+	dataSize, originalSize, sum, chunks, err = Chunked(ctx, r, w, nil, c, blobtype.CompressionNone, expectedSize, aux)
+	if err != nil {
+		return 0, 0, nil, nil, 0, err
+	}
+	return dataSize, originalSize, sum, chunks, blobtype.CompressionNone, nil
+}
@@ -1,11 +1,35 @@
 package write
 
 import (
+	"errors"
 	"fmt"
+	"io"
 	"io/fs"
 	"os"
 )
 
+// ErrFileChanged is returned when a file changes while it is being archived.
+var ErrFileChanged = errors.New("blob: file changed during archive creation")
+
+// SizeChanged reports whether a file no longer matches the size recorded
+// at stat time, given the number of bytes read up to that size. A short read
+// means the file shrank; a readable byte past the recorded size means it grew.
+// The file offset must be positioned just past the bytes already read.
+func SizeChanged(f *os.File, expectedSize int64, read uint64) (bool, error) {
+	if read != uint64(expectedSize) { //nolint:gosec // expectedSize is non-negative
+		return true, nil
+	}
+	var probe [1]byte
+	n, err := f.Read(probe[:])
+	if n > 0 {
+		return true, nil
+	}
+	if err != nil && !errors.Is(err, io.EOF) {
+		return false, err
+	}
+	return false, nil
+}
+
 // CheckFileUnchanged verifies a file wasn't modified during write.
 // In strict mode, it compares size, mtime, and permissions before/after.
 func CheckFileUnchanged(f *os.File, path string, before fs.FileInfo, strict bool) error {
@@ -17,7 +41,7 @@ func CheckFileUnchanged(f *os.File, path string, before fs.FileInfo, strict bool
 		return err
 	}
 	if after.Size() != before.Size() || !after.ModTime().Equal(before.ModTime()) || after.Mode().Perm() != before.Mode().Perm() {
-		return fmt.Errorf("file changed during archive creation: %s", path)
+		return fmt.Errorf("%w: %s", ErrFileChanged, path)
 	}
 	return nil
 }
@@ -32,7 +56,7 @@ func ValidateFileInfo(path string, info, finfo fs.FileInfo, strict bool) error {
 		return fmt.Errorf("missing file info: %s", path)
 	}
 	if !os.SameFile(info, finfo) {
-		return fmt.Errorf("file changed during archive creation: %s", path)
+		return fmt.Errorf("%w: %s", ErrFileChanged, path)
 	}
 	return nil
 }
@@ -1,12 +1,13 @@
 package write
 
 import (
+	"bytes"
 	"context"
 	"crypto/sha256"
 	"errors"
 	"fmt"
+	"hash"
 	"io"
-	"os"
 
 	"github.com/klauspost/compress/zstd"
 
@@ -14,19 +15,32 @@ import (
 	"github.com/meigma/blob/core/internal/file"
 )
 
-// File streams a file through the hash and optional compression pipeline.
-// Returns (dataSize, originalSize, hash, error).
+// File streams a file's content from r through the hash and optional
+// compression pipeline.
+// Returns (dataSize, originalSize, sum, error), where sum is the SHA256 hash.
+//
+// At most expectedSize bytes are read. If the file shrank since it was
+// statted, originalSize reports the bytes actually read; use SizeChanged to
+// detect size changes in either direction.
 //
 // The encoder and buf are reused across calls for performance. Pass nil encoder
 // for uncompressed writes. The buf should be at least 32KB for efficient copying.
-func File(ctx context.Context, f *os.File, w io.Writer, enc *zstd.Encoder, buf []byte, compression blobtype.Compression, expectedSize int64) (dataSize, originalSize uint64, hash []byte, err error) {
+//
+// When aux is non-nil it is reset and fed the uncompressed content alongside
+// the SHA256 hasher; callers read its sum after File returns.
+func File(ctx context.Context, r io.Reader, w io.Writer, enc *zstd.Encoder, buf []byte, compression blobtype.Compression, expectedSize int64, aux hash.Hash) (dataSize, originalSize uint64, sum []byte, err error) {
 	if expectedSize < 0 {
 		return 0, 0, nil, errors.New("negative file size")
 	}
 
-	hasher := sha256.New()
+	sha := sha256.New()
+	var hasher io.Writer = sha
+	if aux != nil {
+		aux.Reset()
+		hasher = io.MultiWriter(sha, aux)
+	}
 	cw := &file.CountingWriter{W: w}
-	cr := &file.CountingReader{R: io.LimitReader(f, expectedSize)}
+	cr := &file.CountingReader{R: io.LimitReader(r, expectedSize)}
 
 	if compression == blobtype.CompressionNone {
 		// Stream: file → TeeReader(hasher) → countingWriter(data)
@@ -45,11 +59,110 @@ func File(ctx context.Context, f *os.File, w io.Writer, enc *zstd.Encoder, buf [
 		}
 	}
 
-	if cr.N != uint64(expectedSize) {
-		return 0, 0, nil, fmt.Errorf("file size changed during archive creation: expected %d, got %d", expectedSize, cr.N)
+	return cw.N, cr.N, sha.Sum(nil), nil
+}
+
+// MaxTrialSize is the largest file FileAdaptive and ChunkedAdaptive should
+// be used for. Their trial compression is staged in memory, so callers
+// decide for larger files with SampleWorthCompressing instead, to keep
+// staging from growing with file size.
+const MaxTrialSize = 16 << 20
+
+// SampleSize is the number of leading bytes SampleWorthCompressing
+// compresses.
+const SampleSize = 4 << 20
+
+// SampleWorthCompressing compresses the first SampleSize bytes of r,
+// discarding the output, and reports whether they save at least minSavings
+// (a fraction in [0, 1)) of their size. r is rewound before returning.
+// Nothing is staged, so it bounds the cost of deciding for files too large
+// for FileAdaptive.
+func SampleWorthCompressing(ctx context.Context, r io.ReadSeeker, enc *zstd.Encoder, buf []byte, minSavings float64) (bool, error) {
+	cw := &file.CountingWriter{W: io.Discard}
+	cr := &file.CountingReader{R: io.LimitReader(r, SampleSize)}
+	enc.Reset(cw)
+	if _, err := file.CopyWithContext(ctx, enc, cr, buf); err != nil {
+		enc.Close()
+		return false, wrapOverflowErr(err)
+	}
+	if err := enc.Close(); err != nil {
+		return false, fmt.Errorf("close zstd encoder: %w", err)
+	}
+	if _, err := r.Seek(0, io.SeekStart); err != nil {
+		return false, fmt.Errorf("rewind after compression sample: %w", err)
+	}
+	return cw.N <= compressionBudget(cr.N, minSavings), nil
+}
+
+// errNotWorthCompressing aborts a trial compression once the output exceeds
+// the size budget.
+var errNotWorthCompressing = errors.New("compressed output exceeds budget")
+
+// FileAdaptive compresses a file and keeps the compressed form only when it
+// saves at least minSavings (a fraction in [0, 1)) of the original size.
+// Returns (dataSize, originalSize, sum, compression, error) where compression
+// reports the algorithm actually used for the entry.
+//
+// The compressed output is staged in scratch, which is reset before use and
+// grows to at most the compressed size budget. When compression does not pay
+// off, r is rewound and written uncompressed.
+func FileAdaptive(ctx context.Context, r io.ReadSeeker, w io.Writer, enc *zstd.Encoder, buf []byte, scratch *bytes.Buffer, expectedSize int64, minSavings float64, aux hash.Hash) (dataSize, originalSize uint64, sum []byte, compression blobtype.Compression, err error) {
+	if expectedSize < 0 {
+		return 0, 0, nil, 0, errors.New("negative file size")
 	}
 
-	return cw.N, cr.N, hasher.Sum(nil), nil
+	budget := compressionBudget(uint64(expectedSize), minSavings)
+	scratch.Reset()
+	dataSize, originalSize, sum, err = File(ctx, r, &budgetWriter{w: scratch, remaining: budget}, enc, buf, blobtype.CompressionZstd, expectedSize, aux)
+	switch {
+	case err == nil:
+		if dataSize <= budget {
+			if _, err := scratch.WriteTo(w); err != nil {
+				return 0, 0, nil, 0, err
+			}
+			return dataSize, originalSize, sum, blobtype.CompressionZstd, nil
+		}
+	case errors.Is(err, errNotWorthCompressing):
+	default:
+		return 0, 0, nil, 0, err
+	}
+
+	if _, err := r.Seek(0, io.SeekStart); err != nil {
+		return 0, 0, nil, 0, fmt.Errorf("rewind for uncompressed write: %w", err)
+	}
+	dataSize, originalSize, sum, err = File(ctx, r, w, nil, buf, blobtype.CompressionNone, expectedSize, aux)
+	if err != nil {
+		return 0, 0, nil, 0, err
+	}
+	return dataSize, originalSize, sum, blobtype.CompressionNone, nil
+}
+
+// compressionBudget returns the largest compressed size that still saves
+// minSavings of size.
+func compressionBudget(size uint64, minSavings float64) uint64 {
+	if minSavings <= 0 {
+		return size
+	}
+	if minSavings >= 1 {
+		return 0
+	}
+	return uint64(float64(size) * (1 - minSavings))
+}
+
+// budgetWriter forwards writes until the byte budget is exhausted.
+type budgetWriter struct {
+	w         io.Writer
+	remaining uint64
+}
+
+// Write implements io.Writer.
+func (bw *budgetWriter) Write(p []byte) (int, error) {
+	if uint64(len(p)) > bw.remaining {
+		return 0, errNotWorthCompressing
+	}
+	n, err := bw.w.Write(p)
+	bw.remaining -= uint64(n) //nolint:gosec // n is bounded by len(p)
+	return n, err
 }
 
 // wrapOverflowErr converts internal overflow errors to the public sentinel.
@@ -0,0 +1,85 @@
+package blob
+
+import (
+	"bytes"
+	"cmp"
+	"fmt"
+	"slices"
+
+	"github.com/meigma/blob/core/internal/index"
+	"github.com/meigma/blob/core/internal/sizing"
+)
+
+// entrySpan is the byte range an entry occupies in the data blob.
+type entrySpan struct {
+	start, end uint64
+	path       string
+	hash       []byte
+}
+
+// validateLayout checks that entry byte ranges lie within the data blob and
+// do not overlap. Empty entries occupy no bytes and never overlap.
+//
+// Create writes entries in path order at increasing offsets, so the common
+// case is a single linear pass; forged or foreign indexes are sorted first.
+func validateLayout(idx *index.Index, sourceSize int64) error {
+	return checkLayout(idx, sourceSize, false)
+}
+
+// validateIndex checks the index against the data blob for WithValidateIndex:
+// the recorded data size must match sourceSize, paths must be sorted and
+// unique, and entries must satisfy validateLayout, except that entries with
+// the same content may share the same bytes.
+func validateIndex(idx *index.Index, sourceSize int64) error {
+	if dataSize, ok := idx.DataSize(); ok && (sourceSize < 0 || dataSize != uint64(sourceSize)) {
+		return fmt.Errorf("%w: index records %d bytes, source has %d", ErrDataSizeMismatch, dataSize, sourceSize)
+	}
+	var prev []byte
+	i := 0
+	for view := range idx.EntriesView() {
+		path := view.PathBytes()
+		if i > 0 && bytes.Compare(prev, path) >= 0 {
+			return fmt.Errorf("%w: %s at position %d follows %s", ErrUnsortedEntries, path, i, prev)
+		}
+		prev = path
+		i++
+	}
+	return checkLayout(idx, sourceSize, true)
+}
+
+// checkLayout implements validateLayout. With shared, entries that occupy
+// exactly the same bytes and have the same hash do not overlap; a writer
+// that deduplicates content stores it once for all its paths.
+func checkLayout(idx *index.Index, sourceSize int64, shared bool) error {
+	if sourceSize < 0 {
+		return ErrSizeOverflow
+	}
+	size := uint64(sourceSize)
+
+	spans := make([]entrySpan, 0, idx.Len())
+	for view := range idx.EntriesView() {
+		end, ok := sizing.AddUint64(view.DataOffset(), view.DataSize())
+		if !ok || end > size {
+			return fmt.Errorf("%w: %s extends past data size %d", ErrSizeOverflow, view.Path(), size)
+		}
+		if view.DataSize() == 0 {
+			continue
+		}
+		spans = append(spans, entrySpan{start: view.DataOffset(), end: end, path: view.Path(), hash: view.HashBytes()})
+	}
+
+	byStart := func(a, b entrySpan) int { return cmp.Compare(a.start, b.start) }
+	if !slices.IsSortedFunc(spans, byStart) {
+		slices.SortFunc(spans, byStart)
+	}
+	for i := 1; i < len(spans); i++ {
+		prev, cur := spans[i-1], spans[i]
+		if shared && cur.start == prev.start && cur.end == prev.end && bytes.Equal(cur.hash, prev.hash) {
+			continue
+		}
+		if cur.start < prev.end {
+			return fmt.Errorf("%w: %s and %s", ErrOverlappingEntries, prev.path, cur.path)
+		}
+	}
+	return nil
+}
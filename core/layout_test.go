@@ -0,0 +1,165 @@
+package blob
+
+import (
+	"bytes"
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/meigma/blob/core/internal/fb"
+	"github.com/meigma/blob/core/testutil"
+)
+
+func TestWithValidateLayout(t *testing.T) {
+	t.Parallel()
+
+	hash := make([]byte, 32)
+	data := make([]byte, 64)
+
+	t.Run("valid archive", func(t *testing.T) {
+		t.Parallel()
+		dir := t.TempDir()
+		createTestFilesBytes(t, dir, map[string][]byte{
+			"a.txt":     []byte("aaaa"),
+			"b.txt":     []byte("bbbbbbbb"),
+			"dir/c.txt": []byte("cccccccccccc"),
+			"empty.txt": {},
+		})
+		var indexBuf, dataBuf bytes.Buffer
+		require.NoError(t, Create(context.Background(), dir, &indexBuf, &dataBuf))
+
+		_, err := New(indexBuf.Bytes(), testutil.NewMockByteSource(dataBuf.Bytes()), WithValidateLayout(true))
+		require.NoError(t, err)
+	})
+
+	t.Run("overlapping entries", func(t *testing.T) {
+		t.Parallel()
+		// Offsets are out of path order to exercise sorting.
+		indexData := testutil.BuildTestIndex(t, []testutil.TestEntry{
+			{Path: "a.txt", DataOffset: 32, DataSize: 16, OriginalSize: 16, Hash: hash, Mode: 0o644},
+			{Path: "b.txt", DataOffset: 0, DataSize: 16, OriginalSize: 16, Hash: hash, Mode: 0o644},
+			{Path: "c.txt", DataOffset: 40, DataSize: 8, OriginalSize: 8, Hash: hash, Mode: 0o644},
+		})
+
+		_, err := New(indexData, testutil.NewMockByteSource(data), WithValidateLayout(true))
+		require.ErrorIs(t, err, ErrOverlappingEntries)
+		assert.Contains(t, err.Error(), "a.txt")
+		assert.Contains(t, err.Error(), "c.txt")
+
+		// Without the option the forged index is accepted.
+		_, err = New(indexData, testutil.NewMockByteSource(data))
+		require.NoError(t, err)
+	})
+
+	t.Run("entry past data size", func(t *testing.T) {
+		t.Parallel()
+		indexData := testutil.BuildTestIndex(t, []testutil.TestEntry{
+			{Path: "a.txt", DataOffset: 60, DataSize: 8, OriginalSize: 8, Hash: hash, Mode: 0o644},
+		})
+
+		_, err := New(indexData, testutil.NewMockByteSource(data), WithValidateLayout(true))
+		require.ErrorIs(t, err, ErrSizeOverflow)
+		assert.Contains(t, err.Error(), "a.txt")
+	})
+}
+
+func TestWithValidateIndex(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	createTestFilesBytes(t, dir, map[string][]byte{
+		"a.txt":     []byte("aaaa"),
+		"b.txt":     []byte("bbbbbbbb"),
+		"c.txt":     []byte("cccccccccccc"),
+		"empty.txt": {},
+	})
+	var indexBuf, dataBuf bytes.Buffer
+	require.NoError(t, Create(context.Background(), dir, &indexBuf, &dataBuf))
+	data := dataBuf.Bytes()
+
+	// tamper returns a copy of the index with mutate applied to its root.
+	tamper := func(t *testing.T, mutate func(root *fb.Index) bool) []byte {
+		t.Helper()
+		indexData := bytes.Clone(indexBuf.Bytes())
+		require.True(t, mutate(fb.GetRootAsIndex(indexData, 0)))
+		return indexData
+	}
+	entryAt := func(root *fb.Index, i int) *fb.Entry {
+		var e fb.Entry
+		root.Entries(&e, i)
+		return &e
+	}
+
+	t.Run("valid archive", func(t *testing.T) {
+		t.Parallel()
+		_, err := New(indexBuf.Bytes(), testutil.NewMockByteSource(data), WithValidateIndex(true))
+		require.NoError(t, err)
+	})
+
+	t.Run("data size mismatch", func(t *testing.T) {
+		t.Parallel()
+		indexData := tamper(t, func(root *fb.Index) bool { return root.MutateDataSize(root.DataSize() + 1) })
+		_, err := New(indexData, testutil.NewMockByteSource(data), WithValidateIndex(true))
+		require.ErrorIs(t, err, ErrDataSizeMismatch)
+		assert.Contains(t, err.Error(), "source has 24")
+
+		_, err = New(indexBuf.Bytes(), testutil.NewMockByteSource(data[:len(data)-1]), WithValidateIndex(true))
+		require.ErrorIs(t, err, ErrDataSizeMismatch)
+
+		// Without the option the forged index is accepted.
+		_, err = New(indexData, testutil.NewMockByteSource(data))
+		require.NoError(t, err)
+	})
+
+	t.Run("unsorted paths", func(t *testing.T) {
+		t.Parallel()
+		indexData := bytes.Clone(indexBuf.Bytes())
+		i := bytes.Index(indexData, []byte("c.txt"))
+		require.Positive(t, i)
+		indexData[i] = 'a'
+		_, err := New(indexData, testutil.NewMockByteSource(data), WithValidateIndex(true))
+		require.ErrorIs(t, err, ErrUnsortedEntries)
+		assert.Contains(t, err.Error(), "a.txt at position 2 follows b.txt")
+	})
+
+	t.Run("entry past data size", func(t *testing.T) {
+		t.Parallel()
+		indexData := tamper(t, func(root *fb.Index) bool { return entryAt(root, 2).MutateDataSize(13) })
+		_, err := New(indexData, testutil.NewMockByteSource(data), WithValidateIndex(true))
+		require.ErrorIs(t, err, ErrSizeOverflow)
+		assert.Contains(t, err.Error(), "c.txt")
+	})
+
+	t.Run("overlapping entries", func(t *testing.T) {
+		t.Parallel()
+		indexData := tamper(t, func(root *fb.Index) bool { return entryAt(root, 2).MutateDataOffset(10) })
+		_, err := New(indexData, testutil.NewMockByteSource(data), WithValidateIndex(true))
+		require.ErrorIs(t, err, ErrOverlappingEntries)
+		assert.Contains(t, err.Error(), "b.txt and c.txt")
+	})
+
+	t.Run("deduplicated content", func(t *testing.T) {
+		t.Parallel()
+		hash := make([]byte, 32)
+		shared := testutil.BuildTestIndexWithMetadata(t, []testutil.TestEntry{
+			{Path: "a.txt", DataOffset: 0, DataSize: 16, OriginalSize: 16, Hash: hash, Mode: 0o644},
+			{Path: "b.txt", DataOffset: 0, DataSize: 16, OriginalSize: 16, Hash: hash, Mode: 0o644},
+		}, &testutil.IndexMetadata{DataSize: 16, DataHash: hash})
+		source := testutil.NewMockByteSource(make([]byte, 16))
+
+		_, err := New(shared, source, WithValidateIndex(true))
+		require.NoError(t, err)
+		_, err = New(shared, source, WithValidateLayout(true))
+		require.ErrorIs(t, err, ErrOverlappingEntries)
+
+		other := bytes.Repeat([]byte{1}, 32)
+		conflicting := testutil.BuildTestIndexWithMetadata(t, []testutil.TestEntry{
+			{Path: "a.txt", DataOffset: 0, DataSize: 16, OriginalSize: 16, Hash: hash, Mode: 0o644},
+			{Path: "b.txt", DataOffset: 0, DataSize: 16, OriginalSize: 16, Hash: other, Mode: 0o644},
+		}, &testutil.IndexMetadata{DataSize: 16, DataHash: hash})
+		_, err = New(conflicting, source, WithValidateIndex(true))
+		require.ErrorIs(t, err, ErrOverlappingEntries)
+	})
+}
@@ -0,0 +1,54 @@
+package blob
+
+import (
+	"bufio"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"time"
+)
+
+// MetadataRecord is a single line of ExportMetadata output.
+type MetadataRecord struct {
+	Path           string    `json:"path"`
+	Size           uint64    `json:"size"`
+	CompressedSize uint64    `json:"compressedSize"`
+	Mode           uint32    `json:"mode"`
+	ModTime        time.Time `json:"modTime"`
+	Hash           string    `json:"hash"`
+	Compression    string    `json:"compression"`
+}
+
+// ExportMetadata writes one JSON record per entry to w as newline-delimited
+// JSON, for example to feed an external search index.
+//
+// Records are written in index order, which sorts paths by byte value.
+// Size is the uncompressed size, CompressedSize is the stored size, Mode is
+// the numeric fs.FileMode, Hash is the hex-encoded SHA256 of the content,
+// and Compression is "none" or "zstd". Only the index is read; file content
+// is never fetched. Records are streamed, so memory use does not grow with
+// the number of entries. For a Subset view, paths are relative to the
+// subset root.
+func (b *Blob) ExportMetadata(w io.Writer) error {
+	bw := bufio.NewWriter(w)
+	enc := json.NewEncoder(bw)
+	for view := range b.Entries() {
+		rec := MetadataRecord{
+			Path:           view.Path(),
+			Size:           view.OriginalSize(),
+			CompressedSize: view.DataSize(),
+			Mode:           uint32(view.Mode()),
+			ModTime:        view.ModTime().UTC(),
+			Hash:           hex.EncodeToString(view.HashBytes()),
+			Compression:    view.Compression().String(),
+		}
+		if err := enc.Encode(&rec); err != nil {
+			return fmt.Errorf("export metadata %s: %w", rec.Path, err)
+		}
+	}
+	if err := bw.Flush(); err != nil {
+		return fmt.Errorf("export metadata: %w", err)
+	}
+	return nil
+}
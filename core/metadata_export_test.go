@@ -0,0 +1,93 @@
+package blob
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/hex"
+	"encoding/json"
+	"io/fs"
+	"slices"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/meigma/blob/core/testutil"
+)
+
+func TestExportMetadata(t *testing.T) {
+	t.Parallel()
+
+	files := map[string][]byte{
+		"z.txt":         []byte("last"),
+		"a.txt":         []byte("alpha"),
+		"dir/sub/c.txt": bytes.Repeat([]byte("charlie "), 64),
+		"dir/b.txt":     []byte("bravo"),
+	}
+	dir := t.TempDir()
+	createTestFilesBytes(t, dir, files)
+
+	var indexBuf, dataBuf bytes.Buffer
+	require.NoError(t, Create(context.Background(), dir, &indexBuf, &dataBuf, CreateWithCompression(CompressionZstd)))
+	source := &countingByteSource{source: testutil.NewMockByteSource(dataBuf.Bytes())}
+	b, err := New(indexBuf.Bytes(), source)
+	require.NoError(t, err)
+
+	var out bytes.Buffer
+	require.NoError(t, b.ExportMetadata(&out))
+	assert.Zero(t, source.ReadCount(), "export must not read file content")
+
+	var records []MetadataRecord
+	scanner := bufio.NewScanner(&out)
+	for scanner.Scan() {
+		var rec MetadataRecord
+		require.NoError(t, json.Unmarshal(scanner.Bytes(), &rec), "line %q", scanner.Text())
+		records = append(records, rec)
+	}
+	require.NoError(t, scanner.Err())
+
+	require.Len(t, records, b.Len())
+	assert.True(t, slices.IsSortedFunc(records, func(x, y MetadataRecord) int {
+		return bytes.Compare([]byte(x.Path), []byte(y.Path))
+	}), "records must be in sorted path order")
+
+	i := 0
+	for view := range b.Entries() {
+		rec := records[i]
+		assert.Equal(t, view.Path(), rec.Path)
+		assert.Equal(t, view.OriginalSize(), rec.Size)
+		assert.Equal(t, view.DataSize(), rec.CompressedSize)
+		assert.Equal(t, view.Mode(), fs.FileMode(rec.Mode))
+		assert.True(t, view.ModTime().Equal(rec.ModTime), "modTime for %s", rec.Path)
+		assert.Equal(t, hex.EncodeToString(view.HashBytes()), rec.Hash)
+		assert.Equal(t, view.Compression().String(), rec.Compression)
+		assert.Equal(t, uint64(len(files[rec.Path])), rec.Size)
+		i++
+	}
+}
+
+func TestExportMetadata_Subset(t *testing.T) {
+	t.Parallel()
+
+	b := createTestArchive(t, map[string][]byte{
+		"a.txt":     []byte("alpha"),
+		"dir/b.txt": []byte("bravo"),
+		"dir/c.txt": []byte("charlie"),
+	}, CompressionNone)
+	sub, err := b.Subset("dir")
+	require.NoError(t, err)
+
+	var out bytes.Buffer
+	require.NoError(t, sub.ExportMetadata(&out))
+
+	var paths []string
+	dec := json.NewDecoder(&out)
+	for dec.More() {
+		var rec MetadataRecord
+		require.NoError(t, dec.Decode(&rec))
+		assert.Equal(t, "none", rec.Compression)
+		paths = append(paths, rec.Path)
+	}
+	assert.Equal(t, []string{"b.txt", "c.txt"}, paths)
+}
@@ -0,0 +1,7 @@
+// Package mmap provides a ByteSource backed by a memory-mapped local file.
+//
+// Reads are served from the mapping without a system call per range, which
+// lowers latency for archives whose data blob lives on local disk. Memory
+// mapping is supported on Linux and macOS; NewSource returns an error
+// wrapping errors.ErrUnsupported elsewhere.
+package mmap
@@ -0,0 +1,239 @@
+//go:build linux || darwin
+
+package mmap_test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+
+	blob "github.com/meigma/blob/core"
+	"github.com/meigma/blob/core/mmap"
+)
+
+func writeFile(t *testing.T, data []byte) string {
+	t.Helper()
+
+	path := filepath.Join(t.TempDir(), "data.blob")
+	if err := os.WriteFile(path, data, 0o644); err != nil {
+		t.Fatalf("WriteFile() error = %v", err)
+	}
+	return path
+}
+
+func TestSource_ReadAt(t *testing.T) {
+	t.Parallel()
+
+	data := []byte("hello world")
+	src, err := mmap.NewSource(writeFile(t, data))
+	if err != nil {
+		t.Fatalf("NewSource() error = %v", err)
+	}
+	t.Cleanup(func() { src.Close() })
+
+	if src.Size() != int64(len(data)) {
+		t.Fatalf("Size() = %d, want %d", src.Size(), len(data))
+	}
+
+	tests := []struct {
+		name    string
+		bufSize int
+		offset  int64
+		wantN   int
+		wantErr error
+	}{
+		{name: "full", bufSize: len(data), offset: 0, wantN: len(data)},
+		{name: "middle", bufSize: 5, offset: 3, wantN: 5},
+		{name: "short read at end", bufSize: 10, offset: 6, wantN: 5, wantErr: io.EOF},
+		{name: "past end", bufSize: 1, offset: int64(len(data)), wantN: 0, wantErr: io.EOF},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			buf := make([]byte, tt.bufSize)
+			n, err := src.ReadAt(buf, tt.offset)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("ReadAt() error = %v, want %v", err, tt.wantErr)
+			}
+			if n != tt.wantN {
+				t.Fatalf("ReadAt() n = %d, want %d", n, tt.wantN)
+			}
+			want := data[tt.offset : tt.offset+int64(tt.wantN)]
+			if !bytes.Equal(buf[:n], want) {
+				t.Fatalf("ReadAt() = %q, want %q", buf[:n], want)
+			}
+		})
+	}
+
+	if _, err := src.ReadAt(make([]byte, 1), -1); err == nil {
+		t.Fatal("ReadAt() with negative offset: expected error")
+	}
+}
+
+func TestSource_SourceIDStable(t *testing.T) {
+	t.Parallel()
+
+	path := writeFile(t, []byte("content"))
+	first, err := mmap.NewSource(path)
+	if err != nil {
+		t.Fatalf("NewSource() error = %v", err)
+	}
+	t.Cleanup(func() { first.Close() })
+	second, err := mmap.NewSource(path)
+	if err != nil {
+		t.Fatalf("NewSource() error = %v", err)
+	}
+	t.Cleanup(func() { second.Close() })
+
+	if first.SourceID() == "" {
+		t.Fatal("SourceID() is empty")
+	}
+	if first.SourceID() != second.SourceID() {
+		t.Fatalf("SourceID() = %q, want %q", second.SourceID(), first.SourceID())
+	}
+
+	other, err := mmap.NewSource(writeFile(t, []byte("content")))
+	if err != nil {
+		t.Fatalf("NewSource() error = %v", err)
+	}
+	t.Cleanup(func() { other.Close() })
+	if other.SourceID() == first.SourceID() {
+		t.Fatal("SourceID() should differ for a different path")
+	}
+}
+
+func TestSource_Close(t *testing.T) {
+	t.Parallel()
+
+	src, err := mmap.NewSource(writeFile(t, []byte("content")))
+	if err != nil {
+		t.Fatalf("NewSource() error = %v", err)
+	}
+	if err := src.Close(); err != nil {
+		t.Fatalf("Close() error = %v", err)
+	}
+	if err := src.Close(); err != nil {
+		t.Fatalf("second Close() error = %v", err)
+	}
+	if _, err := src.ReadAt(make([]byte, 1), 0); !errors.Is(err, os.ErrClosed) {
+		t.Fatalf("ReadAt() after Close error = %v, want %v", err, os.ErrClosed)
+	}
+}
+
+func TestSource_EmptyFile(t *testing.T) {
+	t.Parallel()
+
+	src, err := mmap.NewSource(writeFile(t, nil))
+	if err != nil {
+		t.Fatalf("NewSource() error = %v", err)
+	}
+	t.Cleanup(func() { src.Close() })
+
+	if src.Size() != 0 {
+		t.Fatalf("Size() = %d, want 0", src.Size())
+	}
+	if _, err := src.ReadAt(make([]byte, 1), 0); !errors.Is(err, io.EOF) {
+		t.Fatalf("ReadAt() error = %v, want %v", err, io.EOF)
+	}
+}
+
+func TestNewSource_Missing(t *testing.T) {
+	t.Parallel()
+
+	_, err := mmap.NewSource(filepath.Join(t.TempDir(), "missing"))
+	if !errors.Is(err, os.ErrNotExist) {
+		t.Fatalf("NewSource() error = %v, want %v", err, os.ErrNotExist)
+	}
+}
+
+func TestSource_Blob(t *testing.T) {
+	t.Parallel()
+
+	srcDir := t.TempDir()
+	content := bytes.Repeat([]byte("mapped "), 512)
+	if err := os.WriteFile(filepath.Join(srcDir, "file.txt"), content, 0o644); err != nil {
+		t.Fatalf("WriteFile() error = %v", err)
+	}
+
+	destDir := t.TempDir()
+	bf, err := blob.CreateBlob(context.Background(), srcDir, destDir,
+		blob.CreateBlobWithCompression(blob.CompressionZstd))
+	if err != nil {
+		t.Fatalf("CreateBlob() error = %v", err)
+	}
+	bf.Close()
+
+	indexData, err := os.ReadFile(filepath.Join(destDir, blob.DefaultIndexName))
+	if err != nil {
+		t.Fatalf("ReadFile() error = %v", err)
+	}
+	src, err := mmap.NewSource(filepath.Join(destDir, blob.DefaultDataName))
+	if err != nil {
+		t.Fatalf("NewSource() error = %v", err)
+	}
+	t.Cleanup(func() { src.Close() })
+
+	b, err := blob.New(indexData, src)
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	got, err := b.ReadFile("file.txt")
+	if err != nil {
+		t.Fatalf("ReadFile() error = %v", err)
+	}
+	if !bytes.Equal(got, content) {
+		t.Fatal("ReadFile() content mismatch")
+	}
+}
+
+func BenchmarkReadFile(b *testing.B) {
+	srcDir := b.TempDir()
+	content := bytes.Repeat([]byte("0123456789abcdef"), 4096)
+	if err := os.WriteFile(filepath.Join(srcDir, "file.bin"), content, 0o644); err != nil {
+		b.Fatalf("WriteFile() error = %v", err)
+	}
+	destDir := b.TempDir()
+	bf, err := blob.CreateBlob(context.Background(), srcDir, destDir,
+		blob.CreateBlobWithCompression(blob.CompressionNone))
+	if err != nil {
+		b.Fatalf("CreateBlob() error = %v", err)
+	}
+	b.Cleanup(func() { bf.Close() })
+
+	indexData, err := os.ReadFile(filepath.Join(destDir, blob.DefaultIndexName))
+	if err != nil {
+		b.Fatalf("ReadFile() error = %v", err)
+	}
+	src, err := mmap.NewSource(filepath.Join(destDir, blob.DefaultDataName))
+	if err != nil {
+		b.Fatalf("NewSource() error = %v", err)
+	}
+	b.Cleanup(func() { src.Close() })
+	mapped, err := blob.New(indexData, src)
+	if err != nil {
+		b.Fatalf("New() error = %v", err)
+	}
+
+	for _, bc := range []struct {
+		name string
+		blob *blob.Blob
+	}{
+		{name: "file", blob: bf.Blob},
+		{name: "mmap", blob: mapped},
+	} {
+		b.Run(bc.name, func(b *testing.B) {
+			b.SetBytes(int64(len(content)))
+			b.ReportAllocs()
+			for b.Loop() {
+				if _, err := bc.blob.ReadFile("file.bin"); err != nil {
+					b.Fatalf("ReadFile() error = %v", err)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,88 @@
+package blob
+
+import (
+	"iter"
+	"sync"
+
+	"github.com/meigma/blob/core/internal/blobtype"
+)
+
+// entryIndex is the part of the index used to resolve single paths.
+// It is an interface so tests can observe index lookups.
+type entryIndex interface {
+	LookupView(path string) (blobtype.EntryView, bool)
+	EntriesWithPrefixView(prefix string) iter.Seq[blobtype.EntryView]
+}
+
+// negativeCache remembers archive paths that have no index entry.
+//
+// Each path records whether it is also known not to be a directory, so
+// repeated lookups of a missing path skip both the entry lookup and the
+// directory prefix scan. The cache is bounded by count and evicts the
+// oldest path first. A nil *negativeCache is valid and caches nothing.
+type negativeCache struct {
+	mu    sync.Mutex
+	max   int
+	paths map[string]bool // path -> known not to be a directory
+	order []string        // insertion order, used as a ring once full
+	next  int
+}
+
+// newNegativeCache returns a cache holding up to maxEntries paths, or nil
+// if maxEntries is not positive.
+func newNegativeCache(maxEntries int) *negativeCache {
+	if maxEntries <= 0 {
+		return nil
+	}
+	return &negativeCache{
+		max:   maxEntries,
+		paths: make(map[string]bool),
+	}
+}
+
+// get reports whether name is cached as missing and, if so, whether it is
+// also known not to be a directory.
+func (c *negativeCache) get(name string) (notDir, ok bool) {
+	if c == nil {
+		return false, false
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	notDir, ok = c.paths[name]
+	return notDir, ok
+}
+
+// add records name as missing. Adding a cached path updates notDir without
+// changing its eviction order.
+func (c *negativeCache) add(name string, notDir bool) {
+	if c == nil {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if _, ok := c.paths[name]; ok {
+		c.paths[name] = c.paths[name] || notDir
+		return
+	}
+	if len(c.order) < c.max {
+		c.order = append(c.order, name)
+	} else {
+		delete(c.paths, c.order[c.next])
+		c.order[c.next] = name
+		c.next = (c.next + 1) % c.max
+	}
+	c.paths[name] = notDir
+}
+
+// lookup returns the index entry for the archive path name, consulting the
+// negative cache when one is configured.
+func (b *Blob) lookup(name string) (EntryView, bool) {
+	if _, ok := b.missing.get(name); ok {
+		return EntryView{}, false
+	}
+	view, ok := b.lookupIndex.LookupView(name)
+	if !ok {
+		b.missing.add(name, false)
+	}
+	return view, ok
+}
@@ -0,0 +1,118 @@
+package blob
+
+import (
+	"io/fs"
+	"iter"
+	"sync/atomic"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// countingIndex wraps an entryIndex and counts path lookups and prefix scans.
+type countingIndex struct {
+	entryIndex
+	lookups atomic.Int64
+	scans   atomic.Int64
+}
+
+func (c *countingIndex) LookupView(path string) (EntryView, bool) {
+	c.lookups.Add(1)
+	return c.entryIndex.LookupView(path)
+}
+
+func (c *countingIndex) EntriesWithPrefixView(prefix string) iter.Seq[EntryView] {
+	c.scans.Add(1)
+	return c.entryIndex.EntriesWithPrefixView(prefix)
+}
+
+func (c *countingIndex) total() int64 {
+	return c.lookups.Load() + c.scans.Load()
+}
+
+func newNegativeCacheBlob(t *testing.T, opts ...Option) (*Blob, *countingIndex) {
+	t.Helper()
+	b := createTestArchive(t, map[string][]byte{
+		"config/app.json": []byte("{}"),
+		"lib/a.txt":       []byte("a"),
+	}, CompressionNone)
+	// Rebuild with the requested options over the same archive.
+	nb, err := New(b.IndexData(), b.reader.Source(), opts...)
+	require.NoError(t, err)
+	idx := &countingIndex{entryIndex: nb.lookupIndex}
+	nb.lookupIndex = idx
+	return nb, idx
+}
+
+func TestNegativeCache(t *testing.T) {
+	t.Parallel()
+
+	t.Run("repeated miss skips the index", func(t *testing.T) {
+		t.Parallel()
+		b, idx := newNegativeCacheBlob(t, WithNegativeCache(8))
+
+		_, err := b.Stat("feature-flags.json")
+		require.ErrorIs(t, err, fs.ErrNotExist)
+		require.Positive(t, idx.total())
+
+		before := idx.total()
+		_, err = b.Stat("feature-flags.json")
+		require.ErrorIs(t, err, fs.ErrNotExist)
+		_, err = b.Open("feature-flags.json")
+		require.ErrorIs(t, err, fs.ErrNotExist)
+		_, err = b.ReadFile("feature-flags.json")
+		require.ErrorIs(t, err, fs.ErrNotExist)
+		assert.False(t, b.Exists("feature-flags.json"))
+		assert.Equal(t, before, idx.total())
+	})
+
+	t.Run("implicit directories still resolve", func(t *testing.T) {
+		t.Parallel()
+		b, _ := newNegativeCacheBlob(t, WithNegativeCache(8))
+
+		for range 2 {
+			_, err := b.ReadFile("config")
+			require.ErrorIs(t, err, fs.ErrNotExist)
+			info, err := b.Stat("config")
+			require.NoError(t, err)
+			assert.True(t, info.IsDir())
+		}
+	})
+
+	t.Run("bounded by count", func(t *testing.T) {
+		t.Parallel()
+		b, idx := newNegativeCacheBlob(t, WithNegativeCache(1))
+
+		assert.False(t, b.Exists("missing-1"))
+		assert.False(t, b.Exists("missing-2"))
+
+		before := idx.total()
+		assert.False(t, b.Exists("missing-2"))
+		assert.Equal(t, before, idx.total(), "most recent miss should be cached")
+		assert.False(t, b.Exists("missing-1"))
+		assert.Greater(t, idx.total(), before, "evicted miss should hit the index")
+	})
+
+	t.Run("disabled by default", func(t *testing.T) {
+		t.Parallel()
+		b, idx := newNegativeCacheBlob(t)
+
+		assert.False(t, b.Exists("missing"))
+		before := idx.total()
+		assert.False(t, b.Exists("missing"))
+		assert.Greater(t, idx.total(), before)
+	})
+
+	t.Run("shared with subsets", func(t *testing.T) {
+		t.Parallel()
+		b, idx := newNegativeCacheBlob(t, WithNegativeCache(8))
+		sub, err := b.Subset("config")
+		require.NoError(t, err)
+
+		assert.False(t, b.Exists("config/missing.json"))
+		before := idx.total()
+		assert.False(t, sub.Exists("missing.json"))
+		assert.Equal(t, before, idx.total())
+	})
+}
@@ -0,0 +1,57 @@
+package blob
+
+import (
+	"fmt"
+	"strings"
+)
+
+// PathLimit identifies which path limit was exceeded.
+type PathLimit string
+
+// Path limits.
+const (
+	// PathLimitLength is the limit on a path's length in bytes.
+	PathLimitLength PathLimit = "length"
+
+	// PathLimitDepth is the limit on the number of elements in a path.
+	PathLimitDepth PathLimit = "depth"
+)
+
+// PathLimitError describes a path rejected by a length or depth limit.
+//
+// It matches ErrPathLimit with errors.Is.
+type PathLimitError struct {
+	Path   string    // The offending path, slash-separated and relative to the archive root
+	Limit  PathLimit // Which limit was exceeded
+	Max    int       // The configured limit
+	Actual int       // The path's length or depth
+}
+
+func (e *PathLimitError) Error() string {
+	return fmt.Sprintf("%s: path %s %d exceeds max %d: %s", ErrPathLimit, e.Limit, e.Actual, e.Max, e.Path)
+}
+
+// Unwrap returns ErrPathLimit.
+func (e *PathLimitError) Unwrap() error {
+	return ErrPathLimit
+}
+
+// pathLimits bounds the length and depth of archive paths.
+// Zero or negative values disable a limit.
+type pathLimits struct {
+	maxLength int
+	maxDepth  int
+}
+
+// check returns a *PathLimitError if path exceeds either limit.
+func (l pathLimits) check(path string) error {
+	if l.maxLength > 0 && len(path) > l.maxLength {
+		return &PathLimitError{Path: path, Limit: PathLimitLength, Max: l.maxLength, Actual: len(path)}
+	}
+	if l.maxDepth > 0 {
+		if depth := strings.Count(path, "/") + 1; depth > l.maxDepth {
+			return &PathLimitError{Path: path, Limit: PathLimitDepth, Max: l.maxDepth, Actual: depth}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,104 @@
+package blob
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCreate_PathLimits(t *testing.T) {
+	t.Parallel()
+
+	deep := "a/b/c/d/e/deep.txt"
+	files := map[string][]byte{
+		"top.txt": []byte("top"),
+		deep:      []byte("deep"),
+	}
+
+	tests := []struct {
+		name   string
+		opt    CreateOption
+		limit  PathLimit
+		max    int
+		actual int
+	}{
+		{name: "depth", opt: CreateWithMaxPathDepth(3), limit: PathLimitDepth, max: 3, actual: 6},
+		{name: "length", opt: CreateWithMaxPathLength(10), limit: PathLimitLength, max: 10, actual: len(deep)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			dir := t.TempDir()
+			createTestFilesBytes(t, dir, files)
+
+			var indexBuf, dataBuf bytes.Buffer
+			err := Create(context.Background(), dir, &indexBuf, &dataBuf, tt.opt)
+			require.ErrorIs(t, err, ErrPathLimit)
+
+			var limitErr *PathLimitError
+			require.ErrorAs(t, err, &limitErr)
+			assert.Equal(t, deep, limitErr.Path)
+			assert.Equal(t, tt.limit, limitErr.Limit)
+			assert.Equal(t, tt.max, limitErr.Max)
+			assert.Equal(t, tt.actual, limitErr.Actual)
+		})
+	}
+
+	t.Run("within limits", func(t *testing.T) {
+		t.Parallel()
+
+		dir := t.TempDir()
+		createTestFilesBytes(t, dir, files)
+
+		var indexBuf, dataBuf bytes.Buffer
+		err := Create(context.Background(), dir, &indexBuf, &dataBuf,
+			CreateWithMaxPathDepth(6), CreateWithMaxPathLength(len(deep)))
+		require.NoError(t, err)
+	})
+}
+
+func TestCopy_PathLimits(t *testing.T) {
+	t.Parallel()
+
+	deep := "a/b/c/d/e/deep.txt"
+	b := createTestArchive(t, map[string][]byte{
+		"top.txt": []byte("top"),
+		deep:      []byte("deep"),
+	}, CompressionNone)
+
+	t.Run("CopyDir depth", func(t *testing.T) {
+		t.Parallel()
+
+		dest := t.TempDir()
+		_, err := b.CopyDir(dest, ".", CopyWithMaxPathDepth(3))
+		var limitErr *PathLimitError
+		require.ErrorAs(t, err, &limitErr)
+		assert.Equal(t, PathLimitDepth, limitErr.Limit)
+		assert.Equal(t, deep, limitErr.Path)
+
+		_, statErr := os.Stat(filepath.Join(dest, "top.txt"))
+		assert.True(t, os.IsNotExist(statErr), "nothing should be written")
+	})
+
+	t.Run("CopyToWithOptions length", func(t *testing.T) {
+		t.Parallel()
+
+		_, err := b.CopyToWithOptions(t.TempDir(), []string{"top.txt", deep}, CopyWithMaxPathLength(len(deep)-1))
+		require.ErrorIs(t, err, ErrPathLimit)
+		assert.Contains(t, err.Error(), deep)
+	})
+
+	t.Run("within limits", func(t *testing.T) {
+		t.Parallel()
+
+		stats, err := b.CopyDir(t.TempDir(), ".", CopyWithMaxPathDepth(6), CopyWithMaxPathLength(len(deep)))
+		require.NoError(t, err)
+		assert.Equal(t, 2, stats.FileCount)
+	})
+}
@@ -0,0 +1,124 @@
+package blob
+
+import (
+	"bytes"
+	"context"
+	"io/fs"
+
+	"github.com/meigma/blob/core/cache"
+	"github.com/meigma/blob/core/internal/batch"
+	"github.com/meigma/blob/core/internal/blobtype"
+)
+
+// PrefetchFiles reads the named files into the cache so that later reads
+// are served without touching the data source.
+//
+// Entries are deduplicated by content hash and skipped when already cached.
+// The remaining entries are read with the same batch pipeline as CopyDir:
+// entries stored next to each other in the data blob are fetched with a
+// single range read, and content is hash-verified before it is cached.
+//
+// All names are resolved before anything is read; a missing name or a
+// directory fails with an *fs.PathError wrapping fs.ErrNotExist. Without a
+// cache (see WithCache), PrefetchFiles only resolves the names.
+func (b *Blob) PrefetchFiles(ctx context.Context, paths ...string) error {
+	entries := make([]*batch.Entry, 0, len(paths))
+	seen := make(map[string]struct{}, len(paths))
+	for _, name := range paths {
+		if !fs.ValidPath(name) {
+			return &fs.PathError{Op: "prefetch", Path: name, Err: fs.ErrInvalid}
+		}
+		view, ok := b.lookup(b.resolve(name))
+		if !ok || view.Mode().IsDir() {
+			return &fs.PathError{Op: "prefetch", Path: name, Err: fs.ErrNotExist}
+		}
+		entry := blobtype.EntryFromViewWithPath(view, name)
+		if _, dup := seen[string(entry.Hash)]; dup {
+			continue
+		}
+		seen[string(entry.Hash)] = struct{}{}
+		entries = append(entries, &entry)
+	}
+	if b.cache == nil {
+		return ctx.Err()
+	}
+	ctx, cancel := withRetryScope(ctx)
+	defer cancel()
+	return b.cacheEntries(ctx, entries)
+}
+
+// newBatchProcessor returns a batch processor over the archive data with
+// the default read concurrency, the Blob's logger and cipher, and opts.
+func (b *Blob) newBatchProcessor(opts ...batch.ProcessorOption) *batch.Processor {
+	procOpts := []batch.ProcessorOption{batch.WithReadConcurrency(defaultCopyReadConcurrency)}
+	if b.logger != nil {
+		procOpts = append(procOpts, batch.WithProcessorLogger(b.logger))
+	}
+	if aead := b.reader.Cipher(); aead != nil {
+		procOpts = append(procOpts, batch.WithCipher(aead))
+	}
+	procOpts = append(procOpts, opts...)
+	return batch.NewProcessor(b.reader.Source(), b.reader.Pool(), b.maxFileSize, procOpts...)
+}
+
+// cacheEntries reads entries that are not yet cached with the batch
+// pipeline and stores their verified content in the cache.
+func (b *Blob) cacheEntries(ctx context.Context, entries []*batch.Entry) error {
+	if len(entries) == 0 {
+		return ctx.Err()
+	}
+
+	stats, err := b.newBatchProcessor().ProcessContext(ctx, entries, &cacheSink{cache: b.cache})
+	b.log().Debug("prefetch complete", "cached", stats.Processed, "skipped", stats.Skipped)
+	if err != nil {
+		return err
+	}
+	return ctx.Err()
+}
+
+// cacheSink is a batch.Sink that stores verified content in a cache.
+type cacheSink struct {
+	cache cache.Cache
+}
+
+var _ batch.BufferedSink = (*cacheSink)(nil)
+
+// ShouldProcess returns false if the entry's content is already cached.
+func (s *cacheSink) ShouldProcess(entry *batch.Entry) bool {
+	f, ok := s.cache.Get(entry.Hash)
+	if ok {
+		_ = f.Close()
+	}
+	return !ok
+}
+
+// PutBuffered stores decoded content in the cache.
+func (s *cacheSink) PutBuffered(entry *batch.Entry, content []byte) error {
+	return s.cache.Put(entry.Hash, &bytesFile{
+		Reader: bytes.NewReader(content),
+		size:   int64(len(content)),
+	})
+}
+
+// Writer returns a committer that buffers content and caches it on Commit.
+func (s *cacheSink) Writer(entry *batch.Entry) (batch.Committer, error) {
+	return &cacheCommitter{sink: s, entry: entry}, nil
+}
+
+// cacheCommitter buffers an entry's content until it is verified.
+type cacheCommitter struct {
+	bytes.Buffer
+	sink  *cacheSink
+	entry *batch.Entry
+}
+
+// Commit stores the buffered content in the cache.
+func (c *cacheCommitter) Commit() error {
+	return c.sink.PutBuffered(c.entry, c.Bytes())
+}
+
+// Discard drops the buffered content.
+func (c *cacheCommitter) Discard() error {
+	c.Reset()
+	return nil
+}
@@ -0,0 +1,115 @@
+package blob
+
+import (
+	"bytes"
+	"context"
+	"io/fs"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/meigma/blob/core/testutil"
+)
+
+func TestPrefetchFiles(t *testing.T) {
+	t.Parallel()
+
+	files := map[string][]byte{
+		"a.txt":     []byte("alpha content"),
+		"b.txt":     []byte("bravo content"),
+		"dup.txt":   []byte("alpha content"),
+		"sub/c.txt": bytes.Repeat([]byte("charlie "), 64),
+		"sub/d.txt": []byte("delta content"),
+	}
+	var indexBuf, dataBuf bytes.Buffer
+	dir := t.TempDir()
+	createTestFilesBytes(t, dir, files)
+	require.NoError(t, Create(context.Background(), dir, &indexBuf, &dataBuf, CreateWithCompression(CompressionZstd)))
+
+	newBlob := func(t *testing.T) (*Blob, *countingSource, *testutil.MockCache) {
+		t.Helper()
+		src := newCountingSource(testutil.NewMockByteSource(dataBuf.Bytes()))
+		c := testutil.NewMockCache()
+		b, err := New(indexBuf.Bytes(), src, WithCache(c))
+		require.NoError(t, err)
+		return b, src, c
+	}
+
+	t.Run("reads are served from cache", func(t *testing.T) {
+		t.Parallel()
+		b, src, c := newBlob(t)
+
+		paths := []string{"a.txt", "b.txt", "dup.txt", "sub/c.txt"}
+		require.NoError(t, b.PrefetchFiles(context.Background(), paths...))
+		want := len(files["a.txt"]) + len(files["b.txt"]) + len(files["sub/c.txt"])
+		assert.Equal(t, int64(want), c.SizeBytes(), "identical content should be cached once")
+
+		src.Reset()
+		for _, p := range paths {
+			content, err := b.ReadFile(p)
+			require.NoError(t, err)
+			assert.Equal(t, files[p], content)
+		}
+		assert.Zero(t, src.RangeRequests())
+	})
+
+	t.Run("adjacent entries share a range read", func(t *testing.T) {
+		t.Parallel()
+		b, src, _ := newBlob(t)
+
+		require.NoError(t, b.PrefetchFiles(context.Background(), "a.txt", "b.txt"))
+		assert.Equal(t, int64(1), src.RangeRequests())
+	})
+
+	t.Run("cached entries are skipped", func(t *testing.T) {
+		t.Parallel()
+		b, src, _ := newBlob(t)
+
+		_, err := b.ReadFile("b.txt")
+		require.NoError(t, err)
+		require.NoError(t, b.PrefetchFiles(context.Background(), "b.txt"))
+
+		src.Reset()
+		require.NoError(t, b.PrefetchFiles(context.Background(), "b.txt"))
+		assert.Zero(t, src.RangeRequests())
+	})
+
+	t.Run("missing path", func(t *testing.T) {
+		t.Parallel()
+		b, src, c := newBlob(t)
+
+		err := b.PrefetchFiles(context.Background(), "a.txt", "missing.txt")
+		var pathErr *fs.PathError
+		require.ErrorAs(t, err, &pathErr)
+		assert.Equal(t, "missing.txt", pathErr.Path)
+		assert.ErrorIs(t, err, fs.ErrNotExist)
+		assert.Zero(t, src.RangeRequests())
+		assert.Zero(t, c.SizeBytes())
+	})
+
+	t.Run("directory", func(t *testing.T) {
+		t.Parallel()
+		b, _, _ := newBlob(t)
+		assert.ErrorIs(t, b.PrefetchFiles(context.Background(), "sub"), fs.ErrNotExist)
+	})
+
+	t.Run("canceled", func(t *testing.T) {
+		t.Parallel()
+		b, _, c := newBlob(t)
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+		err := b.PrefetchFiles(ctx, "a.txt")
+		assert.ErrorIs(t, err, context.Canceled)
+		assert.Zero(t, c.SizeBytes())
+	})
+
+	t.Run("no cache", func(t *testing.T) {
+		t.Parallel()
+		src := newCountingSource(testutil.NewMockByteSource(dataBuf.Bytes()))
+		b, err := New(indexBuf.Bytes(), src)
+		require.NoError(t, err)
+		require.NoError(t, b.PrefetchFiles(context.Background(), "a.txt"))
+		assert.Zero(t, src.RangeRequests())
+	})
+}
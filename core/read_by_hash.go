@@ -0,0 +1,90 @@
+package blob
+
+import (
+	"bytes"
+	"context"
+	"slices"
+	"strings"
+	"sync"
+
+	"github.com/meigma/blob/core/internal/index"
+)
+
+// hashIndex lists index positions of non-directory entries ordered by
+// content hash. It is built on first use and shared by Subset views.
+type hashIndex struct {
+	once      sync.Once
+	positions []int
+	hashes    [][]byte
+}
+
+// build fills the hash-ordered tables from idx. The hashes alias the index
+// buffer.
+func (h *hashIndex) build(idx *index.Index) {
+	h.once.Do(func() {
+		type located struct {
+			pos  int
+			hash []byte
+		}
+		n := idx.Len()
+		all := make([]located, 0, n)
+		for i := range n {
+			view, ok := idx.ViewAt(i)
+			if !ok || view.Mode().IsDir() {
+				continue
+			}
+			all = append(all, located{pos: i, hash: view.HashBytes()})
+		}
+		slices.SortStableFunc(all, func(a, b located) int {
+			return bytes.Compare(a.hash, b.hash)
+		})
+		h.positions = make([]int, len(all))
+		h.hashes = make([][]byte, len(all))
+		for i, l := range all {
+			h.positions[i] = l.pos
+			h.hashes[i] = l.hash
+		}
+	})
+}
+
+// ReadByHash returns the content of a file whose SHA256 content hash is
+// hash, for content-addressed access when the path is not known.
+//
+// Identical files share a hash, so when several entries match, any one of
+// them is used; their content is the same. ok is false, with a nil error,
+// when no file in the archive (or Subset view) has the hash. Reads go
+// through the content cache, which is keyed by hash, and the content is
+// verified like ReadFile.
+func (b *Blob) ReadByHash(hash []byte) (content []byte, ok bool, err error) {
+	name, ok := b.pathForHash(hash)
+	if !ok {
+		return nil, false, nil
+	}
+	content, err = b.ReadFileContext(context.Background(), name)
+	if err != nil {
+		return nil, true, err
+	}
+	return content, true, nil
+}
+
+// pathForHash returns the path, relative to the view root, of an entry
+// with the given content hash.
+func (b *Blob) pathForHash(hash []byte) (string, bool) {
+	h := b.hashes
+	h.build(b.idx)
+	i, found := slices.BinarySearchFunc(h.hashes, hash, bytes.Compare)
+	if !found {
+		return "", false
+	}
+	prefix := b.rootPrefix()
+	for ; i < len(h.hashes) && bytes.Equal(h.hashes[i], hash); i++ {
+		view, ok := b.idx.ViewAt(h.positions[i])
+		if !ok {
+			continue
+		}
+		if name := view.Path(); strings.HasPrefix(name, prefix) {
+			return name[len(prefix):], true
+		}
+	}
+	return "", false
+}
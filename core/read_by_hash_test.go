@@ -0,0 +1,92 @@
+package blob
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/meigma/blob/core/testutil"
+)
+
+func TestReadByHash(t *testing.T) {
+	t.Parallel()
+
+	shared := bytes.Repeat([]byte("deduplicated content "), 64)
+	unique := []byte("unique content")
+	files := map[string][]byte{
+		"a/copy.txt":  shared,
+		"b/copy.txt":  shared,
+		"c/other.txt": unique,
+	}
+	sharedHash := sha256.Sum256(shared)
+	uniqueHash := sha256.Sum256(unique)
+	unknownHash := sha256.Sum256([]byte("not in the archive"))
+
+	t.Run("finds deduplicated content", func(t *testing.T) {
+		t.Parallel()
+
+		b := createTestArchive(t, files, CompressionZstd)
+		content, ok, err := b.ReadByHash(sharedHash[:])
+		require.NoError(t, err)
+		require.True(t, ok)
+		assert.Equal(t, shared, content)
+
+		content, ok, err = b.ReadByHash(uniqueHash[:])
+		require.NoError(t, err)
+		require.True(t, ok)
+		assert.Equal(t, unique, content)
+	})
+
+	t.Run("unknown hash", func(t *testing.T) {
+		t.Parallel()
+
+		b := createTestArchive(t, files, CompressionNone)
+		content, ok, err := b.ReadByHash(unknownHash[:])
+		require.NoError(t, err)
+		assert.False(t, ok)
+		assert.Nil(t, content)
+
+		_, ok, err = b.ReadByHash(nil)
+		require.NoError(t, err)
+		assert.False(t, ok)
+	})
+
+	t.Run("serves from cache", func(t *testing.T) {
+		t.Parallel()
+
+		b, source := createTestArchiveWithSource(t, files)
+		counting := &countingByteSource{source: source}
+		cached, err := New(b.IndexData(), counting, WithCache(testutil.NewMockCache()))
+		require.NoError(t, err)
+
+		_, err = cached.ReadFile("a/copy.txt")
+		require.NoError(t, err)
+		reads := counting.ReadCount()
+
+		content, ok, err := cached.ReadByHash(sharedHash[:])
+		require.NoError(t, err)
+		require.True(t, ok)
+		assert.Equal(t, shared, content)
+		assert.Equal(t, reads, counting.ReadCount(), "cached content must not be read again")
+	})
+
+	t.Run("subset only sees its entries", func(t *testing.T) {
+		t.Parallel()
+
+		b := createTestArchive(t, files, CompressionNone)
+		sub, err := b.Subset("c")
+		require.NoError(t, err)
+
+		content, ok, err := sub.ReadByHash(uniqueHash[:])
+		require.NoError(t, err)
+		require.True(t, ok)
+		assert.Equal(t, unique, content)
+
+		_, ok, err = sub.ReadByHash(sharedHash[:])
+		require.NoError(t, err)
+		assert.False(t, ok)
+	})
+}
@@ -0,0 +1,82 @@
+package blob
+
+import (
+	"io"
+)
+
+// NewReaderAtSource returns a ByteSource that reads the data blob from ra.
+//
+// It adapts data already available as an io.ReaderAt, such as a
+// bytes.Reader, an io.SectionReader, or a custom store, without a type of
+// its own. size is the length of the data blob and sourceID its stable
+// identifier (see ByteSource). Reads are bounded by size, so ra may hold
+// more data than the blob; reads ending at size return io.EOF with the
+// bytes read, as io.ReaderAt allows.
+//
+// When ra also implements ReadRange(off, length int64) (io.ReadCloser,
+// error), the returned source does too, so zstd entries are decoded from
+// a range stream instead of through ReadAt.
+func NewReaderAtSource(ra io.ReaderAt, size int64, sourceID string) ByteSource {
+	src := &readerAtSource{ra: ra, size: size, sourceID: sourceID}
+	if rr, ok := ra.(rangeReader); ok {
+		return &readerAtRangeSource{readerAtSource: src, rr: rr}
+	}
+	return src
+}
+
+// NewReadSeeker returns a reader over the whole of source that supports
+// seeking, for libraries that expect an io.ReadSeeker rather than an
+// io.ReaderAt. Reads go through source.ReadAt at the current position, so
+// seeking is free and no data is buffered.
+//
+// The returned *io.SectionReader also implements io.ReaderAt. Each call
+// returns an independent reader positioned at the start.
+func NewReadSeeker(source ByteSource) *io.SectionReader {
+	return io.NewSectionReader(source, 0, source.Size())
+}
+
+// readerAtSource adapts an io.ReaderAt to ByteSource.
+type readerAtSource struct {
+	ra       io.ReaderAt
+	size     int64
+	sourceID string
+}
+
+// ReadAt implements io.ReaderAt, bounded by the source size. Negative
+// offsets are passed to the underlying reader, which rejects them.
+func (s *readerAtSource) ReadAt(p []byte, off int64) (int, error) {
+	if off >= s.size {
+		return 0, io.EOF
+	}
+	if remaining := s.size - off; int64(len(p)) > remaining {
+		n, err := s.ra.ReadAt(p[:remaining], off)
+		if err == nil {
+			err = io.EOF
+		}
+		return n, err
+	}
+	return s.ra.ReadAt(p, off)
+}
+
+// Size returns the size of the data blob.
+func (s *readerAtSource) Size() int64 {
+	return s.size
+}
+
+// SourceID returns the identifier given to NewReaderAtSource.
+func (s *readerAtSource) SourceID() string {
+	return s.sourceID
+}
+
+// readerAtRangeSource is a readerAtSource whose reader supports range
+// streams.
+type readerAtRangeSource struct {
+	*readerAtSource
+	rr rangeReader
+}
+
+// ReadRange returns a reader for [off, off+length) from the underlying
+// reader.
+func (s *readerAtRangeSource) ReadRange(off, length int64) (io.ReadCloser, error) {
+	return s.rr.ReadRange(off, length)
+}
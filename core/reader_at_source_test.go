@@ -0,0 +1,187 @@
+package blob
+
+import (
+	"bytes"
+	"io"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// rangeReaderAt is a countingReaderAt that also serves range streams.
+type rangeReaderAt struct {
+	*countingReaderAt
+	ranges atomic.Int32
+}
+
+func (r *rangeReaderAt) ReadRange(off, length int64) (io.ReadCloser, error) {
+	r.ranges.Add(1)
+	return io.NopCloser(io.NewSectionReader(r.countingReaderAt, off, length)), nil
+}
+
+func TestReaderAtSource(t *testing.T) {
+	t.Parallel()
+
+	// The reader holds trailing bytes beyond the data blob.
+	src := NewReaderAtSource(bytes.NewReader([]byte("0123456789trailer")), 10, "mem:test")
+	assert.Equal(t, int64(10), src.Size())
+	assert.Equal(t, "mem:test", src.SourceID())
+
+	buf := make([]byte, 4)
+	n, err := src.ReadAt(buf, 2)
+	require.NoError(t, err)
+	assert.Equal(t, "2345", string(buf[:n]))
+
+	n, err = src.ReadAt(buf, 8)
+	assert.Equal(t, io.EOF, err)
+	assert.Equal(t, "89", string(buf[:n]))
+
+	n, err = src.ReadAt(buf, 10)
+	assert.Equal(t, io.EOF, err)
+	assert.Zero(t, n)
+
+	_, err = src.ReadAt(buf, -1)
+	assert.Error(t, err)
+
+	_, ok := src.(rangeReader)
+	assert.False(t, ok, "bytes.Reader does not support range streams")
+}
+
+func TestReaderAtSource_CopyDir(t *testing.T) {
+	t.Parallel()
+
+	files := map[string][]byte{
+		"a.txt":       []byte("alpha"),
+		"b.txt":       []byte("bravo"),
+		"dir/c.txt":   []byte("charlie"),
+		"dir/d/e.txt": bytes.Repeat([]byte("echo "), 100),
+	}
+	archive := createTestArchive(t, files, CompressionZstd)
+	data := storedData(t, archive)
+
+	ra := newCountingReaderAt(bytes.NewReader(data))
+	b, err := New(archive.IndexData(), NewReaderAtSource(ra, int64(len(data)), "mem:copy"))
+	require.NoError(t, err)
+
+	dest := t.TempDir()
+	stats, err := b.CopyDir(dest, ".")
+	require.NoError(t, err)
+	assert.Equal(t, len(files), stats.FileCount)
+	for name, want := range files {
+		got, err := os.ReadFile(filepath.Join(dest, filepath.FromSlash(name)))
+		require.NoError(t, err)
+		assert.Equal(t, want, got, name)
+	}
+	// Adjacent entries are fetched with a single ReadAt.
+	assert.Equal(t, int64(1), atomic.LoadInt64(&ra.readCalls))
+}
+
+func TestReaderAtSource_RangeStreams(t *testing.T) {
+	t.Parallel()
+
+	content := bytes.Repeat([]byte("compressible "), 200)
+	archive := createTestArchive(t, map[string][]byte{"big.txt": content}, CompressionZstd)
+	data := storedData(t, archive)
+
+	ra := &rangeReaderAt{countingReaderAt: newCountingReaderAt(bytes.NewReader(data))}
+	src := NewReaderAtSource(ra, int64(len(data)), "mem:range")
+	_, ok := src.(rangeReader)
+	require.True(t, ok)
+
+	b, err := New(archive.IndexData(), src)
+	require.NoError(t, err)
+	f, err := b.Open("big.txt")
+	require.NoError(t, err)
+	got, err := io.ReadAll(f)
+	require.NoError(t, err)
+	require.NoError(t, f.Close())
+	assert.Equal(t, content, got)
+	assert.Equal(t, int32(1), ra.ranges.Load())
+}
+
+// storedData returns the whole data blob of archive.
+func storedData(t *testing.T, archive *Blob) []byte {
+	t.Helper()
+	src := archive.Reader().Source()
+	data, err := io.ReadAll(io.NewSectionReader(src, 0, src.Size()))
+	require.NoError(t, err)
+	return data
+}
+
+func TestNewReadSeeker(t *testing.T) {
+	t.Parallel()
+
+	data := []byte("0123456789abcdefghij")
+	rs := NewReadSeeker(NewReaderAtSource(bytes.NewReader(data), int64(len(data)), "mem:seek"))
+
+	read := func(n int) (string, error) {
+		buf := make([]byte, n)
+		got, err := io.ReadFull(rs, buf)
+		return string(buf[:got]), err
+	}
+
+	pos, err := rs.Seek(5, io.SeekStart)
+	require.NoError(t, err)
+	assert.Equal(t, int64(5), pos)
+	got, err := read(3)
+	require.NoError(t, err)
+	assert.Equal(t, "567", got)
+
+	pos, err = rs.Seek(2, io.SeekCurrent)
+	require.NoError(t, err)
+	assert.Equal(t, int64(10), pos)
+	got, err = read(2)
+	require.NoError(t, err)
+	assert.Equal(t, "ab", got)
+
+	// A read across the end returns the remaining bytes.
+	_, err = rs.Seek(-3, io.SeekEnd)
+	require.NoError(t, err)
+	got, err = read(8)
+	assert.ErrorIs(t, err, io.ErrUnexpectedEOF)
+	assert.Equal(t, "hij", got)
+	_, err = read(1)
+	assert.ErrorIs(t, err, io.EOF)
+
+	// Seeking back after EOF reads again.
+	_, err = rs.Seek(0, io.SeekStart)
+	require.NoError(t, err)
+	all, err := io.ReadAll(rs)
+	require.NoError(t, err)
+	assert.Equal(t, data, all)
+
+	_, err = rs.Seek(-1, io.SeekStart)
+	assert.Error(t, err)
+}
+
+func TestBlobSectionReader(t *testing.T) {
+	t.Parallel()
+
+	archive := createTestArchive(t, map[string][]byte{
+		"a.txt": []byte("alpha"),
+		"b.txt": []byte("bravo"),
+	}, CompressionNone)
+
+	r := archive.SectionReader()
+	assert.Equal(t, archive.Size(), r.Size())
+
+	// Seek straight to a file's stored bytes.
+	view, ok := archive.Entry("b.txt")
+	require.True(t, ok)
+	_, err := r.Seek(int64(view.DataOffset()), io.SeekStart) //nolint:gosec // test offsets are small
+	require.NoError(t, err)
+	buf := make([]byte, view.DataSize())
+	_, err = io.ReadFull(r, buf)
+	require.NoError(t, err)
+	assert.Equal(t, "bravo", string(buf))
+
+	// Readers are independent.
+	other := archive.SectionReader()
+	all, err := io.ReadAll(other)
+	require.NoError(t, err)
+	assert.Equal(t, storedData(t, archive), all)
+}
@@ -0,0 +1,415 @@
+package blob
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/meigma/blob/core/internal/file"
+)
+
+// Retry defaults used by RetryingSource.
+const (
+	DefaultRetryMaxAttempts    = 4
+	DefaultRetryInitialBackoff = 100 * time.Millisecond
+	DefaultRetryMaxBackoff     = 2 * time.Second
+)
+
+// RetryOption configures RetryingSource.
+type RetryOption func(*retryConfig)
+
+type retryConfig struct {
+	maxAttempts    int
+	initialBackoff time.Duration
+	maxBackoff     time.Duration
+	retryable      func(error) bool
+	budgetAttempts int           // 0 = unlimited
+	budgetDelay    time.Duration // 0 = unlimited
+}
+
+// RetryWithMaxAttempts sets the total number of attempts per read, including
+// the first (default: DefaultRetryMaxAttempts). Values < 1 are treated as 1.
+func RetryWithMaxAttempts(n int) RetryOption {
+	return func(cfg *retryConfig) {
+		if n < 1 {
+			n = 1
+		}
+		cfg.maxAttempts = n
+	}
+}
+
+// RetryWithBackoff sets the delay before the first retry and the cap on the
+// delay between attempts. The delay doubles after each failed attempt.
+// Non-positive values keep the defaults.
+func RetryWithBackoff(initial, maxDelay time.Duration) RetryOption {
+	return func(cfg *retryConfig) {
+		if initial > 0 {
+			cfg.initialBackoff = initial
+		}
+		if maxDelay > 0 {
+			cfg.maxBackoff = maxDelay
+		}
+	}
+}
+
+// RetryWithClassifier replaces the function that decides whether a read
+// error is transient. Context cancellation and io.EOF are never retried,
+// regardless of the classifier.
+func RetryWithClassifier(fn func(error) bool) RetryOption {
+	return func(cfg *retryConfig) {
+		if fn != nil {
+			cfg.retryable = fn
+		}
+	}
+}
+
+// RetryWithBudget caps attempts across all reads of a single operation, in
+// addition to the per-read limit set by RetryWithMaxAttempts.
+//
+// maxTotalAttempts bounds the number of read attempts, first attempts
+// included, and maxTotalDelay bounds the total time spent waiting between
+// attempts. Once either is spent, reads that fail are no longer retried and
+// return an error matching ErrRetryBudgetExhausted, so an operation issuing
+// hundreds of reads against a failing source stops quickly instead of
+// retrying each read in turn. Non-positive values leave that dimension
+// unlimited.
+//
+// An operation is one call to ReadFileContext, CopyToContext,
+// CopyDirContext, SyncDirContext or PrefetchFiles, or to their variants
+// without a context; each starts with a fresh budget. Other reads, such as
+// those of a file returned by Open, get a budget per read.
+func RetryWithBudget(maxTotalAttempts int, maxTotalDelay time.Duration) RetryOption {
+	return func(cfg *retryConfig) {
+		cfg.budgetAttempts = max(maxTotalAttempts, 0)
+		cfg.budgetDelay = max(maxTotalDelay, 0)
+	}
+}
+
+// retryBudget tracks the attempts of every read in one operation.
+type retryBudget struct {
+	mu          sync.Mutex
+	maxAttempts int
+	maxDelay    time.Duration
+	attempts    int
+	delay       time.Duration
+	exhausted   bool
+}
+
+// start counts the first attempt of a read, which is never refused.
+func (b *retryBudget) start() {
+	if b == nil {
+		return
+	}
+	b.mu.Lock()
+	b.attempts++
+	b.mu.Unlock()
+}
+
+// spend reserves one more attempt preceded by delay. It returns false, and
+// keeps returning false, once the budget cannot cover it.
+func (b *retryBudget) spend(delay time.Duration) bool {
+	if b == nil {
+		return true
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.exhausted ||
+		(b.maxAttempts > 0 && b.attempts >= b.maxAttempts) ||
+		(b.maxDelay > 0 && b.delay+delay > b.maxDelay) {
+		b.exhausted = true
+		return false
+	}
+	b.attempts++
+	b.delay += delay
+	return true
+}
+
+// retryScopeKey is the context key for the retry budgets of an operation.
+type retryScopeKey struct{}
+
+// retryScope holds one budget per retrying source read by an operation.
+type retryScope struct {
+	mu      sync.Mutex
+	budgets map[*retryingSource]*retryBudget
+}
+
+// withRetryScope returns a context under which reads through a retrying
+// source share one budget until cancel is called. An operation nested in
+// another keeps the outer scope. The returned context can be canceled, so
+// reads are bound to it even when ctx is not.
+func withRetryScope(ctx context.Context) (context.Context, context.CancelFunc) {
+	ctx, cancel := context.WithCancel(ctx)
+	if _, ok := ctx.Value(retryScopeKey{}).(*retryScope); ok {
+		return ctx, cancel
+	}
+	return context.WithValue(ctx, retryScopeKey{}, &retryScope{}), cancel
+}
+
+// IsTransientError reports whether err is likely to succeed on retry.
+//
+// It recognizes errors that report Temporary() == true (such as HTTP 5xx
+// responses from the http source), network timeouts, connection resets, and
+// bodies that ended early.
+func IsTransientError(err error) bool {
+	if err == nil || errors.Is(err, io.EOF) ||
+		errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return false
+	}
+	var temp interface{ Temporary() bool }
+	if errors.As(err, &temp) && temp.Temporary() {
+		return true
+	}
+	var netErr net.Error
+	if errors.As(err, &netErr) && netErr.Timeout() {
+		return true
+	}
+	return errors.Is(err, io.ErrUnexpectedEOF) ||
+		errors.Is(err, syscall.ECONNRESET) ||
+		errors.Is(err, syscall.ECONNREFUSED) ||
+		errors.Is(err, syscall.EPIPE)
+}
+
+// RetryingSource wraps inner so reads that fail with a transient error are
+// retried with exponential backoff.
+//
+// Reads are idempotent, so a failed ReadAt resumes at the first byte not yet
+// read, and a range stream that fails mid-read is reopened at the current
+// offset. Each call is bounded by the configured number of attempts; once
+// exhausted, the last error is returned. Errors that are not transient are
+// returned immediately. RetryWithBudget additionally bounds attempts across
+// all reads of an operation.
+//
+// When reads are bound to a context (for example via ReadFileContext or
+// CopyDirContext), backoff waits end when the context is done, and no retry
+// is attempted if the context deadline would pass before the next attempt.
+//
+// Size and SourceID are those of inner, so cache keys are unchanged. The
+// returned source supports range streams only when inner does.
+func RetryingSource(inner ByteSource, opts ...RetryOption) ByteSource {
+	cfg := retryConfig{
+		maxAttempts:    DefaultRetryMaxAttempts,
+		initialBackoff: DefaultRetryInitialBackoff,
+		maxBackoff:     DefaultRetryMaxBackoff,
+		retryable:      IsTransientError,
+	}
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+	src := &retryingSource{inner: inner, cfg: cfg}
+	if _, ok := inner.(rangeReader); ok {
+		return &retryingRangeSource{retryingSource: src}
+	}
+	return src
+}
+
+// rangeReader is implemented by sources that support range streams.
+type rangeReader interface {
+	ReadRange(off, length int64) (io.ReadCloser, error)
+}
+
+// retryingSource retries ReadAt on transient errors.
+type retryingSource struct {
+	inner ByteSource
+	cfg   retryConfig
+}
+
+// Size returns the size of the inner source.
+func (s *retryingSource) Size() int64 {
+	return s.inner.Size()
+}
+
+// SourceID returns the identifier of the inner source.
+func (s *retryingSource) SourceID() string {
+	return s.inner.SourceID()
+}
+
+// ReadAt implements io.ReaderAt.
+func (s *retryingSource) ReadAt(p []byte, off int64) (int, error) {
+	return s.ReadAtContext(context.Background(), p, off)
+}
+
+// ReadAtContext is like ReadAt but stops retrying once ctx is done.
+func (s *retryingSource) ReadAtContext(ctx context.Context, p []byte, off int64) (int, error) {
+	budget := s.budget(ctx)
+	budget.start()
+	read := 0
+	for attempt := 1; ; attempt++ {
+		n, err := file.ReadAtContext(ctx, s.inner, p[read:], off+int64(read))
+		read += n
+		if err == nil || !s.shouldRetry(err) {
+			return read, err
+		}
+		if werr := s.wait(ctx, budget, attempt, err); werr != nil {
+			return read, werr
+		}
+	}
+}
+
+// budget returns the budget shared by reads under ctx, or nil when
+// RetryWithBudget is not set. Reads outside an operation get their own.
+func (s *retryingSource) budget(ctx context.Context) *retryBudget {
+	if s.cfg.budgetAttempts == 0 && s.cfg.budgetDelay == 0 {
+		return nil
+	}
+	scope, ok := ctx.Value(retryScopeKey{}).(*retryScope)
+	if !ok {
+		return &retryBudget{maxAttempts: s.cfg.budgetAttempts, maxDelay: s.cfg.budgetDelay}
+	}
+	scope.mu.Lock()
+	defer scope.mu.Unlock()
+	b, ok := scope.budgets[s]
+	if !ok {
+		if scope.budgets == nil {
+			scope.budgets = make(map[*retryingSource]*retryBudget)
+		}
+		b = &retryBudget{maxAttempts: s.cfg.budgetAttempts, maxDelay: s.cfg.budgetDelay}
+		scope.budgets[s] = b
+	}
+	return b
+}
+
+// shouldRetry reports whether err is eligible for another attempt.
+func (s *retryingSource) shouldRetry(err error) bool {
+	if errors.Is(err, io.EOF) || errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return false
+	}
+	return s.cfg.retryable(err)
+}
+
+// wait sleeps before the attempt following attempt, which failed with err.
+// It returns a non-nil error when no further attempt should be made.
+func (s *retryingSource) wait(ctx context.Context, budget *retryBudget, attempt int, err error) error {
+	if attempt >= s.cfg.maxAttempts {
+		return fmt.Errorf("giving up after %d attempts: %w", attempt, err)
+	}
+	delay := s.backoff(attempt)
+	if deadline, ok := ctx.Deadline(); ok && time.Until(deadline) < delay {
+		return fmt.Errorf("context deadline too close to retry after %d attempts: %w", attempt, err)
+	}
+	if !budget.spend(delay) {
+		return fmt.Errorf("%w after %d attempts: %w", ErrRetryBudgetExhausted, attempt, err)
+	}
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
+
+// backoff returns the delay after the given failed attempt.
+func (s *retryingSource) backoff(attempt int) time.Duration {
+	delay := s.cfg.initialBackoff
+	for i := 1; i < attempt && delay < s.cfg.maxBackoff; i++ {
+		delay *= 2
+	}
+	return min(delay, s.cfg.maxBackoff)
+}
+
+// retryingRangeSource adds retried range streams to retryingSource.
+type retryingRangeSource struct {
+	*retryingSource
+}
+
+// ReadRange returns a reader for [off, off+length) that reopens the range
+// on transient errors.
+func (s *retryingRangeSource) ReadRange(off, length int64) (io.ReadCloser, error) {
+	return s.ReadRangeContext(context.Background(), off, length)
+}
+
+// ReadRangeContext is like ReadRange but binds the stream and its retries to ctx.
+func (s *retryingRangeSource) ReadRangeContext(ctx context.Context, off, length int64) (io.ReadCloser, error) {
+	r := &retryRangeReader{src: s.retryingSource, ctx: ctx, budget: s.budget(ctx), off: off, remaining: length}
+	r.budget.start()
+	if err := r.open(); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// retryRangeReader streams a range, reopening it at the current offset when
+// a read fails with a transient error. Attempts are counted across the
+// lifetime of the reader.
+type retryRangeReader struct {
+	src       *retryingSource
+	ctx       context.Context //nolint:containedctx // binds a stream to a single call
+	budget    *retryBudget
+	off       int64
+	remaining int64
+	attempt   int
+	rc        io.ReadCloser
+	err       error // sticky error after a failed reopen
+}
+
+// open opens the range at the current offset, retrying transient failures.
+func (r *retryRangeReader) open() error {
+	rr, ok := file.WithContext(r.ctx, r.src.inner).(rangeReader)
+	if !ok {
+		return errors.New("source does not support range reads")
+	}
+	for {
+		r.attempt++
+		rc, err := rr.ReadRange(r.off, r.remaining)
+		if err == nil {
+			r.rc = rc
+			return nil
+		}
+		if rc != nil {
+			rc.Close()
+		}
+		if !r.src.shouldRetry(err) {
+			return err
+		}
+		if werr := r.src.wait(r.ctx, r.budget, r.attempt, err); werr != nil {
+			return werr
+		}
+	}
+}
+
+// Read reads from the current stream, reopening it on transient errors.
+func (r *retryRangeReader) Read(p []byte) (int, error) {
+	for {
+		if r.err != nil {
+			return 0, r.err
+		}
+		if r.rc == nil {
+			return 0, io.EOF
+		}
+		n, err := r.rc.Read(p)
+		r.off += int64(n)
+		r.remaining -= int64(n)
+		if err == nil || !r.src.shouldRetry(err) {
+			return n, err
+		}
+		r.rc.Close()
+		r.rc = nil
+		if werr := r.src.wait(r.ctx, r.budget, r.attempt, err); werr != nil {
+			r.err = werr
+			return n, werr
+		}
+		if oerr := r.open(); oerr != nil {
+			r.err = oerr
+			return n, oerr
+		}
+		if n > 0 {
+			return n, nil
+		}
+	}
+}
+
+// Close closes the current stream.
+func (r *retryRangeReader) Close() error {
+	if r.rc == nil {
+		return nil
+	}
+	err := r.rc.Close()
+	r.rc = nil
+	return err
+}
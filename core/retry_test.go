@@ -0,0 +1,242 @@
+package blob
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/meigma/blob/core/internal/file"
+	"github.com/meigma/blob/core/testutil"
+)
+
+// transientTestError reports itself as temporary so the default classifier retries it.
+type transientTestError struct{}
+
+func (transientTestError) Error() string   { return "transient" }
+func (transientTestError) Temporary() bool { return true }
+
+// flakySource fails the first failures reads with err, then serves data.
+type flakySource struct {
+	*testutil.MockByteSource
+	failures int32
+	err      error
+	calls    atomic.Int32
+}
+
+func (s *flakySource) ReadAt(p []byte, off int64) (int, error) {
+	if s.calls.Add(1) <= s.failures {
+		return 0, s.err
+	}
+	return s.MockByteSource.ReadAt(p, off)
+}
+
+// flakyRangeSource serves range streams that fail once after half of the
+// requested bytes, in addition to the ReadAt behavior of flakySource.
+type flakyRangeSource struct {
+	*flakySource
+	opens atomic.Int32
+}
+
+func (s *flakyRangeSource) ReadRange(off, length int64) (io.ReadCloser, error) {
+	data := s.Bytes()[off : off+length]
+	if s.opens.Add(1) == 1 {
+		return io.NopCloser(io.MultiReader(
+			bytes.NewReader(data[:len(data)/2]),
+			errReader{transientTestError{}},
+		)), nil
+	}
+	return io.NopCloser(bytes.NewReader(data)), nil
+}
+
+type errReader struct{ err error }
+
+func (r errReader) Read([]byte) (int, error) { return 0, r.err }
+
+func TestRetryingSource_ReadAt(t *testing.T) {
+	t.Parallel()
+
+	data := []byte("hello retrying world")
+	fast := RetryWithBackoff(time.Millisecond, time.Millisecond)
+
+	t.Run("succeeds after transient failures", func(t *testing.T) {
+		t.Parallel()
+
+		inner := &flakySource{MockByteSource: testutil.NewMockByteSource(data), failures: 2, err: transientTestError{}}
+		src := RetryingSource(inner, fast, RetryWithMaxAttempts(3))
+		assert.Equal(t, inner.SourceID(), src.SourceID())
+		assert.Equal(t, inner.Size(), src.Size())
+
+		buf := make([]byte, 8)
+		n, err := src.ReadAt(buf, 6)
+		require.NoError(t, err)
+		assert.Equal(t, len(buf), n)
+		assert.Equal(t, data[6:14], buf)
+		assert.Equal(t, int32(3), inner.calls.Load())
+	})
+
+	t.Run("attempts are bounded", func(t *testing.T) {
+		t.Parallel()
+
+		inner := &flakySource{MockByteSource: testutil.NewMockByteSource(data), failures: 10, err: transientTestError{}}
+		src := RetryingSource(inner, fast, RetryWithMaxAttempts(3))
+
+		_, err := src.ReadAt(make([]byte, 4), 0)
+		require.Error(t, err)
+		assert.ErrorIs(t, err, transientTestError{})
+		assert.Equal(t, int32(3), inner.calls.Load())
+	})
+
+	t.Run("permanent errors are not retried", func(t *testing.T) {
+		t.Parallel()
+
+		permanent := errors.New("permanent")
+		inner := &flakySource{MockByteSource: testutil.NewMockByteSource(data), failures: 10, err: permanent}
+		src := RetryingSource(inner, fast)
+
+		_, err := src.ReadAt(make([]byte, 4), 0)
+		require.ErrorIs(t, err, permanent)
+		assert.Equal(t, int32(1), inner.calls.Load())
+	})
+
+	t.Run("deadline stops retries", func(t *testing.T) {
+		t.Parallel()
+
+		inner := &flakySource{MockByteSource: testutil.NewMockByteSource(data), failures: 10, err: transientTestError{}}
+		src := RetryingSource(inner, RetryWithBackoff(time.Hour, time.Hour))
+
+		ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+		defer cancel()
+		_, err := file.ReadAtContext(ctx, src, make([]byte, 4), 0)
+		require.ErrorIs(t, err, transientTestError{})
+		assert.Equal(t, int32(1), inner.calls.Load())
+	})
+}
+
+func TestRetryingSource_ReadRangeResumes(t *testing.T) {
+	t.Parallel()
+
+	data := bytes.Repeat([]byte("0123456789"), 10)
+	inner := &flakyRangeSource{flakySource: &flakySource{MockByteSource: testutil.NewMockByteSource(data)}}
+	src := RetryingSource(inner, RetryWithBackoff(time.Millisecond, time.Millisecond))
+
+	rr, ok := src.(rangeReader)
+	require.True(t, ok, "range support should be preserved")
+
+	rc, err := rr.ReadRange(10, 80)
+	require.NoError(t, err)
+	got, err := io.ReadAll(rc)
+	require.NoError(t, err)
+	require.NoError(t, rc.Close())
+	assert.Equal(t, data[10:90], got)
+	assert.Equal(t, int32(2), inner.opens.Load())
+
+	_, ok = RetryingSource(testutil.NewMockByteSource(data)).(rangeReader)
+	assert.False(t, ok, "range support should not be added")
+}
+
+func TestRetryingSource_Blob(t *testing.T) {
+	t.Parallel()
+
+	files := map[string][]byte{"a.txt": []byte("alpha"), "b.txt": []byte("bravo")}
+	base, mock := createTestArchiveWithSource(t, files)
+
+	inner := &flakySource{MockByteSource: mock, failures: 1, err: transientTestError{}}
+	b, err := New(base.IndexData(), RetryingSource(inner, RetryWithBackoff(time.Millisecond, time.Millisecond)))
+	require.NoError(t, err)
+
+	got, err := b.ReadFile("b.txt")
+	require.NoError(t, err)
+	assert.Equal(t, files["b.txt"], got)
+}
+
+func TestRetryingSource_Budget(t *testing.T) {
+	t.Parallel()
+
+	files := make(map[string][]byte)
+	for i := range 10 {
+		files[fmt.Sprintf("f%d.txt", i)] = []byte(fmt.Sprintf("content %d", i))
+	}
+	base, mock := createTestArchiveWithSource(t, files)
+	fast := RetryWithBackoff(time.Millisecond, time.Millisecond)
+
+	t.Run("copy aborts when budget is spent", func(t *testing.T) {
+		t.Parallel()
+
+		inner := &flakySource{MockByteSource: mock, failures: 1 << 30, err: transientTestError{}}
+		src := RetryingSource(inner, fast, RetryWithMaxAttempts(100), RetryWithBudget(3, 0))
+		b, err := New(base.IndexData(), src)
+		require.NoError(t, err)
+
+		_, err = b.CopyDir(t.TempDir(), ".")
+		require.ErrorIs(t, err, ErrRetryBudgetExhausted)
+		assert.ErrorIs(t, err, transientTestError{})
+		// Three attempts in total, rather than a hundred per read.
+		assert.Equal(t, int32(3), inner.calls.Load())
+	})
+
+	t.Run("each operation gets a fresh budget", func(t *testing.T) {
+		t.Parallel()
+
+		inner := &flakySource{MockByteSource: mock, failures: 3, err: transientTestError{}}
+		src := RetryingSource(inner, fast, RetryWithMaxAttempts(100), RetryWithBudget(2, 0))
+		b, err := New(base.IndexData(), src)
+		require.NoError(t, err)
+
+		_, err = b.CopyDir(t.TempDir(), ".")
+		require.ErrorIs(t, err, ErrRetryBudgetExhausted)
+
+		stats, err := b.CopyDir(t.TempDir(), ".")
+		require.NoError(t, err)
+		assert.Equal(t, len(files), stats.FileCount)
+	})
+
+	t.Run("delay budget", func(t *testing.T) {
+		t.Parallel()
+
+		inner := &flakySource{MockByteSource: mock, failures: 1 << 30, err: transientTestError{}}
+		src := RetryingSource(inner,
+			RetryWithBackoff(10*time.Millisecond, 10*time.Millisecond),
+			RetryWithMaxAttempts(100),
+			RetryWithBudget(0, 25*time.Millisecond))
+
+		_, err := src.ReadAt(make([]byte, 4), 0)
+		require.ErrorIs(t, err, ErrRetryBudgetExhausted)
+		assert.ErrorIs(t, err, transientTestError{})
+		assert.Equal(t, int32(3), inner.calls.Load())
+	})
+
+	t.Run("recovered reads keep working", func(t *testing.T) {
+		t.Parallel()
+
+		inner := &flakySource{MockByteSource: mock, failures: 2, err: transientTestError{}}
+		src := RetryingSource(inner, fast, RetryWithBudget(3, 0))
+		b, err := New(base.IndexData(), src)
+		require.NoError(t, err)
+
+		for name, want := range files {
+			got, err := b.ReadFile(name)
+			require.NoError(t, err)
+			assert.Equal(t, want, got)
+		}
+	})
+}
+
+func TestIsTransientError(t *testing.T) {
+	t.Parallel()
+
+	assert.True(t, IsTransientError(transientTestError{}))
+	assert.True(t, IsTransientError(io.ErrUnexpectedEOF))
+	assert.False(t, IsTransientError(nil))
+	assert.False(t, IsTransientError(io.EOF))
+	assert.False(t, IsTransientError(context.Canceled))
+	assert.False(t, IsTransientError(context.DeadlineExceeded))
+	assert.False(t, IsTransientError(errors.New("permanent")))
+}
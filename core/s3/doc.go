@@ -0,0 +1,6 @@
+// Package s3 provides a ByteSource backed by Amazon S3 ranged GetObject
+// requests, for data blobs kept in object storage instead of an OCI registry.
+//
+// It lives in its own module so that the AWS SDK is only a dependency of
+// programs that import it.
+package s3
@@ -0,0 +1,133 @@
+//go:build integration
+
+package s3_test
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/testcontainers/testcontainers-go"
+	"github.com/testcontainers/testcontainers-go/wait"
+
+	blob "github.com/meigma/blob/core"
+	blobs3 "github.com/meigma/blob/core/s3"
+)
+
+const (
+	minioUser     = "blobtest"
+	minioPassword = "blobtest-secret"
+)
+
+// startMinio starts a MinIO container and returns an S3 client for it.
+func startMinio(t *testing.T) *s3.Client {
+	t.Helper()
+
+	if os.Getenv("SKIP_DOCKER_TESTS") == "1" {
+		t.Skip("SKIP_DOCKER_TESTS is set")
+	}
+
+	ctx := context.Background()
+	container, err := testcontainers.GenericContainer(ctx, testcontainers.GenericContainerRequest{
+		ContainerRequest: testcontainers.ContainerRequest{
+			Image:        "minio/minio:latest",
+			Cmd:          []string{"server", "/data"},
+			ExposedPorts: []string{"9000/tcp"},
+			Env: map[string]string{
+				"MINIO_ROOT_USER":     minioUser,
+				"MINIO_ROOT_PASSWORD": minioPassword,
+			},
+			WaitingFor: wait.ForHTTP("/minio/health/live").WithPort("9000/tcp"),
+		},
+		Started: true,
+	})
+	if err != nil {
+		t.Fatalf("start minio container: %v", err)
+	}
+	t.Cleanup(func() { _ = container.Terminate(context.Background()) })
+
+	host, err := container.Host(ctx)
+	if err != nil {
+		t.Fatalf("resolve minio host: %v", err)
+	}
+	port, err := container.MappedPort(ctx, "9000/tcp")
+	if err != nil {
+		t.Fatalf("resolve minio port: %v", err)
+	}
+
+	return s3.New(s3.Options{
+		Region:       "us-east-1",
+		BaseEndpoint: aws.String(fmt.Sprintf("http://%s:%s", host, port.Port())),
+		UsePathStyle: true,
+		Credentials: aws.CredentialsProviderFunc(func(context.Context) (aws.Credentials, error) {
+			return aws.Credentials{AccessKeyID: minioUser, SecretAccessKey: minioPassword}, nil
+		}),
+	})
+}
+
+func TestSource_Minio(t *testing.T) {
+	ctx := context.Background()
+	client := startMinio(t)
+
+	const bucket = "archives"
+	if _, err := client.CreateBucket(ctx, &s3.CreateBucketInput{Bucket: aws.String(bucket)}); err != nil {
+		t.Fatalf("CreateBucket() error = %v", err)
+	}
+
+	dir := t.TempDir()
+	files := map[string]string{
+		"readme.md":       "# archive in s3",
+		"nested/data.txt": "nested content",
+	}
+	for name, content := range files {
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	var index, data bytes.Buffer
+	if err := blob.Create(ctx, dir, &index, &data); err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+
+	if _, err := client.PutObject(ctx, &s3.PutObjectInput{
+		Bucket: aws.String(bucket),
+		Key:    aws.String("v1/data.blob"),
+		Body:   bytes.NewReader(data.Bytes()),
+	}); err != nil {
+		t.Fatalf("PutObject() error = %v", err)
+	}
+
+	src, err := blobs3.NewSource(ctx, bucket, "v1/data.blob", client)
+	if err != nil {
+		t.Fatalf("NewSource() error = %v", err)
+	}
+	if src.Size() != int64(data.Len()) {
+		t.Fatalf("Size() = %d, want %d", src.Size(), data.Len())
+	}
+	if src.SourceID() == "" {
+		t.Fatal("SourceID() is empty, want the object ETag")
+	}
+
+	b, err := blob.New(index.Bytes(), src)
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	for name, want := range files {
+		got, err := b.ReadFile(name)
+		if err != nil {
+			t.Fatalf("ReadFile(%q) error = %v", name, err)
+		}
+		if string(got) != want {
+			t.Fatalf("ReadFile(%q) = %q, want %q", name, got, want)
+		}
+	}
+}
@@ -0,0 +1,189 @@
+package s3
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/smithy-go"
+)
+
+// API is the subset of the S3 client used by Source. *s3.Client satisfies it.
+type API interface {
+	HeadObject(ctx context.Context, params *s3.HeadObjectInput, optFns ...func(*s3.Options)) (*s3.HeadObjectOutput, error)
+	GetObject(ctx context.Context, params *s3.GetObjectInput, optFns ...func(*s3.Options)) (*s3.GetObjectOutput, error)
+}
+
+var _ API = (*s3.Client)(nil)
+
+// ErrObjectChanged is returned when the object is replaced after the Source
+// was created, so ranges would mix bytes from different versions.
+var ErrObjectChanged = errors.New("s3: object changed since source was opened")
+
+// Source implements random access reads via S3 ranged GetObject requests.
+// It satisfies blob.ByteSource (io.ReaderAt plus Size and SourceID).
+//
+// Every read is pinned to the ETag observed when the Source was created with
+// an If-Match condition; reads after the object is overwritten fail with
+// ErrObjectChanged.
+type Source struct {
+	client API
+	bucket string
+	key    string
+	size   int64
+	etag   string
+}
+
+// NewSource creates a Source for the object at bucket/key.
+// It issues a HeadObject request to determine the size and ETag.
+func NewSource(ctx context.Context, bucket, key string, client API) (*Source, error) {
+	if client == nil {
+		return nil, errors.New("s3: nil client")
+	}
+	out, err := client.HeadObject(ctx, &s3.HeadObjectInput{
+		Bucket: aws.String(bucket),
+		Key:    aws.String(key),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("head object s3://%s/%s: %w", bucket, key, err)
+	}
+	size := aws.ToInt64(out.ContentLength)
+	if size < 0 {
+		return nil, fmt.Errorf("head object s3://%s/%s: invalid content length %d", bucket, key, size)
+	}
+	return &Source{
+		client: client,
+		bucket: bucket,
+		key:    key,
+		size:   size,
+		etag:   aws.ToString(out.ETag),
+	}, nil
+}
+
+// Size returns the size of the object.
+func (s *Source) Size() int64 {
+	return s.size
+}
+
+// SourceID returns the object's ETag, which changes whenever the object
+// content does.
+func (s *Source) SourceID() string {
+	return s.etag
+}
+
+// ReadRange returns a reader for the specified byte range [off, off+length).
+// It returns an error if offset or length is negative. If the offset is at or
+// beyond the object size, it returns io.EOF. The returned reader must be
+// closed by the caller to release the underlying connection.
+func (s *Source) ReadRange(off, length int64) (io.ReadCloser, error) {
+	return s.ReadRangeContext(context.Background(), off, length)
+}
+
+// ReadRangeContext is like ReadRange but binds the request to ctx.
+// Canceling ctx aborts the request and any read from the returned reader.
+func (s *Source) ReadRangeContext(ctx context.Context, off, length int64) (io.ReadCloser, error) {
+	if length < 0 {
+		return nil, fmt.Errorf("read range length %d: negative length", length)
+	}
+	if length == 0 {
+		return io.NopCloser(bytes.NewReader(nil)), nil
+	}
+	if off < 0 {
+		return nil, fmt.Errorf("read range %d: negative offset", off)
+	}
+	if off >= s.size {
+		return io.NopCloser(bytes.NewReader(nil)), io.EOF
+	}
+	length = min(length, s.size-off)
+
+	body, err := s.getRange(ctx, off, off+length-1)
+	if err != nil {
+		return nil, err
+	}
+	return &rangeReadCloser{body: body, reader: io.LimitReader(body, length)}, nil
+}
+
+// ReadAt reads len(p) bytes from the object at the given offset using a
+// ranged GetObject. It implements [io.ReaderAt]. If fewer bytes are available
+// than requested, it returns the number of bytes read along with io.EOF.
+func (s *Source) ReadAt(p []byte, off int64) (int, error) {
+	return s.ReadAtContext(context.Background(), p, off)
+}
+
+// ReadAtContext is like ReadAt but binds the request to ctx.
+// Canceling ctx aborts a request in flight.
+func (s *Source) ReadAtContext(ctx context.Context, p []byte, off int64) (int, error) {
+	if len(p) == 0 {
+		return 0, nil
+	}
+	if off < 0 {
+		return 0, fmt.Errorf("read at %d: negative offset", off)
+	}
+	if off >= s.size {
+		return 0, io.EOF
+	}
+
+	expected := int(min(int64(len(p)), s.size-off))
+	body, err := s.getRange(ctx, off, off+int64(expected)-1)
+	if err != nil {
+		return 0, err
+	}
+	defer func() {
+		_, _ = io.Copy(io.Discard, body) //nolint:errcheck // best-effort drain for connection reuse
+		_ = body.Close()
+	}()
+
+	n, err := io.ReadFull(body, p[:expected])
+	if err != nil {
+		return n, err
+	}
+	if expected < len(p) {
+		return n, io.EOF
+	}
+	return n, nil
+}
+
+// getRange fetches the inclusive byte range [start, end] of the object.
+func (s *Source) getRange(ctx context.Context, start, end int64) (io.ReadCloser, error) {
+	input := &s3.GetObjectInput{
+		Bucket: aws.String(s.bucket),
+		Key:    aws.String(s.key),
+		Range:  aws.String(fmt.Sprintf("bytes=%d-%d", start, end)),
+	}
+	if s.etag != "" {
+		input.IfMatch = aws.String(s.etag)
+	}
+	out, err := s.client.GetObject(ctx, input)
+	if err != nil {
+		if isPreconditionFailed(err) {
+			return nil, fmt.Errorf("%w: s3://%s/%s", ErrObjectChanged, s.bucket, s.key)
+		}
+		return nil, fmt.Errorf("get object s3://%s/%s range %d-%d: %w", s.bucket, s.key, start, end, err)
+	}
+	return out.Body, nil
+}
+
+// isPreconditionFailed reports whether err is S3's response to a failed
+// If-Match condition.
+func isPreconditionFailed(err error) bool {
+	var apiErr smithy.APIError
+	return errors.As(err, &apiErr) && apiErr.ErrorCode() == "PreconditionFailed"
+}
+
+// rangeReadCloser limits reads to the requested range and closes the body.
+type rangeReadCloser struct {
+	body   io.ReadCloser
+	reader io.Reader
+}
+
+func (r *rangeReadCloser) Read(p []byte) (int, error) {
+	return r.reader.Read(p)
+}
+
+func (r *rangeReadCloser) Close() error {
+	return r.body.Close()
+}
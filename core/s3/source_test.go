@@ -0,0 +1,180 @@
+package s3_test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/smithy-go"
+
+	blob "github.com/meigma/blob/core"
+	blobs3 "github.com/meigma/blob/core/s3"
+)
+
+// fakeS3 serves a single object from memory.
+type fakeS3 struct {
+	mu   sync.Mutex
+	data []byte
+	etag string
+	gets int
+}
+
+func (f *fakeS3) HeadObject(_ context.Context, _ *s3.HeadObjectInput, _ ...func(*s3.Options)) (*s3.HeadObjectOutput, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return &s3.HeadObjectOutput{
+		ContentLength: aws.Int64(int64(len(f.data))),
+		ETag:          aws.String(f.etag),
+	}, nil
+}
+
+func (f *fakeS3) GetObject(_ context.Context, in *s3.GetObjectInput, _ ...func(*s3.Options)) (*s3.GetObjectOutput, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.gets++
+	if in.IfMatch != nil && *in.IfMatch != f.etag {
+		return nil, &smithy.GenericAPIError{Code: "PreconditionFailed", Message: "At least one of the pre-conditions you specified did not hold"}
+	}
+	var start, end int64
+	if _, err := fmt.Sscanf(aws.ToString(in.Range), "bytes=%d-%d", &start, &end); err != nil {
+		return nil, err
+	}
+	end = min(end, int64(len(f.data))-1)
+	body := bytes.Clone(f.data[start : end+1])
+	return &s3.GetObjectOutput{Body: io.NopCloser(bytes.NewReader(body))}, nil
+}
+
+func (f *fakeS3) replace(data []byte, etag string) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.data = data
+	f.etag = etag
+}
+
+func TestSource_ReadAt(t *testing.T) {
+	t.Parallel()
+
+	client := &fakeS3{data: []byte("hello world"), etag: `"abc"`}
+	src, err := blobs3.NewSource(context.Background(), "bucket", "data.blob", client)
+	if err != nil {
+		t.Fatalf("NewSource() error = %v", err)
+	}
+	if src.Size() != 11 {
+		t.Fatalf("Size() = %d, want 11", src.Size())
+	}
+	if src.SourceID() != `"abc"` {
+		t.Fatalf("SourceID() = %q, want ETag", src.SourceID())
+	}
+
+	tests := []struct {
+		name    string
+		bufSize int
+		offset  int64
+		want    string
+		wantErr error
+	}{
+		{name: "start", bufSize: 5, offset: 0, want: "hello"},
+		{name: "middle", bufSize: 3, offset: 4, want: "o w"},
+		{name: "short at end", bufSize: 10, offset: 6, want: "world", wantErr: io.EOF},
+		{name: "past end", bufSize: 1, offset: 11, wantErr: io.EOF},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			buf := make([]byte, tt.bufSize)
+			n, err := src.ReadAt(buf, tt.offset)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("ReadAt() error = %v, want %v", err, tt.wantErr)
+			}
+			if got := string(buf[:n]); got != tt.want {
+				t.Fatalf("ReadAt() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSource_ReadRange(t *testing.T) {
+	t.Parallel()
+
+	client := &fakeS3{data: []byte("hello world"), etag: `"abc"`}
+	src, err := blobs3.NewSource(context.Background(), "bucket", "data.blob", client)
+	if err != nil {
+		t.Fatalf("NewSource() error = %v", err)
+	}
+
+	rc, err := src.ReadRange(6, 100)
+	if err != nil {
+		t.Fatalf("ReadRange() error = %v", err)
+	}
+	got, err := io.ReadAll(rc)
+	rc.Close()
+	if err != nil {
+		t.Fatalf("read range: %v", err)
+	}
+	if string(got) != "world" {
+		t.Fatalf("ReadRange() = %q, want %q", got, "world")
+	}
+
+	if _, err := src.ReadRange(11, 1); !errors.Is(err, io.EOF) {
+		t.Fatalf("ReadRange() past end error = %v, want io.EOF", err)
+	}
+	if _, err := src.ReadRange(-1, 1); err == nil {
+		t.Fatal("ReadRange() with negative offset succeeded")
+	}
+}
+
+func TestSource_ObjectChanged(t *testing.T) {
+	t.Parallel()
+
+	client := &fakeS3{data: []byte("hello world"), etag: `"v1"`}
+	src, err := blobs3.NewSource(context.Background(), "bucket", "data.blob", client)
+	if err != nil {
+		t.Fatalf("NewSource() error = %v", err)
+	}
+
+	client.replace([]byte("HELLO WORLD"), `"v2"`)
+	if _, err := src.ReadAt(make([]byte, 5), 0); !errors.Is(err, blobs3.ErrObjectChanged) {
+		t.Fatalf("ReadAt() error = %v, want ErrObjectChanged", err)
+	}
+	if _, err := src.ReadRange(0, 5); !errors.Is(err, blobs3.ErrObjectChanged) {
+		t.Fatalf("ReadRange() error = %v, want ErrObjectChanged", err)
+	}
+}
+
+func TestSource_Blob(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "hello.txt"), []byte("hello from s3"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	var index, data bytes.Buffer
+	if err := blob.Create(context.Background(), dir, &index, &data); err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+
+	client := &fakeS3{data: data.Bytes(), etag: `"archive"`}
+	src, err := blobs3.NewSource(context.Background(), "bucket", "archive.data", client)
+	if err != nil {
+		t.Fatalf("NewSource() error = %v", err)
+	}
+	b, err := blob.New(index.Bytes(), src)
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	got, err := b.ReadFile("hello.txt")
+	if err != nil {
+		t.Fatalf("ReadFile() error = %v", err)
+	}
+	if string(got) != "hello from s3" {
+		t.Fatalf("ReadFile() = %q", got)
+	}
+}
@@ -0,0 +1,79 @@
+package blob
+
+import (
+	"errors"
+	"io/fs"
+)
+
+// Subset returns a Blob scoped to the directory prefix.
+//
+// Paths passed to the returned Blob are relative to prefix, so
+// Subset("etc").Open("nginx/nginx.conf") reads "etc/nginx/nginx.conf" from
+// the archive. Entry views returned by Entry, Entries, and EntriesWithPrefix
+// report paths relative to prefix as well.
+//
+// The subset shares the index, data source, decoder pool, and cache of b,
+// so no data is copied. Archive-wide accessors (IndexData, DataHash,
+// DataSize, Stream, Size) still describe the full underlying archive.
+//
+// The prefix is normalized before use. Subset returns an error if prefix
+// is invalid or does not name a directory in the archive.
+func (b *Blob) Subset(prefix string) (*Blob, error) {
+	prefix = NormalizePath(prefix)
+	if !fs.ValidPath(prefix) {
+		return nil, &fs.PathError{Op: "subset", Path: prefix, Err: fs.ErrInvalid}
+	}
+	if prefix == "." {
+		return b, nil
+	}
+	full := b.resolve(prefix)
+	if !b.isDir(full) {
+		return nil, &fs.PathError{Op: "subset", Path: prefix, Err: fs.ErrNotExist}
+	}
+
+	nb := *b
+	nb.root = full
+	return &nb, nil
+}
+
+// Sub implements fs.SubFS.
+//
+// Sub returns an fs.FS rooted at dir. It behaves like Subset but returns
+// the view as an fs.FS for use with fs.Sub and APIs that accept one, such
+// as template engines. Paths that escape dir are rejected with fs.ErrInvalid.
+func (b *Blob) Sub(dir string) (fs.FS, error) {
+	if !fs.ValidPath(dir) {
+		return nil, &fs.PathError{Op: "sub", Path: dir, Err: fs.ErrInvalid}
+	}
+	sub, err := b.Subset(dir)
+	if err != nil {
+		var pathErr *fs.PathError
+		if errors.As(err, &pathErr) {
+			pathErr.Op = "sub"
+		}
+		return nil, err
+	}
+	return sub, nil
+}
+
+// resolve maps a path relative to the Blob root to its archive path,
+// matching it case-insensitively under WithCaseInsensitiveLookup.
+// The name must already satisfy fs.ValidPath.
+func (b *Blob) resolve(name string) string {
+	if b.root == "" {
+		return b.canonicalPath(name)
+	}
+	if name == "." {
+		return b.root
+	}
+	return b.canonicalPath(b.root + "/" + name)
+}
+
+// rootPrefix returns the archive path prefix shared by all entries visible
+// through b, or "" for the archive root.
+func (b *Blob) rootPrefix() string {
+	if b.root == "" {
+		return ""
+	}
+	return b.root + "/"
+}
@@ -0,0 +1,166 @@
+package blob
+
+import (
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"testing"
+	"testing/fstest"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBlobSubset(t *testing.T) {
+	t.Parallel()
+
+	files := map[string][]byte{
+		"etc/hosts":            []byte("hosts"),
+		"etc/nginx/nginx.conf": []byte("nginx config"),
+		"etc/nginx/mime.types": []byte("mime types"),
+		"etcetera.txt":         []byte("not in subset"),
+		"usr/bin/tool":         []byte("tool"),
+	}
+	b := createTestArchive(t, files, CompressionZstd)
+
+	sub, err := b.Subset("/etc/")
+	require.NoError(t, err)
+
+	t.Run("Open uses relative paths", func(t *testing.T) {
+		t.Parallel()
+
+		f, err := sub.Open("nginx/nginx.conf")
+		require.NoError(t, err)
+		content, err := io.ReadAll(f)
+		require.NoError(t, err)
+		require.NoError(t, f.Close())
+		assert.Equal(t, files["etc/nginx/nginx.conf"], content)
+
+		_, err = sub.Open("etc/hosts")
+		assert.ErrorIs(t, err, fs.ErrNotExist)
+		_, err = sub.Open("../usr/bin/tool")
+		assert.ErrorIs(t, err, fs.ErrInvalid)
+	})
+
+	t.Run("ReadDir and Stat", func(t *testing.T) {
+		t.Parallel()
+
+		entries, err := sub.ReadDir(".")
+		require.NoError(t, err)
+		names := make([]string, 0, len(entries))
+		for _, e := range entries {
+			names = append(names, e.Name())
+		}
+		assert.Equal(t, []string{"hosts", "nginx"}, names)
+
+		info, err := sub.Stat(".")
+		require.NoError(t, err)
+		assert.True(t, info.IsDir())
+		assert.Equal(t, ".", info.Name())
+
+		info, err = sub.Stat("hosts")
+		require.NoError(t, err)
+		assert.Equal(t, int64(len(files["etc/hosts"])), info.Size())
+	})
+
+	t.Run("entries are relativized", func(t *testing.T) {
+		t.Parallel()
+
+		assert.Equal(t, 3, sub.Len())
+		var paths []string
+		for view := range sub.Entries() {
+			paths = append(paths, view.Path())
+		}
+		assert.Equal(t, []string{"hosts", "nginx/mime.types", "nginx/nginx.conf"}, paths)
+
+		view, ok := sub.Entry("hosts")
+		require.True(t, ok)
+		assert.Equal(t, "hosts", view.Path())
+		assert.Equal(t, "hosts", view.Entry().Path)
+	})
+
+	t.Run("EntriesWithSuffix matches relative paths", func(t *testing.T) {
+		t.Parallel()
+
+		suffixPaths := func(b *Blob, suffix string) []string {
+			paths := []string{}
+			for view := range b.EntriesWithSuffix(suffix) {
+				paths = append(paths, view.Path())
+			}
+			return paths
+		}
+		assert.Equal(t, []string{"etc/nginx/nginx.conf"}, suffixPaths(b, ".conf"))
+		assert.Equal(t, []string{"nginx/nginx.conf"}, suffixPaths(sub, ".conf"))
+		assert.Equal(t, []string{"etc/hosts"}, suffixPaths(b, "etc/hosts"))
+		assert.Equal(t, []string{}, suffixPaths(sub, "etc/hosts"))
+		assert.Equal(t, []string{"hosts", "nginx/mime.types", "nginx/nginx.conf"}, suffixPaths(sub, ""))
+	})
+
+	t.Run("nested subset and copy", func(t *testing.T) {
+		t.Parallel()
+
+		nginx, err := sub.Subset("nginx")
+		require.NoError(t, err)
+		content, err := nginx.ReadFile("mime.types")
+		require.NoError(t, err)
+		assert.Equal(t, files["etc/nginx/mime.types"], content)
+
+		destDir := t.TempDir()
+		stats, err := nginx.CopyDir(destDir, ".")
+		require.NoError(t, err)
+		assert.Equal(t, 2, stats.FileCount)
+		got, err := os.ReadFile(filepath.Join(destDir, "nginx.conf"))
+		require.NoError(t, err)
+		assert.Equal(t, files["etc/nginx/nginx.conf"], got)
+	})
+
+	t.Run("invalid prefixes", func(t *testing.T) {
+		t.Parallel()
+
+		_, err := b.Subset("missing")
+		assert.ErrorIs(t, err, fs.ErrNotExist)
+		_, err = b.Subset("etc/hosts")
+		assert.ErrorIs(t, err, fs.ErrNotExist)
+		_, err = b.Subset("../etc")
+		assert.ErrorIs(t, err, fs.ErrInvalid)
+	})
+}
+
+func TestBlobSub(t *testing.T) {
+	t.Parallel()
+
+	files := map[string][]byte{
+		"templates/base.html":         []byte("<html>{{block}}</html>"),
+		"templates/pages/index.html":  []byte("index"),
+		"templates/pages/about.html":  []byte("about"),
+		"templates/pages.html":        []byte("pages"),
+		"templates/partials/nav.html": []byte("nav"),
+		"static/app.css":              []byte("body {}"),
+		"templates-old/legacy.html":   []byte("legacy"),
+	}
+	b := createTestArchive(t, files, CompressionZstd)
+
+	sub, err := fs.Sub(b, "templates")
+	require.NoError(t, err)
+	require.IsType(t, &Blob{}, sub, "fs.Sub should use Blob.Sub")
+
+	require.NoError(t, fstest.TestFS(sub,
+		"base.html", "pages.html", "pages/index.html", "pages/about.html", "partials/nav.html"))
+
+	content, err := fs.ReadFile(sub, "pages/about.html")
+	require.NoError(t, err)
+	assert.Equal(t, files["templates/pages/about.html"], content)
+
+	_, err = sub.Open("../static/app.css")
+	require.ErrorIs(t, err, fs.ErrInvalid)
+
+	_, err = b.Sub("../templates")
+	require.ErrorIs(t, err, fs.ErrInvalid)
+	_, err = b.Sub("missing")
+	require.ErrorIs(t, err, fs.ErrNotExist)
+
+	root, err := b.Sub(".")
+	require.NoError(t, err)
+	assert.Same(t, b, root)
+}
@@ -0,0 +1,148 @@
+package blob
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/meigma/blob/core/internal/batch"
+)
+
+// validateSymlinkTarget rejects link targets that could resolve outside the
+// extraction root: absolute targets and targets that climb above it when
+// resolved relative to the link's directory.
+func validateSymlinkTarget(linkPath, target string) error {
+	if target == "" {
+		return &fs.PathError{Op: "symlink", Path: linkPath, Err: fmt.Errorf("%w: empty target", ErrSymlink)}
+	}
+	if path.IsAbs(target) || filepath.IsAbs(target) || filepath.VolumeName(target) != "" || strings.Contains(target, `\`) {
+		return &fs.PathError{Op: "symlink", Path: linkPath, Err: fmt.Errorf("%w: absolute target %q", ErrSymlink, target)}
+	}
+	resolved := path.Join(path.Dir(linkPath), target)
+	if resolved != "." && !fs.ValidPath(resolved) {
+		return &fs.PathError{Op: "symlink", Path: linkPath, Err: fmt.Errorf("%w: target %q escapes destination", ErrSymlink, target)}
+	}
+	return nil
+}
+
+// maxSymlinkHops bounds the links followed by validateSymlinkChain, as the
+// operating system does; a longer chain fails to resolve with ELOOP.
+const maxSymlinkHops = 40
+
+// validateSymlinkChain rejects a link whose target escapes the extraction
+// root when resolved through the other links created with it. Each target
+// may pass validateSymlinkTarget on its own and still escape once a link in
+// its path is followed: with "d/b -> ..", the target "b/../secret" of
+// "d/a" resolves to "../secret". links maps each link's path to its target.
+func validateSymlinkChain(linkPath string, links map[string]string) error {
+	target := links[linkPath]
+	pending := append(strings.Split(path.Dir(linkPath), "/"), strings.Split(target, "/")...)
+	var resolved []string
+	for hops := 0; len(pending) > 0; {
+		name := pending[0]
+		pending = pending[1:]
+		switch name {
+		case "", ".":
+			continue
+		case "..":
+			if len(resolved) == 0 {
+				return &fs.PathError{Op: "symlink", Path: linkPath, Err: fmt.Errorf("%w: target %q escapes destination through another link", ErrSymlink, target)}
+			}
+			resolved = resolved[:len(resolved)-1]
+			continue
+		}
+		resolved = append(resolved, name)
+		next, ok := links[strings.Join(resolved, "/")]
+		if !ok {
+			continue
+		}
+		if hops++; hops > maxSymlinkHops {
+			return nil // the chain cannot resolve at all
+		}
+		resolved = resolved[:len(resolved)-1]
+		pending = append(strings.Split(next, "/"), pending...)
+	}
+	return nil
+}
+
+// readSymlinkTarget reads the target stored for a symlink entry and
+// validates it for a link created at linkPath.
+func (b *Blob) readSymlinkTarget(entry *batch.Entry, linkPath string) (string, error) {
+	content, err := b.reader.ReadAll(entry)
+	if err != nil {
+		return "", fmt.Errorf("read symlink %s: %w", entry.Path, err)
+	}
+	target := string(content)
+	if err := validateSymlinkTarget(linkPath, target); err != nil {
+		return "", err
+	}
+	return target, nil
+}
+
+// createSymlinkEntries recreates symlink entries under destDir.
+//
+// Every target is validated, on its own and through the other links, before
+// any link is created. Existing paths are skipped unless cfg.overwrite is
+// set; directories are never replaced.
+func (b *Blob) createSymlinkEntries(destDir string, links []*batch.Entry, cfg *copyConfig, budget *extractionBudget) (CopyStats, error) {
+	var stats CopyStats
+	if len(links) == 0 {
+		return stats, nil
+	}
+
+	targets := make([]string, len(links))
+	byPath := make(map[string]string, len(links))
+	for i, link := range links {
+		target, err := b.readSymlinkTarget(link, link.Path)
+		if err != nil {
+			return stats, err
+		}
+		targets[i] = target
+		byPath[link.Path] = target
+	}
+	for _, link := range links {
+		if err := validateSymlinkChain(link.Path, byPath); err != nil {
+			return stats, err
+		}
+	}
+
+	root, err := os.OpenRoot(destDir)
+	if err != nil {
+		return stats, err
+	}
+	defer root.Close()
+
+	for i, link := range links {
+		rel := filepath.FromSlash(link.Path)
+		if info, err := root.Lstat(rel); err == nil {
+			if !cfg.overwrite {
+				stats.Skipped++
+				continue
+			}
+			if info.IsDir() {
+				return stats, &fs.PathError{Op: "symlink", Path: link.Path, Err: errors.New("is a directory")}
+			}
+		}
+		if budget != nil {
+			if err := budget.reserve(link); err != nil {
+				return stats, err
+			}
+		}
+		if err := root.MkdirAll(filepath.Dir(rel), 0o750); err != nil {
+			return stats, fmt.Errorf("create directory %s: %w", path.Dir(link.Path), err)
+		}
+		if cfg.overwrite {
+			_ = root.Remove(rel) //nolint:errcheck // Symlink fails below if removal was needed but failed
+		}
+		if err := root.Symlink(targets[i], rel); err != nil {
+			return stats, fmt.Errorf("create symlink %s: %w", link.Path, err)
+		}
+		stats.FileCount++
+		stats.TotalBytes += link.OriginalSize
+	}
+	return stats, nil
+}
@@ -0,0 +1,119 @@
+package blob
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"hash"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+
+	"github.com/meigma/blob/core/internal/batch"
+)
+
+// SyncDir brings destDir up to date with the files under prefix, writing
+// only those that are missing or whose content differs.
+//
+// A local regular file with the entry's size is compared by content: with
+// the archive's auxiliary checksum when it was created with
+// CreateWithAuxChecksum, which is much cheaper to compute, and with SHA256
+// otherwise. Unchanged files are left untouched and counted in
+// CopyStats.Skipped; changed files are overwritten. Files in destDir that
+// are not in the archive are kept.
+//
+// SyncDir accepts the same options as CopyDir except CopyWithCleanDest and
+// CopyWithPathMapper; CopyWithOverwrite is implied.
+func (b *Blob) SyncDir(destDir, prefix string, opts ...CopyOption) (CopyStats, error) {
+	return b.SyncDirContext(context.Background(), destDir, prefix, opts...)
+}
+
+// SyncDirContext is like SyncDir but stops when ctx is canceled.
+func (b *Blob) SyncDirContext(ctx context.Context, destDir, prefix string, opts ...CopyOption) (CopyStats, error) {
+	cfg := copyConfig{}
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+	if cfg.cleanDest {
+		return CopyStats{}, errors.New("CopyWithCleanDest is not supported by SyncDir")
+	}
+	if cfg.pathMapper != nil {
+		return CopyStats{}, errors.New("CopyWithPathMapper is not supported by SyncDir")
+	}
+	if err := cfg.filter.validate(); err != nil {
+		return CopyStats{}, err
+	}
+	cfg.overwrite = true
+
+	entries, filtered := b.collectPrefixEntries(prefix, &cfg.filter)
+	changed := entries[:0:0]
+	unchanged := 0
+	for _, entry := range entries {
+		if err := ctx.Err(); err != nil {
+			return CopyStats{Filtered: filtered}, err
+		}
+		if !entry.Mode.IsRegular() || !fs.ValidPath(entry.Path) {
+			// Directories, symlinks, and invalid paths are left to copyEntries.
+			changed = append(changed, entry)
+			continue
+		}
+		same, err := b.localMatches(filepath.Join(destDir, filepath.FromSlash(entry.Path)), entry)
+		if err != nil {
+			return CopyStats{Filtered: filtered}, err
+		}
+		if same {
+			unchanged++
+			continue
+		}
+		changed = append(changed, entry)
+	}
+
+	stats, err := b.copyEntries(ctx, destDir, changed, &cfg)
+	stats.Skipped += unchanged
+	stats.Filtered += filtered
+	return stats, err
+}
+
+// localMatches reports whether the regular file at path has the content of
+// entry. Missing files and non-regular files never match.
+func (b *Blob) localMatches(path string, entry *batch.Entry) (bool, error) {
+	info, err := os.Lstat(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	if !info.Mode().IsRegular() || uint64(info.Size()) != entry.OriginalSize { //nolint:gosec // size of a regular file is non-negative
+		return false, nil
+	}
+
+	f, err := os.Open(path) //nolint:gosec // path is within the caller's destination
+	if err != nil {
+		return false, err
+	}
+	defer f.Close()
+
+	if aux := newAuxHasher(b.AuxChecksum()); aux != nil {
+		if err := hashFile(aux, f, path); err != nil {
+			return false, err
+		}
+		return aux.Sum64() == entry.AuxChecksum, nil
+	}
+	h := sha256.New()
+	if err := hashFile(h, f, path); err != nil {
+		return false, err
+	}
+	return bytes.Equal(h.Sum(nil), entry.Hash), nil
+}
+
+// hashFile feeds the content of f to h.
+func hashFile(h hash.Hash, f io.Reader, path string) error {
+	if _, err := io.Copy(h, f); err != nil {
+		return fmt.Errorf("read %s: %w", path, err)
+	}
+	return nil
+}
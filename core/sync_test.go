@@ -0,0 +1,141 @@
+package blob
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/cespare/xxhash/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/meigma/blob/core/testutil"
+)
+
+func TestCreateWithAuxChecksum(t *testing.T) {
+	t.Parallel()
+
+	files := map[string][]byte{
+		"a.txt":     []byte("alpha"),
+		"dir/b.txt": bytes.Repeat([]byte("b"), 4096),
+	}
+	dir := t.TempDir()
+	createTestFilesBytes(t, dir, files)
+
+	for _, compression := range []Compression{CompressionNone, CompressionZstd} {
+		t.Run(compression.String(), func(t *testing.T) {
+			t.Parallel()
+
+			var indexBuf, dataBuf bytes.Buffer
+			require.NoError(t, Create(context.Background(), dir, &indexBuf, &dataBuf,
+				CreateWithCompression(compression), CreateWithAuxChecksum(AuxChecksumXXH64)))
+			b, err := New(indexBuf.Bytes(), testutil.NewMockByteSource(dataBuf.Bytes()))
+			require.NoError(t, err)
+
+			assert.Equal(t, AuxChecksumXXH64, b.AuxChecksum())
+			for path, content := range files {
+				view, ok := b.Entry(path)
+				require.True(t, ok, path)
+				assert.Equal(t, xxhash.Sum64(content), view.AuxChecksum(), path)
+			}
+		})
+	}
+
+	t.Run("disabled", func(t *testing.T) {
+		t.Parallel()
+
+		b := createTestArchive(t, files, CompressionNone)
+		assert.Equal(t, AuxChecksumNone, b.AuxChecksum())
+		view, ok := b.Entry("a.txt")
+		require.True(t, ok)
+		assert.Zero(t, view.AuxChecksum())
+	})
+}
+
+func TestSyncDir(t *testing.T) {
+	t.Parallel()
+
+	files := map[string][]byte{
+		"same.txt":      []byte("unchanged"),
+		"edited.txt":    []byte("original"),
+		"missing.txt":   []byte("new file"),
+		"nested/ok.txt": []byte("nested"),
+	}
+
+	for _, aux := range []AuxChecksum{AuxChecksumNone, AuxChecksumXXH64} {
+		t.Run(aux.String(), func(t *testing.T) {
+			t.Parallel()
+
+			src := t.TempDir()
+			createTestFilesBytes(t, src, files)
+			var indexBuf, dataBuf bytes.Buffer
+			require.NoError(t, Create(context.Background(), src, &indexBuf, &dataBuf, CreateWithAuxChecksum(aux)))
+			b, err := New(indexBuf.Bytes(), testutil.NewMockByteSource(dataBuf.Bytes()))
+			require.NoError(t, err)
+
+			dest := t.TempDir()
+			createTestFilesBytes(t, dest, map[string][]byte{
+				"same.txt":      []byte("unchanged"),
+				"edited.txt":    []byte("modified"), // same size, different content
+				"nested/ok.txt": []byte("nested"),
+				"extra.txt":     []byte("local only"),
+			})
+
+			stats, err := b.SyncDir(dest, "")
+			require.NoError(t, err)
+			assert.Equal(t, 2, stats.FileCount)
+			assert.Equal(t, 2, stats.Skipped)
+
+			for path, want := range files {
+				got, err := os.ReadFile(filepath.Join(dest, filepath.FromSlash(path)))
+				require.NoError(t, err)
+				assert.Equal(t, want, got, path)
+			}
+			_, err = os.Stat(filepath.Join(dest, "extra.txt"))
+			require.NoError(t, err, "files not in the archive are kept")
+		})
+	}
+}
+
+func TestSyncDir_UsesAuxChecksum(t *testing.T) {
+	t.Parallel()
+
+	content := []byte("local content")
+	// The index records a SHA256 hash that does not match the local file but
+	// a matching aux checksum. SyncDir must trust the aux checksum and skip
+	// the file without computing SHA256.
+	bogus := sha256.Sum256([]byte("something else"))
+	entry := testutil.TestEntry{
+		Path:         "file.txt",
+		DataSize:     uint64(len(content)),
+		OriginalSize: uint64(len(content)),
+		Hash:         bogus[:],
+		Mode:         0o644,
+		AuxChecksum:  xxhash.Sum64(content),
+	}
+	withAux := testutil.BuildTestIndexWithMetadata(t, []testutil.TestEntry{entry},
+		&testutil.IndexMetadata{AuxChecksum: AuxChecksumXXH64})
+	withoutAux := testutil.BuildTestIndex(t, []testutil.TestEntry{entry})
+
+	sync := func(t *testing.T, indexData []byte) CopyStats {
+		t.Helper()
+		b, err := New(indexData, testutil.NewMockByteSource(content))
+		require.NoError(t, err)
+		dest := t.TempDir()
+		require.NoError(t, os.WriteFile(filepath.Join(dest, "file.txt"), content, 0o644))
+		stats, _ := b.SyncDir(dest, "")
+		return stats
+	}
+
+	stats := sync(t, withAux)
+	assert.Equal(t, 1, stats.Skipped, "aux checksum match should skip the file")
+	assert.Zero(t, stats.FileCount)
+
+	// Without an aux checksum the SHA256 comparison fails, so the file is
+	// rewritten (and the read fails verification against the bogus hash).
+	stats = sync(t, withoutAux)
+	assert.Zero(t, stats.Skipped)
+}
@@ -0,0 +1,270 @@
+package blob
+
+import (
+	"archive/tar"
+	"fmt"
+	"io"
+	"io/fs"
+	"path"
+	"time"
+
+	"github.com/meigma/blob/core/internal/batch"
+)
+
+// TarMode controls how WriteTar builds tar headers.
+type TarMode uint8
+
+// Tar modes.
+const (
+	// TarModeStandard writes files and explicit directory entries with the
+	// modes, modification times, and ownership recorded in the index.
+	TarModeStandard TarMode = iota
+
+	// TarModeLayerCompatible writes a canonical tar suitable for use as a
+	// container image layer: every parent directory gets an explicit entry,
+	// ownership is 0/0 with no user or group names, modification times are
+	// the Unix epoch, directories are 0755, and files are 0755 when any
+	// execute bit is set and 0644 otherwise. Archives with the same content
+	// produce byte-identical output. Whiteouts are not generated.
+	TarModeLayerCompatible
+)
+
+// TarOption configures WriteTar.
+type TarOption func(*tarConfig)
+
+// tarConfig holds configuration for WriteTar.
+type tarConfig struct {
+	mode   TarMode
+	prefix string
+}
+
+// WriteTarWithMode sets how tar headers are built (default: TarModeStandard).
+func WriteTarWithMode(mode TarMode) TarOption {
+	return func(c *tarConfig) {
+		c.mode = mode
+	}
+}
+
+// WriteTarWithPrefix writes only the entries under the directory prefix,
+// as CopyDir selects them. Paths in the tar keep the prefix. The default
+// writes every entry.
+func WriteTarWithPrefix(prefix string) TarOption {
+	return func(c *tarConfig) {
+		c.prefix = prefix
+	}
+}
+
+// WriteTar writes the archive's entries to w as a tar stream.
+//
+// Entries are written in index order, which sorts paths by byte value, so
+// the output is deterministic. Directory entries use a trailing slash.
+// Symlink entries (see CreateWithSymlinks) are written as symbolic links.
+// File content is verified against its hash as it is written; a mismatch
+// aborts the stream with ErrHashMismatch. For a Subset view, paths are
+// relative to the subset root.
+//
+// File content is fetched with the same batched range reads as CopyDir, so
+// adjacent files are read together and reads run ahead of the writer.
+// Archives whose data is not laid out in index order fall back to reading
+// one file at a time.
+//
+// WriteTar does not close w. The tar footer is only written on success.
+func (b *Blob) WriteTar(w io.Writer, opts ...TarOption) error {
+	cfg := tarConfig{}
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+	if cfg.prefix != "" && cfg.prefix != "." && !fs.ValidPath(cfg.prefix) {
+		return &fs.PathError{Op: "tar", Path: cfg.prefix, Err: fs.ErrInvalid}
+	}
+
+	entries, _ := b.collectPrefixEntries(cfg.prefix, &copyFilter{})
+	s := &tarStream{b: b, mode: cfg.mode, entries: entries, dirs: make(map[string]struct{})}
+	if !dataInIndexOrder(entries) {
+		tw := tar.NewWriter(w)
+		for _, entry := range entries {
+			if err := s.writeEntry(tw, entry); err != nil {
+				return err
+			}
+		}
+		return tw.Close()
+	}
+
+	sink := batch.NewTarSink(w, batch.WithTarHeaderFunc(s.beforeFile))
+	if _, err := b.newBatchProcessor(batch.WithWorkers(-1)).Process(entries, sink); err != nil {
+		return err
+	}
+	if err := sink.Append(s.writeRest); err != nil {
+		return err
+	}
+	return sink.Close()
+}
+
+// tarStream writes the entries of WriteTar in index order. With the batch
+// pipeline, file entries are written by the TarSink and the others are
+// written from beforeFile as the stream reaches them.
+type tarStream struct {
+	b       *Blob
+	mode    TarMode
+	entries []*batch.Entry // index order
+	next    int            // first entry not yet written
+	dirs    map[string]struct{}
+}
+
+// beforeFile writes the entries that precede the file entry in index order
+// and prepares its header.
+func (s *tarStream) beforeFile(tw *tar.Writer, entry *batch.Entry, hdr *tar.Header) error {
+	for ; s.next < len(s.entries) && s.entries[s.next] != entry; s.next++ {
+		if err := s.writeEntry(tw, s.entries[s.next]); err != nil {
+			return err
+		}
+	}
+	if s.next == len(s.entries) {
+		return fmt.Errorf("tar %s: entry out of order", entry.Path)
+	}
+	s.next++
+	if s.mode == TarModeLayerCompatible {
+		if err := writeTarParents(tw, entry.Path, s.dirs); err != nil {
+			return err
+		}
+		normalizeLayerHeader(hdr)
+	}
+	return nil
+}
+
+// writeRest writes the entries after the last file entry.
+func (s *tarStream) writeRest(tw *tar.Writer) error {
+	for ; s.next < len(s.entries); s.next++ {
+		if err := s.writeEntry(tw, s.entries[s.next]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// writeEntry writes one entry, reading file content through Open.
+func (s *tarStream) writeEntry(tw *tar.Writer, entry *batch.Entry) error {
+	name := entry.Path
+	mode := entry.Mode
+
+	if s.mode == TarModeLayerCompatible {
+		if err := writeTarParents(tw, name, s.dirs); err != nil {
+			return err
+		}
+	}
+	if mode.IsDir() {
+		if _, ok := s.dirs[name]; ok {
+			return nil
+		}
+		s.dirs[name] = struct{}{}
+	}
+
+	hdr := &tar.Header{
+		Name:    name,
+		Mode:    int64(mode.Perm()),
+		ModTime: entry.ModTime,
+		Uid:     int(entry.UID),
+		Gid:     int(entry.GID),
+	}
+	switch {
+	case mode.IsDir():
+		hdr.Typeflag = tar.TypeDir
+		hdr.Name += "/"
+	case mode&fs.ModeSymlink != 0:
+		target, err := s.b.ReadFile(name)
+		if err != nil {
+			return fmt.Errorf("tar %s: %w", name, err)
+		}
+		hdr.Typeflag = tar.TypeSymlink
+		hdr.Linkname = string(target)
+	default:
+		hdr.Typeflag = tar.TypeReg
+		hdr.Size = int64(entry.OriginalSize) //nolint:gosec // bounded by the max file size enforced on read
+	}
+	if s.mode == TarModeLayerCompatible {
+		normalizeLayerHeader(hdr)
+	}
+
+	if err := tw.WriteHeader(hdr); err != nil {
+		return fmt.Errorf("tar %s: %w", name, err)
+	}
+	if hdr.Typeflag == tar.TypeReg {
+		if err := s.b.copyArchiveContent(tw, "tar", name); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// dataInIndexOrder reports whether the data offsets of entries never
+// decrease, so the batch pipeline delivers files in index order.
+func dataInIndexOrder(entries []*batch.Entry) bool {
+	var last uint64
+	for _, entry := range entries {
+		if entry.Mode.Type()&(fs.ModeDir|fs.ModeSymlink) != 0 {
+			continue
+		}
+		if entry.DataOffset < last {
+			return false
+		}
+		last = entry.DataOffset
+	}
+	return true
+}
+
+// copyArchiveContent streams and verifies the content of name into w.
+// Errors are prefixed with op, the export format.
+func (b *Blob) copyArchiveContent(w io.Writer, op, name string) error {
+	f, err := b.Open(name)
+	if err != nil {
+		return fmt.Errorf("%s %s: %w", op, name, err)
+	}
+	if _, err := io.Copy(w, f); err != nil {
+		_ = f.Close() //nolint:errcheck // the copy error takes precedence
+		return fmt.Errorf("%s %s: %w", op, name, err)
+	}
+	if err := f.Close(); err != nil {
+		return fmt.Errorf("%s %s: %w", op, name, err)
+	}
+	return nil
+}
+
+// writeTarParents writes directory entries for the parents of name that have
+// not been written yet, outermost first.
+func writeTarParents(tw *tar.Writer, name string, dirs map[string]struct{}) error {
+	var missing []string
+	for dir := path.Dir(name); dir != "."; dir = path.Dir(dir) {
+		if _, ok := dirs[dir]; ok {
+			break
+		}
+		missing = append(missing, dir)
+	}
+	for i := len(missing) - 1; i >= 0; i-- {
+		dir := missing[i]
+		dirs[dir] = struct{}{}
+		hdr := &tar.Header{Typeflag: tar.TypeDir, Name: dir + "/"}
+		normalizeLayerHeader(hdr)
+		if err := tw.WriteHeader(hdr); err != nil {
+			return fmt.Errorf("tar %s: %w", dir, err)
+		}
+	}
+	return nil
+}
+
+// normalizeLayerHeader applies TarModeLayerCompatible normalization to hdr.
+func normalizeLayerHeader(hdr *tar.Header) {
+	hdr.Uid, hdr.Gid = 0, 0
+	hdr.Uname, hdr.Gname = "", ""
+	hdr.ModTime = time.Unix(0, 0)
+	hdr.AccessTime, hdr.ChangeTime = time.Time{}, time.Time{}
+	switch {
+	case hdr.Typeflag == tar.TypeDir:
+		hdr.Mode = 0o755
+	case hdr.Typeflag == tar.TypeSymlink:
+		hdr.Mode = 0o777
+	case hdr.Mode&0o111 != 0:
+		hdr.Mode = 0o755
+	default:
+		hdr.Mode = 0o644
+	}
+}
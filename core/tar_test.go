@@ -0,0 +1,257 @@
+package blob
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"crypto/sha256"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/meigma/blob/core/testutil"
+)
+
+var tarFiles = map[string][]byte{
+	"a.txt":         []byte("alpha"),
+	"bin/run.sh":    []byte("#!/bin/sh\necho run\n"),
+	"dir/sub/c.txt": bytes.Repeat([]byte("charlie "), 64),
+}
+
+// createTarTestArchive builds an archive from tarFiles with bin/run.sh
+// executable and every file stamped with modTime.
+func createTarTestArchive(t *testing.T, compression Compression, modTime time.Time) *Blob {
+	t.Helper()
+
+	dir := t.TempDir()
+	createTestFilesBytes(t, dir, tarFiles)
+	require.NoError(t, os.Chmod(filepath.Join(dir, "bin", "run.sh"), 0o750))
+	for name := range tarFiles {
+		require.NoError(t, os.Chtimes(filepath.Join(dir, filepath.FromSlash(name)), modTime, modTime))
+	}
+
+	var indexBuf, dataBuf bytes.Buffer
+	err := Create(context.Background(), dir, &indexBuf, &dataBuf, CreateWithCompression(compression))
+	require.NoError(t, err)
+	b, err := New(indexBuf.Bytes(), testutil.NewMockByteSource(dataBuf.Bytes()))
+	require.NoError(t, err)
+	return b
+}
+
+// gzipDigest returns the SHA256 digest of the gzipped tar produced by b.
+func gzipDigest(t *testing.T, b *Blob, opts ...TarOption) [sha256.Size]byte {
+	t.Helper()
+
+	var buf bytes.Buffer
+	zw := gzip.NewWriter(&buf)
+	require.NoError(t, b.WriteTar(zw, opts...))
+	require.NoError(t, zw.Close())
+	return sha256.Sum256(buf.Bytes())
+}
+
+func TestBlobWriteTar_LayerCompatible(t *testing.T) {
+	t.Parallel()
+
+	first := createTarTestArchive(t, CompressionNone, time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC))
+	second := createTarTestArchive(t, CompressionZstd, time.Date(2024, 6, 7, 8, 9, 10, 0, time.UTC))
+
+	layer := WriteTarWithMode(TarModeLayerCompatible)
+	assert.Equal(t, gzipDigest(t, first, layer), gzipDigest(t, second, layer), "layer digest should be stable")
+	assert.NotEqual(t, gzipDigest(t, first), gzipDigest(t, second), "standard mode keeps modification times")
+
+	var buf bytes.Buffer
+	require.NoError(t, first.WriteTar(&buf, layer))
+
+	type header struct {
+		name string
+		typ  byte
+		mode int64
+	}
+	var got []header
+	tr := tar.NewReader(&buf)
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		require.NoError(t, err)
+		got = append(got, header{name: hdr.Name, typ: hdr.Typeflag, mode: hdr.Mode})
+
+		assert.Zero(t, hdr.Uid, hdr.Name)
+		assert.Zero(t, hdr.Gid, hdr.Name)
+		assert.Empty(t, hdr.Uname, hdr.Name)
+		assert.True(t, hdr.ModTime.Equal(time.Unix(0, 0)), hdr.Name)
+		if hdr.Typeflag == tar.TypeReg {
+			content, err := io.ReadAll(tr)
+			require.NoError(t, err)
+			assert.Equal(t, tarFiles[hdr.Name], content, hdr.Name)
+		}
+	}
+
+	assert.Equal(t, []header{
+		{name: "a.txt", typ: tar.TypeReg, mode: 0o644},
+		{name: "bin/", typ: tar.TypeDir, mode: 0o755},
+		{name: "bin/run.sh", typ: tar.TypeReg, mode: 0o755},
+		{name: "dir/", typ: tar.TypeDir, mode: 0o755},
+		{name: "dir/sub/", typ: tar.TypeDir, mode: 0o755},
+		{name: "dir/sub/c.txt", typ: tar.TypeReg, mode: 0o644},
+	}, got)
+}
+
+func TestBlobWriteTar_Standard(t *testing.T) {
+	t.Parallel()
+
+	modTime := time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC)
+	b := createTarTestArchive(t, CompressionZstd, modTime)
+
+	var buf bytes.Buffer
+	require.NoError(t, b.WriteTar(&buf))
+
+	var names []string
+	tr := tar.NewReader(&buf)
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		require.NoError(t, err)
+		names = append(names, hdr.Name)
+		assert.True(t, hdr.ModTime.Equal(modTime), hdr.Name)
+		if hdr.Name == "bin/run.sh" {
+			assert.Equal(t, int64(0o750), hdr.Mode)
+		}
+	}
+	assert.Equal(t, []string{"a.txt", "bin/run.sh", "dir/sub/c.txt"}, names)
+}
+
+// readTar returns the regular files, symlink targets, and directory names of
+// a tar stream, in stream order.
+func readTar(t *testing.T, r io.Reader) (names []string, files map[string][]byte, links map[string]string) {
+	t.Helper()
+
+	files = make(map[string][]byte)
+	links = make(map[string]string)
+	tr := tar.NewReader(r)
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			return names, files, links
+		}
+		require.NoError(t, err)
+		names = append(names, hdr.Name)
+		switch hdr.Typeflag {
+		case tar.TypeReg:
+			content, err := io.ReadAll(tr)
+			require.NoError(t, err)
+			files[hdr.Name] = content
+		case tar.TypeSymlink:
+			links[hdr.Name] = hdr.Linkname
+		}
+	}
+}
+
+func TestBlobWriteTar_RoundTrip(t *testing.T) {
+	t.Parallel()
+
+	files := map[string][]byte{
+		"a.txt":             []byte("alpha"),
+		"empty":             {},
+		"empty2":            {},
+		"dir/b.bin":         bytes.Repeat([]byte{0, 1, 2, 3}, 20000),
+		"dir/sub/c.txt":     bytes.Repeat([]byte("charlie "), 64),
+		"dir/sub/empty.txt": {},
+		"z/last.txt":        []byte("zulu"),
+	}
+	srcDir := t.TempDir()
+	createTestFilesBytes(t, srcDir, files)
+	require.NoError(t, os.Symlink("../a.txt", filepath.Join(srcDir, "dir", "link")))
+
+	var indexBuf, dataBuf bytes.Buffer
+	require.NoError(t, Create(context.Background(), srcDir, &indexBuf, &dataBuf,
+		CreateWithSymlinks(true), CreateWithCompression(CompressionZstd)))
+	b, err := New(indexBuf.Bytes(), testutil.NewMockByteSource(dataBuf.Bytes()))
+	require.NoError(t, err)
+
+	var buf bytes.Buffer
+	require.NoError(t, b.WriteTar(&buf))
+	names, gotFiles, gotLinks := readTar(t, bytes.NewReader(buf.Bytes()))
+
+	var want []string
+	for view := range b.Entries() {
+		want = append(want, view.Path())
+	}
+	assert.Equal(t, want, names, "tar entries should be in index order")
+	assert.Equal(t, files, gotFiles)
+	assert.Equal(t, map[string]string{"dir/link": "../a.txt"}, gotLinks)
+
+	// Extract the tar and archive the result again.
+	extractDir := t.TempDir()
+	for name, content := range gotFiles {
+		path := filepath.Join(extractDir, filepath.FromSlash(name))
+		require.NoError(t, os.MkdirAll(filepath.Dir(path), 0o755))
+		require.NoError(t, os.WriteFile(path, content, 0o644))
+	}
+	var index2, data2 bytes.Buffer
+	require.NoError(t, Create(context.Background(), extractDir, &index2, &data2))
+	b2, err := New(index2.Bytes(), testutil.NewMockByteSource(data2.Bytes()))
+	require.NoError(t, err)
+	for name, content := range files {
+		got, err := b2.ReadFile(name)
+		require.NoError(t, err, name)
+		assert.Equal(t, content, got, name)
+	}
+
+	t.Run("prefix", func(t *testing.T) {
+		t.Parallel()
+
+		var buf bytes.Buffer
+		require.NoError(t, b.WriteTar(&buf, WriteTarWithPrefix("dir/sub")))
+		names, _, _ := readTar(t, &buf)
+		assert.Equal(t, []string{"dir/sub/c.txt", "dir/sub/empty.txt"}, names)
+
+		require.ErrorIs(t, b.WriteTar(io.Discard, WriteTarWithPrefix("../x")), fs.ErrInvalid)
+	})
+}
+
+func TestBlobWriteTar_DataOutOfIndexOrder(t *testing.T) {
+	t.Parallel()
+
+	// The data blob stores b.txt before a.txt.
+	a, bb := []byte("alpha"), []byte("bravo!")
+	data := append(slices.Clone(bb), a...)
+	hashA, hashB := sha256.Sum256(a), sha256.Sum256(bb)
+	indexData := testutil.BuildTestIndex(t, []testutil.TestEntry{
+		{Path: "a.txt", DataOffset: uint64(len(bb)), DataSize: uint64(len(a)), OriginalSize: uint64(len(a)), Hash: hashA[:], Mode: 0o644},
+		{Path: "b.txt", DataOffset: 0, DataSize: uint64(len(bb)), OriginalSize: uint64(len(bb)), Hash: hashB[:], Mode: 0o644},
+	})
+	b, err := New(indexData, testutil.NewMockByteSource(data))
+	require.NoError(t, err)
+
+	var buf bytes.Buffer
+	require.NoError(t, b.WriteTar(&buf))
+	names, files, _ := readTar(t, &buf)
+	assert.Equal(t, []string{"a.txt", "b.txt"}, names)
+	assert.Equal(t, map[string][]byte{"a.txt": a, "b.txt": bb}, files)
+}
+
+func TestBlobWriteTar_HashMismatch(t *testing.T) {
+	t.Parallel()
+
+	content := []byte("alpha")
+	wrong := sha256.Sum256([]byte("other"))
+	indexData := testutil.BuildTestIndex(t, []testutil.TestEntry{
+		{Path: "a.txt", DataSize: uint64(len(content)), OriginalSize: uint64(len(content)), Hash: wrong[:], Mode: 0o644},
+	})
+	b, err := New(indexData, testutil.NewMockByteSource(content))
+	require.NoError(t, err)
+
+	require.ErrorIs(t, b.WriteTar(io.Discard), ErrHashMismatch)
+}
@@ -25,12 +25,26 @@ type TestEntry struct {
 	GID          uint32
 	ModTime      time.Time
 	Compression  blobtype.Compression
+	AuxChecksum  uint64
 }
 
 // IndexMetadata holds optional index-level metadata for tests.
 type IndexMetadata struct {
 	DataSize uint64
 	DataHash []byte
+
+	// Version overrides the index format version (default 1).
+	Version uint32
+
+	// AuxChecksum records the algorithm of the entries' AuxChecksum values.
+	AuxChecksum blobtype.AuxChecksum
+
+	// BloomFilter is stored verbatim as the index bloom filter.
+	BloomFilter []byte
+
+	// UnknownFields appends that many uint32 fields after the last field
+	// in the schema, simulating an index written by a newer format.
+	UnknownFields int
 }
 
 // BuildTestIndex creates a FlatBuffers-encoded index from test entries.
@@ -80,6 +94,9 @@ func BuildTestIndexWithMetadata(tb testing.TB, entries []TestEntry, meta *IndexM
 		fb.EntryAddGid(builder, e.GID)
 		fb.EntryAddMtimeNs(builder, e.ModTime.UnixNano())
 		fb.EntryAddCompression(builder, fb.Compression(e.Compression)) //nolint:gosec // Compression is bounded 0-1
+		if e.AuxChecksum != 0 {
+			fb.EntryAddAuxChecksum(builder, e.AuxChecksum)
+		}
 		entryOffsets[i] = fb.EntryEnd(builder)
 	}
 
@@ -98,10 +115,26 @@ func BuildTestIndexWithMetadata(tb testing.TB, entries []TestEntry, meta *IndexM
 		}
 		dataHashOffset = builder.EndVector(len(meta.DataHash))
 	}
+	var bloomOffset flatbuffers.UOffsetT
+	if meta != nil && len(meta.BloomFilter) > 0 {
+		bloomOffset = builder.CreateByteVector(meta.BloomFilter)
+	}
 
 	// Build index
-	fb.IndexStart(builder)
-	fb.IndexAddVersion(builder, 1)
+	version := uint32(1)
+	if meta != nil && meta.Version != 0 {
+		version = meta.Version
+	}
+	if meta != nil && meta.UnknownFields > 0 {
+		const knownFields = 9
+		builder.StartObject(knownFields + meta.UnknownFields)
+		for i := range meta.UnknownFields {
+			builder.PrependUint32Slot(knownFields+i, uint32(i)+1, 0) //nolint:gosec // test field count is small
+		}
+	} else {
+		fb.IndexStart(builder)
+	}
+	fb.IndexAddVersion(builder, version)
 	fb.IndexAddHashAlgorithm(builder, fb.HashAlgorithmSHA256)
 	fb.IndexAddEntries(builder, entriesOffset)
 	if meta != nil {
@@ -109,6 +142,12 @@ func BuildTestIndexWithMetadata(tb testing.TB, entries []TestEntry, meta *IndexM
 		if dataHashOffset != 0 {
 			fb.IndexAddDataHash(builder, dataHashOffset)
 		}
+		if meta.AuxChecksum != blobtype.AuxChecksumNone {
+			fb.IndexAddAuxChecksum(builder, fb.AuxChecksum(meta.AuxChecksum)) //nolint:gosec // AuxChecksum is bounded 0-1
+		}
+		if bloomOffset != 0 {
+			fb.IndexAddBloomFilter(builder, bloomOffset)
+		}
 	}
 	indexOffset := fb.IndexEnd(builder)
 
@@ -0,0 +1,394 @@
+package blob
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/opencontainers/go-digest"
+
+	"github.com/meigma/blob/core/internal/file"
+)
+
+// FileChange describes one change applied by Update.
+//
+// A change either stores Content at Path, adding the file or replacing the
+// existing one, or, when Remove is set, deletes Path from the archive.
+type FileChange struct {
+	// Path is the archive path of the file, relative to the archive root.
+	Path string
+
+	// Content is the new file content. It is ignored when Remove is set.
+	Content []byte
+
+	// Mode holds the permission bits of the new file. Zero means 0o644.
+	Mode fs.FileMode
+
+	// ModTime is the modification time of the new file. The zero value
+	// means the time Update runs.
+	ModTime time.Time
+
+	// Remove deletes Path instead of storing Content.
+	Remove bool
+}
+
+// Update writes a new archive that is base with changes applied.
+//
+// Entries not named by a change keep their data bytes, hashes, and
+// metadata: their stored bytes are copied verbatim from base's data source,
+// with runs of adjacent entries copied in one read, so only added and
+// replaced files are compressed. Encrypted entries are sealed to their
+// offset, so they are decrypted and sealed again at their new one instead.
+// Because file hashes are unchanged, caches keyed by content stay warm
+// across the update. The merged entries are re-sorted and a fresh index is
+// written to dstIndex.
+//
+// Archive-wide settings come from base: its zstd dictionary, encryption
+// scheme, and aux checksum algorithm apply to the new files too, and a bloom
+// filter in base is rebuilt for the new paths. An encrypted base requires
+// CreateWithEncryption with the same scheme and key. Other options, such as
+// CreateWithCompression and CreateWithMaxFiles, apply as in Create. For a
+// Subset view, the new archive holds only the entries under the subset
+// root, with paths relative to it.
+//
+// Removing a path that does not exist, naming a path in more than one
+// change, or storing a file where a directory exists (or under a file)
+// returns an error.
+func Update(ctx context.Context, base *Blob, dstIndex, dstData io.Writer, changes []FileChange, opts ...CreateOption) error {
+	cfg := createConfig{}
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+	w := &writer{cfg: cfg, logger: cfg.logger, now: time.Now()}
+
+	if _, ok := base.idx.ZstdDictionaryHash(); ok && base.zstdDictionary == nil {
+		return errors.New("update: base archive requires its zstd dictionary; open it with WithZstdDictionary")
+	}
+
+	// The dictionary and aux checksum must match the copied entries.
+	w.cfg.zstdDictionary = base.zstdDictionary
+	w.cfg.auxChecksum = base.AuxChecksum()
+	if cfg.encryption != base.Encryption() {
+		return fmt.Errorf("update: encryption %s does not match base archive %s", cfg.encryption, base.Encryption())
+	}
+	if cfg.encryption != EncryptionNone {
+		aead, err := file.NewCipher(cfg.encryption, cfg.encryptionKey)
+		if err != nil {
+			return fmt.Errorf("encryption key: %w", err)
+		}
+		w.aead = aead
+	}
+	if err := w.initChunking(); err != nil {
+		return err
+	}
+
+	entries, err := mergeChanges(base, changes, &w.cfg)
+	if err != nil {
+		return err
+	}
+
+	w.log().Info("updating archive", "entries", len(entries), "changes", len(changes))
+
+	hasher := sha256.New()
+	dataWriter := io.MultiWriter(dstData, hasher)
+	dataSize, err := w.writeUpdate(ctx, base, dataWriter, entries)
+	if err != nil {
+		return err
+	}
+
+	final := make([]Entry, len(entries))
+	for i := range entries {
+		final[i] = entries[i].entry
+	}
+	dataHash := hasher.Sum(nil)
+	indexData := buildIndex(final, indexMetadata{
+		dataSize:       dataSize,
+		dataHash:       dataHash,
+		zstdDictionary: dictionaryHash(w.cfg.zstdDictionary),
+		encryption:     cfg.encryption,
+		auxChecksum:    w.cfg.auxChecksum,
+		bloomFilter:    cfg.bloomFilter || base.idx.HasBloomFilter(),
+	})
+	if _, err := dstIndex.Write(indexData); err != nil {
+		return err
+	}
+
+	if cfg.indexDigest != nil {
+		*cfg.indexDigest = digest.FromBytes(indexData)
+	}
+	if cfg.dataDigest != nil {
+		*cfg.dataDigest = digest.NewDigestFromEncoded(digest.SHA256, hex.EncodeToString(dataHash))
+	}
+	return nil
+}
+
+// updateEntry is an entry of the updated archive. Entries copied from the
+// base keep their base offset in entry until the data is written; change
+// is set for files whose content comes from a FileChange.
+type updateEntry struct {
+	entry  Entry
+	change *FileChange
+}
+
+// mergeChanges applies changes to the entries of base and returns the
+// result sorted by path.
+func mergeChanges(base *Blob, changes []FileChange, cfg *createConfig) ([]updateEntry, error) {
+	byPath := make(map[string]*FileChange, len(changes))
+	for i := range changes {
+		c := &changes[i]
+		if !fs.ValidPath(c.Path) || c.Path == "." {
+			return nil, &fs.PathError{Op: "update", Path: c.Path, Err: fs.ErrInvalid}
+		}
+		if _, ok := byPath[c.Path]; ok {
+			return nil, fmt.Errorf("update %s: path changed more than once", c.Path)
+		}
+		if !c.Remove {
+			if err := cfg.pathLimits.check(c.Path); err != nil {
+				return nil, err
+			}
+		}
+		byPath[c.Path] = c
+	}
+
+	entries := make([]updateEntry, 0, base.Len()+len(changes))
+	removed := make(map[string]bool)
+	for view := range base.Entries() {
+		entry := view.Entry()
+		if c, ok := byPath[entry.Path]; ok {
+			if c.Remove {
+				removed[entry.Path] = true
+				continue
+			}
+			if entry.Mode.IsDir() {
+				return nil, fmt.Errorf("update %s: path is a directory", c.Path)
+			}
+			continue
+		}
+		entries = append(entries, updateEntry{entry: entry})
+	}
+
+	for _, c := range changes {
+		if c.Remove {
+			if !removed[c.Path] {
+				return nil, &fs.PathError{Op: "remove", Path: c.Path, Err: fs.ErrNotExist}
+			}
+			continue
+		}
+		entries = append(entries, updateEntry{
+			entry:  Entry{Path: c.Path},
+			change: byPath[c.Path],
+		})
+	}
+
+	slices.SortFunc(entries, func(a, b updateEntry) int {
+		return strings.Compare(a.entry.Path, b.entry.Path)
+	})
+
+	maxFiles := cfg.maxFiles
+	if maxFiles == 0 {
+		maxFiles = DefaultMaxFiles
+	}
+	if maxFiles > 0 && len(entries) > maxFiles {
+		return nil, ErrTooManyFiles
+	}
+	if err := checkUpdateConflicts(entries, changes); err != nil {
+		return nil, err
+	}
+	return entries, nil
+}
+
+// checkUpdateConflicts rejects stored files that would sit where the merged
+// tree has a directory or beneath another file.
+func checkUpdateConflicts(entries []updateEntry, changes []FileChange) error {
+	kinds := make(map[string]bool, len(entries)) // path -> is directory entry
+	for i := range entries {
+		kinds[entries[i].entry.Path] = entries[i].entry.Mode.IsDir()
+	}
+	for _, c := range changes {
+		if c.Remove {
+			continue
+		}
+		children := c.Path + "/"
+		i, _ := slices.BinarySearchFunc(entries, children, func(e updateEntry, target string) int {
+			return strings.Compare(e.entry.Path, target)
+		})
+		if i < len(entries) && strings.HasPrefix(entries[i].entry.Path, children) {
+			return fmt.Errorf("update %s: path is a directory", c.Path)
+		}
+		for dir := c.Path; ; {
+			slash := strings.LastIndexByte(dir, '/')
+			if slash < 0 {
+				break
+			}
+			dir = dir[:slash]
+			if isDir, ok := kinds[dir]; ok && !isDir {
+				return fmt.Errorf("update %s: parent %s is a file", c.Path, dir)
+			}
+		}
+	}
+	return nil
+}
+
+// writeUpdate writes the data of entries in order, copying base entries and
+// writing changed files, and assigns each entry its new offset.
+func (w *writer) writeUpdate(ctx context.Context, base *Blob, data io.Writer, entries []updateEntry) (uint64, error) {
+	ws, err := w.newWorkspace()
+	if err != nil {
+		return 0, err
+	}
+	src := file.WithContext(ctx, base.reader.Source())
+
+	var total uint64
+	// A pending run of base entries whose stored bytes are contiguous.
+	var runStart, runEnd uint64
+	flush := func() error {
+		if runEnd == runStart {
+			return nil
+		}
+		section := io.NewSectionReader(src, int64(runStart), int64(runEnd-runStart)) //nolint:gosec // offsets were validated by New
+		if _, err := file.CopyWithContext(ctx, data, section, ws.buf); err != nil {
+			return fmt.Errorf("copy base data: %w", err)
+		}
+		runStart, runEnd = 0, 0
+		return nil
+	}
+
+	copied, written := 0, 0
+	for i := range entries {
+		if err := ctx.Err(); err != nil {
+			return 0, err
+		}
+		e := &entries[i]
+		if e.change == nil && file.IsEncrypted(&e.entry) {
+			if err := flush(); err != nil {
+				return 0, err
+			}
+			if err := w.resealEntry(src, data, &e.entry, total); err != nil {
+				return 0, err
+			}
+			if e.entry.DataSize > ^uint64(0)-total {
+				return 0, ErrSizeOverflow
+			}
+			total += e.entry.DataSize
+			copied++
+			continue
+		}
+		if e.change == nil {
+			if runEnd == runStart || e.entry.DataOffset != runEnd {
+				if err := flush(); err != nil {
+					return 0, err
+				}
+				runStart, runEnd = e.entry.DataOffset, e.entry.DataOffset
+			}
+			runEnd += e.entry.DataSize
+			e.entry.DataOffset = total
+			total += e.entry.DataSize
+			copied++
+			continue
+		}
+
+		if err := flush(); err != nil {
+			return 0, err
+		}
+		entry, err := w.writeChange(ctx, data, ws, e.change, total)
+		if err != nil {
+			return 0, err
+		}
+		if entry.DataSize > ^uint64(0)-total {
+			return 0, ErrSizeOverflow
+		}
+		e.entry = entry
+		total += entry.DataSize
+		written++
+		w.reportProgress(StageCompressing, entry.Path, total, 0, written, 0)
+	}
+	if err := flush(); err != nil {
+		return 0, err
+	}
+
+	w.log().Debug("archive data updated", "copied", copied, "written", written, "data_size", total)
+	return total, nil
+}
+
+// resealEntry writes the encrypted base entry to data at offset. Its stored
+// bytes are bound to their old offset, so they are decrypted and sealed
+// again with a fresh nonce; the content is not recompressed.
+func (w *writer) resealEntry(src io.ReaderAt, data io.Writer, entry *Entry, offset uint64) error {
+	sealed := make([]byte, entry.DataSize)
+	section := io.NewSectionReader(src, int64(entry.DataOffset), int64(entry.DataSize)) //nolint:gosec // offsets were validated by New
+	if _, err := io.ReadFull(section, sealed); err != nil {
+		return fmt.Errorf("copy base data: %w", err)
+	}
+	plain, err := file.Decrypt(w.aead, entry, sealed)
+	if err != nil {
+		return fmt.Errorf("update: %w", err)
+	}
+	entry.IndexPath = ""
+	entry.DataOffset = offset
+	return w.sealEntry(data, plain, entry)
+}
+
+// writeChange writes the content of a stored FileChange to data at offset
+// and returns its entry.
+func (w *writer) writeChange(ctx context.Context, data io.Writer, ws *workspace, c *FileChange, offset uint64) (Entry, error) {
+	dest := data
+	if w.aead != nil {
+		w.plain.Reset()
+		dest = &w.plain
+	}
+
+	entry, err := w.writeContent(ctx, bytes.NewReader(c.Content), dest, ws, c.Path, changeInfo{c: c, now: w.now})
+	if err != nil {
+		return Entry{}, err
+	}
+	w.stamp(&entry)
+	entry.DataOffset = offset
+	if w.aead != nil {
+		if err := w.sealEntry(data, w.plain.Bytes(), &entry); err != nil {
+			return Entry{}, err
+		}
+	}
+	return entry, nil
+}
+
+// changeInfo presents a stored FileChange as the fs.FileInfo that supplies
+// its entry metadata and is passed to SkipCompressionFunc predicates.
+type changeInfo struct {
+	c   *FileChange
+	now time.Time
+}
+
+func (i changeInfo) Name() string {
+	if slash := strings.LastIndexByte(i.c.Path, '/'); slash >= 0 {
+		return i.c.Path[slash+1:]
+	}
+	return i.c.Path
+}
+
+func (i changeInfo) Size() int64 { return int64(len(i.c.Content)) }
+
+func (i changeInfo) Mode() fs.FileMode {
+	if perm := i.c.Mode.Perm(); perm != 0 {
+		return perm
+	}
+	return 0o644
+}
+
+func (i changeInfo) ModTime() time.Time {
+	if i.c.ModTime.IsZero() {
+		return i.now
+	}
+	return i.c.ModTime
+}
+
+func (i changeInfo) IsDir() bool { return false }
+
+func (i changeInfo) Sys() any { return nil }
@@ -0,0 +1,208 @@
+package blob
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"io/fs"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/meigma/blob/core/testutil"
+)
+
+func TestUpdate(t *testing.T) {
+	t.Parallel()
+
+	files := map[string][]byte{
+		"a.txt":          bytes.Repeat([]byte("alpha "), 200),
+		"config.yaml":    []byte("version: 1\n"),
+		"dir/b.txt":      bytes.Repeat([]byte("bravo "), 200),
+		"dir/c.txt":      []byte("charlie"),
+		"dir/sub/d.txt":  bytes.Repeat([]byte("delta "), 200),
+		"zz/removed.txt": []byte("gone soon"),
+	}
+	for _, compression := range []Compression{CompressionNone, CompressionZstd} {
+		t.Run(compression.String(), func(t *testing.T) {
+			t.Parallel()
+
+			base := createTestArchive(t, files, compression)
+			modTime := time.Unix(1700000000, 0)
+			changes := []FileChange{
+				{Path: "config.yaml", Content: []byte("version: 2\n")},
+				{Path: "dir/new.txt", Content: bytes.Repeat([]byte("new "), 300), Mode: 0o600, ModTime: modTime},
+				{Path: "zz/removed.txt", Remove: true},
+			}
+
+			var indexBuf, dataBuf bytes.Buffer
+			err := Update(context.Background(), base, &indexBuf, &dataBuf, changes, CreateWithCompression(compression))
+			require.NoError(t, err)
+
+			updated, err := New(indexBuf.Bytes(), testutil.NewMockByteSource(dataBuf.Bytes()))
+			require.NoError(t, err)
+			require.NoError(t, updated.Verify(context.Background()))
+			assert.Equal(t, base.Len(), updated.Len())
+
+			// Unchanged entries keep their hashes, metadata, and stored bytes.
+			for _, path := range []string{"a.txt", "dir/b.txt", "dir/c.txt", "dir/sub/d.txt"} {
+				before, ok := base.Entry(path)
+				require.True(t, ok, path)
+				after, ok := updated.Entry(path)
+				require.True(t, ok, path)
+
+				assert.Equal(t, before.HashBytes(), after.HashBytes(), path)
+				assert.Equal(t, before.DataSize(), after.DataSize(), path)
+				assert.Equal(t, before.OriginalSize(), after.OriginalSize(), path)
+				assert.Equal(t, before.Compression(), after.Compression(), path)
+				assert.Equal(t, before.Mode(), after.Mode(), path)
+				assert.True(t, before.ModTime().Equal(after.ModTime()), path)
+				assert.Equal(t, storedBytes(t, base, path), storedBytes(t, updated, path), path)
+
+				got, err := updated.ReadFile(path)
+				require.NoError(t, err, path)
+				assert.Equal(t, files[path], got, path)
+			}
+
+			got, err := updated.ReadFile("config.yaml")
+			require.NoError(t, err)
+			assert.Equal(t, "version: 2\n", string(got))
+
+			added, ok := updated.Entry("dir/new.txt")
+			require.True(t, ok)
+			assert.Equal(t, fs.FileMode(0o600), added.Mode())
+			assert.True(t, modTime.Equal(added.ModTime()))
+			got, err = updated.ReadFile("dir/new.txt")
+			require.NoError(t, err)
+			assert.Equal(t, bytes.Repeat([]byte("new "), 300), got)
+
+			_, ok = updated.Entry("zz/removed.txt")
+			assert.False(t, ok, "removed file should not be in the new index")
+			assert.False(t, updated.Exists("zz"), "directory of removed file should be gone")
+
+			// Entries are laid out in path order without gaps.
+			var offset uint64
+			var prev string
+			for view := range updated.Entries() {
+				assert.Greater(t, view.Path(), prev)
+				assert.Equal(t, offset, view.DataOffset(), view.Path())
+				offset += view.DataSize()
+				prev = view.Path()
+			}
+			assert.Equal(t, uint64(dataBuf.Len()), offset)
+		})
+	}
+}
+
+// storedBytes returns the bytes an entry occupies in its archive's data.
+func storedBytes(t *testing.T, b *Blob, path string) []byte {
+	t.Helper()
+	view, ok := b.Entry(path)
+	require.True(t, ok, path)
+	section := io.NewSectionReader(b.reader.Source(), int64(view.DataOffset()), int64(view.DataSize())) //nolint:gosec // test offsets are small
+	buf, err := io.ReadAll(section)
+	require.NoError(t, err)
+	return buf
+}
+
+func TestUpdate_Encrypted(t *testing.T) {
+	t.Parallel()
+
+	key := bytes.Repeat([]byte{0x42}, 32)
+	indexData, data := buildEncryptedArchive(t, key, CompressionZstd)
+	base, err := New(indexData, testutil.NewMockByteSource(data), WithDecryptionKey(key))
+	require.NoError(t, err)
+
+	changes := []FileChange{{Path: "added.txt", Content: []byte("another secret")}}
+	var indexBuf, dataBuf bytes.Buffer
+	err = Update(context.Background(), base, &indexBuf, &dataBuf, changes)
+	require.Error(t, err, "updating an encrypted archive needs the key")
+
+	err = Update(context.Background(), base, &indexBuf, &dataBuf, changes,
+		CreateWithCompression(CompressionZstd),
+		CreateWithEncryption(key, EncryptionAESGCM),
+	)
+	require.NoError(t, err)
+	assert.NotContains(t, dataBuf.String(), "another secret")
+
+	updated, err := New(indexBuf.Bytes(), testutil.NewMockByteSource(dataBuf.Bytes()), WithDecryptionKey(key))
+	require.NoError(t, err)
+	require.NoError(t, updated.Verify(context.Background()))
+	for name, want := range encryptionFiles {
+		got, err := updated.ReadFile(name)
+		require.NoError(t, err, name)
+		assert.Equal(t, want, got, name)
+	}
+	got, err := updated.ReadFile("added.txt")
+	require.NoError(t, err)
+	assert.Equal(t, "another secret", string(got))
+}
+
+func TestUpdate_InvalidChanges(t *testing.T) {
+	t.Parallel()
+
+	base := createTestArchive(t, map[string][]byte{
+		"a.txt":     []byte("a"),
+		"dir/b.txt": []byte("b"),
+	}, CompressionNone)
+
+	tests := []struct {
+		name    string
+		changes []FileChange
+		wantErr error
+	}{
+		{
+			name:    "remove missing",
+			changes: []FileChange{{Path: "missing.txt", Remove: true}},
+			wantErr: fs.ErrNotExist,
+		},
+		{
+			name:    "invalid path",
+			changes: []FileChange{{Path: "../escape.txt", Content: []byte("x")}},
+			wantErr: fs.ErrInvalid,
+		},
+		{
+			name: "duplicate path",
+			changes: []FileChange{
+				{Path: "a.txt", Content: []byte("1")},
+				{Path: "a.txt", Remove: true},
+			},
+		},
+		{
+			name:    "file over directory",
+			changes: []FileChange{{Path: "dir", Content: []byte("x")}},
+		},
+		{
+			name:    "file under file",
+			changes: []FileChange{{Path: "a.txt/child", Content: []byte("x")}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			var indexBuf, dataBuf bytes.Buffer
+			err := Update(context.Background(), base, &indexBuf, &dataBuf, tt.changes)
+			require.Error(t, err)
+			if tt.wantErr != nil {
+				require.ErrorIs(t, err, tt.wantErr)
+			}
+			assert.Zero(t, dataBuf.Len(), "nothing should be written for invalid changes")
+		})
+	}
+}
+
+func TestUpdate_MaxFiles(t *testing.T) {
+	t.Parallel()
+
+	base := createTestArchive(t, map[string][]byte{"a.txt": []byte("a"), "b.txt": []byte("b")}, CompressionNone)
+
+	var indexBuf, dataBuf bytes.Buffer
+	err := Update(context.Background(), base, &indexBuf, &dataBuf,
+		[]FileChange{{Path: "c.txt", Content: []byte("c")}},
+		CreateWithMaxFiles(2),
+	)
+	require.ErrorIs(t, err, ErrTooManyFiles)
+}
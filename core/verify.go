@@ -0,0 +1,149 @@
+package blob
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"sync"
+	"sync/atomic"
+
+	"github.com/meigma/blob/core/internal/blobtype"
+	"github.com/meigma/blob/core/internal/file"
+)
+
+// VerifyOption configures Verify.
+type VerifyOption func(*verifyConfig)
+
+// verifyConfig holds configuration for Verify.
+type verifyConfig struct {
+	concurrency int
+	progress    ProgressFunc
+}
+
+// VerifyWithConcurrency sets the number of entries verified in parallel.
+// Values <= 0 verify entries serially (the default).
+func VerifyWithConcurrency(n int) VerifyOption {
+	return func(c *verifyConfig) {
+		c.concurrency = n
+	}
+}
+
+// VerifyWithProgress sets a callback that receives a StageVerifying event
+// after each entry is verified.
+// The callback may be invoked concurrently and must be safe for concurrent use.
+func VerifyWithProgress(fn ProgressFunc) VerifyOption {
+	return func(c *verifyConfig) {
+		c.progress = fn
+	}
+}
+
+// Verify reads every entry from the data source and checks it against the
+// hash recorded in the index.
+//
+// Entries are read through the ByteSource, bypassing any content cache, so
+// Verify detects corruption in the underlying data rather than trusting
+// earlier reads. Explicit directory entries are skipped. When the index
+// records a data blob hash (see DataHash), the whole data blob is hashed and
+// compared as well. For a Subset view, only entries under the subset root
+// are verified and the data blob hash is not checked.
+//
+// Verify checks all entries rather than stopping at the first failure.
+// Each failed entry is reported as an *fs.PathError with Op "verify", and
+// failures are combined with errors.Join in index order. Content that does
+// not match its hash wraps ErrHashMismatch. If ctx is canceled, Verify
+// returns the context error.
+func (b *Blob) Verify(ctx context.Context, opts ...VerifyOption) error {
+	cfg := verifyConfig{}
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+
+	prefix := b.rootPrefix()
+	var entries []blobtype.Entry //nolint:prealloc // size unknown until iteration
+	for view := range b.idx.EntriesWithPrefixView(prefix) {
+		if view.Mode().IsDir() {
+			continue
+		}
+		entries = append(entries, blobtype.EntryFromViewWithPath(view, string(view.PathBytes()[len(prefix):])))
+	}
+
+	errs := make([]error, len(entries))
+	workers := min(max(cfg.concurrency, 1), max(len(entries), 1))
+	var (
+		next atomic.Int64
+		done atomic.Int64
+		wg   sync.WaitGroup
+	)
+	for range workers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			buf := make([]byte, 32*1024)
+			for {
+				i := int(next.Add(1) - 1)
+				if i >= len(entries) || ctx.Err() != nil {
+					return
+				}
+				entry := &entries[i]
+				if err := b.verifyEntry(ctx, entry, buf); err != nil {
+					errs[i] = &fs.PathError{Op: "verify", Path: entry.Path, Err: err}
+				}
+				if cfg.progress != nil {
+					cfg.progress(ProgressEvent{
+						Stage:      StageVerifying,
+						Path:       entry.Path,
+						BytesDone:  entry.OriginalSize,
+						BytesTotal: entry.OriginalSize,
+						FilesDone:  int(done.Add(1)),
+						FilesTotal: len(entries),
+					})
+				}
+			}
+		}()
+	}
+	wg.Wait()
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	if b.root == "" {
+		if err := b.verifyDataHash(ctx); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// verifyEntry streams entry from the data source, checking its hash.
+func (b *Blob) verifyEntry(ctx context.Context, entry *blobtype.Entry, buf []byte) error {
+	f := b.reader.OpenStream(entry)
+	if _, err := file.CopyWithContext(ctx, io.Discard, f, buf); err != nil {
+		_ = f.Close() //nolint:errcheck // the copy error takes precedence
+		return err
+	}
+	return f.Close()
+}
+
+// verifyDataHash hashes the whole data blob and compares it to the hash
+// recorded in the index. It returns nil when the index records no hash.
+func (b *Blob) verifyDataHash(ctx context.Context) error {
+	want, ok := b.idx.DataHash()
+	if !ok {
+		return nil
+	}
+	if size, ok := b.idx.DataSize(); ok && uint64(b.Size()) != size { //nolint:gosec // source sizes are non-negative
+		return fmt.Errorf("verify data blob: size %d does not match index size %d", b.Size(), size)
+	}
+	h := sha256.New()
+	if _, err := file.CopyWithContext(ctx, h, b.Stream(), make([]byte, 32*1024)); err != nil {
+		return fmt.Errorf("verify data blob: %w", err)
+	}
+	if !bytes.Equal(h.Sum(nil), want) {
+		return fmt.Errorf("verify data blob: %w", ErrHashMismatch)
+	}
+	return nil
+}
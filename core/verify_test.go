@@ -0,0 +1,128 @@
+package blob
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io/fs"
+	"sync/atomic"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/meigma/blob/core/testutil"
+)
+
+// buildVerifyArchive creates an archive from files and returns its index and data.
+func buildVerifyArchive(t *testing.T, files map[string][]byte, compression Compression) (indexData, data []byte) {
+	t.Helper()
+
+	dir := t.TempDir()
+	createTestFilesBytes(t, dir, files)
+
+	var indexBuf, dataBuf bytes.Buffer
+	err := Create(context.Background(), dir, &indexBuf, &dataBuf, CreateWithCompression(compression))
+	require.NoError(t, err)
+	return indexBuf.Bytes(), dataBuf.Bytes()
+}
+
+var verifyFiles = map[string][]byte{
+	"a.txt":     bytes.Repeat([]byte("alpha "), 100),
+	"b.txt":     bytes.Repeat([]byte("bravo "), 100),
+	"dir/c.txt": bytes.Repeat([]byte("charlie "), 100),
+	"dir/d.txt": bytes.Repeat([]byte("delta "), 100),
+}
+
+func TestBlobVerify(t *testing.T) {
+	t.Parallel()
+
+	for _, compression := range []Compression{CompressionNone, CompressionZstd} {
+		t.Run(compression.String(), func(t *testing.T) {
+			t.Parallel()
+
+			indexData, data := buildVerifyArchive(t, verifyFiles, compression)
+			b, err := New(indexData, testutil.NewMockByteSource(data))
+			require.NoError(t, err)
+
+			var events atomic.Int64
+			err = b.Verify(context.Background(),
+				VerifyWithConcurrency(3),
+				VerifyWithProgress(func(event ProgressEvent) {
+					assert.Equal(t, StageVerifying, event.Stage)
+					assert.Equal(t, len(verifyFiles), event.FilesTotal)
+					events.Add(1)
+				}),
+			)
+			require.NoError(t, err)
+			assert.Equal(t, int64(len(verifyFiles)), events.Load())
+		})
+	}
+}
+
+func TestBlobVerify_CorruptEntry(t *testing.T) {
+	t.Parallel()
+
+	indexData, data := buildVerifyArchive(t, verifyFiles, CompressionNone)
+	clean, err := New(indexData, testutil.NewMockByteSource(data))
+	require.NoError(t, err)
+
+	view, ok := clean.Entry("dir/c.txt")
+	require.True(t, ok)
+	corrupted := bytes.Clone(data)
+	corrupted[view.DataOffset()+view.DataSize()/2] ^= 0xff
+
+	b, err := New(indexData, testutil.NewMockByteSource(corrupted))
+	require.NoError(t, err)
+
+	err = b.Verify(context.Background(), VerifyWithConcurrency(2))
+	require.ErrorIs(t, err, ErrHashMismatch)
+
+	var joined interface{ Unwrap() []error }
+	require.True(t, errors.As(err, &joined))
+	var paths []string
+	for _, e := range joined.Unwrap() {
+		var pathErr *fs.PathError
+		if errors.As(e, &pathErr) {
+			assert.Equal(t, "verify", pathErr.Op)
+			assert.ErrorIs(t, pathErr.Err, ErrHashMismatch)
+			paths = append(paths, pathErr.Path)
+		}
+	}
+	assert.Equal(t, []string{"dir/c.txt"}, paths)
+	assert.ErrorContains(t, err, "verify data blob")
+}
+
+func TestBlobVerify_Subset(t *testing.T) {
+	t.Parallel()
+
+	indexData, data := buildVerifyArchive(t, verifyFiles, CompressionNone)
+	clean, err := New(indexData, testutil.NewMockByteSource(data))
+	require.NoError(t, err)
+
+	// Corrupt an entry outside the subset.
+	view, ok := clean.Entry("a.txt")
+	require.True(t, ok)
+	corrupted := bytes.Clone(data)
+	corrupted[view.DataOffset()] ^= 0xff
+
+	b, err := New(indexData, testutil.NewMockByteSource(corrupted))
+	require.NoError(t, err)
+	sub, err := b.Subset("dir")
+	require.NoError(t, err)
+
+	require.NoError(t, sub.Verify(context.Background()))
+	require.ErrorIs(t, b.Verify(context.Background()), ErrHashMismatch)
+}
+
+func TestBlobVerify_Canceled(t *testing.T) {
+	t.Parallel()
+
+	indexData, data := buildVerifyArchive(t, verifyFiles, CompressionNone)
+	b, err := New(indexData, testutil.NewMockByteSource(data))
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	require.ErrorIs(t, b.Verify(ctx), context.Canceled)
+}
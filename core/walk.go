@@ -0,0 +1,109 @@
+package blob
+
+import (
+	"errors"
+	"io/fs"
+	"path"
+	"slices"
+	"strings"
+
+	"github.com/meigma/blob/core/internal/blobtype"
+	"github.com/meigma/blob/core/internal/file"
+)
+
+// WalkDir walks the file tree rooted at root, calling fn for each file or
+// directory in the tree, including root.
+//
+// It visits entries in the same order as [fs.WalkDir] and honors
+// [fs.SkipDir] and [fs.SkipAll] the same way, but lists each directory
+// straight from the sorted index: the directory's entries are found by
+// binary search and subdirectory contents are skipped rather than scanned.
+// Every entry is therefore read a bounded number of times, instead of once
+// per ancestor directory as with ReadDir, which keeps walks of deep trees
+// fast.
+func (b *Blob) WalkDir(root string, fn fs.WalkDirFunc) error {
+	info, err := b.Stat(root)
+	if err != nil {
+		err = fn(root, nil, err)
+	} else {
+		err = b.walkDir(root, fs.FileInfoToDirEntry(info), fn)
+	}
+	if errors.Is(err, fs.SkipDir) || errors.Is(err, fs.SkipAll) {
+		return nil
+	}
+	return err
+}
+
+// walkDir mirrors the recursion of fs.WalkDir for a single node.
+func (b *Blob) walkDir(name string, d fs.DirEntry, fn fs.WalkDirFunc) error {
+	if err := fn(name, d, nil); err != nil || !d.IsDir() {
+		if errors.Is(err, fs.SkipDir) && d.IsDir() {
+			err = nil
+		}
+		return err
+	}
+
+	children := b.walkChildren(b.resolve(name))
+	for _, child := range children {
+		if err := b.walkDir(path.Join(name, child.Name()), child, fn); err != nil {
+			if errors.Is(err, fs.SkipDir) {
+				break
+			}
+			return err
+		}
+	}
+	return nil
+}
+
+// walkChildren lists the immediate children of the archive directory dir,
+// sorted by name. The entries of each subdirectory form a contiguous run in
+// the index, so the scan jumps past a run once it has seen its first entry.
+func (b *Blob) walkChildren(dir string) []fs.DirEntry {
+	prefix := file.DirPrefix(dir)
+	var children []fs.DirEntry
+	var explicit map[string]struct{}
+
+	n := b.idx.Len()
+	for i := b.idx.Search(prefix); i < n; {
+		view, ok := b.idx.ViewAt(i)
+		if !ok {
+			break
+		}
+		full := string(view.PathBytes())
+		if !strings.HasPrefix(full, prefix) {
+			break
+		}
+		childName, isSubDir := file.Child(full, prefix)
+		if isSubDir {
+			// Skip the rest of the subdirectory: "0" sorts just after "/".
+			i = b.idx.Search(prefix + childName + "0")
+			// An explicit directory entry sorts before its contents and
+			// wins over the synthesized one.
+			if _, ok := explicit[childName]; !ok {
+				children = append(children, file.NewDirEntry(file.NewDirInfo(childName), nil))
+			}
+			continue
+		}
+		i++
+
+		entry := blobtype.EntryFromViewWithPath(view, full)
+		info, err := file.NewInfo(&entry, childName)
+		if err != nil {
+			info = &file.Info{}
+		}
+		if view.Mode().IsDir() {
+			if explicit == nil {
+				explicit = make(map[string]struct{})
+			}
+			explicit[childName] = struct{}{}
+		}
+		children = append(children, file.NewDirEntry(info, err))
+	}
+
+	// Index order places "a.txt" before directory "a"; fs.WalkDir visits
+	// children sorted by name.
+	slices.SortFunc(children, func(x, y fs.DirEntry) int {
+		return strings.Compare(x.Name(), y.Name())
+	})
+	return children
+}
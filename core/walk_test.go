@@ -0,0 +1,211 @@
+package blob
+
+import (
+	"crypto/sha256"
+	"fmt"
+	"io/fs"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/meigma/blob/core/testutil"
+)
+
+// walkRecord captures one WalkDir callback for comparison.
+type walkRecord struct {
+	path  string
+	name  string
+	isDir bool
+	err   string
+}
+
+func recordWalk(walk func(fs.WalkDirFunc) error, decide func(path string, d fs.DirEntry) error) ([]walkRecord, error) {
+	var records []walkRecord
+	err := walk(func(path string, d fs.DirEntry, err error) error {
+		rec := walkRecord{path: path}
+		if d != nil {
+			rec.name = d.Name()
+			rec.isDir = d.IsDir()
+		}
+		if err != nil {
+			rec.err = err.Error()
+		}
+		records = append(records, rec)
+		if err != nil {
+			return err
+		}
+		if decide != nil {
+			return decide(path, d)
+		}
+		return nil
+	})
+	return records, err
+}
+
+func newWalkTestBlob(t *testing.T) *Blob {
+	t.Helper()
+
+	content := []byte("x")
+	hash := sha256.Sum256(content)
+	fileEntry := func(path string) testutil.TestEntry {
+		return testutil.TestEntry{
+			Path:         path,
+			DataSize:     uint64(len(content)),
+			OriginalSize: uint64(len(content)),
+			Hash:         hash[:],
+			Mode:         0o644,
+		}
+	}
+	entries := []testutil.TestEntry{
+		fileEntry("README.md"),
+		{Path: "a", Mode: fs.ModeDir | 0o700},
+		{Path: "a-b", Mode: fs.ModeDir | 0o755},
+		fileEntry("a-b/c"),
+		fileEntry("a.txt"),
+		fileEntry("a/x"),
+		fileEntry("a/y/z/deep.txt"),
+		fileEntry("a/y/z.txt"),
+		fileEntry("a b/z"),
+		{Path: "explicit", Mode: fs.ModeDir | 0o755},
+		fileEntry("explicit-notes.txt"),
+		fileEntry("explicit/inside.txt"),
+		{Path: "empty", Mode: fs.ModeDir | 0o755},
+		fileEntry("z/1.txt"),
+		fileEntry("z/2.txt"),
+		fileEntry("z/3/4.txt"),
+	}
+	b, err := New(testutil.BuildTestIndex(t, entries), testutil.NewMockByteSource(content))
+	require.NoError(t, err)
+	return b
+}
+
+func TestBlob_WalkDir(t *testing.T) {
+	t.Parallel()
+
+	b := newWalkTestBlob(t)
+
+	tests := []struct {
+		name   string
+		root   string
+		decide func(path string, d fs.DirEntry) error
+	}{
+		{name: "full tree", root: "."},
+		{name: "subdirectory", root: "a"},
+		{name: "file root", root: "a.txt"},
+		{name: "missing root", root: "missing"},
+		{
+			name: "skip directory",
+			root: ".",
+			decide: func(path string, _ fs.DirEntry) error {
+				if path == "a/y" {
+					return fs.SkipDir
+				}
+				return nil
+			},
+		},
+		{
+			name: "skip rest of directory from file",
+			root: ".",
+			decide: func(path string, _ fs.DirEntry) error {
+				if path == "z/1.txt" {
+					return fs.SkipDir
+				}
+				return nil
+			},
+		},
+		{
+			name: "skip all",
+			root: ".",
+			decide: func(path string, _ fs.DirEntry) error {
+				if path == "a/y/z" {
+					return fs.SkipAll
+				}
+				return nil
+			},
+		},
+		{
+			name: "skip root",
+			root: ".",
+			decide: func(path string, _ fs.DirEntry) error {
+				if path == "." {
+					return fs.SkipDir
+				}
+				return nil
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			want, wantErr := recordWalk(func(fn fs.WalkDirFunc) error {
+				return fs.WalkDir(b, tt.root, fn)
+			}, tt.decide)
+			got, gotErr := recordWalk(func(fn fs.WalkDirFunc) error {
+				return b.WalkDir(tt.root, fn)
+			}, tt.decide)
+
+			assert.Equal(t, want, got)
+			if wantErr == nil {
+				assert.NoError(t, gotErr)
+			} else {
+				assert.EqualError(t, gotErr, wantErr.Error())
+			}
+		})
+	}
+}
+
+func TestBlob_WalkDir_Subset(t *testing.T) {
+	t.Parallel()
+
+	b := newWalkTestBlob(t)
+	sub, err := b.Subset("a")
+	require.NoError(t, err)
+
+	want, err := recordWalk(func(fn fs.WalkDirFunc) error {
+		return fs.WalkDir(sub, ".", fn)
+	}, nil)
+	require.NoError(t, err)
+	got, err := recordWalk(func(fn fs.WalkDirFunc) error {
+		return sub.WalkDir(".", fn)
+	}, nil)
+	require.NoError(t, err)
+
+	assert.Equal(t, want, got)
+	assert.Equal(t, walkRecord{path: "y/z/deep.txt", name: "deep.txt"}, got[len(got)-2])
+}
+
+func TestBlob_WalkDir_Deep(t *testing.T) {
+	t.Parallel()
+
+	content := []byte("x")
+	hash := sha256.Sum256(content)
+	var entries []testutil.TestEntry
+	for i := range 3 {
+		for j := range 3 {
+			for k := range 3 {
+				entries = append(entries, testutil.TestEntry{
+					Path:         fmt.Sprintf("d%d/d%d/d%d/f.txt", i, j, k),
+					DataSize:     1,
+					OriginalSize: 1,
+					Hash:         hash[:],
+					Mode:         0o644,
+				})
+			}
+		}
+	}
+	b, err := New(testutil.BuildTestIndex(t, entries), testutil.NewMockByteSource(content))
+	require.NoError(t, err)
+
+	want, err := recordWalk(func(fn fs.WalkDirFunc) error {
+		return fs.WalkDir(b, ".", fn)
+	}, nil)
+	require.NoError(t, err)
+	got, err := recordWalk(func(fn fs.WalkDirFunc) error {
+		return b.WalkDir(".", fn)
+	}, nil)
+	require.NoError(t, err)
+	assert.Equal(t, want, got)
+	assert.Len(t, got, 1+3+9+27+27)
+}
@@ -0,0 +1,167 @@
+package blob
+
+import (
+	"archive/zip"
+	"fmt"
+	"io"
+	"io/fs"
+
+	"github.com/meigma/blob/core/internal/batch"
+)
+
+// ZipOption configures WriteZip.
+type ZipOption func(*zipConfig)
+
+// zipConfig holds configuration for WriteZip.
+type zipConfig struct {
+	method uint16
+	forced bool // method applies to every file
+}
+
+// WriteZipWithMethod writes every file with the given zip method, such as
+// zip.Store or zip.Deflate, instead of choosing one per entry. The method
+// must have a compressor registered with archive/zip.
+func WriteZipWithMethod(method uint16) ZipOption {
+	return func(c *zipConfig) {
+		c.method = method
+		c.forced = true
+	}
+}
+
+// WriteZip writes the entries under the directory prefix to w as a zip
+// stream. An empty prefix or "." writes every entry; paths in the zip keep
+// the prefix.
+//
+// Entries are written in index order with their modes and modification
+// times. Directory entries use a trailing slash, and symlink entries (see
+// CreateWithSymlinks) are written as zip symlinks holding their target.
+// Files stored zstd-compressed in the archive use the zip Store method, so
+// their content is not compressed a second time; uncompressed files use
+// Deflate. File content is verified against its hash as it is written; a
+// mismatch aborts the stream with ErrHashMismatch. For a Subset view, paths
+// are relative to the subset root.
+//
+// File content is fetched with the same batched range reads as CopyDir.
+// Archives whose data is not laid out in index order fall back to reading
+// one file at a time.
+//
+// WriteZip does not close w. The central directory is only written on
+// success.
+func (b *Blob) WriteZip(w io.Writer, prefix string, opts ...ZipOption) error {
+	cfg := zipConfig{}
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+	if prefix != "" && prefix != "." && !fs.ValidPath(prefix) {
+		return &fs.PathError{Op: "zip", Path: prefix, Err: fs.ErrInvalid}
+	}
+
+	entries, _ := b.collectPrefixEntries(prefix, &copyFilter{})
+	s := &zipStream{b: b, cfg: cfg, entries: entries}
+	if !dataInIndexOrder(entries) {
+		zw := zip.NewWriter(w)
+		for _, entry := range entries {
+			if err := s.writeEntry(zw, entry); err != nil {
+				return err
+			}
+		}
+		return zw.Close()
+	}
+
+	sink := batch.NewZipSink(w, batch.WithZipHeaderFunc(s.beforeFile))
+	if _, err := b.newBatchProcessor(batch.WithWorkers(-1)).Process(entries, sink); err != nil {
+		return err
+	}
+	if err := sink.Append(s.writeRest); err != nil {
+		return err
+	}
+	return sink.Close()
+}
+
+// zipStream writes the entries of WriteZip in index order. With the batch
+// pipeline, file entries are written by the ZipSink and the others are
+// written from beforeFile as the stream reaches them.
+type zipStream struct {
+	b       *Blob
+	cfg     zipConfig
+	entries []*batch.Entry // index order
+	next    int            // first entry not yet written
+}
+
+// beforeFile writes the entries that precede the file entry in index order
+// and chooses its method.
+func (s *zipStream) beforeFile(zw *zip.Writer, entry *batch.Entry, hdr *zip.FileHeader) error {
+	for ; s.next < len(s.entries) && s.entries[s.next] != entry; s.next++ {
+		if err := s.writeEntry(zw, s.entries[s.next]); err != nil {
+			return err
+		}
+	}
+	if s.next == len(s.entries) {
+		return fmt.Errorf("zip %s: entry out of order", entry.Path)
+	}
+	s.next++
+	hdr.Method = s.method(entry)
+	return nil
+}
+
+// writeRest writes the entries after the last file entry.
+func (s *zipStream) writeRest(zw *zip.Writer) error {
+	for ; s.next < len(s.entries); s.next++ {
+		if err := s.writeEntry(zw, s.entries[s.next]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// method returns the zip method for a file entry.
+func (s *zipStream) method(entry *batch.Entry) uint16 {
+	switch {
+	case s.cfg.forced:
+		return s.cfg.method
+	case entry.Compression == CompressionZstd:
+		return zip.Store
+	default:
+		return zip.Deflate
+	}
+}
+
+// writeEntry writes one entry, reading file content through Open.
+func (s *zipStream) writeEntry(zw *zip.Writer, entry *batch.Entry) error {
+	name := entry.Path
+	mode := entry.Mode
+
+	hdr := &zip.FileHeader{Name: name, Modified: entry.ModTime}
+	hdr.SetMode(mode)
+	var content []byte
+	switch {
+	case mode.IsDir():
+		hdr.Name += "/"
+		hdr.Method = zip.Store
+	case mode&fs.ModeSymlink != 0:
+		target, err := s.b.ReadFile(name)
+		if err != nil {
+			return fmt.Errorf("zip %s: %w", name, err)
+		}
+		hdr.Method = zip.Store
+		content = target
+	default:
+		hdr.Method = s.method(entry)
+	}
+
+	fw, err := zw.CreateHeader(hdr)
+	if err != nil {
+		return fmt.Errorf("zip %s: %w", name, err)
+	}
+	switch {
+	case mode.IsDir():
+		return nil
+	case content != nil:
+		if _, err := fw.Write(content); err != nil {
+			return fmt.Errorf("zip %s: %w", name, err)
+		}
+		return nil
+	default:
+		return s.b.copyArchiveContent(fw, "zip", name)
+	}
+}
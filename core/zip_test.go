@@ -0,0 +1,161 @@
+package blob
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/meigma/blob/core/testutil"
+)
+
+// readZip returns the names, file contents, and symlink targets of a zip
+// archive, with names in central directory order.
+func readZip(t *testing.T, data []byte) (zr *zip.Reader, names []string, files map[string][]byte, links map[string]string) {
+	t.Helper()
+
+	zr, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	require.NoError(t, err)
+	files = make(map[string][]byte)
+	links = make(map[string]string)
+	for _, f := range zr.File {
+		names = append(names, f.Name)
+		if f.Mode().IsDir() {
+			continue
+		}
+		rc, err := f.Open()
+		require.NoError(t, err, f.Name)
+		content, err := io.ReadAll(rc)
+		require.NoError(t, err, f.Name)
+		require.NoError(t, rc.Close())
+		if f.Mode()&fs.ModeSymlink != 0 {
+			links[f.Name] = string(content)
+		} else {
+			files[f.Name] = content
+		}
+	}
+	return zr, names, files, links
+}
+
+func TestBlobWriteZip(t *testing.T) {
+	t.Parallel()
+
+	files := map[string][]byte{
+		"a.txt":         []byte("alpha"),
+		"bin/run.sh":    []byte("#!/bin/sh\necho run\n"),
+		"dir/b.bin":     bytes.Repeat([]byte{0, 1, 2, 3}, 20000),
+		"dir/sub/c.txt": bytes.Repeat([]byte("charlie "), 64),
+		"dir/sub/empty": {},
+		"z/last.txt":    []byte("zulu"),
+	}
+	modTime := time.Date(2021, 3, 4, 5, 6, 8, 0, time.UTC)
+	srcDir := t.TempDir()
+	createTestFilesBytes(t, srcDir, files)
+	require.NoError(t, os.Chmod(filepath.Join(srcDir, "bin", "run.sh"), 0o750))
+	for name := range files {
+		require.NoError(t, os.Chtimes(filepath.Join(srcDir, filepath.FromSlash(name)), modTime, modTime))
+	}
+	require.NoError(t, os.Symlink("../a.txt", filepath.Join(srcDir, "dir", "link")))
+
+	var indexBuf, dataBuf bytes.Buffer
+	require.NoError(t, Create(context.Background(), srcDir, &indexBuf, &dataBuf,
+		CreateWithSymlinks(true), CreateWithCompression(CompressionZstd)))
+	b, err := New(indexBuf.Bytes(), testutil.NewMockByteSource(dataBuf.Bytes()))
+	require.NoError(t, err)
+
+	var buf bytes.Buffer
+	require.NoError(t, b.WriteZip(&buf, ""))
+	zr, names, gotFiles, gotLinks := readZip(t, buf.Bytes())
+
+	var want []string
+	for view := range b.Entries() {
+		want = append(want, view.Path())
+	}
+	assert.Equal(t, want, names, "zip entries should be in index order")
+	assert.Equal(t, files, gotFiles)
+	assert.Equal(t, map[string]string{"dir/link": "../a.txt"}, gotLinks)
+
+	for _, f := range zr.File {
+		view, ok := b.Entry(f.Name)
+		require.True(t, ok, f.Name)
+		if view.Mode()&fs.ModeSymlink != 0 {
+			continue
+		}
+		assert.Equal(t, view.Mode(), f.Mode(), f.Name)
+		assert.True(t, f.Modified.Equal(modTime), "%s modified %v", f.Name, f.Modified)
+		wantMethod := zip.Deflate
+		if view.Compression() == CompressionZstd {
+			wantMethod = zip.Store
+		}
+		assert.Equal(t, wantMethod, f.Method, f.Name)
+	}
+
+	t.Run("prefix", func(t *testing.T) {
+		t.Parallel()
+
+		var buf bytes.Buffer
+		require.NoError(t, b.WriteZip(&buf, "dir/sub"))
+		_, names, _, _ := readZip(t, buf.Bytes())
+		assert.Equal(t, []string{"dir/sub/c.txt", "dir/sub/empty"}, names)
+
+		require.ErrorIs(t, b.WriteZip(io.Discard, "../x"), fs.ErrInvalid)
+	})
+
+	t.Run("method", func(t *testing.T) {
+		t.Parallel()
+
+		var buf bytes.Buffer
+		require.NoError(t, b.WriteZip(&buf, "", WriteZipWithMethod(zip.Deflate)))
+		zr, _, gotFiles, _ := readZip(t, buf.Bytes())
+		assert.Equal(t, files, gotFiles)
+		for _, f := range zr.File {
+			if f.Mode().IsRegular() {
+				assert.Equal(t, zip.Deflate, f.Method, f.Name)
+			}
+		}
+	})
+}
+
+func TestBlobWriteZip_DataOutOfIndexOrder(t *testing.T) {
+	t.Parallel()
+
+	// The data blob stores b.txt before a.txt.
+	a, bb := []byte("alpha"), []byte("bravo!")
+	data := append(bytes.Clone(bb), a...)
+	hashA, hashB := sha256.Sum256(a), sha256.Sum256(bb)
+	indexData := testutil.BuildTestIndex(t, []testutil.TestEntry{
+		{Path: "a.txt", DataOffset: uint64(len(bb)), DataSize: uint64(len(a)), OriginalSize: uint64(len(a)), Hash: hashA[:], Mode: 0o644},
+		{Path: "b.txt", DataOffset: 0, DataSize: uint64(len(bb)), OriginalSize: uint64(len(bb)), Hash: hashB[:], Mode: 0o644},
+	})
+	b, err := New(indexData, testutil.NewMockByteSource(data))
+	require.NoError(t, err)
+
+	var buf bytes.Buffer
+	require.NoError(t, b.WriteZip(&buf, ""))
+	_, names, files, _ := readZip(t, buf.Bytes())
+	assert.Equal(t, []string{"a.txt", "b.txt"}, names)
+	assert.Equal(t, map[string][]byte{"a.txt": a, "b.txt": bb}, files)
+}
+
+func TestBlobWriteZip_HashMismatch(t *testing.T) {
+	t.Parallel()
+
+	content := []byte("alpha")
+	wrong := sha256.Sum256([]byte("other"))
+	indexData := testutil.BuildTestIndex(t, []testutil.TestEntry{
+		{Path: "a.txt", DataSize: uint64(len(content)), OriginalSize: uint64(len(content)), Hash: wrong[:], Mode: 0o644},
+	})
+	b, err := New(indexData, testutil.NewMockByteSource(content))
+	require.NoError(t, err)
+
+	require.ErrorIs(t, b.WriteZip(io.Discard, ""), ErrHashMismatch)
+}
@@ -0,0 +1,48 @@
+package blob
+
+import (
+	"context"
+
+	"github.com/meigma/blob/registry"
+)
+
+// DeleteOption configures a Client.Delete operation.
+type DeleteOption func(*deleteConfig)
+
+type deleteConfig struct {
+	referrers bool
+}
+
+// DeleteWithReferrers controls whether signatures, attestations, and other
+// referrers are deleted along with the archive (default: false).
+func DeleteWithReferrers(enabled bool) DeleteOption {
+	return func(cfg *deleteConfig) {
+		cfg.referrers = enabled
+	}
+}
+
+// Delete removes the archive at ref from the registry, for example to
+// clean up old versions.
+//
+// A tag is resolved to its manifest digest and the manifest is deleted by
+// digest, which also removes every other tag pointing to it. Use
+// [DeleteWithReferrers] to delete signatures and attestations too. Cached
+// ref and manifest entries for ref are invalidated on success. Registries
+// that reject deletes return [ErrDeleteUnsupported].
+func (c *Client) Delete(ctx context.Context, ref string, opts ...DeleteOption) error {
+	cfg := deleteConfig{}
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+
+	c.log().Debug("deleting archive", "ref", ref)
+
+	regClient := registry.New(buildRegistryOpts(c)...)
+
+	var deleteOpts []registry.DeleteOption
+	if cfg.referrers {
+		deleteOpts = append(deleteOpts, registry.WithDeleteReferrers(true))
+	}
+
+	return regClient.Delete(ctx, ref, deleteOpts...)
+}
@@ -0,0 +1,11 @@
+package blob
+
+import blobcore "github.com/meigma/blob/core"
+
+// Diff compares the files of two pulled archives by path and content hash,
+// reading only their indexes. Pulling an archive fetches just its index, so
+// the files changed between two tags can be listed without downloading any
+// file data. See core.Diff for the exact semantics.
+func Diff(a, b *Archive) (added, removed, changed []string) {
+	return blobcore.Diff(a.Blob, b.Blob)
+}
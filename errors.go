@@ -13,14 +13,57 @@ var (
 	// ErrDecompression is returned when decompression fails.
 	ErrDecompression = blobcore.ErrDecompression
 
+	// ErrDecryption is returned when encrypted content cannot be decrypted.
+	ErrDecryption = blobcore.ErrDecryption
+
 	// ErrSizeOverflow is returned when a size value overflows.
 	ErrSizeOverflow = blobcore.ErrSizeOverflow
 
-	// ErrSymlink is returned when a symlink is encountered during archive creation.
+	// ErrSymlink is returned when a symlink is encountered where not allowed.
 	ErrSymlink = blobcore.ErrSymlink
 
 	// ErrTooManyFiles is returned when the archive contains more files than allowed.
 	ErrTooManyFiles = blobcore.ErrTooManyFiles
+
+	// ErrFileChanged is returned when a file changes while it is being archived.
+	ErrFileChanged = blobcore.ErrFileChanged
+
+	// ErrCompressedRange is returned when a byte range is requested from a compressed file.
+	ErrCompressedRange = blobcore.ErrCompressedRange
+
+	// ErrPathConflict is returned when path rewriting maps two files to the same archive or destination path.
+	ErrPathConflict = blobcore.ErrPathConflict
+
+	// ErrCaseCollision is returned when two archived paths differ only in case.
+	ErrCaseCollision = blobcore.ErrCaseCollision
+
+	// ErrOverlappingEntries is returned when index entries claim overlapping data bytes.
+	ErrOverlappingEntries = blobcore.ErrOverlappingEntries
+
+	// ErrUnsortedEntries is returned when index paths are not in strictly increasing order.
+	ErrUnsortedEntries = blobcore.ErrUnsortedEntries
+
+	// ErrDataSizeMismatch is returned when the index data size differs from the data blob size.
+	ErrDataSizeMismatch = blobcore.ErrDataSizeMismatch
+
+	// ErrExtractionLimit is returned when an extraction exceeds a file count or total size limit.
+	ErrExtractionLimit = blobcore.ErrExtractionLimit
+
+	// ErrPathLimit is returned when a path exceeds a configured length or depth limit.
+	ErrPathLimit = blobcore.ErrPathLimit
+
+	// ErrUnsupportedIndexVersion is returned when an index format version is newer than allowed.
+	ErrUnsupportedIndexVersion = blobcore.ErrUnsupportedIndexVersion
+
+	// ErrRetryBudgetExhausted is returned once a RetryWithBudget budget is spent.
+	ErrRetryBudgetExhausted = blobcore.ErrRetryBudgetExhausted
+
+	// ErrZstdDictionaryMismatch is returned when a zstd dictionary is not the one an index references.
+	ErrZstdDictionaryMismatch = blobcore.ErrZstdDictionaryMismatch
+
+	// ErrDecoderMemoryLimit is returned when open files hold the decoder
+	// memory a read needs under SetGlobalDecoderMemoryLimit.
+	ErrDecoderMemoryLimit = blobcore.ErrDecoderMemoryLimit
 )
 
 // Errors re-exported from registry.
@@ -48,4 +91,23 @@ var (
 
 	// ErrReferrersUnsupported is returned when referrers are not supported by the OCI client.
 	ErrReferrersUnsupported = registry.ErrReferrersUnsupported
+
+	// ErrUnauthorized is returned when the registry rejects the credentials.
+	ErrUnauthorized = registry.ErrUnauthorized
+
+	// ErrForbidden is returned when the credentials lack access to the repository.
+	ErrForbidden = registry.ErrForbidden
+
+	// ErrListTagsUnsupported is returned when the OCI client cannot list tags.
+	ErrListTagsUnsupported = registry.ErrListTagsUnsupported
+
+	// ErrDeleteUnsupported is returned when the OCI client or registry does not support deletes.
+	ErrDeleteUnsupported = registry.ErrDeleteUnsupported
+
+	// ErrCopyUnsupported is returned when the OCI client cannot copy between repositories.
+	ErrCopyUnsupported = registry.ErrCopyUnsupported
+
+	// ErrPlatformNotFound is returned when an image index has no manifest for the platform
+	// requested with PullWithPlatform.
+	ErrPlatformNotFound = registry.ErrPlatformNotFound
 )
@@ -9,6 +9,15 @@ import (
 // Manifest represents a blob archive manifest from an OCI registry.
 type Manifest = registry.BlobManifest
 
+// VerifyingReader checks streamed content against a descriptor digest.
+type VerifyingReader = registry.VerifyingReader
+
+// DigestMismatchError reports streamed content that does not match its descriptor.
+type DigestMismatchError = registry.DigestMismatchError
+
+// NewVerifyingReader wraps a reader to verify it against a descriptor as it is read.
+var NewVerifyingReader = registry.NewVerifyingReader
+
 // FetchOption configures a Fetch operation.
 type FetchOption func(*fetchConfig)
 
@@ -51,6 +60,31 @@ func (c *Client) Fetch(ctx context.Context, ref string, opts ...FetchOption) (*M
 	return regClient.Fetch(ctx, ref, fetchOpts...)
 }
 
+// FetchData streams the raw data blob of the archive at ref, verifying its
+// digest as bytes are read.
+//
+// This suits mirroring: the data blob can be copied elsewhere while it is
+// verified, without a second pass. The returned reader yields a
+// *DigestMismatchError instead of io.EOF if the content does not match the
+// manifest. The caller must close it.
+func (c *Client) FetchData(ctx context.Context, ref string, opts ...FetchOption) (*VerifyingReader, error) {
+	cfg := fetchConfig{}
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+
+	c.log().Debug("fetching data blob", "ref", ref)
+
+	regClient := registry.New(buildRegistryOpts(c)...)
+
+	var fetchOpts []registry.FetchOption
+	if cfg.skipCache {
+		fetchOpts = append(fetchOpts, registry.WithSkipCache())
+	}
+
+	return regClient.FetchData(ctx, ref, fetchOpts...)
+}
+
 // Tag creates or updates a tag pointing to an existing manifest.
 //
 // The ref specifies the repository and new tag (e.g., "registry.com/repo:latest").
@@ -166,26 +166,12 @@ func InspectWithMaxIndexSize(size int64) InspectOption {
 // Referrers (signatures, attestations) can be fetched on-demand via
 // [InspectResult.Referrers].
 func (c *Client) Inspect(ctx context.Context, ref string, opts ...InspectOption) (*InspectResult, error) {
-	cfg := inspectConfig{}
-	for _, opt := range opts {
-		opt(&cfg)
-	}
-
 	// Build registry client options
 	regOpts := buildRegistryOpts(c)
 	regClient := registry.New(regOpts...)
 
-	// Build inspect options for registry client
-	var inspectOpts []registry.InspectOption
-	if cfg.skipCache {
-		inspectOpts = append(inspectOpts, registry.WithInspectSkipCache())
-	}
-	if cfg.maxIndexSize > 0 {
-		inspectOpts = append(inspectOpts, registry.WithInspectMaxIndexSize(cfg.maxIndexSize))
-	}
-
 	// Inspect via registry client
-	result, err := regClient.Inspect(ctx, ref, inspectOpts...)
+	result, err := regClient.Inspect(ctx, ref, registryInspectOptions(opts)...)
 	if err != nil {
 		return nil, err
 	}
@@ -203,3 +189,20 @@ func (c *Client) Inspect(ctx context.Context, ref string, opts ...InspectOption)
 		ref:      ref,
 	}, nil
 }
+
+// registryInspectOptions converts inspect options to registry inspect options.
+func registryInspectOptions(opts []InspectOption) []registry.InspectOption {
+	cfg := inspectConfig{}
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+
+	var inspectOpts []registry.InspectOption
+	if cfg.skipCache {
+		inspectOpts = append(inspectOpts, registry.WithInspectSkipCache())
+	}
+	if cfg.maxIndexSize > 0 {
+		inspectOpts = append(inspectOpts, registry.WithInspectMaxIndexSize(cfg.maxIndexSize))
+	}
+	return inspectOpts
+}
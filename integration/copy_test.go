@@ -0,0 +1,64 @@
+//go:build integration
+
+package integration
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/meigma/blob"
+)
+
+func TestClient_Copy_BetweenRegistries(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	srcAddr := getRegistry(t)
+	dstAddr := getSecondRegistry(t)
+	client := newTestClient(t, srcAddr)
+
+	dir := t.TempDir()
+	createTestFiles(t, dir, smallArchive)
+
+	srcRef := testRef(srcAddr, "copy-between-registries")
+	dstRef := testRef(dstAddr, "copy-between-registries")
+	annotations := map[string]string{"org.example.stage": "staging"}
+	require.NoError(t, client.Push(ctx, srcRef, dir, blob.PushWithAnnotations(annotations)), "Push")
+
+	signer := staticSigner{
+		data:      []byte(`{"signature":"test"}`),
+		mediaType: "application/vnd.dev.sigstore.bundle.v0.3+json",
+	}
+	_, err := client.Sign(ctx, srcRef, signer)
+	require.NoError(t, err, "Sign")
+
+	require.NoError(t, client.Copy(ctx, srcRef, dstRef), "Copy")
+
+	srcManifest, err := client.Fetch(ctx, srcRef)
+	require.NoError(t, err, "Fetch source")
+	dstManifest, err := client.Fetch(ctx, dstRef)
+	require.NoError(t, err, "Fetch destination")
+	assert.Equal(t, srcManifest.Digest(), dstManifest.Digest(), "digest must be preserved")
+	assert.Equal(t, "staging", dstManifest.Annotations()["org.example.stage"])
+
+	archive, err := client.Pull(ctx, dstRef)
+	require.NoError(t, err, "Pull destination")
+	for path, expected := range smallArchive {
+		content, err := archive.ReadFile(path)
+		require.NoError(t, err, "ReadFile(%q)", path)
+		assert.Equal(t, expected, content)
+	}
+
+	srcReferrers, err := client.FetchReferrers(ctx, srcRef, signer.mediaType)
+	require.NoError(t, err, "FetchReferrers source")
+	dstReferrers, err := client.FetchReferrers(ctx, dstRef, signer.mediaType)
+	require.NoError(t, err, "FetchReferrers destination")
+	require.Len(t, dstReferrers, 1)
+	assert.Equal(t, srcReferrers, dstReferrers)
+
+	// A second copy finds every blob in place and succeeds.
+	require.NoError(t, client.Copy(ctx, srcRef, dstRef), "second Copy")
+}
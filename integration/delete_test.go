@@ -0,0 +1,43 @@
+//go:build integration
+
+package integration
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/meigma/blob"
+)
+
+func TestClient_Delete(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	addr := getRegistry(t)
+	client := newTestClient(t, addr)
+
+	dir := t.TempDir()
+	createTestFiles(t, dir, smallArchive)
+
+	ref := testRef(addr, "delete")
+	require.NoError(t, client.Push(ctx, ref, dir), "Push")
+
+	signer := staticSigner{
+		data:      []byte(`{"signature":"test"}`),
+		mediaType: "application/vnd.dev.sigstore.bundle.v0.3+json",
+	}
+	_, err := client.Sign(ctx, ref, signer)
+	require.NoError(t, err, "Sign")
+
+	_, err = client.Pull(ctx, ref)
+	require.NoError(t, err, "Pull before delete")
+
+	require.NoError(t, client.Delete(ctx, ref, blob.DeleteWithReferrers(true)), "Delete")
+
+	_, err = client.Pull(ctx, ref)
+	require.Error(t, err, "Pull after delete")
+	assert.ErrorIs(t, err, blob.ErrNotFound)
+}
@@ -24,6 +24,10 @@ var (
 	registryOnce sync.Once
 	registryAddr string
 	errRegistry  error
+
+	secondRegistryOnce sync.Once
+	secondRegistryAddr string
+	errSecondRegistry  error
 )
 
 // getRegistry returns the shared registry address, starting the container if needed.
@@ -47,11 +51,33 @@ func getRegistry(tb testing.TB) string {
 	return registryAddr
 }
 
+// getSecondRegistry returns the address of a second, independent registry
+// for tests that transfer archives between registries.
+func getSecondRegistry(tb testing.TB) string {
+	tb.Helper()
+
+	if os.Getenv("SKIP_DOCKER_TESTS") == "1" {
+		tb.Skip("SKIP_DOCKER_TESTS is set")
+	}
+
+	secondRegistryOnce.Do(func() {
+		ctx := context.Background()
+		secondRegistryAddr, errSecondRegistry = startRegistryContainer(ctx)
+	})
+
+	if errSecondRegistry != nil {
+		tb.Fatalf("start second registry container: %v", errSecondRegistry)
+	}
+
+	return secondRegistryAddr
+}
+
 // startRegistryContainer starts a registry:2 container and returns the host:port address.
 func startRegistryContainer(ctx context.Context) (string, error) {
 	req := testcontainers.ContainerRequest{
 		Image:        "registry:2",
 		ExposedPorts: []string{"5000/tcp"},
+		Env:          map[string]string{"REGISTRY_STORAGE_DELETE_ENABLED": "true"},
 		WaitingFor:   wait.ForHTTP("/v2/").WithPort("5000/tcp").WithStatusCodeMatcher(isOKStatus),
 	}
 
@@ -0,0 +1,76 @@
+//go:build integration
+
+package integration
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/opencontainers/go-digest"
+	specs "github.com/opencontainers/image-spec/specs-go"
+	ocispec "github.com/opencontainers/image-spec/specs-go/v1"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"oras.land/oras-go/v2/registry/remote"
+
+	"github.com/meigma/blob"
+)
+
+func TestPull_WithPlatform(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	registryAddr := getRegistry(t)
+	client := newTestClient(t, registryAddr)
+
+	repo, err := remote.NewRepository(registryAddr + "/test/pull-platform")
+	require.NoError(t, err)
+	repo.PlainHTTP = true
+
+	// Push one archive per platform, then group them under a single tag
+	// with an OCI image index.
+	platforms := []ocispec.Platform{
+		{OS: "linux", Architecture: "amd64"},
+		{OS: "linux", Architecture: "arm64"},
+	}
+	index := ocispec.Index{
+		Versioned: specs.Versioned{SchemaVersion: 2},
+		MediaType: ocispec.MediaTypeImageIndex,
+	}
+	for _, p := range platforms {
+		dir := t.TempDir()
+		createTestFiles(t, dir, map[string][]byte{"platform.txt": []byte(p.OS + "/" + p.Architecture)})
+
+		tag := p.OS + "-" + p.Architecture
+		require.NoError(t, client.Push(ctx, testRefWithTag(registryAddr, "pull-platform", tag), dir), "Push %s", tag)
+
+		desc, err := repo.Resolve(ctx, tag)
+		require.NoError(t, err, "Resolve %s", tag)
+		desc.Platform = &p
+		index.Manifests = append(index.Manifests, desc)
+	}
+
+	raw, err := json.Marshal(index)
+	require.NoError(t, err)
+	indexDesc := ocispec.Descriptor{
+		MediaType: ocispec.MediaTypeImageIndex,
+		Digest:    digest.FromBytes(raw),
+		Size:      int64(len(raw)),
+	}
+	require.NoError(t, repo.PushReference(ctx, indexDesc, bytes.NewReader(raw), "multi"), "push image index")
+
+	ref := testRefWithTag(registryAddr, "pull-platform", "multi")
+	for _, p := range platforms {
+		archive, err := client.Pull(ctx, ref, blob.PullWithPlatform(p.OS, p.Architecture))
+		require.NoError(t, err, "Pull %s/%s", p.OS, p.Architecture)
+
+		content, err := archive.ReadFile("platform.txt")
+		require.NoError(t, err)
+		assert.Equal(t, p.OS+"/"+p.Architecture, string(content))
+	}
+
+	_, err = client.Pull(ctx, ref, blob.PullWithPlatform("windows", "amd64"))
+	require.ErrorIs(t, err, blob.ErrPlatformNotFound)
+}
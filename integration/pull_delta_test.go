@@ -0,0 +1,134 @@
+//go:build integration
+
+package integration
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/http/httputil"
+	"net/url"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// rangeRecordingProxy forwards requests to a registry and records the Range
+// header of every blob GET request, keyed by blob digest.
+type rangeRecordingProxy struct {
+	addr string
+
+	mu     sync.Mutex
+	ranges map[string][]string
+}
+
+func newRangeRecordingProxy(t *testing.T, registryAddr string) *rangeRecordingProxy {
+	t.Helper()
+
+	p := &rangeRecordingProxy{ranges: make(map[string][]string)}
+	target := &url.URL{Scheme: "http", Host: registryAddr}
+	proxy := httputil.NewSingleHostReverseProxy(target)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if _, dgst, ok := strings.Cut(r.URL.Path, "/blobs/"); ok && r.Method == http.MethodGet && r.Header.Get("Range") != "" {
+			p.mu.Lock()
+			p.ranges[dgst] = append(p.ranges[dgst], r.Header.Get("Range"))
+			p.mu.Unlock()
+		}
+		proxy.ServeHTTP(w, r)
+	}))
+	t.Cleanup(srv.Close)
+	p.addr = strings.TrimPrefix(srv.URL, "http://")
+	return p
+}
+
+// dataRanges returns the recorded ranges of the blob, excluding the
+// single-byte probe made when an archive's data source is opened.
+func (p *rangeRecordingProxy) dataRanges(dgst string) []string {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	var ranges []string
+	for _, r := range p.ranges[dgst] {
+		if r != "bytes=0-0" {
+			ranges = append(ranges, r)
+		}
+	}
+	return ranges
+}
+
+func TestClient_PullDelta(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	proxy := newRangeRecordingProxy(t, getRegistry(t))
+	client := newTestClient(t, proxy.addr)
+
+	v1 := map[string][]byte{
+		"app/main.bin":   makeRandomContent(32 * 1024),
+		"app/config.txt": []byte("replicas: 1"),
+		"lib/a.so":       makeRandomContent(32 * 1024),
+		"lib/b.so":       makeRandomContent(32 * 1024),
+		"old.txt":        []byte("removed in v2"),
+	}
+	v2 := map[string][]byte{
+		"app/main.bin":   makeRandomContent(32 * 1024), // changed
+		"app/config.txt": v1["app/config.txt"],
+		"lib/a.so":       v1["lib/a.so"],
+		"lib/b.so":       v1["lib/b.so"],
+		"new.txt":        []byte("added in v2"),
+	}
+	push := func(tag string, files map[string][]byte) string {
+		dir := t.TempDir()
+		createTestFiles(t, dir, files)
+		ref := testRefWithTag(proxy.addr, "pull-delta", tag)
+		require.NoError(t, client.Push(ctx, ref, dir), "Push %s", tag)
+		return ref
+	}
+	oldRef, newRef := push("v1", v1), push("v2", v2)
+
+	old, err := client.Pull(ctx, oldRef)
+	require.NoError(t, err, "Pull v1")
+	dest := t.TempDir()
+	_, err = old.CopyDir(dest, ".")
+	require.NoError(t, err, "CopyDir v1")
+
+	stats, err := client.PullDelta(ctx, old.Blob, newRef, dest)
+	require.NoError(t, err, "PullDelta")
+	assert.Equal(t, 2, stats.FileCount, "only the changed and added files are written")
+	assert.Equal(t, 3, stats.Skipped)
+
+	for path, expected := range v2 {
+		content, err := os.ReadFile(filepath.Join(dest, path))
+		require.NoError(t, err, path)
+		assert.Equal(t, expected, content, path)
+	}
+	assert.NoFileExists(t, filepath.Join(dest, "old.txt"))
+
+	// Every data range request falls within an added or changed file.
+	archive, err := client.Pull(ctx, newRef)
+	require.NoError(t, err, "Pull v2")
+	type span struct{ start, end uint64 }
+	var changed []span
+	for _, path := range []string{"app/main.bin", "new.txt"} {
+		view, ok := archive.Entry(path)
+		require.True(t, ok, path)
+		changed = append(changed, span{view.DataOffset(), view.DataOffset() + view.DataSize() - 1})
+	}
+	ranges := proxy.dataRanges(archive.Manifest().DataDescriptor().Digest.String())
+	require.NotEmpty(t, ranges, "changed files must be fetched")
+	for _, r := range ranges {
+		var start, end uint64
+		_, err := fmt.Sscanf(r, "bytes=%d-%d", &start, &end)
+		require.NoError(t, err, r)
+		assert.True(t, slices.ContainsFunc(changed, func(s span) bool {
+			return s.start <= start && end <= s.end
+		}), "range %s reads an unchanged file", r)
+	}
+}
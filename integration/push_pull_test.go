@@ -14,6 +14,7 @@ import (
 
 	"github.com/meigma/blob"
 	blobcore "github.com/meigma/blob/core"
+	"github.com/meigma/blob/registry"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
@@ -120,6 +121,37 @@ func TestPush_WithAnnotations(t *testing.T) {
 	}
 }
 
+func TestPull_Annotations(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	registryAddr := getRegistry(t)
+	client := newTestClient(t, registryAddr, blob.WithManifestCacheDir(t.TempDir()))
+
+	dir := t.TempDir()
+	createTestFiles(t, dir, smallArchive)
+
+	annotations := map[string]string{
+		"org.opencontainers.image.created":  "2024-06-01T12:00:00Z",
+		"org.opencontainers.image.revision": "3f2c1e0",
+		"org.opencontainers.image.source":   "https://ci.example.com/builds/1234",
+	}
+
+	ref := testRef(registryAddr, "pull-annotations")
+	require.NoError(t, client.Push(ctx, ref, dir, blob.PushWithAnnotations(annotations)), "Push")
+
+	// The second pull reads the manifest from the disk cache.
+	for range 2 {
+		archive, err := client.Pull(ctx, ref)
+		require.NoError(t, err, "Pull")
+
+		ann := archive.Annotations()
+		for key, value := range annotations {
+			assert.Equal(t, value, ann[key], "annotation %q", key)
+		}
+	}
+}
+
 func TestPush_WithChangeDetection(t *testing.T) {
 	t.Parallel()
 
@@ -195,6 +227,31 @@ func TestPush_WithSkipCompression(t *testing.T) {
 	assertFilesMatch(t, archive, files)
 }
 
+func TestPush_WithIndexAsConfig(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	registryAddr := getRegistry(t)
+	client := newTestClient(t, registryAddr)
+
+	dir := t.TempDir()
+	createTestFiles(t, dir, nestedArchive)
+
+	ref := testRef(registryAddr, "push-index-as-config")
+	err := client.Push(ctx, ref, dir, blob.PushWithIndexAsConfig(true))
+	require.NoError(t, err, "Push with index as config")
+
+	manifest, err := client.Fetch(ctx, ref)
+	require.NoError(t, err, "Fetch")
+	assert.Equal(t, registry.MediaTypeIndex, manifest.Raw().Config.MediaType)
+	assert.Len(t, manifest.Raw().Layers, 1)
+
+	archive, err := client.Pull(ctx, ref)
+	require.NoError(t, err, "Pull")
+	assertFilesMatch(t, archive, nestedArchive)
+	assert.True(t, archive.IsDir("dir2/deep"))
+}
+
 // --- Pull Operations ---
 
 func TestPull_Basic(t *testing.T) {
@@ -0,0 +1,86 @@
+//go:build integration
+
+package integration
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/http/httputil"
+	"net/url"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/meigma/blob"
+)
+
+// uploadCountingProxy forwards requests to a registry and counts the bytes
+// sent in blob upload requests.
+type uploadCountingProxy struct {
+	addr     string
+	uploaded atomic.Int64
+}
+
+func newUploadCountingProxy(t *testing.T, registryAddr string) *uploadCountingProxy {
+	t.Helper()
+
+	p := &uploadCountingProxy{}
+	target := &url.URL{Scheme: "http", Host: registryAddr}
+	proxy := httputil.NewSingleHostReverseProxy(target)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if strings.Contains(r.URL.Path, "/blobs/uploads/") && r.Body != nil {
+			r.Body = &countingBody{ReadCloser: r.Body, n: &p.uploaded}
+		}
+		proxy.ServeHTTP(w, r)
+	}))
+	t.Cleanup(srv.Close)
+	p.addr = strings.TrimPrefix(srv.URL, "http://")
+	return p
+}
+
+type countingBody struct {
+	io.ReadCloser
+	n *atomic.Int64
+}
+
+func (b *countingBody) Read(p []byte) (int, error) {
+	n, err := b.ReadCloser.Read(p)
+	b.n.Add(int64(n))
+	return n, err
+}
+
+func TestPush_SkipsExistingBlobs(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	proxy := newUploadCountingProxy(t, getRegistry(t))
+	client := newTestClient(t, proxy.addr)
+
+	dir := t.TempDir()
+	createTestFiles(t, dir, smallArchive)
+
+	require.NoError(t, client.Push(ctx, testRefWithTag(proxy.addr, "push-skip-existing", "v1"), dir), "first Push")
+	require.Positive(t, proxy.uploaded.Load(), "first push must upload blobs")
+
+	proxy.uploaded.Store(0)
+	ref := testRefWithTag(proxy.addr, "push-skip-existing", "v2")
+	require.NoError(t, client.Push(ctx, ref, dir), "second Push")
+	assert.Zero(t, proxy.uploaded.Load(), "second push must not upload any blob bytes")
+
+	archive, err := client.Pull(ctx, ref)
+	require.NoError(t, err, "Pull")
+	for path, expected := range smallArchive {
+		content, err := archive.ReadFile(path)
+		require.NoError(t, err, "ReadFile(%q)", path)
+		assert.Equal(t, expected, content)
+	}
+
+	proxy.uploaded.Store(0)
+	require.NoError(t, client.Push(ctx, testRefWithTag(proxy.addr, "push-skip-existing", "v3"), dir, blob.PushWithForceUpload(true)), "forced Push")
+	assert.Positive(t, proxy.uploaded.Load(), "forced push must upload blobs")
+}
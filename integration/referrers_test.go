@@ -0,0 +1,96 @@
+//go:build integration
+
+package integration
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/http/httputil"
+	"net/url"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/meigma/blob"
+)
+
+// countingTransport counts the requests it forwards.
+type countingTransport struct {
+	next     http.RoundTripper
+	requests atomic.Int64
+}
+
+func (t *countingTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	t.requests.Add(1)
+	return t.next.RoundTrip(req)
+}
+
+// staticSigner returns a fixed signature payload.
+type staticSigner struct {
+	data      []byte
+	mediaType string
+}
+
+func (s staticSigner) SignManifest(context.Context, []byte) (data []byte, mediaType string, err error) {
+	return s.data, s.mediaType, nil
+}
+
+// newCountingProxy starts a reverse proxy to registryAddr and returns its
+// host:port address and the transport counting forwarded requests.
+func newCountingProxy(tb testing.TB, registryAddr string) (string, *countingTransport) {
+	tb.Helper()
+
+	target, err := url.Parse("http://" + registryAddr)
+	require.NoError(tb, err)
+
+	transport := &countingTransport{next: http.DefaultTransport}
+	proxy := httputil.NewSingleHostReverseProxy(target)
+	proxy.Transport = transport
+
+	server := httptest.NewServer(proxy)
+	tb.Cleanup(server.Close)
+
+	return strings.TrimPrefix(server.URL, "http://"), transport
+}
+
+func TestFetchReferrers_Cached(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	registryAddr := getRegistry(t)
+	client := newTestClient(t, registryAddr)
+
+	dir := t.TempDir()
+	createTestFiles(t, dir, smallArchive)
+
+	ref := testRef(registryAddr, "fetch-referrers-cached")
+	require.NoError(t, client.Push(ctx, ref, dir), "Push")
+
+	signer := staticSigner{
+		data:      []byte(`{"signature":"test"}`),
+		mediaType: "application/vnd.dev.sigstore.bundle.v0.3+json",
+	}
+	_, err := client.Sign(ctx, ref, signer)
+	require.NoError(t, err, "Sign")
+
+	proxyAddr, transport := newCountingProxy(t, registryAddr)
+	proxyRef := testRef(proxyAddr, "fetch-referrers-cached")
+	cachedClient := newTestClient(t, proxyAddr, blob.WithCacheDir(t.TempDir()))
+
+	first, err := cachedClient.FetchReferrers(ctx, proxyRef, signer.mediaType)
+	require.NoError(t, err, "first FetchReferrers")
+	require.Len(t, first, 1)
+	assert.Equal(t, signer.mediaType, first[0].ArtifactType)
+	assert.NotEmpty(t, first[0].Content)
+	require.Positive(t, transport.requests.Load(), "first fetch should reach the registry")
+
+	before := transport.requests.Load()
+	second, err := cachedClient.FetchReferrers(ctx, proxyRef, signer.mediaType)
+	require.NoError(t, err, "second FetchReferrers")
+	assert.Equal(t, first, second)
+	assert.Equal(t, before, transport.requests.Load(), "second fetch should be served from cache")
+}
@@ -0,0 +1,33 @@
+//go:build integration
+
+package integration
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestClient_ListTags(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	addr := getRegistry(t)
+	client := newTestClient(t, addr)
+
+	dir := t.TempDir()
+	createTestFiles(t, dir, smallArchive)
+
+	tags := []string{"v1.0.0", "v1.1.0", "v2.0.0"}
+	for _, tag := range tags {
+		ref := testRefWithTag(addr, "list-tags", tag)
+		require.NoError(t, client.Push(ctx, ref, dir), "Push(%q)", ref)
+	}
+
+	listed, err := client.ListTags(ctx, fmt.Sprintf("%s/test/list-tags", addr))
+	require.NoError(t, err, "ListTags")
+	assert.ElementsMatch(t, tags, listed)
+}
@@ -0,0 +1,167 @@
+package policy
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	orasregistry "oras.land/oras-go/v2/registry"
+
+	"github.com/meigma/blob/registry"
+)
+
+// CacheOption configures Cached.
+type CacheOption func(*cachedPolicy)
+
+// WithAllowTTL sets how long a passing result is reused. Zero, the default,
+// keeps it until the process exits.
+func WithAllowTTL(ttl time.Duration) CacheOption {
+	return func(c *cachedPolicy) {
+		c.allowTTL = ttl
+	}
+}
+
+// WithDenyTTL caches failing results for ttl, so known-bad artifacts are
+// rejected without verifying them again. Only rejections reported as a
+// *VerificationError are cached; other failures, such as a registry that
+// could not be reached, are evaluated again. Denials are not cached by
+// default.
+// A shorter TTL than WithAllowTTL lets a fixed artifact pass soon after it
+// is republished with valid attestations.
+func WithDenyTTL(ttl time.Duration) CacheOption {
+	return func(c *cachedPolicy) {
+		c.denyTTL = ttl
+	}
+}
+
+// Cached returns a policy that reuses the results of inner, keyed by the
+// repository and manifest digest of the request.
+//
+// Manifest digests are immutable, so a digest that passed once in a
+// repository passes again until the allow TTL expires and inner is not
+// evaluated. The same digest in another repository is evaluated on its own,
+// since its attestations live there. Failures are cached only with
+// WithDenyTTL. Results of evaluations cut short by context cancellation are
+// never cached, and requests without a digest always evaluate inner.
+//
+// The returned policy is safe for concurrent use. Concurrent requests for a
+// digest that is not cached each evaluate inner.
+func Cached(inner registry.Policy, opts ...CacheOption) registry.Policy {
+	c := &cachedPolicy{
+		inner:   inner,
+		entries: make(map[string]cacheEntry),
+		now:     time.Now,
+	}
+	for _, opt := range opts {
+		opt(c)
+	}
+	return c
+}
+
+// cachedPolicy implements Cached.
+type cachedPolicy struct {
+	inner    registry.Policy
+	allowTTL time.Duration
+	denyTTL  time.Duration
+	now      func() time.Time
+
+	mu      sync.Mutex
+	entries map[string]cacheEntry // repository@digest -> result
+}
+
+// cacheEntry is a cached result. A zero expires never expires.
+type cacheEntry struct {
+	err     error
+	expires time.Time
+}
+
+// Evaluate implements registry.Policy.
+//
+//nolint:gocritic // req passed by value per registry.Policy interface contract
+func (c *cachedPolicy) Evaluate(ctx context.Context, req registry.PolicyRequest) error {
+	if req.Digest == "" {
+		return c.inner.Evaluate(ctx, req)
+	}
+	key := cacheKey(req.Ref, req.Digest)
+	if entry, ok := c.lookup(key); ok {
+		return entry.err
+	}
+
+	err := c.inner.Evaluate(ctx, req)
+	if ctx.Err() == nil {
+		c.store(key, err)
+	}
+	return err
+}
+
+// cacheKey returns the key for digest in the repository of ref. A ref that
+// does not parse is used whole.
+func cacheKey(ref, digest string) string {
+	if r, err := orasregistry.ParseReference(ref); err == nil {
+		ref = r.Registry + "/" + r.Repository
+	}
+	return ref + "@" + digest
+}
+
+// isVerificationFailure reports whether err rejects the artifact itself:
+// a *VerificationError, or a *MultiError made only of such failures.
+func isVerificationFailure(err error) bool {
+	var multi *MultiError
+	if errors.As(err, &multi) {
+		if len(multi.Errors) == 0 {
+			return false
+		}
+		for _, e := range multi.Errors {
+			if !isVerificationFailure(e) {
+				return false
+			}
+		}
+		return true
+	}
+	var verr *VerificationError
+	return errors.As(err, &verr)
+}
+
+// lookup returns the unexpired result for key.
+func (c *cachedPolicy) lookup(key string) (cacheEntry, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	entry, ok := c.entries[key]
+	if !ok {
+		return cacheEntry{}, false
+	}
+	if !entry.expires.IsZero() && !c.now().Before(entry.expires) {
+		delete(c.entries, key)
+		return cacheEntry{}, false
+	}
+	return entry, true
+}
+
+// store caches the result of evaluating key and drops expired entries.
+func (c *cachedPolicy) store(key string, err error) {
+	ttl := c.allowTTL
+	if err != nil {
+		if c.denyTTL <= 0 || !isVerificationFailure(err) {
+			return
+		}
+		ttl = c.denyTTL
+	}
+
+	now := c.now()
+	entry := cacheEntry{err: err}
+	if ttl > 0 {
+		entry.expires = now.Add(ttl)
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for d, e := range c.entries {
+		if !e.expires.IsZero() && !now.Before(e.expires) {
+			delete(c.entries, d)
+		}
+	}
+	c.entries[key] = entry
+}
+
+var _ registry.Policy = (*cachedPolicy)(nil)
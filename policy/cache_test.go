@@ -0,0 +1,138 @@
+package policy
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/meigma/blob/registry"
+)
+
+// countingPolicy counts evaluations and returns err.
+type countingPolicy struct {
+	calls int
+	err   error
+}
+
+//nolint:gocritic // req passed by value per registry.Policy interface contract
+func (p *countingPolicy) Evaluate(context.Context, registry.PolicyRequest) error {
+	p.calls++
+	return p.err
+}
+
+// fakeClock is a settable time source.
+type fakeClock struct{ t time.Time }
+
+func (c *fakeClock) now() time.Time { return c.t }
+
+func newCached(inner registry.Policy, clock *fakeClock, opts ...CacheOption) registry.Policy {
+	p := Cached(inner, opts...)
+	p.(*cachedPolicy).now = clock.now
+	return p
+}
+
+func TestCached(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	reqA := registry.PolicyRequest{Ref: "example.com/app:v1", Digest: "sha256:aaa"}
+	reqB := registry.PolicyRequest{Ref: "example.com/app:v1", Digest: "sha256:bbb"}
+
+	t.Run("allow is cached until the TTL expires", func(t *testing.T) {
+		t.Parallel()
+
+		inner := &countingPolicy{}
+		clock := &fakeClock{t: time.Unix(1000, 0)}
+		p := newCached(inner, clock, WithAllowTTL(time.Minute))
+
+		for range 3 {
+			require.NoError(t, p.Evaluate(ctx, reqA))
+		}
+		assert.Equal(t, 1, inner.calls)
+
+		require.NoError(t, p.Evaluate(ctx, reqB))
+		assert.Equal(t, 2, inner.calls, "another digest is evaluated")
+
+		clock.t = clock.t.Add(time.Minute)
+		require.NoError(t, p.Evaluate(ctx, reqA))
+		assert.Equal(t, 3, inner.calls, "expired result is evaluated again")
+	})
+
+	t.Run("denials are not cached by default", func(t *testing.T) {
+		t.Parallel()
+
+		inner := &countingPolicy{err: errors.New("denied")}
+		p := newCached(inner, &fakeClock{t: time.Unix(1000, 0)})
+		require.Error(t, p.Evaluate(ctx, reqA))
+		require.Error(t, p.Evaluate(ctx, reqA))
+		assert.Equal(t, 2, inner.calls)
+	})
+
+	t.Run("deny TTL", func(t *testing.T) {
+		t.Parallel()
+
+		denied := &VerificationError{Policy: "test", Reason: ReasonNoSignature}
+		inner := &countingPolicy{err: denied}
+		clock := &fakeClock{t: time.Unix(1000, 0)}
+		p := newCached(inner, clock, WithDenyTTL(10*time.Second))
+
+		require.ErrorIs(t, p.Evaluate(ctx, reqA), denied)
+		require.ErrorIs(t, p.Evaluate(ctx, reqA), denied)
+		assert.Equal(t, 1, inner.calls)
+
+		clock.t = clock.t.Add(10 * time.Second)
+		inner.err = nil
+		require.NoError(t, p.Evaluate(ctx, reqA))
+		assert.Equal(t, 2, inner.calls)
+	})
+
+	t.Run("other failures are not cached", func(t *testing.T) {
+		t.Parallel()
+
+		for _, err := range []error{
+			errors.New("registry unavailable"),
+			&MultiError{Errors: []error{&VerificationError{Policy: "test"}, errors.New("registry unavailable")}},
+		} {
+			inner := &countingPolicy{err: err}
+			p := newCached(inner, &fakeClock{t: time.Unix(1000, 0)}, WithDenyTTL(time.Minute))
+			require.Error(t, p.Evaluate(ctx, reqA))
+			require.Error(t, p.Evaluate(ctx, reqA))
+			assert.Equal(t, 2, inner.calls, "%v", err)
+		}
+	})
+
+	t.Run("results are kept per repository", func(t *testing.T) {
+		t.Parallel()
+
+		inner := &countingPolicy{}
+		p := newCached(inner, &fakeClock{t: time.Unix(1000, 0)})
+
+		require.NoError(t, p.Evaluate(ctx, reqA))
+		require.NoError(t, p.Evaluate(ctx, registry.PolicyRequest{Ref: "example.com/app:latest", Digest: "sha256:aaa"}))
+		assert.Equal(t, 1, inner.calls, "another ref in the same repository shares the result")
+
+		require.NoError(t, p.Evaluate(ctx, registry.PolicyRequest{Ref: "example.com/other:v1", Digest: "sha256:aaa"}))
+		assert.Equal(t, 2, inner.calls, "the same digest in another repository is evaluated")
+	})
+
+	t.Run("cancelled evaluations and missing digests are not cached", func(t *testing.T) {
+		t.Parallel()
+
+		inner := &countingPolicy{}
+		p := newCached(inner, &fakeClock{t: time.Unix(1000, 0)})
+
+		cancelled, cancel := context.WithCancel(ctx)
+		cancel()
+		require.NoError(t, p.Evaluate(cancelled, reqA))
+		require.NoError(t, p.Evaluate(ctx, reqA))
+		assert.Equal(t, 2, inner.calls)
+
+		require.NoError(t, p.Evaluate(ctx, registry.PolicyRequest{}))
+		require.NoError(t, p.Evaluate(ctx, registry.PolicyRequest{}))
+		assert.Equal(t, 4, inner.calls)
+	})
+}
@@ -0,0 +1,151 @@
+package policy
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Reason classifies why a policy rejected an artifact.
+type Reason int
+
+// Verification failure reasons.
+const (
+	// ReasonUnknown is an unclassified failure.
+	ReasonUnknown Reason = iota
+
+	// ReasonNoSignature means no signature was found for the manifest.
+	ReasonNoSignature
+
+	// ReasonInvalidSignature means a signature was found but did not verify.
+	ReasonInvalidSignature
+
+	// ReasonIdentityMismatch means a signature verified but was made by an
+	// identity the policy does not accept.
+	ReasonIdentityMismatch
+
+	// ReasonNoAttestation means no matching attestation was found.
+	ReasonNoAttestation
+
+	// ReasonInvalidAttestation means an attestation could not be parsed.
+	ReasonInvalidAttestation
+
+	// ReasonBuilderMismatch means the provenance names another builder.
+	ReasonBuilderMismatch
+
+	// ReasonSourceMismatch means the provenance names another source
+	// repository.
+	ReasonSourceMismatch
+
+	// ReasonRefMismatch means the source ref is not allowed.
+	ReasonRefMismatch
+
+	// ReasonWorkflowMismatch means the build workflow is not allowed.
+	ReasonWorkflowMismatch
+
+	// ReasonMaterialNotAllowed means a build material is not allowed.
+	ReasonMaterialNotAllowed
+
+	// ReasonPolicyDenied means a policy engine, such as Rego or gittuf,
+	// denied the artifact.
+	ReasonPolicyDenied
+
+	// ReasonEvaluationFailed means the policy could not be evaluated, for
+	// example because a Rego policy failed or timed out.
+	ReasonEvaluationFailed
+
+	// ReasonReferrersUnsupported means the registry does not support the
+	// referrers API, so signatures and attestations cannot be found.
+	ReasonReferrersUnsupported
+
+	// ReasonFetchFailed means fetching verification material failed.
+	ReasonFetchFailed
+)
+
+var reasonNames = [...]string{
+	ReasonUnknown:              "unknown",
+	ReasonNoSignature:          "no signature",
+	ReasonInvalidSignature:     "invalid signature",
+	ReasonIdentityMismatch:     "identity mismatch",
+	ReasonNoAttestation:        "no attestation",
+	ReasonInvalidAttestation:   "invalid attestation",
+	ReasonBuilderMismatch:      "builder mismatch",
+	ReasonSourceMismatch:       "source mismatch",
+	ReasonRefMismatch:          "ref mismatch",
+	ReasonWorkflowMismatch:     "workflow mismatch",
+	ReasonMaterialNotAllowed:   "material not allowed",
+	ReasonPolicyDenied:         "policy denied",
+	ReasonEvaluationFailed:     "evaluation failed",
+	ReasonReferrersUnsupported: "referrers unsupported",
+	ReasonFetchFailed:          "fetch failed",
+}
+
+// String returns a short description of the reason.
+func (r Reason) String() string {
+	if r >= 0 && int(r) < len(reasonNames) {
+		return reasonNames[r]
+	}
+	return fmt.Sprintf("Reason(%d)", int(r))
+}
+
+// VerificationError describes why a policy rejected an artifact. The
+// sigstore, slsa, opa, and gittuf policies return it, so callers can use
+// errors.As to tell, for example, a missing signature from one made by the
+// wrong identity.
+type VerificationError struct {
+	// Policy names the policy that failed, such as "sigstore" or "slsa".
+	Policy string
+
+	// Reason classifies the failure.
+	Reason Reason
+
+	// Subject is the offending value when there is one: the signer
+	// identity, builder ID, source repository or ref, material URI, or
+	// attestation digest.
+	Subject string
+
+	// Err is the underlying error. It carries the policy's own sentinel
+	// errors, such as slsa.ErrBuilderMismatch.
+	Err error
+}
+
+// Error returns the underlying error message, or a description built from
+// the policy and reason when there is none.
+func (e *VerificationError) Error() string {
+	if e.Err != nil {
+		return e.Err.Error()
+	}
+	msg := e.Policy + ": " + e.Reason.String()
+	if e.Subject != "" {
+		msg += ": " + e.Subject
+	}
+	return msg
+}
+
+// Unwrap returns the underlying error.
+func (e *VerificationError) Unwrap() error {
+	return e.Err
+}
+
+// MultiError holds the failures of a composed policy. RequireAll returns one
+// holding the first failure and RequireAny one holding the failure of every
+// policy. errors.Is and errors.As search all of them.
+type MultiError struct {
+	Errors []error
+}
+
+// Error joins the failure messages.
+func (e *MultiError) Error() string {
+	if len(e.Errors) == 1 {
+		return e.Errors[0].Error()
+	}
+	msgs := make([]string, len(e.Errors))
+	for i, err := range e.Errors {
+		msgs[i] = err.Error()
+	}
+	return fmt.Sprintf("policy: all %d policies failed: %s", len(e.Errors), strings.Join(msgs, "; "))
+}
+
+// Unwrap returns the failures.
+func (e *MultiError) Unwrap() []error {
+	return e.Errors
+}
@@ -0,0 +1,176 @@
+package policy
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/opencontainers/go-digest"
+	ocispec "github.com/opencontainers/image-spec/specs-go/v1"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/meigma/blob/registry"
+)
+
+// recordingClient is a registry.PolicyClient that counts requests. When
+// release is set, each request waits for it to be closed.
+type recordingClient struct {
+	referrers   map[string][]ocispec.Descriptor // artifact type -> referrers
+	descriptors map[digest.Digest][]byte
+	release     chan struct{}
+
+	referrerCalls atomic.Int32
+	fetchCalls    atomic.Int32
+}
+
+//nolint:gocritic // implements registry.PolicyClient interface
+func (c *recordingClient) Referrers(_ context.Context, _ string, _ ocispec.Descriptor, artifactType string) ([]ocispec.Descriptor, error) {
+	c.referrerCalls.Add(1)
+	if c.release != nil {
+		<-c.release
+	}
+	return c.referrers[artifactType], nil
+}
+
+//nolint:gocritic // implements registry.PolicyClient interface
+func (c *recordingClient) FetchDescriptor(_ context.Context, _ string, desc ocispec.Descriptor) ([]byte, error) {
+	c.fetchCalls.Add(1)
+	if c.release != nil {
+		<-c.release
+	}
+	data, ok := c.descriptors[desc.Digest]
+	if !ok {
+		return nil, registry.ErrNotFound
+	}
+	return data, nil
+}
+
+const testAttestationType = "application/vnd.in-toto+json"
+
+func newRecordingClient() *recordingClient {
+	attestation := []byte(`{"predicateType":"https://slsa.dev/provenance/v1"}`)
+	desc := ocispec.Descriptor{
+		MediaType:    testAttestationType,
+		ArtifactType: testAttestationType,
+		Digest:       digest.FromBytes(attestation),
+		Size:         int64(len(attestation)),
+	}
+	return &recordingClient{
+		referrers:   map[string][]ocispec.Descriptor{testAttestationType: {desc}},
+		descriptors: map[digest.Digest][]byte{desc.Digest: attestation},
+	}
+}
+
+// attestationPolicy lists attestation referrers and fetches each one, as the
+// sigstore, slsa, and gittuf policies do.
+func attestationPolicy(verdict error) registry.Policy {
+	return registry.PolicyFunc(func(ctx context.Context, req registry.PolicyRequest) error {
+		referrers, err := req.Client.Referrers(ctx, req.Ref, req.Subject, testAttestationType)
+		if err != nil {
+			return err
+		}
+		for _, ref := range referrers {
+			if _, err := req.Client.FetchDescriptor(ctx, req.Ref, ref); err != nil {
+				return err
+			}
+		}
+		return verdict
+	})
+}
+
+func fetchRequest(client registry.PolicyClient) registry.PolicyRequest {
+	manifest := digest.FromString("manifest")
+	return registry.PolicyRequest{
+		Ref:     "example.com/repo:v1",
+		Digest:  manifest.String(),
+		Subject: ocispec.Descriptor{MediaType: ocispec.MediaTypeImageManifest, Digest: manifest},
+		Client:  client,
+	}
+}
+
+func TestSharedFetches(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+
+	for _, n := range []int{1, 3, 10} {
+		client := newRecordingClient()
+		policies := make([]registry.Policy, n)
+		for i := range policies {
+			policies[i] = attestationPolicy(nil)
+		}
+
+		require.NoError(t, RequireAll(policies...).Evaluate(ctx, fetchRequest(client)))
+		assert.Equal(t, int32(1), client.referrerCalls.Load(), "%d policies", n)
+		assert.Equal(t, int32(1), client.fetchCalls.Load(), "%d policies", n)
+	}
+
+	t.Run("nested compositions share one cache", func(t *testing.T) {
+		t.Parallel()
+
+		client := newRecordingClient()
+		p := RequireAll(
+			attestationPolicy(nil),
+			RequireAny(attestationPolicy(errors.New("builder mismatch")), attestationPolicy(nil)),
+			Not(attestationPolicy(errors.New("revoked"))),
+		)
+		require.NoError(t, p.Evaluate(ctx, fetchRequest(client)))
+		assert.Equal(t, int32(1), client.referrerCalls.Load())
+		assert.Equal(t, int32(1), client.fetchCalls.Load())
+	})
+
+	t.Run("each evaluation fetches again", func(t *testing.T) {
+		t.Parallel()
+
+		client := newRecordingClient()
+		p := RequireAll(attestationPolicy(nil), attestationPolicy(nil))
+		require.NoError(t, p.Evaluate(ctx, fetchRequest(client)))
+		require.NoError(t, p.Evaluate(ctx, fetchRequest(client)))
+		assert.Equal(t, int32(2), client.referrerCalls.Load())
+	})
+
+	t.Run("errors are shared", func(t *testing.T) {
+		t.Parallel()
+
+		client := newRecordingClient()
+		client.descriptors = nil
+		err := RequireAny(attestationPolicy(nil), attestationPolicy(nil)).Evaluate(ctx, fetchRequest(client))
+		require.ErrorIs(t, err, registry.ErrNotFound)
+		assert.Equal(t, int32(1), client.fetchCalls.Load())
+	})
+}
+
+func TestFetchCache_Concurrent(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	client := newRecordingClient()
+	client.release = make(chan struct{})
+	req := registry.ShareFetches(fetchRequest(client))
+
+	const n = 8
+	var wg sync.WaitGroup
+	errs := make([]error, n)
+	for i := range n {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			errs[i] = attestationPolicy(nil).Evaluate(ctx, req)
+		}()
+	}
+	// Hold the first fetch open; the other goroutines wait for it or reuse
+	// its result.
+	require.Eventually(t, func() bool { return client.referrerCalls.Load() == 1 }, time.Second, time.Millisecond)
+	close(client.release)
+	wg.Wait()
+
+	for _, err := range errs {
+		require.NoError(t, err)
+	}
+	assert.Equal(t, int32(1), client.referrerCalls.Load())
+	assert.Equal(t, int32(1), client.fetchCalls.Load())
+}
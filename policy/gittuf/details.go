@@ -0,0 +1,228 @@
+package gittuf
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+
+	gittuflib "github.com/gittuf/gittuf/experimental/gittuf"
+	"github.com/gittuf/gittuf/pkg/gitinterface"
+	"github.com/secure-systems-lab/go-securesystemslib/signerverifier"
+)
+
+// RSL entry format, as written by gittuf.
+const (
+	rslRef                    = "refs/gittuf/reference-state-log"
+	rslReferenceEntryHeader   = "RSL Reference Entry"
+	rslPropagationEntryHeader = "RSL Propagation Entry"
+	rslAnnotationEntryHeader  = "RSL Annotation Entry"
+	rslBeginMessage           = "-----BEGIN MESSAGE-----"
+)
+
+// errNoRSLEntry indicates the RSL has no entry recording the verified ref.
+var errNoRSLEntry = errors.New("gittuf: no RSL entry for ref")
+
+// VerificationDetails describes what a successful gittuf verification
+// checked. It is available from [Policy.VerificationDetails] after
+// [Policy.Evaluate] accepts a manifest.
+type VerificationDetails struct {
+	// Repository is the source repository URL.
+	Repository string
+
+	// Ref is the fully qualified git reference that was verified.
+	Ref string
+
+	// RSLEntryID is the commit ID of the latest Reference State Log entry
+	// for Ref.
+	RSLEntryID string
+
+	// TargetID is the object ID the RSL entry records for Ref.
+	TargetID string
+
+	// PrincipalIDs lists the principals authorized for Ref by the gittuf
+	// policy whose keys signed the RSL entry, sorted.
+	PrincipalIDs []string
+
+	// KeyIDs lists the IDs of the keys that signed the RSL entry, sorted.
+	KeyIDs []string
+}
+
+// rslEntry is the subset of an RSL entry needed to report verification
+// details.
+type rslEntry struct {
+	id       gitinterface.Hash
+	header   string
+	ref      string
+	targetID string
+	entryIDs []string
+	skip     bool
+}
+
+// resolveVerificationDetails finds the latest RSL entry for ref and the
+// authorized keys that signed it.
+func resolveVerificationDetails(ctx context.Context, repo *gittuflib.Repository, ref string) (*VerificationDetails, error) {
+	git := repo.GetGitRepository()
+
+	absRef, err := git.AbsoluteReference(ref)
+	if err != nil {
+		return nil, fmt.Errorf("gittuf: resolve ref %s: %w", ref, err)
+	}
+
+	entry, err := latestRSLEntry(git, absRef)
+	if err != nil {
+		return nil, err
+	}
+
+	authorized, err := authorizedPrincipals(ctx, repo, absRef)
+	if err != nil {
+		return nil, err
+	}
+
+	details := &VerificationDetails{
+		Ref:        absRef,
+		RSLEntryID: entry.id.String(),
+		TargetID:   entry.targetID,
+	}
+	for _, principal := range authorized {
+		signed := false
+		for _, key := range principal.Keys() {
+			if git.VerifySignature(ctx, entry.id, key) != nil {
+				continue
+			}
+			signed = true
+			details.KeyIDs = append(details.KeyIDs, key.KeyID)
+		}
+		if signed {
+			details.PrincipalIDs = append(details.PrincipalIDs, principal.ID())
+		}
+	}
+	slices.Sort(details.KeyIDs)
+	details.KeyIDs = slices.Compact(details.KeyIDs)
+
+	return details, nil
+}
+
+// latestRSLEntry walks the RSL from its tip and returns the newest
+// reference entry for ref that has not been skipped by an annotation.
+func latestRSLEntry(git *gitinterface.Repository, ref string) (*rslEntry, error) {
+	id, err := git.GetReference(rslRef)
+	if err != nil {
+		return nil, fmt.Errorf("gittuf: read RSL: %w", err)
+	}
+
+	skipped := make(map[string]bool)
+	for {
+		message, err := git.GetCommitMessage(id)
+		if err != nil {
+			return nil, fmt.Errorf("gittuf: read RSL entry %s: %w", id, err)
+		}
+		entry := parseRSLEntry(id, message)
+
+		switch entry.header {
+		case rslAnnotationEntryHeader:
+			if entry.skip {
+				for _, skippedID := range entry.entryIDs {
+					skipped[skippedID] = true
+				}
+			}
+		case rslReferenceEntryHeader, rslPropagationEntryHeader:
+			if entry.ref == ref && !skipped[id.String()] {
+				return entry, nil
+			}
+		}
+
+		parents, err := git.GetCommitParentIDs(id)
+		if err != nil {
+			return nil, fmt.Errorf("gittuf: read RSL entry %s: %w", id, err)
+		}
+		if len(parents) == 0 {
+			return nil, fmt.Errorf("%w: %s", errNoRSLEntry, ref)
+		}
+		id = parents[0]
+	}
+}
+
+// parseRSLEntry parses the header and key-value lines of an RSL entry
+// commit message. Unknown headers yield an entry that matches nothing.
+func parseRSLEntry(id gitinterface.Hash, message string) *rslEntry {
+	header, body, _ := strings.Cut(message, "\n")
+	entry := &rslEntry{id: id, header: strings.TrimSpace(header)}
+
+	for _, line := range strings.Split(body, "\n") {
+		line = strings.TrimSpace(line)
+		if line == rslBeginMessage {
+			break
+		}
+		key, value, ok := strings.Cut(line, ":")
+		if !ok {
+			continue
+		}
+		value = strings.TrimSpace(value)
+		switch strings.TrimSpace(key) {
+		case "ref":
+			entry.ref = value
+		case "targetID":
+			entry.targetID = value
+		case "entryID":
+			entry.entryIDs = append(entry.entryIDs, value)
+		case "skip":
+			entry.skip = value == "true"
+		}
+	}
+	return entry
+}
+
+// principal is the subset of a gittuf principal used to match signers.
+type principal interface {
+	ID() string
+	Keys() []*signerverifier.SSLibKey
+}
+
+// authorizedPrincipals returns the principals trusted by the rules that
+// protect ref, sorted by ID. Principals are declared in the top-level
+// targets metadata or in the metadata of a delegating rule.
+func authorizedPrincipals(ctx context.Context, repo *gittuflib.Repository, ref string) ([]principal, error) {
+	rules, err := repo.ListRules(ctx, "policy")
+	if err != nil {
+		return nil, fmt.Errorf("gittuf: list policy rules: %w", err)
+	}
+
+	known := make(map[string]principal)
+	addPrincipals := func(role string) {
+		principals, err := repo.ListPrincipals(ctx, "policy", role)
+		if err != nil {
+			return
+		}
+		for id, p := range principals {
+			known[id] = p
+		}
+	}
+	addPrincipals("targets")
+
+	authorizedIDs := make(map[string]bool)
+	for _, rule := range rules {
+		addPrincipals(rule.Delegation.ID())
+		if !rule.Delegation.Matches("git:" + ref) {
+			continue
+		}
+		for _, id := range rule.Delegation.GetPrincipalIDs().Contents() {
+			authorizedIDs[id] = true
+		}
+	}
+
+	ids := make([]string, 0, len(authorizedIDs))
+	for id := range authorizedIDs {
+		if _, ok := known[id]; ok {
+			ids = append(ids, id)
+		}
+	}
+	slices.Sort(ids)
+
+	authorized := make([]principal, 0, len(ids))
+	for _, id := range ids {
+		authorized = append(authorized, known[id])
+	}
+	return authorized, nil
+}
@@ -0,0 +1,173 @@
+package gittuf
+
+import (
+	"context"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	gittuflib "github.com/gittuf/gittuf/experimental/gittuf"
+	rslopts "github.com/gittuf/gittuf/experimental/gittuf/options/rsl"
+	"github.com/opencontainers/go-digest"
+	ocispec "github.com/opencontainers/image-spec/specs-go/v1"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/meigma/blob/registry"
+)
+
+// gittufFixture is a local repository whose main branch is protected by a
+// gittuf policy and recorded in its RSL by a single SSH key.
+type gittufFixture struct {
+	dir         string
+	repo        *gittuflib.Repository
+	principalID string
+}
+
+// git runs a git command in the fixture repository and returns its
+// trimmed output.
+func (f *gittufFixture) git(t *testing.T, args ...string) string {
+	t.Helper()
+
+	cmd := exec.Command("git", args...)
+	cmd.Dir = f.dir
+	out, err := cmd.CombinedOutput()
+	require.NoError(t, err, string(out))
+	return strings.TrimSpace(string(out))
+}
+
+// commit creates a signed empty commit on main and records it in the RSL.
+func (f *gittufFixture) commit(t *testing.T, message string) {
+	t.Helper()
+
+	f.git(t, "commit", "-q", "--allow-empty", "-S", "-m", message)
+	require.NoError(t, f.repo.RecordRSLEntryForReference(context.Background(), "main", true, rslopts.WithRecordLocalOnly()))
+}
+
+// sliceOf builds a slice without naming its element type, which for gittuf
+// principals lives in an internal package.
+func sliceOf[T any](v ...T) []T { return v }
+
+// newGittufFixture creates a gittuf repository with a rule that only lets a
+// freshly generated SSH key update refs/heads/main.
+func newGittufFixture(t *testing.T) *gittufFixture {
+	t.Helper()
+
+	for _, tool := range []string{"git", "ssh-keygen"} {
+		if _, err := exec.LookPath(tool); err != nil {
+			t.Skipf("%s not available", tool)
+		}
+	}
+
+	ctx := context.Background()
+	f := &gittufFixture{dir: t.TempDir()}
+	keyPath := filepath.Join(t.TempDir(), "key")
+
+	out, err := exec.Command("ssh-keygen", "-t", "ecdsa", "-b", "256", "-N", "", "-q", "-f", keyPath).CombinedOutput()
+	require.NoError(t, err, string(out))
+	f.git(t, "init", "-q", "-b", "main")
+	f.git(t, "config", "user.name", "Test")
+	f.git(t, "config", "user.email", "test@example.com")
+	f.git(t, "config", "gpg.format", "ssh")
+	f.git(t, "config", "user.signingkey", keyPath)
+
+	f.repo, err = gittuflib.LoadRepository(f.dir)
+	require.NoError(t, err)
+	signer, err := gittuflib.LoadSigner(f.repo, keyPath)
+	require.NoError(t, err)
+	principal, err := gittuflib.LoadPublicKey(keyPath + ".pub")
+	require.NoError(t, err)
+	f.principalID = principal.ID()
+
+	require.NoError(t, f.repo.InitializeRoot(ctx, signer, false))
+	require.NoError(t, f.repo.AddTopLevelTargetsKey(ctx, signer, principal, false))
+	require.NoError(t, f.repo.InitializeTargets(ctx, signer, "targets", false))
+	require.NoError(t, f.repo.AddPrincipalToTargets(ctx, signer, "targets", sliceOf(principal), false))
+	require.NoError(t, f.repo.AddDelegation(ctx, signer, "targets", "protect-main",
+		[]string{f.principalID}, []string{"git:refs/heads/main"}, 1, false))
+	require.NoError(t, f.repo.StagePolicy(ctx, "", true, false))
+	require.NoError(t, f.repo.ApplyPolicy(ctx, "", true, false))
+
+	f.commit(t, "init")
+	require.NoError(t, f.repo.VerifyRef(ctx, "main"))
+
+	return f
+}
+
+func TestResolveVerificationDetails(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	f := newGittufFixture(t)
+
+	details, err := resolveVerificationDetails(ctx, f.repo, "main")
+	require.NoError(t, err)
+	assert.Equal(t, "refs/heads/main", details.Ref)
+	assert.Equal(t, f.git(t, "rev-parse", rslRef), details.RSLEntryID)
+	assert.Equal(t, f.git(t, "rev-parse", "main"), details.TargetID)
+	assert.Equal(t, []string{f.principalID}, details.PrincipalIDs)
+	assert.Equal(t, []string{f.principalID}, details.KeyIDs)
+
+	// A skipped entry is not reported; the previous entry for the ref is.
+	first := details
+	f.commit(t, "second")
+	second, err := resolveVerificationDetails(ctx, f.repo, "main")
+	require.NoError(t, err)
+	assert.NotEqual(t, first.RSLEntryID, second.RSLEntryID)
+
+	require.NoError(t, f.repo.RecordRSLAnnotation(ctx, []string{second.RSLEntryID}, true, "revert", true,
+		rslopts.WithAnnotateLocalOnly()))
+	details, err = resolveVerificationDetails(ctx, f.repo, "main")
+	require.NoError(t, err)
+	assert.Equal(t, first.RSLEntryID, details.RSLEntryID)
+	assert.Equal(t, first.TargetID, details.TargetID)
+
+	_, err = resolveVerificationDetails(ctx, f.repo, "refs/heads/missing")
+	require.Error(t, err)
+}
+
+func TestPolicy_VerificationDetails(t *testing.T) {
+	t.Parallel()
+
+	p, err := NewPolicy(WithRepository("https://github.com/test/repo"), WithAllowMissingProvenance())
+	require.NoError(t, err)
+
+	manifestDigest := digest.FromString("manifest")
+	req := registry.PolicyRequest{
+		Ref:    "example.com/repo:tag",
+		Digest: manifestDigest.String(),
+		Subject: ocispec.Descriptor{
+			MediaType: "application/vnd.oci.image.manifest.v1+json",
+			Digest:    manifestDigest,
+			Size:      100,
+		},
+		Client: &mockPolicyClient{},
+	}
+
+	// Skipped verification records no details.
+	require.NoError(t, p.Evaluate(context.Background(), req))
+	_, ok := p.VerificationDetails(req.Digest)
+	assert.False(t, ok)
+
+	want := &VerificationDetails{Repository: "https://github.com/test/repo", Ref: "refs/heads/main"}
+	p.details[req.Digest] = want
+	got, ok := p.VerificationDetails(req.Digest)
+	require.True(t, ok)
+	assert.Same(t, want, got)
+}
+
+func TestParseRSLEntry(t *testing.T) {
+	t.Parallel()
+
+	entry := parseRSLEntry(nil, "RSL Reference Entry\n\nref: refs/heads/main\ntargetID: abc\nnumber: 3")
+	assert.Equal(t, rslReferenceEntryHeader, entry.header)
+	assert.Equal(t, "refs/heads/main", entry.ref)
+	assert.Equal(t, "abc", entry.targetID)
+
+	entry = parseRSLEntry(nil, "RSL Annotation Entry\n\nentryID: a\nentryID: b\nskip: true\n"+
+		"-----BEGIN MESSAGE-----\nskip: false\n-----END MESSAGE-----")
+	assert.Equal(t, rslAnnotationEntryHeader, entry.header)
+	assert.Equal(t, []string{"a", "b"}, entry.entryIDs)
+	assert.True(t, entry.skip)
+}
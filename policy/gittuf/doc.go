@@ -69,6 +69,18 @@
 //	    gittuf.WithCacheTTL(2 * time.Hour),
 //	)
 //
+// # Verification Details
+//
+// After [Policy.Evaluate] accepts a manifest, [Policy.VerificationDetails]
+// reports the RSL entry that recorded the verified ref and which authorized
+// principals and keys signed it:
+//
+//	if err := policy.Evaluate(ctx, req); err == nil {
+//	    if d, ok := policy.VerificationDetails(req.Digest); ok {
+//	        log.Printf("%s at %s signed by %v", d.Ref, d.RSLEntryID, d.KeyIDs)
+//	    }
+//	}
+//
 // # Trust Model
 //
 // By default, the policy uses Trust On First Use (TOFU) for gittuf root keys.
@@ -1,6 +1,10 @@
 package gittuf
 
-import "errors"
+import (
+	"errors"
+
+	blobpolicy "github.com/meigma/blob/policy"
+)
 
 // Sentinel errors for gittuf policy validation.
 var (
@@ -31,3 +35,8 @@ var (
 	// ErrPullRSLFailed indicates refreshing the RSL from remote failed.
 	ErrPullRSLFailed = errors.New("gittuf: pull RSL failed")
 )
+
+// verificationError returns a policy.VerificationError from the gittuf policy.
+func verificationError(reason blobpolicy.Reason, subject string, err error) error {
+	return &blobpolicy.VerificationError{Policy: "gittuf", Reason: reason, Subject: subject, Err: err}
+}
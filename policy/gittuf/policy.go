@@ -7,10 +7,12 @@ import (
 	"errors"
 	"fmt"
 	"log/slog"
+	"sync"
 
 	verifyopts "github.com/gittuf/gittuf/experimental/gittuf/options/verify"
 	ocispec "github.com/opencontainers/image-spec/specs-go/v1"
 
+	blobpolicy "github.com/meigma/blob/policy"
 	"github.com/meigma/blob/registry"
 )
 
@@ -42,6 +44,11 @@ type Policy struct {
 	// Graceful degradation
 	allowMissingGittuf     bool
 	allowMissingProvenance bool
+
+	// details records the verification details of accepted manifests,
+	// keyed by manifest digest.
+	detailsMu sync.Mutex
+	details   map[string]*VerificationDetails
 }
 
 // NewPolicy creates a gittuf source provenance policy.
@@ -50,6 +57,7 @@ func NewPolicy(opts ...PolicyOption) (*Policy, error) {
 		cache:      DefaultCache(),
 		logger:     slog.New(slog.DiscardHandler),
 		latestOnly: true, // Faster verification by default
+		details:    make(map[string]*VerificationDetails),
 	}
 
 	for _, opt := range opts {
@@ -107,7 +115,7 @@ func (p *Policy) Evaluate(ctx context.Context, req registry.PolicyRequest) error
 				slog.Any("error", err))
 			return nil
 		}
-		return fmt.Errorf("%w: %v", ErrCloneFailed, err)
+		return verificationError(blobpolicy.ReasonFetchFailed, p.repoURL, fmt.Errorf("%w: %v", ErrCloneFailed, err))
 	}
 
 	// 3. Check if repository has gittuf enabled
@@ -121,7 +129,7 @@ func (p *Policy) Evaluate(ctx context.Context, req registry.PolicyRequest) error
 				slog.String("repo", p.repoURL))
 			return nil
 		}
-		return ErrNoGittufPolicy
+		return verificationError(blobpolicy.ReasonPolicyDenied, p.repoURL, ErrNoGittufPolicy)
 	}
 
 	// 4. Refresh RSL from remote
@@ -137,7 +145,7 @@ func (p *Policy) Evaluate(ctx context.Context, req registry.PolicyRequest) error
 		refToVerify = p.overrideRef
 	}
 	if refToVerify == "" {
-		return ErrNoRefToVerify
+		return verificationError(blobpolicy.ReasonEvaluationFailed, "", ErrNoRefToVerify)
 	}
 
 	// 6. Build verify options
@@ -152,15 +160,41 @@ func (p *Policy) Evaluate(ctx context.Context, req registry.PolicyRequest) error
 		slog.Bool("latest_only", p.latestOnly))
 
 	if err := repo.VerifyRef(ctx, refToVerify, verifyOpts...); err != nil {
-		return fmt.Errorf("%w: %v", ErrVerificationFailed, err)
+		return verificationError(blobpolicy.ReasonPolicyDenied, refToVerify, fmt.Errorf("%w: %v", ErrVerificationFailed, err))
 	}
 
 	p.logger.Debug("gittuf verification successful",
 		slog.String("ref", refToVerify))
 
+	// 8. Record which RSL entry and authorized keys were verified
+	details, err := resolveVerificationDetails(ctx, repo, refToVerify)
+	if err != nil {
+		p.logger.Warn("failed to resolve gittuf verification details",
+			slog.String("ref", refToVerify),
+			slog.Any("error", err))
+		return nil
+	}
+	details.Repository = p.repoURL
+	p.detailsMu.Lock()
+	p.details[req.Digest] = details
+	p.detailsMu.Unlock()
+
 	return nil
 }
 
+// VerificationDetails returns the RSL entry and authorized signing keys
+// checked when [Policy.Evaluate] last accepted the manifest with the given
+// digest. The second return value is false if no successful verification
+// has been recorded for digest, including when verification was skipped by
+// [WithAllowMissingGittuf] or [WithAllowMissingProvenance].
+func (p *Policy) VerificationDetails(digest string) (*VerificationDetails, bool) {
+	p.detailsMu.Lock()
+	defer p.detailsMu.Unlock()
+
+	details, ok := p.details[digest]
+	return details, ok
+}
+
 // sourceInfo contains extracted source provenance information.
 type sourceInfo struct {
 	Repo   string // Source repository URL
@@ -179,7 +213,8 @@ func (p *Policy) extractSourceInfo(ctx context.Context, req registry.PolicyReque
 		referrers, err := req.Client.Referrers(ctx, req.Ref, req.Subject, artifactType)
 		if err != nil {
 			if errors.Is(err, registry.ErrReferrersUnsupported) {
-				return nil, fmt.Errorf("gittuf: registry does not support referrers API: %w", err)
+				return nil, verificationError(blobpolicy.ReasonReferrersUnsupported, "",
+					fmt.Errorf("gittuf: registry does not support referrers API: %w", err))
 			}
 			p.logger.Debug("failed to list referrers",
 				slog.String("artifact_type", artifactType),
@@ -195,7 +230,7 @@ func (p *Policy) extractSourceInfo(ctx context.Context, req registry.PolicyReque
 		}
 	}
 
-	return nil, ErrNoSLSAProvenance
+	return nil, verificationError(blobpolicy.ReasonNoAttestation, "", ErrNoSLSAProvenance)
 }
 
 // tryExtractSourceInfo attempts to extract source info from a single attestation.
@@ -4,6 +4,7 @@ import (
 	"context"
 	"encoding/base64"
 	"encoding/json"
+	"errors"
 	"os"
 	"path/filepath"
 	"testing"
@@ -14,6 +15,7 @@ import (
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 
+	blobpolicy "github.com/meigma/blob/policy"
 	"github.com/meigma/blob/registry"
 )
 
@@ -179,6 +181,7 @@ func TestPolicy_NoSLSAProvenance(t *testing.T) {
 
 	err = p.Evaluate(context.Background(), req)
 	require.ErrorIs(t, err, ErrNoSLSAProvenance)
+	assertReason(t, err, blobpolicy.ReasonNoAttestation)
 }
 
 func TestPolicy_AllowMissingProvenance(t *testing.T) {
@@ -238,6 +241,16 @@ func TestPolicy_ReferrersUnsupported(t *testing.T) {
 	err = p.Evaluate(context.Background(), req)
 	require.Error(t, err)
 	assert.Contains(t, err.Error(), "does not support referrers")
+	assertReason(t, err, blobpolicy.ReasonReferrersUnsupported)
+}
+
+func assertReason(t *testing.T, err error, want blobpolicy.Reason) {
+	t.Helper()
+
+	var verr *blobpolicy.VerificationError
+	require.True(t, errors.As(err, &verr), "expected *policy.VerificationError, got %T", err)
+	assert.Equal(t, "gittuf", verr.Policy)
+	assert.Equal(t, want, verr.Reason)
 }
 
 func TestPolicy_ExtractSourceInfo(t *testing.T) {
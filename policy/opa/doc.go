@@ -55,6 +55,19 @@
 //	    msg := "source must be from myorg"
 //	}
 //
+// # Policy Data
+//
+// External data such as allowlists can be supplied with WithData or
+// WithDataFile instead of being written into the Rego source:
+//
+//	policy, err := opa.NewPolicy(
+//	    opa.WithPolicyFile("policy.rego"),
+//	    opa.WithDataFile("trusted_builders.yaml"),
+//	)
+//
+// Each top-level key becomes a document under data, so a file containing
+// "trusted_builders: [...]" is read in Rego as data.trusted_builders.
+//
 // # Input Structure
 //
 // The Rego input has the following structure:
@@ -63,7 +76,19 @@
 //	    "manifest": {
 //	        "reference": "ghcr.io/myorg/myarchive:v1",
 //	        "digest": "sha256:abc123...",
-//	        "mediaType": "application/vnd.oci.image.manifest.v1+json"
+//	        "mediaType": "application/vnd.oci.image.manifest.v1+json",
+//	        "annotations": {
+//	            "org.opencontainers.image.source": "https://github.com/myorg/myrepo",
+//	            "org.opencontainers.image.created": "2024-01-02T03:04:05Z"
+//	        },
+//	        "layers": [
+//	            {
+//	                "mediaType": "application/vnd.meigma.blob.index.v1+flatbuffers",
+//	                "digest": "sha256:def456...",
+//	                "size": 1024
+//	            },
+//	            ...
+//	        ]
 //	    },
 //	    "attestations": [
 //	        {
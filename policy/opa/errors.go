@@ -1,6 +1,10 @@
 package opa
 
-import "errors"
+import (
+	"errors"
+
+	blobpolicy "github.com/meigma/blob/policy"
+)
 
 var (
 	// ErrNoPolicy indicates that no Rego policy was provided.
@@ -15,6 +19,15 @@ var (
 	// ErrPolicyEvaluation indicates a failure during policy evaluation.
 	ErrPolicyEvaluation = errors.New("opa: policy evaluation failed")
 
+	// ErrEvalTimeout indicates that policy evaluation exceeded the limit set
+	// with WithEvalTimeout.
+	ErrEvalTimeout = errors.New("opa: policy evaluation timed out")
+
 	// ErrInvalidAttestation indicates an attestation could not be parsed.
 	ErrInvalidAttestation = errors.New("opa: invalid attestation format")
 )
+
+// verificationError returns a policy.VerificationError from the opa policy.
+func verificationError(reason blobpolicy.Reason, subject string, err error) error {
+	return &blobpolicy.VerificationError{Policy: "opa", Reason: reason, Subject: subject, Err: err}
+}
@@ -19,6 +19,28 @@ type ManifestInput struct {
 
 	// MediaType is the manifest media type.
 	MediaType string `json:"mediaType"`
+
+	// Annotations are the manifest annotations, such as
+	// "org.opencontainers.image.source". Omitted when the manifest has none.
+	Annotations map[string]string `json:"annotations,omitempty"`
+
+	// Layers describes the manifest layers in manifest order.
+	Layers []DescriptorInput `json:"layers,omitempty"`
+}
+
+// DescriptorInput represents an OCI content descriptor.
+type DescriptorInput struct {
+	// MediaType is the media type of the referenced content.
+	MediaType string `json:"mediaType"`
+
+	// Digest is the content digest (e.g., "sha256:abc123...").
+	Digest string `json:"digest"`
+
+	// Size is the content size in bytes.
+	Size int64 `json:"size"`
+
+	// Annotations are the descriptor annotations. Omitted when empty.
+	Annotations map[string]string `json:"annotations,omitempty"`
 }
 
 // AttestationInput represents a parsed in-toto statement.
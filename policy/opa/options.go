@@ -1,11 +1,15 @@
 package opa
 
 import (
-	"context"
+	"fmt"
+	"io/fs"
 	"log/slog"
+	"maps"
 	"os"
+	"path/filepath"
+	"time"
 
-	"github.com/open-policy-agent/opa/v1/rego"
+	"github.com/open-policy-agent/opa/v1/util"
 )
 
 // PolicyOption configures a Policy.
@@ -13,16 +17,11 @@ type PolicyOption func(*Policy) error
 
 // WithPolicy compiles inline Rego source code.
 // The policy must define data.blob.policy.allow or data.blob.policy.deny rules.
+// Calling WithPolicy again replaces the inline source; modules loaded from
+// files, directories, and bundles are compiled alongside it.
 func WithPolicy(regoSource string) PolicyOption {
 	return func(p *Policy) error {
-		query, err := rego.New(
-			rego.Query("data.blob.policy"),
-			rego.Module("policy.rego", regoSource),
-		).PrepareForEval(context.Background())
-		if err != nil {
-			return err
-		}
-		p.query = &query
+		p.modules[inlineModuleName] = regoSource
 		return nil
 	}
 }
@@ -30,13 +29,90 @@ func WithPolicy(regoSource string) PolicyOption {
 // WithPolicyFile loads and compiles a Rego policy from a file.
 // The policy must define data.blob.policy.allow or data.blob.policy.deny rules.
 func WithPolicyFile(path string) PolicyOption {
+	return WithPolicyFiles(path)
+}
+
+// WithPolicyFiles loads Rego modules from files and compiles them together,
+// so rules in one module can use helpers defined in another. Compilation
+// errors name the offending file.
+func WithPolicyFiles(paths ...string) PolicyOption {
+	return func(p *Policy) error {
+		for _, path := range paths {
+			//nolint:gosec // path is intentionally user-provided for policy loading
+			data, err := os.ReadFile(path)
+			if err != nil {
+				return err
+			}
+			p.modules[path] = string(data)
+		}
+		return nil
+	}
+}
+
+// WithPolicyDir loads every ".rego" file under dir, including
+// subdirectories, as with WithPolicyFiles. A directory without Rego files
+// is an error.
+func WithPolicyDir(dir string) PolicyOption {
+	return func(p *Policy) error {
+		var paths []string
+		err := filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+			if err != nil {
+				return err
+			}
+			if !d.IsDir() && filepath.Ext(path) == ".rego" {
+				paths = append(paths, path)
+			}
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+		if len(paths) == 0 {
+			return fmt.Errorf("policy dir %s: no .rego files", dir)
+		}
+		return WithPolicyFiles(paths...)(p)
+	}
+}
+
+// WithPolicyBundle loads an OPA bundle, either a directory or a ".tar.gz"
+// file as built by "opa build". Its modules are compiled with any other
+// policy sources and its data documents are available to them.
+func WithPolicyBundle(path string) PolicyOption {
+	return func(p *Policy) error {
+		if _, err := os.Stat(path); err != nil {
+			return err
+		}
+		p.bundles = append(p.bundles, path)
+		return nil
+	}
+}
+
+// WithData adds documents to the OPA data document, so Rego can read them
+// as data.<key>; for example, {"trusted_builders": [...]} is available as
+// data.trusted_builders. This keeps allowlists and other policy data out of
+// the Rego source. Keys from later WithData and WithDataFile options replace
+// earlier ones. Values must be JSON-compatible.
+func WithData(data map[string]any) PolicyOption {
+	return func(p *Policy) error {
+		maps.Copy(p.data, data)
+		return nil
+	}
+}
+
+// WithDataFile loads a JSON or YAML object from path and adds its keys to
+// the data document as with WithData.
+func WithDataFile(path string) PolicyOption {
 	return func(p *Policy) error {
 		//nolint:gosec // path is intentionally user-provided for policy loading
-		data, err := os.ReadFile(path)
+		raw, err := os.ReadFile(path)
 		if err != nil {
 			return err
 		}
-		return WithPolicy(string(data))(p)
+		var data map[string]any
+		if err := util.Unmarshal(raw, &data); err != nil {
+			return fmt.Errorf("data file %s: %w", path, err)
+		}
+		return WithData(data)(p)
 	}
 }
 
@@ -59,6 +135,34 @@ func WithPredicateTypes(types ...string) PolicyOption {
 	}
 }
 
+// WithEvalTimeout bounds how long a single Rego evaluation may run. An
+// evaluation still running after d is cancelled and Evaluate returns
+// ErrEvalTimeout. Zero, the default, applies no limit beyond the caller's
+// context.
+func WithEvalTimeout(d time.Duration) PolicyOption {
+	return func(p *Policy) error {
+		if d < 0 {
+			return fmt.Errorf("negative eval timeout %s", d)
+		}
+		p.evalTimeout = d
+		return nil
+	}
+}
+
+// WithMaxAttestations caps how many attestations are fetched and passed to
+// the Rego input. Referrers are processed in the order the registry lists
+// them and fetching stops once n matching attestations are collected. Zero,
+// the default, applies no limit.
+func WithMaxAttestations(n int) PolicyOption {
+	return func(p *Policy) error {
+		if n < 0 {
+			return fmt.Errorf("negative max attestations %d", n)
+		}
+		p.maxAttestations = n
+		return nil
+	}
+}
+
 // WithLogger sets a custom logger for the policy.
 func WithLogger(logger *slog.Logger) PolicyOption {
 	return func(p *Policy) error {
@@ -2,19 +2,29 @@ package opa
 
 import (
 	"context"
+	"encoding/json"
 	"errors"
 	"fmt"
 	"log/slog"
+	"maps"
+	"slices"
+	"time"
 
 	"github.com/open-policy-agent/opa/v1/rego"
+	"github.com/open-policy-agent/opa/v1/storage/inmem"
 	ocispec "github.com/opencontainers/image-spec/specs-go/v1"
 
+	blobpolicy "github.com/meigma/blob/policy"
 	"github.com/meigma/blob/registry"
 )
 
 // DefaultArtifactType is the OCI artifact type for in-toto attestations.
 const DefaultArtifactType = "application/vnd.in-toto+json"
 
+// inlineModuleName is the file name given to WithPolicy source in
+// compilation errors.
+const inlineModuleName = "policy.rego"
+
 // Default SLSA provenance predicate types.
 var defaultPredicateTypes = []string{
 	"https://slsa.dev/provenance/v1",
@@ -25,10 +35,15 @@ var defaultPredicateTypes = []string{
 // It fetches in-toto attestation referrers from the registry and evaluates
 // them against a compiled Rego policy.
 type Policy struct {
-	query          *rego.PreparedEvalQuery
-	artifactType   string
-	predicateTypes []string
-	logger         *slog.Logger
+	query           *rego.PreparedEvalQuery
+	modules         map[string]string // file name -> Rego source
+	bundles         []string
+	data            map[string]any // data document from WithData
+	artifactType    string
+	predicateTypes  []string
+	evalTimeout     time.Duration
+	maxAttestations int
+	logger          *slog.Logger
 }
 
 // NewPolicy creates an OPA-based attestation validation policy.
@@ -36,6 +51,8 @@ func NewPolicy(opts ...PolicyOption) (*Policy, error) {
 	p := &Policy{
 		artifactType:   DefaultArtifactType,
 		predicateTypes: defaultPredicateTypes,
+		modules:        make(map[string]string),
+		data:           make(map[string]any),
 		logger:         slog.New(slog.DiscardHandler),
 	}
 
@@ -45,13 +62,38 @@ func NewPolicy(opts ...PolicyOption) (*Policy, error) {
 		}
 	}
 
-	if p.query == nil {
+	if len(p.modules) == 0 && len(p.bundles) == 0 {
 		return nil, ErrNoPolicy
 	}
 
+	if err := p.prepare(); err != nil {
+		return nil, fmt.Errorf("opa: %w", err)
+	}
+
 	return p, nil
 }
 
+// prepare compiles all policy sources into one query.
+func (p *Policy) prepare() error {
+	args := []func(*rego.Rego){rego.Query("data.blob.policy")}
+	for _, name := range slices.Sorted(maps.Keys(p.modules)) {
+		args = append(args, rego.Module(name, p.modules[name]))
+	}
+	for _, path := range p.bundles {
+		args = append(args, rego.LoadBundle(path))
+	}
+	if len(p.data) > 0 {
+		args = append(args, rego.Store(inmem.NewFromObject(p.data)))
+	}
+
+	query, err := rego.New(args...).PrepareForEval(context.Background())
+	if err != nil {
+		return err
+	}
+	p.query = &query
+	return nil
+}
+
 // Evaluate implements registry.Policy.
 //
 //nolint:gocritic // req passed by value per registry.Policy interface contract
@@ -65,9 +107,10 @@ func (p *Policy) Evaluate(ctx context.Context, req registry.PolicyRequest) error
 	referrers, err := req.Client.Referrers(ctx, req.Ref, req.Subject, p.artifactType)
 	if err != nil {
 		if errors.Is(err, registry.ErrReferrersUnsupported) {
-			return errors.New("opa: registry does not support referrers API")
+			return verificationError(blobpolicy.ReasonReferrersUnsupported, "",
+				errors.New("opa: registry does not support referrers API"))
 		}
-		return fmt.Errorf("opa: list referrers: %w", err)
+		return verificationError(blobpolicy.ReasonFetchFailed, "", fmt.Errorf("opa: list referrers: %w", err))
 	}
 
 	p.logger.Debug("opa: found attestation referrers",
@@ -76,7 +119,7 @@ func (p *Policy) Evaluate(ctx context.Context, req registry.PolicyRequest) error
 	// Collect and parse attestations
 	attestations := p.fetchAttestations(ctx, req, referrers)
 	if len(attestations) == 0 {
-		return ErrNoAttestations
+		return verificationError(blobpolicy.ReasonNoAttestation, "", ErrNoAttestations)
 	}
 
 	p.logger.Debug("opa: parsed attestations",
@@ -84,11 +127,7 @@ func (p *Policy) Evaluate(ctx context.Context, req registry.PolicyRequest) error
 
 	// Build Rego input
 	input := Input{
-		Manifest: ManifestInput{
-			Reference: req.Ref,
-			Digest:    req.Digest,
-			MediaType: req.Subject.MediaType,
-		},
+		Manifest:     p.manifestInput(ctx, req),
 		Attestations: attestations,
 	}
 
@@ -96,6 +135,50 @@ func (p *Policy) Evaluate(ctx context.Context, req registry.PolicyRequest) error
 	return p.evaluatePolicy(ctx, input)
 }
 
+// manifestInput builds the manifest section of the Rego input. Annotations
+// and layers come from req.Manifest; when it is not set, the subject
+// manifest is fetched instead. A manifest that cannot be fetched or parsed
+// leaves them empty.
+//
+//nolint:gocritic // req passed by value per registry.Policy interface contract
+func (p *Policy) manifestInput(ctx context.Context, req registry.PolicyRequest) ManifestInput {
+	in := ManifestInput{
+		Reference: req.Ref,
+		Digest:    req.Digest,
+		MediaType: req.Subject.MediaType,
+	}
+
+	var manifest ocispec.Manifest
+	if req.Manifest != nil {
+		manifest = req.Manifest.Raw()
+	} else {
+		data, err := req.Client.FetchDescriptor(ctx, req.Ref, req.Subject)
+		if err != nil {
+			p.logger.Warn("opa: failed to fetch manifest for input",
+				slog.String("digest", req.Subject.Digest.String()),
+				slog.Any("error", err))
+			return in
+		}
+		if err := json.Unmarshal(data, &manifest); err != nil {
+			p.logger.Warn("opa: failed to parse manifest for input",
+				slog.String("digest", req.Subject.Digest.String()),
+				slog.Any("error", err))
+			return in
+		}
+	}
+
+	in.Annotations = manifest.Annotations
+	for _, layer := range manifest.Layers {
+		in.Layers = append(in.Layers, DescriptorInput{
+			MediaType:   layer.MediaType,
+			Digest:      layer.Digest.String(),
+			Size:        layer.Size,
+			Annotations: layer.Annotations,
+		})
+	}
+	return in
+}
+
 // fetchAttestations retrieves and parses attestations from referrers.
 // For OCI image manifests (like Sigstore bundles), it fetches the layers containing
 // the actual attestation content.
@@ -105,6 +188,12 @@ func (p *Policy) fetchAttestations(ctx context.Context, req registry.PolicyReque
 	attestations := make([]AttestationInput, 0, len(referrers))
 
 	for _, ref := range referrers {
+		if p.maxAttestations > 0 && len(attestations) >= p.maxAttestations {
+			p.logger.Debug("opa: attestation limit reached, skipping remaining referrers",
+				slog.Int("limit", p.maxAttestations),
+				slog.Int("referrers", len(referrers)))
+			break
+		}
 		atts := p.fetchAttestationFromReferrer(ctx, req, ref)
 		for _, att := range atts {
 			if !matchesPredicateType(&att, p.predicateTypes) {
@@ -116,6 +205,9 @@ func (p *Policy) fetchAttestations(ctx context.Context, req registry.PolicyReque
 		}
 	}
 
+	if p.maxAttestations > 0 && len(attestations) > p.maxAttestations {
+		attestations = attestations[:p.maxAttestations]
+	}
 	return attestations
 }
 
@@ -183,25 +275,38 @@ func (p *Policy) fetchAttestationsFromLayers(ctx context.Context, req registry.P
 func (p *Policy) evaluatePolicy(ctx context.Context, input Input) error {
 	p.logger.Debug("opa: evaluating rego policy")
 
-	results, err := p.query.Eval(ctx, rego.EvalInput(input))
+	evalCtx := ctx
+	if p.evalTimeout > 0 {
+		var cancel context.CancelFunc
+		evalCtx, cancel = context.WithTimeout(ctx, p.evalTimeout)
+		defer cancel()
+	}
+
+	results, err := p.query.Eval(evalCtx, rego.EvalInput(input))
 	if err != nil {
-		return fmt.Errorf("%w: %v", ErrPolicyEvaluation, err)
+		if ctx.Err() == nil && errors.Is(evalCtx.Err(), context.DeadlineExceeded) {
+			return verificationError(blobpolicy.ReasonEvaluationFailed, "",
+				fmt.Errorf("%w after %s", ErrEvalTimeout, p.evalTimeout))
+		}
+		return verificationError(blobpolicy.ReasonEvaluationFailed, "", fmt.Errorf("%w: %v", ErrPolicyEvaluation, err))
 	}
 
 	if len(results) == 0 {
-		return fmt.Errorf("%w: no results from policy evaluation", ErrPolicyEvaluation)
+		return verificationError(blobpolicy.ReasonEvaluationFailed, "",
+			fmt.Errorf("%w: no results from policy evaluation", ErrPolicyEvaluation))
 	}
 
 	// Extract the policy result
 	result, ok := results[0].Expressions[0].Value.(map[string]any)
 	if !ok {
-		return fmt.Errorf("%w: unexpected result type", ErrPolicyEvaluation)
+		return verificationError(blobpolicy.ReasonEvaluationFailed, "",
+			fmt.Errorf("%w: unexpected result type", ErrPolicyEvaluation))
 	}
 
 	err = checkPolicyResult(result)
 	if err != nil {
 		p.logger.Debug("opa: policy denied", slog.Any("error", err))
-		return err
+		return verificationError(blobpolicy.ReasonPolicyDenied, input.Manifest.Digest, err)
 	}
 
 	p.logger.Info("opa: policy allowed")
@@ -4,13 +4,18 @@ import (
 	"context"
 	"encoding/base64"
 	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
 	"testing"
+	"time"
 
 	"github.com/opencontainers/go-digest"
 	ocispec "github.com/opencontainers/image-spec/specs-go/v1"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 
+	blobpolicy "github.com/meigma/blob/policy"
 	"github.com/meigma/blob/registry"
 )
 
@@ -20,6 +25,7 @@ type mockPolicyClient struct {
 	referrerErr error
 	descriptors map[string][]byte
 	fetchErr    error
+	fetches     int
 }
 
 //nolint:gocritic // implements registry.PolicyClient interface
@@ -32,6 +38,7 @@ func (m *mockPolicyClient) Referrers(_ context.Context, _ string, _ ocispec.Desc
 
 //nolint:gocritic // implements registry.PolicyClient interface
 func (m *mockPolicyClient) FetchDescriptor(_ context.Context, _ string, desc ocispec.Descriptor) ([]byte, error) {
+	m.fetches++
 	if m.fetchErr != nil {
 		return nil, m.fetchErr
 	}
@@ -530,3 +537,332 @@ func TestMatchesPredicateType(t *testing.T) {
 		})
 	}
 }
+
+// newAttestationsClient returns a client listing n SLSA attestations.
+func newAttestationsClient(n int) *mockPolicyClient {
+	client := &mockPolicyClient{descriptors: make(map[string][]byte, n)}
+	for i := range n {
+		envelope := createDSSEEnvelope(createSLSAStatement(fmt.Sprintf("https://builder/%d", i)))
+		d := digest.FromBytes(envelope)
+		client.referrers = append(client.referrers, ocispec.Descriptor{
+			MediaType:    DefaultArtifactType,
+			Digest:       d,
+			Size:         int64(len(envelope)),
+			ArtifactType: DefaultArtifactType,
+		})
+		client.descriptors[d.String()] = envelope
+	}
+	return client
+}
+
+func newPolicyRequest(client registry.PolicyClient) registry.PolicyRequest {
+	manifestDigest := digest.FromString("manifest")
+	return registry.PolicyRequest{
+		Ref:    "example.com/repo:tag",
+		Digest: manifestDigest.String(),
+		Subject: ocispec.Descriptor{
+			MediaType: "application/vnd.oci.image.manifest.v1+json",
+			Digest:    manifestDigest,
+			Size:      100,
+		},
+		Client: client,
+	}
+}
+
+func TestPolicy_EvalTimeout(t *testing.T) {
+	t.Parallel()
+
+	// Ten billion iterations; the timeout cancels long before the end.
+	slow := `
+		package blob.policy
+		import rego.v1
+
+		default allow := false
+
+		allow if {
+			some i in numbers.range(1, 100000)
+			some j in numbers.range(1, 100000)
+			i == j + 1000000
+		}
+	`
+	policy, err := NewPolicy(WithPolicy(slow), WithEvalTimeout(50*time.Millisecond))
+	require.NoError(t, err)
+
+	start := time.Now()
+	err = policy.Evaluate(context.Background(), newPolicyRequest(newAttestationsClient(1)))
+	require.ErrorIs(t, err, ErrEvalTimeout)
+	assert.Less(t, time.Since(start), 10*time.Second)
+
+	t.Run("caller cancellation is not a timeout", func(t *testing.T) {
+		t.Parallel()
+
+		ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+		defer cancel()
+		policy, err := NewPolicy(WithPolicy(slow), WithEvalTimeout(time.Hour))
+		require.NoError(t, err)
+		err = policy.Evaluate(ctx, newPolicyRequest(newAttestationsClient(1)))
+		require.ErrorIs(t, err, ErrPolicyEvaluation)
+		assert.NotErrorIs(t, err, ErrEvalTimeout)
+	})
+
+	t.Run("negative", func(t *testing.T) {
+		t.Parallel()
+
+		_, err := NewPolicy(WithPolicy(slow), WithEvalTimeout(-time.Second))
+		require.Error(t, err)
+	})
+}
+
+func TestPolicy_MaxAttestations(t *testing.T) {
+	t.Parallel()
+
+	policy, err := NewPolicy(WithPolicy(`
+		package blob.policy
+		import rego.v1
+
+		default allow := false
+
+		allow if count(input.attestations) == 3
+	`), WithMaxAttestations(3))
+	require.NoError(t, err)
+
+	client := newAttestationsClient(50)
+	require.NoError(t, policy.Evaluate(context.Background(), newPolicyRequest(client)))
+	// Three attestations plus the subject manifest for the input.
+	assert.Equal(t, 4, client.fetches, "fetching should stop at the limit")
+
+	_, err = NewPolicy(WithPolicy("package blob.policy"), WithMaxAttestations(-1))
+	require.Error(t, err)
+}
+
+func TestPolicy_ManifestInput(t *testing.T) {
+	t.Parallel()
+
+	policy, err := NewPolicy(WithPolicy(`
+		package blob.policy
+		import rego.v1
+
+		default allow := false
+
+		allow if {
+			input.manifest.annotations["org.opencontainers.image.source"] == "https://github.com/myorg/myrepo"
+			some layer in input.manifest.layers
+			layer.mediaType == "application/vnd.meigma.blob.data.v1"
+		}
+	`))
+	require.NoError(t, err)
+
+	withManifest := func(annotations map[string]string) *mockPolicyClient {
+		client := newAttestationsClient(1)
+		manifest, err := json.Marshal(ocispec.Manifest{
+			MediaType:   ocispec.MediaTypeImageManifest,
+			Annotations: annotations,
+			Layers: []ocispec.Descriptor{
+				{MediaType: "application/vnd.meigma.blob.index.v1+flatbuffers", Digest: digest.FromString("index"), Size: 5},
+				{MediaType: "application/vnd.meigma.blob.data.v1", Digest: digest.FromString("data"), Size: 4},
+			},
+		})
+		require.NoError(t, err)
+		client.descriptors[digest.FromString("manifest").String()] = manifest
+		return client
+	}
+
+	client := withManifest(map[string]string{"org.opencontainers.image.source": "https://github.com/myorg/myrepo"})
+	require.NoError(t, policy.Evaluate(context.Background(), newPolicyRequest(client)))
+
+	client = withManifest(map[string]string{"org.opencontainers.image.source": "https://github.com/other/repo"})
+	require.ErrorIs(t, policy.Evaluate(context.Background(), newPolicyRequest(client)), ErrPolicyDenied)
+
+	client = withManifest(nil)
+	require.ErrorIs(t, policy.Evaluate(context.Background(), newPolicyRequest(client)), ErrPolicyDenied)
+}
+
+// Multi-file policy: lib.rego defines the helper used by main.rego.
+const (
+	libRego = `
+		package blob.lib
+		import rego.v1
+
+		trusted_builder(att) if {
+			att.predicate.runDetails.builder.id == "https://github.com/actions/runner/github-hosted"
+		}
+	`
+	mainRego = `
+		package blob.policy
+		import rego.v1
+		import data.blob.lib
+
+		default allow := false
+
+		allow if {
+			some att in input.attestations
+			lib.trusted_builder(att)
+		}
+	`
+)
+
+// writePolicyFiles writes files under a new directory and returns it.
+func writePolicyFiles(t *testing.T, files map[string]string) string {
+	t.Helper()
+	dir := t.TempDir()
+	for name, content := range files {
+		path := filepath.Join(dir, filepath.FromSlash(name))
+		require.NoError(t, os.MkdirAll(filepath.Dir(path), 0o755))
+		require.NoError(t, os.WriteFile(path, []byte(content), 0o600))
+	}
+	return dir
+}
+
+// trustedRequest returns a request whose only attestation has builderID.
+func trustedRequest(builderID string) registry.PolicyRequest {
+	envelope := createDSSEEnvelope(createSLSAStatement(builderID))
+	d := digest.FromBytes(envelope)
+	return newPolicyRequest(&mockPolicyClient{
+		referrers:   []ocispec.Descriptor{{MediaType: DefaultArtifactType, Digest: d, Size: int64(len(envelope))}},
+		descriptors: map[string][]byte{d.String(): envelope},
+	})
+}
+
+func TestPolicy_MultipleModules(t *testing.T) {
+	t.Parallel()
+
+	dir := writePolicyFiles(t, map[string]string{
+		"main.rego":     mainRego,
+		"lib/lib.rego":  libRego,
+		"lib/README.md": "not a policy",
+	})
+
+	tests := []struct {
+		name string
+		opt  PolicyOption
+	}{
+		{"files", WithPolicyFiles(filepath.Join(dir, "main.rego"), filepath.Join(dir, "lib", "lib.rego"))},
+		{"dir", WithPolicyDir(dir)},
+		{"bundle", WithPolicyBundle(dir)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			policy, err := NewPolicy(tt.opt)
+			require.NoError(t, err)
+			require.NoError(t, policy.Evaluate(context.Background(),
+				trustedRequest("https://github.com/actions/runner/github-hosted")))
+			require.ErrorIs(t, policy.Evaluate(context.Background(),
+				trustedRequest("https://evil.example.com/builder")), ErrPolicyDenied)
+		})
+	}
+
+	t.Run("missing helper", func(t *testing.T) {
+		t.Parallel()
+
+		_, err := NewPolicy(WithPolicyFiles(filepath.Join(dir, "main.rego")))
+		require.Error(t, err)
+	})
+
+	t.Run("error names file", func(t *testing.T) {
+		t.Parallel()
+
+		bad := writePolicyFiles(t, map[string]string{
+			"main.rego":   mainRego,
+			"broken.rego": "package blob.lib\n invalid rego {{{",
+		})
+		_, err := NewPolicy(WithPolicyDir(bad))
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "broken.rego")
+	})
+
+	t.Run("empty dir", func(t *testing.T) {
+		t.Parallel()
+
+		_, err := NewPolicy(WithPolicyDir(t.TempDir()))
+		require.Error(t, err)
+	})
+}
+
+func TestPolicy_Data(t *testing.T) {
+	t.Parallel()
+
+	allowlist := `
+		package blob.policy
+		import rego.v1
+
+		default allow := false
+
+		allow if {
+			some att in input.attestations
+			att.predicate.runDetails.builder.id in data.trusted_builders
+		}
+	`
+	trusted := "https://github.com/actions/runner/github-hosted"
+	other := "https://builder.example.com"
+
+	policy, err := NewPolicy(WithPolicy(allowlist), WithData(map[string]any{
+		"trusted_builders": []any{trusted},
+	}))
+	require.NoError(t, err)
+	require.NoError(t, policy.Evaluate(context.Background(), trustedRequest(trusted)))
+	require.ErrorIs(t, policy.Evaluate(context.Background(), trustedRequest(other)), ErrPolicyDenied)
+
+	t.Run("file", func(t *testing.T) {
+		t.Parallel()
+
+		dir := writePolicyFiles(t, map[string]string{
+			"data.json": `{"trusted_builders": ["` + other + `"]}`,
+			"data.yaml": "trusted_builders:\n  - " + other + "\n",
+		})
+		for _, name := range []string{"data.json", "data.yaml"} {
+			policy, err := NewPolicy(WithPolicy(allowlist), WithDataFile(filepath.Join(dir, name)))
+			require.NoError(t, err, name)
+			require.NoError(t, policy.Evaluate(context.Background(), trustedRequest(other)), name)
+			require.ErrorIs(t, policy.Evaluate(context.Background(), trustedRequest(trusted)), ErrPolicyDenied, name)
+		}
+	})
+
+	t.Run("later data replaces keys", func(t *testing.T) {
+		t.Parallel()
+
+		policy, err := NewPolicy(WithPolicy(allowlist),
+			WithData(map[string]any{"trusted_builders": []any{trusted}}),
+			WithData(map[string]any{"trusted_builders": []any{other}}))
+		require.NoError(t, err)
+		require.ErrorIs(t, policy.Evaluate(context.Background(), trustedRequest(trusted)), ErrPolicyDenied)
+	})
+
+	t.Run("invalid file", func(t *testing.T) {
+		t.Parallel()
+
+		dir := writePolicyFiles(t, map[string]string{"data.json": "[1, 2"})
+		_, err := NewPolicy(WithPolicy(allowlist), WithDataFile(filepath.Join(dir, "data.json")))
+		require.Error(t, err)
+	})
+}
+
+func TestPolicy_VerificationError(t *testing.T) {
+	t.Parallel()
+
+	policy, err := NewPolicy(WithPolicy(`
+		package blob.policy
+		import rego.v1
+
+		deny contains "untrusted builder" if {
+			some att in input.attestations
+			att.predicate.runDetails.builder.id != "https://github.com/actions/runner/github-hosted"
+		}
+	`))
+	require.NoError(t, err)
+
+	req := trustedRequest("https://evil.example.com/builder")
+	err = policy.Evaluate(context.Background(), req)
+	var verr *blobpolicy.VerificationError
+	require.ErrorAs(t, err, &verr)
+	assert.Equal(t, "opa", verr.Policy)
+	assert.Equal(t, blobpolicy.ReasonPolicyDenied, verr.Reason)
+	assert.Equal(t, req.Digest, verr.Subject)
+	require.ErrorIs(t, err, ErrPolicyDenied)
+	assert.Contains(t, err.Error(), "untrusted builder")
+
+	err = policy.Evaluate(context.Background(), newPolicyRequest(newAttestationsClient(0)))
+	require.ErrorAs(t, err, &verr)
+	assert.Equal(t, blobpolicy.ReasonNoAttestation, verr.Reason)
+}
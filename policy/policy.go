@@ -19,35 +19,50 @@
 //	    slsa.RequireSource("https://github.com/myorg/repo2"),
 //	)
 //
+// Use Not to deny artifacts that match a policy:
+//
+//	notRevoked := policy.Not(slsa.RequireBuilder(revokedBuilderID))
+//
 // Compositions can be nested:
 //
 //	policy := policy.RequireAll(
 //	    sigstorePolicy,
 //	    policy.RequireAny(slsaPolicy1, slsaPolicy2),
 //	)
+//
+// # Shared Fetches
+//
+// The policies in a composition share the registry fetches of a request.
+// Referrer listings and attestations are fetched once, however many
+// policies read them.
 package policy
 
 import (
 	"context"
 	"errors"
 	"fmt"
-	"strings"
 
 	"github.com/meigma/blob/registry"
 )
 
 // RequireAll returns a policy that passes only if all given policies pass.
 //
-// Policies are evaluated in order. Evaluation stops at the first failure.
-// If no policies are provided, the returned policy always passes.
+// Policies are evaluated in order. Evaluation stops at the first failure,
+// which is returned in a *MultiError. If no policies are provided, the
+// returned policy always passes.
+//
+// The policies share the request's registry fetches: each referrer listing
+// and attestation is fetched once and reused by every policy, including
+// those in nested compositions.
 func RequireAll(policies ...registry.Policy) registry.Policy {
 	return registry.PolicyFunc(func(ctx context.Context, req registry.PolicyRequest) error {
+		req = registry.ShareFetches(req)
 		for i, p := range policies {
 			if p == nil {
 				continue
 			}
 			if err := p.Evaluate(ctx, req); err != nil {
-				return fmt.Errorf("policy %d: %w", i+1, err)
+				return &MultiError{Errors: []error{fmt.Errorf("policy %d: %w", i+1, err)}}
 			}
 		}
 		return nil
@@ -57,10 +72,13 @@ func RequireAll(policies ...registry.Policy) registry.Policy {
 // RequireAny returns a policy that passes if at least one policy passes.
 //
 // All policies are evaluated until one succeeds. If all policies fail,
-// the error includes messages from all failed policies.
+// the error is a *MultiError holding every failure.
 // If no policies are provided, the returned policy fails with an error.
+// Registry fetches are shared between the policies as in RequireAll.
 func RequireAny(policies ...registry.Policy) registry.Policy {
 	return registry.PolicyFunc(func(ctx context.Context, req registry.PolicyRequest) error {
+		req = registry.ShareFetches(req)
+
 		// Filter nil policies
 		var validPolicies []registry.Policy
 		for _, p := range policies {
@@ -73,17 +91,43 @@ func RequireAny(policies ...registry.Policy) registry.Policy {
 			return errors.New("policy: RequireAny requires at least one policy")
 		}
 
-		var errs []string
+		var errs []error
 		for _, p := range validPolicies {
 			if err := p.Evaluate(ctx, req); err != nil {
-				errs = append(errs, err.Error())
+				errs = append(errs, err)
 				continue
 			}
 			return nil // At least one passed
 		}
 
 		// All failed
-		return fmt.Errorf("policy: all %d policies failed: %s",
-			len(validPolicies), strings.Join(errs, "; "))
+		return &MultiError{Errors: errs}
+	})
+}
+
+// ErrNegatedPolicyPassed is returned by a Not policy when the policy it
+// negates passes.
+var ErrNegatedPolicyPassed = errors.New("policy: negated policy passed")
+
+// Not returns a policy that inverts the verdict of p: it passes when p
+// fails and fails with ErrNegatedPolicyPassed when p passes. Use it to
+// deny artifacts that match a policy, such as a known-bad builder.
+//
+// A failure caused by cancellation of ctx is returned unchanged rather than
+// treated as a pass. A nil p counts as passing, as in RequireAll, so
+// Not(nil) always fails.
+func Not(p registry.Policy) registry.Policy {
+	return registry.PolicyFunc(func(ctx context.Context, req registry.PolicyRequest) error {
+		if p == nil {
+			return ErrNegatedPolicyPassed
+		}
+		err := p.Evaluate(ctx, req)
+		if err == nil {
+			return ErrNegatedPolicyPassed
+		}
+		if ctx.Err() != nil {
+			return err
+		}
+		return nil
 	})
 }
@@ -0,0 +1,137 @@
+package policy
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/meigma/blob/registry"
+)
+
+func TestRequireAny(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+
+	t.Run("short-circuits on first pass", func(t *testing.T) {
+		t.Parallel()
+
+		fail := &countingPolicy{err: errors.New("fulcio: no signature")}
+		pass := &countingPolicy{}
+		after := &countingPolicy{}
+		require.NoError(t, RequireAny(fail, pass, after).Evaluate(ctx, registry.PolicyRequest{}))
+		assert.Equal(t, 1, fail.calls)
+		assert.Equal(t, 1, pass.calls)
+		assert.Zero(t, after.calls, "policies after a pass are not evaluated")
+	})
+
+	t.Run("aggregates errors when all fail", func(t *testing.T) {
+		t.Parallel()
+
+		err := RequireAny(
+			&countingPolicy{err: errors.New("fulcio: no signature")},
+			&countingPolicy{err: errors.New("key: bad signature")},
+		).Evaluate(ctx, registry.PolicyRequest{})
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "fulcio: no signature")
+		assert.Contains(t, err.Error(), "key: bad signature")
+	})
+
+	t.Run("no policies", func(t *testing.T) {
+		t.Parallel()
+
+		require.Error(t, RequireAny(nil).Evaluate(ctx, registry.PolicyRequest{}))
+	})
+}
+
+func TestNot(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+
+	require.ErrorIs(t, Not(&countingPolicy{}).Evaluate(ctx, registry.PolicyRequest{}), ErrNegatedPolicyPassed)
+	require.NoError(t, Not(&countingPolicy{err: errors.New("builder mismatch")}).Evaluate(ctx, registry.PolicyRequest{}))
+	require.ErrorIs(t, Not(nil).Evaluate(ctx, registry.PolicyRequest{}), ErrNegatedPolicyPassed)
+
+	// Double negation restores the verdict.
+	require.NoError(t, Not(Not(&countingPolicy{})).Evaluate(ctx, registry.PolicyRequest{}))
+
+	t.Run("cancellation is not a pass", func(t *testing.T) {
+		t.Parallel()
+
+		cancelled, cancel := context.WithCancel(ctx)
+		cancel()
+		inner := registry.PolicyFunc(func(ctx context.Context, _ registry.PolicyRequest) error {
+			return ctx.Err()
+		})
+		require.ErrorIs(t, Not(inner).Evaluate(cancelled, registry.PolicyRequest{}), context.Canceled)
+	})
+}
+
+func TestMultiError(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	sigErr := &VerificationError{Policy: "sigstore", Reason: ReasonIdentityMismatch, Subject: "https://github.com/evil/repo"}
+	slsaErr := &VerificationError{Policy: "slsa", Reason: ReasonBuilderMismatch, Subject: "https://example.com/builder"}
+
+	t.Run("RequireAll holds the first failure", func(t *testing.T) {
+		t.Parallel()
+
+		after := &countingPolicy{err: slsaErr}
+		err := RequireAll(&countingPolicy{}, &countingPolicy{err: sigErr}, after).Evaluate(ctx, registry.PolicyRequest{})
+
+		var merr *MultiError
+		require.ErrorAs(t, err, &merr)
+		require.Len(t, merr.Errors, 1)
+		assert.Zero(t, after.calls)
+
+		var verr *VerificationError
+		require.ErrorAs(t, err, &verr)
+		assert.Equal(t, "sigstore", verr.Policy)
+		assert.Equal(t, ReasonIdentityMismatch, verr.Reason)
+		assert.Equal(t, "https://github.com/evil/repo", verr.Subject)
+		assert.Equal(t, "policy 2: sigstore: identity mismatch: https://github.com/evil/repo", err.Error())
+	})
+
+	t.Run("RequireAny holds every failure", func(t *testing.T) {
+		t.Parallel()
+
+		err := RequireAny(&countingPolicy{err: sigErr}, &countingPolicy{err: slsaErr}).Evaluate(ctx, registry.PolicyRequest{})
+
+		var merr *MultiError
+		require.ErrorAs(t, err, &merr)
+		require.Len(t, merr.Errors, 2)
+
+		var reasons []Reason
+		for _, e := range merr.Errors {
+			var verr *VerificationError
+			require.ErrorAs(t, e, &verr)
+			reasons = append(reasons, verr.Reason)
+		}
+		assert.Equal(t, []Reason{ReasonIdentityMismatch, ReasonBuilderMismatch}, reasons)
+		assert.ErrorIs(t, err, slsaErr)
+		assert.Contains(t, err.Error(), "all 2 policies failed")
+	})
+
+	t.Run("nested compositions", func(t *testing.T) {
+		t.Parallel()
+
+		err := RequireAll(RequireAny(&countingPolicy{err: slsaErr})).Evaluate(ctx, registry.PolicyRequest{})
+
+		var verr *VerificationError
+		require.ErrorAs(t, err, &verr)
+		assert.Equal(t, ReasonBuilderMismatch, verr.Reason)
+	})
+}
+
+func TestReason_String(t *testing.T) {
+	t.Parallel()
+
+	assert.Equal(t, "no signature", ReasonNoSignature.String())
+	assert.Equal(t, "fetch failed", ReasonFetchFailed.String())
+	assert.Equal(t, "Reason(99)", Reason(99).String())
+}
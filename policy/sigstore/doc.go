@@ -34,6 +34,35 @@
 //	// Pull will fail if the archive is not signed by the expected identity
 //	archive, err := c.Pull(ctx, "ghcr.io/myorg/myarchive:v1")
 //
+// # Key-Based Verification
+//
+// Bundles signed with a long-lived key (see WithPrivateKey) are verified
+// against the pinned public key instead of a Fulcio certificate and OIDC
+// identity:
+//
+//	policy, err := sigstore.NewPolicy(
+//	    sigstore.WithPublicKey("/etc/blob/cosign.pub"),
+//	)
+//
+// # Transparency Log
+//
+// Keyless signatures must carry a verified Rekor transparency log entry
+// unless WithRequireTlog(false) is given; key-based signatures need one only
+// with WithRequireTlog(true). WithTlogThreshold requires more than one.
+//
+// # Offline Verification
+//
+// By default NewPolicy fetches the trusted root from the public Sigstore TUF
+// repository. In air-gapped environments, supply it from local files with
+// WithTrustedRootFile (a trusted_root.json) or WithTUFCacheDir (a copy of a
+// TUF cache such as ~/.sigstore/root). Neither contacts the network, and
+// both fail with ErrTrustedRootExpired when the local copy is stale:
+//
+//	policy, err := sigstore.NewPolicy(
+//	    sigstore.WithTrustedRootFile("/etc/blob/trusted_root.json"),
+//	    sigstore.WithIdentity("https://accounts.google.com", "user@example.com"),
+//	)
+//
 // # Signing
 //
 // The Signer creates Sigstore bundles that can be stored as OCI referrer artifacts.
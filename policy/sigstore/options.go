@@ -3,11 +3,17 @@ package sigstore
 import (
 	"context"
 	"crypto"
+	"errors"
+	"fmt"
 	"log/slog"
+	"os"
+	"time"
 
 	"github.com/sigstore/sigstore-go/pkg/root"
 	"github.com/sigstore/sigstore-go/pkg/sign"
 	"github.com/sigstore/sigstore-go/pkg/verify"
+	"github.com/sigstore/sigstore/pkg/cryptoutils"
+	"github.com/sigstore/sigstore/pkg/signature"
 )
 
 // PolicyOption configures a Policy.
@@ -24,10 +30,13 @@ func WithTrustedRoot(tr root.TrustedMaterial) PolicyOption {
 	}
 }
 
-// WithTrustedRootFile loads a trusted root from a JSON file.
+// WithTrustedRootFile loads a trusted root from a JSON file, such as a
+// trusted_root.json exported from the Sigstore TUF repository. No network
+// access is needed. It fails with ErrTrustedRootExpired when every
+// certificate authority and transparency log in the root has expired.
 func WithTrustedRootFile(path string) PolicyOption {
 	return func(p *Policy) error {
-		tr, err := root.NewTrustedRootFromPath(path)
+		tr, err := loadTrustedRootFile(path, time.Now())
 		if err != nil {
 			return err
 		}
@@ -36,6 +45,90 @@ func WithTrustedRootFile(path string) PolicyOption {
 	}
 }
 
+// WithTUFCacheDir loads the trusted root from a local copy of the public
+// Sigstore TUF repository cache (by default ~/.sigstore/root) instead of
+// fetching it. The TUF repository is never contacted, so the cache must
+// already hold current metadata and the trusted root target; NewPolicy
+// fails with ErrTrustedRootExpired when the cached metadata has expired.
+//
+// WithTrustedRoot and WithTrustedRootFile take precedence over this option.
+func WithTUFCacheDir(dir string) PolicyOption {
+	return func(p *Policy) error {
+		if dir == "" {
+			return errors.New("sigstore: TUF cache directory required")
+		}
+		p.tufCacheDir = dir
+		return nil
+	}
+}
+
+// WithPublicKey reads a PEM-encoded public key from path and requires
+// bundles to be signed with the matching private key. See WithPublicKeyPEM.
+func WithPublicKey(path string) PolicyOption {
+	return func(p *Policy) error {
+		pemData, err := os.ReadFile(path) //nolint:gosec // path is caller-supplied configuration
+		if err != nil {
+			return fmt.Errorf("sigstore: read public key: %w", err)
+		}
+		return WithPublicKeyPEM(pemData)(p)
+	}
+}
+
+// WithPublicKeyPEM requires bundles to be signed with the private key
+// matching the PEM-encoded public key, as produced by a Signer configured
+// with WithPrivateKey. This supports long-lived signing keys instead of
+// keyless signing: there is no Fulcio certificate or OIDC identity to check,
+// so it cannot be combined with WithIdentity. The key is pinned, so no
+// trusted root is fetched unless WithTrustedRoot, WithTrustedRootFile, or
+// WithTUFCacheDir is also given.
+func WithPublicKeyPEM(pemData []byte) PolicyOption {
+	return func(p *Policy) error {
+		pub, err := cryptoutils.UnmarshalPEMToPublicKey(pemData)
+		if err != nil {
+			return fmt.Errorf("sigstore: parse public key: %w", err)
+		}
+		verifier, err := signature.LoadDefaultVerifier(pub)
+		if err != nil {
+			return fmt.Errorf("sigstore: load public key: %w", err)
+		}
+		p.publicKey = root.NewExpiringKey(verifier, time.Time{}, time.Time{})
+		return nil
+	}
+}
+
+// WithRequireTlog controls whether bundles must carry a verified Rekor
+// transparency log entry. By default an entry is required for keyless
+// (Fulcio) signatures and not for signatures from a WithPublicKey key.
+// Disable it to accept offline bundles from private deployments; keyless
+// bundles then still need a signed timestamp to prove when the short-lived
+// certificate was used.
+//
+// Requiring a log entry with WithPublicKey fetches the default trusted root
+// unless one is supplied, since the Rekor keys are needed to verify entries.
+func WithRequireTlog(require bool) PolicyOption {
+	return func(p *Policy) error {
+		switch {
+		case !require:
+			p.tlogThreshold = 0
+		case p.tlogThreshold < 1:
+			p.tlogThreshold = 1
+		}
+		return nil
+	}
+}
+
+// WithTlogThreshold sets how many verified transparency log entries a
+// bundle must carry. Zero is the same as WithRequireTlog(false).
+func WithTlogThreshold(n int) PolicyOption {
+	return func(p *Policy) error {
+		if n < 0 {
+			return fmt.Errorf("sigstore: transparency log threshold must not be negative, got %d", n)
+		}
+		p.tlogThreshold = n
+		return nil
+	}
+}
+
 // WithIdentity requires signatures from a specific OIDC identity.
 // The issuer is the OIDC provider URL (e.g., "https://accounts.google.com").
 // The subject is the expected identity (e.g., "user@example.com").
@@ -7,10 +7,13 @@ import (
 	"errors"
 	"fmt"
 	"log/slog"
+	"time"
 
+	blobpolicy "github.com/meigma/blob/policy"
 	"github.com/meigma/blob/registry"
 	ocispec "github.com/opencontainers/image-spec/specs-go/v1"
 	"github.com/sigstore/sigstore-go/pkg/bundle"
+	"github.com/sigstore/sigstore-go/pkg/fulcio/certificate"
 	"github.com/sigstore/sigstore-go/pkg/root"
 	"github.com/sigstore/sigstore-go/pkg/verify"
 )
@@ -23,14 +26,21 @@ const SignatureArtifactType = "application/vnd.dev.sigstore.bundle.v0.3+json"
 // against the trusted root.
 type Policy struct {
 	trustedRoot root.TrustedMaterial
+	tufCacheDir string
+	publicKey   *root.ExpiringKey
 	identity    *verify.CertificateIdentity
 	logger      *slog.Logger
+
+	// tlogThreshold is the number of transparency log entries required, or
+	// -1 to choose based on whether a public key is pinned.
+	tlogThreshold int
 }
 
 // NewPolicy creates a sigstore-based verification policy.
 func NewPolicy(opts ...PolicyOption) (*Policy, error) {
 	p := &Policy{
-		logger: slog.New(slog.DiscardHandler),
+		logger:        slog.New(slog.DiscardHandler),
+		tlogThreshold: -1,
 	}
 	for _, opt := range opts {
 		if err := opt(p); err != nil {
@@ -38,8 +48,30 @@ func NewPolicy(opts ...PolicyOption) (*Policy, error) {
 		}
 	}
 
-	// Default to public Sigstore instance if no trusted root provided
-	if p.trustedRoot == nil {
+	if p.publicKey != nil && p.identity != nil {
+		return nil, errors.New("sigstore: WithPublicKey cannot be combined with WithIdentity")
+	}
+
+	if p.tlogThreshold < 0 {
+		p.tlogThreshold = 1
+		if p.publicKey != nil {
+			p.tlogThreshold = 0
+		}
+	}
+
+	// Default to public Sigstore instance if no trusted root provided.
+	// A pinned public key needs no trusted root unless log entries must be
+	// verified.
+	switch {
+	case p.trustedRoot != nil:
+	case p.tufCacheDir != "":
+		tr, err := fetchTrustedRootFromCache(p.tufCacheDir, time.Now())
+		if err != nil {
+			return nil, err
+		}
+		p.trustedRoot = tr
+	case p.publicKey != nil && p.tlogThreshold == 0:
+	default:
 		tr, err := root.FetchTrustedRoot()
 		if err != nil {
 			return nil, fmt.Errorf("sigstore fetch trusted root: %w", err)
@@ -47,8 +79,21 @@ func NewPolicy(opts ...PolicyOption) (*Policy, error) {
 		p.trustedRoot = tr
 	}
 
+	if p.publicKey != nil {
+		// The key is pinned, so it is returned whatever key hint the bundle
+		// carries; a bundle signed by another key fails signature checks.
+		keys := root.NewTrustedPublicKeyMaterial(func(string) (root.TimeConstrainedVerifier, error) {
+			return p.publicKey, nil
+		})
+		if p.trustedRoot != nil {
+			p.trustedRoot = root.TrustedMaterialCollection{p.trustedRoot, keys}
+		} else {
+			p.trustedRoot = keys
+		}
+	}
+
 	// Warn if no identity is configured
-	if p.identity == nil {
+	if p.identity == nil && p.publicKey == nil {
 		p.logger.Warn("sigstore policy created without identity requirement; " +
 			"any valid signature will be accepted regardless of signer")
 	}
@@ -68,22 +113,23 @@ func (p *Policy) Evaluate(ctx context.Context, req registry.PolicyRequest) error
 	referrers, err := req.Client.Referrers(ctx, req.Ref, req.Subject, SignatureArtifactType)
 	if err != nil {
 		if errors.Is(err, registry.ErrReferrersUnsupported) {
-			return errors.New("sigstore: registry does not support referrers API")
+			return verificationError(blobpolicy.ReasonReferrersUnsupported, "",
+				errors.New("sigstore: registry does not support referrers API"))
 		}
-		return fmt.Errorf("sigstore: list referrers: %w", err)
+		return verificationError(blobpolicy.ReasonFetchFailed, "", fmt.Errorf("sigstore: list referrers: %w", err))
 	}
 
 	p.logger.Debug("sigstore: found signature referrers",
 		slog.Int("count", len(referrers)))
 
 	if len(referrers) == 0 {
-		return errors.New("sigstore: no signatures found for manifest")
+		return verificationError(blobpolicy.ReasonNoSignature, "", errors.New("sigstore: no signatures found for manifest"))
 	}
 
 	// Get the manifest payload for verification
 	payload, err := req.Client.FetchDescriptor(ctx, req.Ref, req.Subject)
 	if err != nil {
-		return fmt.Errorf("sigstore: fetch manifest: %w", err)
+		return verificationError(blobpolicy.ReasonFetchFailed, "", fmt.Errorf("sigstore: fetch manifest: %w", err))
 	}
 
 	// Try to verify at least one signature
@@ -95,7 +141,8 @@ func (p *Policy) Evaluate(ctx context.Context, req registry.PolicyRequest) error
 
 		bundleData, err := req.Client.FetchDescriptor(ctx, req.Ref, ref)
 		if err != nil {
-			lastErr = fmt.Errorf("sigstore: fetch bundle: %w", err)
+			lastErr = verificationError(blobpolicy.ReasonFetchFailed, ref.Digest.String(),
+				fmt.Errorf("sigstore: fetch bundle: %w", err))
 			p.logger.Debug("sigstore: failed to fetch bundle",
 				slog.Any("error", err))
 			continue
@@ -117,7 +164,7 @@ func (p *Policy) Evaluate(ctx context.Context, req registry.PolicyRequest) error
 	if lastErr != nil {
 		return fmt.Errorf("sigstore: verification failed: %w", lastErr)
 	}
-	return errors.New("sigstore: no valid signatures found")
+	return verificationError(blobpolicy.ReasonNoSignature, "", errors.New("sigstore: no valid signatures found"))
 }
 
 // ociArtifactManifest represents a minimal OCI artifact manifest structure
@@ -146,7 +193,7 @@ func parseOCIArtifactLayers(data []byte) ([]ocispec.Descriptor, bool, error) {
 		layers = manifest.Blobs
 	}
 	if len(layers) == 0 {
-		return nil, true, errors.New("sigstore: manifest contains no layers")
+		return nil, true, verificationError(blobpolicy.ReasonNoSignature, "", errors.New("sigstore: manifest contains no layers"))
 	}
 
 	return layers, true, nil
@@ -166,7 +213,8 @@ func (p *Policy) verifyBundleData(ctx context.Context, req registry.PolicyReques
 		for _, layer := range layers {
 			layerData, err := req.Client.FetchDescriptor(ctx, req.Ref, layer)
 			if err != nil {
-				lastErr = fmt.Errorf("sigstore: fetch bundle layer: %w", err)
+				lastErr = verificationError(blobpolicy.ReasonFetchFailed, layer.Digest.String(),
+					fmt.Errorf("sigstore: fetch bundle layer: %w", err))
 				continue
 			}
 
@@ -181,7 +229,7 @@ func (p *Policy) verifyBundleData(ctx context.Context, req registry.PolicyReques
 		if lastErr != nil {
 			return lastErr
 		}
-		return errors.New("sigstore: no valid bundle layers found")
+		return verificationError(blobpolicy.ReasonNoSignature, "", errors.New("sigstore: no valid bundle layers found"))
 	}
 
 	return p.verifyBundle(data, payload)
@@ -192,29 +240,29 @@ func (p *Policy) verifyBundleData(ctx context.Context, req registry.PolicyReques
 func (p *Policy) verifyBundle(bundleData, payload []byte) error {
 	var b bundle.Bundle
 	if err := b.UnmarshalJSON(bundleData); err != nil {
-		return fmt.Errorf("parse bundle: %w", err)
+		return verificationError(blobpolicy.ReasonInvalidSignature, "", fmt.Errorf("parse bundle: %w", err))
 	}
 
 	p.logger.Debug("sigstore: parsed bundle, verifying signature")
 
 	// Build verifier with transparency log and timestamp requirements
-	verifier, err := verify.NewVerifier(
-		p.trustedRoot,
-		verify.WithObserverTimestamps(1),
-		verify.WithTransparencyLog(1),
-	)
+	verifier, err := verify.NewVerifier(p.trustedRoot, p.verifierOptions()...)
 	if err != nil {
 		return fmt.Errorf("create verifier: %w", err)
 	}
 
 	// Build verification policy
 	var policyOpts []verify.PolicyOption
-	if p.identity != nil {
+	switch {
+	case p.publicKey != nil:
+		p.logger.Debug("sigstore: requiring signature from pinned public key")
+		policyOpts = append(policyOpts, verify.WithKey())
+	case p.identity != nil:
 		p.logger.Debug("sigstore: checking identity requirement",
 			slog.String("issuer", p.identity.Issuer.Issuer),
 			slog.String("subject", p.identity.SubjectAlternativeName.SubjectAlternativeName))
 		policyOpts = append(policyOpts, verify.WithCertificateIdentity(*p.identity))
-	} else {
+	default:
 		p.logger.Debug("sigstore: no identity requirement configured, accepting any valid signature")
 		policyOpts = append(policyOpts, verify.WithoutIdentitiesUnsafe())
 	}
@@ -226,11 +274,52 @@ func (p *Policy) verifyBundle(bundleData, payload []byte) error {
 
 	_, err = verifier.Verify(&b, policy)
 	if err != nil {
-		return fmt.Errorf("signature invalid: %w", err)
+		err = fmt.Errorf("signature invalid: %w", err)
+		var identityErr *verify.ErrNoMatchingCertificateIdentity
+		if errors.As(err, &identityErr) {
+			return verificationError(blobpolicy.ReasonIdentityMismatch, signerIdentity(&b), err)
+		}
+		return verificationError(blobpolicy.ReasonInvalidSignature, "", err)
 	}
 
 	return nil
 }
 
+// verifierOptions returns the timestamp and transparency log requirements.
+func (p *Policy) verifierOptions() []verify.VerifierOption {
+	var opts []verify.VerifierOption
+	if p.publicKey != nil {
+		// A pinned key has no validity period, so there is no signing time
+		// to establish from a transparency log or timestamp authority.
+		opts = append(opts, verify.WithCurrentTime())
+	} else {
+		opts = append(opts, verify.WithObserverTimestamps(1))
+	}
+	if p.tlogThreshold > 0 {
+		opts = append(opts, verify.WithTransparencyLog(p.tlogThreshold))
+	}
+	return opts
+}
+
+// signerIdentity returns the subject alternative name of the bundle's
+// signing certificate, or "" when it has none.
+func signerIdentity(b *bundle.Bundle) string {
+	content, err := b.VerificationContent()
+	if err != nil || content.Certificate() == nil {
+		return ""
+	}
+	summary, err := certificate.SummarizeCertificate(content.Certificate())
+	if err != nil {
+		return ""
+	}
+	return summary.SubjectAlternativeName
+}
+
 // Ensure Policy implements registry.Policy.
 var _ registry.Policy = (*Policy)(nil)
+
+// verificationError returns a policy.VerificationError from the sigstore
+// policy.
+func verificationError(reason blobpolicy.Reason, subject string, err error) error {
+	return &blobpolicy.VerificationError{Policy: "sigstore", Reason: reason, Subject: subject, Err: err}
+}
@@ -2,13 +2,23 @@ package sigstore
 
 import (
 	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"errors"
+	"os"
+	"path/filepath"
 	"testing"
 
 	"github.com/opencontainers/go-digest"
 	ocispec "github.com/opencontainers/image-spec/specs-go/v1"
+	"github.com/sigstore/sigstore/pkg/cryptoutils"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 
+	blobpolicy "github.com/meigma/blob/policy"
 	"github.com/meigma/blob/registry"
 )
 
@@ -67,6 +77,7 @@ func TestPolicy_NoSignatures(t *testing.T) {
 	err = policy.Evaluate(context.Background(), req)
 	require.Error(t, err)
 	assert.Contains(t, err.Error(), "no signatures found")
+	assertReason(t, err, blobpolicy.ReasonNoSignature)
 }
 
 func TestPolicy_ReferrersUnsupported(t *testing.T) {
@@ -95,6 +106,7 @@ func TestPolicy_ReferrersUnsupported(t *testing.T) {
 	err = policy.Evaluate(context.Background(), req)
 	require.Error(t, err)
 	assert.Contains(t, err.Error(), "does not support referrers")
+	assertReason(t, err, blobpolicy.ReasonReferrersUnsupported)
 }
 
 func TestPolicy_InvalidBundle(t *testing.T) {
@@ -137,6 +149,152 @@ func TestPolicy_InvalidBundle(t *testing.T) {
 	err = policy.Evaluate(context.Background(), req)
 	require.Error(t, err)
 	assert.Contains(t, err.Error(), "verification failed")
+	assertReason(t, err, blobpolicy.ReasonInvalidSignature)
+}
+
+func assertReason(t *testing.T, err error, want blobpolicy.Reason) {
+	t.Helper()
+
+	var verr *blobpolicy.VerificationError
+	require.True(t, errors.As(err, &verr), "expected *policy.VerificationError, got %T", err)
+	assert.Equal(t, "sigstore", verr.Policy)
+	assert.Equal(t, want, verr.Reason)
+}
+
+// signedRequest returns a policy request whose registry serves manifest and
+// a single signature bundle referring to it.
+func signedRequest(manifest, bundle []byte) registry.PolicyRequest {
+	manifestDigest := digest.FromBytes(manifest)
+	bundleDigest := digest.FromBytes(bundle)
+	return registry.PolicyRequest{
+		Ref:    "example.com/repo:tag",
+		Digest: manifestDigest.String(),
+		Subject: ocispec.Descriptor{
+			MediaType: ocispec.MediaTypeImageManifest,
+			Digest:    manifestDigest,
+			Size:      int64(len(manifest)),
+		},
+		Client: &mockPolicyClient{
+			referrers: []ocispec.Descriptor{{
+				MediaType:    SignatureArtifactType,
+				ArtifactType: SignatureArtifactType,
+				Digest:       bundleDigest,
+				Size:         int64(len(bundle)),
+			}},
+			descriptors: map[string][]byte{
+				manifestDigest.String(): manifest,
+				bundleDigest.String():   bundle,
+			},
+		},
+	}
+}
+
+// signWithKey signs manifest with key and returns the bundle along with the
+// PEM-encoded public key.
+func signWithKey(t *testing.T, key crypto.Signer, manifest []byte) (bundle, pubPEM []byte) {
+	t.Helper()
+
+	signer, err := NewSigner(WithPrivateKey(key))
+	require.NoError(t, err)
+	sig, err := signer.Sign(context.Background(), manifest)
+	require.NoError(t, err)
+
+	pubPEM, err = cryptoutils.MarshalPublicKeyToPEM(key.Public())
+	require.NoError(t, err)
+	return sig.Data, pubPEM
+}
+
+func TestWithPublicKey(t *testing.T) {
+	t.Parallel()
+
+	manifest := []byte(`{"schemaVersion":2,"mediaType":"application/vnd.oci.image.manifest.v1+json"}`)
+
+	keys := map[string]func() (crypto.Signer, error){
+		"ecdsa-p256": func() (crypto.Signer, error) { return ecdsa.GenerateKey(elliptic.P256(), rand.Reader) },
+		"ecdsa-p384": func() (crypto.Signer, error) { return ecdsa.GenerateKey(elliptic.P384(), rand.Reader) },
+		"ed25519": func() (crypto.Signer, error) {
+			_, key, err := ed25519.GenerateKey(rand.Reader)
+			return key, err
+		},
+	}
+	for name, generate := range keys {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			key, err := generate()
+			require.NoError(t, err)
+			bundle, pubPEM := signWithKey(t, key, manifest)
+
+			p, err := NewPolicy(WithPublicKeyPEM(pubPEM))
+			require.NoError(t, err)
+			require.NoError(t, p.Evaluate(context.Background(), signedRequest(manifest, bundle)))
+		})
+	}
+
+	t.Run("from file", func(t *testing.T) {
+		t.Parallel()
+
+		key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+		require.NoError(t, err)
+		bundle, pubPEM := signWithKey(t, key, manifest)
+
+		path := filepath.Join(t.TempDir(), "cosign.pub")
+		require.NoError(t, os.WriteFile(path, pubPEM, 0o600))
+
+		p, err := NewPolicy(WithPublicKey(path))
+		require.NoError(t, err)
+		require.NoError(t, p.Evaluate(context.Background(), signedRequest(manifest, bundle)))
+	})
+
+	t.Run("mismatched key", func(t *testing.T) {
+		t.Parallel()
+
+		signing, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+		require.NoError(t, err)
+		other, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+		require.NoError(t, err)
+		bundle, _ := signWithKey(t, signing, manifest)
+		otherPEM, err := cryptoutils.MarshalPublicKeyToPEM(other.Public())
+		require.NoError(t, err)
+
+		p, err := NewPolicy(WithPublicKeyPEM(otherPEM))
+		require.NoError(t, err)
+		err = p.Evaluate(context.Background(), signedRequest(manifest, bundle))
+		assertReason(t, err, blobpolicy.ReasonInvalidSignature)
+	})
+
+	t.Run("different manifest", func(t *testing.T) {
+		t.Parallel()
+
+		key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+		require.NoError(t, err)
+		bundle, pubPEM := signWithKey(t, key, manifest)
+
+		p, err := NewPolicy(WithPublicKeyPEM(pubPEM))
+		require.NoError(t, err)
+		err = p.Evaluate(context.Background(), signedRequest([]byte(`{"schemaVersion":2}`), bundle))
+		assertReason(t, err, blobpolicy.ReasonInvalidSignature)
+	})
+
+	t.Run("invalid PEM", func(t *testing.T) {
+		t.Parallel()
+
+		_, err := NewPolicy(WithPublicKeyPEM([]byte("not a key")))
+		require.Error(t, err)
+		_, err = NewPolicy(WithPublicKey(filepath.Join(t.TempDir(), "missing.pub")))
+		require.Error(t, err)
+	})
+
+	t.Run("identity not allowed", func(t *testing.T) {
+		t.Parallel()
+
+		key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+		require.NoError(t, err)
+		_, pubPEM := signWithKey(t, key, manifest)
+
+		_, err = NewPolicy(WithPublicKeyPEM(pubPEM), WithIdentity("https://accounts.google.com", "user@example.com"))
+		require.Error(t, err)
+	})
 }
 
 func TestWithIdentity(t *testing.T) {
@@ -0,0 +1,148 @@
+package sigstore
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/sigstore/sigstore-go/pkg/root"
+	"github.com/sigstore/sigstore-go/pkg/tuf"
+	"github.com/theupdateframework/go-tuf/v2/metadata"
+)
+
+// ErrTrustedRootExpired indicates the local trusted root or TUF metadata is
+// stale and must be refreshed from the Sigstore TUF repository.
+var ErrTrustedRootExpired = errors.New("sigstore: trusted root expired")
+
+// errTUFOffline is returned by the TUF fetcher when the policy was
+// configured with WithTUFCacheDir and the cache lacks a required file.
+var errTUFOffline = errors.New("sigstore: TUF cache incomplete and network access is disabled")
+
+// loadTrustedRootFile reads a trusted root JSON file and checks that it is
+// not stale.
+func loadTrustedRootFile(path string, now time.Time) (*root.TrustedRoot, error) {
+	tr, err := root.NewTrustedRootFromPath(path)
+	if err != nil {
+		return nil, fmt.Errorf("sigstore: load trusted root %s: %w", path, err)
+	}
+	if err := checkTrustedRootExpiry(tr, now); err != nil {
+		return nil, fmt.Errorf("%w: %s", err, path)
+	}
+	return tr, nil
+}
+
+// checkTrustedRootExpiry reports ErrTrustedRootExpired when every Fulcio
+// certificate authority and Rekor log in tr stopped being valid before now.
+// Keys retired during normal rotation are expected, so a root is only stale
+// when none of them is current.
+func checkTrustedRootExpiry(tr *root.TrustedRoot, now time.Time) error {
+	var ends []time.Time
+	for _, ca := range tr.FulcioCertificateAuthorities() {
+		if fca, ok := ca.(*root.FulcioCertificateAuthority); ok {
+			ends = append(ends, fca.ValidityPeriodEnd)
+		}
+	}
+	for _, tlog := range tr.RekorLogs() {
+		ends = append(ends, tlog.ValidityPeriodEnd)
+	}
+
+	var latest time.Time
+	for _, end := range ends {
+		if end.IsZero() || now.Before(end) {
+			return nil
+		}
+		if end.After(latest) {
+			latest = end
+		}
+	}
+	if latest.IsZero() {
+		return nil
+	}
+	return fmt.Errorf("%w: all keys expired by %s", ErrTrustedRootExpired, latest.UTC().Format(time.RFC3339))
+}
+
+// fetchTrustedRootFromCache loads the trusted root from a TUF cache
+// directory without contacting the TUF repository. The directory has the
+// layout written by sigstore-go and cosign, such as ~/.sigstore/root.
+func fetchTrustedRootFromCache(dir string, now time.Time) (*root.TrustedRoot, error) {
+	opts := tuf.DefaultOptions().
+		WithCachePath(dir).
+		WithForceCache().
+		WithFetcher(offlineFetcher{})
+
+	metadataDir := filepath.Join(dir, tuf.URLToPath(opts.RepositoryBaseURL))
+	if err := checkTUFExpiry(metadataDir, now); err != nil {
+		return nil, err
+	}
+
+	tr, err := root.FetchTrustedRootWithOptions(opts)
+	if err != nil {
+		return nil, fmt.Errorf("sigstore: load trusted root from TUF cache %s: %w", dir, err)
+	}
+	if err := checkTrustedRootExpiry(tr, now); err != nil {
+		return nil, fmt.Errorf("%w: TUF cache %s", err, dir)
+	}
+	return tr, nil
+}
+
+// checkTUFExpiry reports ErrTrustedRootExpired when any top-level TUF
+// metadata file cached in dir has expired. Missing files are left for the
+// TUF client to report.
+func checkTUFExpiry(dir string, now time.Time) error {
+	checks := []struct {
+		role    string
+		expires func([]byte) (time.Time, error)
+	}{
+		{metadata.TIMESTAMP, func(b []byte) (time.Time, error) {
+			md, err := metadata.Timestamp().FromBytes(b)
+			if err != nil {
+				return time.Time{}, err
+			}
+			return md.Signed.Expires, nil
+		}},
+		{metadata.SNAPSHOT, func(b []byte) (time.Time, error) {
+			md, err := metadata.Snapshot().FromBytes(b)
+			if err != nil {
+				return time.Time{}, err
+			}
+			return md.Signed.Expires, nil
+		}},
+		{metadata.TARGETS, func(b []byte) (time.Time, error) {
+			md, err := metadata.Targets().FromBytes(b)
+			if err != nil {
+				return time.Time{}, err
+			}
+			return md.Signed.Expires, nil
+		}},
+	}
+
+	for _, c := range checks {
+		path := filepath.Join(dir, c.role+".json")
+		data, err := os.ReadFile(path) //nolint:gosec // path is built from the caller-supplied cache directory
+		if errors.Is(err, os.ErrNotExist) {
+			continue
+		}
+		if err != nil {
+			return fmt.Errorf("sigstore: read TUF metadata: %w", err)
+		}
+		expires, err := c.expires(data)
+		if err != nil {
+			return fmt.Errorf("sigstore: parse TUF metadata %s: %w", path, err)
+		}
+		if !now.Before(expires) {
+			return fmt.Errorf("%w: TUF %s metadata in %s expired at %s",
+				ErrTrustedRootExpired, c.role, dir, expires.UTC().Format(time.RFC3339))
+		}
+	}
+	return nil
+}
+
+// offlineFetcher is a TUF fetcher that never touches the network.
+type offlineFetcher struct{}
+
+// DownloadFile implements fetcher.Fetcher.
+func (offlineFetcher) DownloadFile(urlPath string, _ int64, _ time.Duration) ([]byte, error) {
+	return nil, fmt.Errorf("%w: %s", errTUFOffline, urlPath)
+}
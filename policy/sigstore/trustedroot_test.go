@@ -0,0 +1,257 @@
+package sigstore
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	protobundle "github.com/sigstore/protobuf-specs/gen/pb-go/bundle/v1"
+	protocommon "github.com/sigstore/protobuf-specs/gen/pb-go/common/v1"
+	protorekor "github.com/sigstore/protobuf-specs/gen/pb-go/rekor/v1"
+	"github.com/sigstore/sigstore-go/pkg/bundle"
+	"github.com/sigstore/sigstore-go/pkg/root"
+	"github.com/sigstore/sigstore-go/pkg/testing/ca"
+	"github.com/sigstore/sigstore-go/pkg/tlog"
+	"github.com/sigstore/sigstore-go/pkg/tuf"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	blobpolicy "github.com/meigma/blob/policy"
+)
+
+const (
+	testIssuer   = "https://issuer.example.com"
+	testIdentity = "signer@example.com"
+)
+
+// offlineFixture is a trusted root file and a bundle signing a manifest,
+// produced by an in-process Sigstore instance.
+type offlineFixture struct {
+	trustedRootPath string
+	manifest        []byte
+	bundle          []byte
+}
+
+// newOfflineFixture signs manifest with a virtual Fulcio and Rekor and
+// writes their trusted root to a temporary file.
+func newOfflineFixture(t *testing.T, manifest []byte) offlineFixture {
+	t.Helper()
+
+	vs, err := ca.NewVirtualSigstore()
+	require.NoError(t, err)
+
+	entity, err := vs.Sign(testIdentity, testIssuer, manifest)
+	require.NoError(t, err)
+
+	// Rekor log IDs in the virtual instance are hex strings; the trusted root
+	// format stores the raw key ID.
+	logs := vs.RekorLogs()
+	for _, l := range logs {
+		l.ID, err = hex.DecodeString(string(l.ID))
+		require.NoError(t, err)
+	}
+	tr, err := root.NewTrustedRoot(root.TrustedRootMediaType01,
+		vs.FulcioCertificateAuthorities(), vs.CTLogs(), vs.TimestampingAuthorities(), logs)
+	require.NoError(t, err)
+	trJSON, err := tr.MarshalJSON()
+	require.NoError(t, err)
+	trustedRootPath := filepath.Join(t.TempDir(), "trusted_root.json")
+	require.NoError(t, os.WriteFile(trustedRootPath, trJSON, 0o600))
+
+	vc, err := entity.VerificationContent()
+	require.NoError(t, err)
+	sc, err := entity.SignatureContent()
+	require.NoError(t, err)
+	entries, err := entity.TlogEntries()
+	require.NoError(t, err)
+	require.Len(t, entries, 1)
+
+	// The virtual entry keeps its signed entry timestamp private, so sign
+	// the entry again to build the inclusion promise.
+	tle := entries[0].TransparencyLogEntry()
+	logID, err := vs.RekorLogID()
+	require.NoError(t, err)
+	set, err := vs.RekorSignPayload(tlog.RekorPayload{
+		Body:           base64.StdEncoding.EncodeToString(tle.GetCanonicalizedBody()),
+		IntegratedTime: tle.GetIntegratedTime(),
+		LogIndex:       tle.GetLogIndex(),
+		LogID:          logID,
+	})
+	require.NoError(t, err)
+	tle.KindVersion = &protorekor.KindVersion{Kind: "hashedrekord", Version: "0.0.1"}
+	tle.InclusionPromise = &protorekor.InclusionPromise{SignedEntryTimestamp: set}
+
+	sum := sha256.Sum256(manifest)
+	b, err := bundle.NewBundle(&protobundle.Bundle{
+		MediaType: "application/vnd.dev.sigstore.bundle+json;version=0.1",
+		VerificationMaterial: &protobundle.VerificationMaterial{
+			Content: &protobundle.VerificationMaterial_X509CertificateChain{
+				X509CertificateChain: &protocommon.X509CertificateChain{
+					Certificates: []*protocommon.X509Certificate{{RawBytes: vc.Certificate().Raw}},
+				},
+			},
+			TlogEntries: []*protorekor.TransparencyLogEntry{tle},
+		},
+		Content: &protobundle.Bundle_MessageSignature{
+			MessageSignature: &protocommon.MessageSignature{
+				MessageDigest: &protocommon.HashOutput{
+					Algorithm: protocommon.HashAlgorithm_SHA2_256,
+					Digest:    sum[:],
+				},
+				Signature: sc.MessageSignatureContent().Signature(),
+			},
+		},
+	})
+	require.NoError(t, err)
+	bundleJSON, err := b.MarshalJSON()
+	require.NoError(t, err)
+
+	return offlineFixture{trustedRootPath: trustedRootPath, manifest: manifest, bundle: bundleJSON}
+}
+
+func TestWithTrustedRootFile_VerifiesOffline(t *testing.T) {
+	t.Parallel()
+
+	f := newOfflineFixture(t, []byte(`{"schemaVersion":2,"mediaType":"application/vnd.oci.image.manifest.v1+json"}`))
+
+	p, err := NewPolicy(
+		WithTrustedRootFile(f.trustedRootPath),
+		WithIdentity(testIssuer, testIdentity),
+	)
+	require.NoError(t, err)
+	require.NoError(t, p.Evaluate(context.Background(), signedRequest(f.manifest, f.bundle)))
+
+	// The same bundle is rejected for another identity.
+	p, err = NewPolicy(
+		WithTrustedRootFile(f.trustedRootPath),
+		WithIdentity(testIssuer, "someone-else@example.com"),
+	)
+	require.NoError(t, err)
+	err = p.Evaluate(context.Background(), signedRequest(f.manifest, f.bundle))
+	assertReason(t, err, blobpolicy.ReasonIdentityMismatch)
+}
+
+func TestLoadTrustedRootFile_Expired(t *testing.T) {
+	t.Parallel()
+
+	f := newOfflineFixture(t, []byte(`{"schemaVersion":2}`))
+
+	_, err := loadTrustedRootFile(f.trustedRootPath, time.Now())
+	require.NoError(t, err)
+
+	// The virtual Fulcio and Rekor keys are valid for an hour.
+	_, err = loadTrustedRootFile(f.trustedRootPath, time.Now().Add(2*time.Hour))
+	require.ErrorIs(t, err, ErrTrustedRootExpired)
+	assert.Contains(t, err.Error(), f.trustedRootPath)
+}
+
+func TestWithTUFCacheDir(t *testing.T) {
+	t.Parallel()
+
+	t.Run("empty dir", func(t *testing.T) {
+		t.Parallel()
+
+		_, err := NewPolicy(WithTUFCacheDir(""))
+		require.Error(t, err)
+	})
+
+	t.Run("empty cache does not use network", func(t *testing.T) {
+		t.Parallel()
+
+		_, err := NewPolicy(WithTUFCacheDir(t.TempDir()))
+		require.ErrorIs(t, err, errTUFOffline)
+	})
+
+	t.Run("expired metadata", func(t *testing.T) {
+		t.Parallel()
+
+		dir := t.TempDir()
+		metadataDir := filepath.Join(dir, tuf.URLToPath(tuf.DefaultMirror))
+		require.NoError(t, os.MkdirAll(metadataDir, 0o750))
+		timestamp := `{"signed":{"_type":"timestamp","spec_version":"1.0.31","version":1,` +
+			`"expires":"2020-01-01T00:00:00Z","meta":{"snapshot.json":{"version":1}}},"signatures":[]}`
+		require.NoError(t, os.WriteFile(filepath.Join(metadataDir, "timestamp.json"), []byte(timestamp), 0o600))
+
+		_, err := NewPolicy(WithTUFCacheDir(dir))
+		require.ErrorIs(t, err, ErrTrustedRootExpired)
+		assert.Contains(t, err.Error(), "timestamp")
+	})
+
+	t.Run("explicit trusted root takes precedence", func(t *testing.T) {
+		t.Parallel()
+
+		f := newOfflineFixture(t, []byte(`{"schemaVersion":2}`))
+		_, err := NewPolicy(WithTUFCacheDir(t.TempDir()), WithTrustedRootFile(f.trustedRootPath))
+		require.NoError(t, err)
+	})
+}
+
+func TestTlogRequirement(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	manifest := []byte(`{"schemaVersion":2,"mediaType":"application/vnd.oci.image.manifest.v1+json"}`)
+	keyless := newOfflineFixture(t, manifest)
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	keyBundle, pubPEM := signWithKey(t, key, manifest) // no tlog entry
+
+	t.Run("key bundle without tlog", func(t *testing.T) {
+		t.Parallel()
+
+		p, err := NewPolicy(WithPublicKeyPEM(pubPEM))
+		require.NoError(t, err)
+		require.NoError(t, p.Evaluate(ctx, signedRequest(manifest, keyBundle)), "not required by default for keys")
+
+		p, err = NewPolicy(WithPublicKeyPEM(pubPEM), WithRequireTlog(false))
+		require.NoError(t, err)
+		require.NoError(t, p.Evaluate(ctx, signedRequest(manifest, keyBundle)))
+
+		for _, opt := range []PolicyOption{WithRequireTlog(true), WithTlogThreshold(1)} {
+			p, err = NewPolicy(WithPublicKeyPEM(pubPEM), WithTrustedRootFile(keyless.trustedRootPath), opt)
+			require.NoError(t, err)
+			err = p.Evaluate(ctx, signedRequest(manifest, keyBundle))
+			assertReason(t, err, blobpolicy.ReasonInvalidSignature)
+		}
+	})
+
+	t.Run("keyless bundle with one tlog entry", func(t *testing.T) {
+		t.Parallel()
+
+		req := signedRequest(keyless.manifest, keyless.bundle)
+		for _, opt := range []PolicyOption{WithRequireTlog(true), WithTlogThreshold(1)} {
+			p, err := NewPolicy(WithTrustedRootFile(keyless.trustedRootPath), opt)
+			require.NoError(t, err)
+			require.NoError(t, p.Evaluate(ctx, req))
+		}
+
+		p, err := NewPolicy(WithTrustedRootFile(keyless.trustedRootPath), WithTlogThreshold(2))
+		require.NoError(t, err)
+		err = p.Evaluate(ctx, req)
+		assertReason(t, err, blobpolicy.ReasonInvalidSignature)
+
+		// Without the log, a keyless bundle needs a signed timestamp, which
+		// this one lacks.
+		p, err = NewPolicy(WithTrustedRootFile(keyless.trustedRootPath), WithRequireTlog(false))
+		require.NoError(t, err)
+		err = p.Evaluate(ctx, req)
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "timestamps")
+	})
+
+	t.Run("negative threshold", func(t *testing.T) {
+		t.Parallel()
+
+		_, err := NewPolicy(WithPublicKeyPEM(pubPEM), WithTlogThreshold(-1))
+		require.Error(t, err)
+	})
+}
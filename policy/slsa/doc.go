@@ -28,6 +28,11 @@
 //	policy := slsa.RequireSource("https://github.com/myorg/myrepo",
 //	    slsa.WithBranches("main"))
 //
+// [RequireMaterials] verifies every build input comes from an allowed location:
+//
+//	policy := slsa.RequireMaterials(
+//	    slsa.WithMaterialURIPrefix("git+https://github.com/myorg/"))
+//
 // [GitHubActionsWorkflow] combines builder and source validation for GitHub Actions:
 //
 //	policy, err := slsa.GitHubActionsWorkflow("myorg/myrepo",
@@ -1,6 +1,10 @@
 package slsa
 
-import "errors"
+import (
+	"errors"
+
+	blobpolicy "github.com/meigma/blob/policy"
+)
 
 // Sentinel errors for SLSA policy validation.
 var (
@@ -22,6 +26,15 @@ var (
 	// ErrWorkflowMismatch indicates the workflow path doesn't match.
 	ErrWorkflowMismatch = errors.New("slsa: workflow path mismatch")
 
+	// ErrMaterialNotAllowed indicates a build material comes from outside
+	// the allowed locations.
+	ErrMaterialNotAllowed = errors.New("slsa: material not allowed")
+
 	// ErrNoValidators indicates no validators were configured.
 	ErrNoValidators = errors.New("slsa: at least one validator required")
 )
+
+// verificationError returns a policy.VerificationError from the slsa policy.
+func verificationError(reason blobpolicy.Reason, subject string, err error) error {
+	return &blobpolicy.VerificationError{Policy: "slsa", Reason: reason, Subject: subject, Err: err}
+}
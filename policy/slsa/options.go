@@ -1,6 +1,7 @@
 package slsa
 
 import (
+	"fmt"
 	"log/slog"
 	"regexp"
 	"strings"
@@ -26,6 +27,22 @@ func WithArtifactTypes(types ...string) PolicyOption {
 	}
 }
 
+// WithPredicateTypes restricts the SLSA provenance versions accepted, for
+// example to "https://slsa.dev/provenance/v1" only. Provenance with other
+// predicate types is ignored. Each type must be one of SLSAPredicateTypes,
+// which is also the default.
+func WithPredicateTypes(types ...string) PolicyOption {
+	return func(p *Policy) error {
+		for _, t := range types {
+			if !isSLSAPredicateType(t) {
+				return fmt.Errorf("unsupported predicate type %q", t)
+			}
+		}
+		p.predicateTypes = types
+		return nil
+	}
+}
+
 // SourceOption configures RequireSource.
 type SourceOption func(*sourceConfig)
 
@@ -66,6 +83,22 @@ func WithTags(tags ...string) SourceOption {
 	}
 }
 
+// MaterialOption configures RequireMaterials.
+type MaterialOption func(*materialConfig)
+
+type materialConfig struct {
+	uriPrefixes []string
+}
+
+// WithMaterialURIPrefix allows materials whose URI starts with prefix, such
+// as "git+https://github.com/myorg/" or "pkg:golang/". It may be given
+// more than once.
+func WithMaterialURIPrefix(prefix string) MaterialOption {
+	return func(c *materialConfig) {
+		c.uriPrefixes = append(c.uriPrefixes, prefix)
+	}
+}
+
 // GitHubActionsWorkflowOption configures GitHubActionsWorkflow.
 type GitHubActionsWorkflowOption func(*ghActionsConfig)
 
@@ -6,18 +6,21 @@ import (
 	"errors"
 	"fmt"
 	"log/slog"
+	"slices"
 	"strings"
 
 	ocispec "github.com/opencontainers/image-spec/specs-go/v1"
 
+	blobpolicy "github.com/meigma/blob/policy"
 	"github.com/meigma/blob/registry"
 )
 
 // Policy implements registry.Policy for SLSA provenance validation.
 type Policy struct {
-	validators    []provenanceValidator
-	artifactTypes []string
-	logger        *slog.Logger
+	validators     []provenanceValidator
+	artifactTypes  []string
+	predicateTypes []string
+	logger         *slog.Logger
 }
 
 // provenanceValidator validates a single provenance attestation.
@@ -26,8 +29,9 @@ type provenanceValidator func(*Provenance) error
 // NewPolicy creates an SLSA provenance policy with the given options.
 func NewPolicy(opts ...PolicyOption) (*Policy, error) {
 	p := &Policy{
-		artifactTypes: []string{InTotoArtifactType, SigstoreBundleArtifactType},
-		logger:        slog.New(slog.DiscardHandler),
+		artifactTypes:  []string{InTotoArtifactType, SigstoreBundleArtifactType},
+		predicateTypes: SLSAPredicateTypes,
+		logger:         slog.New(slog.DiscardHandler),
 	}
 
 	for _, opt := range opts {
@@ -61,7 +65,7 @@ func (p *Policy) Evaluate(ctx context.Context, req registry.PolicyRequest) error
 		slog.Int("count", len(provenances)))
 
 	if len(provenances) == 0 {
-		return ErrNoAttestations
+		return verificationError(blobpolicy.ReasonNoAttestation, "", ErrNoAttestations)
 	}
 
 	// Try to find at least one valid provenance
@@ -98,7 +102,8 @@ func (p *Policy) fetchProvenances(ctx context.Context, req registry.PolicyReques
 		referrers, err := req.Client.Referrers(ctx, req.Ref, req.Subject, artifactType)
 		if err != nil {
 			if errors.Is(err, registry.ErrReferrersUnsupported) {
-				return nil, errors.New("slsa: registry does not support referrers API")
+				return nil, verificationError(blobpolicy.ReasonReferrersUnsupported, "",
+					errors.New("slsa: registry does not support referrers API"))
 			}
 			p.logger.Debug("failed to list referrers",
 				slog.String("artifact_type", artifactType),
@@ -108,9 +113,15 @@ func (p *Policy) fetchProvenances(ctx context.Context, req registry.PolicyReques
 
 		for _, ref := range referrers {
 			prov := p.fetchProvenance(ctx, req, ref)
-			if prov != nil {
-				provenances = append(provenances, prov)
+			if prov == nil {
+				continue
+			}
+			if !slices.Contains(p.predicateTypes, prov.PredicateType) {
+				p.logger.Debug("skipping provenance with non-accepted predicate type",
+					slog.String("predicate_type", prov.PredicateType))
+				continue
 			}
+			provenances = append(provenances, prov)
 		}
 	}
 
@@ -208,6 +219,30 @@ func RequireSource(repo string, opts ...SourceOption) *Policy {
 	return p
 }
 
+// RequireMaterials creates a policy requiring every build input to come from
+// an allowed location.
+//
+// Each material (resolvedDependencies in SLSA v1, materials in v0.2) must
+// have a URI starting with one of the prefixes given with
+// WithMaterialURIPrefix; a material outside them, or without a URI, fails
+// with ErrMaterialNotAllowed. Without prefixes, any material is denied.
+// Provenance that lists no materials passes.
+//
+// Example:
+//
+//	policy := slsa.RequireMaterials(
+//	    slsa.WithMaterialURIPrefix("git+https://github.com/myorg/"),
+//	    slsa.WithMaterialURIPrefix("pkg:golang/"),
+//	)
+func RequireMaterials(opts ...MaterialOption) *Policy {
+	cfg := &materialConfig{}
+	for _, opt := range opts {
+		opt(cfg)
+	}
+	p, _ := NewPolicy(withMaterialsValidator(cfg))
+	return p
+}
+
 // GitHubActionsWorkflow creates a policy for GitHub Actions workflows.
 //
 // This validates that:
@@ -257,8 +292,8 @@ func withBuilderValidator(builderID string) PolicyOption {
 	return func(p *Policy) error {
 		p.validators = append(p.validators, func(prov *Provenance) error {
 			if prov.BuilderID != builderID {
-				return fmt.Errorf("%w: got %q, want %q",
-					ErrBuilderMismatch, prov.BuilderID, builderID)
+				return verificationError(blobpolicy.ReasonBuilderMismatch, prov.BuilderID,
+					fmt.Errorf("%w: got %q, want %q", ErrBuilderMismatch, prov.BuilderID, builderID))
 			}
 			return nil
 		})
@@ -270,13 +305,13 @@ func withSourceValidator(cfg *sourceConfig) PolicyOption {
 	return func(p *Policy) error {
 		p.validators = append(p.validators, func(prov *Provenance) error {
 			if !strings.HasPrefix(prov.SourceRepo, cfg.repo) {
-				return fmt.Errorf("%w: got %q, want prefix %q",
-					ErrSourceMismatch, prov.SourceRepo, cfg.repo)
+				return verificationError(blobpolicy.ReasonSourceMismatch, prov.SourceRepo,
+					fmt.Errorf("%w: got %q, want prefix %q", ErrSourceMismatch, prov.SourceRepo, cfg.repo))
 			}
 
 			if cfg.ref != "" && prov.SourceRef != cfg.ref {
-				return fmt.Errorf("%w: got %q, want %q",
-					ErrRefMismatch, prov.SourceRef, cfg.ref)
+				return verificationError(blobpolicy.ReasonRefMismatch, prov.SourceRef,
+					fmt.Errorf("%w: got %q, want %q", ErrRefMismatch, prov.SourceRef, cfg.ref))
 			}
 
 			if len(cfg.refPatterns) > 0 {
@@ -288,8 +323,8 @@ func withSourceValidator(cfg *sourceConfig) PolicyOption {
 					}
 				}
 				if !matched {
-					return fmt.Errorf("%w: %q does not match allowed patterns",
-						ErrRefMismatch, prov.SourceRef)
+					return verificationError(blobpolicy.ReasonRefMismatch, prov.SourceRef,
+						fmt.Errorf("%w: %q does not match allowed patterns", ErrRefMismatch, prov.SourceRef))
 				}
 			}
 
@@ -299,20 +334,37 @@ func withSourceValidator(cfg *sourceConfig) PolicyOption {
 	}
 }
 
+func withMaterialsValidator(cfg *materialConfig) PolicyOption {
+	return func(p *Policy) error {
+		p.validators = append(p.validators, func(prov *Provenance) error {
+			for _, m := range prov.Materials {
+				if m.URI == "" || !slices.ContainsFunc(cfg.uriPrefixes, func(prefix string) bool {
+					return strings.HasPrefix(m.URI, prefix)
+				}) {
+					return verificationError(blobpolicy.ReasonMaterialNotAllowed, m.URI,
+						fmt.Errorf("%w: %q is outside the allowed prefixes %q", ErrMaterialNotAllowed, m.URI, cfg.uriPrefixes))
+				}
+			}
+			return nil
+		})
+		return nil
+	}
+}
+
 func withGitHubActionsValidator(cfg *ghActionsConfig) PolicyOption {
 	return func(p *Policy) error {
 		p.validators = append(p.validators, func(prov *Provenance) error {
 			// Verify repository
 			expectedRepo := "https://github.com/" + cfg.repo
 			if !strings.HasPrefix(prov.SourceRepo, expectedRepo) {
-				return fmt.Errorf("%w: got %q, want prefix %q",
-					ErrSourceMismatch, prov.SourceRepo, expectedRepo)
+				return verificationError(blobpolicy.ReasonSourceMismatch, prov.SourceRepo,
+					fmt.Errorf("%w: got %q, want prefix %q", ErrSourceMismatch, prov.SourceRepo, expectedRepo))
 			}
 
 			// Verify workflow path if specified
 			if cfg.workflowPath != "" && prov.WorkflowPath != cfg.workflowPath {
-				return fmt.Errorf("%w: got %q, want %q",
-					ErrWorkflowMismatch, prov.WorkflowPath, cfg.workflowPath)
+				return verificationError(blobpolicy.ReasonWorkflowMismatch, prov.WorkflowPath,
+					fmt.Errorf("%w: got %q, want %q", ErrWorkflowMismatch, prov.WorkflowPath, cfg.workflowPath))
 			}
 
 			// Verify ref patterns if specified
@@ -325,8 +377,8 @@ func withGitHubActionsValidator(cfg *ghActionsConfig) PolicyOption {
 					}
 				}
 				if !matched {
-					return fmt.Errorf("%w: %q does not match allowed patterns",
-						ErrRefMismatch, prov.SourceRef)
+					return verificationError(blobpolicy.ReasonRefMismatch, prov.SourceRef,
+						fmt.Errorf("%w: %q does not match allowed patterns", ErrRefMismatch, prov.SourceRef))
 				}
 			}
 
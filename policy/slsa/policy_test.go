@@ -12,6 +12,7 @@ import (
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 
+	blobpolicy "github.com/meigma/blob/policy"
 	"github.com/meigma/blob/registry"
 )
 
@@ -572,3 +573,256 @@ func TestGlobToRegex(t *testing.T) {
 		})
 	}
 }
+
+// realSLSAv1Statement returns provenance shaped like the output of
+// actions/attest-build-provenance.
+func realSLSAv1Statement() map[string]any {
+	return map[string]any{
+		"_type":         "https://in-toto.io/Statement/v1",
+		"predicateType": "https://slsa.dev/provenance/v1",
+		"subject":       []any{map[string]any{"name": "archive", "digest": map[string]any{"sha256": "abc123"}}},
+		"predicate": map[string]any{
+			"buildDefinition": map[string]any{
+				"buildType": "https://actions.github.io/buildtypes/workflow/v1",
+				"externalParameters": map[string]any{
+					"workflow": map[string]any{
+						"ref":        "refs/heads/main",
+						"repository": "https://github.com/myorg/myrepo",
+						"path":       ".github/workflows/release.yml",
+					},
+				},
+				"internalParameters": map[string]any{
+					"github": map[string]any{"event_name": "push", "repository_id": "123"},
+				},
+				"resolvedDependencies": []any{
+					map[string]any{
+						"uri":    "git+https://github.com/myorg/myrepo@refs/heads/main",
+						"digest": map[string]any{"gitCommit": "0123456789abcdef0123456789abcdef01234567"},
+					},
+				},
+			},
+			"runDetails": map[string]any{
+				"builder":  map[string]any{"id": "https://github.com/myorg/myrepo/.github/workflows/release.yml@refs/heads/main"},
+				"metadata": map[string]any{"invocationId": "https://github.com/myorg/myrepo/actions/runs/1/attempts/1"},
+				"byproducts": []any{
+					map[string]any{"name": "build.log", "digest": map[string]any{"sha256": "def456"}},
+				},
+			},
+		},
+	}
+}
+
+// realSLSAv02Statement returns provenance shaped like the output of the
+// slsa-github-generator generic workflow.
+func realSLSAv02Statement() map[string]any {
+	return map[string]any{
+		"_type":         "https://in-toto.io/Statement/v0.1",
+		"predicateType": "https://slsa.dev/provenance/v0.2",
+		"subject":       []any{map[string]any{"name": "archive", "digest": map[string]any{"sha256": "abc123"}}},
+		"predicate": map[string]any{
+			"builder":   map[string]any{"id": "https://github.com/slsa-framework/slsa-github-generator/.github/workflows/generator_generic_slsa3.yml@refs/tags/v1.9.0"},
+			"buildType": "https://github.com/slsa-framework/slsa-github-generator/generic@v1",
+			"invocation": map[string]any{
+				"configSource": map[string]any{
+					"uri":        "git+https://github.com/myorg/myrepo@refs/heads/main",
+					"digest":     map[string]any{"sha1": "0123456789abcdef0123456789abcdef01234567"},
+					"entryPoint": ".github/workflows/release.yml",
+				},
+				"environment": map[string]any{"github_event_name": "push"},
+			},
+			"materials": []any{
+				map[string]any{
+					"uri":    "git+https://github.com/myorg/myrepo@refs/heads/main",
+					"digest": map[string]any{"sha1": "0123456789abcdef0123456789abcdef01234567"},
+				},
+			},
+		},
+	}
+}
+
+// statementRequest returns a request whose referrers are the statements.
+func statementRequest(statements ...map[string]any) registry.PolicyRequest {
+	client := &mockPolicyClient{descriptors: make(map[string][]byte)}
+	for _, stmt := range statements {
+		envelope := createDSSEEnvelope(stmt)
+		d := digest.FromBytes(envelope)
+		client.referrers = append(client.referrers, ocispec.Descriptor{
+			MediaType: InTotoArtifactType,
+			Digest:    d,
+			Size:      int64(len(envelope)),
+		})
+		client.descriptors[d.String()] = envelope
+	}
+	return registry.PolicyRequest{
+		Ref:     "example.com/repo:tag",
+		Digest:  "sha256:abc123",
+		Subject: ocispec.Descriptor{MediaType: ocispec.MediaTypeImageManifest, Digest: digest.FromString("manifest")},
+		Client:  client,
+	}
+}
+
+func TestParseProvenance_Versions(t *testing.T) {
+	t.Parallel()
+
+	for name, stmt := range map[string]map[string]any{
+		"v1":   realSLSAv1Statement(),
+		"v0.2": realSLSAv02Statement(),
+	} {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			prov, err := ParseProvenance(createDSSEEnvelope(stmt))
+			require.NoError(t, err)
+			assert.Equal(t, "https://github.com/myorg/myrepo", prov.SourceRepo)
+			assert.Equal(t, "refs/heads/main", prov.SourceRef)
+			assert.Equal(t, "0123456789abcdef0123456789abcdef01234567", prov.SourceDigest)
+			assert.Equal(t, ".github/workflows/release.yml", prov.WorkflowPath)
+			require.Len(t, prov.Materials, 1)
+			assert.Equal(t, "git+https://github.com/myorg/myrepo@refs/heads/main", prov.Materials[0].URI)
+
+			policy := RequireSource("https://github.com/myorg/myrepo", WithBranches("main"))
+			require.NoError(t, policy.Evaluate(context.Background(), statementRequest(stmt)))
+		})
+	}
+
+	prov, err := ParseProvenance(createDSSEEnvelope(realSLSAv1Statement()))
+	require.NoError(t, err)
+	assert.Equal(t, []ResourceDescriptor{{Name: "build.log", Digest: map[string]string{"sha256": "def456"}}}, prov.Byproducts)
+}
+
+func TestWithPredicateTypes(t *testing.T) {
+	t.Parallel()
+
+	v1Only, err := GitHubActionsWorkflow("myorg/myrepo", WithPredicateTypes("https://slsa.dev/provenance/v1"))
+	require.NoError(t, err)
+	require.NoError(t, v1Only.Evaluate(context.Background(), statementRequest(realSLSAv1Statement())))
+	require.ErrorIs(t, v1Only.Evaluate(context.Background(), statementRequest(realSLSAv02Statement())), ErrNoAttestations)
+	require.NoError(t, v1Only.Evaluate(context.Background(),
+		statementRequest(realSLSAv02Statement(), realSLSAv1Statement())))
+
+	_, err = GitHubActionsWorkflow("myorg/myrepo", WithPredicateTypes("https://example.com/other"))
+	require.Error(t, err)
+}
+
+func TestSplitGitURI(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		uri, repo, ref string
+	}{
+		{"git+https://github.com/myorg/myrepo@refs/heads/main", "https://github.com/myorg/myrepo", "refs/heads/main"},
+		{"git+https://github.com/myorg/myrepo.git@refs/tags/v1.0.0", "https://github.com/myorg/myrepo", "refs/tags/v1.0.0"},
+		{"git+https://user@example.com/repo@abc123", "https://user@example.com/repo", "abc123"},
+		{"git+https://github.com/myorg/myrepo", "https://github.com/myorg/myrepo", ""},
+		{"https://github.com/myorg/myrepo", "https://github.com/myorg/myrepo", ""},
+	}
+	for _, tt := range tests {
+		repo, ref := splitGitURI(tt.uri)
+		assert.Equal(t, tt.repo, repo, tt.uri)
+		assert.Equal(t, tt.ref, ref, tt.uri)
+	}
+}
+
+func TestRequireMaterials(t *testing.T) {
+	t.Parallel()
+
+	withMaterial := func(stmt map[string]any, uri string) map[string]any {
+		pred := stmt["predicate"].(map[string]any)
+		material := map[string]any{"uri": uri, "digest": map[string]any{"sha256": "fed987"}}
+		if def, ok := pred["buildDefinition"].(map[string]any); ok {
+			def["resolvedDependencies"] = append(def["resolvedDependencies"].([]any), material)
+		} else {
+			pred["materials"] = append(pred["materials"].([]any), material)
+		}
+		return stmt
+	}
+
+	policy := RequireMaterials(
+		WithMaterialURIPrefix("git+https://github.com/myorg/"),
+		WithMaterialURIPrefix("pkg:golang/"),
+	)
+
+	for name, newStmt := range map[string]func() map[string]any{
+		"v1":   realSLSAv1Statement,
+		"v0.2": realSLSAv02Statement,
+	} {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			allowed := withMaterial(newStmt(), "pkg:golang/golang.org/x/sync@v0.10.0")
+			require.NoError(t, policy.Evaluate(context.Background(), statementRequest(allowed)))
+
+			injected := withMaterial(newStmt(), "git+https://github.com/attacker/payload@refs/heads/main")
+			err := policy.Evaluate(context.Background(), statementRequest(injected))
+			require.ErrorIs(t, err, ErrMaterialNotAllowed)
+			assert.Contains(t, err.Error(), "attacker/payload")
+		})
+	}
+
+	t.Run("no prefixes", func(t *testing.T) {
+		t.Parallel()
+
+		err := RequireMaterials().Evaluate(context.Background(), statementRequest(realSLSAv1Statement()))
+		require.ErrorIs(t, err, ErrMaterialNotAllowed)
+	})
+}
+
+func TestVerificationErrors(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	tests := []struct {
+		name    string
+		policy  registry.Policy
+		req     registry.PolicyRequest
+		reason  blobpolicy.Reason
+		subject string
+	}{
+		{
+			name:    "builder",
+			policy:  RequireBuilder("https://builder.example.com"),
+			req:     statementRequest(realSLSAv1Statement()),
+			reason:  blobpolicy.ReasonBuilderMismatch,
+			subject: "https://github.com/myorg/myrepo/.github/workflows/release.yml@refs/heads/main",
+		},
+		{
+			name:    "source",
+			policy:  RequireSource("https://github.com/other/repo"),
+			req:     statementRequest(realSLSAv02Statement()),
+			reason:  blobpolicy.ReasonSourceMismatch,
+			subject: "https://github.com/myorg/myrepo",
+		},
+		{
+			name:    "ref",
+			policy:  RequireSource("https://github.com/myorg/myrepo", WithTags("v*")),
+			req:     statementRequest(realSLSAv1Statement()),
+			reason:  blobpolicy.ReasonRefMismatch,
+			subject: "refs/heads/main",
+		},
+		{
+			name:    "material",
+			policy:  RequireMaterials(WithMaterialURIPrefix("pkg:")),
+			req:     statementRequest(realSLSAv1Statement()),
+			reason:  blobpolicy.ReasonMaterialNotAllowed,
+			subject: "git+https://github.com/myorg/myrepo@refs/heads/main",
+		},
+		{
+			name:   "no attestations",
+			policy: RequireBuilder("https://builder.example.com"),
+			req:    statementRequest(),
+			reason: blobpolicy.ReasonNoAttestation,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			err := tt.policy.Evaluate(ctx, tt.req)
+			var verr *blobpolicy.VerificationError
+			require.ErrorAs(t, err, &verr)
+			assert.Equal(t, "slsa", verr.Policy)
+			assert.Equal(t, tt.reason, verr.Reason)
+			assert.Equal(t, tt.subject, verr.Subject)
+		})
+	}
+}
@@ -4,14 +4,15 @@ import (
 	"encoding/base64"
 	"encoding/json"
 	"fmt"
+	"strings"
 
 	"github.com/secure-systems-lab/go-securesystemslib/dsse"
 )
 
 // SLSAPredicateTypes are the supported SLSA provenance predicate types.
 var SLSAPredicateTypes = []string{
-	"https://slsa.dev/provenance/v1",
-	"https://slsa.dev/provenance/v0.2",
+	predicateTypeV1,
+	predicateTypeV02,
 }
 
 // DSSEPayloadType is the expected payload type for in-toto statements.
@@ -48,10 +49,33 @@ type Provenance struct {
 	// WorkflowPath is the workflow file path (for GitHub Actions).
 	WorkflowPath string
 
+	// Materials are the build inputs.
+	// For SLSA v1: from buildDefinition.resolvedDependencies
+	// For SLSA v0.2: from materials
+	Materials []ResourceDescriptor
+
+	// Byproducts are additional build outputs recorded by the builder, such
+	// as logs or SBOMs, from runDetails.byproducts (v1 only).
+	Byproducts []ResourceDescriptor
+
 	// Raw contains the full predicate for advanced inspection.
 	Raw map[string]any
 }
 
+// ResourceDescriptor identifies a build input or output. It covers both the
+// v1 ResourceDescriptor and the v0.2 material layouts.
+type ResourceDescriptor struct {
+	// URI locates the resource, such as
+	// "git+https://github.com/myorg/myrepo@refs/heads/main".
+	URI string `json:"uri"`
+
+	// Digest maps algorithm names to digest values.
+	Digest map[string]string `json:"digest"`
+
+	// Name is the resource name (v1 only).
+	Name string `json:"name"`
+}
+
 // inTotoStatement represents an in-toto statement.
 type inTotoStatement struct {
 	Type          string           `json:"_type"`
@@ -119,7 +143,7 @@ func parseFromDSSE(envelope *dsse.Envelope) (*Provenance, error) {
 		}
 	}
 
-	return extractProvenance(stmt.PredicateType, predicate)
+	return extractProvenance(stmt.PredicateType, stmt.Predicate, predicate)
 }
 
 func isSLSAPredicateType(pt string) bool {
@@ -131,94 +155,198 @@ func isSLSAPredicateType(pt string) bool {
 	return false
 }
 
-func extractProvenance(predicateType string, predicate map[string]any) (*Provenance, error) {
+// SLSA provenance predicate types.
+const (
+	predicateTypeV1  = "https://slsa.dev/provenance/v1"
+	predicateTypeV02 = "https://slsa.dev/provenance/v0.2"
+)
+
+// predicateV1 holds the fields read from an SLSA v1 predicate.
+type predicateV1 struct {
+	BuildDefinition struct {
+		BuildType          string `json:"buildType"`
+		ExternalParameters struct {
+			// GitHub Actions build types.
+			Workflow struct {
+				Repository string `json:"repository"`
+				Ref        string `json:"ref"`
+				Path       string `json:"path"`
+			} `json:"workflow"`
+			// Generic build types; either a URI or a resource descriptor.
+			Source json.RawMessage `json:"source"`
+		} `json:"externalParameters"`
+		ResolvedDependencies []ResourceDescriptor `json:"resolvedDependencies"`
+	} `json:"buildDefinition"`
+	RunDetails struct {
+		Builder struct {
+			ID string `json:"id"`
+		} `json:"builder"`
+		Byproducts []ResourceDescriptor `json:"byproducts"`
+	} `json:"runDetails"`
+}
+
+// predicateV02 holds the fields read from an SLSA v0.2 predicate.
+type predicateV02 struct {
+	Builder struct {
+		ID string `json:"id"`
+	} `json:"builder"`
+	Invocation struct {
+		ConfigSource struct {
+			URI        string            `json:"uri"`
+			Digest     map[string]string `json:"digest"`
+			EntryPoint string            `json:"entryPoint"`
+		} `json:"configSource"`
+	} `json:"invocation"`
+	Materials []ResourceDescriptor `json:"materials"`
+}
+
+// extractProvenance normalizes a predicate of either version into a
+// Provenance. raw is the predicate JSON and predicate its decoded form.
+func extractProvenance(predicateType string, raw *json.RawMessage, predicate map[string]any) (*Provenance, error) {
 	prov := &Provenance{
 		PredicateType: predicateType,
 		Raw:           predicate,
 	}
+	if raw == nil {
+		return prov, nil
+	}
 
-	// SLSA v1 format
-	if predicateType == "https://slsa.dev/provenance/v1" {
-		extractSLSAv1(prov, predicate)
+	var err error
+	if predicateType == predicateTypeV1 {
+		err = extractSLSAv1(prov, *raw)
 	} else {
-		// SLSA v0.2 format
-		extractSLSAv02(prov, predicate)
+		err = extractSLSAv02(prov, *raw)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%w: parse predicate: %v", ErrInvalidProvenance, err)
 	}
-
 	return prov, nil
 }
 
 // extractSLSAv1 extracts fields from SLSA provenance v1 format.
-func extractSLSAv1(prov *Provenance, predicate map[string]any) {
-	// Builder ID: runDetails.builder.id
-	if runDetails, ok := predicate["runDetails"].(map[string]any); ok {
-		if builder, ok := runDetails["builder"].(map[string]any); ok {
-			prov.BuilderID, _ = builder["id"].(string)
+//
+// The source comes from externalParameters.workflow for GitHub Actions
+// build types, then externalParameters.source, then the first resolved
+// dependency with a git URI.
+func extractSLSAv1(prov *Provenance, raw json.RawMessage) error {
+	var pred predicateV1
+	if err := json.Unmarshal(raw, &pred); err != nil {
+		return err
+	}
+	def := &pred.BuildDefinition
+	prov.BuilderID = pred.RunDetails.Builder.ID
+	prov.BuildType = def.BuildType
+	prov.Materials = def.ResolvedDependencies
+	prov.Byproducts = pred.RunDetails.Byproducts
+
+	workflow := def.ExternalParameters.Workflow
+	prov.SourceRepo, prov.SourceRef = normalizeRepo(workflow.Repository), workflow.Ref
+	prov.WorkflowPath = workflow.Path
+
+	if prov.SourceRepo == "" {
+		if uri := sourceURI(def.ExternalParameters.Source); uri != "" {
+			prov.SourceRepo, prov.SourceRef = splitGitURI(uri)
 		}
 	}
+	setSourceFromMaterials(prov, "gitCommit", "sha1")
+	return nil
+}
 
-	// Build definition fields
-	if buildDef, ok := predicate["buildDefinition"].(map[string]any); ok {
-		prov.BuildType, _ = buildDef["buildType"].(string)
+// extractSLSAv02 extracts fields from SLSA provenance v0.2 format.
+//
+// The source comes from invocation.configSource, then the first material
+// with a git URI.
+func extractSLSAv02(prov *Provenance, raw json.RawMessage) error {
+	var pred predicateV02
+	if err := json.Unmarshal(raw, &pred); err != nil {
+		return err
+	}
+	prov.BuilderID = pred.Builder.ID
+	prov.Materials = pred.Materials
 
-		if extParams, ok := buildDef["externalParameters"].(map[string]any); ok {
-			// GitHub Actions format
-			if workflow, ok := extParams["workflow"].(map[string]any); ok {
-				prov.SourceRepo, _ = workflow["repository"].(string)
-				prov.SourceRef, _ = workflow["ref"].(string)
-				prov.WorkflowPath, _ = workflow["path"].(string)
-			}
-		}
+	config := &pred.Invocation.ConfigSource
+	if config.URI != "" {
+		prov.SourceRepo, prov.SourceRef = splitGitURI(config.URI)
+		prov.SourceDigest = config.Digest["sha1"]
+	}
+	prov.WorkflowPath = config.EntryPoint
 
-		// Resolved dependencies for source info
-		if resolvedDeps, ok := buildDef["resolvedDependencies"].([]any); ok {
-			for _, dep := range resolvedDeps {
-				if depMap, ok := dep.(map[string]any); ok {
-					if uri, ok := depMap["uri"].(string); ok && prov.SourceRepo == "" {
-						prov.SourceRepo = uri
-					}
-					if digest, ok := depMap["digest"].(map[string]any); ok {
-						if sha, ok := digest["gitCommit"].(string); ok {
-							prov.SourceDigest = sha
-						}
-					}
-				}
-			}
-		}
+	setSourceFromMaterials(prov, "sha1", "gitCommit")
+	return nil
+}
+
+// sourceURI returns the URI of a v1 externalParameters.source value, which
+// is either a string or a resource descriptor.
+func sourceURI(raw json.RawMessage) string {
+	if len(raw) == 0 {
+		return ""
 	}
+	var uri string
+	if json.Unmarshal(raw, &uri) == nil {
+		return uri
+	}
+	var desc ResourceDescriptor
+	if json.Unmarshal(raw, &desc) == nil {
+		return desc.URI
+	}
+	return ""
 }
 
-// extractSLSAv02 extracts fields from SLSA provenance v0.2 format.
-func extractSLSAv02(prov *Provenance, predicate map[string]any) {
-	// Builder ID: builder.id
-	if builder, ok := predicate["builder"].(map[string]any); ok {
-		prov.BuilderID, _ = builder["id"].(string)
-	}
-
-	// Invocation fields
-	if invocation, ok := predicate["invocation"].(map[string]any); ok {
-		if configSource, ok := invocation["configSource"].(map[string]any); ok {
-			prov.SourceRepo, _ = configSource["uri"].(string)
-			if digest, ok := configSource["digest"].(map[string]any); ok {
-				prov.SourceDigest, _ = digest["sha1"].(string)
+// setSourceFromMaterials fills in the source fields that are still empty
+// from the materials. The repository and ref come from the first git
+// material; the digest from the material for the source repository, using
+// the first of digestKeys it has.
+func setSourceFromMaterials(prov *Provenance, digestKeys ...string) {
+	for _, m := range prov.Materials {
+		if !strings.HasPrefix(m.URI, "git+") {
+			continue
+		}
+		repo, ref := splitGitURI(m.URI)
+		if prov.SourceRepo == "" {
+			prov.SourceRepo = repo
+		}
+		if repo != prov.SourceRepo {
+			continue
+		}
+		if prov.SourceRef == "" {
+			prov.SourceRef = ref
+		}
+		if prov.SourceDigest == "" {
+			for _, key := range digestKeys {
+				if d := m.Digest[key]; d != "" {
+					prov.SourceDigest = d
+					break
+				}
 			}
-			prov.WorkflowPath, _ = configSource["entryPoint"].(string)
 		}
+		return
 	}
+}
 
-	// Materials for source info
-	if materials, ok := predicate["materials"].([]any); ok {
-		for _, mat := range materials {
-			if matMap, ok := mat.(map[string]any); ok {
-				if uri, ok := matMap["uri"].(string); ok && prov.SourceRepo == "" {
-					prov.SourceRepo = uri
-				}
-				if digest, ok := matMap["digest"].(map[string]any); ok {
-					if sha, ok := digest["sha1"].(string); ok && prov.SourceDigest == "" {
-						prov.SourceDigest = sha
-					}
-				}
-			}
+// splitGitURI splits an SPDX-style git URI such as
+// "git+https://github.com/myorg/myrepo@refs/heads/main" into the repository
+// URL "https://github.com/myorg/myrepo" and the ref "refs/heads/main".
+// Other URIs are returned unchanged with an empty ref.
+func splitGitURI(uri string) (repo, ref string) {
+	if !strings.HasPrefix(uri, "git+") {
+		return uri, ""
+	}
+	repo = strings.TrimPrefix(uri, "git+")
+	// The ref follows the last "@" in the path; an "@" before the path
+	// separates user info from the host.
+	path := 0
+	if scheme := strings.Index(repo, "://"); scheme >= 0 {
+		if slash := strings.IndexByte(repo[scheme+3:], '/'); slash >= 0 {
+			path = scheme + 3 + slash
 		}
 	}
+	if at := strings.LastIndexByte(repo[path:], '@'); at >= 0 {
+		repo, ref = repo[:path+at], repo[path+at+1:]
+	}
+	return normalizeRepo(repo), ref
+}
+
+// normalizeRepo strips a trailing ".git" from a repository URL.
+func normalizeRepo(repo string) string {
+	return strings.TrimSuffix(repo, ".git")
 }
@@ -37,4 +37,16 @@ const (
 
 	// StageExtracting indicates files are being extracted.
 	StageExtracting = blobcore.StageExtracting
+
+	// StageVerifying indicates files are being verified against their hashes.
+	StageVerifying = blobcore.StageVerifying
+
+	// StageFetchingData indicates data blob bytes are being fetched.
+	StageFetchingData = blobcore.StageFetchingData
+
+	// StageExtractingFile indicates a single file has started extracting.
+	StageExtractingFile = blobcore.StageExtractingFile
+
+	// StageExtractedFile indicates a single file has finished extracting.
+	StageExtractedFile = blobcore.StageExtractedFile
 )
@@ -2,6 +2,8 @@ package blob
 
 import (
 	"context"
+	"maps"
+	"time"
 
 	blobcore "github.com/meigma/blob/core"
 	"github.com/meigma/blob/registry"
@@ -11,6 +13,22 @@ import (
 // It embeds *core.Blob, so all Blob methods are directly accessible.
 type Archive struct {
 	*blobcore.Blob
+
+	manifest *Manifest
+}
+
+// Manifest returns the manifest the archive was pulled from.
+func (a *Archive) Manifest() *Manifest {
+	return a.manifest
+}
+
+// Annotations returns a copy of the manifest annotations, such as those set
+// with [PushWithAnnotations] and org.opencontainers.image.created.
+func (a *Archive) Annotations() map[string]string {
+	if a.manifest == nil {
+		return nil
+	}
+	return maps.Clone(a.manifest.Annotations())
 }
 
 // Pull retrieves an archive from the registry with lazy data loading.
@@ -27,8 +45,14 @@ func (c *Client) Pull(ctx context.Context, ref string, opts ...PullOption) (*Arc
 
 	c.log().Info("pulling from registry", "ref", ref)
 
-	// Build registry client options
-	regOpts := buildRegistryOpts(c)
+	event := &AuditEvent{Operation: AuditPull, Ref: ref, Verdict: AuditVerdictNone, Start: time.Now()}
+
+	// Build registry client options, recording policy verdicts when audited
+	policies := c.policies
+	if c.auditHook != nil {
+		policies = auditPolicies(policies, event)
+	}
+	regOpts := registryOpts(c, policies)
 
 	regClient := registry.New(regOpts...)
 
@@ -43,6 +67,12 @@ func (c *Client) Pull(ctx context.Context, ref string, opts ...PullOption) (*Arc
 	if c.blockCache != nil {
 		pullOpts = append(pullOpts, registry.WithBlockCache(c.blockCache))
 	}
+	if cfg.retry {
+		pullOpts = append(pullOpts, registry.WithPullRetry(cfg.retryOpts...))
+	}
+	if cfg.platformOS != "" || cfg.platformArch != "" {
+		pullOpts = append(pullOpts, registry.WithPullPlatform(cfg.platformOS, cfg.platformArch))
+	}
 
 	// Pass through blob options
 	blobOpts := cfg.blobOpts
@@ -67,19 +97,42 @@ func (c *Client) Pull(ctx context.Context, ref string, opts ...PullOption) (*Arc
 		pullOpts = append(pullOpts, registry.WithPullProgress(cfg.progress))
 	}
 
+	// Capture the manifest for annotations
+	var manifest *Manifest
+	pullOpts = append(pullOpts, registry.WithPullManifest(func(m *Manifest) {
+		manifest = m
+	}))
+
 	// Pull via registry client
 	blob, err := regClient.Pull(ctx, ref, pullOpts...)
 	if err != nil {
+		event.Err = err
+		c.audit(event)
 		return nil, err
 	}
 
-	return &Archive{Blob: blob}, nil
+	event.Digest = manifest.Digest()
+	if !blob.IndexFromCache() {
+		event.BytesTransferred = manifest.IndexDescriptor().Size
+	}
+	c.audit(event)
+
+	return &Archive{Blob: blob, manifest: manifest}, nil
 }
 
 // buildRegistryOpts creates registry.Option slice from Client configuration.
 func buildRegistryOpts(c *Client) []registry.Option {
+	return registryOpts(c, c.policies)
+}
+
+// registryOpts creates registry.Option slice from Client configuration,
+// enforcing policies in place of the client's own.
+func registryOpts(c *Client, policies []Policy) []registry.Option {
 	var regOpts []registry.Option //nolint:prealloc // size depends on optional config
 	regOpts = append(regOpts, registry.WithOrasOptions(c.orasOpts...))
+	if c.oci != nil {
+		regOpts = append(regOpts, registry.WithOCIClient(c.oci))
+	}
 	if c.refCache != nil {
 		regOpts = append(regOpts, registry.WithRefCache(c.refCache))
 	}
@@ -89,7 +142,10 @@ func buildRegistryOpts(c *Client) []registry.Option {
 	if c.indexCache != nil {
 		regOpts = append(regOpts, registry.WithIndexCache(c.indexCache))
 	}
-	for _, p := range c.policies {
+	if c.referrerCache != nil {
+		regOpts = append(regOpts, registry.WithReferrerCache(c.referrerCache))
+	}
+	for _, p := range policies {
 		regOpts = append(regOpts, registry.WithPolicy(p))
 	}
 	if c.logger != nil {
@@ -0,0 +1,81 @@
+package blob
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+
+	blobcore "github.com/meigma/blob/core"
+)
+
+// PullDelta updates destDir, which holds the extracted files of old, to the
+// archive at newRef, downloading only the files that changed.
+//
+// The new archive is pulled as with Pull, so only its index is fetched up
+// front, and compared with old using Diff. Files removed from the archive
+// are deleted from destDir, which is created if missing, then added and
+// changed files are extracted over the existing ones. Unchanged files are
+// left in place without reading their data and are counted in
+// CopyStats.Skipped. Directories emptied by removals are kept, and files
+// whose mode or modification time changed but whose content did not are not
+// rewritten.
+//
+// On error, destDir may be partly updated; the returned stats count the
+// files written before the failure.
+func (c *Client) PullDelta(ctx context.Context, old *blobcore.Blob, newRef, destDir string, opts ...PullOption) (CopyStats, error) {
+	archive, err := c.Pull(ctx, newRef, opts...)
+	if err != nil {
+		return CopyStats{}, err
+	}
+
+	added, removed, changed := blobcore.Diff(old, archive.Blob)
+	c.log().Debug("computed archive delta", "ref", newRef,
+		"added", len(added), "removed", len(removed), "changed", len(changed))
+
+	if err := os.MkdirAll(destDir, 0o750); err != nil {
+		return CopyStats{}, err
+	}
+	if err := removeFiles(destDir, removed); err != nil {
+		return CopyStats{}, err
+	}
+
+	paths := slices.Concat(added, changed)
+	stats, err := archive.CopyToContext(ctx, destDir, paths, CopyWithOverwrite(true))
+	stats.Skipped += countFiles(archive.Blob) - len(paths)
+	return stats, err
+}
+
+// removeFiles deletes the archive paths from destDir. Missing files are
+// ignored, and paths cannot escape destDir.
+func removeFiles(destDir string, paths []string) error {
+	if len(paths) == 0 {
+		return nil
+	}
+	root, err := os.OpenRoot(destDir)
+	if err != nil {
+		return err
+	}
+	defer root.Close()
+
+	for _, p := range paths {
+		if err := root.Remove(filepath.FromSlash(p)); err != nil && !errors.Is(err, fs.ErrNotExist) {
+			return fmt.Errorf("remove %s: %w", p, err)
+		}
+	}
+	return nil
+}
+
+// countFiles returns the number of non-directory entries in b.
+func countFiles(b *blobcore.Blob) int {
+	n := 0
+	for view := range b.Entries() {
+		if !view.Mode().IsDir() {
+			n++
+		}
+	}
+	return n
+}
@@ -0,0 +1,109 @@
+package blob
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestClient_PullDelta(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	r := newMemRegistry(t)
+	c, _ := newAuditClient(t, r)
+
+	random := func(n int) []byte {
+		b := make([]byte, n)
+		_, err := rand.Read(b)
+		require.NoError(t, err)
+		return b
+	}
+	v1 := map[string][]byte{
+		"a.bin":        random(8 << 10),
+		"b.bin":        random(8 << 10),
+		"c.bin":        random(8 << 10),
+		"dir/d.bin":    random(8 << 10),
+		"dir/gone.bin": random(8 << 10),
+		"e.bin":        random(8 << 10),
+	}
+	v2 := map[string][]byte{
+		"a.bin":     v1["a.bin"],
+		"b.bin":     random(8 << 10), // changed
+		"c.bin":     v1["c.bin"],
+		"dir/d.bin": v1["dir/d.bin"],
+		"e.bin":     v1["e.bin"],
+		"f.bin":     random(8 << 10), // added
+	}
+	push := func(tag string, files map[string][]byte) string {
+		dir := t.TempDir()
+		for name, data := range files {
+			require.NoError(t, os.MkdirAll(filepath.Join(dir, filepath.Dir(name)), 0o755))
+			require.NoError(t, os.WriteFile(filepath.Join(dir, name), data, 0o600))
+		}
+		ref := "registry.example.com/repo:" + tag
+		require.NoError(t, c.Push(ctx, ref, dir, PushWithCompression(CompressionNone)))
+		return ref
+	}
+	oldRef, newRef := push("v1", v1), push("v2", v2)
+
+	old, err := c.Pull(ctx, oldRef)
+	require.NoError(t, err)
+	dest := t.TempDir()
+	_, err = old.CopyDir(dest, ".")
+	require.NoError(t, err)
+	// A local edit to an unchanged file survives, proving it is not rewritten.
+	require.NoError(t, os.WriteFile(filepath.Join(dest, "c.bin"), []byte("local"), 0o600))
+
+	stats, err := c.PullDelta(ctx, old.Blob, newRef, dest)
+	require.NoError(t, err)
+	assert.Equal(t, 2, stats.FileCount)
+	assert.Equal(t, 4, stats.Skipped)
+
+	for name, want := range v2 {
+		got, err := os.ReadFile(filepath.Join(dest, name))
+		require.NoError(t, err, name)
+		if name == "c.bin" {
+			want = []byte("local")
+		}
+		assert.True(t, bytes.Equal(want, got), name)
+	}
+	assert.NoFileExists(t, filepath.Join(dest, "dir/gone.bin"))
+
+	// Only the added and changed files were read from the new data blob.
+	archive, err := c.Pull(ctx, newRef)
+	require.NoError(t, err)
+	var want []string
+	for _, name := range []string{"b.bin", "f.bin"} {
+		view, ok := archive.Entry(name)
+		require.True(t, ok)
+		want = append(want, fmt.Sprintf("bytes=%d-%d", view.DataOffset(), view.DataOffset()+view.DataSize()-1))
+	}
+	var got []string
+	r.mu.Lock()
+	for _, rng := range r.ranges[archive.Manifest().DataDescriptor().Digest] {
+		// Each Pull probes the data blob when opening it.
+		if rng != "" && rng != "bytes=0-0" {
+			got = append(got, rng)
+		}
+	}
+	r.mu.Unlock()
+	assert.ElementsMatch(t, want, got)
+
+	t.Run("missing destination", func(t *testing.T) {
+		t.Parallel()
+
+		dest := filepath.Join(t.TempDir(), "new")
+		stats, err := c.PullDelta(ctx, old.Blob, newRef, dest)
+		require.NoError(t, err)
+		assert.Equal(t, 2, stats.FileCount)
+		assert.FileExists(t, filepath.Join(dest, "f.bin"))
+	})
+}
@@ -10,6 +10,10 @@ type pullConfig struct {
 	maxIndexSize int64
 	blobOpts     []blobcore.Option
 	progress     ProgressFunc
+	retry        bool
+	retryOpts    []RetryOption
+	platformOS   string
+	platformArch string
 }
 
 // PullWithSkipCache bypasses the ref and manifest caches.
@@ -30,6 +34,18 @@ func PullWithMaxIndexSize(maxBytes int64) PullOption {
 	}
 }
 
+// PullWithRetry retries data range requests that fail with a transient
+// error, configured by opts (see RetryingSource). Add [RetryWithBudget] to
+// cap the total attempts of each operation on the pulled archive, so a
+// failing registry aborts a large CopyDir quickly instead of retrying every
+// request. Every operation starts with a fresh budget.
+func PullWithRetry(opts ...RetryOption) PullOption {
+	return func(cfg *pullConfig) {
+		cfg.retry = true
+		cfg.retryOpts = append(cfg.retryOpts, opts...)
+	}
+}
+
 // --- Decoder options (passed to core.Blob) ---
 
 // PullWithMaxFileSize limits the maximum per-file size (compressed and uncompressed).
@@ -55,6 +71,51 @@ func PullWithDecoderLowmem(enabled bool) PullOption {
 	}
 }
 
+// PullWithMaxIndexVersion sets the newest index format version accepted
+// (default: IndexVersion). Raise it to read archives pushed by a newer
+// release whose index changes are backward compatible.
+func PullWithMaxIndexVersion(v uint32) PullOption {
+	return func(cfg *pullConfig) {
+		cfg.blobOpts = append(cfg.blobOpts, blobcore.WithMaxIndexVersion(v))
+	}
+}
+
+// PullWithChunkedPrefetch makes a cache miss fetch every file stored in the
+// same chunkBytes-aligned window of the data blob, so reads of neighboring
+// small files are served from the cache. It requires a content cache
+// (see WithContentCacheDir). Zero disables it (the default).
+func PullWithChunkedPrefetch(chunkBytes uint64) PullOption {
+	return func(cfg *pullConfig) {
+		cfg.blobOpts = append(cfg.blobOpts, blobcore.WithChunkedPrefetch(chunkBytes))
+	}
+}
+
+// PullWithCaseInsensitiveLookup makes the pulled archive resolve paths
+// case-insensitively, preferring an exact match. See
+// WithCaseInsensitiveLookup.
+func PullWithCaseInsensitiveLookup(enabled bool) PullOption {
+	return func(cfg *pullConfig) {
+		cfg.blobOpts = append(cfg.blobOpts, blobcore.WithCaseInsensitiveLookup(enabled))
+	}
+}
+
+// PullWithNegativeCache remembers up to maxEntries missing paths so
+// repeated lookups of the same absent file skip the index search.
+// Values <= 0 disable it (the default).
+func PullWithNegativeCache(maxEntries int) PullOption {
+	return func(cfg *pullConfig) {
+		cfg.blobOpts = append(cfg.blobOpts, blobcore.WithNegativeCache(maxEntries))
+	}
+}
+
+// PullWithDecryptionKey sets the key used to read archives pushed with
+// PushWithEncryption. Reads fail with ErrDecryption without it.
+func PullWithDecryptionKey(key []byte) PullOption {
+	return func(cfg *pullConfig) {
+		cfg.blobOpts = append(cfg.blobOpts, blobcore.WithDecryptionKey(key))
+	}
+}
+
 // PullWithVerifyOnClose controls whether Close drains the file to verify the hash.
 //
 // When false, Close returns without reading the remaining data. Integrity is
@@ -65,11 +126,55 @@ func PullWithVerifyOnClose(enabled bool) PullOption {
 	}
 }
 
+// PullWithMaxFiles rejects archives whose index lists more than n entries
+// with ErrTooManyFiles. Zero or negative disables the limit (the default).
+func PullWithMaxFiles(n int) PullOption {
+	return func(cfg *pullConfig) {
+		cfg.blobOpts = append(cfg.blobOpts, blobcore.WithMaxFiles(n))
+	}
+}
+
+// PullWithValidateLayout controls whether the index layout is checked on pull.
+// When enabled, entries that overlap or extend past the data blob are rejected
+// with ErrOverlappingEntries or ErrSizeOverflow.
+func PullWithValidateLayout(enabled bool) PullOption {
+	return func(cfg *pullConfig) {
+		cfg.blobOpts = append(cfg.blobOpts, blobcore.WithValidateLayout(enabled))
+	}
+}
+
+// PullWithValidateIndex controls whether the index is checked against the
+// data blob on pull. When enabled, a data size mismatch, unsorted paths, or
+// entries that overlap or extend past the data blob are rejected. See
+// blobcore.WithValidateIndex.
+func PullWithValidateIndex(enabled bool) PullOption {
+	return func(cfg *pullConfig) {
+		cfg.blobOpts = append(cfg.blobOpts, blobcore.WithValidateIndex(enabled))
+	}
+}
+
 // PullWithProgress sets a callback to receive progress updates during pull.
-// The callback receives events for manifest and index fetching.
+// The callback receives events for manifest and index fetching, then a
+// StageFetchingData event each time data blob bytes arrive from the
+// registry. Data reads happen lazily after Pull returns, so BytesDone counts
+// the bytes fetched so far and can exceed BytesTotal if ranges are fetched
+// more than once.
 // The callback may be invoked concurrently and must be safe for concurrent use.
 func PullWithProgress(fn ProgressFunc) PullOption {
 	return func(cfg *pullConfig) {
 		cfg.progress = fn
 	}
 }
+
+// PullWithPlatform selects the archive built for os and arch (for example
+// "linux", "arm64") when the reference resolves to an OCI image index that
+// groups per-platform archives under one tag.
+//
+// Pull returns [ErrPlatformNotFound] if the index has no manifest for the
+// platform. References that resolve to a single archive are pulled as usual.
+func PullWithPlatform(os, arch string) PullOption {
+	return func(cfg *pullConfig) {
+		cfg.platformOS = os
+		cfg.platformArch = arch
+	}
+}
@@ -4,6 +4,9 @@ import (
 	"bytes"
 	"context"
 	"fmt"
+	"time"
+
+	ocispec "github.com/opencontainers/image-spec/specs-go/v1"
 
 	blobcore "github.com/meigma/blob/core"
 	"github.com/meigma/blob/registry"
@@ -24,6 +27,15 @@ func (c *Client) Push(ctx context.Context, ref, srcDir string, opts ...PushOptio
 
 	c.log().Info("pushing to registry", "ref", ref, "src_dir", srcDir)
 
+	event := &AuditEvent{Operation: AuditPush, Ref: ref, Verdict: AuditVerdictNone, Start: time.Now()}
+	err := c.push(ctx, srcDir, &cfg, event)
+	event.Err = err
+	c.audit(event)
+	return err
+}
+
+// push creates an archive from srcDir and pushes it to event.Ref.
+func (c *Client) push(ctx context.Context, srcDir string, cfg *pushConfig, event *AuditEvent) error {
 	// Create archive in memory
 	var indexBuf, dataBuf bytes.Buffer
 	createOpts := cfg.createOpts
@@ -35,13 +47,14 @@ func (c *Client) Push(ctx context.Context, ref, srcDir string, opts ...PushOptio
 	}
 
 	// Create Blob from buffers
-	archive, err := blobcore.New(indexBuf.Bytes(), &memSource{data: dataBuf.Bytes()})
+	archive, err := blobcore.New(indexBuf.Bytes(), blobcore.NewReaderAtSource(bytes.NewReader(dataBuf.Bytes()), int64(dataBuf.Len()), "memory"),
+		blobcore.WithZstdDictionary(cfg.dictionary))
 	if err != nil {
 		return fmt.Errorf("load archive: %w", err)
 	}
 
 	// Push via registry client
-	return c.pushArchive(ctx, ref, archive, &cfg)
+	return c.pushArchive(ctx, archive, cfg, event)
 }
 
 // PushArchive pushes an existing archive to the registry.
@@ -55,31 +68,18 @@ func (c *Client) PushArchive(ctx context.Context, ref string, archive *blobcore.
 	for _, opt := range opts {
 		opt(&cfg)
 	}
-	return c.pushArchive(ctx, ref, archive, &cfg)
-}
 
-// pushArchive is the internal push implementation.
-func (c *Client) pushArchive(ctx context.Context, ref string, archive *blobcore.Blob, cfg *pushConfig) error {
-	// Build registry client options
-	var regOpts []registry.Option //nolint:prealloc // size depends on optional config
-	regOpts = append(regOpts, registry.WithOrasOptions(c.orasOpts...))
-	if c.refCache != nil {
-		regOpts = append(regOpts, registry.WithRefCache(c.refCache))
-	}
-	if c.manifestCache != nil {
-		regOpts = append(regOpts, registry.WithManifestCache(c.manifestCache))
-	}
-	if c.indexCache != nil {
-		regOpts = append(regOpts, registry.WithIndexCache(c.indexCache))
-	}
-	for _, p := range c.policies {
-		regOpts = append(regOpts, registry.WithPolicy(p))
-	}
-	if c.logger != nil {
-		regOpts = append(regOpts, registry.WithLogger(c.logger))
-	}
+	event := &AuditEvent{Operation: AuditPush, Ref: ref, Verdict: AuditVerdictNone, Start: time.Now()}
+	err := c.pushArchive(ctx, archive, &cfg, event)
+	event.Err = err
+	c.audit(event)
+	return err
+}
 
-	regClient := registry.New(regOpts...)
+// pushArchive is the internal push implementation. It pushes archive to
+// event.Ref and records the pushed manifest and size in event.
+func (c *Client) pushArchive(ctx context.Context, archive *blobcore.Blob, cfg *pushConfig, event *AuditEvent) error {
+	regClient := registry.New(buildRegistryOpts(c)...)
 
 	// Build push options
 	var pushOpts []registry.PushOption
@@ -92,27 +92,17 @@ func (c *Client) pushArchive(ctx context.Context, ref string, archive *blobcore.
 	if cfg.progress != nil {
 		pushOpts = append(pushOpts, registry.WithProgress(cfg.progress))
 	}
-
-	return regClient.Push(ctx, ref, archive, pushOpts...)
-}
-
-// memSource implements blobcore.ByteSource for in-memory data.
-type memSource struct {
-	data []byte
-}
-
-func (m *memSource) ReadAt(p []byte, off int64) (int, error) {
-	if off >= int64(len(m.data)) {
-		return 0, fmt.Errorf("offset %d exceeds data length %d", off, len(m.data))
+	if cfg.indexConfig {
+		pushOpts = append(pushOpts, registry.WithIndexAsConfig(true))
 	}
-	n := copy(p, m.data[off:])
-	return n, nil
-}
-
-func (m *memSource) Size() int64 {
-	return int64(len(m.data))
-}
+	if cfg.forceUpload {
+		pushOpts = append(pushOpts, registry.WithForceUpload(true))
+	}
+	pushOpts = append(pushOpts, registry.WithPushManifest(func(desc ocispec.Descriptor) {
+		event.Digest = desc.Digest.String()
+	}), registry.WithPushUploaded(func(n int64) {
+		event.BytesTransferred = n
+	}))
 
-func (m *memSource) SourceID() string {
-	return "memory"
+	return regClient.Push(ctx, event.Ref, archive, pushOpts...)
 }
@@ -1,6 +1,10 @@
 package blob
 
-import blobcore "github.com/meigma/blob/core"
+import (
+	"time"
+
+	blobcore "github.com/meigma/blob/core"
+)
 
 // PushOption configures a Push or PushArchive operation.
 type PushOption func(*pushConfig)
@@ -10,6 +14,9 @@ type pushConfig struct {
 	annotations map[string]string
 	createOpts  []blobcore.CreateOption
 	progress    ProgressFunc
+	indexConfig bool
+	forceUpload bool
+	dictionary  []byte
 }
 
 // PushWithTags applies additional tags to the pushed manifest.
@@ -47,6 +54,50 @@ func PushWithCompression(c Compression) PushOption {
 	}
 }
 
+// PushWithCompressionLevel sets the zstd encoder level for archive creation.
+// It has no effect unless compression is enabled with [PushWithCompression].
+func PushWithCompressionLevel(level CompressionLevel) PushOption {
+	return func(cfg *pushConfig) {
+		cfg.createOpts = append(cfg.createOpts, blobcore.CreateWithCompressionLevel(level))
+	}
+}
+
+// PushWithCompressionWorkers compresses up to n files concurrently during
+// archive creation. The archive is identical for any n. See
+// blobcore.CreateWithCompressionWorkers.
+func PushWithCompressionWorkers(n int) PushOption {
+	return func(cfg *pushConfig) {
+		cfg.createOpts = append(cfg.createOpts, blobcore.CreateWithCompressionWorkers(n))
+	}
+}
+
+// PushWithTempDir sets the directory for temporary files created during
+// archive creation. See blobcore.CreateWithTempDir.
+func PushWithTempDir(dir string) PushOption {
+	return func(cfg *pushConfig) {
+		cfg.createOpts = append(cfg.createOpts, blobcore.CreateWithTempDir(dir))
+	}
+}
+
+// PushWithMinCompressionRatio sets the minimum fraction of the original size
+// that compression must save for a file to be stored compressed.
+// Values <= 0 keep every file compressed.
+func PushWithMinCompressionRatio(ratio float64) PushOption {
+	return func(cfg *pushConfig) {
+		cfg.createOpts = append(cfg.createOpts, blobcore.CreateWithMinCompressionRatio(ratio))
+	}
+}
+
+// PushWithZstdDictionary compresses files using a pre-trained zstd dictionary.
+// The dictionary is pushed as its own blob, referenced from the index, and
+// Pull fetches it automatically; see TrainZstdDictionary.
+func PushWithZstdDictionary(dict []byte) PushOption {
+	return func(cfg *pushConfig) {
+		cfg.createOpts = append(cfg.createOpts, blobcore.CreateWithZstdDictionary(dict))
+		cfg.dictionary = dict
+	}
+}
+
 // PushWithSkipCompression adds predicates that decide to store a file uncompressed.
 // If any predicate returns true, compression is skipped for that file.
 func PushWithSkipCompression(fns ...SkipCompressionFunc) PushOption {
@@ -55,6 +106,14 @@ func PushWithSkipCompression(fns ...SkipCompressionFunc) PushOption {
 	}
 }
 
+// PushWithConcurrentModification sets how files that change size while the
+// archive is created are handled (default: ConcurrentModificationError).
+func PushWithConcurrentModification(mode ConcurrentModification) PushOption {
+	return func(cfg *pushConfig) {
+		cfg.createOpts = append(cfg.createOpts, blobcore.CreateWithConcurrentModification(mode))
+	}
+}
+
 // PushWithChangeDetection controls whether the writer verifies files did not change
 // during archive creation.
 func PushWithChangeDetection(cd ChangeDetection) PushOption {
@@ -71,9 +130,147 @@ func PushWithMaxFiles(n int) PushOption {
 	}
 }
 
+// PushWithMaxPathLength rejects files whose archive path is longer than n
+// bytes, failing the push with a *PathLimitError. Zero or negative disables
+// the limit.
+func PushWithMaxPathLength(n int) PushOption {
+	return func(cfg *pushConfig) {
+		cfg.createOpts = append(cfg.createOpts, blobcore.CreateWithMaxPathLength(n))
+	}
+}
+
+// PushWithMaxPathDepth rejects files whose archive path has more than n
+// elements, failing the push with a *PathLimitError. Zero or negative
+// disables the limit.
+func PushWithMaxPathDepth(n int) PushOption {
+	return func(cfg *pushConfig) {
+		cfg.createOpts = append(cfg.createOpts, blobcore.CreateWithMaxPathDepth(n))
+	}
+}
+
+// PushWithRejectCaseCollisions fails the push with ErrCaseCollision when
+// enabled and two archived paths differ only in case, such as "README" and
+// "readme". See CreateWithRejectCaseCollisions.
+func PushWithRejectCaseCollisions(enabled bool) PushOption {
+	return func(cfg *pushConfig) {
+		cfg.createOpts = append(cfg.createOpts, blobcore.CreateWithRejectCaseCollisions(enabled))
+	}
+}
+
+// PushWithExclude leaves out files and directories matching any of the
+// gitignore-style patterns, such as ".git" or "*.tmp". Excluded directories
+// are not walked. See CreateWithExclude for the pattern rules.
+func PushWithExclude(patterns ...string) PushOption {
+	return func(cfg *pushConfig) {
+		cfg.createOpts = append(cfg.createOpts, blobcore.CreateWithExclude(patterns...))
+	}
+}
+
+// PushWithInclude restricts the archive to files matching any of patterns.
+// Include patterns take precedence over PushWithExclude.
+func PushWithInclude(patterns ...string) PushOption {
+	return func(cfg *pushConfig) {
+		cfg.createOpts = append(cfg.createOpts, blobcore.CreateWithInclude(patterns...))
+	}
+}
+
+// PushWithIgnoreFile reads gitignore-style exclusion patterns from the named
+// file, such as ".blobignore", at the root of the pushed directory.
+func PushWithIgnoreFile(name string) PushOption {
+	return func(cfg *pushConfig) {
+		cfg.createOpts = append(cfg.createOpts, blobcore.CreateWithIgnoreFile(name))
+	}
+}
+
+// PushWithStripPrefix drops the first n elements of every archived path,
+// like tar --strip-components. Files that map to the same path fail the
+// push with ErrPathConflict.
+func PushWithStripPrefix(n int) PushOption {
+	return func(cfg *pushConfig) {
+		cfg.createOpts = append(cfg.createOpts, blobcore.CreateWithStripPrefix(n))
+	}
+}
+
+// PushWithPathPrefix stores every file under the directory p in the archive.
+func PushWithPathPrefix(p string) PushOption {
+	return func(cfg *pushConfig) {
+		cfg.createOpts = append(cfg.createOpts, blobcore.CreateWithPathPrefix(p))
+	}
+}
+
+// PushWithSymlinks records symbolic links in the archive instead of skipping
+// them. Use CopyWithSymlinks to recreate them during extraction.
+func PushWithSymlinks(enabled bool) PushOption {
+	return func(cfg *pushConfig) {
+		cfg.createOpts = append(cfg.createOpts, blobcore.CreateWithSymlinks(enabled))
+	}
+}
+
+// PushWithModTime records t as the modification time of every file, so
+// pushing the same tree from different checkouts produces the same archive
+// digests. Use time.Unix(0, 0) for the conventional epoch timestamp.
+func PushWithModTime(t time.Time) PushOption {
+	return func(cfg *pushConfig) {
+		cfg.createOpts = append(cfg.createOpts, blobcore.CreateWithModTime(t))
+	}
+}
+
+// PushWithAuxChecksum records a fast per-file checksum in the index
+// alongside the SHA256 hash, for cheap local change detection with SyncDir.
+func PushWithAuxChecksum(alg AuxChecksum) PushOption {
+	return func(cfg *pushConfig) {
+		cfg.createOpts = append(cfg.createOpts, blobcore.CreateWithAuxChecksum(alg))
+	}
+}
+
+// PushWithChunking stores files larger than avgChunkSize*4 bytes as
+// content-defined chunks, so Open and ReadFile on pulled archives with a
+// cache fetch only the chunks of a large file that changed since an
+// earlier version. See blobcore.CreateWithChunking.
+func PushWithChunking(avgChunkSize int) PushOption {
+	return func(cfg *pushConfig) {
+		cfg.createOpts = append(cfg.createOpts, blobcore.CreateWithChunking(avgChunkSize))
+	}
+}
+
+// PushWithBloomFilter stores a bloom filter over the archived paths in the
+// index, so lookups of missing paths are usually rejected without a search.
+func PushWithBloomFilter(enabled bool) PushOption {
+	return func(cfg *pushConfig) {
+		cfg.createOpts = append(cfg.createOpts, blobcore.CreateWithBloomFilter(enabled))
+	}
+}
+
+// PushWithEncryption encrypts file content in the data blob with key.
+// The index, including paths, stays in the clear. Pull the archive with
+// PullWithDecryptionKey to read it.
+func PushWithEncryption(key []byte, scheme Encryption) PushOption {
+	return func(cfg *pushConfig) {
+		cfg.createOpts = append(cfg.createOpts, blobcore.CreateWithEncryption(key, scheme))
+	}
+}
+
+// PushWithIndexAsConfig stores the index blob as the manifest config
+// instead of a layer, for compatibility with tools that surface an
+// artifact's config as its metadata. Pull reads both layouts.
+func PushWithIndexAsConfig(enabled bool) PushOption {
+	return func(cfg *pushConfig) {
+		cfg.indexConfig = enabled
+	}
+}
+
+// PushWithForceUpload uploads the index and data blobs even when the
+// registry already holds them, for example from an earlier push of the same
+// content under another tag (default: false).
+func PushWithForceUpload(enabled bool) PushOption {
+	return func(cfg *pushConfig) {
+		cfg.forceUpload = enabled
+	}
+}
+
 // PushWithProgress sets a callback to receive progress updates during push.
-// The callback receives events for archive creation (compressing files) and
-// blob uploads (pushing index and data).
+// The callback receives events for archive creation (counting files, then
+// compressing them, with file and byte totals) and blob uploads (pushing index and data), which report bytes as they are sent.
 // The callback may be invoked concurrently and must be safe for concurrent use.
 func PushWithProgress(fn ProgressFunc) PushOption {
 	return func(cfg *pushConfig) {
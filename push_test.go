@@ -0,0 +1,48 @@
+package blob
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/meigma/blob/registry"
+)
+
+func TestPushWithZstdDictionary(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	const ref = "registry.example.com/repo:v1"
+
+	dir := t.TempDir()
+	for i := range 50 {
+		content := fmt.Sprintf(`{"kind":"ServiceConfig","name":"svc-%03d","replicas":%d}`, i, i%3+1)
+		require.NoError(t, os.WriteFile(filepath.Join(dir, fmt.Sprintf("svc-%03d.json", i)), []byte(content), 0o600))
+	}
+	dict, err := TrainZstdDictionary(ctx, dir, 1<<10)
+	require.NoError(t, err)
+
+	r := newMemRegistry(t)
+	c, _ := newAuditClient(t, r)
+	require.NoError(t, c.Push(ctx, ref, dir,
+		PushWithCompression(CompressionZstd),
+		PushWithZstdDictionary(dict)))
+
+	manifest, err := c.Fetch(ctx, ref)
+	require.NoError(t, err)
+	dictDesc, ok := manifest.ZstdDictionaryDescriptor()
+	require.True(t, ok, "dictionary is pushed as its own layer")
+	assert.Equal(t, registry.MediaTypeZstdDictionary, dictDesc.MediaType)
+	assert.Equal(t, dict, r.blobs[dictDesc.Digest])
+
+	archive, err := c.Pull(ctx, ref)
+	require.NoError(t, err)
+	got, err := archive.ReadFile("svc-007.json")
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"kind":"ServiceConfig","name":"svc-007","replicas":2}`, string(got))
+}
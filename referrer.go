@@ -1,6 +1,12 @@
 package blob
 
-import ocispec "github.com/opencontainers/image-spec/specs-go/v1"
+import (
+	"context"
+
+	ocispec "github.com/opencontainers/image-spec/specs-go/v1"
+
+	"github.com/meigma/blob/registry"
+)
 
 // Referrer describes an artifact that references a manifest.
 //
@@ -23,6 +29,10 @@ type Referrer struct {
 
 	// Annotations contains optional metadata key-value pairs.
 	Annotations map[string]string
+
+	// Content holds the raw referrer content. It is only populated by
+	// [Client.FetchReferrers].
+	Content []byte
 }
 
 // referrerFromDescriptor converts an OCI descriptor to a Referrer.
@@ -35,3 +45,28 @@ func referrerFromDescriptor(desc *ocispec.Descriptor) Referrer {
 		Annotations:  desc.Annotations,
 	}
 }
+
+// FetchReferrers fetches referrer artifacts and their raw content without
+// evaluating policies.
+//
+// The artifactType parameter filters referrers by type. Pass "" to get all.
+// When a referrer cache is configured (see [WithReferrerCacheDir] and
+// [WithCacheDir]), listings and content are cached so repeated inspections
+// are served locally. Listings expire after the TTL set with
+// [WithReferrerCacheTTL]; content is cached by digest without expiry.
+// Returns [registry.ErrReferrersUnsupported] if the registry doesn't support referrers.
+func (c *Client) FetchReferrers(ctx context.Context, ref, artifactType string) ([]Referrer, error) {
+	regClient := registry.New(buildRegistryOpts(c)...)
+
+	fetched, err := regClient.FetchReferrers(ctx, ref, artifactType)
+	if err != nil {
+		return nil, err
+	}
+
+	referrers := make([]Referrer, len(fetched))
+	for i := range fetched {
+		referrers[i] = referrerFromDescriptor(&fetched[i].Descriptor)
+		referrers[i].Content = fetched[i].Content
+	}
+	return referrers, nil
+}
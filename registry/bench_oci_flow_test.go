@@ -5,7 +5,6 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
-	"io"
 	"math/rand"
 	"os"
 	"path/filepath"
@@ -37,39 +36,6 @@ type benchOCIData struct {
 	dataSize int64
 }
 
-// memByteSource is an in-memory implementation of blob.ByteSource for benchmarks.
-type memByteSource struct {
-	data     []byte
-	sourceID string
-}
-
-// newMemByteSource creates a memByteSource wrapping the provided data.
-func newMemByteSource(data []byte) *memByteSource {
-	return &memByteSource{
-		data:     data,
-		sourceID: fmt.Sprintf("mem:%d", len(data)),
-	}
-}
-
-func (m *memByteSource) ReadAt(p []byte, off int64) (int, error) {
-	if off >= int64(len(m.data)) {
-		return 0, io.EOF
-	}
-	n := copy(p, m.data[off:])
-	if off+int64(n) >= int64(len(m.data)) {
-		return n, io.EOF
-	}
-	return n, nil
-}
-
-func (m *memByteSource) Size() int64 {
-	return int64(len(m.data))
-}
-
-func (m *memByteSource) SourceID() string {
-	return m.sourceID
-}
-
 // benchRefCache is a thread-safe in-memory RefCache for benchmarks.
 type benchRefCache struct {
 	mu   sync.RWMutex
@@ -398,7 +364,7 @@ func createBenchOCIData(tb testing.TB, fileCount, fileSize int, compression blob
 		tb.Fatalf("create archive: %v", err)
 	}
 
-	b, err := blob.New(indexBuf.Bytes(), newMemByteSource(dataBuf.Bytes()))
+	b, err := blob.New(indexBuf.Bytes(), blob.NewReaderAtSource(bytes.NewReader(dataBuf.Bytes()), int64(dataBuf.Len()), fmt.Sprintf("mem:%d", dataBuf.Len())))
 	if err != nil {
 		tb.Fatalf("create blob: %v", err)
 	}
@@ -77,3 +77,42 @@ type IndexCache interface {
 	// Returns the number of bytes freed.
 	Prune(targetBytes int64) (int64, error)
 }
+
+// ReferrerCache caches referrer listings and referrer content.
+//
+// This allows attestations and signatures to be inspected repeatedly
+// without contacting the registry. Listings can change as referrers are
+// attached, so implementations should expire them; content is addressed
+// by digest and can be kept indefinitely.
+type ReferrerCache interface {
+	// GetReferrers returns the cached referrer descriptors for a subject
+	// digest and artifact type filter ("" for all types). Expired listings
+	// are reported as misses.
+	GetReferrers(subject, artifactType string) (referrers []ocispec.Descriptor, ok bool)
+
+	// PutReferrers caches the referrer descriptors for a subject digest and
+	// artifact type filter, replacing any previous listing.
+	PutReferrers(subject, artifactType string, referrers []ocispec.Descriptor) error
+
+	// DeleteReferrers removes a cached referrer listing.
+	DeleteReferrers(subject, artifactType string) error
+
+	// GetContent returns the cached content bytes for a referrer digest.
+	GetContent(digest string) (content []byte, ok bool)
+
+	// PutContent caches raw referrer content by digest.
+	PutContent(digest string, raw []byte) error
+
+	// Delete removes cached referrer content.
+	Delete(digest string) error
+
+	// MaxBytes returns the configured cache size limit (0 = unlimited).
+	MaxBytes() int64
+
+	// SizeBytes returns the current cache size in bytes.
+	SizeBytes() int64
+
+	// Prune removes cached entries until the cache is at or below targetBytes.
+	// Returns the number of bytes freed.
+	Prune(targetBytes int64) (int64, error)
+}
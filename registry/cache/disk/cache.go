@@ -31,6 +31,7 @@ type config struct {
 	dirPerm        os.FileMode
 	maxBytes       int64
 	refTTL         time.Duration
+	referrerTTL    time.Duration
 	logger         *slog.Logger
 }
 
@@ -68,6 +69,15 @@ func WithRefCacheTTL(ttl time.Duration) Option {
 	}
 }
 
+// WithReferrerCacheTTL sets the time-to-live for referrer listings. Content
+// is addressed by digest and does not expire. Use 0 to disable TTL
+// expiration.
+func WithReferrerCacheTTL(ttl time.Duration) Option {
+	return func(c *config) {
+		c.referrerTTL = ttl
+	}
+}
+
 // WithLogger sets the logger for cache operations.
 // If not set, logging is disabled.
 func WithLogger(logger *slog.Logger) Option {
@@ -0,0 +1,374 @@
+package disk
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	ocispec "github.com/opencontainers/image-spec/specs-go/v1"
+)
+
+const (
+	referrerListDir    = "lists"
+	referrerContentDir = "content"
+)
+
+// ReferrerCache stores referrer listings and referrer content on disk.
+//
+// Listings are keyed by a SHA256 hash of the subject digest and artifact
+// type and stored as JSON descriptor arrays. Content is stored by digest
+// and validated on read to prevent cache poisoning.
+//
+// Listings older than the TTL set with WithReferrerCacheTTL are treated as
+// misses, so referrers attached later become visible once the listing
+// expires; DeleteReferrers refreshes a listing immediately. Without a TTL,
+// listings are kept until they are deleted or pruned. Content never
+// expires.
+type ReferrerCache struct {
+	dir            string
+	shardPrefixLen int
+	dirPerm        os.FileMode
+	maxBytes       int64
+	ttl            time.Duration
+	bytes          atomic.Int64
+	pruneMu        sync.Mutex
+	logger         *slog.Logger
+}
+
+// log returns the logger, falling back to a discard logger if nil.
+func (c *ReferrerCache) log() *slog.Logger {
+	if c.logger == nil {
+		return slog.New(slog.DiscardHandler)
+	}
+	return c.logger
+}
+
+// NewReferrerCache creates a disk-backed referrer cache rooted at dir.
+func NewReferrerCache(dir string, opts ...Option) (*ReferrerCache, error) {
+	if dir == "" {
+		return nil, errors.New("cache dir is empty")
+	}
+	cfg := defaultConfig()
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+	if cfg.shardPrefixLen < 0 {
+		return nil, errors.New("shard prefix length must be >= 0")
+	}
+	if cfg.maxBytes < 0 {
+		return nil, errors.New("max bytes must be >= 0")
+	}
+	if cfg.referrerTTL < 0 {
+		return nil, errors.New("referrer cache ttl must be >= 0")
+	}
+	if err := os.MkdirAll(dir, cfg.dirPerm); err != nil {
+		return nil, err
+	}
+	c := &ReferrerCache{
+		dir:            dir,
+		shardPrefixLen: cfg.shardPrefixLen,
+		dirPerm:        cfg.dirPerm,
+		maxBytes:       cfg.maxBytes,
+		ttl:            cfg.referrerTTL,
+		logger:         cfg.logger,
+	}
+	if size, err := dirSize(dir); err == nil {
+		c.bytes.Store(size)
+	} else {
+		return nil, err
+	}
+	c.log().Info("referrer cache initialized", "dir", dir, "max_bytes", c.maxBytes)
+	return c, nil
+}
+
+// GetReferrers returns the cached referrer descriptors for a subject digest
+// and artifact type filter.
+//
+// Corrupted listings (invalid JSON or descriptor digests) are automatically deleted.
+// Listings older than the configured TTL are treated as cache misses.
+func (c *ReferrerCache) GetReferrers(subject, artifactType string) (referrers []ocispec.Descriptor, ok bool) {
+	path := c.listPath(subject, artifactType)
+	root, err := os.OpenRoot(c.dir)
+	if err != nil {
+		return nil, false
+	}
+	defer root.Close()
+
+	if c.ttl > 0 {
+		info, statErr := root.Stat(path)
+		if statErr != nil {
+			c.log().Debug("referrer cache miss", "subject", subject[:min(16, len(subject))])
+			return nil, false
+		}
+		if time.Since(info.ModTime()) > c.ttl {
+			c.log().Debug("referrer cache expired", "subject", subject[:min(16, len(subject))])
+			_ = c.deleteByPath(root, path) //nolint:errcheck // best-effort cleanup
+			return nil, false
+		}
+	}
+
+	data, err := root.ReadFile(path)
+	if err != nil {
+		c.log().Debug("referrer cache miss", "subject", subject[:min(16, len(subject))])
+		return nil, false
+	}
+
+	if err := json.Unmarshal(data, &referrers); err != nil || !validDescriptors(referrers) {
+		c.log().Warn("referrer cache corrupted entry deleted", "subject", subject[:min(16, len(subject))])
+		_ = c.deleteByPath(root, path) //nolint:errcheck // best-effort cleanup
+		return nil, false
+	}
+	c.log().Debug("referrer cache hit", "subject", subject[:min(16, len(subject))])
+	return referrers, true
+}
+
+// PutReferrers caches the referrer descriptors for a subject digest and
+// artifact type filter, replacing any previous listing.
+func (c *ReferrerCache) PutReferrers(subject, artifactType string, referrers []ocispec.Descriptor) error {
+	if referrers == nil {
+		referrers = []ocispec.Descriptor{}
+	}
+	data, err := json.Marshal(referrers)
+	if err != nil {
+		return fmt.Errorf("encode referrers: %w", err)
+	}
+
+	root, err := os.OpenRoot(c.dir)
+	if err != nil {
+		return fmt.Errorf("open cache root: %w", err)
+	}
+	defer root.Close()
+
+	path := c.listPath(subject, artifactType)
+	if err := c.deleteByPath(root, path); err != nil {
+		return fmt.Errorf("replace cache entry: %w", err)
+	}
+	return c.write(root, path, data, "referrers-*")
+}
+
+// DeleteReferrers removes a cached referrer listing.
+func (c *ReferrerCache) DeleteReferrers(subject, artifactType string) error {
+	root, err := os.OpenRoot(c.dir)
+	if err != nil {
+		return err
+	}
+	defer root.Close()
+	return c.deleteByPath(root, c.listPath(subject, artifactType))
+}
+
+// GetContent returns the cached content bytes for a referrer digest.
+//
+// Corrupted cache entries (digest mismatch) are automatically deleted.
+func (c *ReferrerCache) GetContent(dgst string) (content []byte, ok bool) {
+	path, err := c.contentPath(dgst)
+	if err != nil {
+		return nil, false
+	}
+
+	root, err := os.OpenRoot(c.dir)
+	if err != nil {
+		return nil, false
+	}
+	defer root.Close()
+
+	data, err := root.ReadFile(path)
+	if err != nil {
+		c.log().Debug("referrer content cache miss", "digest", dgst[:min(16, len(dgst))])
+		return nil, false
+	}
+
+	match, err := digestMatches(dgst, data)
+	if err != nil || !match {
+		c.log().Warn("referrer content cache corrupted entry deleted", "digest", dgst[:min(16, len(dgst))])
+		_ = c.deleteByPath(root, path) //nolint:errcheck // best-effort cleanup
+		return nil, false
+	}
+
+	c.log().Debug("referrer content cache hit", "digest", dgst[:min(16, len(dgst))])
+	return data, true
+}
+
+// PutContent caches raw referrer content by digest.
+func (c *ReferrerCache) PutContent(dgst string, raw []byte) error {
+	path, err := c.contentPath(dgst)
+	if err != nil {
+		return err
+	}
+
+	root, err := os.OpenRoot(c.dir)
+	if err != nil {
+		return fmt.Errorf("open cache root: %w", err)
+	}
+	defer root.Close()
+
+	if _, statErr := root.Stat(path); statErr == nil {
+		return nil
+	} else if !errors.Is(statErr, fs.ErrNotExist) {
+		return fmt.Errorf("stat cache entry: %w", statErr)
+	}
+
+	match, err := digestMatches(dgst, raw)
+	if err != nil {
+		return err
+	}
+	if !match {
+		return fmt.Errorf("referrer digest mismatch for %q", dgst)
+	}
+
+	return c.write(root, path, raw, "content-*")
+}
+
+// Delete removes cached referrer content.
+func (c *ReferrerCache) Delete(dgst string) error {
+	path, err := c.contentPath(dgst)
+	if err != nil {
+		return err
+	}
+	root, err := os.OpenRoot(c.dir)
+	if err != nil {
+		return err
+	}
+	defer root.Close()
+	return c.deleteByPath(root, path)
+}
+
+// MaxBytes returns the configured cache size limit (0 = unlimited).
+func (c *ReferrerCache) MaxBytes() int64 {
+	return c.maxBytes
+}
+
+// SizeBytes returns the current cache size in bytes.
+func (c *ReferrerCache) SizeBytes() int64 {
+	return c.bytes.Load()
+}
+
+// Prune removes cached entries until the cache is at or below targetBytes.
+// It returns the number of bytes freed.
+func (c *ReferrerCache) Prune(targetBytes int64) (int64, error) {
+	if targetBytes < 0 {
+		targetBytes = 0
+	}
+	c.pruneMu.Lock()
+	defer c.pruneMu.Unlock()
+
+	freed, remaining, err := pruneDir(c.dir, targetBytes)
+	if err != nil {
+		return 0, err
+	}
+	c.bytes.Store(remaining)
+	return freed, nil
+}
+
+// write atomically stores data at path, skipping silently if the cache is full.
+func (c *ReferrerCache) write(root *os.Root, path string, data []byte, pattern string) error {
+	written := int64(len(data))
+	hasCapacity, capacityErr := c.ensureCapacity(written)
+	if capacityErr != nil {
+		return capacityErr
+	}
+	if !hasCapacity {
+		return nil // Cache full, skip silently
+	}
+
+	dir := filepath.Dir(path)
+	if mkdirErr := root.MkdirAll(dir, c.dirPerm); mkdirErr != nil {
+		return fmt.Errorf("create cache dir: %w", mkdirErr)
+	}
+
+	tmp, tmpPath, err := createTemp(root, dir, pattern)
+	if err != nil {
+		return fmt.Errorf("create temp cache file: %w", err)
+	}
+
+	if _, err := tmp.Write(data); err != nil {
+		_ = tmp.Close()          //nolint:errcheck // best-effort cleanup
+		_ = root.Remove(tmpPath) //nolint:errcheck // best-effort cleanup
+		return fmt.Errorf("write cache file: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		_ = root.Remove(tmpPath) //nolint:errcheck // best-effort cleanup
+		return fmt.Errorf("close cache file: %w", err)
+	}
+
+	if err := root.Rename(tmpPath, path); err != nil {
+		_ = root.Remove(tmpPath) //nolint:errcheck // best-effort cleanup
+		return fmt.Errorf("rename cache file: %w", err)
+	}
+
+	c.bytes.Add(written)
+	return nil
+}
+
+func (c *ReferrerCache) listPath(subject, artifactType string) string {
+	sum := sha256.Sum256([]byte(subject + "\x00" + artifactType))
+	return c.shardedPath(referrerListDir, hex.EncodeToString(sum[:]))
+}
+
+func (c *ReferrerCache) contentPath(dgst string) (string, error) {
+	hexHash, err := sanitizeHexDigest(dgst)
+	if err != nil {
+		return "", err
+	}
+	return c.shardedPath(referrerContentDir, hexHash), nil
+}
+
+func (c *ReferrerCache) shardedPath(kind, hexHash string) string {
+	if c.shardPrefixLen <= 0 {
+		return filepath.Join(kind, hexHash)
+	}
+	prefixLen := min(c.shardPrefixLen, len(hexHash))
+	return filepath.Join(kind, hexHash[:prefixLen], hexHash)
+}
+
+func (c *ReferrerCache) ensureCapacity(need int64) (bool, error) {
+	if c.maxBytes <= 0 {
+		return true, nil
+	}
+	if need > c.maxBytes {
+		return false, nil
+	}
+	if c.SizeBytes()+need <= c.maxBytes {
+		return true, nil
+	}
+	if _, err := c.Prune(c.maxBytes - need); err != nil {
+		return false, err
+	}
+	return c.SizeBytes()+need <= c.maxBytes, nil
+}
+
+func (c *ReferrerCache) deleteByPath(root *os.Root, path string) error {
+	info, err := root.Stat(path)
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return nil
+		}
+		return err
+	}
+	if err := root.Remove(path); err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return nil
+		}
+		return err
+	}
+	c.bytes.Add(-info.Size())
+	return nil
+}
+
+// validDescriptors reports whether every descriptor has a valid digest.
+func validDescriptors(descs []ocispec.Descriptor) bool {
+	for i := range descs {
+		if err := descs[i].Digest.Validate(); err != nil {
+			return false
+		}
+	}
+	return true
+}
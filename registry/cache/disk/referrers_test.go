@@ -0,0 +1,211 @@
+package disk
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/opencontainers/go-digest"
+	ocispec "github.com/opencontainers/image-spec/specs-go/v1"
+)
+
+func TestReferrerCacheReferrers(t *testing.T) {
+	t.Parallel()
+
+	c, err := NewReferrerCache(t.TempDir())
+	if err != nil {
+		t.Fatalf("NewReferrerCache() error = %v", err)
+	}
+
+	subject := digest.FromString("subject").String()
+	sig := ocispec.Descriptor{
+		MediaType:    ocispec.MediaTypeImageManifest,
+		ArtifactType: "application/vnd.dev.sigstore.bundle.v0.3+json",
+		Digest:       digest.FromString("signature"),
+		Size:         9,
+	}
+
+	if _, ok := c.GetReferrers(subject, ""); ok {
+		t.Fatal("GetReferrers() ok = true, want false before put")
+	}
+	if err := c.PutReferrers(subject, "", []ocispec.Descriptor{sig}); err != nil {
+		t.Fatalf("PutReferrers() error = %v", err)
+	}
+
+	got, ok := c.GetReferrers(subject, "")
+	if !ok {
+		t.Fatal("GetReferrers() ok = false, want true")
+	}
+	if len(got) != 1 || got[0].Digest != sig.Digest || got[0].ArtifactType != sig.ArtifactType {
+		t.Fatalf("GetReferrers() = %+v, want [%+v]", got, sig)
+	}
+
+	// Listings are keyed by artifact type.
+	if _, ok := c.GetReferrers(subject, "application/other"); ok {
+		t.Fatal("GetReferrers() ok = true for a different artifact type")
+	}
+
+	// Empty listings are cached and replace earlier ones.
+	if err := c.PutReferrers(subject, "", nil); err != nil {
+		t.Fatalf("PutReferrers() error = %v", err)
+	}
+	got, ok = c.GetReferrers(subject, "")
+	if !ok || len(got) != 0 {
+		t.Fatalf("GetReferrers() = %v, %v; want empty hit", got, ok)
+	}
+	if c.SizeBytes() != int64(len("[]")) {
+		t.Fatalf("SizeBytes() = %d, want %d after replace", c.SizeBytes(), len("[]"))
+	}
+
+	if err := c.DeleteReferrers(subject, ""); err != nil {
+		t.Fatalf("DeleteReferrers() error = %v", err)
+	}
+	if _, ok := c.GetReferrers(subject, ""); ok {
+		t.Fatal("GetReferrers() ok = true after delete")
+	}
+	if c.SizeBytes() != 0 {
+		t.Fatalf("SizeBytes() = %d, want 0 after delete", c.SizeBytes())
+	}
+}
+
+func TestReferrerCacheContent(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	c, err := NewReferrerCache(dir)
+	if err != nil {
+		t.Fatalf("NewReferrerCache() error = %v", err)
+	}
+
+	content := []byte(`{"payload":"signature"}`)
+	dgst := digest.FromBytes(content)
+
+	if err := c.PutContent(dgst.String(), []byte("tampered")); err == nil {
+		t.Fatal("PutContent() error = nil, want digest mismatch")
+	}
+	if err := c.PutContent(dgst.String(), content); err != nil {
+		t.Fatalf("PutContent() error = %v", err)
+	}
+
+	got, ok := c.GetContent(dgst.String())
+	if !ok {
+		t.Fatal("GetContent() ok = false, want true")
+	}
+	if !bytes.Equal(got, content) {
+		t.Fatalf("GetContent() = %q, want %q", got, content)
+	}
+
+	// Corrupted content is deleted on read.
+	path := filepath.Join(dir, referrerContentDir, dgst.Encoded()[:defaultShardPrefixLen], dgst.Encoded())
+	if err := os.WriteFile(path, []byte("corrupt"), 0o600); err != nil {
+		t.Fatalf("WriteFile() error = %v", err)
+	}
+	if _, ok := c.GetContent(dgst.String()); ok {
+		t.Fatal("GetContent() ok = true, want false for corrupted data")
+	}
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		t.Fatal("expected corrupted cache file to be deleted")
+	}
+}
+
+func TestReferrerCacheCorruptedListing(t *testing.T) {
+	t.Parallel()
+
+	c, err := NewReferrerCache(t.TempDir(), WithShardPrefixLen(0))
+	if err != nil {
+		t.Fatalf("NewReferrerCache() error = %v", err)
+	}
+
+	subject := digest.FromString("subject").String()
+	path := filepath.Join(c.dir, c.listPath(subject, ""))
+	if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+		t.Fatalf("MkdirAll() error = %v", err)
+	}
+	if err := os.WriteFile(path, []byte(`[{"digest":"not-a-digest"}]`), 0o600); err != nil {
+		t.Fatalf("WriteFile() error = %v", err)
+	}
+
+	if _, ok := c.GetReferrers(subject, ""); ok {
+		t.Fatal("GetReferrers() ok = true, want false for corrupted listing")
+	}
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		t.Fatal("expected corrupted listing to be deleted")
+	}
+}
+
+func TestReferrerCacheMaxBytes(t *testing.T) {
+	t.Parallel()
+
+	c, err := NewReferrerCache(t.TempDir(), WithMaxBytes(8))
+	if err != nil {
+		t.Fatalf("NewReferrerCache() error = %v", err)
+	}
+
+	content := []byte("larger than the cache")
+	dgst := digest.FromBytes(content)
+	if err := c.PutContent(dgst.String(), content); err != nil {
+		t.Fatalf("PutContent() error = %v", err)
+	}
+	if _, ok := c.GetContent(dgst.String()); ok {
+		t.Fatal("GetContent() ok = true, want false when content exceeds max bytes")
+	}
+	if c.MaxBytes() != 8 {
+		t.Fatalf("MaxBytes() = %d, want 8", c.MaxBytes())
+	}
+
+	if _, err := NewReferrerCache(t.TempDir(), WithMaxBytes(-1)); err == nil {
+		t.Fatal("NewReferrerCache() error = nil, want error for negative max bytes")
+	}
+}
+
+func TestReferrerCacheTTLExpiresListing(t *testing.T) {
+	t.Parallel()
+
+	ttl := time.Minute
+	c, err := NewReferrerCache(t.TempDir(), WithReferrerCacheTTL(ttl))
+	if err != nil {
+		t.Fatalf("NewReferrerCache() error = %v", err)
+	}
+
+	subject := digest.FromString("subject").String()
+	content := []byte("signature")
+	sig := ocispec.Descriptor{MediaType: ocispec.MediaTypeImageManifest, Digest: digest.FromBytes(content), Size: int64(len(content))}
+	if err := c.PutReferrers(subject, "", []ocispec.Descriptor{sig}); err != nil {
+		t.Fatalf("PutReferrers() error = %v", err)
+	}
+	if err := c.PutContent(sig.Digest.String(), content); err != nil {
+		t.Fatalf("PutContent() error = %v", err)
+	}
+	if _, ok := c.GetReferrers(subject, ""); !ok {
+		t.Fatal("GetReferrers() ok = false, want true for unexpired listing")
+	}
+
+	expired := time.Now().Add(-2 * ttl)
+	listPath := filepath.Join(c.dir, c.listPath(subject, ""))
+	contentPath, err := c.contentPath(sig.Digest.String())
+	if err != nil {
+		t.Fatalf("contentPath() error = %v", err)
+	}
+	for _, path := range []string{listPath, filepath.Join(c.dir, contentPath)} {
+		if err := os.Chtimes(path, expired, expired); err != nil {
+			t.Fatalf("Chtimes() error = %v", err)
+		}
+	}
+
+	if _, ok := c.GetReferrers(subject, ""); ok {
+		t.Fatal("GetReferrers() ok = true, want false for expired listing")
+	}
+	if _, err := os.Stat(listPath); !errors.Is(err, os.ErrNotExist) {
+		t.Fatalf("expected listing to be deleted, got err=%v", err)
+	}
+	if _, ok := c.GetContent(sig.Digest.String()); !ok {
+		t.Fatal("GetContent() ok = false, want content kept past the listing TTL")
+	}
+
+	if _, err := NewReferrerCache(t.TempDir(), WithReferrerCacheTTL(-time.Second)); err == nil {
+		t.Fatal("NewReferrerCache() error = nil, want error for negative ttl")
+	}
+}
@@ -13,6 +13,7 @@ type Client struct {
 	refCache      cache.RefCache
 	manifestCache cache.ManifestCache
 	indexCache    cache.IndexCache
+	referrerCache cache.ReferrerCache
 	policies      []Policy
 	logger        *slog.Logger
 
@@ -66,6 +66,14 @@ func WithIndexCache(ic cache.IndexCache) Option {
 	}
 }
 
+// WithReferrerCache sets the cache for referrer listings and content
+// used by FetchReferrers.
+func WithReferrerCache(rc cache.ReferrerCache) Option {
+	return func(c *Client) {
+		c.referrerCache = rc
+	}
+}
+
 // WithPolicy adds a policy that must pass for Fetch and Pull operations.
 func WithPolicy(policy Policy) Option {
 	return func(c *Client) {
@@ -77,6 +85,8 @@ func WithPolicy(policy Policy) Option {
 }
 
 // WithPolicies adds policies that must pass for Fetch and Pull operations.
+// All policies of a client share the registry fetches of one evaluation,
+// as the policies of a policy.RequireAll composition do.
 func WithPolicies(policies ...Policy) Option {
 	return func(c *Client) {
 		for _, policy := range policies {
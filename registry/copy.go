@@ -0,0 +1,252 @@
+package registry
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+
+	ocispec "github.com/opencontainers/image-spec/specs-go/v1"
+
+	"github.com/meigma/blob/registry/oras"
+)
+
+var _ copyProvider = (*oras.Client)(nil)
+
+// copyProvider is an optional interface that OCIClient implementations
+// can provide to support Copy.
+type copyProvider interface {
+	// BlobExists reports whether the repository already holds the blob.
+	BlobExists(ctx context.Context, repoRef string, desc *ocispec.Descriptor) (bool, error)
+
+	// MountBlob mounts a blob from another repository on the same registry,
+	// uploading it from getContent if mounting is not supported.
+	MountBlob(ctx context.Context, repoRef, fromRepo string, desc *ocispec.Descriptor, getContent func() (io.ReadCloser, error)) error
+
+	// PushManifestRaw pushes manifest bytes unchanged, optionally tagging them.
+	PushManifestRaw(ctx context.Context, repoRef, tag string, desc *ocispec.Descriptor, raw []byte) error
+}
+
+// Copy transfers the archive at srcRef to dstRef without extracting it.
+//
+// The manifest, index, and data blobs are copied by digest, so the archive
+// keeps its digest and annotations. Blobs already present in the destination
+// are skipped, and blobs on the same registry are mounted instead of
+// uploaded. Referrers of the manifest (signatures, attestations) are copied
+// as well unless disabled with WithCopyReferrers(false).
+//
+// If srcRef names an OCI image index, every manifest it lists is copied,
+// with its blobs and referrers, before the index itself. Copy returns
+// ErrCopyUnsupported if the OCI client cannot read image indexes.
+//
+// The dstRef tag is applied to the copied manifest. If dstRef has no tag,
+// the tag from srcRef is used; if dstRef names a digest, it must match the
+// source manifest. Copy returns ErrCopyUnsupported if the OCI client does
+// not implement the operations it needs.
+func (c *Client) Copy(ctx context.Context, srcRef, dstRef string, opts ...CopyOption) error {
+	cfg := copyConfig{}
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+
+	provider, ok := c.oci.(copyProvider)
+	if !ok {
+		return ErrCopyUnsupported
+	}
+
+	src, err := parseClientRef(srcRef)
+	if err != nil {
+		return err
+	}
+	if src.reference == "" {
+		return fmt.Errorf("%w: source reference must include a tag or digest", ErrInvalidReference)
+	}
+	dst, err := parseClientRef(dstRef)
+	if err != nil {
+		return err
+	}
+
+	// Resolve from the registry: promotions must see the current tag, and
+	// the media type tells an image index from a manifest.
+	desc, err := c.oci.Resolve(ctx, srcRef, src.reference)
+	if err != nil {
+		return mapOCIError(err)
+	}
+	srcDigest := desc.Digest.String()
+
+	tag := dst.reference
+	switch {
+	case tag == "" && !isDigest(src.reference):
+		tag = src.reference
+	case isDigest(tag):
+		if tag != srcDigest {
+			return fmt.Errorf("%w: destination digest %s does not match source %s", ErrInvalidReference, tag, srcDigest)
+		}
+		tag = ""
+	}
+
+	cp := &copier{
+		client:    c,
+		provider:  provider,
+		srcRef:    srcRef,
+		dstRef:    dstRef,
+		referrers: !cfg.skipReferrers,
+		seen:      make(map[string]struct{}),
+	}
+	if src.registry == dst.registry && src.repository != dst.repository {
+		cp.mountFrom = src.repository
+	}
+
+	c.log().Info("copying archive", "src", srcRef, "dst", dstRef, "digest", srcDigest)
+	desc, err = cp.copyManifest(ctx, desc, tag)
+	if err != nil {
+		return err
+	}
+	if !cp.referrers {
+		return nil
+	}
+	return cp.copyReferrers(ctx, desc)
+}
+
+// copier holds the state of a single Copy operation.
+type copier struct {
+	client    *Client
+	provider  copyProvider
+	srcRef    string
+	dstRef    string
+	mountFrom string              // source repository when on the same registry
+	referrers bool                // copy referrers of image index entries
+	seen      map[string]struct{} // manifest digests already copied
+}
+
+// copyManifest copies the manifest identified by desc and the blobs it
+// references, then pushes the manifest to the destination under tag.
+// It returns desc completed with the manifest's media type and size.
+func (cp *copier) copyManifest(ctx context.Context, desc ocispec.Descriptor, tag string) (ocispec.Descriptor, error) {
+	if desc.MediaType == ocispec.MediaTypeImageIndex {
+		return cp.copyIndex(ctx, desc, tag)
+	}
+
+	manifest, raw, err := cp.client.oci.FetchManifest(ctx, cp.srcRef, &desc)
+	if err != nil {
+		return ocispec.Descriptor{}, mapOCIError(err)
+	}
+	desc.Size = int64(len(raw))
+	if desc.MediaType == "" {
+		desc.MediaType = manifest.MediaType
+	}
+	if desc.MediaType == "" {
+		desc.MediaType = ocispec.MediaTypeImageManifest
+	}
+	cp.seen[desc.Digest.String()] = struct{}{}
+
+	blobs := make([]ocispec.Descriptor, 0, len(manifest.Layers)+1)
+	if manifest.Config.Digest != "" {
+		blobs = append(blobs, manifest.Config)
+	}
+	blobs = append(blobs, manifest.Layers...)
+	for i := range blobs {
+		if err := cp.copyBlob(ctx, &blobs[i]); err != nil {
+			return ocispec.Descriptor{}, err
+		}
+	}
+
+	if err := cp.provider.PushManifestRaw(ctx, cp.dstRef, tag, &desc, raw); err != nil {
+		return ocispec.Descriptor{}, fmt.Errorf("push manifest %s: %w", desc.Digest, mapOCIError(err))
+	}
+	return desc, nil
+}
+
+// copyIndex copies the image index identified by desc. The manifests it
+// lists are copied first, with their referrers, so the destination never
+// holds an index whose entries are missing.
+func (cp *copier) copyIndex(ctx context.Context, desc ocispec.Descriptor, tag string) (ocispec.Descriptor, error) {
+	fetcher, ok := cp.client.oci.(indexFetcher)
+	if !ok {
+		return ocispec.Descriptor{}, fmt.Errorf("%w: %s is an image index, which the OCI client cannot read", ErrCopyUnsupported, desc.Digest)
+	}
+	index, raw, err := fetcher.FetchIndex(ctx, cp.srcRef, &desc)
+	if err != nil {
+		return ocispec.Descriptor{}, fmt.Errorf("fetch image index: %w", mapOCIError(err))
+	}
+	desc.Size = int64(len(raw))
+	cp.seen[desc.Digest.String()] = struct{}{}
+
+	for _, entry := range index.Manifests {
+		if _, ok := cp.seen[entry.Digest.String()]; ok {
+			continue
+		}
+		copied, err := cp.copyManifest(ctx, entry, "")
+		if err != nil {
+			return ocispec.Descriptor{}, fmt.Errorf("copy index entry %s: %w", entry.Digest, err)
+		}
+		if cp.referrers {
+			if err := cp.copyReferrers(ctx, copied); err != nil {
+				return ocispec.Descriptor{}, err
+			}
+		}
+	}
+
+	if err := cp.provider.PushManifestRaw(ctx, cp.dstRef, tag, &desc, raw); err != nil {
+		return ocispec.Descriptor{}, fmt.Errorf("push image index %s: %w", desc.Digest, mapOCIError(err))
+	}
+	return desc, nil
+}
+
+// copyBlob copies a single blob unless the destination already has it.
+func (cp *copier) copyBlob(ctx context.Context, desc *ocispec.Descriptor) error {
+	exists, err := cp.provider.BlobExists(ctx, cp.dstRef, desc)
+	if err != nil {
+		return fmt.Errorf("check blob %s: %w", desc.Digest, mapOCIError(err))
+	}
+	if exists {
+		cp.client.log().Debug("blob exists in destination", "digest", desc.Digest)
+		return nil
+	}
+
+	fetch := func() (io.ReadCloser, error) {
+		return cp.client.oci.FetchBlob(ctx, cp.srcRef, desc)
+	}
+	if cp.mountFrom != "" {
+		if err := cp.provider.MountBlob(ctx, cp.dstRef, cp.mountFrom, desc, fetch); err != nil {
+			return fmt.Errorf("mount blob %s: %w", desc.Digest, mapOCIError(err))
+		}
+		return nil
+	}
+
+	rc, err := fetch()
+	if err != nil {
+		return fmt.Errorf("fetch blob %s: %w", desc.Digest, mapOCIError(err))
+	}
+	defer rc.Close()
+	if err := cp.client.oci.PushBlob(ctx, cp.dstRef, desc, rc); err != nil {
+		return fmt.Errorf("push blob %s: %w", desc.Digest, mapOCIError(err))
+	}
+	return nil
+}
+
+// copyReferrers copies the referrers of subject, and their referrers in
+// turn. Registries without referrers support are treated as having none.
+func (cp *copier) copyReferrers(ctx context.Context, subject ocispec.Descriptor) error {
+	descs, err := cp.client.Referrers(ctx, cp.srcRef, subject, "")
+	if errors.Is(err, ErrReferrersUnsupported) {
+		cp.client.log().Debug("referrers unsupported; skipping", "src", cp.srcRef)
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	for _, desc := range descs {
+		if _, ok := cp.seen[desc.Digest.String()]; ok {
+			continue
+		}
+		copied, err := cp.copyManifest(ctx, desc, "")
+		if err != nil {
+			return fmt.Errorf("copy referrer %s: %w", desc.Digest, err)
+		}
+		if err := cp.copyReferrers(ctx, copied); err != nil {
+			return err
+		}
+	}
+	return nil
+}
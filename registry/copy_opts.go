@@ -0,0 +1,16 @@
+package registry
+
+// CopyOption configures a Copy operation.
+type CopyOption func(*copyConfig)
+
+type copyConfig struct {
+	skipReferrers bool
+}
+
+// WithCopyReferrers controls whether referrer artifacts such as signatures
+// and attestations are copied along with the archive (default: true).
+func WithCopyReferrers(enabled bool) CopyOption {
+	return func(cfg *copyConfig) {
+		cfg.skipReferrers = !enabled
+	}
+}
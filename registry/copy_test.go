@@ -0,0 +1,402 @@
+package registry
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"sync"
+	"testing"
+
+	"github.com/opencontainers/go-digest"
+	"github.com/opencontainers/image-spec/specs-go"
+	ocispec "github.com/opencontainers/image-spec/specs-go/v1"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	orasregistry "oras.land/oras-go/v2/registry"
+
+	"github.com/meigma/blob/registry/oras"
+)
+
+// memRegistry is an in-memory OCIClient spanning any number of registries
+// and repositories. It implements the optional copy, image index, and
+// referrers methods.
+type memRegistry struct {
+	mu        sync.Mutex
+	blobs     map[string][]byte // repo@digest
+	manifests map[string][]byte // repo@digest
+	tags      map[string]string // repo:tag -> digest
+	referrers map[string][]ocispec.Descriptor
+	pushes    int
+	mounts    int
+}
+
+func newMemRegistry() *memRegistry {
+	return &memRegistry{
+		blobs:     make(map[string][]byte),
+		manifests: make(map[string][]byte),
+		tags:      make(map[string]string),
+		referrers: make(map[string][]ocispec.Descriptor),
+	}
+}
+
+func memRepo(repoRef string) string {
+	r, err := orasregistry.ParseReference(repoRef)
+	if err != nil {
+		panic(err)
+	}
+	return r.Registry + "/" + r.Repository
+}
+
+func (m *memRegistry) PushBlob(_ context.Context, repoRef string, desc *ocispec.Descriptor, r io.Reader) error {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return err
+	}
+	if digest.FromBytes(data) != desc.Digest {
+		return oras.ErrDigestMismatch
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.blobs[memRepo(repoRef)+"@"+desc.Digest.String()] = data
+	m.pushes++
+	return nil
+}
+
+func (m *memRegistry) FetchBlob(_ context.Context, repoRef string, desc *ocispec.Descriptor) (io.ReadCloser, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	data, ok := m.blobs[memRepo(repoRef)+"@"+desc.Digest.String()]
+	if !ok {
+		return nil, oras.ErrNotFound
+	}
+	return io.NopCloser(bytes.NewReader(data)), nil
+}
+
+func (m *memRegistry) PushManifest(ctx context.Context, repoRef, tag string, manifest *ocispec.Manifest) (ocispec.Descriptor, error) {
+	raw, err := json.Marshal(manifest)
+	if err != nil {
+		return ocispec.Descriptor{}, err
+	}
+	desc := ocispec.Descriptor{MediaType: ocispec.MediaTypeImageManifest, Digest: digest.FromBytes(raw), Size: int64(len(raw))}
+	return desc, m.PushManifestRaw(ctx, repoRef, tag, &desc, raw)
+}
+
+func (m *memRegistry) PushManifestByDigest(ctx context.Context, repoRef string, manifest *ocispec.Manifest) (ocispec.Descriptor, error) {
+	return m.PushManifest(ctx, repoRef, "", manifest)
+}
+
+func (m *memRegistry) FetchManifest(_ context.Context, repoRef string, expected *ocispec.Descriptor) (ocispec.Manifest, []byte, error) {
+	m.mu.Lock()
+	raw, ok := m.manifests[memRepo(repoRef)+"@"+expected.Digest.String()]
+	m.mu.Unlock()
+	if !ok {
+		return ocispec.Manifest{}, nil, oras.ErrNotFound
+	}
+	var manifest ocispec.Manifest
+	if err := json.Unmarshal(raw, &manifest); err != nil {
+		return ocispec.Manifest{}, nil, err
+	}
+	return manifest, raw, nil
+}
+
+func (m *memRegistry) Resolve(_ context.Context, repoRef, ref string) (ocispec.Descriptor, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	repo := memRepo(repoRef)
+	dgst := ref
+	if !isDigest(ref) {
+		var ok bool
+		if dgst, ok = m.tags[repo+":"+ref]; !ok {
+			return ocispec.Descriptor{}, oras.ErrNotFound
+		}
+	}
+	raw, ok := m.manifests[repo+"@"+dgst]
+	if !ok {
+		return ocispec.Descriptor{}, oras.ErrNotFound
+	}
+	var content struct {
+		MediaType string `json:"mediaType"`
+	}
+	if err := json.Unmarshal(raw, &content); err != nil {
+		return ocispec.Descriptor{}, err
+	}
+	return ocispec.Descriptor{MediaType: content.MediaType, Digest: digest.Digest(dgst), Size: int64(len(raw))}, nil
+}
+
+func (m *memRegistry) FetchIndex(_ context.Context, repoRef string, expected *ocispec.Descriptor) (ocispec.Index, []byte, error) {
+	m.mu.Lock()
+	raw, ok := m.manifests[memRepo(repoRef)+"@"+expected.Digest.String()]
+	m.mu.Unlock()
+	if !ok {
+		return ocispec.Index{}, nil, oras.ErrNotFound
+	}
+	var index ocispec.Index
+	if err := json.Unmarshal(raw, &index); err != nil {
+		return ocispec.Index{}, nil, err
+	}
+	return index, raw, nil
+}
+
+func (m *memRegistry) Tag(_ context.Context, repoRef string, desc *ocispec.Descriptor, tag string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.tags[memRepo(repoRef)+":"+tag] = desc.Digest.String()
+	return nil
+}
+
+func (m *memRegistry) BlobURL(string, string) (string, error) {
+	return "", errors.New("not implemented")
+}
+
+func (m *memRegistry) AuthHeaders(context.Context, string) (http.Header, error) {
+	return nil, errors.New("not implemented")
+}
+
+func (m *memRegistry) InvalidateAuthHeaders(string) error { return nil }
+
+func (m *memRegistry) BlobExists(_ context.Context, repoRef string, desc *ocispec.Descriptor) (bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	_, ok := m.blobs[memRepo(repoRef)+"@"+desc.Digest.String()]
+	return ok, nil
+}
+
+func (m *memRegistry) MountBlob(_ context.Context, repoRef, fromRepo string, desc *ocispec.Descriptor, _ func() (io.ReadCloser, error)) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	r, err := orasregistry.ParseReference(repoRef)
+	if err != nil {
+		return err
+	}
+	data, ok := m.blobs[r.Registry+"/"+fromRepo+"@"+desc.Digest.String()]
+	if !ok {
+		return oras.ErrNotFound
+	}
+	m.blobs[memRepo(repoRef)+"@"+desc.Digest.String()] = data
+	m.mounts++
+	return nil
+}
+
+func (m *memRegistry) PushManifestRaw(_ context.Context, repoRef, tag string, desc *ocispec.Descriptor, raw []byte) error {
+	var manifest ocispec.Manifest
+	if err := json.Unmarshal(raw, &manifest); err != nil {
+		return err
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	repo := memRepo(repoRef)
+	key := repo + "@" + desc.Digest.String()
+	if _, exists := m.manifests[key]; !exists && manifest.Subject != nil {
+		subjectKey := repo + "@" + manifest.Subject.Digest.String()
+		m.referrers[subjectKey] = append(m.referrers[subjectKey], ocispec.Descriptor{
+			MediaType:    desc.MediaType,
+			ArtifactType: manifest.ArtifactType,
+			Digest:       desc.Digest,
+			Size:         desc.Size,
+		})
+	}
+	m.manifests[key] = raw
+	if tag != "" {
+		m.tags[repo+":"+tag] = desc.Digest.String()
+	}
+	return nil
+}
+
+func (m *memRegistry) Referrers(_ context.Context, repoRef string, subject ocispec.Descriptor, _ string) ([]ocispec.Descriptor, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.referrers[memRepo(repoRef)+"@"+subject.Digest.String()], nil
+}
+
+func (m *memRegistry) hasBlob(repoRef string, dgst digest.Digest) bool {
+	ok, _ := m.BlobExists(context.Background(), repoRef, &ocispec.Descriptor{Digest: dgst})
+	return ok
+}
+
+// seedArchive pushes an archive manifest with an annotation and a signature
+// referrer to ref, returning the archive and signature manifest digests.
+func seedArchive(t *testing.T, m *memRegistry, ref string) (archive, signature ocispec.Descriptor) {
+	t.Helper()
+	ctx := context.Background()
+
+	push := func(mediaType string, data []byte) ocispec.Descriptor {
+		desc := ocispec.Descriptor{MediaType: mediaType, Digest: digest.FromBytes(data), Size: int64(len(data))}
+		require.NoError(t, m.PushBlob(ctx, ref, &desc, bytes.NewReader(data)))
+		return desc
+	}
+	config := push(ocispec.MediaTypeEmptyJSON, []byte("{}"))
+	manifest := &ocispec.Manifest{
+		Versioned:    specs.Versioned{SchemaVersion: 2},
+		MediaType:    ocispec.MediaTypeImageManifest,
+		ArtifactType: ArtifactType,
+		Config:       config,
+		Layers: []ocispec.Descriptor{
+			push(MediaTypeIndex, []byte("index")),
+			push(MediaTypeData, []byte("data")),
+		},
+		Annotations: map[string]string{"org.example.stage": "staging"},
+	}
+	archive, err := m.PushManifest(ctx, ref, "v1", manifest)
+	require.NoError(t, err)
+
+	sig := &ocispec.Manifest{
+		Versioned:    specs.Versioned{SchemaVersion: 2},
+		MediaType:    ocispec.MediaTypeImageManifest,
+		ArtifactType: "application/vnd.dev.sigstore.bundle.v0.3+json",
+		Config:       config,
+		Layers:       []ocispec.Descriptor{push("application/vnd.dev.sigstore.bundle.v0.3+json", []byte("signature"))},
+		Subject:      &archive,
+	}
+	signature, err = m.PushManifestByDigest(ctx, ref, sig)
+	require.NoError(t, err)
+	return archive, signature
+}
+
+func TestClient_Copy(t *testing.T) {
+	t.Parallel()
+
+	const (
+		srcRef = "staging.example.com/app:v1"
+		dstRef = "prod.example.com/app:v1"
+	)
+
+	t.Run("copies manifest, blobs, and referrers", func(t *testing.T) {
+		t.Parallel()
+		m := newMemRegistry()
+		archive, signature := seedArchive(t, m, srcRef)
+		client := New(WithOCIClient(m))
+
+		require.NoError(t, client.Copy(context.Background(), srcRef, dstRef))
+
+		desc, err := m.Resolve(context.Background(), dstRef, "v1")
+		require.NoError(t, err)
+		assert.Equal(t, archive.Digest, desc.Digest, "digest must be preserved")
+
+		manifest, _, err := m.FetchManifest(context.Background(), dstRef, &desc)
+		require.NoError(t, err)
+		assert.Equal(t, "staging", manifest.Annotations["org.example.stage"])
+		for _, layer := range manifest.Layers {
+			assert.True(t, m.hasBlob(dstRef, layer.Digest))
+		}
+
+		referrers, err := m.Referrers(context.Background(), dstRef, archive, "")
+		require.NoError(t, err)
+		require.Len(t, referrers, 1)
+		assert.Equal(t, signature.Digest, referrers[0].Digest)
+		assert.True(t, m.hasBlob(dstRef, digest.FromBytes([]byte("signature"))))
+	})
+
+	t.Run("copies image index entries", func(t *testing.T) {
+		t.Parallel()
+		m := newMemRegistry()
+		archive, signature := seedArchive(t, m, srcRef)
+		index := ocispec.Index{
+			Versioned: specs.Versioned{SchemaVersion: 2},
+			MediaType: ocispec.MediaTypeImageIndex,
+			Manifests: []ocispec.Descriptor{{
+				MediaType: archive.MediaType,
+				Digest:    archive.Digest,
+				Size:      archive.Size,
+				Platform:  &ocispec.Platform{OS: "linux", Architecture: "amd64"},
+			}},
+		}
+		raw, err := json.Marshal(index)
+		require.NoError(t, err)
+		indexDesc := ocispec.Descriptor{MediaType: ocispec.MediaTypeImageIndex, Digest: digest.FromBytes(raw), Size: int64(len(raw))}
+		require.NoError(t, m.PushManifestRaw(context.Background(), srcRef, "multi", &indexDesc, raw))
+
+		require.NoError(t, New(WithOCIClient(m)).Copy(context.Background(), "staging.example.com/app:multi", dstRef))
+
+		desc, err := m.Resolve(context.Background(), dstRef, "v1")
+		require.NoError(t, err)
+		assert.Equal(t, indexDesc.Digest, desc.Digest, "digest must be preserved")
+		assert.Equal(t, ocispec.MediaTypeImageIndex, desc.MediaType)
+
+		entry, err := m.Resolve(context.Background(), dstRef, archive.Digest.String())
+		require.NoError(t, err, "index entries must be copied")
+		manifest, _, err := m.FetchManifest(context.Background(), dstRef, &entry)
+		require.NoError(t, err)
+		for _, layer := range manifest.Layers {
+			assert.True(t, m.hasBlob(dstRef, layer.Digest))
+		}
+
+		referrers, err := m.Referrers(context.Background(), dstRef, archive, "")
+		require.NoError(t, err)
+		require.Len(t, referrers, 1)
+		assert.Equal(t, signature.Digest, referrers[0].Digest)
+	})
+
+	t.Run("skips blobs present in destination", func(t *testing.T) {
+		t.Parallel()
+		m := newMemRegistry()
+		seedArchive(t, m, srcRef)
+		data := []byte("data")
+		require.NoError(t, m.PushBlob(context.Background(), dstRef,
+			&ocispec.Descriptor{Digest: digest.FromBytes(data), Size: int64(len(data))}, bytes.NewReader(data)))
+		before := m.pushes
+
+		require.NoError(t, New(WithOCIClient(m)).Copy(context.Background(), srcRef, dstRef))
+		// config, index, and signature blobs; the data blob is skipped and the
+		// config blob is shared by both manifests.
+		assert.Equal(t, 3, m.pushes-before)
+	})
+
+	t.Run("mounts within a registry", func(t *testing.T) {
+		t.Parallel()
+		m := newMemRegistry()
+		seedArchive(t, m, srcRef)
+		before := m.pushes
+
+		require.NoError(t, New(WithOCIClient(m)).Copy(context.Background(), srcRef, "staging.example.com/release:v1"))
+		assert.Equal(t, before, m.pushes)
+		assert.Equal(t, 4, m.mounts)
+	})
+
+	t.Run("without referrers", func(t *testing.T) {
+		t.Parallel()
+		m := newMemRegistry()
+		archive, _ := seedArchive(t, m, srcRef)
+
+		require.NoError(t, New(WithOCIClient(m)).Copy(context.Background(), srcRef, dstRef, WithCopyReferrers(false)))
+		referrers, err := m.Referrers(context.Background(), dstRef, archive, "")
+		require.NoError(t, err)
+		assert.Empty(t, referrers)
+	})
+
+	t.Run("destination tag defaults to source tag", func(t *testing.T) {
+		t.Parallel()
+		m := newMemRegistry()
+		archive, _ := seedArchive(t, m, srcRef)
+
+		require.NoError(t, New(WithOCIClient(m)).Copy(context.Background(), srcRef, "prod.example.com/app"))
+		desc, err := m.Resolve(context.Background(), dstRef, "v1")
+		require.NoError(t, err)
+		assert.Equal(t, archive.Digest, desc.Digest)
+	})
+
+	t.Run("destination digest must match", func(t *testing.T) {
+		t.Parallel()
+		m := newMemRegistry()
+		seedArchive(t, m, srcRef)
+
+		wrong := fmt.Sprintf("prod.example.com/app@%s", digest.FromString("other"))
+		err := New(WithOCIClient(m)).Copy(context.Background(), srcRef, wrong)
+		assert.ErrorIs(t, err, ErrInvalidReference)
+	})
+
+	t.Run("missing source", func(t *testing.T) {
+		t.Parallel()
+		err := New(WithOCIClient(newMemRegistry())).Copy(context.Background(), srcRef, dstRef)
+		assert.ErrorIs(t, err, ErrNotFound)
+	})
+
+	t.Run("unsupported client", func(t *testing.T) {
+		t.Parallel()
+		err := New(WithOCIClient(&mockOCIClient{})).Copy(context.Background(), srcRef, dstRef)
+		assert.ErrorIs(t, err, ErrCopyUnsupported)
+	})
+}
@@ -0,0 +1,121 @@
+package registry
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	ocispec "github.com/opencontainers/image-spec/specs-go/v1"
+
+	"github.com/meigma/blob/registry/oras"
+)
+
+var _ manifestDeleter = (*oras.Client)(nil)
+
+// manifestDeleter is an optional interface that OCIClient implementations
+// can provide to support Delete.
+type manifestDeleter interface {
+	DeleteManifest(ctx context.Context, repoRef string, desc *ocispec.Descriptor) error
+}
+
+// Delete removes the archive manifest at ref from the registry.
+//
+// Tags are resolved to a manifest digest first and the manifest is deleted
+// by digest, so registries that only accept digest-based deletes are
+// supported. Deleting a manifest removes every tag that points to it. With
+// WithDeleteReferrers(true), referrers of the manifest (signatures,
+// attestations) are deleted first; registries without referrers support are
+// treated as having none. On success the ref and manifest cache entries for
+// ref are invalidated.
+//
+// Delete returns ErrDeleteUnsupported if the OCI client does not implement
+// deletes or the registry rejects them.
+func (c *Client) Delete(ctx context.Context, ref string, opts ...DeleteOption) error {
+	cfg := deleteConfig{}
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+
+	deleter, ok := c.oci.(manifestDeleter)
+	if !ok {
+		return ErrDeleteUnsupported
+	}
+
+	parsedRef, err := parseClientRef(ref)
+	if err != nil {
+		return err
+	}
+	if parsedRef.reference == "" {
+		return fmt.Errorf("%w: reference must include a tag or digest", ErrInvalidReference)
+	}
+
+	// Always resolve from the registry: a stale cached digest would delete
+	// the wrong manifest.
+	desc, err := c.oci.Resolve(ctx, ref, parsedRef.reference)
+	if err != nil {
+		return mapOCIError(err)
+	}
+
+	c.log().Info("deleting archive", "ref", ref, "digest", desc.Digest.String())
+
+	if cfg.referrers {
+		if err := c.deleteReferrers(ctx, deleter, ref, desc, make(map[string]struct{})); err != nil {
+			return err
+		}
+	}
+
+	if err := deleter.DeleteManifest(ctx, ref, &desc); err != nil {
+		return mapOCIError(err)
+	}
+
+	c.invalidateCaches(ref, desc.Digest.String())
+	return nil
+}
+
+// deleteReferrers deletes the referrers of subject, depth first, so that
+// referrers of referrers are removed before their subject.
+//
+//nolint:gocritic // hugeParam: descriptors are passed by value like oras-go
+func (c *Client) deleteReferrers(ctx context.Context, deleter manifestDeleter, ref string, subject ocispec.Descriptor, seen map[string]struct{}) error {
+	referrers, err := c.Referrers(ctx, ref, subject, "")
+	if errors.Is(err, ErrReferrersUnsupported) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("list referrers of %s: %w", subject.Digest, err)
+	}
+
+	for i := range referrers {
+		referrer := &referrers[i]
+		key := referrer.Digest.String()
+		if _, ok := seen[key]; ok {
+			continue
+		}
+		seen[key] = struct{}{}
+
+		if err := c.deleteReferrers(ctx, deleter, ref, *referrer, seen); err != nil {
+			return err
+		}
+		c.log().Debug("deleting referrer", "digest", key, "artifact_type", referrer.ArtifactType)
+		if err := deleter.DeleteManifest(ctx, ref, referrer); err != nil {
+			return fmt.Errorf("delete referrer %s: %w", key, mapOCIError(err))
+		}
+		c.invalidateCaches("", key)
+	}
+	return nil
+}
+
+// invalidateCaches removes cached state for a deleted manifest. Errors are
+// ignored: the registry is the source of truth and stale entries are
+// detected on the next fetch.
+func (c *Client) invalidateCaches(ref, dgst string) {
+	if ref != "" && c.refCache != nil {
+		_ = c.refCache.Delete(ref) //nolint:errcheck // best-effort cleanup
+	}
+	if c.manifestCache != nil {
+		_ = c.manifestCache.Delete(dgst) //nolint:errcheck // best-effort cleanup
+	}
+	if c.referrerCache != nil {
+		_ = c.referrerCache.DeleteReferrers(dgst, "") //nolint:errcheck // best-effort cleanup
+	}
+}
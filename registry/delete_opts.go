@@ -0,0 +1,16 @@
+package registry
+
+// DeleteOption configures a Delete operation.
+type DeleteOption func(*deleteConfig)
+
+type deleteConfig struct {
+	referrers bool
+}
+
+// WithDeleteReferrers controls whether referrer artifacts such as signatures
+// and attestations are deleted along with the archive (default: false).
+func WithDeleteReferrers(enabled bool) DeleteOption {
+	return func(cfg *deleteConfig) {
+		cfg.referrers = enabled
+	}
+}
@@ -0,0 +1,130 @@
+package registry
+
+import (
+	"context"
+	"testing"
+
+	ocispec "github.com/opencontainers/image-spec/specs-go/v1"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/meigma/blob/registry/oras"
+)
+
+func (m *memRegistry) DeleteManifest(_ context.Context, repoRef string, desc *ocispec.Descriptor) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	repo := memRepo(repoRef)
+	key := repo + "@" + desc.Digest.String()
+	if _, ok := m.manifests[key]; !ok {
+		return oras.ErrNotFound
+	}
+	delete(m.manifests, key)
+	delete(m.referrers, key)
+	for tagKey, dgst := range m.tags {
+		if dgst == desc.Digest.String() {
+			delete(m.tags, tagKey)
+		}
+	}
+	return nil
+}
+
+func (m *memRegistry) hasManifest(repoRef string, desc ocispec.Descriptor) bool {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	_, ok := m.manifests[memRepo(repoRef)+"@"+desc.Digest.String()]
+	return ok
+}
+
+func TestClient_Delete(t *testing.T) {
+	t.Parallel()
+
+	const ref = "registry.example.com/repo:v1"
+
+	t.Run("deletes tag and manifest", func(t *testing.T) {
+		t.Parallel()
+
+		m := newMemRegistry()
+		archive, signature := seedArchive(t, m, ref)
+		c := &Client{oci: m}
+
+		require.NoError(t, c.Delete(context.Background(), ref))
+
+		assert.False(t, m.hasManifest(ref, archive))
+		assert.True(t, m.hasManifest(ref, signature), "referrers are kept by default")
+		_, err := c.Fetch(context.Background(), ref, WithSkipCache())
+		require.ErrorIs(t, err, ErrNotFound)
+	})
+
+	t.Run("deletes referrers when requested", func(t *testing.T) {
+		t.Parallel()
+
+		m := newMemRegistry()
+		archive, signature := seedArchive(t, m, ref)
+		c := &Client{oci: m}
+
+		require.NoError(t, c.Delete(context.Background(), ref, WithDeleteReferrers(true)))
+
+		assert.False(t, m.hasManifest(ref, archive))
+		assert.False(t, m.hasManifest(ref, signature))
+	})
+
+	t.Run("deletes by digest", func(t *testing.T) {
+		t.Parallel()
+
+		m := newMemRegistry()
+		archive, _ := seedArchive(t, m, ref)
+		c := &Client{oci: m}
+
+		require.NoError(t, c.Delete(context.Background(), "registry.example.com/repo@"+archive.Digest.String()))
+		assert.False(t, m.hasManifest(ref, archive))
+	})
+
+	t.Run("invalidates caches", func(t *testing.T) {
+		t.Parallel()
+
+		m := newMemRegistry()
+		archive, _ := seedArchive(t, m, ref)
+		refCache := newBenchRefCache()
+		manifestCache := newBenchManifestCache()
+		c := &Client{oci: m, refCache: refCache, manifestCache: manifestCache}
+
+		_, err := c.Fetch(context.Background(), ref)
+		require.NoError(t, err)
+		_, ok := refCache.GetDigest(ref)
+		require.True(t, ok)
+
+		require.NoError(t, c.Delete(context.Background(), ref))
+
+		_, ok = refCache.GetDigest(ref)
+		assert.False(t, ok, "ref cache entry must be removed")
+		_, _, ok = manifestCache.GetManifest(archive.Digest.String())
+		assert.False(t, ok, "manifest cache entry must be removed")
+		_, err = c.Fetch(context.Background(), ref)
+		require.ErrorIs(t, err, ErrNotFound)
+	})
+
+	t.Run("missing ref", func(t *testing.T) {
+		t.Parallel()
+
+		c := &Client{oci: newMemRegistry()}
+		err := c.Delete(context.Background(), ref)
+		require.ErrorIs(t, err, ErrNotFound)
+	})
+
+	t.Run("requires tag or digest", func(t *testing.T) {
+		t.Parallel()
+
+		c := &Client{oci: newMemRegistry()}
+		err := c.Delete(context.Background(), "registry.example.com/repo")
+		require.ErrorIs(t, err, ErrInvalidReference)
+	})
+
+	t.Run("unsupported client", func(t *testing.T) {
+		t.Parallel()
+
+		c := &Client{oci: &mockOCIClient{}}
+		err := c.Delete(context.Background(), ref)
+		require.ErrorIs(t, err, ErrDeleteUnsupported)
+	})
+}
@@ -27,4 +27,22 @@ var (
 
 	// ErrReferrersUnsupported is returned when referrers are not supported by the OCI client.
 	ErrReferrersUnsupported = errors.New("client: referrers unsupported")
+
+	// ErrUnauthorized is returned when the registry rejects the credentials.
+	ErrUnauthorized = errors.New("client: unauthorized")
+
+	// ErrForbidden is returned when the credentials lack access to the repository.
+	ErrForbidden = errors.New("client: forbidden")
+
+	// ErrListTagsUnsupported is returned when tag listing is not supported by the OCI client.
+	ErrListTagsUnsupported = errors.New("client: tag listing unsupported")
+
+	// ErrDeleteUnsupported is returned when the OCI client or registry does not support deletes.
+	ErrDeleteUnsupported = errors.New("client: delete unsupported")
+
+	// ErrCopyUnsupported is returned when the OCI client cannot copy between repositories.
+	ErrCopyUnsupported = errors.New("client: copy unsupported")
+
+	// ErrPlatformNotFound is returned when an image index has no manifest for the requested platform.
+	ErrPlatformNotFound = errors.New("client: platform not found")
 )
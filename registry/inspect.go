@@ -46,7 +46,7 @@ func (c *Client) Inspect(ctx context.Context, ref string, opts ...InspectOption)
 		skipCache:    cfg.skipCache,
 		maxIndexSize: cfg.maxIndexSize,
 	}
-	indexData, err := c.fetchIndexBlob(ctx, ref, manifest, pullCfg)
+	indexData, _, err := c.fetchIndexBlob(ctx, ref, manifest, pullCfg, nil)
 	if err != nil {
 		return nil, err
 	}
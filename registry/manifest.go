@@ -16,6 +16,7 @@ type BlobManifest struct {
 	digest    string
 	indexDesc ocispec.Descriptor
 	dataDesc  ocispec.Descriptor
+	dictDesc  *ocispec.Descriptor // nil when the archive has no dictionary
 	created   time.Time
 }
 
@@ -29,6 +30,15 @@ func (m *BlobManifest) DataDescriptor() ocispec.Descriptor {
 	return m.dataDesc
 }
 
+// ZstdDictionaryDescriptor returns the descriptor for the zstd dictionary
+// blob. ok is false when the archive was created without a dictionary.
+func (m *BlobManifest) ZstdDictionaryDescriptor() (ocispec.Descriptor, bool) {
+	if m.dictDesc == nil {
+		return ocispec.Descriptor{}, false
+	}
+	return *m.dictDesc, true
+}
+
 // Digest returns the manifest digest.
 func (m *BlobManifest) Digest() string {
 	return m.digest
@@ -61,8 +71,16 @@ func parseBlobManifest(manifest *ocispec.Manifest, digest string) (*BlobManifest
 	}
 
 	var indexDesc, dataDesc ocispec.Descriptor
+	var dictDesc *ocispec.Descriptor
 	var foundIndex, foundData bool
 
+	// The index may be stored as the config blob instead of a layer.
+	indexInConfig := manifest.Config.MediaType == MediaTypeIndex
+	if indexInConfig {
+		indexDesc = manifest.Config
+		foundIndex = true
+	}
+
 	for _, layer := range manifest.Layers {
 		switch layer.MediaType {
 		case MediaTypeIndex:
@@ -77,6 +95,11 @@ func parseBlobManifest(manifest *ocispec.Manifest, digest string) (*BlobManifest
 			}
 			dataDesc = layer
 			foundData = true
+		case MediaTypeZstdDictionary:
+			if dictDesc != nil {
+				return nil, fmt.Errorf("%w: multiple zstd dictionary layers", ErrInvalidManifest)
+			}
+			dictDesc = &layer
 		}
 	}
 
@@ -86,8 +109,15 @@ func parseBlobManifest(manifest *ocispec.Manifest, digest string) (*BlobManifest
 	if !foundData {
 		return nil, ErrMissingData
 	}
-	if len(manifest.Layers) != 2 {
-		return nil, fmt.Errorf("%w: expected 2 layers, got %d", ErrInvalidManifest, len(manifest.Layers))
+	wantLayers := 2
+	if indexInConfig {
+		wantLayers = 1
+	}
+	if dictDesc != nil {
+		wantLayers++
+	}
+	if len(manifest.Layers) != wantLayers {
+		return nil, fmt.Errorf("%w: expected %d layers, got %d", ErrInvalidManifest, wantLayers, len(manifest.Layers))
 	}
 
 	var created time.Time
@@ -102,6 +132,7 @@ func parseBlobManifest(manifest *ocispec.Manifest, digest string) (*BlobManifest
 		digest:    digest,
 		indexDesc: indexDesc,
 		dataDesc:  dataDesc,
+		dictDesc:  dictDesc,
 		created:   created,
 	}, nil
 }
@@ -10,4 +10,8 @@ const (
 
 	// MediaTypeData is the media type for the concatenated data blob.
 	MediaTypeData = "application/vnd.meigma.blob.data.v1"
+
+	// MediaTypeZstdDictionary is the media type for the optional zstd
+	// dictionary blob shared by compressed entries.
+	MediaTypeZstdDictionary = "application/vnd.meigma.blob.zstd-dictionary.v1"
 )
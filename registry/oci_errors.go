@@ -18,6 +18,15 @@ func mapOCIError(err error) error {
 	if errors.Is(err, oras.ErrNotFound) {
 		return fmt.Errorf("%w: %v", ErrNotFound, err)
 	}
+	if errors.Is(err, oras.ErrUnauthorized) {
+		return fmt.Errorf("%w: %w", ErrUnauthorized, err)
+	}
+	if errors.Is(err, oras.ErrForbidden) {
+		return fmt.Errorf("%w: %w", ErrForbidden, err)
+	}
+	if errors.Is(err, oras.ErrDeleteUnsupported) {
+		return fmt.Errorf("%w: %w", ErrDeleteUnsupported, err)
+	}
 	if errors.Is(err, oras.ErrReferrersUnsupported) {
 		return fmt.Errorf("%w: %v", ErrReferrersUnsupported, err)
 	}
@@ -33,6 +33,8 @@ type Client struct {
 	credStore       credentials.Store
 	authClient      *auth.Client // shared auth client with token cache
 	authHeaderCache *authHeaderCache
+	uploads         *uploadSessions
+	uploadChunkSize int64
 	logger          *slog.Logger //nolint:unused // reserved for future use
 }
 
@@ -41,6 +43,8 @@ func New(opts ...Option) *Client {
 	c := &Client{
 		userAgent:       "blob-client/1.0",
 		authHeaderCache: newAuthHeaderCache(defaultAuthHeaderCacheTTL),
+		uploads:         newUploadSessions(),
+		uploadChunkSize: DefaultUploadChunkSize,
 	}
 	for _, opt := range opts {
 		opt(c)
@@ -208,23 +212,62 @@ func (c *Client) PushManifestByDigest(ctx context.Context, repoRef string, manif
 // Call Resolve first and pass the resolved descriptor to avoid extra lookups.
 // Handles both OCI 1.0 and 1.1 manifest formats.
 func (c *Client) FetchManifest(ctx context.Context, repoRef string, expected *ocispec.Descriptor) (ocispec.Manifest, []byte, error) {
-	if err := validateDescriptor(expected); err != nil {
+	raw, err := c.fetchManifestBytes(ctx, repoRef, expected, ocispec.MediaTypeImageManifest)
+	if err != nil {
 		return ocispec.Manifest{}, nil, err
 	}
 
+	var manifest ocispec.Manifest
+	if err := json.Unmarshal(raw, &manifest); err != nil {
+		return ocispec.Manifest{}, nil, fmt.Errorf("%w: %v", ErrManifestInvalid, err)
+	}
+
+	return manifest, raw, nil
+}
+
+// FetchIndex fetches an OCI image index from the repository by descriptor.
+//
+// Resolve reports an image index with the media type
+// application/vnd.oci.image.index.v1+json; pass that descriptor here to
+// read the per-platform manifest descriptors it lists.
+func (c *Client) FetchIndex(ctx context.Context, repoRef string, expected *ocispec.Descriptor) (ocispec.Index, []byte, error) {
+	raw, err := c.fetchManifestBytes(ctx, repoRef, expected, ocispec.MediaTypeImageIndex)
+	if err != nil {
+		return ocispec.Index{}, nil, err
+	}
+
+	var index ocispec.Index
+	if err := json.Unmarshal(raw, &index); err != nil {
+		return ocispec.Index{}, nil, fmt.Errorf("%w: %v", ErrManifestInvalid, err)
+	}
+	if index.MediaType != "" && index.MediaType != ocispec.MediaTypeImageIndex {
+		return ocispec.Index{}, nil, fmt.Errorf("%w: unsupported media type %s", ErrManifestInvalid, index.MediaType)
+	}
+
+	return index, raw, nil
+}
+
+// fetchManifestBytes fetches and verifies the raw bytes of a manifest or
+// index. When expected has no media type, content the registry labels with
+// a media type other than mediaType is rejected.
+func (c *Client) fetchManifestBytes(ctx context.Context, repoRef string, expected *ocispec.Descriptor, mediaType string) ([]byte, error) {
+	if err := validateDescriptor(expected); err != nil {
+		return nil, err
+	}
+
 	repo, err := c.repository(repoRef)
 	if err != nil {
-		return ocispec.Manifest{}, nil, err
+		return nil, err
 	}
 
 	desc, rc, err := repo.FetchReference(ctx, expected.Digest.String())
 	if err != nil {
-		return ocispec.Manifest{}, nil, mapError(err)
+		return nil, mapError(err)
 	}
 	defer rc.Close()
 
-	if expected.MediaType == "" && desc.MediaType != "" && desc.MediaType != ocispec.MediaTypeImageManifest {
-		return ocispec.Manifest{}, nil, fmt.Errorf("%w: unsupported media type %s", ErrManifestInvalid, desc.MediaType)
+	if expected.MediaType == "" && desc.MediaType != "" && desc.MediaType != mediaType {
+		return nil, fmt.Errorf("%w: unsupported media type %s", ErrManifestInvalid, desc.MediaType)
 	}
 
 	// Use returned descriptor's size if expected size is 0
@@ -239,23 +282,18 @@ func (c *Client) FetchManifest(ctx context.Context, repoRef string, expected *oc
 
 	raw, err := io.ReadAll(reader)
 	if err != nil {
-		return ocispec.Manifest{}, nil, fmt.Errorf("%w: %v", ErrManifestInvalid, err)
+		return nil, fmt.Errorf("%w: %v", ErrManifestInvalid, err)
 	}
 	if size > 0 && int64(len(raw)) != size {
-		return ocispec.Manifest{}, nil, fmt.Errorf("%w: expected %d bytes, got %d", ErrSizeMismatch, size, len(raw))
+		return nil, fmt.Errorf("%w: expected %d bytes, got %d", ErrSizeMismatch, size, len(raw))
 	}
 
 	computed := expected.Digest.Algorithm().FromBytes(raw)
 	if computed != expected.Digest {
-		return ocispec.Manifest{}, nil, fmt.Errorf("%w: expected %s, got %s", ErrDigestMismatch, expected.Digest, computed)
+		return nil, fmt.Errorf("%w: expected %s, got %s", ErrDigestMismatch, expected.Digest, computed)
 	}
 
-	var manifest ocispec.Manifest
-	if err := json.Unmarshal(raw, &manifest); err != nil {
-		return ocispec.Manifest{}, nil, fmt.Errorf("%w: %v", ErrManifestInvalid, err)
-	}
-
-	return manifest, raw, nil
+	return raw, nil
 }
 
 // Resolve resolves a reference to a descriptor.
@@ -293,6 +331,123 @@ func (c *Client) Tag(ctx context.Context, repoRef string, desc *ocispec.Descript
 	return nil
 }
 
+// ListTags returns all tags in the repository in the order the registry
+// reports them. Paginated responses are followed until the last page.
+func (c *Client) ListTags(ctx context.Context, repoRef string) ([]string, error) {
+	repo, err := c.repository(repoRef)
+	if err != nil {
+		return nil, err
+	}
+
+	tags := []string{}
+	err = repo.Tags(ctx, "", func(page []string) error {
+		tags = append(tags, page...)
+		return nil
+	})
+	if err != nil {
+		return nil, mapError(err)
+	}
+	return tags, nil
+}
+
+// BlobExists reports whether the repository already holds the blob.
+func (c *Client) BlobExists(ctx context.Context, repoRef string, desc *ocispec.Descriptor) (bool, error) {
+	if err := validateDescriptor(desc); err != nil {
+		return false, err
+	}
+
+	repo, err := c.repository(repoRef)
+	if err != nil {
+		return false, err
+	}
+
+	exists, err := repo.Blobs().Exists(ctx, *desc)
+	if err != nil {
+		return false, mapError(err)
+	}
+	return exists, nil
+}
+
+// MountBlob makes a blob from fromRepo, another repository on the same
+// registry, available in repoRef without uploading it.
+//
+// If the registry does not support cross-repository mounts, the blob is
+// uploaded from getContent instead. fromRepo is a repository path without
+// the registry host.
+func (c *Client) MountBlob(ctx context.Context, repoRef, fromRepo string, desc *ocispec.Descriptor, getContent func() (io.ReadCloser, error)) error {
+	if err := validateDescriptor(desc); err != nil {
+		return err
+	}
+
+	repo, err := c.repository(repoRef)
+	if err != nil {
+		return err
+	}
+
+	if err := repo.Mount(ctx, *desc, fromRepo, getContent); err != nil {
+		return mapError(err)
+	}
+	return nil
+}
+
+// PushManifestRaw pushes manifest bytes exactly as given, preserving the
+// digest in desc. If tag is non-empty the manifest is also tagged.
+//
+// Unlike PushManifest, the manifest is not re-serialized, which makes this
+// suitable for copying manifests between repositories.
+func (c *Client) PushManifestRaw(ctx context.Context, repoRef, tag string, desc *ocispec.Descriptor, raw []byte) error {
+	if err := validateDescriptor(desc); err != nil {
+		return err
+	}
+	if int64(len(raw)) != desc.Size {
+		return fmt.Errorf("%w: expected %d bytes, got %d", ErrSizeMismatch, desc.Size, len(raw))
+	}
+	if computed := desc.Digest.Algorithm().FromBytes(raw); computed != desc.Digest {
+		return fmt.Errorf("%w: expected %s, got %s", ErrDigestMismatch, desc.Digest, computed)
+	}
+
+	repo, err := c.repository(repoRef)
+	if err != nil {
+		return err
+	}
+
+	if tag == "" {
+		err = repo.Push(ctx, *desc, bytes.NewReader(raw))
+	} else {
+		err = repo.PushReference(ctx, *desc, bytes.NewReader(raw), tag)
+	}
+	if err != nil {
+		return mapError(err)
+	}
+	return nil
+}
+
+// DeleteManifest deletes the manifest identified by desc.
+//
+// The manifest is always deleted by digest, which every registry that
+// allows deletes supports. Registries that reject deletes return
+// ErrDeleteUnsupported.
+func (c *Client) DeleteManifest(ctx context.Context, repoRef string, desc *ocispec.Descriptor) error {
+	if err := validateDescriptor(desc); err != nil {
+		return err
+	}
+
+	repo, err := c.repository(repoRef)
+	if err != nil {
+		return err
+	}
+
+	if err := repo.Manifests().Delete(ctx, *desc); err != nil {
+		var errResp *errcode.ErrorResponse
+		if errors.Is(err, errdef.ErrUnsupported) ||
+			(errors.As(err, &errResp) && errResp.StatusCode == http.StatusMethodNotAllowed) {
+			return fmt.Errorf("%w: %v", ErrDeleteUnsupported, err)
+		}
+		return mapError(err)
+	}
+	return nil
+}
+
 // Referrers lists referrer descriptors for the given subject manifest.
 //
 //nolint:gocritic // hugeParam: matches oras-go interface patterns
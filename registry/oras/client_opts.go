@@ -75,6 +75,17 @@ func WithAuthHeaderCacheTTL(ttl time.Duration) Option {
 	}
 }
 
+// WithUploadChunkSize sets the size of each PATCH request used by
+// PushBlobResumable (default: DefaultUploadChunkSize). Values <= 0 keep
+// the default.
+func WithUploadChunkSize(size int64) Option {
+	return func(c *Client) {
+		if size > 0 {
+			c.uploadChunkSize = size
+		}
+	}
+}
+
 // WithLogger sets a logger for the client.
 // If nil, a discard logger is used (default behavior).
 func WithLogger(logger *slog.Logger) Option {
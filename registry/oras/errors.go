@@ -30,4 +30,7 @@ var (
 
 	// ErrReferrersUnsupported is returned when the registry does not support referrers.
 	ErrReferrersUnsupported = errors.New("oci: referrers unsupported")
+
+	// ErrDeleteUnsupported is returned when the registry does not allow deletes.
+	ErrDeleteUnsupported = errors.New("oci: delete unsupported")
 )
@@ -0,0 +1,306 @@
+package oras
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+
+	ocispec "github.com/opencontainers/image-spec/specs-go/v1"
+	"oras.land/oras-go/v2/registry/remote/auth"
+	"oras.land/oras-go/v2/registry/remote/errcode"
+)
+
+// DefaultUploadChunkSize is the default size of each PATCH request in a
+// chunked blob upload (16 MB).
+const DefaultUploadChunkSize = 16 << 20
+
+// maxChunkRetries is the number of consecutive failed chunks tolerated
+// before a chunked upload gives up.
+const maxChunkRetries = 3
+
+var (
+	// errChunkedUnsupported reports a registry that rejects PATCH uploads.
+	errChunkedUnsupported = errors.New("chunked upload unsupported")
+
+	// errUploadExpired reports an upload session the registry no longer knows.
+	errUploadExpired = errors.New("upload session expired")
+)
+
+// uploadSessions remembers the location of unfinished chunked uploads so a
+// later push of the same blob can resume instead of starting over.
+type uploadSessions struct {
+	mu        sync.Mutex
+	locations map[string]*url.URL
+}
+
+func newUploadSessions() *uploadSessions {
+	return &uploadSessions{locations: make(map[string]*url.URL)}
+}
+
+func (s *uploadSessions) get(key string) (*url.URL, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	loc, ok := s.locations[key]
+	return loc, ok
+}
+
+func (s *uploadSessions) put(key string, loc *url.URL) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.locations[key] = loc
+}
+
+func (s *uploadSessions) delete(key string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.locations, key)
+}
+
+// PushBlobResumable pushes a blob with chunked (PATCH) uploads so that a
+// failure does not restart the transfer from zero.
+//
+// After a failed chunk, the registry is asked how many bytes it holds and
+// the upload continues from there. If the upload still fails, the session
+// is remembered and a later PushBlobResumable of the same blob on this
+// Client resumes it. Blobs no larger than the chunk size, and registries
+// that reject PATCH uploads, fall back to a monolithic PushBlob.
+func (c *Client) PushBlobResumable(ctx context.Context, repoRef string, desc *ocispec.Descriptor, content io.ReaderAt) error {
+	if err := validateDescriptor(desc); err != nil {
+		return err
+	}
+	if content == nil {
+		return fmt.Errorf("%w: content reader is nil", ErrInvalidDescriptor)
+	}
+	if desc.Size <= c.uploadChunkSize {
+		return c.PushBlob(ctx, repoRef, desc, io.NewSectionReader(content, 0, desc.Size))
+	}
+
+	ref, err := parseRef(repoRef)
+	if err != nil {
+		return err
+	}
+	// Pushing usually requires both pull and push actions.
+	ctx = auth.AppendRepositoryScope(ctx, ref, auth.ActionPull, auth.ActionPush)
+	key := ref.Registry + "/" + ref.Repository + "@" + desc.Digest.String()
+	u := &chunkedUpload{client: c, ctx: ctx, desc: desc, content: content}
+
+	var location *url.URL
+	var offset int64
+	if loc, ok := c.uploads.get(key); ok {
+		if loc, offset, err = u.status(loc); err == nil {
+			location = loc
+		} else {
+			c.uploads.delete(key)
+			offset = 0
+		}
+	}
+	if location == nil {
+		scheme := "https"
+		if c.plainHTTP {
+			scheme = "http"
+		}
+		location, err = u.start(fmt.Sprintf("%s://%s/v2/%s/blobs/uploads/", scheme, ref.Host(), ref.Repository))
+		if err != nil {
+			return mapError(err)
+		}
+		c.uploads.put(key, location)
+	}
+
+	failures := 0
+	for offset < desc.Size {
+		end := min(offset+c.uploadChunkSize, desc.Size)
+		next, err := u.patch(location, offset, end)
+		if err == nil {
+			location, offset, failures = next, end, 0
+			c.uploads.put(key, location)
+			continue
+		}
+		if errors.Is(err, errChunkedUnsupported) && offset == 0 {
+			c.uploads.delete(key)
+			return c.PushBlob(ctx, repoRef, desc, io.NewSectionReader(content, 0, desc.Size))
+		}
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		failures++
+		if failures > maxChunkRetries {
+			return mapError(err)
+		}
+		loc, off, statusErr := u.status(location)
+		if statusErr != nil {
+			if errors.Is(statusErr, errUploadExpired) {
+				c.uploads.delete(key)
+			}
+			return mapError(err)
+		}
+		location, offset = loc, off
+	}
+
+	err = u.complete(location)
+	c.uploads.delete(key)
+	if err != nil {
+		return mapError(err)
+	}
+	return nil
+}
+
+// chunkedUpload performs the requests of a single chunked blob upload.
+type chunkedUpload struct {
+	client  *Client
+	ctx     context.Context //nolint:containedctx // scoped to a single upload
+	desc    *ocispec.Descriptor
+	content io.ReaderAt
+}
+
+// start opens an upload session and returns its location.
+func (u *chunkedUpload) start(uploadURL string) (*url.URL, error) {
+	resp, err := u.do(http.MethodPost, uploadURL, nil, 0, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusAccepted {
+		return nil, errorResponse(resp)
+	}
+	return resp.Location()
+}
+
+// patch uploads content[offset:end] and returns the next upload location.
+func (u *chunkedUpload) patch(location *url.URL, offset, end int64) (*url.URL, error) {
+	length := end - offset
+	header := http.Header{
+		"Content-Type":  []string{"application/octet-stream"},
+		"Content-Range": []string{fmt.Sprintf("%d-%d", offset, end-1)},
+	}
+	body := func() (io.ReadCloser, error) {
+		return io.NopCloser(io.NewSectionReader(u.content, offset, length)), nil
+	}
+	resp, err := u.do(http.MethodPatch, location.String(), body, length, header)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	switch resp.StatusCode {
+	case http.StatusAccepted:
+		if resp.Header.Get("Location") == "" {
+			return location, nil
+		}
+		return resp.Location()
+	case http.StatusNotFound:
+		return nil, fmt.Errorf("%w: %w", errUploadExpired, errorResponse(resp))
+	case http.StatusMethodNotAllowed, http.StatusNotImplemented:
+		return nil, fmt.Errorf("%w: %w", errChunkedUnsupported, errorResponse(resp))
+	default:
+		return nil, errorResponse(resp)
+	}
+}
+
+// status asks the registry how many bytes of the upload it holds and
+// returns the location and offset to continue from.
+func (u *chunkedUpload) status(location *url.URL) (*url.URL, int64, error) {
+	resp, err := u.do(http.MethodGet, location.String(), nil, 0, nil)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer resp.Body.Close()
+	switch resp.StatusCode {
+	case http.StatusNoContent, http.StatusAccepted:
+	case http.StatusNotFound:
+		return nil, 0, fmt.Errorf("%w: %w", errUploadExpired, errorResponse(resp))
+	default:
+		return nil, 0, errorResponse(resp)
+	}
+
+	next := location
+	if resp.Header.Get("Location") != "" {
+		if next, err = resp.Location(); err != nil {
+			return nil, 0, err
+		}
+	}
+	offset, err := parseUploadRange(resp.Header.Get("Range"))
+	if err != nil {
+		return nil, 0, err
+	}
+	return next, offset, nil
+}
+
+// complete finishes the upload, letting the registry verify the digest.
+func (u *chunkedUpload) complete(location *url.URL) error {
+	final := *location
+	q := final.Query()
+	q.Set("digest", u.desc.Digest.String())
+	final.RawQuery = q.Encode()
+
+	resp, err := u.do(http.MethodPut, final.String(), nil, 0, http.Header{
+		"Content-Type": []string{"application/octet-stream"},
+	})
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusCreated {
+		return errorResponse(resp)
+	}
+	return nil
+}
+
+// do sends a request through the shared auth client. A non-nil body
+// function supplies (and re-supplies on auth retries) a body of length n.
+func (u *chunkedUpload) do(method, target string, body func() (io.ReadCloser, error), n int64, header http.Header) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(u.ctx, method, target, nil)
+	if err != nil {
+		return nil, err
+	}
+	for k, v := range header {
+		req.Header[k] = v
+	}
+	if body != nil {
+		if req.Body, err = body(); err != nil {
+			return nil, err
+		}
+		req.GetBody = body
+		req.ContentLength = n
+	}
+	return u.client.authClient.Do(req)
+}
+
+// parseUploadRange returns the offset following an upload status Range
+// header of the form "0-<last>". A missing header means no bytes are held.
+func parseUploadRange(value string) (int64, error) {
+	if value == "" {
+		return 0, nil
+	}
+	_, last, ok := strings.Cut(strings.TrimPrefix(value, "bytes="), "-")
+	if !ok {
+		return 0, fmt.Errorf("invalid upload range %q", value)
+	}
+	n, err := strconv.ParseInt(last, 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid upload range %q: %w", value, err)
+	}
+	return n + 1, nil
+}
+
+// errorResponse builds an errcode.ErrorResponse from a failed response so
+// that mapError can classify it.
+func errorResponse(resp *http.Response) error {
+	errResp := &errcode.ErrorResponse{
+		Method:     resp.Request.Method,
+		URL:        resp.Request.URL,
+		StatusCode: resp.StatusCode,
+	}
+	var body struct {
+		Errors errcode.Errors `json:"errors"`
+	}
+	if err := json.NewDecoder(io.LimitReader(resp.Body, 8<<10)).Decode(&body); err == nil {
+		errResp.Errors = body.Errors
+	}
+	return errResp
+}
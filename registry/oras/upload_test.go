@@ -0,0 +1,219 @@
+package oras
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/opencontainers/go-digest"
+	ocispec "github.com/opencontainers/image-spec/specs-go/v1"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// uploadRegistry is a minimal registry implementing the blob upload API.
+type uploadRegistry struct {
+	mu          sync.Mutex
+	sessions    map[string]*bytes.Buffer
+	blobs       map[digest.Digest][]byte
+	posts       int
+	patches     int
+	received    int64
+	failPatches map[int]bool // patch call numbers (1-based) that fail mid-body
+	rejectPatch bool
+}
+
+func newUploadRegistry(t *testing.T) (*uploadRegistry, string) {
+	t.Helper()
+	r := &uploadRegistry{
+		sessions:    make(map[string]*bytes.Buffer),
+		blobs:       make(map[digest.Digest][]byte),
+		failPatches: make(map[int]bool),
+	}
+	srv := httptest.NewServer(http.HandlerFunc(r.serve))
+	t.Cleanup(srv.Close)
+	return r, strings.TrimPrefix(srv.URL, "http://") + "/repo:latest"
+}
+
+func (r *uploadRegistry) serve(w http.ResponseWriter, req *http.Request) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	const prefix = "/v2/repo/blobs/uploads/"
+	if !strings.HasPrefix(req.URL.Path, prefix) {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+	id := strings.TrimPrefix(req.URL.Path, prefix)
+	if req.Method == http.MethodPost {
+		r.posts++
+		id = strconv.Itoa(r.posts)
+		r.sessions[id] = &bytes.Buffer{}
+		w.Header().Set("Location", prefix+id)
+		w.WriteHeader(http.StatusAccepted)
+		return
+	}
+	buf, ok := r.sessions[id]
+	if !ok {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+	setRange := func() {
+		w.Header().Set("Location", prefix+id)
+		w.Header().Set("Range", fmt.Sprintf("0-%d", buf.Len()-1))
+	}
+
+	switch req.Method {
+	case http.MethodPatch:
+		if r.rejectPatch {
+			w.WriteHeader(http.StatusMethodNotAllowed)
+			return
+		}
+		r.patches++
+		start, _, _ := strings.Cut(req.Header.Get("Content-Range"), "-")
+		if off, err := strconv.Atoi(start); err != nil || off != buf.Len() {
+			w.WriteHeader(http.StatusRequestedRangeNotSatisfiable)
+			return
+		}
+		body, _ := io.ReadAll(req.Body)
+		if r.failPatches[r.patches] {
+			// Keep half the chunk, as if the connection dropped mid-body.
+			body = body[:len(body)/2]
+			buf.Write(body)
+			r.received += int64(len(body))
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		buf.Write(body)
+		r.received += int64(len(body))
+		setRange()
+		w.WriteHeader(http.StatusAccepted)
+	case http.MethodGet:
+		setRange()
+		w.WriteHeader(http.StatusNoContent)
+	case http.MethodPut:
+		body, _ := io.ReadAll(req.Body)
+		buf.Write(body)
+		r.received += int64(len(body))
+		dgst := digest.Digest(req.URL.Query().Get("digest"))
+		if digest.FromBytes(buf.Bytes()) != dgst {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		r.blobs[dgst] = bytes.Clone(buf.Bytes())
+		delete(r.sessions, id)
+		w.WriteHeader(http.StatusCreated)
+	default:
+		w.WriteHeader(http.StatusMethodNotAllowed)
+	}
+}
+
+func TestClient_PushBlobResumable(t *testing.T) {
+	t.Parallel()
+
+	const chunkSize = 1024
+	content := bytes.Repeat([]byte("0123456789abcdef"), 10*chunkSize/16+7)
+	desc := &ocispec.Descriptor{
+		MediaType: "application/octet-stream",
+		Digest:    digest.FromBytes(content),
+		Size:      int64(len(content)),
+	}
+	newClient := func() *Client {
+		return New(WithPlainHTTP(true), WithAnonymous(), WithUploadChunkSize(chunkSize))
+	}
+
+	t.Run("uploads in chunks", func(t *testing.T) {
+		t.Parallel()
+
+		reg, ref := newUploadRegistry(t)
+		require.NoError(t, newClient().PushBlobResumable(context.Background(), ref, desc, bytes.NewReader(content)))
+
+		assert.Equal(t, content, reg.blobs[desc.Digest])
+		assert.Equal(t, 11, reg.patches)
+		assert.Equal(t, desc.Size, reg.received)
+	})
+
+	t.Run("resumes after a failed chunk", func(t *testing.T) {
+		t.Parallel()
+
+		reg, ref := newUploadRegistry(t)
+		reg.failPatches[4] = true
+		require.NoError(t, newClient().PushBlobResumable(context.Background(), ref, desc, bytes.NewReader(content)))
+
+		assert.Equal(t, content, reg.blobs[desc.Digest])
+		assert.Equal(t, desc.Size, reg.received, "no byte may be uploaded twice")
+		assert.Equal(t, 1, reg.posts)
+	})
+
+	t.Run("resumes a failed push on the next call", func(t *testing.T) {
+		t.Parallel()
+
+		reg, ref := newUploadRegistry(t)
+		for i := 3; i <= 3+maxChunkRetries; i++ {
+			reg.failPatches[i] = true
+		}
+		c := newClient()
+		require.Error(t, c.PushBlobResumable(context.Background(), ref, desc, bytes.NewReader(content)))
+		assert.Empty(t, reg.blobs)
+
+		require.NoError(t, c.PushBlobResumable(context.Background(), ref, desc, bytes.NewReader(content)))
+		assert.Equal(t, content, reg.blobs[desc.Digest])
+		assert.Equal(t, desc.Size, reg.received, "no byte may be uploaded twice")
+		assert.Equal(t, 1, reg.posts, "the upload session must be reused")
+	})
+
+	t.Run("falls back to monolithic upload", func(t *testing.T) {
+		t.Parallel()
+
+		reg, ref := newUploadRegistry(t)
+		reg.rejectPatch = true
+		require.NoError(t, newClient().PushBlobResumable(context.Background(), ref, desc, bytes.NewReader(content)))
+
+		assert.Equal(t, content, reg.blobs[desc.Digest])
+		assert.Zero(t, reg.patches)
+	})
+
+	t.Run("small blobs are pushed whole", func(t *testing.T) {
+		t.Parallel()
+
+		small := []byte("small")
+		smallDesc := &ocispec.Descriptor{MediaType: "application/octet-stream", Digest: digest.FromBytes(small), Size: int64(len(small))}
+		reg, ref := newUploadRegistry(t)
+		require.NoError(t, newClient().PushBlobResumable(context.Background(), ref, smallDesc, bytes.NewReader(small)))
+
+		assert.Equal(t, small, reg.blobs[smallDesc.Digest])
+		assert.Zero(t, reg.patches)
+	})
+}
+
+func TestParseUploadRange(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		value   string
+		want    int64
+		wantErr bool
+	}{
+		{value: "", want: 0},
+		{value: "0-1023", want: 1024},
+		{value: "bytes=0-9", want: 10},
+		{value: "garbage", wantErr: true},
+		{value: "0-x", wantErr: true},
+	}
+	for _, tt := range tests {
+		got, err := parseUploadRange(tt.value)
+		if tt.wantErr {
+			assert.Error(t, err, tt.value)
+			continue
+		}
+		require.NoError(t, err, tt.value)
+		assert.Equal(t, tt.want, got, tt.value)
+	}
+}
@@ -0,0 +1,80 @@
+package registry
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	ocispec "github.com/opencontainers/image-spec/specs-go/v1"
+
+	"github.com/meigma/blob/registry/oras"
+)
+
+var _ indexFetcher = (*oras.Client)(nil)
+
+// indexFetcher is an optional interface that OCIClient implementations can
+// provide to support pulling from multi-platform image indexes.
+type indexFetcher interface {
+	FetchIndex(ctx context.Context, repoRef string, expected *ocispec.Descriptor) (ocispec.Index, []byte, error)
+}
+
+// platformRef returns a digest reference to the manifest for os/arch when
+// ref resolves to an OCI image index. References that resolve to a single
+// manifest are returned unchanged.
+//
+// The tag is always resolved against the registry, because the ref cache
+// does not record whether a digest names an index.
+func (c *Client) platformRef(ctx context.Context, ref string, platform *ocispec.Platform) (string, error) {
+	parsedRef, err := parseClientRef(ref)
+	if err != nil {
+		return "", err
+	}
+	if parsedRef.reference == "" {
+		return "", fmt.Errorf("%w: reference must include a tag or digest", ErrInvalidReference)
+	}
+
+	desc, err := c.oci.Resolve(ctx, ref, parsedRef.reference)
+	if err != nil {
+		return "", mapOCIError(err)
+	}
+	if desc.MediaType != ocispec.MediaTypeImageIndex {
+		return ref, nil
+	}
+
+	fetcher, ok := c.oci.(indexFetcher)
+	if !ok {
+		return "", fmt.Errorf("%w: %s is an image index, which the OCI client cannot read", ErrInvalidManifest, ref)
+	}
+	index, _, err := fetcher.FetchIndex(ctx, ref, &desc)
+	if err != nil {
+		return "", fmt.Errorf("fetch image index: %w", mapOCIError(err))
+	}
+
+	match, err := selectPlatform(&index, platform)
+	if err != nil {
+		return "", err
+	}
+	c.log().Debug("selected platform manifest", "ref", ref, "platform", platformString(platform), "digest", match.Digest.String())
+	return parsedRef.registry + "/" + parsedRef.repository + "@" + match.Digest.String(), nil
+}
+
+// selectPlatform returns the first manifest in index built for platform.
+func selectPlatform(index *ocispec.Index, platform *ocispec.Platform) (ocispec.Descriptor, error) {
+	available := make([]string, 0, len(index.Manifests))
+	for _, desc := range index.Manifests {
+		if desc.Platform == nil {
+			continue
+		}
+		if desc.Platform.OS == platform.OS && desc.Platform.Architecture == platform.Architecture {
+			return desc, nil
+		}
+		available = append(available, platformString(desc.Platform))
+	}
+	return ocispec.Descriptor{}, fmt.Errorf("%w: no manifest for %s (available: %s)",
+		ErrPlatformNotFound, platformString(platform), strings.Join(available, ", "))
+}
+
+// platformString formats a platform as os/arch.
+func platformString(p *ocispec.Platform) string {
+	return p.OS + "/" + p.Architecture
+}
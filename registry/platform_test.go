@@ -0,0 +1,171 @@
+package registry
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"testing"
+
+	"github.com/opencontainers/go-digest"
+	specs "github.com/opencontainers/image-spec/specs-go"
+	ocispec "github.com/opencontainers/image-spec/specs-go/v1"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// platformMockOCIClient extends pullMockOCIClient with image index support.
+type platformMockOCIClient struct {
+	pullMockOCIClient
+	FetchIndexFunc func(ctx context.Context, repoRef string, expected *ocispec.Descriptor) (ocispec.Index, []byte, error)
+}
+
+func (m *platformMockOCIClient) FetchIndex(ctx context.Context, repoRef string, expected *ocispec.Descriptor) (ocispec.Index, []byte, error) {
+	if m.FetchIndexFunc != nil {
+		return m.FetchIndexFunc(ctx, repoRef, expected)
+	}
+	return ocispec.Index{}, nil, errNotImplemented
+}
+
+func TestClient_Pull_Platform(t *testing.T) {
+	t.Parallel()
+
+	const testRef = "registry.example.com/repo:v1"
+
+	indexData, dataBytes := createTestBlobData(t)
+	dataServer := startDataServer(t, dataBytes)
+
+	// Two per-platform archives that differ only in their annotations.
+	manifests := make(map[digest.Digest][]byte)
+	var imageIndex ocispec.Index
+	imageIndex.SchemaVersion = 2
+	imageIndex.MediaType = ocispec.MediaTypeImageIndex
+	for _, arch := range []string{"amd64", "arm64"} {
+		manifest, _, _ := manifestForIndexData(t, indexData, dataBytes)
+		manifest.Annotations["org.example.arch"] = arch
+		raw := mustMarshalManifest(t, manifest)
+		dgst := digest.FromBytes(raw)
+		manifests[dgst] = raw
+		imageIndex.Manifests = append(imageIndex.Manifests, ocispec.Descriptor{
+			MediaType: ocispec.MediaTypeImageManifest,
+			Digest:    dgst,
+			Size:      int64(len(raw)),
+			Platform:  &ocispec.Platform{OS: "linux", Architecture: arch},
+		})
+	}
+	rawIndex, err := json.Marshal(imageIndex)
+	require.NoError(t, err)
+	indexDesc := ocispec.Descriptor{
+		MediaType: ocispec.MediaTypeImageIndex,
+		Digest:    digest.FromBytes(rawIndex),
+		Size:      int64(len(rawIndex)),
+	}
+
+	newMock := func() *platformMockOCIClient {
+		mock := &platformMockOCIClient{}
+		mock.ResolveFunc = func(context.Context, string, string) (ocispec.Descriptor, error) {
+			return indexDesc, nil
+		}
+		mock.FetchIndexFunc = func(_ context.Context, _ string, expected *ocispec.Descriptor) (ocispec.Index, []byte, error) {
+			assert.Equal(t, indexDesc.Digest, expected.Digest)
+			return imageIndex, rawIndex, nil
+		}
+		mock.FetchManifestFunc = func(_ context.Context, _ string, expected *ocispec.Descriptor) (ocispec.Manifest, []byte, error) {
+			raw, ok := manifests[expected.Digest]
+			if !ok {
+				return ocispec.Manifest{}, nil, errNotImplemented
+			}
+			var m ocispec.Manifest
+			require.NoError(t, json.Unmarshal(raw, &m))
+			return m, raw, nil
+		}
+		mock.FetchBlobFunc = func(context.Context, string, *ocispec.Descriptor) (io.ReadCloser, error) {
+			return io.NopCloser(bytes.NewReader(indexData)), nil
+		}
+		mock.BlobURLFunc = func(string, string) (string, error) {
+			return dataServer.URL, nil
+		}
+		mock.AuthHeadersFunc = func(context.Context, string) (http.Header, error) {
+			return http.Header{}, nil
+		}
+		return mock
+	}
+
+	t.Run("selects matching platform", func(t *testing.T) {
+		t.Parallel()
+
+		for _, arch := range []string{"amd64", "arm64"} {
+			var got *BlobManifest
+			c := &Client{oci: newMock()}
+			b, err := c.Pull(context.Background(), testRef,
+				WithPullPlatform("linux", arch),
+				WithPullManifest(func(m *BlobManifest) { got = m }))
+			require.NoError(t, err, arch)
+			require.NotNil(t, got, arch)
+			assert.Equal(t, arch, got.Annotations()["org.example.arch"])
+
+			content, err := b.ReadFile("test.txt")
+			require.NoError(t, err, arch)
+			assert.Equal(t, "test content", string(content))
+		}
+	})
+
+	t.Run("no matching platform", func(t *testing.T) {
+		t.Parallel()
+
+		c := &Client{oci: newMock()}
+		_, err := c.Pull(context.Background(), testRef, WithPullPlatform("windows", "amd64"))
+		require.ErrorIs(t, err, ErrPlatformNotFound)
+		assert.Contains(t, err.Error(), "windows/amd64")
+		assert.Contains(t, err.Error(), "linux/amd64, linux/arm64")
+	})
+
+	t.Run("single manifest ignores platform", func(t *testing.T) {
+		t.Parallel()
+
+		mock := newMock()
+		manifestDesc := imageIndex.Manifests[0]
+		manifestDesc.Platform = nil
+		mock.ResolveFunc = func(context.Context, string, string) (ocispec.Descriptor, error) {
+			return manifestDesc, nil
+		}
+		mock.FetchIndexFunc = func(context.Context, string, *ocispec.Descriptor) (ocispec.Index, []byte, error) {
+			t.Error("FetchIndex called for a single manifest")
+			return ocispec.Index{}, nil, errNotImplemented
+		}
+
+		c := &Client{oci: mock}
+		_, err := c.Pull(context.Background(), testRef, WithPullPlatform("linux", "arm64"))
+		require.NoError(t, err)
+	})
+
+	t.Run("client without index support", func(t *testing.T) {
+		t.Parallel()
+
+		mock := newMock()
+		c := &Client{oci: &mock.pullMockOCIClient}
+		_, err := c.Pull(context.Background(), testRef, WithPullPlatform("linux", "arm64"))
+		require.ErrorIs(t, err, ErrInvalidManifest)
+	})
+}
+
+func TestSelectPlatform(t *testing.T) {
+	t.Parallel()
+
+	index := ocispec.Index{
+		Versioned: specs.Versioned{SchemaVersion: 2},
+		Manifests: []ocispec.Descriptor{
+			{Digest: digest.FromString("attestation")},
+			{Digest: digest.FromString("darwin"), Platform: &ocispec.Platform{OS: "darwin", Architecture: "arm64"}},
+			{Digest: digest.FromString("linux"), Platform: &ocispec.Platform{OS: "linux", Architecture: "arm64"}},
+		},
+	}
+
+	desc, err := selectPlatform(&index, &ocispec.Platform{OS: "linux", Architecture: "arm64"})
+	require.NoError(t, err)
+	assert.Equal(t, digest.FromString("linux"), desc.Digest)
+
+	_, err = selectPlatform(&index, &ocispec.Platform{OS: "linux", Architecture: "amd64"})
+	require.ErrorIs(t, err, ErrPlatformNotFound)
+}
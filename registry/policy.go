@@ -58,13 +58,15 @@ func (c *Client) evaluatePolicies(ctx context.Context, ref, digestStr string, ma
 		subject.Size = int64(len(raw))
 	}
 
-	req := PolicyRequest{
+	// All policies share one set of fetches, so attestations several of
+	// them inspect are fetched once.
+	req := ShareFetches(PolicyRequest{
 		Ref:      ref,
 		Digest:   digestStr,
 		Manifest: manifest,
 		Subject:  subject,
 		Client:   c,
-	}
+	})
 
 	for i, policy := range c.policies {
 		if err := policy.Evaluate(ctx, req); err != nil {
@@ -0,0 +1,126 @@
+package registry
+
+import (
+	"context"
+	"slices"
+	"sync"
+
+	ocispec "github.com/opencontainers/image-spec/specs-go/v1"
+)
+
+// ShareFetches returns req with its client wrapped so that every policy
+// evaluating it fetches each referrer listing and descriptor once. A
+// request whose client is already shared is returned unchanged, which keeps
+// one cache across nested compositions.
+//
+// The Client shares fetches between all of its policies; compositions such
+// as policy.RequireAll call ShareFetches for policies evaluated on their own.
+//
+//nolint:gocritic // req passed by value per Policy interface contract
+func ShareFetches(req PolicyRequest) PolicyRequest {
+	if req.Client == nil {
+		return req
+	}
+	if _, ok := req.Client.(*fetchCache); ok {
+		return req
+	}
+	req.Client = newFetchCache(req.Client)
+	return req
+}
+
+// fetchCache is a PolicyClient that memoizes the referrer listings
+// and descriptor contents fetched during one policy evaluation. Concurrent
+// requests for the same listing or descriptor share a single fetch.
+//
+// Results of fetches cut short by context cancellation are not kept, so a
+// later caller with a live context fetches again.
+type fetchCache struct {
+	client PolicyClient
+
+	mu          sync.Mutex
+	referrers   map[referrersKey]*fetchCall[[]ocispec.Descriptor]
+	descriptors map[descriptorKey]*fetchCall[[]byte]
+}
+
+// referrersKey identifies a referrer listing.
+type referrersKey struct {
+	ref          string
+	subject      string
+	artifactType string
+}
+
+// descriptorKey identifies descriptor content.
+type descriptorKey struct {
+	ref    string
+	digest string
+}
+
+// fetchCall is an in-flight or completed fetch. done is closed once val and
+// err are set.
+type fetchCall[T any] struct {
+	done chan struct{}
+	val  T
+	err  error
+}
+
+func newFetchCache(client PolicyClient) *fetchCache {
+	return &fetchCache{
+		client:      client,
+		referrers:   make(map[referrersKey]*fetchCall[[]ocispec.Descriptor]),
+		descriptors: make(map[descriptorKey]*fetchCall[[]byte]),
+	}
+}
+
+// Referrers implements PolicyClient.
+//
+//nolint:gocritic // subject passed by value per PolicyClient interface contract
+func (c *fetchCache) Referrers(ctx context.Context, ref string, subject ocispec.Descriptor, artifactType string) ([]ocispec.Descriptor, error) {
+	key := referrersKey{ref: ref, subject: subject.Digest.String(), artifactType: artifactType}
+	descs, err := fetchOnce(ctx, &c.mu, c.referrers, key, func() ([]ocispec.Descriptor, error) {
+		return c.client.Referrers(ctx, ref, subject, artifactType)
+	})
+	return slices.Clone(descs), err
+}
+
+// FetchDescriptor implements PolicyClient.
+//
+//nolint:gocritic // desc passed by value per PolicyClient interface contract
+func (c *fetchCache) FetchDescriptor(ctx context.Context, ref string, desc ocispec.Descriptor) ([]byte, error) {
+	key := descriptorKey{ref: ref, digest: desc.Digest.String()}
+	data, err := fetchOnce(ctx, &c.mu, c.descriptors, key, func() ([]byte, error) {
+		return c.client.FetchDescriptor(ctx, ref, desc)
+	})
+	return slices.Clone(data), err
+}
+
+// fetchOnce returns the result of fetch for key, calling fetch only if no
+// other caller has fetched or is fetching key. A result whose fetch was
+// interrupted by cancellation of its caller's context is dropped once
+// waiters have seen it.
+func fetchOnce[K comparable, T any](ctx context.Context, mu *sync.Mutex, calls map[K]*fetchCall[T], key K, fetch func() (T, error)) (T, error) {
+	mu.Lock()
+	if call, ok := calls[key]; ok {
+		mu.Unlock()
+		select {
+		case <-call.done:
+			return call.val, call.err
+		case <-ctx.Done():
+			var zero T
+			return zero, ctx.Err()
+		}
+	}
+	call := &fetchCall[T]{done: make(chan struct{})}
+	calls[key] = call
+	mu.Unlock()
+
+	call.val, call.err = fetch()
+	if call.err != nil && ctx.Err() != nil {
+		mu.Lock()
+		delete(calls, key)
+		mu.Unlock()
+	}
+	close(call.done)
+	return call.val, call.err
+}
+
+var _ PolicyClient = (*fetchCache)(nil)
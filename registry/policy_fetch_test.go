@@ -0,0 +1,74 @@
+package registry
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"sync/atomic"
+	"testing"
+
+	"github.com/opencontainers/go-digest"
+	ocispec "github.com/opencontainers/image-spec/specs-go/v1"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fetchDescriptorPolicy fetches desc through the request's client.
+//
+//nolint:gocritic // desc captured by value for the policy closure
+func fetchDescriptorPolicy(desc ocispec.Descriptor) Policy {
+	return PolicyFunc(func(ctx context.Context, req PolicyRequest) error {
+		_, err := req.Client.FetchDescriptor(ctx, req.Ref, desc)
+		return err
+	})
+}
+
+func TestClient_PoliciesShareFetches(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	attestation := []byte(`{"predicateType":"https://slsa.dev/provenance/v1"}`)
+	desc := ocispec.Descriptor{Digest: digest.FromBytes(attestation), Size: int64(len(attestation))}
+
+	var fetches atomic.Int32
+	mock := &pullMockOCIClient{
+		FetchBlobFunc: func(context.Context, string, *ocispec.Descriptor) (io.ReadCloser, error) {
+			fetches.Add(1)
+			return io.NopCloser(bytes.NewReader(attestation)), nil
+		},
+	}
+	c := New(WithOCIClient(mock), WithPolicies(
+		fetchDescriptorPolicy(desc),
+		fetchDescriptorPolicy(desc),
+		fetchDescriptorPolicy(desc),
+	))
+
+	dgst := digest.FromString("manifest").String()
+	require.NoError(t, c.evaluatePolicies(ctx, "example.com/repo:v1", dgst, &BlobManifest{}, nil))
+	assert.Equal(t, int32(1), fetches.Load(), "policies registered on the client share fetches")
+
+	require.NoError(t, c.evaluatePolicies(ctx, "example.com/repo:v1", dgst, &BlobManifest{}, nil))
+	assert.Equal(t, int32(2), fetches.Load(), "each evaluation fetches again")
+}
+
+func TestFetchCache_Cancellation(t *testing.T) {
+	t.Parallel()
+
+	cache := newFetchCache(New())
+
+	cancelled, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	_, err := fetchOnce(cancelled, &cache.mu, cache.descriptors, descriptorKey{digest: "sha256:x"}, func() ([]byte, error) {
+		return nil, cancelled.Err()
+	})
+	require.ErrorIs(t, err, context.Canceled)
+	assert.Empty(t, cache.descriptors, "cancelled fetches are not kept")
+
+	data, err := fetchOnce(context.Background(), &cache.mu, cache.descriptors, descriptorKey{digest: "sha256:x"}, func() ([]byte, error) {
+		return []byte("content"), nil
+	})
+	require.NoError(t, err)
+	assert.Equal(t, []byte("content"), data)
+	assert.Len(t, cache.descriptors, 1)
+}
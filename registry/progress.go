@@ -0,0 +1,177 @@
+package registry
+
+import (
+	"context"
+	"io"
+	"sync"
+
+	blob "github.com/meigma/blob/core"
+)
+
+// progressCounter reports byte-level progress for one stage. Reported
+// BytesDone values never decrease.
+type progressCounter struct {
+	fn    blob.ProgressFunc
+	stage blob.ProgressStage
+	total uint64
+
+	mu   sync.Mutex
+	done uint64
+}
+
+// newProgressCounter returns a counter for stage, or nil if fn is nil.
+// All methods are no-ops on a nil counter.
+func newProgressCounter(fn blob.ProgressFunc, stage blob.ProgressStage, total uint64) *progressCounter {
+	if fn == nil {
+		return nil
+	}
+	return &progressCounter{fn: fn, stage: stage, total: total}
+}
+
+// advanceTo reports n bytes done if n is past the last reported value.
+func (p *progressCounter) advanceTo(n uint64) {
+	if p == nil {
+		return
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if n <= p.done {
+		return
+	}
+	p.done = n
+	p.fn(blob.ProgressEvent{Stage: p.stage, BytesDone: n, BytesTotal: p.total})
+}
+
+// add reports n more bytes done.
+func (p *progressCounter) add(n uint64) {
+	if p == nil || n == 0 {
+		return
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.done += n
+	p.fn(blob.ProgressEvent{Stage: p.stage, BytesDone: p.done, BytesTotal: p.total})
+}
+
+// progressReader reports bytes as they are read from r.
+type progressReader struct {
+	r        io.Reader
+	progress *progressCounter
+}
+
+func (r *progressReader) Read(p []byte) (int, error) {
+	n, err := r.r.Read(p)
+	r.progress.add(uint64(n)) //nolint:gosec // n is never negative
+	return n, err
+}
+
+// progressReaderAt reports the furthest offset read from ra, so re-reads
+// after a resumed upload do not count twice.
+type progressReaderAt struct {
+	ra       io.ReaderAt
+	progress *progressCounter
+}
+
+func (r *progressReaderAt) ReadAt(p []byte, off int64) (int, error) {
+	n, err := r.ra.ReadAt(p, off)
+	r.progress.advanceTo(uint64(off) + uint64(n)) //nolint:gosec // offsets and counts are never negative
+	return n, err
+}
+
+// contextReaderAt is implemented by sources whose reads can be canceled.
+type contextReaderAt interface {
+	ReadAtContext(ctx context.Context, p []byte, off int64) (int, error)
+}
+
+// rangeReader is implemented by sources that support range streams.
+type rangeReader interface {
+	ReadRange(off, length int64) (io.ReadCloser, error)
+}
+
+// contextRangeReader is implemented by range streams that can be canceled.
+type contextRangeReader interface {
+	ReadRangeContext(ctx context.Context, off, length int64) (io.ReadCloser, error)
+}
+
+// newProgressSource wraps inner so every byte it returns is reported to
+// progress. The returned source supports range streams only when inner does.
+func newProgressSource(inner blob.ByteSource, progress *progressCounter) blob.ByteSource {
+	src := &progressSource{inner: inner, progress: progress}
+	if _, ok := inner.(rangeReader); ok {
+		return &progressRangeSource{progressSource: src}
+	}
+	return src
+}
+
+// progressSource reports the bytes fetched from a lazily read data source.
+type progressSource struct {
+	inner    blob.ByteSource
+	progress *progressCounter
+}
+
+func (s *progressSource) Size() int64 {
+	return s.inner.Size()
+}
+
+func (s *progressSource) SourceID() string {
+	return s.inner.SourceID()
+}
+
+func (s *progressSource) ReadAt(p []byte, off int64) (int, error) {
+	n, err := s.inner.ReadAt(p, off)
+	s.progress.add(uint64(n)) //nolint:gosec // n is never negative
+	return n, err
+}
+
+// ReadAtContext forwards to the inner source's ReadAtContext when it has one.
+func (s *progressSource) ReadAtContext(ctx context.Context, p []byte, off int64) (int, error) {
+	var n int
+	var err error
+	if cr, ok := s.inner.(contextReaderAt); ok {
+		n, err = cr.ReadAtContext(ctx, p, off)
+	} else {
+		if err := ctx.Err(); err != nil {
+			return 0, err
+		}
+		n, err = s.inner.ReadAt(p, off)
+	}
+	s.progress.add(uint64(n)) //nolint:gosec // n is never negative
+	return n, err
+}
+
+// progressRangeSource adds reported range streams to progressSource.
+type progressRangeSource struct {
+	*progressSource
+}
+
+// ReadRange returns a reader for [off, off+length) that reports bytes as
+// they are streamed.
+func (s *progressRangeSource) ReadRange(off, length int64) (io.ReadCloser, error) {
+	return s.ReadRangeContext(context.Background(), off, length)
+}
+
+// ReadRangeContext is like ReadRange but binds the stream to ctx when the
+// inner source supports it.
+func (s *progressRangeSource) ReadRangeContext(ctx context.Context, off, length int64) (io.ReadCloser, error) {
+	var rc io.ReadCloser
+	var err error
+	if cr, ok := s.inner.(contextRangeReader); ok {
+		rc, err = cr.ReadRangeContext(ctx, off, length)
+	} else {
+		rc, err = s.inner.(rangeReader).ReadRange(off, length)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &progressReadCloser{progressReader: progressReader{r: rc, progress: s.progress}, c: rc}, nil
+}
+
+// progressReadCloser is a progressReader that closes the underlying stream.
+type progressReadCloser struct {
+	progressReader
+	c io.Closer
+}
+
+func (r *progressReadCloser) Close() error {
+	return r.c.Close()
+}
@@ -0,0 +1,204 @@
+package registry
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+
+	ocispec "github.com/opencontainers/image-spec/specs-go/v1"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	blob "github.com/meigma/blob/core"
+)
+
+// progressRecorder collects progress events for later inspection.
+type progressRecorder struct {
+	mu     sync.Mutex
+	events []blob.ProgressEvent
+}
+
+func (r *progressRecorder) record(ev blob.ProgressEvent) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.events = append(r.events, ev)
+}
+
+// stages returns the distinct stages in the order they were first seen.
+func (r *progressRecorder) stages() []blob.ProgressStage {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	var out []blob.ProgressStage
+	for _, ev := range r.events {
+		if len(out) == 0 || out[len(out)-1] != ev.Stage {
+			out = append(out, ev.Stage)
+		}
+	}
+	return out
+}
+
+// stageEvents returns the events reported for stage.
+func (r *progressRecorder) stageEvents(stage blob.ProgressStage) []blob.ProgressEvent {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	var out []blob.ProgressEvent
+	for _, ev := range r.events {
+		if ev.Stage == stage {
+			out = append(out, ev)
+		}
+	}
+	return out
+}
+
+func assertMonotonic(t *testing.T, events []blob.ProgressEvent) {
+	t.Helper()
+	for i := 1; i < len(events); i++ {
+		assert.GreaterOrEqual(t, events[i].BytesDone, events[i-1].BytesDone, "event %d went backwards", i)
+	}
+}
+
+// createLargeTestBlob creates an archive whose data blob spans many reads.
+func createLargeTestBlob(t *testing.T) *blob.Blob {
+	t.Helper()
+
+	dir := t.TempDir()
+	content := make([]byte, 256<<10)
+	_, err := rand.Read(content)
+	require.NoError(t, err)
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "large.bin"), content, 0o644))
+
+	blobFile, err := blob.CreateBlob(context.Background(), dir, t.TempDir())
+	require.NoError(t, err)
+	t.Cleanup(func() { blobFile.Close() })
+	return blobFile.Blob
+}
+
+func TestClient_Push_ReportsByteProgress(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	b := createLargeTestBlob(t)
+
+	t.Run("upload", func(t *testing.T) {
+		t.Parallel()
+
+		rec := &progressRecorder{}
+		c := &Client{oci: newMemRegistry()}
+		require.NoError(t, c.Push(ctx, "registry.example.com/repo:v1", b, WithProgress(rec.record)))
+
+		assert.Equal(t, []blob.ProgressStage{blob.StagePushingIndex, blob.StagePushingData}, rec.stages())
+
+		index := rec.stageEvents(blob.StagePushingIndex)
+		assertMonotonic(t, index)
+		assert.Equal(t, uint64(len(b.IndexData())), index[len(index)-1].BytesDone)
+
+		data := rec.stageEvents(blob.StagePushingData)
+		assertMonotonic(t, data)
+		assert.Greater(t, len(data), 2, "data upload should report intermediate progress")
+		total := uint64(b.Size()) //nolint:gosec // size is non-negative
+		for _, ev := range data {
+			assert.Equal(t, total, ev.BytesTotal)
+			assert.LessOrEqual(t, ev.BytesDone, total)
+		}
+		assert.Equal(t, total, data[len(data)-1].BytesDone)
+	})
+
+	t.Run("skipped blobs complete", func(t *testing.T) {
+		t.Parallel()
+
+		m := newMemRegistry()
+		c := &Client{oci: m}
+		require.NoError(t, c.Push(ctx, "registry.example.com/repo:v1", b))
+
+		rec := &progressRecorder{}
+		require.NoError(t, c.Push(ctx, "registry.example.com/repo:v2", b, WithProgress(rec.record)))
+
+		data := rec.stageEvents(blob.StagePushingData)
+		require.Len(t, data, 2, "start and completion only")
+		assert.Equal(t, uint64(b.Size()), data[1].BytesDone) //nolint:gosec // size is non-negative
+	})
+}
+
+func TestClient_Pull_ReportsByteProgress(t *testing.T) {
+	t.Parallel()
+
+	indexData, dataBytes := createTestBlobData(t)
+	dataServer := startDataServer(t, dataBytes)
+	manifest, manifestBytes, manifestDesc := manifestForIndexData(t, indexData, dataBytes)
+
+	mock := &pullMockOCIClient{}
+	mock.ResolveFunc = func(context.Context, string, string) (ocispec.Descriptor, error) {
+		return manifestDesc, nil
+	}
+	mock.FetchManifestFunc = func(context.Context, string, *ocispec.Descriptor) (ocispec.Manifest, []byte, error) {
+		return manifest, manifestBytes, nil
+	}
+	mock.FetchBlobFunc = func(context.Context, string, *ocispec.Descriptor) (io.ReadCloser, error) {
+		return io.NopCloser(bytes.NewReader(indexData)), nil
+	}
+	mock.BlobURLFunc = func(string, string) (string, error) {
+		return dataServer.URL, nil
+	}
+	mock.AuthHeadersFunc = func(context.Context, string) (http.Header, error) {
+		return http.Header{}, nil
+	}
+
+	rec := &progressRecorder{}
+	c := &Client{oci: mock}
+	b, err := c.Pull(context.Background(), "registry.example.com/repo:v1", WithPullProgress(rec.record))
+	require.NoError(t, err)
+	assert.Empty(t, rec.stageEvents(blob.StageFetchingData), "data is fetched lazily")
+
+	content, err := b.ReadFile("test.txt")
+	require.NoError(t, err)
+	assert.Equal(t, "test content", string(content))
+
+	assert.Equal(t, []blob.ProgressStage{
+		blob.StageFetchingManifest,
+		blob.StageFetchingIndex,
+		blob.StageFetchingData,
+	}, rec.stages())
+
+	index := rec.stageEvents(blob.StageFetchingIndex)
+	assertMonotonic(t, index)
+	assert.Equal(t, uint64(len(indexData)), index[len(index)-1].BytesDone)
+
+	data := rec.stageEvents(blob.StageFetchingData)
+	assertMonotonic(t, data)
+	for _, ev := range data {
+		assert.Equal(t, uint64(len(dataBytes)), ev.BytesTotal)
+	}
+	assert.Positive(t, data[len(data)-1].BytesDone)
+}
+
+func TestProgressCounter(t *testing.T) {
+	t.Parallel()
+
+	t.Run("nil callback", func(t *testing.T) {
+		t.Parallel()
+		p := newProgressCounter(nil, blob.StageFetchingData, 10)
+		assert.Nil(t, p)
+		p.add(5)
+		p.advanceTo(10)
+	})
+
+	t.Run("advanceTo ignores rewinds", func(t *testing.T) {
+		t.Parallel()
+		rec := &progressRecorder{}
+		p := newProgressCounter(rec.record, blob.StagePushingData, 10)
+		p.advanceTo(6)
+		p.advanceTo(3)
+		p.advanceTo(6)
+		p.advanceTo(10)
+		data := rec.stageEvents(blob.StagePushingData)
+		require.Len(t, data, 2)
+		assert.Equal(t, uint64(6), data[0].BytesDone)
+		assert.Equal(t, uint64(10), data[1].BytesDone)
+	})
+}
@@ -21,7 +21,8 @@ type authClientProvider interface {
 // Pull retrieves a blob archive from an OCI registry.
 //
 // The returned Blob is lazy: file data is fetched on demand via HTTP range
-// requests. The index blob is downloaded immediately as it is small.
+// requests. The index blob, and the zstd dictionary blob of archives that
+// have one, are downloaded immediately as they are small.
 //
 // The caller should close the Blob when done if it wraps file resources.
 func (c *Client) Pull(ctx context.Context, ref string, opts ...PullOption) (*blob.Blob, error) {
@@ -34,6 +35,15 @@ func (c *Client) Pull(ctx context.Context, ref string, opts ...PullOption) (*blo
 
 	c.log().Info("pulling archive", "ref", ref)
 
+	// Step 0: Select the platform manifest when ref names an image index
+	if cfg.platform != nil {
+		platformRef, err := c.platformRef(ctx, ref, cfg.platform)
+		if err != nil {
+			return nil, err
+		}
+		ref = platformRef
+	}
+
 	// Step 1: Fetch manifest (handles caching internally)
 	reportPullProgress(cfg.progress, blob.StageFetchingManifest, 0, 0)
 	var fetchOpts []FetchOption
@@ -49,11 +59,22 @@ func (c *Client) Pull(ctx context.Context, ref string, opts ...PullOption) (*blo
 	// Step 2: Fetch index blob (small, download fully)
 	indexDesc := manifest.IndexDescriptor()
 	reportPullProgress(cfg.progress, blob.StageFetchingIndex, 0, sizeToUint64(indexDesc.Size))
-	indexData, err := c.fetchIndexBlob(ctx, ref, manifest, &cfg)
+	indexProgress := newProgressCounter(cfg.progress, blob.StageFetchingIndex, sizeToUint64(indexDesc.Size))
+	indexData, fromCache, err := c.fetchIndexBlob(ctx, ref, manifest, &cfg, indexProgress)
 	if err != nil {
 		return nil, err
 	}
-	reportPullProgress(cfg.progress, blob.StageFetchingIndex, uint64(len(indexData)), uint64(len(indexData)))
+	indexProgress.advanceTo(uint64(len(indexData)))
+
+	// Step 2b: Fetch the zstd dictionary blob, if the archive has one
+	blobOpts := []blob.Option{blob.WithIndexFromCache(fromCache)}
+	if dictDesc, ok := manifest.ZstdDictionaryDescriptor(); ok {
+		dict, dictErr := c.fetchZstdDictionary(ctx, ref, &dictDesc, &cfg)
+		if dictErr != nil {
+			return nil, dictErr
+		}
+		blobOpts = append(blobOpts, blob.WithZstdDictionary(dict))
+	}
 
 	// Step 3: Create HTTP source for lazy data access
 	source, err := c.createDataSource(ctx, ref, manifest)
@@ -62,10 +83,19 @@ func (c *Client) Pull(ctx context.Context, ref string, opts ...PullOption) (*blo
 	}
 	c.log().Debug("created data source", "url", source.SourceID())
 
-	// Step 4: Wrap source with block cache if configured
+	// Step 4: Wrap source with progress, retries and block cache if configured.
+	// Progress wraps the HTTP source directly so only bytes actually
+	// transferred are reported.
 	var dataSource blob.ByteSource = source
+	if cfg.progress != nil {
+		dataSource = newProgressSource(dataSource, newProgressCounter(cfg.progress, blob.StageFetchingData, sizeToUint64(source.Size())))
+	}
+	if cfg.retry {
+		dataSource = blob.RetryingSource(dataSource, cfg.retryOpts...)
+		c.log().Debug("wrapped data source with retries")
+	}
 	if cfg.blockCache != nil {
-		wrapped, wrapErr := cfg.blockCache.Wrap(source)
+		wrapped, wrapErr := cfg.blockCache.Wrap(dataSource)
 		if wrapErr != nil {
 			return nil, fmt.Errorf("wrap data source with block cache: %w", wrapErr)
 		}
@@ -74,48 +104,84 @@ func (c *Client) Pull(ctx context.Context, ref string, opts ...PullOption) (*blo
 	}
 
 	// Step 5: Create Blob with index data and lazy data source
-	return blob.New(indexData, dataSource, cfg.blobOpts...)
+	blobOpts = append(blobOpts, cfg.blobOpts...)
+	b, err := blob.New(indexData, dataSource, blobOpts...)
+	if err != nil {
+		return nil, err
+	}
+	if cfg.manifestFn != nil {
+		cfg.manifestFn(manifest)
+	}
+	return b, nil
 }
 
 // fetchIndexBlob fetches the index blob, using cache if available.
-func (c *Client) fetchIndexBlob(ctx context.Context, ref string, manifest *BlobManifest, cfg *pullConfig) ([]byte, error) {
+// fromCache reports whether the index was served from the index cache.
+// Bytes read from the registry are reported to progress.
+func (c *Client) fetchIndexBlob(ctx context.Context, ref string, manifest *BlobManifest, cfg *pullConfig, progress *progressCounter) ([]byte, bool, error) {
 	indexDesc := manifest.IndexDescriptor()
 	indexDigest := indexDesc.Digest.String()
 
 	if cfg.maxIndexSize > 0 && indexDesc.Size > cfg.maxIndexSize {
-		return nil, fmt.Errorf("read index blob: index blob too large: %d > %d", indexDesc.Size, cfg.maxIndexSize)
+		return nil, false, fmt.Errorf("read index blob: index blob too large: %d > %d", indexDesc.Size, cfg.maxIndexSize)
 	}
 
 	// Try cache first
 	if indexData, ok := c.tryIndexCache(indexDigest, &indexDesc, cfg); ok {
-		return indexData, nil
+		return indexData, true, nil
 	}
 
 	// Fetch from registry
 	indexReader, err := c.oci.FetchBlob(ctx, ref, &indexDesc)
 	if err != nil {
-		return nil, fmt.Errorf("fetch index blob: %w", mapOCIError(err))
+		return nil, false, fmt.Errorf("fetch index blob: %w", mapOCIError(err))
 	}
 	defer indexReader.Close()
 
-	indexData, err := readIndexData(indexReader, indexDesc.Size, cfg.maxIndexSize)
+	var r io.Reader = indexReader
+	if progress != nil {
+		r = &progressReader{r: indexReader, progress: progress}
+	}
+	indexData, err := readIndexData(r, indexDesc.Size, cfg.maxIndexSize)
 	if err != nil {
-		return nil, fmt.Errorf("read index blob: %w", err)
+		return nil, false, fmt.Errorf("read index blob: %w", err)
 	}
 
 	// Verify digest
 	if err := c.verifyIndexDigest(indexData, &indexDesc); err != nil {
-		return nil, err
+		return nil, false, err
 	}
 
 	// Store in cache
 	if c.indexCache != nil {
 		if err := c.indexCache.PutIndex(indexDigest, indexData); err != nil {
-			return nil, fmt.Errorf("cache index: %w", err)
+			return nil, false, fmt.Errorf("cache index: %w", err)
 		}
 	}
 
-	return indexData, nil
+	return indexData, false, nil
+}
+
+// fetchZstdDictionary fetches and verifies the zstd dictionary blob. Like the
+// index, it is small and bounded by WithMaxIndexSize.
+func (c *Client) fetchZstdDictionary(ctx context.Context, ref string, desc *ocispec.Descriptor, cfg *pullConfig) ([]byte, error) {
+	rc, err := c.oci.FetchBlob(ctx, ref, desc)
+	if err != nil {
+		return nil, fmt.Errorf("fetch zstd dictionary blob: %w", mapOCIError(err))
+	}
+	defer rc.Close()
+
+	dict, err := readIndexData(rc, desc.Size, cfg.maxIndexSize)
+	if err != nil {
+		return nil, fmt.Errorf("read zstd dictionary blob: %w", err)
+	}
+	if err := desc.Digest.Validate(); err != nil {
+		return nil, fmt.Errorf("read zstd dictionary blob: %w: invalid digest %q: %v", ErrInvalidManifest, desc.Digest, err)
+	}
+	if computed := desc.Digest.Algorithm().FromBytes(dict); computed != desc.Digest {
+		return nil, fmt.Errorf("read zstd dictionary blob: %w: expected %s, got %s", ErrDigestMismatch, desc.Digest, computed)
+	}
+	return dict, nil
 }
 
 // tryIndexCache attempts to get the index from cache, returning (data, true) on hit.
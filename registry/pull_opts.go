@@ -1,6 +1,8 @@
 package registry
 
 import (
+	ocispec "github.com/opencontainers/image-spec/specs-go/v1"
+
 	blob "github.com/meigma/blob/core"
 	"github.com/meigma/blob/core/cache"
 )
@@ -16,6 +18,10 @@ type pullConfig struct {
 	maxIndexSize int64
 	progress     blob.ProgressFunc
 	blockCache   cache.BlockCache
+	retryOpts    []blob.RetryOption
+	retry        bool
+	manifestFn   func(*BlobManifest)
+	platform     *ocispec.Platform
 }
 
 const defaultMaxIndexSize = 8 << 20 // 8 MiB
@@ -31,6 +37,7 @@ func WithBlobOptions(opts ...blob.Option) PullOption {
 }
 
 // WithMaxIndexSize sets the maximum number of bytes allowed for the index blob.
+// The same limit applies to the zstd dictionary blob.
 //
 // Use a value <= 0 to disable the limit.
 func WithMaxIndexSize(maxBytes int64) PullOption {
@@ -49,7 +56,11 @@ func WithPullSkipCache() PullOption {
 }
 
 // WithPullProgress sets a callback to receive progress updates during pull.
-// The callback receives events for manifest and index fetching.
+// The callback receives events for manifest and index fetching, then a
+// StageFetchingData event each time data blob bytes arrive from the
+// registry. Data reads happen lazily after Pull returns, so BytesDone counts
+// the bytes fetched so far and can exceed BytesTotal if ranges are fetched
+// more than once.
 // The callback may be invoked concurrently and must be safe for concurrent use.
 func WithPullProgress(fn blob.ProgressFunc) PullOption {
 	return func(cfg *pullConfig) {
@@ -57,6 +68,27 @@ func WithPullProgress(fn blob.ProgressFunc) PullOption {
 	}
 }
 
+// WithPullManifest sets a callback that receives the manifest of the pulled
+// archive once Pull succeeds. Use it to read annotations or the manifest
+// digest without a second fetch. The manifest may come from the manifest
+// cache, whose raw bytes round-trip the annotations unchanged.
+func WithPullManifest(fn func(*BlobManifest)) PullOption {
+	return func(cfg *pullConfig) {
+		cfg.manifestFn = fn
+	}
+}
+
+// WithPullPlatform selects the archive built for os and arch when the
+// reference resolves to an OCI image index, such as a tag that groups
+// per-platform archives. Pull fails with ErrPlatformNotFound if the index
+// lists no manifest for the platform. References that resolve to a single
+// archive manifest are pulled as usual.
+func WithPullPlatform(os, arch string) PullOption {
+	return func(cfg *pullConfig) {
+		cfg.platform = &ocispec.Platform{OS: os, Architecture: arch}
+	}
+}
+
 // WithBlockCache sets a block cache to wrap the HTTP data source.
 // This caches HTTP range request blocks for improved performance on
 // random access patterns.
@@ -65,3 +97,14 @@ func WithBlockCache(bc cache.BlockCache) PullOption {
 		cfg.blockCache = bc
 	}
 }
+
+// WithPullRetry wraps the data source with blob.RetryingSource so range
+// requests that fail with a transient error are retried. Use
+// blob.RetryWithBudget to cap attempts across every read of one operation
+// on the pulled archive, such as a CopyDir.
+func WithPullRetry(opts ...blob.RetryOption) PullOption {
+	return func(cfg *pullConfig) {
+		cfg.retry = true
+		cfg.retryOpts = append(cfg.retryOpts, opts...)
+	}
+}
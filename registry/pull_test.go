@@ -10,7 +10,9 @@ import (
 	"net/http/httptest"
 	"os"
 	"strconv"
+	"sync/atomic"
 	"testing"
+	"time"
 
 	"github.com/opencontainers/go-digest"
 	ocispec "github.com/opencontainers/image-spec/specs-go/v1"
@@ -18,6 +20,7 @@ import (
 	"github.com/stretchr/testify/require"
 
 	blob "github.com/meigma/blob/core"
+	"github.com/meigma/blob/registry/cache/disk"
 )
 
 // pullMockOCIClient extends mockOCIClient with Pull-specific methods
@@ -193,6 +196,53 @@ func TestClient_Pull(t *testing.T) {
 		assert.Equal(t, "test content", string(content))
 	})
 
+	t.Run("retry budget bounds data requests", func(t *testing.T) {
+		t.Parallel()
+
+		indexData, dataBytes := createTestBlobData(t)
+		// Serve the size probes made when the source is created, then fail.
+		healthy := startDataServer(t, dataBytes)
+		var requests atomic.Int32
+		dataServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if requests.Add(1) <= 2 {
+				healthy.Config.Handler.ServeHTTP(w, r)
+				return
+			}
+			http.Error(w, "unavailable", http.StatusServiceUnavailable)
+		}))
+		t.Cleanup(dataServer.Close)
+		testManifest, testManifestBytes, testManifestDesc := manifestForIndexData(t, indexData, dataBytes)
+
+		mock := &pullMockOCIClient{}
+		mock.ResolveFunc = func(ctx context.Context, repoRef, ref string) (ocispec.Descriptor, error) {
+			return testManifestDesc, nil
+		}
+		mock.FetchManifestFunc = func(ctx context.Context, repoRef string, expected *ocispec.Descriptor) (ocispec.Manifest, []byte, error) {
+			return testManifest, testManifestBytes, nil
+		}
+		mock.FetchBlobFunc = func(ctx context.Context, repoRef string, desc *ocispec.Descriptor) (io.ReadCloser, error) {
+			return io.NopCloser(bytes.NewReader(indexData)), nil
+		}
+		mock.BlobURLFunc = func(repoRef, dgst string) (string, error) {
+			return dataServer.URL, nil
+		}
+		mock.AuthHeadersFunc = func(ctx context.Context, repoRef string) (http.Header, error) {
+			return http.Header{}, nil
+		}
+
+		c := &Client{oci: mock}
+		b, err := c.Pull(context.Background(), testRef, WithPullRetry(
+			blob.RetryWithBackoff(time.Millisecond, time.Millisecond),
+			blob.RetryWithMaxAttempts(10),
+			blob.RetryWithBudget(3, 0),
+		))
+		require.NoError(t, err)
+
+		_, err = b.ReadFile("test.txt")
+		require.ErrorIs(t, err, blob.ErrRetryBudgetExhausted)
+		assert.Equal(t, int32(2+3), requests.Load(), "probes and the three budgeted attempts")
+	})
+
 	t.Run("index cache hit skips fetch blob", func(t *testing.T) {
 		t.Parallel()
 
@@ -276,6 +326,11 @@ func TestClient_Pull(t *testing.T) {
 		cached, ok := indexCache.GetIndex(indexDigest)
 		require.True(t, ok)
 		assert.Equal(t, indexData, cached)
+		assert.False(t, b.IndexFromCache(), "cold pull should fetch the index")
+
+		warm, err := c.Pull(context.Background(), testRef)
+		require.NoError(t, err)
+		assert.True(t, warm.IndexFromCache(), "warm pull should use the cached index")
 	})
 
 	t.Run("index size limit enforced", func(t *testing.T) {
@@ -557,3 +612,59 @@ func TestWithMaxIndexSize(t *testing.T) {
 
 	assert.Equal(t, int64(123), cfg.maxIndexSize)
 }
+
+func TestClient_Pull_ReportsManifest(t *testing.T) {
+	t.Parallel()
+
+	indexData, dataBytes := createTestBlobData(t)
+	dataServer := startDataServer(t, dataBytes)
+
+	manifest := testManifest()
+	manifest.Layers[0].Digest = digest.FromBytes(indexData)
+	manifest.Layers[0].Size = int64(len(indexData))
+	manifest.Layers[1].Digest = digest.FromBytes(dataBytes)
+	manifest.Layers[1].Size = int64(len(dataBytes))
+	manifest.Annotations["org.opencontainers.image.revision"] = "0123abcd"
+	manifest.Annotations["org.opencontainers.image.source"] = "https://ci.example.com/build/42"
+	manifestBytes := mustMarshalManifest(t, manifest)
+	manifestDesc := ocispec.Descriptor{
+		MediaType: ocispec.MediaTypeImageManifest,
+		Digest:    digest.FromBytes(manifestBytes),
+		Size:      int64(len(manifestBytes)),
+	}
+
+	var manifestFetches atomic.Int32
+	mock := &pullMockOCIClient{}
+	mock.ResolveFunc = func(context.Context, string, string) (ocispec.Descriptor, error) {
+		return manifestDesc, nil
+	}
+	mock.FetchManifestFunc = func(context.Context, string, *ocispec.Descriptor) (ocispec.Manifest, []byte, error) {
+		manifestFetches.Add(1)
+		return manifest, manifestBytes, nil
+	}
+	mock.FetchBlobFunc = func(context.Context, string, *ocispec.Descriptor) (io.ReadCloser, error) {
+		return io.NopCloser(bytes.NewReader(indexData)), nil
+	}
+	mock.BlobURLFunc = func(string, string) (string, error) {
+		return dataServer.URL, nil
+	}
+	mock.AuthHeadersFunc = func(context.Context, string) (http.Header, error) {
+		return http.Header{}, nil
+	}
+
+	manifestCache, err := disk.NewManifestCache(t.TempDir())
+	require.NoError(t, err)
+	c := &Client{oci: mock, manifestCache: manifestCache}
+
+	for i := range 2 {
+		var got *BlobManifest
+		_, err := c.Pull(context.Background(), "registry.example.com/repo:v1", WithPullManifest(func(m *BlobManifest) {
+			got = m
+		}))
+		require.NoError(t, err, "pull %d", i)
+		require.NotNil(t, got, "pull %d", i)
+		assert.Equal(t, manifest.Annotations, got.Annotations(), "pull %d", i)
+		assert.Equal(t, manifestDesc.Digest.String(), got.Digest(), "pull %d", i)
+	}
+	assert.Equal(t, int32(1), manifestFetches.Load(), "second pull should be served from the manifest cache")
+}
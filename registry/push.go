@@ -6,6 +6,7 @@ import (
 	"encoding/hex"
 	"errors"
 	"fmt"
+	"io"
 	"math"
 	"time"
 
@@ -13,12 +14,37 @@ import (
 	"github.com/opencontainers/go-digest"
 	"github.com/opencontainers/image-spec/specs-go"
 	ocispec "github.com/opencontainers/image-spec/specs-go/v1"
+
+	"github.com/meigma/blob/registry/oras"
+)
+
+var (
+	_ blobExister     = (*oras.Client)(nil)
+	_ resumablePusher = (*oras.Client)(nil)
 )
 
+// blobExister is an optional interface that OCIClient implementations can
+// provide so Push skips blobs the registry already holds.
+type blobExister interface {
+	BlobExists(ctx context.Context, repoRef string, desc *ocispec.Descriptor) (bool, error)
+}
+
+// resumablePusher is an optional interface that OCIClient implementations
+// can provide to upload large blobs in resumable chunks.
+type resumablePusher interface {
+	PushBlobResumable(ctx context.Context, repoRef string, desc *ocispec.Descriptor, content io.ReaderAt) error
+}
+
 // Push pushes a blob archive to an OCI registry.
 //
 // The archive is pushed as two blobs (index and data) with a manifest
-// linking them. The ref must include a tag (e.g., "registry.com/repo:v1.0.0").
+// linking them, plus a third for the zstd dictionary of archives created
+// with one; b must then have been opened with blob.WithZstdDictionary. The ref must include a tag (e.g., "registry.com/repo:v1.0.0").
+//
+// Blobs the repository already holds, such as the data blob of an archive
+// pushed earlier under another tag, are not uploaded again unless
+// WithForceUpload is set. When the OCI client supports it, blobs are
+// uploaded in chunks and a failed upload resumes where it stopped.
 //
 // Use WithTags to apply additional tags to the same manifest.
 func (c *Client) Push(ctx context.Context, ref string, b *blob.Blob, opts ...PushOption) error {
@@ -42,6 +68,11 @@ func (c *Client) Push(ctx context.Context, ref string, b *blob.Blob, opts ...Pus
 	if err != nil {
 		return err
 	}
+	if _, ok := b.ZstdDictionaryHash(); ok {
+		if _, ok := b.ZstdDictionary(); !ok {
+			return errors.New("push: archive requires its zstd dictionary; open it with WithZstdDictionary")
+		}
+	}
 
 	indexData := b.IndexData()
 	c.log().Info("pushing archive",
@@ -50,12 +81,16 @@ func (c *Client) Push(ctx context.Context, ref string, b *blob.Blob, opts ...Pus
 		"data_size", dataDesc.Size,
 	)
 
-	// Step 1: Push empty config blob (required by OCI spec)
-	configDesc, err := c.pushEmptyConfig(ctx, ref)
-	if err != nil {
-		return fmt.Errorf("push config: %w", err)
+	// Step 1: Push empty config blob (required by OCI spec) unless the
+	// index takes its place
+	var configDesc ocispec.Descriptor
+	if !cfg.indexConfig {
+		configDesc, err = c.pushEmptyConfig(ctx, ref, &cfg)
+		if err != nil {
+			return fmt.Errorf("push config: %w", err)
+		}
+		c.log().Debug("pushed config blob", "digest", configDesc.Digest.String())
 	}
-	c.log().Debug("pushed config blob", "digest", configDesc.Digest.String())
 
 	// Step 2: Push index blob
 	indexDesc := ocispec.Descriptor{
@@ -64,29 +99,48 @@ func (c *Client) Push(ctx context.Context, ref string, b *blob.Blob, opts ...Pus
 		Size:      int64(len(indexData)),
 	}
 	reportProgress(cfg.progress, blob.StagePushingIndex, 0, sizeToUint64(indexDesc.Size))
-	if pushErr := c.oci.PushBlob(ctx, ref, &indexDesc, bytes.NewReader(indexData)); pushErr != nil {
-		return fmt.Errorf("push index blob: %w", mapOCIError(pushErr))
+	indexProgress := newProgressCounter(cfg.progress, blob.StagePushingIndex, sizeToUint64(indexDesc.Size))
+	if pushErr := c.pushBlob(ctx, ref, &indexDesc, bytes.NewReader(indexData), &cfg, indexProgress); pushErr != nil {
+		return fmt.Errorf("push index blob: %w", pushErr)
 	}
-	reportProgress(cfg.progress, blob.StagePushingIndex, sizeToUint64(indexDesc.Size), sizeToUint64(indexDesc.Size))
 	c.log().Debug("pushed index blob", "digest", indexDesc.Digest.String(), "size", indexDesc.Size)
 
 	// Step 3: Push data blob
 	reportProgress(cfg.progress, blob.StagePushingData, 0, sizeToUint64(dataDesc.Size))
-	if pushErr := c.oci.PushBlob(ctx, ref, &dataDesc, b.Stream()); pushErr != nil {
-		return fmt.Errorf("push data blob: %w", mapOCIError(pushErr))
+	dataProgress := newProgressCounter(cfg.progress, blob.StagePushingData, sizeToUint64(dataDesc.Size))
+	if pushErr := c.pushBlob(ctx, ref, &dataDesc, b.Stream(), &cfg, dataProgress); pushErr != nil {
+		return fmt.Errorf("push data blob: %w", pushErr)
 	}
-	reportProgress(cfg.progress, blob.StagePushingData, sizeToUint64(dataDesc.Size), sizeToUint64(dataDesc.Size))
 	c.log().Debug("pushed data blob", "digest", dataDesc.Digest.String(), "size", dataDesc.Size)
 
-	// Step 4: Build and push manifest
-	manifest := buildManifest(&configDesc, &indexDesc, &dataDesc, cfg.annotations)
+	// Step 4: Push the zstd dictionary blob, if the index references one
+	var dictDesc *ocispec.Descriptor
+	if dict, ok := b.ZstdDictionary(); ok {
+		dictDesc = &ocispec.Descriptor{
+			MediaType: MediaTypeZstdDictionary,
+			Digest:    digest.FromBytes(dict),
+			Size:      int64(len(dict)),
+		}
+		if pushErr := c.pushBlob(ctx, ref, dictDesc, bytes.NewReader(dict), &cfg, nil); pushErr != nil {
+			return fmt.Errorf("push zstd dictionary blob: %w", pushErr)
+		}
+		c.log().Debug("pushed zstd dictionary blob", "digest", dictDesc.Digest.String(), "size", dictDesc.Size)
+	}
+
+	// Step 5: Build and push manifest
+	var manifest ocispec.Manifest
+	if cfg.indexConfig {
+		manifest = buildManifest(&indexDesc, nil, &dataDesc, dictDesc, cfg.annotations)
+	} else {
+		manifest = buildManifest(&configDesc, &indexDesc, &dataDesc, dictDesc, cfg.annotations)
+	}
 	manifestDesc, err := c.oci.PushManifest(ctx, ref, tag, &manifest)
 	if err != nil {
 		return fmt.Errorf("push manifest: %w", mapOCIError(err))
 	}
 	c.log().Info("pushed manifest", "digest", manifestDesc.Digest.String())
 
-	// Step 5: Apply additional tags
+	// Step 6: Apply additional tags
 	for _, additionalTag := range cfg.tags {
 		if tagErr := c.oci.Tag(ctx, ref, &manifestDesc, additionalTag); tagErr != nil {
 			return fmt.Errorf("tag %q: %w", additionalTag, mapOCIError(tagErr))
@@ -94,23 +148,70 @@ func (c *Client) Push(ctx context.Context, ref string, b *blob.Blob, opts ...Pus
 		c.log().Debug("applied tag", "tag", additionalTag)
 	}
 
+	if cfg.manifestFn != nil {
+		cfg.manifestFn(manifestDesc)
+	}
+	if cfg.uploadedFn != nil {
+		cfg.uploadedFn(cfg.uploaded)
+	}
 	return nil
 }
 
 // pushEmptyConfig pushes the empty JSON config blob required by OCI manifests.
-func (c *Client) pushEmptyConfig(ctx context.Context, ref string) (ocispec.Descriptor, error) {
+func (c *Client) pushEmptyConfig(ctx context.Context, ref string, cfg *pushConfig) (ocispec.Descriptor, error) {
 	config := []byte("{}")
 	desc := ocispec.Descriptor{
 		MediaType: ocispec.MediaTypeEmptyJSON,
 		Digest:    digest.FromBytes(config),
 		Size:      int64(len(config)),
 	}
-	if err := c.oci.PushBlob(ctx, ref, &desc, bytes.NewReader(config)); err != nil {
-		return ocispec.Descriptor{}, mapOCIError(err)
+	if err := c.pushBlob(ctx, ref, &desc, bytes.NewReader(config), cfg, nil); err != nil {
+		return ocispec.Descriptor{}, err
 	}
 	return desc, nil
 }
 
+// pushBlob uploads a blob unless the repository already holds it.
+// Content that supports random access is uploaded resumably when the OCI
+// client allows it. Bytes read for the upload are reported to progress,
+// which completes once the blob is pushed or found to exist.
+func (c *Client) pushBlob(ctx context.Context, ref string, desc *ocispec.Descriptor, content io.Reader, cfg *pushConfig, progress *progressCounter) error {
+	if !cfg.forceUpload {
+		if exister, ok := c.oci.(blobExister); ok {
+			exists, err := exister.BlobExists(ctx, ref, desc)
+			if err != nil {
+				c.log().Debug("blob existence check failed", "digest", desc.Digest.String(), "error", err)
+			}
+			if exists {
+				c.log().Debug("blob already exists, skipping upload", "digest", desc.Digest.String(), "size", desc.Size)
+				progress.advanceTo(sizeToUint64(desc.Size))
+				return nil
+			}
+		}
+	}
+
+	var err error
+	pusher, resumable := c.oci.(resumablePusher)
+	ra, seekable := content.(io.ReaderAt)
+	if resumable && seekable {
+		if progress != nil {
+			ra = &progressReaderAt{ra: ra, progress: progress}
+		}
+		err = pusher.PushBlobResumable(ctx, ref, desc, ra)
+	} else {
+		if progress != nil {
+			content = &progressReader{r: content, progress: progress}
+		}
+		err = c.oci.PushBlob(ctx, ref, desc, content)
+	}
+	if err != nil {
+		return mapOCIError(err)
+	}
+	cfg.uploaded += desc.Size
+	progress.advanceTo(sizeToUint64(desc.Size))
+	return nil
+}
+
 // dataDescriptor builds the data blob descriptor from pre-computed metadata.
 func dataDescriptor(b *blob.Blob) (ocispec.Descriptor, error) {
 	hashBytes, ok := b.DataHash()
@@ -139,7 +240,10 @@ func dataDescriptor(b *blob.Blob) (ocispec.Descriptor, error) {
 }
 
 // buildManifest creates an OCI manifest for a blob archive.
-func buildManifest(configDesc, indexDesc, dataDesc *ocispec.Descriptor, customAnnotations map[string]string) ocispec.Manifest {
+// A nil indexDesc omits the index layer, for archives whose config
+// descriptor is the index blob. A non-nil dictDesc adds the zstd dictionary
+// layer after the data layer.
+func buildManifest(configDesc, indexDesc, dataDesc, dictDesc *ocispec.Descriptor, customAnnotations map[string]string) ocispec.Manifest {
 	annotations := make(map[string]string)
 	for k, v := range customAnnotations {
 		annotations[k] = v
@@ -148,12 +252,20 @@ func buildManifest(configDesc, indexDesc, dataDesc *ocispec.Descriptor, customAn
 		annotations[ocispec.AnnotationCreated] = time.Now().UTC().Format(time.RFC3339)
 	}
 
+	layers := []ocispec.Descriptor{*dataDesc}
+	if indexDesc != nil {
+		layers = []ocispec.Descriptor{*indexDesc, *dataDesc}
+	}
+	if dictDesc != nil {
+		layers = append(layers, *dictDesc)
+	}
+
 	return ocispec.Manifest{
 		Versioned:    specs.Versioned{SchemaVersion: 2},
 		MediaType:    ocispec.MediaTypeImageManifest,
 		ArtifactType: ArtifactType,
 		Config:       *configDesc,
-		Layers:       []ocispec.Descriptor{*indexDesc, *dataDesc},
+		Layers:       layers,
 		Annotations:  annotations,
 	}
 }
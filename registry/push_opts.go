@@ -1,6 +1,10 @@
 package registry
 
-import blob "github.com/meigma/blob/core"
+import (
+	ocispec "github.com/opencontainers/image-spec/specs-go/v1"
+
+	blob "github.com/meigma/blob/core"
+)
 
 // PushOption configures a Push operation.
 type PushOption func(*pushConfig)
@@ -9,6 +13,12 @@ type pushConfig struct {
 	tags        []string
 	annotations map[string]string
 	progress    blob.ProgressFunc
+	indexConfig bool
+	forceUpload bool
+	manifestFn  func(ocispec.Descriptor)
+	uploadedFn  func(int64)
+
+	uploaded int64 // blob bytes uploaded so far
 }
 
 // WithTags applies additional tags to the pushed manifest.
@@ -37,10 +47,48 @@ func WithAnnotations(annotations map[string]string) PushOption {
 }
 
 // WithProgress sets a callback to receive progress updates during push.
-// The callback receives events for index and data blob uploads.
+// The callback receives events for index and data blob uploads, with
+// BytesDone advancing as bytes are sent and never decreasing within a stage.
 // The callback may be invoked concurrently and must be safe for concurrent use.
 func WithProgress(fn blob.ProgressFunc) PushOption {
 	return func(cfg *pushConfig) {
 		cfg.progress = fn
 	}
 }
+
+// WithIndexAsConfig stores the index blob as the manifest config instead of
+// a layer, leaving the data blob as the only layer.
+//
+// This suits tools that surface an artifact's config as its metadata.
+// Fetch, Inspect, and Pull read both layouts transparently.
+func WithIndexAsConfig(enabled bool) PushOption {
+	return func(cfg *pushConfig) {
+		cfg.indexConfig = enabled
+	}
+}
+
+// WithForceUpload uploads every blob even when the repository already holds
+// it (default: false).
+func WithForceUpload(enabled bool) PushOption {
+	return func(cfg *pushConfig) {
+		cfg.forceUpload = enabled
+	}
+}
+
+// WithPushManifest sets a callback that receives the descriptor of the
+// pushed manifest once Push succeeds. Use it to record the manifest digest
+// without resolving the tag again.
+func WithPushManifest(fn func(ocispec.Descriptor)) PushOption {
+	return func(cfg *pushConfig) {
+		cfg.manifestFn = fn
+	}
+}
+
+// WithPushUploaded sets a callback that receives the number of blob bytes
+// uploaded once Push succeeds. Blobs the repository already held and were
+// skipped are not counted.
+func WithPushUploaded(fn func(bytes int64)) PushOption {
+	return func(cfg *pushConfig) {
+		cfg.uploadedFn = fn
+	}
+}
@@ -297,6 +297,89 @@ func TestClient_Push_VerifiesManifestStructure(t *testing.T) {
 	assert.NotEmpty(t, capturedManifest.Annotations[ocispec.AnnotationCreated])
 }
 
+func TestClient_Push_ReportsManifest(t *testing.T) {
+	t.Parallel()
+
+	testBlob := createTestBlob(t)
+	want := ocispec.Descriptor{
+		MediaType: ocispec.MediaTypeImageManifest,
+		Digest:    digest.FromString("manifest"),
+		Size:      100,
+	}
+	mock := &mockOCIClient{
+		PushBlobFunc: func(ctx context.Context, repoRef string, desc *ocispec.Descriptor, r io.Reader) error {
+			_, _ = io.Copy(io.Discard, r)
+			return nil
+		},
+		PushManifestFunc: func(ctx context.Context, repoRef, tag string, manifest *ocispec.Manifest) (ocispec.Descriptor, error) {
+			return want, nil
+		},
+		TagFunc: func(ctx context.Context, repoRef string, desc *ocispec.Descriptor, tag string) error {
+			return errors.New("tag rejected")
+		},
+	}
+	c := &Client{oci: mock}
+
+	var got []ocispec.Descriptor
+	record := WithPushManifest(func(desc ocispec.Descriptor) { got = append(got, desc) })
+	require.NoError(t, c.Push(context.Background(), "registry.example.com/repo:v1.0.0", testBlob, record))
+	assert.Equal(t, []ocispec.Descriptor{want}, got)
+
+	// A failed push reports nothing.
+	got = nil
+	err := c.Push(context.Background(), "registry.example.com/repo:v1.0.0", testBlob, record, WithTags("latest"))
+	require.Error(t, err)
+	assert.Empty(t, got)
+}
+
+func TestClient_Push_IndexAsConfig(t *testing.T) {
+	t.Parallel()
+
+	testBlob := createTestBlob(t)
+
+	var pushed []string
+	var capturedManifest *ocispec.Manifest
+	mock := &mockOCIClient{
+		PushBlobFunc: func(ctx context.Context, repoRef string, desc *ocispec.Descriptor, r io.Reader) error {
+			pushed = append(pushed, desc.MediaType)
+			_, _ = io.Copy(io.Discard, r)
+			return nil
+		},
+		PushManifestFunc: func(ctx context.Context, repoRef, tag string, manifest *ocispec.Manifest) (ocispec.Descriptor, error) {
+			capturedManifest = manifest
+			return ocispec.Descriptor{
+				MediaType: ocispec.MediaTypeImageManifest,
+				Digest:    digest.FromString("manifest"),
+				Size:      100,
+			}, nil
+		},
+	}
+
+	c := &Client{oci: mock}
+	err := c.Push(context.Background(), "registry.example.com/repo:v1.0.0", testBlob, WithIndexAsConfig(true))
+	require.NoError(t, err)
+	require.NotNil(t, capturedManifest)
+
+	// No empty config blob is pushed; the index is the config.
+	assert.Equal(t, []string{MediaTypeIndex, MediaTypeData}, pushed)
+	assert.Equal(t, MediaTypeIndex, capturedManifest.Config.MediaType)
+	assert.Equal(t, digest.FromBytes(testBlob.IndexData()), capturedManifest.Config.Digest)
+	require.Len(t, capturedManifest.Layers, 1)
+	assert.Equal(t, MediaTypeData, capturedManifest.Layers[0].MediaType)
+
+	// The manifest parses back with the config as the index descriptor.
+	parsed, err := parseBlobManifest(capturedManifest, "sha256:abc")
+	require.NoError(t, err)
+	assert.Equal(t, capturedManifest.Config, parsed.IndexDescriptor())
+	assert.Equal(t, capturedManifest.Layers[0], parsed.DataDescriptor())
+
+	// An index layer alongside an index config is rejected.
+	withLayer := *capturedManifest
+	withLayer.Layers = []ocispec.Descriptor{capturedManifest.Config, capturedManifest.Layers[0]}
+	_, err = parseBlobManifest(&withLayer, "sha256:abc")
+	require.ErrorIs(t, err, ErrInvalidManifest)
+}
+
 func TestClient_Push_VerifiesBlobDescriptors(t *testing.T) {
 	t.Parallel()
 
@@ -376,3 +459,44 @@ func TestWithAnnotations(t *testing.T) {
 	})(&cfg)
 	assert.Equal(t, "newvalue", cfg.annotations["key1"])
 }
+
+func TestClient_Push_SkipsExistingBlobs(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	b := createTestBlob(t)
+
+	t.Run("second push uploads nothing", func(t *testing.T) {
+		t.Parallel()
+
+		m := newMemRegistry()
+		c := &Client{oci: m}
+		var uploaded int64
+		record := WithPushUploaded(func(n int64) { uploaded = n })
+		require.NoError(t, c.Push(ctx, "registry.example.com/repo:v1", b, record))
+		first := m.pushes
+		require.Equal(t, 3, first, "config, index, and data blobs")
+		assert.Greater(t, uploaded, int64(len(b.IndexData())))
+
+		require.NoError(t, c.Push(ctx, "registry.example.com/repo:v2", b, record))
+		assert.Equal(t, first, m.pushes, "existing blobs must not be uploaded again")
+		assert.Zero(t, uploaded, "skipped blobs are not counted")
+
+		_, err := c.Fetch(ctx, "registry.example.com/repo:v2")
+		require.NoError(t, err)
+	})
+
+	t.Run("force upload", func(t *testing.T) {
+		t.Parallel()
+
+		m := newMemRegistry()
+		c := &Client{oci: m}
+		var uploaded []int64
+		record := WithPushUploaded(func(n int64) { uploaded = append(uploaded, n) })
+		require.NoError(t, c.Push(ctx, "registry.example.com/repo:v1", b, record))
+		require.NoError(t, c.Push(ctx, "registry.example.com/repo:v2", b, record, WithForceUpload(true)))
+		assert.Equal(t, 6, m.pushes)
+		require.Len(t, uploaded, 2)
+		assert.Equal(t, uploaded[0], uploaded[1])
+	})
+}
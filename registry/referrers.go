@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"io"
 
+	"github.com/opencontainers/go-digest"
 	ocispec "github.com/opencontainers/image-spec/specs-go/v1"
 )
 
@@ -45,3 +46,120 @@ func (c *Client) FetchDescriptor(ctx context.Context, ref string, desc ocispec.D
 	}
 	return data, nil
 }
+
+// Referrer pairs a referrer descriptor with its raw content.
+type Referrer struct {
+	// Descriptor identifies the referrer artifact.
+	Descriptor ocispec.Descriptor
+
+	// Content holds the bytes addressed by Descriptor. For OCI artifacts
+	// such as signatures this is the referrer manifest.
+	Content []byte
+}
+
+// FetchReferrers lists the referrers of the manifest at ref and fetches
+// their content, without evaluating policies.
+//
+// The artifactType parameter filters referrers by type. Pass "" to get all.
+// When a referrer cache is configured, listings and content are served from
+// it and populated on a miss; combined with the ref and manifest caches,
+// repeated calls for the same ref make no registry requests.
+func (c *Client) FetchReferrers(ctx context.Context, ref, artifactType string) ([]Referrer, error) {
+	parsedRef, err := parseClientRef(ref)
+	if err != nil {
+		return nil, err
+	}
+	if parsedRef.reference == "" {
+		return nil, fmt.Errorf("%w: reference must include a tag or digest", ErrInvalidReference)
+	}
+
+	digestStr, err := c.resolveDigest(ctx, ref, parsedRef.reference, false)
+	if err != nil {
+		return nil, err
+	}
+
+	descs, err := c.listReferrers(ctx, ref, digestStr, artifactType)
+	if err != nil {
+		return nil, err
+	}
+
+	referrers := make([]Referrer, len(descs))
+	for i := range descs {
+		content, err := c.fetchReferrerContent(ctx, ref, &descs[i])
+		if err != nil {
+			return nil, err
+		}
+		referrers[i] = Referrer{Descriptor: descs[i], Content: content}
+	}
+	return referrers, nil
+}
+
+// listReferrers returns the referrer descriptors for a subject digest,
+// using the referrer cache if available.
+func (c *Client) listReferrers(ctx context.Context, ref, subjectDigest, artifactType string) ([]ocispec.Descriptor, error) {
+	if c.referrerCache != nil {
+		if descs, ok := c.referrerCache.GetReferrers(subjectDigest, artifactType); ok {
+			return descs, nil
+		}
+	}
+
+	manifest, raw, fromCache, err := c.fetchManifestByDigest(ctx, ref, subjectDigest, false)
+	if err != nil {
+		return nil, err
+	}
+	if !fromCache && c.manifestCache != nil {
+		if err := c.manifestCache.PutManifest(subjectDigest, raw); err != nil {
+			return nil, fmt.Errorf("cache manifest: %w", err)
+		}
+	}
+
+	dgst, err := digest.Parse(subjectDigest)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidReference, err)
+	}
+	subject := ocispec.Descriptor{
+		MediaType: manifest.Raw().MediaType,
+		Digest:    dgst,
+		Size:      int64(len(raw)),
+	}
+	descs, err := c.Referrers(ctx, ref, subject, artifactType)
+	if err != nil {
+		return nil, err
+	}
+
+	if c.referrerCache != nil {
+		if err := c.referrerCache.PutReferrers(subjectDigest, artifactType, descs); err != nil {
+			return nil, fmt.Errorf("cache referrers: %w", err)
+		}
+	}
+	return descs, nil
+}
+
+// fetchReferrerContent returns the verified content for a referrer
+// descriptor, using the referrer cache if available.
+func (c *Client) fetchReferrerContent(ctx context.Context, ref string, desc *ocispec.Descriptor) ([]byte, error) {
+	dgst := desc.Digest.String()
+	if c.referrerCache != nil {
+		if content, ok := c.referrerCache.GetContent(dgst); ok {
+			return content, nil
+		}
+	}
+
+	content, err := c.FetchDescriptor(ctx, ref, *desc)
+	if err != nil {
+		return nil, fmt.Errorf("fetch referrer %s: %w", dgst, err)
+	}
+	if err := desc.Digest.Validate(); err != nil {
+		return nil, fmt.Errorf("fetch referrer %s: %w", dgst, err)
+	}
+	if desc.Digest.Algorithm().FromBytes(content) != desc.Digest {
+		return nil, fmt.Errorf("fetch referrer %s: %w", dgst, ErrDigestMismatch)
+	}
+
+	if c.referrerCache != nil {
+		if err := c.referrerCache.PutContent(dgst, content); err != nil {
+			return nil, fmt.Errorf("cache referrer: %w", err)
+		}
+	}
+	return content, nil
+}
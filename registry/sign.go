@@ -73,7 +73,7 @@ func (c *Client) Sign(ctx context.Context, ref string, signer ManifestSigner, op
 	c.log().Debug("pushed signature blob", "digest", sigDigest.String(), "size", len(sigData))
 
 	// Step 5: Push empty config blob (required by OCI artifact pattern)
-	configDesc, err := c.pushEmptyConfig(ctx, ref)
+	configDesc, err := c.pushEmptyConfig(ctx, ref, &pushConfig{})
 	if err != nil {
 		return "", fmt.Errorf("push config: %w", err)
 	}
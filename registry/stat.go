@@ -0,0 +1,66 @@
+package registry
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	blob "github.com/meigma/blob/core"
+)
+
+// ArchiveInfo summarizes an archive from its manifest and index alone.
+type ArchiveInfo struct {
+	// Digest is the manifest digest.
+	Digest string
+
+	// Created is the creation time from the manifest annotations, or the
+	// zero time if absent.
+	Created time.Time
+
+	// DataSize is the size of the data blob in bytes.
+	DataSize int64
+
+	// IndexSize is the size of the index blob in bytes.
+	IndexSize int64
+
+	// FileCount is the number of files in the archive.
+	FileCount int
+
+	// UncompressedSize is the sum of all file sizes once extracted.
+	UncompressedSize uint64
+
+	// Compression counts files by the algorithm their content is stored with.
+	Compression map[blob.Compression]int
+}
+
+// Stat returns a summary of the archive at ref without touching the data
+// blob.
+//
+// Only the manifest and index blob are fetched, and both are served from
+// the configured caches when possible, so Stat is cheap enough to call
+// before deciding whether to Pull.
+func (c *Client) Stat(ctx context.Context, ref string, opts ...InspectOption) (ArchiveInfo, error) {
+	result, err := c.Inspect(ctx, ref, opts...)
+	if err != nil {
+		return ArchiveInfo{}, err
+	}
+
+	index, err := blob.NewIndexView(result.IndexData)
+	if err != nil {
+		return ArchiveInfo{}, fmt.Errorf("parse index blob: %w", err)
+	}
+
+	info := ArchiveInfo{
+		Digest:      result.Manifest.Digest(),
+		Created:     result.Manifest.Created(),
+		DataSize:    result.Manifest.DataDescriptor().Size,
+		IndexSize:   result.Manifest.IndexDescriptor().Size,
+		FileCount:   index.Len(),
+		Compression: make(map[blob.Compression]int),
+	}
+	for entry := range index.Entries() {
+		info.UncompressedSize += entry.OriginalSize()
+		info.Compression[entry.Compression()]++
+	}
+	return info, nil
+}
@@ -0,0 +1,91 @@
+package registry
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"github.com/opencontainers/go-digest"
+	ocispec "github.com/opencontainers/image-spec/specs-go/v1"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	blob "github.com/meigma/blob/core"
+)
+
+func TestClient_Stat(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "compressible.txt"), []byte(strings.Repeat("stat me ", 4096)), 0o644))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "tiny.txt"), []byte("x"), 0o644))
+	blobFile, err := blob.CreateBlob(context.Background(), dir, t.TempDir(), blob.CreateBlobWithCompression(blob.CompressionZstd))
+	require.NoError(t, err)
+	t.Cleanup(func() { blobFile.Close() })
+
+	indexData := blobFile.IndexData()
+	var data bytes.Buffer
+	_, err = io.Copy(&data, blobFile.Stream())
+	require.NoError(t, err)
+	manifest, manifestBytes, manifestDesc := manifestForIndexData(t, indexData, data.Bytes())
+	indexDigest := digest.FromBytes(indexData)
+
+	// The data server stands in for the data blob; Stat must never reach it.
+	var dataRequests atomic.Int32
+	dataServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		dataRequests.Add(1)
+		http.Error(w, "data blob must not be fetched", http.StatusBadRequest)
+	}))
+	t.Cleanup(dataServer.Close)
+
+	var indexFetches, dataFetches atomic.Int32
+	mock := &pullMockOCIClient{}
+	mock.ResolveFunc = func(context.Context, string, string) (ocispec.Descriptor, error) {
+		return manifestDesc, nil
+	}
+	mock.FetchManifestFunc = func(context.Context, string, *ocispec.Descriptor) (ocispec.Manifest, []byte, error) {
+		return manifest, manifestBytes, nil
+	}
+	mock.FetchBlobFunc = func(_ context.Context, _ string, desc *ocispec.Descriptor) (io.ReadCloser, error) {
+		if desc.Digest != indexDigest {
+			dataFetches.Add(1)
+			return nil, errNotImplemented
+		}
+		indexFetches.Add(1)
+		return io.NopCloser(bytes.NewReader(indexData)), nil
+	}
+	mock.BlobURLFunc = func(string, string) (string, error) {
+		return dataServer.URL, nil
+	}
+	mock.AuthHeadersFunc = func(context.Context, string) (http.Header, error) {
+		return http.Header{}, nil
+	}
+
+	c := &Client{oci: mock, indexCache: newMemIndexCache()}
+	for range 2 {
+		info, err := c.Stat(context.Background(), "registry.example.com/repo:v1")
+		require.NoError(t, err)
+
+		assert.Equal(t, manifestDesc.Digest.String(), info.Digest)
+		assert.Equal(t, int64(data.Len()), info.DataSize)
+		assert.Equal(t, int64(len(indexData)), info.IndexSize)
+		assert.Equal(t, 2, info.FileCount)
+		assert.Equal(t, uint64(4096*len("stat me ")+1), info.UncompressedSize)
+		assert.Equal(t, map[blob.Compression]int{
+			blob.CompressionZstd: 1,
+			blob.CompressionNone: 1,
+		}, info.Compression)
+		assert.False(t, info.Created.IsZero())
+	}
+
+	assert.Equal(t, int32(1), indexFetches.Load(), "second Stat should use the index cache")
+	assert.Zero(t, dataFetches.Load(), "data blob fetched")
+	assert.Zero(t, dataRequests.Load(), "data blob range requested")
+}
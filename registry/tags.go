@@ -0,0 +1,45 @@
+package registry
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/meigma/blob/registry/oras"
+)
+
+// tagLister is an optional interface that OCIClient implementations can
+// provide to support ListTags.
+type tagLister interface {
+	ListTags(ctx context.Context, repoRef string) ([]string, error)
+}
+
+var _ tagLister = (*oras.Client)(nil)
+
+// ListTags returns the tags of a repository in the order the registry
+// reports them.
+//
+// The repo is a repository reference without a tag or digest (e.g.,
+// "ghcr.io/org/archive"). Paginated listings are followed transparently.
+// Rejected credentials surface as ErrUnauthorized or ErrForbidden, and a
+// missing repository as ErrNotFound.
+func (c *Client) ListTags(ctx context.Context, repo string) ([]string, error) {
+	parsedRef, err := parseClientRef(repo)
+	if err != nil {
+		return nil, err
+	}
+	if parsedRef.reference != "" {
+		return nil, fmt.Errorf("%w: repository must not include a tag or digest", ErrInvalidReference)
+	}
+
+	lister, ok := c.oci.(tagLister)
+	if !ok {
+		return nil, ErrListTagsUnsupported
+	}
+
+	c.log().Debug("listing tags", "repo", repo)
+	tags, err := lister.ListTags(ctx, repo)
+	if err != nil {
+		return nil, mapOCIError(err)
+	}
+	return tags, nil
+}
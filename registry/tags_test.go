@@ -0,0 +1,84 @@
+package registry
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/meigma/blob/registry/oras"
+)
+
+// tagListingClient extends mockOCIClient with the optional ListTags method.
+type tagListingClient struct {
+	mockOCIClient
+	listTags func(ctx context.Context, repoRef string) ([]string, error)
+}
+
+func (m *tagListingClient) ListTags(ctx context.Context, repoRef string) ([]string, error) {
+	return m.listTags(ctx, repoRef)
+}
+
+func TestClient_ListTags(t *testing.T) {
+	t.Parallel()
+
+	const repo = "registry.example.com/repo"
+
+	t.Run("returns tags in registry order", func(t *testing.T) {
+		t.Parallel()
+
+		mock := &tagListingClient{listTags: func(_ context.Context, repoRef string) ([]string, error) {
+			assert.Equal(t, repo, repoRef)
+			return []string{"v2", "v1", "latest"}, nil
+		}}
+		c := &Client{oci: mock}
+
+		tags, err := c.ListTags(context.Background(), repo)
+		require.NoError(t, err)
+		assert.Equal(t, []string{"v2", "v1", "latest"}, tags)
+	})
+
+	t.Run("maps registry errors", func(t *testing.T) {
+		t.Parallel()
+
+		tests := []struct {
+			name    string
+			ociErr  error
+			wantErr error
+		}{
+			{name: "unauthorized", ociErr: oras.ErrUnauthorized, wantErr: ErrUnauthorized},
+			{name: "forbidden", ociErr: oras.ErrForbidden, wantErr: ErrForbidden},
+			{name: "not found", ociErr: oras.ErrNotFound, wantErr: ErrNotFound},
+		}
+		for _, tt := range tests {
+			t.Run(tt.name, func(t *testing.T) {
+				t.Parallel()
+
+				mock := &tagListingClient{listTags: func(context.Context, string) ([]string, error) {
+					return nil, tt.ociErr
+				}}
+				c := &Client{oci: mock}
+
+				_, err := c.ListTags(context.Background(), repo)
+				require.ErrorIs(t, err, tt.wantErr)
+			})
+		}
+	})
+
+	t.Run("rejects tag or digest", func(t *testing.T) {
+		t.Parallel()
+
+		c := &Client{oci: &tagListingClient{}}
+		_, err := c.ListTags(context.Background(), repo+":v1")
+		require.ErrorIs(t, err, ErrInvalidReference)
+	})
+
+	t.Run("unsupported client", func(t *testing.T) {
+		t.Parallel()
+
+		c := &Client{oci: &mockOCIClient{}}
+		_, err := c.ListTags(context.Background(), repo)
+		require.ErrorIs(t, err, ErrListTagsUnsupported)
+	})
+}
@@ -0,0 +1,158 @@
+package registry
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"hash"
+	"io"
+
+	"github.com/opencontainers/go-digest"
+	ocispec "github.com/opencontainers/image-spec/specs-go/v1"
+)
+
+// DigestMismatchError reports streamed content whose size or digest does not
+// match its descriptor. It matches ErrDigestMismatch with errors.Is.
+type DigestMismatchError struct {
+	Expected     digest.Digest
+	Actual       digest.Digest // empty when the size check failed first
+	ExpectedSize int64
+	ActualSize   int64
+}
+
+func (e *DigestMismatchError) Error() string {
+	if e.Actual == "" {
+		return fmt.Sprintf("%s: %s: expected %d bytes, got at least %d", ErrDigestMismatch, e.Expected, e.ExpectedSize, e.ActualSize)
+	}
+	if e.ActualSize != e.ExpectedSize {
+		return fmt.Sprintf("%s: %s: expected %d bytes, got %d", ErrDigestMismatch, e.Expected, e.ExpectedSize, e.ActualSize)
+	}
+	return fmt.Sprintf("%s: expected %s, got %s", ErrDigestMismatch, e.Expected, e.Actual)
+}
+
+// Unwrap returns ErrDigestMismatch.
+func (e *DigestMismatchError) Unwrap() error {
+	return ErrDigestMismatch
+}
+
+// VerifyingReader computes the digest of content as it is read and checks it
+// against a descriptor, so a blob can be verified while it is copied
+// elsewhere without a second pass.
+//
+// Read returns a *DigestMismatchError in place of io.EOF when the content
+// does not match, and fails as soon as more bytes than the descriptor size
+// arrive. Callers must treat the copied bytes as untrusted until Read has
+// returned io.EOF.
+type VerifyingReader struct {
+	r        io.Reader
+	closer   io.Closer
+	desc     ocispec.Descriptor
+	hash     hash.Hash
+	n        int64
+	err      error // sticky result once verification completes or fails
+	verified bool
+}
+
+// NewVerifyingReader wraps r to verify it against desc.
+//
+// If r implements io.Closer, Close closes it. NewVerifyingReader returns an
+// error if the descriptor digest is invalid or its algorithm unavailable.
+func NewVerifyingReader(r io.Reader, desc ocispec.Descriptor) (*VerifyingReader, error) {
+	if err := desc.Digest.Validate(); err != nil {
+		return nil, fmt.Errorf("%w: invalid digest %q: %v", ErrInvalidManifest, desc.Digest, err)
+	}
+	vr := &VerifyingReader{
+		r:    r,
+		desc: desc,
+		hash: desc.Digest.Algorithm().Hash(),
+	}
+	if c, ok := r.(io.Closer); ok {
+		vr.closer = c
+	}
+	return vr, nil
+}
+
+// Read reads from the underlying reader, hashing the bytes returned.
+func (v *VerifyingReader) Read(p []byte) (int, error) {
+	if v.err != nil {
+		return 0, v.err
+	}
+	n, err := v.r.Read(p)
+	if n > 0 {
+		v.hash.Write(p[:n])
+		v.n += int64(n)
+		if v.desc.Size >= 0 && v.n > v.desc.Size {
+			v.err = &DigestMismatchError{Expected: v.desc.Digest, ExpectedSize: v.desc.Size, ActualSize: v.n}
+			return n, v.err
+		}
+	}
+	switch {
+	case errors.Is(err, io.EOF):
+		v.err = v.verify()
+		return n, v.err
+	case err != nil:
+		v.err = err
+		return n, err
+	}
+	return n, nil
+}
+
+// verify compares the content read so far with the descriptor.
+func (v *VerifyingReader) verify() error {
+	actual := digest.NewDigest(v.desc.Digest.Algorithm(), v.hash)
+	if actual != v.desc.Digest || v.n != v.desc.Size {
+		return &DigestMismatchError{
+			Expected:     v.desc.Digest,
+			Actual:       actual,
+			ExpectedSize: v.desc.Size,
+			ActualSize:   v.n,
+		}
+	}
+	v.verified = true
+	return io.EOF
+}
+
+// Verified reports whether the full content has been read and matched the
+// descriptor.
+func (v *VerifyingReader) Verified() bool {
+	return v.verified
+}
+
+// Close closes the underlying reader. If the content was read to the end,
+// Close returns the verification error, if any; otherwise it reports
+// nothing about integrity.
+func (v *VerifyingReader) Close() error {
+	var closeErr error
+	if v.closer != nil {
+		closeErr = v.closer.Close()
+	}
+	if errors.Is(v.err, ErrDigestMismatch) {
+		return v.err
+	}
+	return closeErr
+}
+
+// FetchData streams the data blob of the archive at ref, verifying it against
+// the manifest's data descriptor as it is read.
+//
+// The manifest is resolved and checked against configured policies as in
+// Fetch. The returned reader yields a *DigestMismatchError instead of io.EOF
+// if the content does not match. The caller must close it.
+func (c *Client) FetchData(ctx context.Context, ref string, opts ...FetchOption) (*VerifyingReader, error) {
+	manifest, err := c.Fetch(ctx, ref, opts...)
+	if err != nil {
+		return nil, err
+	}
+
+	dataDesc := manifest.DataDescriptor()
+	rc, err := c.oci.FetchBlob(ctx, ref, &dataDesc)
+	if err != nil {
+		return nil, fmt.Errorf("fetch data blob: %w", mapOCIError(err))
+	}
+	vr, err := NewVerifyingReader(rc, dataDesc)
+	if err != nil {
+		rc.Close()
+		return nil, err
+	}
+	return vr, nil
+}
@@ -0,0 +1,139 @@
+package registry
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"testing"
+
+	"github.com/opencontainers/go-digest"
+	ocispec "github.com/opencontainers/image-spec/specs-go/v1"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestVerifyingReader(t *testing.T) {
+	t.Parallel()
+
+	data := bytes.Repeat([]byte("verify me "), 1000)
+	desc := ocispec.Descriptor{Digest: digest.FromBytes(data), Size: int64(len(data))}
+
+	t.Run("good data", func(t *testing.T) {
+		t.Parallel()
+
+		vr, err := NewVerifyingReader(bytes.NewReader(data), desc)
+		require.NoError(t, err)
+
+		var buf bytes.Buffer
+		_, err = io.Copy(&buf, vr)
+		require.NoError(t, err)
+		require.NoError(t, vr.Close())
+		assert.True(t, vr.Verified())
+		assert.Equal(t, data, buf.Bytes())
+	})
+
+	t.Run("corrupted byte", func(t *testing.T) {
+		t.Parallel()
+
+		corrupted := bytes.Clone(data)
+		corrupted[len(corrupted)/2] ^= 0xff
+		vr, err := NewVerifyingReader(io.NopCloser(bytes.NewReader(corrupted)), desc)
+		require.NoError(t, err)
+
+		_, err = io.Copy(io.Discard, vr)
+		var mismatch *DigestMismatchError
+		require.ErrorAs(t, err, &mismatch)
+		assert.ErrorIs(t, err, ErrDigestMismatch)
+		assert.Equal(t, desc.Digest, mismatch.Expected)
+		assert.Equal(t, digest.FromBytes(corrupted), mismatch.Actual)
+		assert.False(t, vr.Verified())
+		assert.ErrorIs(t, vr.Close(), ErrDigestMismatch)
+	})
+
+	t.Run("truncated", func(t *testing.T) {
+		t.Parallel()
+
+		vr, err := NewVerifyingReader(bytes.NewReader(data[:len(data)-1]), desc)
+		require.NoError(t, err)
+
+		_, err = io.Copy(io.Discard, vr)
+		require.ErrorIs(t, err, ErrDigestMismatch)
+	})
+
+	t.Run("oversized", func(t *testing.T) {
+		t.Parallel()
+
+		vr, err := NewVerifyingReader(io.MultiReader(bytes.NewReader(data), bytes.NewReader([]byte("x"))), desc)
+		require.NoError(t, err)
+
+		_, err = io.Copy(io.Discard, vr)
+		var mismatch *DigestMismatchError
+		require.ErrorAs(t, err, &mismatch)
+		assert.Equal(t, int64(len(data)+1), mismatch.ActualSize)
+	})
+
+	t.Run("invalid digest", func(t *testing.T) {
+		t.Parallel()
+
+		_, err := NewVerifyingReader(bytes.NewReader(data), ocispec.Descriptor{Digest: "sha256:bad"})
+		require.ErrorIs(t, err, ErrInvalidManifest)
+	})
+}
+
+func TestClient_FetchData(t *testing.T) {
+	t.Parallel()
+
+	const testRef = "registry.example.com/repo:v1.0.0"
+
+	for _, tc := range []struct {
+		name    string
+		corrupt bool
+	}{
+		{name: "verified"},
+		{name: "corrupted", corrupt: true},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			indexData, dataBytes := createTestBlobData(t)
+			manifest, manifestBytes, manifestDesc := manifestForIndexData(t, indexData, dataBytes)
+
+			served := dataBytes
+			if tc.corrupt {
+				served = bytes.Clone(dataBytes)
+				served[0] ^= 0xff
+			}
+
+			mock := &pullMockOCIClient{}
+			mock.ResolveFunc = func(ctx context.Context, repoRef, ref string) (ocispec.Descriptor, error) {
+				return manifestDesc, nil
+			}
+			mock.FetchManifestFunc = func(ctx context.Context, repoRef string, expected *ocispec.Descriptor) (ocispec.Manifest, []byte, error) {
+				return manifest, manifestBytes, nil
+			}
+			mock.FetchBlobFunc = func(ctx context.Context, repoRef string, desc *ocispec.Descriptor) (io.ReadCloser, error) {
+				if desc.Digest != manifest.Layers[1].Digest {
+					return nil, errors.New("unexpected blob requested")
+				}
+				return io.NopCloser(bytes.NewReader(served)), nil
+			}
+
+			c := &Client{oci: mock}
+			vr, err := c.FetchData(context.Background(), testRef)
+			require.NoError(t, err)
+
+			var buf bytes.Buffer
+			_, err = io.Copy(&buf, vr)
+			closeErr := vr.Close()
+			if tc.corrupt {
+				require.ErrorIs(t, err, ErrDigestMismatch)
+				require.ErrorIs(t, closeErr, ErrDigestMismatch)
+				return
+			}
+			require.NoError(t, err)
+			require.NoError(t, closeErr)
+			assert.Equal(t, dataBytes, buf.Bytes())
+		})
+	}
+}
@@ -16,20 +16,8 @@ type ManifestSigner = registry.ManifestSigner
 //
 // Returns the digest of the signature manifest.
 func (c *Client) Sign(ctx context.Context, ref string, signer ManifestSigner, opts ...SignOption) (string, error) {
-	// Build registry client options
-	var regOpts []registry.Option //nolint:prealloc // size depends on optional config
-	regOpts = append(regOpts, registry.WithOrasOptions(c.orasOpts...))
-	if c.refCache != nil {
-		regOpts = append(regOpts, registry.WithRefCache(c.refCache))
-	}
-	if c.manifestCache != nil {
-		regOpts = append(regOpts, registry.WithManifestCache(c.manifestCache))
-	}
-	if c.indexCache != nil {
-		regOpts = append(regOpts, registry.WithIndexCache(c.indexCache))
-	}
-
-	regClient := registry.New(regOpts...)
+	// Signing pushes a referrer and evaluates no policies.
+	regClient := registry.New(registryOpts(c, nil)...)
 
 	// Build sign options
 	var signOpts []registry.SignOption
@@ -0,0 +1,23 @@
+package blob
+
+import (
+	"context"
+
+	"github.com/meigma/blob/registry"
+)
+
+// ArchiveInfo summarizes an archive from its manifest and index alone.
+type ArchiveInfo = registry.ArchiveInfo
+
+// Stat returns the size, file count, compression summary, and digest of
+// the archive at ref without downloading the data blob.
+//
+// Only the manifest and the small index blob are fetched, using the
+// manifest and index caches when configured. Use [Client.Inspect] for
+// per-file metadata.
+func (c *Client) Stat(ctx context.Context, ref string, opts ...InspectOption) (ArchiveInfo, error) {
+	c.log().Debug("stat archive", "ref", ref)
+
+	regClient := registry.New(buildRegistryOpts(c)...)
+	return regClient.Stat(ctx, ref, registryInspectOptions(opts)...)
+}
@@ -0,0 +1,20 @@
+package blob
+
+import (
+	"context"
+
+	"github.com/meigma/blob/registry"
+)
+
+// ListTags returns the tags of a repository in the order the registry
+// reports them, for example to discover the available archive versions.
+//
+// The repo must not include a tag or digest (e.g., "ghcr.io/org/archive").
+// Paginated listings are followed transparently. Rejected credentials
+// return [ErrUnauthorized] or [ErrForbidden].
+func (c *Client) ListTags(ctx context.Context, repo string) ([]string, error) {
+	c.log().Debug("listing tags", "repo", repo)
+
+	regClient := registry.New(buildRegistryOpts(c)...)
+	return regClient.ListTags(ctx, repo)
+}
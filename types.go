@@ -10,60 +10,225 @@ type IndexView = blobcore.IndexView
 // Compression identifies the compression algorithm used for a file.
 type Compression = blobcore.Compression
 
+// Encryption identifies the scheme used to encrypt file content.
+type Encryption = blobcore.Encryption
+
+// AuxChecksum identifies the non-cryptographic per-file checksum recorded
+// alongside SHA256 hashes.
+type AuxChecksum = blobcore.AuxChecksum
+
 // Entry represents a file in the archive.
 type Entry = blobcore.Entry
 
+// CompressionLevel controls the speed/ratio tradeoff of the zstd encoder.
+type CompressionLevel = blobcore.CompressionLevel
+
 // EntryView provides a read-only view of an index entry.
 type EntryView = blobcore.EntryView
 
 // ChangeDetection controls how strictly file changes are detected during creation.
 type ChangeDetection = blobcore.ChangeDetection
 
+// ConcurrentModification controls how files that change size during creation are handled.
+type ConcurrentModification = blobcore.ConcurrentModification
+
 // SkipCompressionFunc returns true when a file should be stored uncompressed.
 type SkipCompressionFunc = blobcore.SkipCompressionFunc
 
 // CopyOption configures CopyTo and CopyDir operations.
 type CopyOption = blobcore.CopyOption
 
+// VerifyOption configures Blob.Verify.
+type VerifyOption = blobcore.VerifyOption
+
+// TarOption configures Blob.WriteTar.
+type TarOption = blobcore.TarOption
+
+// TarMode controls how Blob.WriteTar builds tar headers.
+type TarMode = blobcore.TarMode
+
+// ZipOption configures Blob.WriteZip.
+type ZipOption = blobcore.ZipOption
+
+// MetadataRecord is a single line of Blob.ExportMetadata output.
+type MetadataRecord = blobcore.MetadataRecord
+
 // CopyStats contains statistics about a copy operation.
 type CopyStats = blobcore.CopyStats
 
+// ExtractionProgress records the work completed by a resumable extraction.
+type ExtractionProgress = blobcore.ExtractionProgress
+
+// ProgressStore persists extraction progress outside the current process.
+type ProgressStore = blobcore.ProgressStore
+
 // DirStats contains statistics about files under a directory prefix.
 type DirStats = blobcore.DirStats
 
 // ValidationError describes why a path failed validation.
 type ValidationError = blobcore.ValidationError
 
+// ExtractionLimitError describes an extraction aborted by CopyWithMaxFiles
+// or CopyWithMaxTotalBytes.
+type ExtractionLimitError = blobcore.ExtractionLimitError
+
+// ExtractionLimit identifies which extraction limit was exceeded.
+type ExtractionLimit = blobcore.ExtractionLimit
+
+// PathLimitError describes a path rejected by a length or depth limit.
+type PathLimitError = blobcore.PathLimitError
+
+// PathLimit identifies which path limit was exceeded.
+type PathLimit = blobcore.PathLimit
+
+// PathMapper rewrites an entry's destination path during extraction.
+type PathMapper = blobcore.PathMapper
+
+// IndexVersionError describes an index whose format version is outside the
+// accepted range.
+type IndexVersionError = blobcore.IndexVersionError
+
+// IndexVersion is the index format version written by Push and CreateBlob.
+const IndexVersion = blobcore.IndexVersion
+
+// PathLimit constants.
+const (
+	PathLimitLength = blobcore.PathLimitLength
+	PathLimitDepth  = blobcore.PathLimitDepth
+)
+
+// ExtractionLimit constants.
+const (
+	ExtractionLimitFiles = blobcore.ExtractionLimitFiles
+	ExtractionLimitBytes = blobcore.ExtractionLimitBytes
+)
+
 // ByteSource provides random access to the data blob.
 type ByteSource = blobcore.ByteSource
 
+// RetryOption configures RetryingSource.
+type RetryOption = blobcore.RetryOption
+
 // Compression constants.
 const (
 	CompressionNone = blobcore.CompressionNone
 	CompressionZstd = blobcore.CompressionZstd
 )
 
+// Encryption constants.
+const (
+	EncryptionNone   = blobcore.EncryptionNone
+	EncryptionAESGCM = blobcore.EncryptionAESGCM
+)
+
+// AuxChecksum constants.
+const (
+	AuxChecksumNone  = blobcore.AuxChecksumNone
+	AuxChecksumXXH64 = blobcore.AuxChecksumXXH64
+)
+
+// CompressionLevel constants.
+const (
+	CompressionLevelDefault = blobcore.CompressionLevelDefault
+	CompressionLevelFastest = blobcore.CompressionLevelFastest
+	CompressionLevelBetter  = blobcore.CompressionLevelBetter
+	CompressionLevelBest    = blobcore.CompressionLevelBest
+)
+
 // ChangeDetection constants.
 const (
 	ChangeDetectionNone   = blobcore.ChangeDetectionNone
 	ChangeDetectionStrict = blobcore.ChangeDetectionStrict
 )
 
+// ConcurrentModification constants.
+const (
+	ConcurrentModificationError    = blobcore.ConcurrentModificationError
+	ConcurrentModificationRetry    = blobcore.ConcurrentModificationRetry
+	ConcurrentModificationTruncate = blobcore.ConcurrentModificationTruncate
+)
+
 // Copy options re-exported from core.
 var (
-	CopyWithOverwrite       = blobcore.CopyWithOverwrite
-	CopyWithPreserveMode    = blobcore.CopyWithPreserveMode
-	CopyWithPreserveTimes   = blobcore.CopyWithPreserveTimes
-	CopyWithCleanDest       = blobcore.CopyWithCleanDest
-	CopyWithWorkers         = blobcore.CopyWithWorkers
-	CopyWithReadConcurrency = blobcore.CopyWithReadConcurrency
-	CopyWithReadAheadBytes  = blobcore.CopyWithReadAheadBytes
+	CopyWithOverwrite         = blobcore.CopyWithOverwrite
+	CopyWithPreserveMode      = blobcore.CopyWithPreserveMode
+	CopyWithPreserveTimes     = blobcore.CopyWithPreserveTimes
+	CopyWithCleanDest         = blobcore.CopyWithCleanDest
+	CopyWithWorkers           = blobcore.CopyWithWorkers
+	CopyWithReadConcurrency   = blobcore.CopyWithReadConcurrency
+	CopyWithReadAheadBytes    = blobcore.CopyWithReadAheadBytes
+	CopyWithMaxBytesPerSecond = blobcore.CopyWithMaxBytesPerSecond
+	CopyWithProgress          = blobcore.CopyWithProgress
+	CopyWithPerFileProgress   = blobcore.CopyWithPerFileProgress
+	CopyWithOrderedProgress   = blobcore.CopyWithOrderedProgress
+	CopyWithProgressStore     = blobcore.CopyWithProgressStore
+	CopyWithInclude           = blobcore.CopyWithInclude
+	CopyWithExclude           = blobcore.CopyWithExclude
+	CopyWithMaxFiles          = blobcore.CopyWithMaxFiles
+	CopyWithMaxTotalBytes     = blobcore.CopyWithMaxTotalBytes
+	CopyWithMaxPathLength     = blobcore.CopyWithMaxPathLength
+	CopyWithMaxPathDepth      = blobcore.CopyWithMaxPathDepth
+	CopyWithSymlinks          = blobcore.CopyWithSymlinks
+	CopyWithPathMapper        = blobcore.CopyWithPathMapper
+)
+
+// TarMode constants.
+const (
+	TarModeStandard        = blobcore.TarModeStandard
+	TarModeLayerCompatible = blobcore.TarModeLayerCompatible
+)
+
+// Tar options re-exported from core.
+var (
+	WriteTarWithMode   = blobcore.WriteTarWithMode
+	WriteTarWithPrefix = blobcore.WriteTarWithPrefix
+)
+
+// Zip options re-exported from core.
+var (
+	WriteZipWithMethod = blobcore.WriteZipWithMethod
+)
+
+// Verify options re-exported from core.
+var (
+	VerifyWithConcurrency = blobcore.VerifyWithConcurrency
+	VerifyWithProgress    = blobcore.VerifyWithProgress
+)
+
+// Retrying source support re-exported from core.
+var (
+	RetryingSource       = blobcore.RetryingSource
+	RetryWithMaxAttempts = blobcore.RetryWithMaxAttempts
+	RetryWithBackoff     = blobcore.RetryWithBackoff
+	RetryWithClassifier  = blobcore.RetryWithClassifier
+	RetryWithBudget      = blobcore.RetryWithBudget
+	IsTransientError     = blobcore.IsTransientError
+)
+
+// NewReaderAtSource adapts an io.ReaderAt holding the data blob to a
+// ByteSource.
+var NewReaderAtSource = blobcore.NewReaderAtSource
+
+// NewReadSeeker returns a seekable reader over a whole ByteSource.
+var NewReadSeeker = blobcore.NewReadSeeker
+
+// NewFailoverSource serves reads from the first healthy source among mirrors
+// of the same data blob.
+var NewFailoverSource = blobcore.NewFailoverSource
+
+// Process-wide decoder memory limit re-exported from core.
+var (
+	SetGlobalDecoderMemoryLimit = blobcore.SetGlobalDecoderMemoryLimit
+	GlobalDecoderMemoryInUse    = blobcore.GlobalDecoderMemoryInUse
 )
 
 // DefaultSkipCompression returns a SkipCompressionFunc that skips small files
 // and known already-compressed extensions.
 var DefaultSkipCompression = blobcore.DefaultSkipCompression
 
+// TrainZstdDictionary builds a zstd dictionary from the regular files in dir.
+var TrainZstdDictionary = blobcore.TrainZstdDictionary
+
 // NormalizePath converts a user-provided path to fs.ValidPath format.
 var NormalizePath = blobcore.NormalizePath
 